@@ -29,6 +29,10 @@ func (e Error) Tag(tag Error) Error {
 	return Error(string(tag) + ":" + string(e))
 }
 
+// KvdbPrefix is the kvdb prefix of the alerts, which changes when an alert is
+// raised, cleared or deleted
+const KvdbPrefix = kvdbKey
+
 const (
 	kvdbKey                    = "alerts"
 	typeAssertionError   Error = "type assertion error"
@@ -1078,6 +1078,20 @@ func (c *Cluster) ToStorageCluster() *StorageCluster {
 	return cluster
 }
 
+// FindNode returns the node of the cluster with the id, the management ip or
+// the data ip, or nil if it is not found
+func (c *Cluster) FindNode(id string) *Node {
+	if len(id) == 0 {
+		return nil
+	}
+	for _, n := range c.Nodes {
+		if n.Id == id || n.MgmtIp == id || n.DataIp == id {
+			return n
+		}
+	}
+	return nil
+}
+
 func CloudBackupStatusTypeToSdkCloudBackupStatusType(
 	t CloudBackupStatusType,
 ) SdkCloudBackupStatusType {
@@ -1393,6 +1407,22 @@ func (s *SdkStoragePolicy) IsPublic(accessType Ownership_AccessType) bool {
 	return s.GetOwnership() == nil || s.GetOwnership().IsPublic(accessType)
 }
 
+// IsPermitted returns true if the user in the context has the access to the
+// volume template
+func (t *SdkVolumeTemplate) IsPermitted(ctx context.Context, accessType Ownership_AccessType) bool {
+	if t.GetOwnership() == nil || t.GetOwnership().IsPublic(accessType) {
+		return true
+	}
+
+	if userinfo, ok := auth.NewUserInfoFromContext(ctx); ok {
+		return t.GetOwnership().IsPermitted(userinfo, accessType)
+	}
+
+	// There is no user information in the context so authorization is not
+	// running
+	return true
+}
+
 func CloudBackupRequestedStateToSdkCloudBackupRequestedState(
 	t string,
 ) SdkCloudBackupRequestedState {
@@ -115,3 +115,18 @@ func TestStringToSdkCloudBackupStatusType(t *testing.T) {
 			StringToSdkCloudBackupStatusType(test.internalType))
 	}
 }
+
+func TestClusterFindNode(t *testing.T) {
+	c := &Cluster{
+		Nodes: []*Node{
+			{Id: "node1", MgmtIp: "10.0.0.1", DataIp: "10.1.0.1"},
+			{Id: "node2", MgmtIp: "10.0.0.2", DataIp: "10.1.0.2"},
+		},
+	}
+
+	assert.Equal(t, "node1", c.FindNode("node1").Id)
+	assert.Equal(t, "node2", c.FindNode("10.0.0.2").Id)
+	assert.Equal(t, "node2", c.FindNode("10.1.0.2").Id)
+	assert.Nil(t, c.FindNode("node3"))
+	assert.Nil(t, c.FindNode(""))
+}
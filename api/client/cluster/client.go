@@ -12,6 +12,7 @@ import (
 	"github.com/libopenstorage/openstorage/pkg/clusterdomain"
 	"github.com/libopenstorage/openstorage/pkg/defrag"
 	"github.com/libopenstorage/openstorage/pkg/diags"
+	"github.com/libopenstorage/openstorage/pkg/fstrim"
 	"github.com/libopenstorage/openstorage/pkg/job"
 	"github.com/libopenstorage/openstorage/pkg/nodedrain"
 	"github.com/libopenstorage/openstorage/pkg/schedule"
@@ -39,6 +40,7 @@ type clusterClient struct {
 	nodedrain.UnsupportedNodeDrainProvider
 	diags.UnsupportedDiagsProvider
 	defrag.UnsupportedDefragProvider
+	fstrim.UnsupportedFilesystemTrimProvider
 	c *client.Client
 }
 
@@ -51,6 +51,35 @@ func (mr *MockOpenStorageDiagsServerMockRecorder) Collect(arg0, arg1 interface{}
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collect", reflect.TypeOf((*MockOpenStorageDiagsServer)(nil).Collect), arg0, arg1)
 }
 
+// CollectNode mocks base method.
+func (m *MockOpenStorageDiagsServer) CollectNode(arg0 context.Context, arg1 *api.SdkDiagsCollectNodeRequest) (*api.SdkDiagsCollectNodeResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectNode", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkDiagsCollectNodeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectNode indicates an expected call of CollectNode.
+func (mr *MockOpenStorageDiagsServerMockRecorder) CollectNode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectNode", reflect.TypeOf((*MockOpenStorageDiagsServer)(nil).CollectNode), arg0, arg1)
+}
+
+// CollectStream mocks base method.
+func (m *MockOpenStorageDiagsServer) CollectStream(arg0 *api.SdkDiagsCollectRequest, arg1 api.OpenStorageDiags_CollectStreamServer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectStream", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CollectStream indicates an expected call of CollectStream.
+func (mr *MockOpenStorageDiagsServerMockRecorder) CollectStream(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectStream", reflect.TypeOf((*MockOpenStorageDiagsServer)(nil).CollectStream), arg0, arg1)
+}
+
 // MockOpenStorageDiagsClient is a mock of OpenStorageDiagsClient interface.
 type MockOpenStorageDiagsClient struct {
 	ctrl     *gomock.Controller
@@ -93,3 +122,43 @@ func (mr *MockOpenStorageDiagsClientMockRecorder) Collect(arg0, arg1 interface{}
 	varargs := append([]interface{}{arg0, arg1}, arg2...)
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collect", reflect.TypeOf((*MockOpenStorageDiagsClient)(nil).Collect), varargs...)
 }
+
+// CollectNode mocks base method.
+func (m *MockOpenStorageDiagsClient) CollectNode(arg0 context.Context, arg1 *api.SdkDiagsCollectNodeRequest, arg2 ...grpc.CallOption) (*api.SdkDiagsCollectNodeResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CollectNode", varargs...)
+	ret0, _ := ret[0].(*api.SdkDiagsCollectNodeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectNode indicates an expected call of CollectNode.
+func (mr *MockOpenStorageDiagsClientMockRecorder) CollectNode(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectNode", reflect.TypeOf((*MockOpenStorageDiagsClient)(nil).CollectNode), varargs...)
+}
+
+// CollectStream mocks base method.
+func (m *MockOpenStorageDiagsClient) CollectStream(arg0 context.Context, arg1 *api.SdkDiagsCollectRequest, arg2 ...grpc.CallOption) (api.OpenStorageDiags_CollectStreamClient, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CollectStream", varargs...)
+	ret0, _ := ret[0].(api.OpenStorageDiags_CollectStreamClient)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectStream indicates an expected call of CollectStream.
+func (mr *MockOpenStorageDiagsClientMockRecorder) CollectStream(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectStream", reflect.TypeOf((*MockOpenStorageDiagsClient)(nil).CollectStream), varargs...)
+}
@@ -96,6 +96,21 @@ func (mr *MockOpenStorageFilesystemTrimServerMockRecorder) AutoFSTrimUsage(arg0,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AutoFSTrimUsage", reflect.TypeOf((*MockOpenStorageFilesystemTrimServer)(nil).AutoFSTrimUsage), arg0, arg1)
 }
 
+// CreateSchedule mocks base method.
+func (m *MockOpenStorageFilesystemTrimServer) CreateSchedule(arg0 context.Context, arg1 *api.SdkCreateFilesystemTrimScheduleRequest) (*api.SdkCreateFilesystemTrimScheduleResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateSchedule", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkCreateFilesystemTrimScheduleResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockOpenStorageFilesystemTrimServerMockRecorder) CreateSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockOpenStorageFilesystemTrimServer)(nil).CreateSchedule), arg0, arg1)
+}
+
 // Start mocks base method.
 func (m *MockOpenStorageFilesystemTrimServer) Start(arg0 context.Context, arg1 *api.SdkFilesystemTrimStartRequest) (*api.SdkFilesystemTrimStartResponse, error) {
 	m.ctrl.T.Helper()
@@ -244,6 +259,26 @@ func (mr *MockOpenStorageFilesystemTrimClientMockRecorder) AutoFSTrimUsage(arg0,
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AutoFSTrimUsage", reflect.TypeOf((*MockOpenStorageFilesystemTrimClient)(nil).AutoFSTrimUsage), varargs...)
 }
 
+// CreateSchedule mocks base method.
+func (m *MockOpenStorageFilesystemTrimClient) CreateSchedule(arg0 context.Context, arg1 *api.SdkCreateFilesystemTrimScheduleRequest, arg2 ...grpc.CallOption) (*api.SdkCreateFilesystemTrimScheduleResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "CreateSchedule", varargs...)
+	ret0, _ := ret[0].(*api.SdkCreateFilesystemTrimScheduleResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateSchedule indicates an expected call of CreateSchedule.
+func (mr *MockOpenStorageFilesystemTrimClientMockRecorder) CreateSchedule(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateSchedule", reflect.TypeOf((*MockOpenStorageFilesystemTrimClient)(nil).CreateSchedule), varargs...)
+}
+
 // Start mocks base method.
 func (m *MockOpenStorageFilesystemTrimClient) Start(arg0 context.Context, arg1 *api.SdkFilesystemTrimStartRequest, arg2 ...grpc.CallOption) (*api.SdkFilesystemTrimStartResponse, error) {
 	m.ctrl.T.Helper()
@@ -36,6 +36,21 @@ func (m *MockOpenStorageVolumeServer) EXPECT() *MockOpenStorageVolumeServerMockR
 	return m.recorder
 }
 
+// Bulk mocks base method.
+func (m *MockOpenStorageVolumeServer) Bulk(arg0 context.Context, arg1 *api.SdkVolumeBulkRequest) (*api.SdkVolumeBulkResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Bulk", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkVolumeBulkResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Bulk indicates an expected call of Bulk.
+func (mr *MockOpenStorageVolumeServerMockRecorder) Bulk(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bulk", reflect.TypeOf((*MockOpenStorageVolumeServer)(nil).Bulk), arg0, arg1)
+}
+
 // CapacityUsage mocks base method.
 func (m *MockOpenStorageVolumeServer) CapacityUsage(arg0 context.Context, arg1 *api.SdkVolumeCapacityUsageRequest) (*api.SdkVolumeCapacityUsageResponse, error) {
 	m.ctrl.T.Helper()
@@ -246,6 +261,51 @@ func (mr *MockOpenStorageVolumeServerMockRecorder) Stats(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockOpenStorageVolumeServer)(nil).Stats), arg0, arg1)
 }
 
+// TrashEnumerate mocks base method.
+func (m *MockOpenStorageVolumeServer) TrashEnumerate(arg0 context.Context, arg1 *api.SdkVolumeTrashEnumerateRequest) (*api.SdkVolumeTrashEnumerateResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrashEnumerate", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkVolumeTrashEnumerateResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrashEnumerate indicates an expected call of TrashEnumerate.
+func (mr *MockOpenStorageVolumeServerMockRecorder) TrashEnumerate(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashEnumerate", reflect.TypeOf((*MockOpenStorageVolumeServer)(nil).TrashEnumerate), arg0, arg1)
+}
+
+// TrashPurge mocks base method.
+func (m *MockOpenStorageVolumeServer) TrashPurge(arg0 context.Context, arg1 *api.SdkVolumeTrashPurgeRequest) (*api.SdkVolumeTrashPurgeResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrashPurge", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkVolumeTrashPurgeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrashPurge indicates an expected call of TrashPurge.
+func (mr *MockOpenStorageVolumeServerMockRecorder) TrashPurge(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashPurge", reflect.TypeOf((*MockOpenStorageVolumeServer)(nil).TrashPurge), arg0, arg1)
+}
+
+// TrashRestore mocks base method.
+func (m *MockOpenStorageVolumeServer) TrashRestore(arg0 context.Context, arg1 *api.SdkVolumeTrashRestoreRequest) (*api.SdkVolumeTrashRestoreResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TrashRestore", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkVolumeTrashRestoreResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrashRestore indicates an expected call of TrashRestore.
+func (mr *MockOpenStorageVolumeServerMockRecorder) TrashRestore(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashRestore", reflect.TypeOf((*MockOpenStorageVolumeServer)(nil).TrashRestore), arg0, arg1)
+}
+
 // Update mocks base method.
 func (m *MockOpenStorageVolumeServer) Update(arg0 context.Context, arg1 *api.SdkVolumeUpdateRequest) (*api.SdkVolumeUpdateResponse, error) {
 	m.ctrl.T.Helper()
@@ -299,6 +359,26 @@ func (m *MockOpenStorageVolumeClient) EXPECT() *MockOpenStorageVolumeClientMockR
 	return m.recorder
 }
 
+// Bulk mocks base method.
+func (m *MockOpenStorageVolumeClient) Bulk(arg0 context.Context, arg1 *api.SdkVolumeBulkRequest, arg2 ...grpc.CallOption) (*api.SdkVolumeBulkResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Bulk", varargs...)
+	ret0, _ := ret[0].(*api.SdkVolumeBulkResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Bulk indicates an expected call of Bulk.
+func (mr *MockOpenStorageVolumeClientMockRecorder) Bulk(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Bulk", reflect.TypeOf((*MockOpenStorageVolumeClient)(nil).Bulk), varargs...)
+}
+
 // CapacityUsage mocks base method.
 func (m *MockOpenStorageVolumeClient) CapacityUsage(arg0 context.Context, arg1 *api.SdkVolumeCapacityUsageRequest, arg2 ...grpc.CallOption) (*api.SdkVolumeCapacityUsageResponse, error) {
 	m.ctrl.T.Helper()
@@ -579,6 +659,66 @@ func (mr *MockOpenStorageVolumeClientMockRecorder) Stats(arg0, arg1 interface{},
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockOpenStorageVolumeClient)(nil).Stats), varargs...)
 }
 
+// TrashEnumerate mocks base method.
+func (m *MockOpenStorageVolumeClient) TrashEnumerate(arg0 context.Context, arg1 *api.SdkVolumeTrashEnumerateRequest, arg2 ...grpc.CallOption) (*api.SdkVolumeTrashEnumerateResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TrashEnumerate", varargs...)
+	ret0, _ := ret[0].(*api.SdkVolumeTrashEnumerateResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrashEnumerate indicates an expected call of TrashEnumerate.
+func (mr *MockOpenStorageVolumeClientMockRecorder) TrashEnumerate(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashEnumerate", reflect.TypeOf((*MockOpenStorageVolumeClient)(nil).TrashEnumerate), varargs...)
+}
+
+// TrashPurge mocks base method.
+func (m *MockOpenStorageVolumeClient) TrashPurge(arg0 context.Context, arg1 *api.SdkVolumeTrashPurgeRequest, arg2 ...grpc.CallOption) (*api.SdkVolumeTrashPurgeResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TrashPurge", varargs...)
+	ret0, _ := ret[0].(*api.SdkVolumeTrashPurgeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrashPurge indicates an expected call of TrashPurge.
+func (mr *MockOpenStorageVolumeClientMockRecorder) TrashPurge(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashPurge", reflect.TypeOf((*MockOpenStorageVolumeClient)(nil).TrashPurge), varargs...)
+}
+
+// TrashRestore mocks base method.
+func (m *MockOpenStorageVolumeClient) TrashRestore(arg0 context.Context, arg1 *api.SdkVolumeTrashRestoreRequest, arg2 ...grpc.CallOption) (*api.SdkVolumeTrashRestoreResponse, error) {
+	m.ctrl.T.Helper()
+	varargs := []interface{}{arg0, arg1}
+	for _, a := range arg2 {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "TrashRestore", varargs...)
+	ret0, _ := ret[0].(*api.SdkVolumeTrashRestoreResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TrashRestore indicates an expected call of TrashRestore.
+func (mr *MockOpenStorageVolumeClientMockRecorder) TrashRestore(arg0, arg1 interface{}, arg2 ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{arg0, arg1}, arg2...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TrashRestore", reflect.TypeOf((*MockOpenStorageVolumeClient)(nil).TrashRestore), varargs...)
+}
+
 // Update mocks base method.
 func (m *MockOpenStorageVolumeClient) Update(arg0 context.Context, arg1 *api.SdkVolumeUpdateRequest, arg2 ...grpc.CallOption) (*api.SdkVolumeUpdateResponse, error) {
 	m.ctrl.T.Helper()
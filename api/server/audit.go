@@ -0,0 +1,178 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/audit"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+)
+
+var (
+	// AuditSink is set by osd program to save the audit log of the mutating
+	// requests to the volume management API
+	AuditSink audit.Sink
+)
+
+// auditResponseWriter keeps the status and the body of a response
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+// auditRoute records the mutating requests to the route in the audit log:
+// the route, the resources of the request and of its response, the request
+// with its secrets redacted and its result. The requests which the handlers
+// forward to the SDK server are recorded by it too.
+func auditRoute(route *Route, next http.Handler) http.Handler {
+	if route.verb == http.MethodGet {
+		return next
+	}
+	method := route.verb + " " + route.path
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sink := AuditSink
+		if sink == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		entry := &api.SdkAuditEntry{
+			Time:          timestamppb.Now(),
+			Method:        method,
+			CorrelationId: r.Header.Get(correlation.ContextIDKey),
+		}
+		var body []byte
+		if r.Body != nil {
+			body, _ = ioutil.ReadAll(r.Body)
+			r.Body.Close()
+			r.Body = ioutil.NopCloser(bytes.NewReader(body))
+		}
+
+		resp := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(resp, r)
+
+		entry.Request = audit.RedactJSON(body)
+		entry.ResourceIds = routeResourceIds(r, body, resp.body.Bytes())
+		if resp.status >= http.StatusBadRequest {
+			entry.Code = int32(httpStatusCode(resp.status))
+			entry.Message = strings.TrimSpace(resp.body.String())
+		} else if msg := responseError(resp.body.Bytes()); len(msg) != 0 {
+			entry.Code = int32(codes.Unknown)
+			entry.Message = msg
+		}
+
+		if err := sink.Write(entry); err != nil {
+			logrus.Errorf("Unable to write %s request to the audit log: %v", method, err)
+		}
+	})
+}
+
+// auditRoutes returns the routes with their mutating requests recorded in
+// the audit log
+func auditRoutes(routes []*Route) []*Route {
+	for _, route := range routes {
+		route.fn = auditRoute(route, http.HandlerFunc(route.fn)).ServeHTTP
+	}
+	return routes
+}
+
+// routeResourceIds returns the variables of the path of the request, then
+// the ids found in its body and in the body of its response
+func routeResourceIds(r *http.Request, body, resp []byte) []string {
+	vars := mux.Vars(r)
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if len(id) != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, name := range names {
+		add(vars[name])
+	}
+	for _, id := range audit.JSONResourceIds(body, resp) {
+		add(id)
+	}
+	return ids
+}
+
+// responseError returns the error of the responses which report it in their
+// body, such as api.VolumeResponse, possibly nested in another response
+func responseError(data []byte) string {
+	var doc map[string]interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return ""
+	}
+	var find func(doc map[string]interface{}) string
+	find = func(doc map[string]interface{}) string {
+		if msg, ok := doc["error"].(string); ok && len(msg) != 0 {
+			return msg
+		}
+		for _, v := range doc {
+			if nested, ok := v.(map[string]interface{}); ok {
+				if msg := find(nested); len(msg) != 0 {
+					return msg
+				}
+			}
+		}
+		return ""
+	}
+	return find(doc)
+}
+
+// httpStatusCode returns the gRPC code of an HTTP error status
+func httpStatusCode(status int) codes.Code {
+	switch status {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return codes.FailedPrecondition
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusInternalServerError:
+		return codes.Internal
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+	return codes.Unknown
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+// memoryAuditSink keeps the audit log entries in memory
+type memoryAuditSink struct {
+	entries []*api.SdkAuditEntry
+}
+
+func (m *memoryAuditSink) Write(entry *api.SdkAuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func TestAuditRoutes(t *testing.T) {
+	sink := &memoryAuditSink{}
+	AuditSink = sink
+	defer func() { AuditSink = nil }()
+
+	router := mux.NewRouter()
+	routes := auditRoutes([]*Route{
+		{verb: "GET", path: "/v1/volumes/{id}", fn: func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(&api.Volume{Id: mux.Vars(r)["id"]})
+		}},
+		{verb: "PUT", path: "/v1/volumes/{id}", fn: func(w http.ResponseWriter, r *http.Request) {
+			var req api.VolumeSetRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			json.NewEncoder(w).Encode(&api.VolumeSetResponse{
+				VolumeResponse: &api.VolumeResponse{Error: "attach failed"},
+			})
+		}},
+		{verb: "POST", path: "/v1/creds", fn: func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "invalid credentials", http.StatusBadRequest)
+		}},
+	})
+	for _, route := range routes {
+		router.Methods(route.verb).Path(route.path).HandlerFunc(route.fn)
+	}
+	serve := func(method, path string, body interface{}) *httptest.ResponseRecorder {
+		data, err := json.Marshal(body)
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewReader(data))
+		req.Header.Set("correlation-context-id", "correlation")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Read-only requests are not recorded
+	serve("GET", "/v1/volumes/vol", nil)
+	assert.Empty(t, sink.entries)
+
+	// The errors reported in the body of the response are recorded
+	w := serve("PUT", "/v1/volumes/vol", &api.VolumeSetRequest{
+		Action:  &api.VolumeStateAction{Attach: api.VolumeActionParam_VOLUME_ACTION_PARAM_ON},
+		Options: map[string]string{"secret_key": "secret"},
+	})
+	assert.Contains(t, w.Body.String(), "attach failed")
+	require.Len(t, sink.entries, 1)
+	entry := sink.entries[0]
+	assert.Equal(t, "PUT /v1/volumes/{id}", entry.GetMethod())
+	assert.Equal(t, []string{"vol"}, entry.GetResourceIds())
+	assert.Equal(t, "correlation", entry.GetCorrelationId())
+	assert.Contains(t, entry.GetRequest(), "secret_key")
+	assert.NotContains(t, entry.GetRequest(), `"secret"`)
+	assert.Equal(t, int32(codes.Unknown), entry.GetCode())
+	assert.Equal(t, "attach failed", entry.GetMessage())
+
+	// The HTTP errors are recorded
+	w = serve("POST", "/v1/creds", map[string]string{"CredSecretKey": "secret"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	require.Len(t, sink.entries, 2)
+	entry = sink.entries[1]
+	assert.Equal(t, "POST /v1/creds", entry.GetMethod())
+	assert.NotContains(t, entry.GetRequest(), `"secret"`)
+	assert.Equal(t, int32(codes.InvalidArgument), entry.GetCode())
+	assert.Equal(t, "invalid credentials", entry.GetMessage())
+}
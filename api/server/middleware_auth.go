@@ -11,9 +11,11 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/libopenstorage/openstorage/api"
+	clustermanager "github.com/libopenstorage/openstorage/cluster/manager"
 	"github.com/libopenstorage/openstorage/pkg/auth"
 	osecrets "github.com/libopenstorage/openstorage/pkg/auth/secrets"
 	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/pkg/nodedrain"
 	"github.com/libopenstorage/openstorage/volume"
 	volumedrivers "github.com/libopenstorage/openstorage/volume/drivers"
 	lsecrets "github.com/libopenstorage/secrets"
@@ -212,7 +214,10 @@ func (a *authMiddleware) setWithAuth(w http.ResponseWriter, r *http.Request, nex
 		if req.Action.Attach != api.VolumeActionParam_VOLUME_ACTION_PARAM_NONE {
 			isOpDone = true
 			if req.Action.Attach == api.VolumeActionParam_VOLUME_ACTION_PARAM_ON {
-				_, err = d.Attach(ctx, volumeID, req.Options)
+				// Volumes cannot be attached while the node is drained
+				if err = checkAttachmentsCordon(); err == nil {
+					_, err = d.Attach(ctx, volumeID, req.Options)
+				}
 			} else {
 				err = d.Detach(ctx, volumeID, req.Options)
 			}
@@ -525,3 +530,28 @@ func (a *authMiddleware) getBody(r *http.Request) io.ReadCloser {
 	r.Body = rdr2
 	return rdr1
 }
+
+// checkAttachmentsCordon fails the attach of volumes on this node while its
+// volume attachments are cordoned by a node drain, like the attach of the SDK
+func checkAttachmentsCordon() error {
+	inst, err := clustermanager.Inst()
+	if err != nil {
+		return err
+	}
+	cordon, ok := inst.(nodedrain.AttachmentsCordon)
+	if !ok {
+		return nil
+	}
+
+	c, err := inst.Enumerate()
+	if err != nil {
+		return fmt.Errorf("failed to determine node id: %v", err)
+	}
+	cordoned, err := cordon.AttachmentsCordoned(c.NodeId)
+	if err != nil {
+		return fmt.Errorf("failed to check if attachments are cordoned on node %s: %v", c.NodeId, err)
+	} else if cordoned {
+		return fmt.Errorf("volume attachments are cordoned on node %s", c.NodeId)
+	}
+	return nil
+}
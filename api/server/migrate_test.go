@@ -56,6 +56,12 @@ func TestMigrateStart(t *testing.T) {
 	assert.NotNil(t, resp.TaskId)
 	assert.Equal(t, goodRequest.TaskId, resp.TaskId)
 
+	// Cancel Migrate, since a volume being migrated cannot be deleted
+	err = volumeclient.VolumeDriver(cl).CloudMigrateCancel(&api.CloudMigrateCancelRequest{
+		TaskId: goodRequest.TaskId,
+	})
+	assert.Nil(t, err)
+
 	// Assert volume information is correct
 	volumes := api.NewOpenStorageVolumeClient(testVolDriver.Conn())
 	ctx, err := contextWithToken(context.Background(), "test", "system.admin", testSharedSecret)
@@ -81,6 +87,14 @@ func TestMigrateCancel(t *testing.T) {
 	cl, err := volumeclient.NewAuthDriverClient(ts.URL, "fake", version, token, "", "fake")
 	assert.NoError(t, err)
 
+	// Start Migrate
+	_, err = volumeclient.VolumeDriver(cl).CloudMigrateStart(&api.CloudMigrateStartRequest{
+		TaskId:    "goodTaskID",
+		Operation: api.CloudMigrate_MigrateCluster,
+		ClusterId: "clusterID",
+	})
+	assert.Nil(t, err)
+
 	goodRequest := &api.CloudMigrateCancelRequest{
 		TaskId: "goodTaskID",
 	}
@@ -104,6 +118,14 @@ func TestMigrateStatus(t *testing.T) {
 	cl, err := volumeclient.NewAuthDriverClient(ts.URL, "fake", version, token, "", "fake")
 	assert.NoError(t, err)
 
+	// Start Migrate
+	_, err = volumeclient.VolumeDriver(cl).CloudMigrateStart(&api.CloudMigrateStartRequest{
+		TaskId:    "goodTaskID",
+		Operation: api.CloudMigrate_MigrateCluster,
+		ClusterId: "clusterID",
+	})
+	assert.Nil(t, err)
+
 	// Get Migrate status
 	resp, err := volumeclient.VolumeDriver(cl).CloudMigrateStatus(&api.CloudMigrateStatusRequest{})
 	assert.Nil(t, err)
@@ -0,0 +1,59 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/audit"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// AuditServer is an implementation of the gRPC OpenStorageAudit interface
+type AuditServer struct {
+	sink audit.Sink
+}
+
+// Enumerate returns the most recent audit log entries matching the filters
+func (s *AuditServer) Enumerate(
+	ctx context.Context,
+	req *api.SdkAuditEnumerateRequest,
+) (*api.SdkAuditEnumerateResponse, error) {
+	if s.sink == nil {
+		return nil, status.Error(codes.Unavailable, "Audit log has not been configured")
+	}
+	enumerator, ok := s.sink.(audit.Enumerator)
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "Audit log sink does not support queries")
+	}
+
+	if req.GetStartTime() != nil && req.GetEndTime() != nil &&
+		req.GetEndTime().AsTime().Before(req.GetStartTime().AsTime()) {
+		return nil, status.Error(codes.InvalidArgument, "End time must not be before start time")
+	}
+
+	entries, err := enumerator.Enumerate(req)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to enumerate audit log: %v", err)
+	}
+
+	return &api.SdkAuditEnumerateResponse{
+		Entries: entries,
+	}, nil
+}
@@ -0,0 +1,284 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/audit"
+	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/role"
+)
+
+// memoryAuditSink keeps the audit log entries in memory
+type memoryAuditSink struct {
+	entries []*api.SdkAuditEntry
+}
+
+func (m *memoryAuditSink) Write(entry *api.SdkAuditEntry) error {
+	m.entries = append(m.entries, entry)
+	return nil
+}
+
+func (m *memoryAuditSink) Enumerate(req *api.SdkAuditEnumerateRequest) ([]*api.SdkAuditEntry, error) {
+	return audit.Filter(req, m.entries), nil
+}
+
+func TestAuditServerInterceptor(t *testing.T) {
+	sink := &memoryAuditSink{}
+	s := &sdkGrpcServer{
+		config: ServerConfig{
+			AuditSink: sink,
+		},
+	}
+	ctx := auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{
+		Username: "user",
+		Claims: auth.Claims{
+			Roles: []string{"system.user"},
+		},
+	})
+
+	// Read-only requests are not recorded
+	_, err := s.auditServerUnaryInterceptor(
+		ctx,
+		&api.SdkVolumeInspectRequest{VolumeId: "vol"},
+		&grpc.UnaryServerInfo{FullMethod: "/openstorage.api.OpenStorageVolume/Inspect"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &api.SdkVolumeInspectResponse{}, nil
+		})
+	assert.NoError(t, err)
+	assert.Empty(t, sink.entries)
+
+	// Successful mutating request with a secret
+	_, err = s.auditServerUnaryInterceptor(
+		ctx,
+		&api.SdkVolumeCreateRequest{
+			Name: "vol",
+			Spec: &api.VolumeSpec{Passphrase: "secret"},
+		},
+		&grpc.UnaryServerInfo{FullMethod: "/openstorage.api.OpenStorageVolume/Create"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &api.SdkVolumeCreateResponse{VolumeId: "vol-id"}, nil
+		})
+	assert.NoError(t, err)
+	require.Len(t, sink.entries, 1)
+	entry := sink.entries[0]
+	assert.Equal(t, "user", entry.GetUsername())
+	assert.Equal(t, []string{"system.user"}, entry.GetRoles())
+	assert.Equal(t, "/openstorage.api.OpenStorageVolume/Create", entry.GetMethod())
+	assert.Equal(t, []string{"vol-id"}, entry.GetResourceIds())
+	assert.Equal(t, int32(codes.OK), entry.GetCode())
+	assert.NotNil(t, entry.GetTime())
+	assert.NotContains(t, entry.GetRequest(), "secret")
+	var req map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(entry.GetRequest()), &req))
+	assert.Equal(t, "vol", req["name"])
+
+	// Failed mutating request
+	_, err = s.auditServerUnaryInterceptor(
+		ctx,
+		&api.SdkVolumeDeleteRequest{VolumeId: "vol-id"},
+		&grpc.UnaryServerInfo{FullMethod: "/openstorage.api.OpenStorageVolume/Delete"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, status.Error(codes.NotFound, "not found")
+		})
+	assert.Error(t, err)
+	require.Len(t, sink.entries, 2)
+	entry = sink.entries[1]
+	assert.Equal(t, []string{"vol-id"}, entry.GetResourceIds())
+	assert.Equal(t, int32(codes.NotFound), entry.GetCode())
+	assert.Equal(t, "not found", entry.GetMessage())
+}
+
+// auditTestStream receives one request
+type auditTestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req proto.Message
+}
+
+func (s *auditTestStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *auditTestStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.req)
+	return nil
+}
+
+func TestAuditServerStreamInterceptor(t *testing.T) {
+	sink := &memoryAuditSink{}
+	s := &sdkGrpcServer{
+		config: ServerConfig{
+			AuditSink: sink,
+		},
+	}
+	stream := &auditTestStream{
+		ctx: auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{Username: "user"}),
+		req: &api.SdkDiagsCollectRequest{Node: &api.DiagsNodeSelector{NodeIds: []string{"node1"}}},
+	}
+
+	err := s.auditServerStreamInterceptor(
+		nil,
+		stream,
+		&grpc.StreamServerInfo{FullMethod: "/openstorage.api.OpenStorageDiags/CollectStream"},
+		func(srv interface{}, stream grpc.ServerStream) error {
+			var req api.SdkDiagsCollectRequest
+			require.NoError(t, stream.RecvMsg(&req))
+			return status.Error(codes.Aborted, "aborted")
+		})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	require.Len(t, sink.entries, 1)
+	entry := sink.entries[0]
+	assert.Equal(t, "user", entry.GetUsername())
+	assert.Equal(t, "/openstorage.api.OpenStorageDiags/CollectStream", entry.GetMethod())
+	assert.Contains(t, entry.GetRequest(), "node1")
+	assert.Equal(t, int32(codes.Aborted), entry.GetCode())
+	assert.Equal(t, "aborted", entry.GetMessage())
+}
+
+func TestAuditServerPermissionDenied(t *testing.T) {
+	kv, err := kvdb.New(mem.Name, "audit", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	rm, err := role.NewSdkRoleManager(kv)
+	require.NoError(t, err)
+	sink := &memoryAuditSink{}
+	s := &sdkGrpcServer{
+		config: ServerConfig{
+			AuditSink: sink,
+			Security: &SecurityConfig{
+				Authenticators: map[string]auth.Authenticator{"test": nil},
+			},
+		},
+		roleServer:      rm,
+		auditLogOutput:  ioutil.Discard,
+		accessLogOutput: ioutil.Discard,
+	}
+	ctx := auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{
+		Username: "viewer",
+		Claims: auth.Claims{
+			Roles: []string{role.SystemViewRoleName},
+		},
+	})
+
+	// Requests which are not authorized are recorded
+	_, err = s.invoke(
+		ctx,
+		"/openstorage.api.OpenStorageVolume/Delete",
+		&api.SdkVolumeDeleteRequest{VolumeId: "vol-id"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Fatal("request must not be authorized")
+			return nil, nil
+		})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	require.Len(t, sink.entries, 1)
+	assert.Equal(t, "viewer", sink.entries[0].GetUsername())
+	assert.Equal(t, []string{"vol-id"}, sink.entries[0].GetResourceIds())
+	assert.Equal(t, int32(codes.PermissionDenied), sink.entries[0].GetCode())
+}
+
+func TestAuditServerEnumerateSystemView(t *testing.T) {
+	kv, err := kvdb.New(mem.Name, "audit", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	rm, err := role.NewSdkRoleManager(kv)
+	require.NoError(t, err)
+	sink := &memoryAuditSink{}
+	s := &sdkGrpcServer{
+		config: ServerConfig{
+			AuditSink: sink,
+			Security: &SecurityConfig{
+				Authenticators: map[string]auth.Authenticator{"test": nil},
+			},
+		},
+		roleServer:      rm,
+		auditServer:     &AuditServer{sink: sink},
+		auditLogOutput:  ioutil.Discard,
+		accessLogOutput: ioutil.Discard,
+	}
+	viewer := auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{
+		Username: "viewer",
+		Claims: auth.Claims{
+			Roles: []string{role.SystemViewRoleName},
+		},
+	})
+	admin := auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{
+		Username: "admin",
+		Claims: auth.Claims{
+			Roles: []string{role.SystemAdminRoleName},
+		},
+	})
+	enumerate := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return s.auditServer.Enumerate(ctx, req.(*api.SdkAuditEnumerateRequest))
+	}
+
+	// Viewers cannot read the requests of other users
+	_, err = s.invoke(
+		viewer,
+		"/openstorage.api.OpenStorageAudit/Enumerate",
+		&api.SdkAuditEnumerateRequest{},
+		enumerate)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	resp, err := s.invoke(
+		admin,
+		"/openstorage.api.OpenStorageAudit/Enumerate",
+		&api.SdkAuditEnumerateRequest{},
+		enumerate)
+	require.NoError(t, err)
+	assert.Len(t, resp.(*api.SdkAuditEnumerateResponse).GetEntries(), 1)
+}
+
+func TestAuditServerEnumerate(t *testing.T) {
+	// Not configured
+	s := &AuditServer{}
+	_, err := s.Enumerate(context.Background(), &api.SdkAuditEnumerateRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	sink := &memoryAuditSink{}
+	s = &AuditServer{sink: sink}
+	now := timestamppb.Now()
+	sink.Write(&api.SdkAuditEntry{Time: now, Username: "user1"})
+	sink.Write(&api.SdkAuditEntry{Time: now, Username: "user2"})
+
+	// Bad time range
+	_, err = s.Enumerate(context.Background(), &api.SdkAuditEnumerateRequest{
+		StartTime: now,
+		EndTime:   timestamppb.New(now.AsTime().Add(-1)),
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := s.Enumerate(context.Background(), &api.SdkAuditEnumerateRequest{
+		Username: "user2",
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetEntries(), 1)
+	assert.Equal(t, "user2", resp.GetEntries()[0].GetUsername())
+}
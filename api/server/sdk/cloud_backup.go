@@ -22,6 +22,7 @@ import (
 
 	"github.com/libopenstorage/openstorage/api"
 	api_err "github.com/libopenstorage/openstorage/api/errors"
+	"github.com/libopenstorage/openstorage/pkg/parser"
 	"github.com/libopenstorage/openstorage/volume"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -347,8 +348,11 @@ func (s *CloudBackupServer) EnumerateWithFilters(
 	if s.driver(ctx) == nil {
 		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
 	}
+	selector, err := parseLabelSelector(req.GetLabelSelector())
+	if err != nil {
+		return nil, err
+	}
 	credId := req.GetCredentialId()
-	var err error
 	if len(req.GetCredentialId()) == 0 {
 		credId, err = s.defaultCloudBackupCreds(ctx)
 		if err != nil {
@@ -382,7 +386,43 @@ func (s *CloudBackupServer) EnumerateWithFilters(
 		return nil, status.Errorf(codes.Internal, "Failed to enumerate backups: %v", err)
 	}
 
-	return r.ToSdkCloudBackupEnumerateWithFiltersResponse(), nil
+	resp := r.ToSdkCloudBackupEnumerateWithFiltersResponse()
+	if len(selector) != 0 {
+		matches := make([]*api.SdkCloudBackupInfo, 0, len(resp.Backups))
+		for _, backup := range resp.Backups {
+			if parser.MatchLabelSelector(backup.GetMetadata(), selector) {
+				matches = append(matches, backup)
+			}
+		}
+		resp.Backups = matches
+	}
+	page, next, err := paginate(req, len(resp.Backups), func(i int, key api.SdkSortOrder_Key) (pageItem, bool) {
+		backup := resp.Backups[i]
+		item := pageItem{id: backup.GetId()}
+		switch key {
+		case api.SdkSortOrder_ID:
+			item.value = backup.GetId()
+		case api.SdkSortOrder_NAME:
+			item.value = backup.GetSrcVolumeName()
+		case api.SdkSortOrder_CREATION_TIME:
+			item.value = pageTime(backup.GetTimestamp())
+		default:
+			return item, false
+		}
+		return item, true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	backups := make([]*api.SdkCloudBackupInfo, 0, len(page))
+	for _, i := range page {
+		backups = append(backups, resp.Backups[i])
+	}
+	resp.Backups = backups
+	resp.NextPageToken = next
+
+	return resp, nil
 }
 
 // Status provides status on a backup
@@ -0,0 +1,381 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+	"github.com/libopenstorage/openstorage/pkg/sched"
+	"github.com/libopenstorage/openstorage/pkg/schedule"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+const (
+	// cloudBackupSchedulesPrefix keeps the records of the cloud backup
+	// schedules which have been paused, by schedule ID
+	cloudBackupSchedulesPrefix = "cluster/cloud_backup_schedules/"
+)
+
+// cloudBackupScheduleRecord is saved for the cloud backup schedules which
+// have been paused. The driver cannot pause its schedules, so a paused
+// schedule is deleted from the driver and created again when it is resumed,
+// with a new ID in the driver. The record keeps the ID of the schedule in
+// the registry the same.
+type cloudBackupScheduleRecord struct {
+	// DriverID is the ID of the schedule in the driver while it runs
+	DriverID string `json:"driver_id"`
+	// Paused is true while the schedule is deleted from the driver
+	Paused bool `json:"paused"`
+	// Info is the schedule to create again when it is resumed
+	Info api.CloudBackupScheduleInfo `json:"info"`
+}
+
+// cloudBackupScheduleSource lists the cloud backup schedules of the volume
+// driver in the schedule registry. The driver keeps and runs them itself.
+type cloudBackupScheduleSource struct {
+	driver volume.VolumeDriver
+	kv     kvdb.Kvdb
+}
+
+// NewCloudBackupScheduleSource returns the source of the cloud backup
+// schedules of a volume driver. The paused schedules are kept in kv.
+func NewCloudBackupScheduleSource(driver volume.VolumeDriver, kv kvdb.Kvdb) schedule.Source {
+	return &cloudBackupScheduleSource{
+		driver: driver,
+		kv:     kv,
+	}
+}
+
+func cloudBackupScheduleKey(id string) string {
+	return cloudBackupSchedulesPrefix + id
+}
+
+// records returns the records of the schedules which have been paused, by
+// schedule ID
+func (s *cloudBackupScheduleSource) records() (map[string]*cloudBackupScheduleRecord, error) {
+	kvps, err := s.kv.Enumerate(cloudBackupSchedulesPrefix)
+	if err != nil {
+		return nil, err
+	}
+	records := make(map[string]*cloudBackupScheduleRecord, len(kvps))
+	for _, kvp := range kvps {
+		rec := &cloudBackupScheduleRecord{}
+		if err := json.Unmarshal(kvp.Value, rec); err != nil {
+			logrus.Warnf("Invalid record of cloud backup schedule %s: %v", kvp.Key, err)
+			continue
+		}
+		records[strings.TrimPrefix(kvp.Key, cloudBackupSchedulesPrefix)] = rec
+	}
+	return records, nil
+}
+
+// Schedules returns the cloud backup schedules of the driver, and those which
+// are paused
+func (s *cloudBackupScheduleSource) Schedules() ([]*api.Schedule, error) {
+	r, err := s.driver.CloudBackupSchedEnumerate()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to enumerate cloud backup schedules: %v", err)
+	}
+	records, err := s.records()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get paused cloud backup schedules: %v", err)
+	}
+
+	now := time.Now()
+	schedules := make([]*api.Schedule, 0, len(r.Schedules)+len(records))
+	resumed := make(map[string]bool, len(records))
+	for id, rec := range records {
+		if rec.Paused {
+			schedules = append(schedules, s.toSchedule(id, rec.Info, true, now))
+			continue
+		}
+		// The schedule may have been deleted with the cloud backup api
+		info, ok := r.Schedules[rec.DriverID]
+		if !ok {
+			continue
+		}
+		resumed[rec.DriverID] = true
+		schedules = append(schedules, s.toSchedule(id, info, false, now))
+	}
+	for id, info := range r.Schedules {
+		if !resumed[id] {
+			schedules = append(schedules, s.toSchedule(id, info, false, now))
+		}
+	}
+	return schedules, nil
+}
+
+// toSchedule returns a cloud backup schedule of the driver as a schedule of
+// the registry
+func (s *cloudBackupScheduleSource) toSchedule(
+	id string,
+	info api.CloudBackupScheduleInfo,
+	paused bool,
+	now time.Time,
+) *api.Schedule {
+	sch := &api.Schedule{
+		Id:        id,
+		StartTime: info.Schedule,
+		Type:      api.Job_CLOUD_BACKUP,
+		Paused:    paused,
+		Tasks: []*api.Job{
+			{
+				Type: api.Job_CLOUD_BACKUP,
+				Job: &api.Job_CloudBackup{
+					CloudBackup: ToSdkCloudBackupdScheduleInfo(info),
+				},
+			},
+		},
+	}
+	if intervals, err := sched.ParseSchedule(info.Schedule); err == nil && len(intervals) != 0 {
+		sch.StartTime = sched.ScheduleSummary(intervals, nil)
+		var next time.Time
+		for _, interval := range intervals {
+			t := sched.NextAfter(interval, now)
+			if next.IsZero() || t.Before(next) {
+				next = t
+			}
+		}
+		if !paused {
+			sch.NextRunTime = prototime.TimeToTimestamp(next)
+		}
+	}
+	sch.LastRunTime, sch.Runs = s.runs(info)
+	return sch
+}
+
+// runs returns the time of the last backup of the source volume of a
+// schedule, and the outcome of the most recent backups. The driver does not
+// record which schedule took a backup, so they include the backups of the
+// volume which were not taken by the schedule. The backups of the group
+// schedules are not returned.
+func (s *cloudBackupScheduleSource) runs(
+	info api.CloudBackupScheduleInfo,
+) (*timestamppb.Timestamp, []*api.ScheduleRun) {
+	if len(info.SrcVolumeID) == 0 {
+		return nil, nil
+	}
+	r, err := s.driver.CloudBackupHistory(&api.CloudBackupHistoryRequest{
+		SrcVolumeID: info.SrcVolumeID,
+	})
+	if err != nil {
+		logrus.Warnf("Failed to get the cloud backups of volume %s: %v", info.SrcVolumeID, err)
+		return nil, nil
+	}
+
+	var last time.Time
+	runs := make([]*api.ScheduleRun, 0, len(r.HistoryList))
+	for _, item := range r.HistoryList {
+		if item.Timestamp.After(last) {
+			last = item.Timestamp
+		}
+		run := &api.ScheduleRun{
+			EndTime: prototime.TimeToTimestamp(item.Timestamp),
+			Message: fmt.Sprintf("Backup of volume %s is %s", item.SrcVolumeID, item.Status),
+		}
+		switch api.CloudBackupStatusType(item.Status) {
+		case api.CloudBackupStatusDone:
+			run.State = api.Job_DONE
+		case api.CloudBackupStatusFailed, api.CloudBackupStatusAborted, api.CloudBackupStatusStopped:
+			run.State = api.Job_FAILED
+		default:
+			// The backup is still running
+			continue
+		}
+		runs = append(runs, run)
+	}
+	if len(runs) > schedule.MaxRuns {
+		runs = runs[len(runs)-schedule.MaxRuns:]
+	}
+	if last.IsZero() {
+		return nil, runs
+	}
+	return prototime.TimeToTimestamp(last), runs
+}
+
+// record returns the record of a schedule, or a new record if the schedule
+// has never been paused
+func (s *cloudBackupScheduleSource) record(id string) (*cloudBackupScheduleRecord, *kvdb.KVPair, error) {
+	kvp, err := s.kv.Get(cloudBackupScheduleKey(id))
+	if err == kvdb.ErrNotFound {
+		return &cloudBackupScheduleRecord{DriverID: id}, nil, nil
+	} else if err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "Failed to get cloud backup schedule %s: %v", id, err)
+	}
+	rec := &cloudBackupScheduleRecord{}
+	if err := json.Unmarshal(kvp.Value, rec); err != nil {
+		return nil, nil, status.Errorf(codes.Internal, "Invalid record of cloud backup schedule %s: %v", id, err)
+	}
+	return rec, kvp, nil
+}
+
+// save saves the record of a schedule, unless it has been changed since it
+// was read as kvp
+func (s *cloudBackupScheduleSource) save(id string, rec *cloudBackupScheduleRecord, kvp *kvdb.KVPair) error {
+	value, err := json.Marshal(rec)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to marshal cloud backup schedule %s: %v", id, err)
+	}
+	if kvp == nil {
+		_, err = s.kv.Create(cloudBackupScheduleKey(id), string(value), 0)
+	} else {
+		kvp.Value = value
+		_, err = s.kv.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil)
+	}
+	if err == kvdb.ErrExist || err == kvdb.ErrModified || err == kvdb.ErrValueMismatch {
+		return status.Errorf(codes.Aborted, "Cloud backup schedule %s was changed at the same time, try again", id)
+	} else if err != nil {
+		return status.Errorf(codes.Internal, "Failed to save cloud backup schedule %s: %v", id, err)
+	}
+	return nil
+}
+
+// DeleteSchedule deletes a cloud backup schedule of the driver
+func (s *cloudBackupScheduleSource) DeleteSchedule(id string) error {
+	rec, kvp, err := s.record(id)
+	if err != nil {
+		return err
+	}
+	if !rec.Paused {
+		if err := s.driver.CloudBackupSchedDelete(&api.CloudBackupSchedDeleteRequest{
+			UUID: rec.DriverID,
+		}); err != nil {
+			return status.Errorf(codes.Internal, "Failed to delete cloud backup schedule: %v", err)
+		}
+	}
+	if kvp != nil {
+		if _, err := s.kv.Delete(cloudBackupScheduleKey(id)); err != nil && err != kvdb.ErrNotFound {
+			return status.Errorf(codes.Internal, "Failed to delete cloud backup schedule %s: %v", id, err)
+		}
+	}
+	return nil
+}
+
+// PauseSchedule pauses a cloud backup schedule by deleting it from the
+// driver, or resumes it by creating it again. Only the schedules of a volume
+// or of a group or labels of volumes can be created again.
+func (s *cloudBackupScheduleSource) PauseSchedule(id string, paused bool) (*api.Schedule, error) {
+	rec, kvp, err := s.record(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if paused && !rec.Paused {
+		r, err := s.driver.CloudBackupSchedEnumerate()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to enumerate cloud backup schedules: %v", err)
+		}
+		info, ok := r.Schedules[rec.DriverID]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "Schedule %s of type %s not found", id, api.Job_CLOUD_BACKUP)
+		} else if len(info.SrcVolumeID) == 0 && len(info.GroupID) == 0 && len(info.Labels) == 0 {
+			return nil, status.Errorf(codes.FailedPrecondition,
+				"Cloud backup schedule %s of a list of volumes cannot be paused", id)
+		}
+
+		// The schedule is saved before it is deleted from the driver, so
+		// that it is never lost
+		var prev []byte
+		if kvp != nil {
+			prev = kvp.Value
+		}
+		if err := s.save(id, &cloudBackupScheduleRecord{Paused: true, Info: info}, kvp); err != nil {
+			return nil, err
+		}
+		if err := s.driver.CloudBackupSchedDelete(&api.CloudBackupSchedDeleteRequest{
+			UUID: rec.DriverID,
+		}); err != nil {
+			s.restore(id, prev)
+			return nil, status.Errorf(codes.Internal, "Failed to pause cloud backup schedule %s: %v", id, err)
+		}
+	} else if !paused && rec.Paused {
+		driverID, err := s.create(rec.Info)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to resume cloud backup schedule %s: %v", id, err)
+		}
+		if err := s.save(id, &cloudBackupScheduleRecord{DriverID: driverID}, kvp); err != nil {
+			// Another request has changed the schedule first
+			if derr := s.driver.CloudBackupSchedDelete(&api.CloudBackupSchedDeleteRequest{
+				UUID: driverID,
+			}); derr != nil {
+				logrus.Warnf("Failed to delete cloud backup schedule %s of a failed resume: %v", driverID, derr)
+			}
+			return nil, err
+		}
+	}
+
+	schedules, err := s.Schedules()
+	if err != nil {
+		return nil, err
+	}
+	for _, sch := range schedules {
+		if sch.GetId() == id {
+			return sch, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "Schedule %s of type %s not found", id, api.Job_CLOUD_BACKUP)
+}
+
+// create creates a paused schedule again in the driver, and returns its new
+// ID in the driver
+func (s *cloudBackupScheduleSource) create(info api.CloudBackupScheduleInfo) (string, error) {
+	var r *api.CloudBackupSchedCreateResponse
+	var err error
+	if len(info.SrcVolumeID) != 0 {
+		r, err = s.driver.CloudBackupSchedCreate(&api.CloudBackupSchedCreateRequest{
+			CloudBackupScheduleInfo: info,
+		})
+	} else {
+		r, err = s.driver.CloudBackupGroupSchedCreate(&api.CloudBackupGroupSchedCreateRequest{
+			GroupID:        info.GroupID,
+			Labels:         info.Labels,
+			CredentialUUID: info.CredentialUUID,
+			Schedule:       info.Schedule,
+			MaxBackups:     info.MaxBackups,
+			Full:           info.Full,
+			RetentionDays:  info.RetentionDays,
+		})
+	}
+	if err != nil {
+		return "", err
+	}
+	return r.UUID, nil
+}
+
+// restore puts back the record of a schedule which could not be paused
+func (s *cloudBackupScheduleSource) restore(id string, prev []byte) {
+	var err error
+	if prev == nil {
+		_, err = s.kv.Delete(cloudBackupScheduleKey(id))
+	} else {
+		_, err = s.kv.Put(cloudBackupScheduleKey(id), prev, 0)
+	}
+	if err != nil {
+		logrus.Warnf("Failed to restore cloud backup schedule %s: %v", id, err)
+	}
+}
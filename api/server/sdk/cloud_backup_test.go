@@ -27,7 +27,9 @@ import (
 	"github.com/libopenstorage/openstorage/api"
 	api_err "github.com/libopenstorage/openstorage/api/errors"
 	"github.com/libopenstorage/openstorage/volume"
+	"github.com/portworx/kvdb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -1184,6 +1186,120 @@ func TestSdkCloudBackupSchedEnumerate(t *testing.T) {
 	}
 }
 
+func TestCloudBackupScheduleSource(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	// The driver deletes and creates its schedules when they are paused and
+	// resumed
+	schedules := map[string]api.CloudBackupScheduleInfo{
+		"test-uuid-1": api.CloudBackupScheduleInfo{
+			SrcVolumeID:    "myid",
+			CredentialUUID: "test-uuid-1",
+			Schedule:       "- freq: daily\n  hour: 1\n  minute: 30\n",
+			MaxBackups:     4,
+		},
+	}
+	s.MockDriver().
+		EXPECT().
+		CloudBackupSchedEnumerate().
+		DoAndReturn(func() (*api.CloudBackupSchedEnumerateResponse, error) {
+			list := make(map[string]api.CloudBackupScheduleInfo, len(schedules))
+			for id, info := range schedules {
+				list[id] = info
+			}
+			return &api.CloudBackupSchedEnumerateResponse{Schedules: list}, nil
+		}).
+		AnyTimes()
+	s.MockDriver().
+		EXPECT().
+		CloudBackupSchedDelete(gomock.Any()).
+		DoAndReturn(func(req *api.CloudBackupSchedDeleteRequest) error {
+			delete(schedules, req.UUID)
+			return nil
+		}).
+		Times(2)
+	s.MockDriver().
+		EXPECT().
+		CloudBackupSchedCreate(gomock.Any()).
+		DoAndReturn(func(req *api.CloudBackupSchedCreateRequest) (*api.CloudBackupSchedCreateResponse, error) {
+			schedules["test-uuid-2"] = req.CloudBackupScheduleInfo
+			return &api.CloudBackupSchedCreateResponse{UUID: "test-uuid-2"}, nil
+		}).
+		Times(1)
+	lastBackup := time.Now().Add(-time.Hour).Round(time.Second)
+	s.MockDriver().
+		EXPECT().
+		CloudBackupHistory(&api.CloudBackupHistoryRequest{SrcVolumeID: "myid"}).
+		Return(&api.CloudBackupHistoryResponse{
+			HistoryList: []api.CloudBackupHistoryItem{
+				{
+					SrcVolumeID: "myid",
+					Timestamp:   lastBackup.Add(-24 * time.Hour),
+					Status:      string(api.CloudBackupStatusFailed),
+				},
+				{
+					SrcVolumeID: "myid",
+					Timestamp:   lastBackup,
+					Status:      string(api.CloudBackupStatusDone),
+				},
+			},
+		}, nil).
+		AnyTimes()
+
+	source := NewCloudBackupScheduleSource(s.MockDriver(), kvdb.Instance())
+	list, err := source.Schedules()
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+	assert.Equal(t, "test-uuid-1", list[0].GetId())
+	assert.Equal(t, api.Job_CLOUD_BACKUP, list[0].GetType())
+	assert.Equal(t, "daily @01:30", list[0].GetStartTime())
+	next := list[0].GetNextRunTime().AsTime().Local()
+	assert.Equal(t, 1, next.Hour())
+	assert.Equal(t, 30, next.Minute())
+	assert.Len(t, list[0].GetTasks(), 1)
+	assert.Equal(t, "myid", list[0].GetTasks()[0].GetCloudBackup().GetSrcVolumeId())
+	assert.True(t, lastBackup.Equal(list[0].GetLastRunTime().AsTime()))
+	require.Len(t, list[0].GetRuns(), 2)
+	assert.Equal(t, api.Job_FAILED, list[0].GetRuns()[0].GetState())
+	assert.Equal(t, api.Job_DONE, list[0].GetRuns()[1].GetState())
+
+	// A paused schedule is kept in kvdb while it is deleted from the driver
+	sch, err := source.PauseSchedule("test-uuid-1", true)
+	require.NoError(t, err)
+	assert.True(t, sch.GetPaused())
+	assert.Nil(t, sch.GetNextRunTime())
+	assert.Empty(t, schedules)
+	list, err = source.Schedules()
+	assert.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "test-uuid-1", list[0].GetId())
+	assert.True(t, list[0].GetPaused())
+
+	// A resumed schedule keeps its ID
+	sch, err = source.PauseSchedule("test-uuid-1", false)
+	require.NoError(t, err)
+	assert.False(t, sch.GetPaused())
+	assert.NotNil(t, sch.GetNextRunTime())
+	assert.Equal(t, "test-uuid-1", sch.GetId())
+	assert.Contains(t, schedules, "test-uuid-2")
+	list, err = source.Schedules()
+	assert.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, "test-uuid-1", list[0].GetId())
+
+	_, err = source.PauseSchedule("other", true)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	assert.NoError(t, source.DeleteSchedule("test-uuid-1"))
+	assert.Empty(t, schedules)
+	list, err = source.Schedules()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+}
+
 func TestSdkCloudBackupSize(t *testing.T) {
 
 	// Create server and client connection
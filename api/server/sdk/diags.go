@@ -41,3 +41,19 @@ func (s *DiagsServer) Collect(ctx context.Context, in *api.SdkDiagsCollectReques
 
 	return s.cluster().Collect(ctx, in)
 }
+
+func (s *DiagsServer) CollectNode(ctx context.Context, in *api.SdkDiagsCollectNodeRequest) (*api.SdkDiagsCollectNodeResponse, error) {
+	if s.cluster() == nil {
+		return nil, status.Error(codes.Unavailable, errors.ErrResourceNotInitialized.Error())
+	}
+
+	return s.cluster().CollectNode(ctx, in)
+}
+
+func (s *DiagsServer) CollectStream(in *api.SdkDiagsCollectRequest, stream api.OpenStorageDiags_CollectStreamServer) error {
+	if s.cluster() == nil {
+		return status.Error(codes.Unavailable, errors.ErrResourceNotInitialized.Error())
+	}
+
+	return s.cluster().CollectStream(in, stream)
+}
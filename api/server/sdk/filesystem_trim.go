@@ -133,6 +133,20 @@ func (s *FilesystemTrimServer) AutoFSTrimPush(
 	return r, err
 }
 
+// Create a schedule to trim the filesystems of the volumes periodically
+func (s *FilesystemTrimServer) CreateSchedule(
+	ctx context.Context,
+	req *api.SdkCreateFilesystemTrimScheduleRequest,
+) (*api.SdkCreateFilesystemTrimScheduleResponse, error) {
+	if s.driver(ctx) == nil {
+		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+	var err error
+	r, err := s.server.cluster().CreateFilesystemTrimSchedule(ctx, req)
+
+	return r, err
+}
+
 // Pop a job to of auto fstrim queue
 func (s *FilesystemTrimServer) AutoFSTrimPop(
 	ctx context.Context,
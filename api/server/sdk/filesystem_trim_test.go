@@ -112,3 +112,51 @@ func TestSdkFilesystemTrimStop(t *testing.T) {
 	_, err := c.Stop(context.Background(), req)
 	assert.NoError(t, err)
 }
+
+func TestSdkFilesystemTrimCreateSchedule(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	trimTask := &api.FilesystemTrimJob{
+		VolumeIds:          []string{"myvol"},
+		MaxDurationMinutes: 60,
+	}
+	testMockResp := &api.SdkCreateFilesystemTrimScheduleResponse{
+		Schedule: &api.Schedule{
+			Id:                 "12345",
+			StartTime:          "daily=01:00",
+			MaxDurationMinutes: 60,
+			Type:               api.Job_FILESYSTEM_TRIM,
+			Tasks: []*api.Job{
+				{
+					Type: api.Job_FILESYSTEM_TRIM,
+					Job: &api.Job_FilesystemTrim{
+						FilesystemTrim: trimTask,
+					},
+				},
+			},
+		},
+	}
+
+	// Create response
+	s.MockCluster().
+		EXPECT().
+		CreateFilesystemTrimSchedule(gomock.Any(), gomock.Any()).
+		Return(testMockResp, nil).
+		Times(1)
+
+	// Setup client
+	c := api.NewOpenStorageFilesystemTrimClient(s.Conn())
+
+	// Get info
+	resp, err := c.CreateSchedule(context.Background(), &api.SdkCreateFilesystemTrimScheduleRequest{
+		TrimTask:  trimTask,
+		StartTime: "daily=01:00",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, testMockResp.Schedule.Id, resp.Schedule.Id)
+	assert.Len(t, resp.Schedule.Tasks, 1)
+	assert.Equal(t, trimTask.VolumeIds, resp.Schedule.Tasks[0].GetFilesystemTrim().VolumeIds)
+}
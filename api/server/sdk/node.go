@@ -53,13 +53,21 @@ func (s *NodeServer) Enumerate(
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	nodeIds := make([]string, len(c.Nodes))
-	for i, v := range c.Nodes {
-		nodeIds[i] = v.Id
+	page, next, err := paginate(req, len(c.Nodes), func(i int, key api.SdkSortOrder_Key) (pageItem, bool) {
+		return nodePageItem(c.Nodes[i], key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodeIds := make([]string, len(page))
+	for i, n := range page {
+		nodeIds[i] = c.Nodes[n].Id
 	}
 
 	return &api.SdkNodeEnumerateResponse{
-		NodeIds: nodeIds,
+		NodeIds:       nodeIds,
+		NextPageToken: next,
 	}, nil
 }
 
@@ -76,16 +84,38 @@ func (s *NodeServer) EnumerateWithFilters(
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	nodes := make([]*api.StorageNode, len(c.Nodes))
-	for i, node := range c.Nodes {
-		nodes[i] = node.ToStorageNode()
+	page, next, err := paginate(req, len(c.Nodes), func(i int, key api.SdkSortOrder_Key) (pageItem, bool) {
+		return nodePageItem(c.Nodes[i], key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]*api.StorageNode, len(page))
+	for i, n := range page {
+		nodes[i] = c.Nodes[n].ToStorageNode()
 	}
 
 	return &api.SdkNodeEnumerateWithFiltersResponse{
-		Nodes: nodes,
+		Nodes:         nodes,
+		NextPageToken: next,
 	}, nil
 }
 
+// nodePageItem returns the position of a node in a sorted listing
+func nodePageItem(node *api.Node, key api.SdkSortOrder_Key) (pageItem, bool) {
+	item := pageItem{id: node.Id}
+	switch key {
+	case api.SdkSortOrder_ID:
+		item.value = node.Id
+	case api.SdkSortOrder_NAME:
+		item.value = node.Hostname
+	default:
+		return item, false
+	}
+	return item, true
+}
+
 // Inspect returns information about a specific node
 func (s *NodeServer) Inspect(
 	ctx context.Context,
@@ -88,6 +88,44 @@ func TestSdkNodeEnumerate(t *testing.T) {
 	assert.Equal(t, r.GetNodeIds()[0], "nodeid")
 }
 
+func TestSdkNodeEnumeratePaginated(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	cluster := api.Cluster{
+		Id:     "someid",
+		NodeId: "somenodeid",
+		Nodes: []*api.Node{
+			{Id: "node1", Hostname: "c"},
+			{Id: "node2", Hostname: "a"},
+			{Id: "node3", Hostname: "b"},
+		},
+	}
+	s.MockCluster().EXPECT().Enumerate().Return(cluster, nil).Times(2)
+
+	// Setup client
+	c := api.NewOpenStorageNodeClient(s.Conn())
+
+	req := &api.SdkNodeEnumerateRequest{
+		PageSize: 2,
+		Sort: &api.SdkSortOrder{
+			Key: api.SdkSortOrder_NAME,
+		},
+	}
+	r, err := c.Enumerate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node2", "node3"}, r.GetNodeIds())
+	assert.NotEmpty(t, r.GetNextPageToken())
+
+	req.PageToken = r.GetNextPageToken()
+	r, err = c.Enumerate(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node1"}, r.GetNodeIds())
+	assert.Empty(t, r.GetNextPageToken())
+}
+
 func TestSdkNodeEnumerateFail(t *testing.T) {
 
 	// Create server and client connection
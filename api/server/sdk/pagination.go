@@ -0,0 +1,194 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sdk
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/libopenstorage/openstorage/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// pageRequest is implemented by the enumerate requests which support
+// pagination and sorting
+type pageRequest interface {
+	GetPageSize() uint32
+	GetPageToken() string
+	GetSort() *api.SdkSortOrder
+}
+
+// pageItem is the position of an item in a sorted listing. Items are ordered
+// by value, and by id when the values are equal.
+type pageItem struct {
+	id    string
+	value string
+}
+
+// pageItemFunc returns the position of the item at index i when sorting by
+// key, or false if the items cannot be sorted by key.
+type pageItemFunc func(i int, key api.SdkSortOrder_Key) (pageItem, bool)
+
+// pageToken is the position of the last item returned in a page. The next
+// page starts after this position instead of at an offset, so items created
+// or deleted between requests never cause others to be skipped or returned
+// twice.
+type pageToken struct {
+	Key        api.SdkSortOrder_Key `json:"key"`
+	Descending bool                 `json:"descending"`
+	Value      string               `json:"value"`
+	Id         string               `json:"id"`
+}
+
+func (t *pageToken) encode() string {
+	b, _ := json.Marshal(t)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodePageToken(s string) (*pageToken, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	t := &pageToken{}
+	if err := json.Unmarshal(b, t); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// less orders a before b
+func (a pageItem) less(b pageItem, descending bool) bool {
+	if a.value == b.value {
+		if descending {
+			return a.id > b.id
+		}
+		return a.id < b.id
+	}
+	if descending {
+		return a.value > b.value
+	}
+	return a.value < b.value
+}
+
+// paginate returns the indexes of the n items which are part of the page
+// requested by req, in order, and the token of the following page. Requests
+// which do not ask for a page size, page token or sort order get all the
+// items in their original order.
+func paginate(req pageRequest, n int, itemFn pageItemFunc) ([]int, string, error) {
+	if req.GetPageSize() == 0 && len(req.GetPageToken()) == 0 && req.GetSort() == nil {
+		indexes := make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, "", nil
+	}
+
+	key := req.GetSort().GetKey()
+	descending := req.GetSort().GetDescending()
+
+	items := make([]pageItem, n)
+	for i := range items {
+		item, ok := itemFn(i, key)
+		if !ok {
+			return nil, "", status.Errorf(
+				codes.InvalidArgument,
+				"Sort key %v is not supported",
+				key)
+		}
+		items[i] = item
+	}
+
+	var start *pageItem
+	if len(req.GetPageToken()) != 0 {
+		token, err := decodePageToken(req.GetPageToken())
+		if err != nil {
+			return nil, "", status.Errorf(codes.InvalidArgument, "Invalid page token: %v", err)
+		}
+		if token.Key != key || token.Descending != descending {
+			return nil, "", status.Error(
+				codes.InvalidArgument,
+				"Page token was returned for a different sort order")
+		}
+		start = &pageItem{id: token.Id, value: token.Value}
+	}
+
+	indexes := make([]int, 0, n)
+	for i, item := range items {
+		if start == nil || start.less(item, descending) {
+			indexes = append(indexes, i)
+		}
+	}
+	sort.Slice(indexes, func(i, j int) bool {
+		return items[indexes[i]].less(items[indexes[j]], descending)
+	})
+
+	if req.GetPageSize() == 0 || len(indexes) <= int(req.GetPageSize()) {
+		return indexes, "", nil
+	}
+	indexes = indexes[:req.GetPageSize()]
+	last := items[indexes[len(indexes)-1]]
+	next := &pageToken{
+		Key:        key,
+		Descending: descending,
+		Value:      last.value,
+		Id:         last.id,
+	}
+	return indexes, next.encode(), nil
+}
+
+// pageSize returns a sort value which orders sizes numerically
+func pageSize(size uint64) string {
+	return fmt.Sprintf("%020d", size)
+}
+
+// pageTime returns a sort value which orders timestamps chronologically.
+// Unset timestamps and those before the epoch sort first.
+func pageTime(ts *timestamppb.Timestamp) string {
+	var nsec int64
+	if ts != nil {
+		nsec = ts.AsTime().UnixNano()
+	}
+	if nsec < 0 {
+		nsec = 0
+	}
+	return fmt.Sprintf("%020d", nsec)
+}
+
+// volumePageItem returns the position of a volume or snapshot in a sorted
+// listing
+func volumePageItem(vol *api.Volume, key api.SdkSortOrder_Key) (pageItem, bool) {
+	item := pageItem{id: vol.GetId()}
+	switch key {
+	case api.SdkSortOrder_ID:
+		item.value = vol.GetId()
+	case api.SdkSortOrder_NAME:
+		item.value = vol.GetLocator().GetName()
+	case api.SdkSortOrder_CREATION_TIME:
+		item.value = pageTime(vol.GetCtime())
+	case api.SdkSortOrder_SIZE:
+		item.value = pageSize(vol.GetSpec().GetSize())
+	default:
+		return item, false
+	}
+	return item, true
+}
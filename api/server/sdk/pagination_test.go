@@ -0,0 +1,148 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+func pageVolumes(vols []*api.Volume) pageItemFunc {
+	return func(i int, key api.SdkSortOrder_Key) (pageItem, bool) {
+		return volumePageItem(vols[i], key)
+	}
+}
+
+func pageIds(vols []*api.Volume, page []int) []string {
+	ids := make([]string, len(page))
+	for i, n := range page {
+		ids[i] = vols[n].GetId()
+	}
+	return ids
+}
+
+func TestPaginateUnsorted(t *testing.T) {
+	vols := []*api.Volume{{Id: "c"}, {Id: "a"}, {Id: "b"}}
+
+	page, next, err := paginate(&api.SdkVolumeEnumerateWithFiltersRequest{}, len(vols), pageVolumes(vols))
+	assert.NoError(t, err)
+	assert.Empty(t, next)
+	assert.Equal(t, []string{"c", "a", "b"}, pageIds(vols, page))
+}
+
+func TestPaginateSorted(t *testing.T) {
+	now := time.Now()
+	vols := []*api.Volume{
+		{
+			Id:      "1",
+			Locator: &api.VolumeLocator{Name: "b"},
+			Ctime:   timestamppb.New(now.Add(time.Hour)),
+			Spec:    &api.VolumeSpec{Size: 100},
+		},
+		{
+			Id:      "2",
+			Locator: &api.VolumeLocator{Name: "a"},
+			Ctime:   timestamppb.New(now),
+			Spec:    &api.VolumeSpec{Size: 20},
+		},
+		{
+			Id:      "3",
+			Locator: &api.VolumeLocator{Name: "a"},
+			Ctime:   timestamppb.New(now.Add(-time.Hour)),
+			Spec:    &api.VolumeSpec{Size: 3},
+		},
+	}
+
+	tests := []struct {
+		sort     *api.SdkSortOrder
+		expected []string
+	}{
+		{&api.SdkSortOrder{}, []string{"1", "2", "3"}},
+		{&api.SdkSortOrder{Descending: true}, []string{"3", "2", "1"}},
+		{&api.SdkSortOrder{Key: api.SdkSortOrder_NAME}, []string{"2", "3", "1"}},
+		{&api.SdkSortOrder{Key: api.SdkSortOrder_NAME, Descending: true}, []string{"1", "3", "2"}},
+		{&api.SdkSortOrder{Key: api.SdkSortOrder_CREATION_TIME}, []string{"3", "2", "1"}},
+		{&api.SdkSortOrder{Key: api.SdkSortOrder_SIZE}, []string{"3", "2", "1"}},
+	}
+	for _, test := range tests {
+		page, next, err := paginate(&api.SdkVolumeEnumerateWithFiltersRequest{
+			Sort: test.sort,
+		}, len(vols), pageVolumes(vols))
+		assert.NoError(t, err)
+		assert.Empty(t, next)
+		assert.Equal(t, test.expected, pageIds(vols, page), "sort %v", test.sort)
+	}
+}
+
+func TestPaginatePages(t *testing.T) {
+	vols := []*api.Volume{{Id: "d"}, {Id: "b"}, {Id: "a"}, {Id: "c"}, {Id: "e"}}
+	req := &api.SdkVolumeEnumerateWithFiltersRequest{PageSize: 2}
+
+	page, next, err := paginate(req, len(vols), pageVolumes(vols))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, pageIds(vols, page))
+	require.NotEmpty(t, next)
+
+	// Items created or deleted before the token do not move the next page
+	vols = []*api.Volume{{Id: "d"}, {Id: "aa"}, {Id: "c"}, {Id: "e"}}
+	req.PageToken = next
+	page, next, err = paginate(req, len(vols), pageVolumes(vols))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "d"}, pageIds(vols, page))
+	require.NotEmpty(t, next)
+
+	req.PageToken = next
+	page, next, err = paginate(req, len(vols), pageVolumes(vols))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e"}, pageIds(vols, page))
+	assert.Empty(t, next)
+}
+
+func TestPaginateBadArguments(t *testing.T) {
+	vols := []*api.Volume{{Id: "a"}, {Id: "b"}}
+	_, next, err := paginate(&api.SdkVolumeEnumerateWithFiltersRequest{PageSize: 1}, len(vols), pageVolumes(vols))
+	require.NoError(t, err)
+
+	// Token from a different sort order
+	_, _, err = paginate(&api.SdkVolumeEnumerateWithFiltersRequest{
+		PageToken: next,
+		Sort:      &api.SdkSortOrder{Descending: true},
+	}, len(vols), pageVolumes(vols))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Garbage token
+	_, _, err = paginate(&api.SdkVolumeEnumerateWithFiltersRequest{
+		PageToken: "not a token",
+	}, len(vols), pageVolumes(vols))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Unsupported key
+	_, _, err = paginate(&api.SdkNodeEnumerateRequest{
+		Sort: &api.SdkSortOrder{Key: api.SdkSortOrder_SIZE},
+	}, 1, func(i int, key api.SdkSortOrder_Key) (pageItem, bool) {
+		return nodePageItem(&api.Node{Id: "node"}, key)
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
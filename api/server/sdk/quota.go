@@ -0,0 +1,61 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/sirupsen/logrus"
+)
+
+// chargeQuota moves the usage of a volume in the quotas, see
+// quota.Manager.Charge. It returns a function which reverts the change if
+// the operation on the volume fails.
+func (s *VolumeServer) chargeQuota(
+	from *api.Ownership,
+	fromUsage *api.SdkQuotaUsage,
+	to *api.Ownership,
+	toUsage *api.SdkQuotaUsage,
+) (func(), error) {
+	q := s.server.quota()
+	if q == nil {
+		return func() {}, nil
+	}
+
+	if err := q.Charge(from, fromUsage, to, toUsage); err != nil {
+		return nil, err
+	}
+	return func() {
+		if err := q.Charge(to, toUsage, from, fromUsage); err != nil {
+			logrus.Warnf("Failed to revert the usage of the quotas: %v", err)
+		}
+	}, nil
+}
+
+// recomputeQuotaUsage sets the usage of the quotas from the volumes of the
+// driver
+func (s *VolumeServer) recomputeQuotaUsage() error {
+	q := s.server.quota()
+	if q == nil {
+		return nil
+	}
+
+	return q.Recompute(func() ([]*api.Volume, error) {
+		return s.driver(context.Background()).Enumerate(&api.VolumeLocator{}, nil)
+	})
+}
@@ -0,0 +1,342 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/quota"
+)
+
+// setupQuota adds a quota manager to the test server
+func setupQuota(t *testing.T, s *testServer) *quota.SdkQuotaManager {
+	kv, err := kvdb.New(mem.Name, "quota", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	qm, err := quota.NewSdkQuotaManager(kv)
+	require.NoError(t, err)
+	s.server.netServer.quotaServer = qm
+	return qm
+}
+
+// quotaUsage returns the usage of a user
+func quotaUsage(t *testing.T, qm *quota.SdkQuotaManager, name string) *api.SdkQuotaUsage {
+	resp, err := qm.Inspect(context.Background(), &api.SdkQuotaInspectRequest{Name: name})
+	require.NoError(t, err)
+	return resp.GetUsage()
+}
+
+func TestSdkVolumeCreateQuota(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	qm := setupQuota(t, s)
+
+	_, err := qm.Set(context.Background(), &api.SdkQuotaSetRequest{
+		Quota: &api.SdkQuota{
+			Name:       "user1",
+			MaxVolumes: 1,
+		},
+	})
+	require.NoError(t, err)
+
+	createReq := func(name string) *api.SdkVolumeCreateRequest {
+		return &api.SdkVolumeCreateRequest{
+			Name: name,
+			Spec: &api.VolumeSpec{
+				Size:      1234,
+				Ownership: &api.Ownership{Owner: "user1"},
+			},
+		}
+	}
+	notFound := func(name string) {
+		s.MockDriver().
+			EXPECT().
+			Inspect(gomock.Any(), []string{name}).
+			Return(nil, fmt.Errorf("not found"))
+		s.MockDriver().
+			EXPECT().
+			Enumerate(&api.VolumeLocator{Name: name}, nil).
+			Return(nil, fmt.Errorf("not found"))
+	}
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	// The driver fails, the usage is not charged
+	notFound("vol1")
+	s.MockDriver().
+		EXPECT().
+		Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("", fmt.Errorf("MOCK ERROR"))
+	_, err = c.Create(context.Background(), createReq("vol1"))
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	notFound("vol1")
+	s.MockDriver().
+		EXPECT().
+		Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return("id1", nil)
+	_, err = c.Create(context.Background(), createReq("vol1"))
+	require.NoError(t, err)
+
+	resp, err := qm.Inspect(context.Background(), &api.SdkQuotaInspectRequest{Name: "user1"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), resp.GetUsage().GetVolumes())
+	assert.Equal(t, uint64(1234), resp.GetUsage().GetProvisionedBytes())
+
+	// Over the quota, the driver is not called
+	notFound("vol2")
+	_, err = c.Create(context.Background(), createReq("vol2"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestSdkVolumeCloneQuota(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	qm := setupQuota(t, s)
+
+	for _, name := range []string{"user1", "user2"} {
+		_, err := qm.Set(context.Background(), &api.SdkQuotaSetRequest{
+			Quota: &api.SdkQuota{Name: name, MaxVolumes: 10},
+		})
+		require.NoError(t, err)
+	}
+
+	// The parent is owned by user1 and cloned by user2. The driver returns
+	// a new object each time, as the server changes them.
+	newVolume := func(id string) *api.Volume {
+		return &api.Volume{
+			Id: id,
+			Spec: &api.VolumeSpec{
+				Size: 1000,
+				Ownership: &api.Ownership{
+					Owner: "user1",
+					Acls: &api.Ownership_AccessControl{
+						Collaborators: map[string]api.Ownership_AccessType{
+							"user2": api.Ownership_Read,
+						},
+					},
+				},
+			},
+			Locator: &api.VolumeLocator{Name: id},
+		}
+	}
+	parent := newVolume("parent")
+	require.NoError(t, qm.Charge(nil, nil, parent.GetSpec().GetOwnership(), quota.VolumeUsage(parent)))
+	ctx := auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{
+		Username: "user2",
+	})
+
+	// Only the parent and the clones exist, the clones have the ownership
+	// of the parent until they are updated. The names are not used.
+	s.MockDriver().
+		EXPECT().
+		Inspect(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, ids []string) ([]*api.Volume, error) {
+			if ids[0] == parent.GetId() {
+				return []*api.Volume{newVolume(ids[0])}, nil
+			}
+			return nil, fmt.Errorf("not found")
+		}).
+		AnyTimes()
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(locator *api.VolumeLocator, _ map[string]string) ([]*api.Volume, error) {
+			if len(locator.GetVolumeIds()) == 0 {
+				return nil, fmt.Errorf("not found")
+			}
+			return []*api.Volume{newVolume(locator.GetVolumeIds()[0])}, nil
+		}).
+		AnyTimes()
+	clone := func(name string, setErr error) error {
+		s.MockDriver().
+			EXPECT().
+			Snapshot(gomock.Any(), parent.GetId(), false, gomock.Any(), false).
+			Return(name, nil)
+		s.MockDriver().
+			EXPECT().
+			Set(gomock.Any(), name, nil, gomock.Any()).
+			Return(setErr)
+		_, err := s.server.netServer.volumeServer.Clone(ctx, &api.SdkVolumeCloneRequest{
+			Name:     name,
+			ParentId: parent.GetId(),
+		})
+		return err
+	}
+
+	// The clone is charged once to user2, the usage of user1 does not change
+	require.NoError(t, clone("clone1", nil))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 1000}, quotaUsage(t, qm, "user1"))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 1000}, quotaUsage(t, qm, "user2"))
+
+	// The ownership of the clone cannot be updated, the clone is deleted
+	// and its usage released
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "clone2").
+		Return(nil)
+	assert.Error(t, clone("clone2", fmt.Errorf("MOCK ERROR")))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 1000}, quotaUsage(t, qm, "user1"))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 1000}, quotaUsage(t, qm, "user2"))
+}
+
+func TestSdkVolumeUpdateQuota(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	qm := setupQuota(t, s)
+
+	for _, name := range []string{"user1", "user2"} {
+		_, err := qm.Set(context.Background(), &api.SdkQuotaSetRequest{
+			Quota: &api.SdkQuota{Name: name, MaxProvisionedBytes: 1000},
+		})
+		require.NoError(t, err)
+	}
+
+	// The driver returns a new object each time, as the server changes it
+	size := uint64(100)
+	newVolume := func() *api.Volume {
+		return &api.Volume{
+			Id: "vol1",
+			Spec: &api.VolumeSpec{
+				Size:      size,
+				Ownership: &api.Ownership{Owner: "user1"},
+			},
+		}
+	}
+	vol := newVolume()
+	require.NoError(t, qm.Charge(nil, nil, vol.GetSpec().GetOwnership(), quota.VolumeUsage(vol)))
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{vol.GetId()}}, nil).
+		DoAndReturn(func(*api.VolumeLocator, map[string]string) ([]*api.Volume, error) {
+			return []*api.Volume{newVolume()}, nil
+		}).
+		AnyTimes()
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	resize := func(size uint64) error {
+		_, err := c.Update(context.Background(), &api.SdkVolumeUpdateRequest{
+			VolumeId: vol.GetId(),
+			Spec: &api.VolumeSpecUpdate{
+				SizeOpt: &api.VolumeSpecUpdate_Size{Size: size},
+			},
+		})
+		return err
+	}
+
+	// Resize
+	s.MockDriver().
+		EXPECT().
+		Set(gomock.Any(), vol.GetId(), nil, gomock.Any()).
+		Return(nil)
+	require.NoError(t, resize(500))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 500}, quotaUsage(t, qm, "user1"))
+	size = 500
+
+	// Over the quota, the driver is not called
+	err := resize(2000)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 500}, quotaUsage(t, qm, "user1"))
+
+	// The driver fails, the usage is reverted
+	s.MockDriver().
+		EXPECT().
+		Set(gomock.Any(), vol.GetId(), nil, gomock.Any()).
+		Return(fmt.Errorf("MOCK ERROR"))
+	err = resize(800)
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 500}, quotaUsage(t, qm, "user1"))
+
+	// Change the owner, the usage moves to the new owner
+	s.MockDriver().
+		EXPECT().
+		Set(gomock.Any(), vol.GetId(), nil, gomock.Any()).
+		Return(nil)
+	_, err = c.Update(context.Background(), &api.SdkVolumeUpdateRequest{
+		VolumeId: vol.GetId(),
+		Spec: &api.VolumeSpecUpdate{
+			Ownership: &api.Ownership{Owner: "user2"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, &api.SdkQuotaUsage{}, quotaUsage(t, qm, "user1"))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 500}, quotaUsage(t, qm, "user2"))
+}
+
+func TestSdkVolumeSnapshotCreateQuota(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	qm := setupQuota(t, s)
+
+	_, err := qm.Set(context.Background(), &api.SdkQuotaSetRequest{
+		Quota: &api.SdkQuota{Name: "user1", MaxSnapshots: 1},
+	})
+	require.NoError(t, err)
+
+	vol := &api.Volume{
+		Id: "vol1",
+		Spec: &api.VolumeSpec{
+			Size:      100,
+			Ownership: &api.Ownership{Owner: "user1"},
+		},
+	}
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{vol.GetId()}}, nil).
+		Return([]*api.Volume{vol}, nil).
+		AnyTimes()
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	snapshot := func(name string) error {
+		_, err := c.SnapshotCreate(context.Background(), &api.SdkVolumeSnapshotCreateRequest{
+			VolumeId: vol.GetId(),
+			Name:     name,
+		})
+		return err
+	}
+
+	// The driver fails, the usage is not charged
+	s.MockDriver().
+		EXPECT().
+		Snapshot(gomock.Any(), vol.GetId(), true, &api.VolumeLocator{Name: "snap1"}, false).
+		Return("", fmt.Errorf("MOCK ERROR"))
+	assert.Equal(t, codes.Internal, status.Code(snapshot("snap1")))
+	assert.Equal(t, &api.SdkQuotaUsage{}, quotaUsage(t, qm, "user1"))
+
+	s.MockDriver().
+		EXPECT().
+		Snapshot(gomock.Any(), vol.GetId(), true, &api.VolumeLocator{Name: "snap1"}, false).
+		Return("snap1", nil)
+	require.NoError(t, snapshot("snap1"))
+	assert.Equal(t, &api.SdkQuotaUsage{Snapshots: 1}, quotaUsage(t, qm, "user1"))
+
+	// Over the quota, the driver is not called
+	assert.Equal(t, codes.ResourceExhausted, status.Code(snapshot("snap2")))
+	assert.Equal(t, &api.SdkQuotaUsage{Snapshots: 1}, quotaUsage(t, qm, "user1"))
+}
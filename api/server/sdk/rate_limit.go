@@ -0,0 +1,457 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/grpcserver"
+	"github.com/libopenstorage/openstorage/pkg/role"
+)
+
+const (
+	// RateLimitAnyRole is the role of a RateLimit which applies to all
+	// callers, including those which are not authenticated
+	RateLimitAnyRole = "*"
+
+	// ContextMetadataRetryAfterKey is the metadata key of the response header
+	// with the number of seconds to wait before retrying a throttled request.
+	// The REST Gateway returns it in the Retry-After HTTP header.
+	ContextMetadataRetryAfterKey = "retry-after"
+
+	// rateLimitDefaultRetry is the retry hint of throttled requests when the
+	// time at which they can succeed is not known, for example because too
+	// many requests are in flight
+	rateLimitDefaultRetry = time.Second
+	// rateLimitIdleTimeout is how long the limits of a user are kept after
+	// its last request
+	rateLimitIdleTimeout = 10 * time.Minute
+	// rateLimitForwardedForKey is the metadata key where the REST Gateway
+	// appends the address of its HTTP client
+	rateLimitForwardedForKey = "x-forwarded-for"
+	// rateLimitGatewayKey is the metadata key of the token with which the
+	// REST Gateway proves that a request comes from it
+	rateLimitGatewayKey = "x-sdk-gateway-token"
+)
+
+var (
+	rateLimitThrottled = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "sdk",
+			Name:      "rate_limit_throttled_total",
+			Help:      "Total number of SDK requests rejected by the rate limits.",
+		},
+		[]string{"grpc_service", "grpc_method", "role", "reason"},
+	)
+	rateLimitInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "sdk",
+			Name:      "rate_limit_in_flight",
+			Help:      "Number of SDK requests in flight subject to a rate limit.",
+		},
+		[]string{"role"},
+	)
+
+	// rateLimitLongLivedMethods are the streams which last as long as their
+	// client wants them to. They are charged to the rate of the caller but
+	// not to its requests in flight, so that a few watches do not use up the
+	// requests in flight of a user.
+	rateLimitLongLivedMethods = map[string]bool{
+		"/openstorage.api.OpenStorageWatch/Watch":         true,
+		"/openstorage.api.OpenStorageDiags/CollectStream": true,
+	}
+)
+
+// RateLimit limits the requests the users with a role can make to the SDK.
+// Each user gets its own token bucket and in flight count for each limit.
+// Callers which are not authenticated, such as all the callers when auth is
+// disabled, get their own by address. Requests through the REST Gateway are
+// keyed by the address of the HTTP client. The other clients of the unix
+// domain socket are local processes, which share the limits of the socket.
+type RateLimit struct {
+	// Role is the name of the role the limit applies to, for example
+	// role.SystemUserRoleName, or RateLimitAnyRole.
+	Role string
+	// Rules select the services and apis the limit applies to, the same
+	// way they do in a role. The limit applies to all methods if not set.
+	Rules []*api.SdkRule
+	// RequestsPerSecond is the rate at which the token bucket refills.
+	// The rate of requests is not limited if zero.
+	RequestsPerSecond float64
+	// Burst is the size of the token bucket. Defaults to RequestsPerSecond
+	// rounded up.
+	Burst int
+	// MaxInFlight is the maximum number of requests running at the same
+	// time. The number of requests in flight is not limited if zero. Long
+	// lived streams, such as watches, are not counted.
+	MaxInFlight int
+}
+
+// Validate returns an error if the limit is not valid
+func (l *RateLimit) Validate() error {
+	if len(l.Role) == 0 {
+		return fmt.Errorf("Rate limit must supply a role")
+	} else if l.RequestsPerSecond < 0 || l.Burst < 0 || l.MaxInFlight < 0 {
+		return fmt.Errorf("Rate limit of role %s must not be negative", l.Role)
+	} else if l.RequestsPerSecond == 0 && l.MaxInFlight == 0 {
+		return fmt.Errorf("Rate limit of role %s must supply requests per second or max in flight", l.Role)
+	}
+	return nil
+}
+
+// ParseRateLimit parses a rate limit given as comma separated key=value
+// pairs: role, rps for RequestsPerSecond, burst and inflight for MaxInFlight.
+// The services and methods keys select the services and apis of the limit,
+// as a rule of a role with the names separated by "|". For example
+// "role=system.user,services=volume|cloudbackup,methods=create*,rps=1" or
+// "role=*,rps=10,burst=20,inflight=5".
+func ParseRateLimit(s string) (RateLimit, error) {
+	var l RateLimit
+	var rule *api.SdkRule
+	for _, pair := range strings.Split(s, ",") {
+		kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+		if len(kv) != 2 {
+			return l, fmt.Errorf("Rate limit %q must be made of key=value pairs", s)
+		}
+		var err error
+		switch kv[0] {
+		case "role":
+			l.Role = kv[1]
+		case "rps":
+			l.RequestsPerSecond, err = strconv.ParseFloat(kv[1], 64)
+		case "burst":
+			l.Burst, err = strconv.Atoi(kv[1])
+		case "inflight":
+			l.MaxInFlight, err = strconv.Atoi(kv[1])
+		case "services", "methods":
+			if rule == nil {
+				rule = &api.SdkRule{}
+				l.Rules = []*api.SdkRule{rule}
+			}
+			if len(kv[1]) == 0 {
+				err = fmt.Errorf("must not be empty")
+			} else if kv[0] == "services" {
+				rule.Services = strings.Split(kv[1], "|")
+			} else {
+				rule.Apis = strings.Split(kv[1], "|")
+			}
+		default:
+			return l, fmt.Errorf("Unknown key %q in rate limit %q", kv[0], s)
+		}
+		if err != nil {
+			return l, fmt.Errorf("Invalid %s in rate limit %q: %v", kv[0], s, err)
+		}
+	}
+	if rule != nil && len(rule.Services) == 0 {
+		rule.Services = []string{"*"}
+	} else if rule != nil && len(rule.Apis) == 0 {
+		rule.Apis = []string{"*"}
+	}
+	return l, l.Validate()
+}
+
+// matches returns true if the limit applies to a caller with roles calling
+// fullMethod
+func (l *RateLimit) matches(roles []string, fullMethod string) bool {
+	if len(l.Rules) != 0 && role.VerifyRules(l.Rules, api.SdkRootPath, fullMethod) != nil {
+		return false
+	}
+	if l.Role == RateLimitAnyRole {
+		return true
+	}
+	for _, r := range roles {
+		if r == l.Role {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitState is the state of a limit for a user
+type rateLimitState struct {
+	limiter  *rate.Limiter
+	inFlight int
+	lastUsed time.Time
+}
+
+// rateLimiter applies the rate limits of the SDK server. It is shared by the
+// gRPC servers on the network and on the unix domain socket, so requests
+// through the REST Gateway count against the same limits.
+type rateLimiter struct {
+	limits []RateLimit
+	// gatewayToken is passed by the REST Gateway with its requests, so that
+	// the address of its HTTP client is not taken from other callers
+	gatewayToken string
+
+	lock      sync.Mutex
+	states    map[string]*rateLimitState
+	lastPrune time.Time
+}
+
+func newRateLimiter(limits []RateLimit) (*rateLimiter, error) {
+	if len(limits) == 0 {
+		return nil, nil
+	}
+	for i := range limits {
+		if err := limits[i].Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("Failed to generate the token of the REST Gateway: %v", err)
+	}
+
+	return &rateLimiter{
+		limits:       limits,
+		gatewayToken: hex.EncodeToString(token),
+		states:       make(map[string]*rateLimitState),
+		lastPrune:    time.Now(),
+	}, nil
+}
+
+// caller returns the name of the caller the limits are kept for: its
+// username or, if it is not authenticated, its address. The REST Gateway
+// connects through the unix domain socket and appends the address of its
+// HTTP client to the x-forwarded-for metadata, so the last address in it is
+// the one of the caller. It is only trusted along with the token of the
+// gateway. The other clients of the socket share the same limits.
+func (r *rateLimiter) caller(ctx context.Context) string {
+	if userinfo, ok := auth.NewUserInfoFromContext(ctx); ok && !userinfo.Guest && len(userinfo.Username) != 0 {
+		return userinfo.Username
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if p.Addr.Network() == "unix" {
+		md, _ := metadata.FromIncomingContext(ctx)
+		forwarded := md.Get(rateLimitForwardedForKey)
+		if len(forwarded) == 0 || !r.fromGateway(md) {
+			return "unix"
+		}
+		addrs := strings.Split(forwarded[len(forwarded)-1], ",")
+		return strings.TrimSpace(addrs[len(addrs)-1])
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// fromGateway returns true if the request with the metadata md was made by
+// the REST Gateway
+func (r *rateLimiter) fromGateway(md metadata.MD) bool {
+	tokens := md.Get(rateLimitGatewayKey)
+	return len(tokens) == 1 &&
+		subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(r.gatewayToken)) == 1
+}
+
+// gatewayUnaryClientInterceptor passes the token of the REST Gateway with
+// its unary requests
+func (r *rateLimiter) gatewayUnaryClientInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	ctx = metadata.AppendToOutgoingContext(ctx, rateLimitGatewayKey, r.gatewayToken)
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
+
+// gatewayStreamClientInterceptor passes the token of the REST Gateway with
+// its streaming requests
+func (r *rateLimiter) gatewayStreamClientInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	ctx = metadata.AppendToOutgoingContext(ctx, rateLimitGatewayKey, r.gatewayToken)
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// acquire checks the first limit which applies to the caller of fullMethod.
+// It returns a function to call once the request is done, or a
+// ResourceExhausted error with the time to wait before retrying. The
+// requests made by a handler with invoke, such as those on each volume of a
+// bulk request, are charged to the rate of the caller but not to its requests
+// in flight, as the request of the handler is already in flight. So are the
+// long lived streams.
+func (r *rateLimiter) acquire(ctx context.Context, fullMethod string) (func(), time.Duration, error) {
+	var roles []string
+	if userinfo, ok := auth.NewUserInfoFromContext(ctx); ok {
+		roles = userinfo.Claims.Roles
+	}
+	username := r.caller(ctx)
+	inFlight := !isInvoked(ctx) && !rateLimitLongLivedMethods[fullMethod]
+
+	var limit *RateLimit
+	var key string
+	for i := range r.limits {
+		if r.limits[i].matches(roles, fullMethod) {
+			limit = &r.limits[i]
+			key = strconv.Itoa(i) + "/" + username
+			break
+		}
+	}
+	if limit == nil {
+		return func() {}, 0, nil
+	}
+
+	reqService, reqApi := grpcserver.GetMethodInformation(api.SdkRootPath, fullMethod)
+	throttled := func(reason, description string, retry time.Duration) (func(), time.Duration, error) {
+		rateLimitThrottled.WithLabelValues(reqService, reqApi, limit.Role, reason).Inc()
+		return nil, retry, status.Errorf(
+			codes.ResourceExhausted,
+			"Too many requests by user %q: %s limit of role %s exceeded, retry after %v",
+			username, description, limit.Role, retry)
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	r.prune(now)
+	state, ok := r.states[key]
+	if !ok {
+		state = &rateLimitState{}
+		if limit.RequestsPerSecond > 0 {
+			burst := limit.Burst
+			if burst == 0 {
+				burst = int(math.Ceil(limit.RequestsPerSecond))
+			}
+			state.limiter = rate.NewLimiter(rate.Limit(limit.RequestsPerSecond), burst)
+		}
+		r.states[key] = state
+	}
+	state.lastUsed = now
+
+	if limit.MaxInFlight > 0 && state.inFlight >= limit.MaxInFlight && inFlight {
+		return throttled("in_flight", "in flight", rateLimitDefaultRetry)
+	}
+	if state.limiter != nil {
+		reservation := state.limiter.ReserveN(now, 1)
+		if !reservation.OK() {
+			return throttled("rate", "rate", rateLimitDefaultRetry)
+		}
+		if delay := reservation.DelayFrom(now); delay > 0 {
+			reservation.CancelAt(now)
+			return throttled("rate", "rate", delay)
+		}
+	}
+
+	if !inFlight {
+		return func() {}, 0, nil
+	}
+	state.inFlight++
+	rateLimitInFlight.WithLabelValues(limit.Role).Inc()
+	return func() {
+		r.lock.Lock()
+		defer r.lock.Unlock()
+		state.inFlight--
+		state.lastUsed = time.Now()
+		rateLimitInFlight.WithLabelValues(limit.Role).Dec()
+	}, 0, nil
+}
+
+// prune removes the state of the users which have not made requests for a
+// while. Must be called with the lock held.
+func (r *rateLimiter) prune(now time.Time) {
+	if now.Sub(r.lastPrune) < rateLimitIdleTimeout {
+		return
+	}
+	r.lastPrune = now
+	for key, state := range r.states {
+		if state.inFlight == 0 && now.Sub(state.lastUsed) > rateLimitIdleTimeout {
+			delete(r.states, key)
+		}
+	}
+}
+
+// retryAfter returns the header with the retry hint of a throttled request
+func retryAfter(retry time.Duration) metadata.MD {
+	return metadata.Pairs(
+		ContextMetadataRetryAfterKey,
+		strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+}
+
+func (s *sdkGrpcServer) rateLimitServerUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if s.rateLimiter == nil {
+		return handler(ctx, req)
+	}
+
+	done, retry, err := s.rateLimiter.acquire(ctx, info.FullMethod)
+	if err != nil {
+		grpc.SetHeader(ctx, retryAfter(retry))
+		return nil, err
+	}
+	defer done()
+
+	return handler(ctx, req)
+}
+
+func (s *sdkGrpcServer) rateLimitServerStreamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if s.rateLimiter == nil {
+		return handler(srv, stream)
+	}
+
+	done, retry, err := s.rateLimiter.acquire(stream.Context(), info.FullMethod)
+	if err != nil {
+		stream.SetHeader(retryAfter(retry))
+		return err
+	}
+	defer done()
+
+	return handler(srv, stream)
+}
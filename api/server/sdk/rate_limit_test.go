@@ -0,0 +1,300 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/role"
+)
+
+const (
+	rateLimitInspectMethod = "/openstorage.api.OpenStorageVolume/Inspect"
+	rateLimitCreateMethod  = "/openstorage.api.OpenStorageVolume/Create"
+	rateLimitBulkMethod    = "/openstorage.api.OpenStorageVolume/Bulk"
+	rateLimitWatchMethod   = "/openstorage.api.OpenStorageWatch/Watch"
+)
+
+func rateLimitUser(name string, roles ...string) context.Context {
+	return auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{
+		Username: name,
+		Claims: auth.Claims{
+			Roles: roles,
+		},
+	})
+}
+
+func TestRateLimitBadArguments(t *testing.T) {
+	tests := []RateLimit{
+		// No role
+		{RequestsPerSecond: 1},
+		// No limit
+		{Role: role.SystemUserRoleName},
+		// Negative limit
+		{Role: role.SystemUserRoleName, RequestsPerSecond: 1, MaxInFlight: -1},
+	}
+	for _, limit := range tests {
+		_, err := newRateLimiter([]RateLimit{limit})
+		assert.Error(t, err, "limit %+v", limit)
+	}
+
+	// No limits
+	r, err := newRateLimiter(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, r)
+}
+
+func TestRateLimitRequestsPerSecond(t *testing.T) {
+	r, err := newRateLimiter([]RateLimit{
+		{
+			Role: role.SystemUserRoleName,
+			Rules: []*api.SdkRule{
+				{
+					Services: []string{"volume"},
+					Apis:     []string{"inspect*", "enumerate*"},
+				},
+			},
+			RequestsPerSecond: 0.001,
+			Burst:             2,
+		},
+	})
+	require.NoError(t, err)
+
+	// Each user has its own bucket
+	for _, user := range []string{"user1", "user2"} {
+		ctx := rateLimitUser(user, role.SystemUserRoleName)
+		for i := 0; i < 2; i++ {
+			done, _, err := r.acquire(ctx, rateLimitInspectMethod)
+			require.NoError(t, err)
+			done()
+		}
+		_, retry, err := r.acquire(ctx, rateLimitInspectMethod)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.True(t, retry > 0)
+	}
+
+	// Methods and roles the limit does not apply to are not throttled
+	ctx := rateLimitUser("user1", role.SystemUserRoleName)
+	_, _, err = r.acquire(ctx, rateLimitCreateMethod)
+	assert.NoError(t, err)
+	ctx = rateLimitUser("admin", role.SystemAdminRoleName)
+	for i := 0; i < 3; i++ {
+		_, _, err = r.acquire(ctx, rateLimitInspectMethod)
+		assert.NoError(t, err)
+	}
+}
+
+func TestRateLimitMaxInFlight(t *testing.T) {
+	r, err := newRateLimiter([]RateLimit{
+		{
+			Role:        role.SystemUserRoleName,
+			MaxInFlight: 1,
+		},
+		{
+			Role:        RateLimitAnyRole,
+			MaxInFlight: 2,
+		},
+	})
+	require.NoError(t, err)
+
+	// The first matching limit applies
+	ctx := rateLimitUser("user1", role.SystemUserRoleName)
+	done, _, err := r.acquire(ctx, rateLimitCreateMethod)
+	require.NoError(t, err)
+	_, retry, err := r.acquire(ctx, rateLimitInspectMethod)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, rateLimitDefaultRetry, retry)
+
+	// Requests are allowed again once others are done
+	done()
+	done, _, err = r.acquire(ctx, rateLimitInspectMethod)
+	assert.NoError(t, err)
+	done()
+
+	// Callers without user information share the limit of any role
+	ctx = context.Background()
+	for i := 0; i < 2; i++ {
+		_, _, err = r.acquire(ctx, rateLimitInspectMethod)
+		require.NoError(t, err)
+	}
+	_, _, err = r.acquire(ctx, rateLimitInspectMethod)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRateLimitInvoked(t *testing.T) {
+	r, err := newRateLimiter([]RateLimit{
+		{
+			Role:              RateLimitAnyRole,
+			RequestsPerSecond: 0.001,
+			Burst:             3,
+			MaxInFlight:       1,
+		},
+	})
+	require.NoError(t, err)
+
+	// The requests made by a handler of a request in flight are only charged
+	// to the rate
+	ctx := rateLimitUser("user1", role.SystemUserRoleName)
+	done, _, err := r.acquire(ctx, rateLimitBulkMethod)
+	require.NoError(t, err)
+	invoked := context.WithValue(ctx, invokedContextKey{}, true)
+	for i := 0; i < 2; i++ {
+		_, _, err = r.acquire(invoked, rateLimitCreateMethod)
+		require.NoError(t, err)
+	}
+	_, _, err = r.acquire(invoked, rateLimitCreateMethod)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	done()
+}
+
+func TestRateLimitLongLived(t *testing.T) {
+	r, err := newRateLimiter([]RateLimit{
+		{
+			Role:              RateLimitAnyRole,
+			RequestsPerSecond: 0.001,
+			Burst:             3,
+			MaxInFlight:       1,
+		},
+	})
+	require.NoError(t, err)
+
+	// Long lived streams are only charged to the rate, and do not keep
+	// other requests from running
+	ctx := rateLimitUser("user1", role.SystemUserRoleName)
+	for i := 0; i < 2; i++ {
+		_, _, err = r.acquire(ctx, rateLimitWatchMethod)
+		require.NoError(t, err)
+	}
+	done, _, err := r.acquire(ctx, rateLimitInspectMethod)
+	require.NoError(t, err)
+	done()
+	_, _, err = r.acquire(ctx, rateLimitWatchMethod)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRateLimitCaller(t *testing.T) {
+	r, err := newRateLimiter([]RateLimit{{Role: RateLimitAnyRole, MaxInFlight: 1}})
+	require.NoError(t, err)
+
+	tcp := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}
+	unix := &net.UnixAddr{Name: "/var/lib/osd/driver/fake-sdk.sock", Net: "unix"}
+	spoofed := metadata.Pairs(rateLimitForwardedForKey, "1.2.3.4, 10.0.0.2")
+	forwarded := metadata.Join(spoofed, metadata.Pairs(rateLimitGatewayKey, r.gatewayToken))
+	wrongToken := metadata.Join(spoofed, metadata.Pairs(rateLimitGatewayKey, "token"))
+
+	tests := []struct {
+		ctx    context.Context
+		caller string
+	}{
+		{context.Background(), ""},
+		{rateLimitUser("user1", role.SystemUserRoleName), "user1"},
+		{peer.NewContext(context.Background(), &peer.Peer{Addr: tcp}), "10.0.0.1"},
+		{
+			auth.ContextSaveUserInfo(peer.NewContext(context.Background(), &peer.Peer{Addr: tcp}), auth.NewGuestUser()),
+			"10.0.0.1",
+		},
+		{peer.NewContext(context.Background(), &peer.Peer{Addr: unix}), "unix"},
+		// The REST Gateway appends the address of its client
+		{
+			metadata.NewIncomingContext(peer.NewContext(context.Background(), &peer.Peer{Addr: unix}), forwarded),
+			"10.0.0.2",
+		},
+		// Only the REST Gateway is trusted with x-forwarded-for
+		{
+			metadata.NewIncomingContext(peer.NewContext(context.Background(), &peer.Peer{Addr: tcp}), forwarded),
+			"10.0.0.1",
+		},
+		{
+			metadata.NewIncomingContext(peer.NewContext(context.Background(), &peer.Peer{Addr: unix}), spoofed),
+			"unix",
+		},
+		{
+			metadata.NewIncomingContext(peer.NewContext(context.Background(), &peer.Peer{Addr: unix}), wrongToken),
+			"unix",
+		},
+	}
+	for i, test := range tests {
+		assert.Equal(t, test.caller, r.caller(test.ctx), "test %d", i)
+	}
+
+	// Anonymous callers get their own limits by address
+	_, _, err = r.acquire(tests[2].ctx, rateLimitInspectMethod)
+	require.NoError(t, err)
+	_, _, err = r.acquire(tests[2].ctx, rateLimitInspectMethod)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, _, err = r.acquire(tests[5].ctx, rateLimitInspectMethod)
+	assert.NoError(t, err)
+}
+
+func TestParseRateLimit(t *testing.T) {
+	l, err := ParseRateLimit("role=system.user,rps=2.5,burst=5,inflight=3")
+	require.NoError(t, err)
+	assert.Equal(t, RateLimit{
+		Role:              role.SystemUserRoleName,
+		RequestsPerSecond: 2.5,
+		Burst:             5,
+		MaxInFlight:       3,
+	}, l)
+
+	l, err = ParseRateLimit("role=*, inflight=10")
+	require.NoError(t, err)
+	assert.Equal(t, RateLimitAnyRole, l.Role)
+	assert.Equal(t, 10, l.MaxInFlight)
+	assert.Empty(t, l.Rules)
+
+	l, err = ParseRateLimit("role=system.user,services=volume|cloudbackup,methods=create*,rps=1")
+	require.NoError(t, err)
+	assert.Equal(t, []*api.SdkRule{
+		{
+			Services: []string{"volume", "cloudbackup"},
+			Apis:     []string{"create*"},
+		},
+	}, l.Rules)
+
+	l, err = ParseRateLimit("role=*,methods=enumerate*,inflight=1")
+	require.NoError(t, err)
+	assert.Equal(t, []*api.SdkRule{
+		{
+			Services: []string{"*"},
+			Apis:     []string{"enumerate*"},
+		},
+	}, l.Rules)
+
+	for _, bad := range []string{
+		"",
+		"role=system.user",
+		"rps=1",
+		"role=system.user,rps",
+		"role=system.user,rps=fast",
+		"role=system.user,rate=1",
+		"role=system.user,services=,rps=1",
+	} {
+		_, err := ParseRateLimit(bad)
+		assert.Error(t, err, bad)
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"mime"
 	"net/http"
+	"net/textproto"
 
 	"github.com/gobuffalo/packr"
 	"github.com/grpc-ecosystem/grpc-gateway/runtime"
@@ -123,15 +124,22 @@ func (s *sdkRestGateway) restServerSetupHandlers() (http.Handler, error) {
 	mux.Handle("/metrics", promhttp.Handler())
 
 	// Create a router just for HTTP REST gRPC Server Gateway
-	gmux := runtime.NewServeMux()
-
-	// Connect to gRPC unix domain socket
-	conn, err := grpcserver.Connect(
-		s.grpcServer.Address(),
-		[]grpc.DialOption{
-			grpc.WithInsecure(),
-			grpc.WithUnaryInterceptor(correlation.ContextUnaryClientInterceptor),
-		})
+	gmux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(restHeaderMatcher),
+		runtime.WithOutgoingHeaderMatcher(restOutgoingHeaderMatcher))
+
+	// Connect to gRPC unix domain socket. The rate limits trust the address
+	// of the HTTP client passed by the gateway along with its token.
+	dialOptions := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(correlation.ContextUnaryClientInterceptor),
+	}
+	if limiter := s.grpcServer.rateLimiter; limiter != nil {
+		dialOptions = append(dialOptions,
+			grpc.WithChainUnaryInterceptor(limiter.gatewayUnaryClientInterceptor),
+			grpc.WithStreamInterceptor(limiter.gatewayStreamClientInterceptor))
+	}
+	conn, err := grpcserver.Connect(s.grpcServer.Address(), dialOptions)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to connect to gRPC handler: %v", err)
 	}
@@ -163,6 +171,9 @@ func (s *sdkRestGateway) restServerSetupHandlers() (http.Handler, error) {
 		api.RegisterOpenStorageVerifyChecksumHandler,
 		api.RegisterOpenStorageJobHandler,
 		api.RegisterOpenStorageScheduleHandler,
+		api.RegisterOpenStorageAuditHandler,
+		api.RegisterOpenStorageQuotaHandler,
+		api.RegisterOpenStorageVolumeTemplateHandler,
 	}
 
 	// REST Gateway extensions
@@ -188,3 +199,21 @@ func (s *sdkRestGateway) restServerSetupHandlers() (http.Handler, error) {
 	cmux := c.Handler(mux)
 	return cmux, nil
 }
+
+// restHeaderMatcher passes the Idempotency-Key header to the gRPC server along
+// with the headers passed by default
+func restHeaderMatcher(key string) (string, bool) {
+	if textproto.CanonicalMIMEHeaderKey(key) == "Idempotency-Key" {
+		return ContextMetadataIdempotencyKey, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// restOutgoingHeaderMatcher returns the retry hint of throttled requests in
+// the Retry-After HTTP header, and the other headers with the default prefix
+func restOutgoingHeaderMatcher(key string) (string, bool) {
+	if key == ContextMetadataRetryAfterKey {
+		return "Retry-After", true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
@@ -50,3 +50,14 @@ func (s *ScheduleServer) Delete(
 
 	return s.cluster().DeleteSchedule(ctx, req)
 }
+
+func (s *ScheduleServer) Update(
+	ctx context.Context,
+	req *api.SdkUpdateScheduleRequest,
+) (*api.SdkUpdateScheduleResponse, error) {
+	if s.cluster() == nil {
+		return nil, status.Error(codes.Unavailable, errors.ErrResourceNotInitialized.Error())
+	}
+
+	return s.cluster().UpdateSchedule(ctx, req)
+}
@@ -138,3 +138,33 @@ func TestDeleteSchedule(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, resp)
 }
+
+func TestUpdateSchedule(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	req := &api.SdkUpdateScheduleRequest{
+		Id:     "12345",
+		Type:   "DEFRAG",
+		Paused: true,
+	}
+	s.MockCluster().
+		EXPECT().
+		UpdateSchedule(gomock.Any(), gomock.Any()).
+		Return(&api.SdkUpdateScheduleResponse{
+			Schedule: &api.Schedule{
+				Id:     "12345",
+				Type:   api.Job_DEFRAG,
+				Paused: true,
+			},
+		}, nil)
+
+	// Setup client
+	c := api.NewOpenStorageScheduleClient(s.Conn())
+
+	// Pause the schedule
+	r, err := c.Update(context.Background(), req)
+	assert.NoError(t, err)
+	assert.True(t, r.GetSchedule().GetPaused())
+}
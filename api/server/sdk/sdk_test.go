@@ -134,6 +134,7 @@ func newTestServer(t *testing.T) *testServer {
 		Cluster:             tester.c,
 		StoragePolicy:       sp,
 		AlertsFilterDeleter: tester.a,
+		Kvdb:                kv,
 		AccessOutput:        ioutil.Discard,
 		AuditOutput:         ioutil.Discard,
 		Security: &SecurityConfig{
@@ -33,14 +33,20 @@ import (
 	"github.com/libopenstorage/openstorage/api/spec"
 	bucket "github.com/libopenstorage/openstorage/bucket"
 	"github.com/libopenstorage/openstorage/cluster"
+	"github.com/libopenstorage/openstorage/pkg/audit"
 	"github.com/libopenstorage/openstorage/pkg/auth"
 	"github.com/libopenstorage/openstorage/pkg/correlation"
 	"github.com/libopenstorage/openstorage/pkg/grpcserver"
 	"github.com/libopenstorage/openstorage/pkg/loadbalancer"
+	"github.com/libopenstorage/openstorage/pkg/nodedrain"
+	"github.com/libopenstorage/openstorage/pkg/quota"
 	"github.com/libopenstorage/openstorage/pkg/role"
+	"github.com/libopenstorage/openstorage/pkg/sched"
 	policy "github.com/libopenstorage/openstorage/pkg/storagepolicy"
 	"github.com/libopenstorage/openstorage/volume"
 	volumedrivers "github.com/libopenstorage/openstorage/volume/drivers"
+	"github.com/portworx/kvdb"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
@@ -114,6 +120,11 @@ type ServerConfig struct {
 	AlertsFilterDeleter alerts.FilterDeleter
 	// StoragePolicy Manager
 	StoragePolicy policy.PolicyManager
+	// (optional) Quota manager. Quotas are not enforced if not set.
+	Quota quota.Manager
+	// (optional) Volume template service. Volumes cannot be created from
+	// templates if not set.
+	VolumeTemplate api.OpenStorageVolumeTemplateServer
 	// gRPC round robin load balancer
 	RoundRobinBalancer loadbalancer.Balancer
 	// Security configuration
@@ -138,6 +149,24 @@ type ServerConfig struct {
 	// to the RestSererExtensions slice. These handlers will be registered on the
 	// REST Gateway http server.
 	RestServerExtensions []func(context.Context, *runtime.ServeMux, *grpc.ClientConn) error
+
+	// (optional) AuditSink saves the audit log of the mutating requests.
+	// The audit log is disabled if not set.
+	AuditSink audit.Sink
+	// (optional) RateLimits limit the rate and number of requests in flight
+	// of each user, or of each address for callers which are not
+	// authenticated. The first limit matching the role of the user and the
+	// method applies, so more specific limits must come first.
+	RateLimits []RateLimit
+	// (optional) VolumeTrash keeps the deleted volumes in a trash from
+	// which they can be restored. Volumes are deleted immediately if not set.
+	VolumeTrash *VolumeTrashConfig
+	// (optional) AttachmentsCordon fails the attach of volumes on this node
+	// while its volume attachments are cordoned by a node drain
+	AttachmentsCordon nodedrain.AttachmentsCordon
+	// (optional) Kvdb saves the responses of the mutating requests made
+	// with an idempotency key. Idempotency keys are rejected if not set.
+	Kvdb kvdb.Kvdb
 }
 
 // Server is an implementation of the gRPC SDK interface
@@ -151,6 +180,9 @@ type Server struct {
 
 	accessLog *os.File
 	auditLog  *os.File
+
+	// Task purging the expired volumes of the trash
+	trashPurgeTask sched.TaskID
 }
 
 type serverAccessor interface {
@@ -161,6 +193,11 @@ type serverAccessor interface {
 	balancer() loadbalancer.Balancer
 	auditLogWriter() io.Writer
 	port() string
+	quota() quota.Manager
+	volumeTrash() *VolumeTrashConfig
+	volumeTemplate() api.OpenStorageVolumeTemplateServer
+	attachmentsCordon() nodedrain.AttachmentsCordon
+	invoke(ctx context.Context, fullMethod string, req interface{}, handler grpc.UnaryHandler) (interface{}, error)
 }
 
 type logger struct {
@@ -183,6 +220,9 @@ type sdkGrpcServer struct {
 	// gRPC request balancer
 	grpcBalancer loadbalancer.Balancer
 
+	// Rate limits shared with the other gRPC server
+	rateLimiter *rateLimiter
+
 	// Interface implementations
 	clusterHandler       cluster.Cluster
 	volumeDriverHandlers map[string]volume.VolumeDriver
@@ -211,6 +251,9 @@ type sdkGrpcServer struct {
 	filesystemCheckServer api.OpenStorageFilesystemCheckServer
 	filesystemDefragServer api.OpenStorageFilesystemDefragServer
 	verifyChecksumServer  api.OpenStorageVerifyChecksumServer
+	auditServer           api.OpenStorageAuditServer
+	quotaServer           quota.Manager
+	volumeTemplateServer  api.OpenStorageVolumeTemplateServer
 	bucketServer          *BucketServer
 	watcherServer         *WatcherServer
 }
@@ -259,6 +302,17 @@ func New(config *ServerConfig) (*Server, error) {
 		config.AccessOutput = accessLog
 	}
 
+	limiter, err := newRateLimiter(config.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.VolumeTrash != nil {
+		if err := config.VolumeTrash.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	_, port, err := net.SplitHostPort(config.Address)
 	if err != nil {
 		logrus.Warnf("SDK Address NOT in host:port format, failed to get port %v", err.Error())
@@ -278,6 +332,9 @@ func New(config *ServerConfig) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	netServer.rateLimiter = limiter
+	udsServer.rateLimiter = limiter
+	udsServer.watcherServer.eventLogs = netServer.watcherServer.eventLogs
 
 	// Create REST Gateway and connect it to the unix domain socket server
 	restGateway, err := newSdkRestGateway(config, udsServer)
@@ -302,18 +359,25 @@ func (s *Server) Start() error {
 	// watcherServer should only be called once, since netServer an udsServer both implments grpcServer,
 	// we are starting the watch server here
 	go s.netServer.watcherServer.startWatcher(s.watcherCtx)
+	// The usage of the quotas may have changed while the server was down
+	if err := s.netServer.volumeServer.recomputeQuotaUsage(); err != nil {
+		logrus.Warnf("Failed to recompute the usage of the quotas: %v", err)
+	}
 	if err := s.netServer.Start(); err != nil {
 		return err
 	} else if err := s.udsServer.Start(); err != nil {
 		return err
 	} else if err := s.restGateway.Start(); err != nil {
 		return err
+	} else if err := s.startVolumeTrashPurger(); err != nil {
+		return err
 	}
 
 	return nil
 }
 
 func (s *Server) Stop() {
+	s.stopVolumeTrashPurger()
 	s.netServer.Stop()
 	s.udsServer.Stop()
 	s.restGateway.Stop()
@@ -479,13 +543,20 @@ func newSdkGrpcServer(config *ServerConfig) (*sdkGrpcServer, error) {
 	}
 	s.watcherServer = &WatcherServer{
 		volumeServer: s.volumeServer,
+		server:       s,
+		eventLogs:    newWatchEventLogs(),
 	}
 	s.verifyChecksumServer = &VerifyChecksumServer{
 		server: s,
 	}
+	s.auditServer = &AuditServer{
+		sink: config.AuditSink,
+	}
 
 	s.roleServer = config.Security.Role
 	s.policyServer = config.StoragePolicy
+	s.quotaServer = config.Quota
+	s.volumeTemplateServer = config.VolumeTemplate
 	// For the SDK server set the grpc balancer to the provided round robin balancer
 	s.grpcBalancer = config.RoundRobinBalancer
 
@@ -517,35 +588,34 @@ func (s *sdkGrpcServer) Start() error {
 	}
 
 	// Setup authentication and authorization using interceptors if auth is enabled
+	unaryInterceptors := []grpc.UnaryServerInterceptor{
+		s.rwlockUnaryIntercepter,
+		correlationInterceptor.ContextUnaryServerInterceptor,
+	}
+	if len(s.config.Security.Authenticators) != 0 {
+		unaryInterceptors = append(unaryInterceptors, grpc_auth.UnaryServerInterceptor(s.auth))
+	}
+	unaryInterceptors = append(unaryInterceptors, s.requestInterceptors()...)
+	unaryInterceptors = append(unaryInterceptors, grpc_prometheus.UnaryServerInterceptor)
+	opts = append(opts, grpc.UnaryInterceptor(
+		grpc_middleware.ChainUnaryServer(unaryInterceptors...)))
 	if len(s.config.Security.Authenticators) != 0 {
-		opts = append(opts, grpc.UnaryInterceptor(
-			grpc_middleware.ChainUnaryServer(
-				s.rwlockUnaryIntercepter,
-				correlationInterceptor.ContextUnaryServerInterceptor,
-				grpc_auth.UnaryServerInterceptor(s.auth),
-				s.authorizationServerUnaryInterceptor,
-				s.loggerServerUnaryInterceptor,
-				grpc_prometheus.UnaryServerInterceptor,
-			)))
 		opts = append(opts, grpc.StreamInterceptor(
 			grpc_middleware.ChainStreamServer(
 				s.rwlockStreamIntercepter,
 				grpc_auth.StreamServerInterceptor(s.auth),
+				s.auditServerStreamInterceptor,
 				s.authorizationServerStreamInterceptor,
+				s.rateLimitServerStreamInterceptor,
 				s.loggerServerStreamInterceptor,
 				grpc_prometheus.StreamServerInterceptor,
 			)))
 	} else {
-		opts = append(opts, grpc.UnaryInterceptor(
-			grpc_middleware.ChainUnaryServer(
-				s.rwlockUnaryIntercepter,
-				correlationInterceptor.ContextUnaryServerInterceptor,
-				s.loggerServerUnaryInterceptor,
-				grpc_prometheus.UnaryServerInterceptor,
-			)))
 		opts = append(opts, grpc.StreamInterceptor(
 			grpc_middleware.ChainStreamServer(
 				s.rwlockStreamIntercepter,
+				s.auditServerStreamInterceptor,
+				s.rateLimitServerStreamInterceptor,
 				s.loggerServerStreamInterceptor,
 				grpc_prometheus.StreamServerInterceptor,
 			)))
@@ -573,6 +643,7 @@ func (s *sdkGrpcServer) Start() error {
 		api.RegisterOpenStorageFilesystemCheckServer(grpcServer, s.filesystemCheckServer)
 		api.RegisterOpenStorageFilesystemDefragServer(grpcServer, s.filesystemDefragServer)
 		api.RegisterOpenStorageVerifyChecksumServer(grpcServer, s.verifyChecksumServer)
+		api.RegisterOpenStorageAuditServer(grpcServer, s.auditServer)
 		api.RegisterOpenStorageWatchServer(grpcServer, s.watcherServer)
 		api.RegisterOpenStorageScheduleServer(grpcServer, s.scheduleServer)
 		if s.diagsServer != nil {
@@ -590,6 +661,12 @@ func (s *sdkGrpcServer) Start() error {
 		if s.config.Security.Role != nil {
 			api.RegisterOpenStorageRoleServer(grpcServer, s.roleServer)
 		}
+		if s.quotaServer != nil {
+			api.RegisterOpenStorageQuotaServer(grpcServer, s.quotaServer)
+		}
+		if s.volumeTemplateServer != nil {
+			api.RegisterOpenStorageVolumeTemplateServer(grpcServer, s.volumeTemplateServer)
+		}
 		if s.jobServer != nil {
 			api.RegisterOpenStorageJobServer(grpcServer, s.jobServer)
 		}
@@ -615,6 +692,16 @@ func (s *sdkGrpcServer) registerPrometheusMetrics(grpcServer *grpc.Server) {
 	// Initialize the metrics
 	grpcMetrics := grpc_prometheus.NewServerMetrics()
 	grpcMetrics.InitializeMetrics(grpcServer)
+
+	// Register the rate limit metrics. They are shared by the network
+	// and unix domain socket servers, so only the first one registers them.
+	for _, c := range []prometheus.Collector{rateLimitThrottled, rateLimitInFlight} {
+		if err := prometheus.Register(c); err != nil {
+			if _, ok := err.(prometheus.AlreadyRegisteredError); !ok {
+				s.log.Warnf("Unable to register rate limit metrics: %v", err)
+			}
+		}
+	}
 }
 
 func (s *sdkGrpcServer) registerServerExtensions(grpcServer *grpc.Server) {
@@ -695,3 +782,19 @@ func (s *sdkGrpcServer) auditLogWriter() io.Writer {
 func (s *sdkGrpcServer) port() string {
 	return s.config.port
 }
+
+func (s *sdkGrpcServer) quota() quota.Manager {
+	return s.quotaServer
+}
+
+func (s *sdkGrpcServer) volumeTrash() *VolumeTrashConfig {
+	return s.config.VolumeTrash
+}
+
+func (s *sdkGrpcServer) volumeTemplate() api.OpenStorageVolumeTemplateServer {
+	return s.volumeTemplateServer
+}
+
+func (s *sdkGrpcServer) attachmentsCordon() nodedrain.AttachmentsCordon {
+	return s.config.AttachmentsCordon
+}
@@ -18,20 +18,33 @@ package sdk
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/audit"
 	"github.com/libopenstorage/openstorage/pkg/auth"
 	"github.com/libopenstorage/openstorage/pkg/correlation"
 	"github.com/libopenstorage/openstorage/pkg/grpcserver"
+	"github.com/libopenstorage/openstorage/pkg/role"
 
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
 	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/auth"
 	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
@@ -46,8 +59,54 @@ const (
 	// to indicate the request needs to be terminated at the receiving node
 	// and should not be forwarded to another node.
 	ContextRoundRobinTerminateKey = "round-robin-terminate"
+
+	// ContextMetadataIdempotencyKey is the metadata key of the idempotency key
+	// of a mutating request. Requests retried with the same key are run only
+	// once and the response of the first one is returned to the retries.
+	// The REST Gateway passes the Idempotency-Key HTTP header in this key.
+	ContextMetadataIdempotencyKey = "idempotency-key"
+
+	// idempotencyKvdbPrefix is the kvdb prefix of the idempotency records
+	idempotencyKvdbPrefix = "sdk/idempotency/"
+	// idempotencyKeyMaxLength is the maximum length of an idempotency key
+	idempotencyKeyMaxLength = 256
 )
 
+var (
+	// idempotencyKeyTTL is how long the response to a request with an
+	// idempotency key is kept
+	idempotencyKeyTTL = 24 * time.Hour
+	// idempotencyPendingTimeout is how long a request with an idempotency key
+	// can run before it is considered lost, for example because the server
+	// restarted, and a retry with the same key is run again.
+	idempotencyPendingTimeout = 10 * time.Minute
+
+	// idempotentMethods are the methods which support idempotency keys
+	idempotentMethods = map[string]bool{
+		"/openstorage.api.OpenStorageVolume/Create":         true,
+		"/openstorage.api.OpenStorageVolume/Clone":          true,
+		"/openstorage.api.OpenStorageVolume/SnapshotCreate": true,
+		"/openstorage.api.OpenStorageCloudBackup/Create":    true,
+	}
+)
+
+// idempotencyRecord is saved in kvdb for each idempotency key
+type idempotencyRecord struct {
+	// Method of the request
+	Method string `json:"method"`
+	// RequestHash is the hash of the request, used to reject a key reused
+	// with a different request
+	RequestHash string `json:"request_hash"`
+	// Started is when the first request with the key started
+	Started time.Time `json:"started"`
+	// Done is set once the request has completed successfully
+	Done bool `json:"done"`
+	// ResponseType is the full name of the protobuf message of the response
+	ResponseType string `json:"response_type,omitempty"`
+	// Response is the marshalled response
+	Response []byte `json:"response,omitempty"`
+}
+
 // This interceptor provides a way to lock out any unary calls while we adjust the server
 func (s *sdkGrpcServer) rwlockUnaryIntercepter(
 	ctx context.Context,
@@ -128,6 +187,50 @@ func (s *sdkGrpcServer) auth(ctx context.Context) (context.Context, error) {
 	}
 }
 
+// requestInterceptors are the unary interceptors which check and record each
+// authenticated request. They also run for the requests made by a handler on
+// behalf of its caller with invoke. The audit log comes first so that the
+// requests which are denied are recorded too.
+func (s *sdkGrpcServer) requestInterceptors() []grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{s.auditServerUnaryInterceptor}
+	if s.config.Security != nil && len(s.config.Security.Authenticators) != 0 {
+		interceptors = append(interceptors, s.authorizationServerUnaryInterceptor)
+	}
+	return append(interceptors,
+		s.rateLimitServerUnaryInterceptor,
+		s.idempotencyServerUnaryInterceptor,
+		s.loggerServerUnaryInterceptor,
+	)
+}
+
+// invokedContextKey marks the context of the requests made with invoke
+type invokedContextKey struct{}
+
+// invoke runs handler as a request to fullMethod made on behalf of the caller
+// of ctx, such as the request on one volume of a bulk request. The request
+// is authorized, audited, rate limited and made idempotent as if the caller
+// had sent it.
+func (s *sdkGrpcServer) invoke(
+	ctx context.Context,
+	fullMethod string,
+	req interface{},
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	info := &grpc.UnaryServerInfo{
+		Server:     s.volumeServer,
+		FullMethod: fullMethod,
+	}
+	ctx = context.WithValue(ctx, invokedContextKey{}, true)
+	return grpc_middleware.ChainUnaryServer(s.requestInterceptors()...)(ctx, req, info, handler)
+}
+
+// isInvoked returns true if ctx is the context of a request made by a handler
+// with invoke
+func isInvoked(ctx context.Context) bool {
+	invoked, _ := ctx.Value(invokedContextKey{}).(bool)
+	return invoked
+}
+
 func (s *sdkGrpcServer) loggerInterceptor(ctx context.Context, handler func() error, fullMethod string) error {
 	reqid := uuid.New()
 	log := correlation.NewFunctionLogger(ctx)
@@ -273,3 +376,264 @@ func (s *sdkGrpcServer) authorizationServerStreamInterceptor(
 		return handler(srv, stream)
 	}, info.FullMethod)
 }
+
+// idempotencyKey returns the key where the idempotency record of a key is
+// saved. Keys are scoped to the user and method so that a user can never get
+// the response to the request of another user.
+func idempotencyKey(ctx context.Context, method, key string) string {
+	var username string
+	if userinfo, ok := auth.NewUserInfoFromContext(ctx); ok {
+		username = userinfo.Username
+	}
+	sum := sha256.Sum256([]byte(username + "\x00" + method + "\x00" + key))
+	return idempotencyKvdbPrefix + hex.EncodeToString(sum[:])
+}
+
+// idempotencyRequestHash returns the hash of a request
+func idempotencyRequestHash(req proto.Message) (string, error) {
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// replay returns the response saved in the record
+func (r *idempotencyRecord) replay() (interface{}, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(r.ResponseType))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Unable to replay response: %v", err)
+	}
+	resp := mt.New().Interface()
+	if err := proto.Unmarshal(r.Response, resp); err != nil {
+		return nil, status.Errorf(codes.Internal, "Unable to replay response: %v", err)
+	}
+	return resp, nil
+}
+
+// This interceptor runs mutating requests with the same idempotency key only
+// once. The first request with a key saves its response in kvdb, and retries
+// with the same key and request get the saved response. A key reused with a
+// different request is rejected. Failed requests are not saved so that they
+// can be retried.
+func (s *sdkGrpcServer) idempotencyServerUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	if !idempotentMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(ContextMetadataIdempotencyKey)
+	if len(keys) == 0 || len(keys[0]) == 0 {
+		return handler(ctx, req)
+	}
+	key := keys[0]
+	if len(key) > idempotencyKeyMaxLength {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"Idempotency key must not be longer than %d characters",
+			idempotencyKeyMaxLength)
+	}
+
+	kv := s.config.Kvdb
+	msg, ok := req.(proto.Message)
+	if kv == nil || !ok {
+		return nil, status.Error(codes.Unimplemented, "Idempotency keys are not supported by this server")
+	}
+	hash, err := idempotencyRequestHash(msg)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Unable to hash request: %v", err)
+	}
+
+	path := idempotencyKey(ctx, info.FullMethod, key)
+	record := &idempotencyRecord{
+		Method:      info.FullMethod,
+		RequestHash: hash,
+		Started:     time.Now(),
+	}
+	ttl := uint64(idempotencyKeyTTL.Seconds())
+	kvp, err := kv.Create(path, record, ttl)
+	if err == kvdb.ErrExist {
+		prev := &idempotencyRecord{}
+		prevKvp, err := kv.GetVal(path, prev)
+		if err != nil {
+			return nil, status.Errorf(
+				codes.Aborted,
+				"Unable to get the request with idempotency key %s, try again: %v",
+				key, err)
+		}
+		if prev.RequestHash != hash {
+			return nil, status.Errorf(
+				codes.InvalidArgument,
+				"Idempotency key %s was already used with a different request",
+				key)
+		}
+		if prev.Done {
+			return prev.replay()
+		}
+		if time.Since(prev.Started) < idempotencyPendingTimeout {
+			return nil, status.Errorf(
+				codes.Aborted,
+				"Request with idempotency key %s is still running",
+				key)
+		}
+
+		// The first request was lost, run it again. The record is taken
+		// over only if it was not changed since it was read, so that only
+		// one of the retries arriving together runs the request.
+		value, err := json.Marshal(record)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Unable to save idempotency key %s: %v", key, err)
+		}
+		prevKvp.Value = value
+		prevKvp.TTL = int64(ttl)
+		kvp, err = kv.CompareAndSet(prevKvp, kvdb.KVModifiedIndex, nil)
+		if err == kvdb.ErrModified || err == kvdb.ErrValueMismatch || err == kvdb.ErrNotFound {
+			return nil, status.Errorf(
+				codes.Aborted,
+				"Request with idempotency key %s is still running",
+				key)
+		} else if err != nil {
+			return nil, status.Errorf(codes.Internal, "Unable to reset idempotency key %s: %v", key, err)
+		}
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Unable to save idempotency key %s: %v", key, err)
+	}
+
+	// The record is only deleted or completed while it is the one saved by
+	// this request, and not taken over by a retry
+	resp, err := handler(ctx, req)
+	if err != nil {
+		_, derr := kv.CompareAndDelete(kvp, kvdb.KVModifiedIndex)
+		if derr != nil && derr != kvdb.ErrNotFound {
+			logrus.Warnf("Unable to delete idempotency key %s of failed request: %v", key, derr)
+		}
+		return resp, err
+	}
+
+	if respMsg, ok := resp.(proto.Message); ok {
+		b, merr := proto.Marshal(respMsg)
+		if merr == nil {
+			record.Done = true
+			record.ResponseType = string(respMsg.ProtoReflect().Descriptor().FullName())
+			record.Response = b
+			kvp.Value, merr = json.Marshal(record)
+		}
+		if merr == nil {
+			kvp.TTL = int64(ttl)
+			_, merr = kv.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil)
+		}
+		if merr != nil {
+			logrus.Warnf("Unable to save response of request with idempotency key %s: %v", key, merr)
+		}
+	}
+
+	return resp, nil
+}
+
+// isReadOnlyMethod returns true if the method is allowed by the default
+// read-only role, in which case it is not recorded in the audit log
+func isReadOnlyMethod(fullMethod string) bool {
+	return role.VerifyRules(
+		role.DefaultRoles[role.SystemViewRoleName].Rules,
+		api.SdkRootPath,
+		fullMethod) == nil
+}
+
+// auditRequest records in the audit log who made the mutating request to
+// fullMethod, on which resources, the request with its secrets redacted and
+// its result. handler serves the request and returns its message, which is
+// nil for the streams which received none, its response and its error.
+func (s *sdkGrpcServer) auditRequest(
+	ctx context.Context,
+	fullMethod string,
+	handler func() (interface{}, interface{}, error),
+) error {
+	if s.config.AuditSink == nil || isReadOnlyMethod(fullMethod) {
+		_, _, err := handler()
+		return err
+	}
+
+	entry := &api.SdkAuditEntry{
+		Time:          timestamppb.Now(),
+		Method:        fullMethod,
+		CorrelationId: correlation.RequestContextFromContextValue(ctx).ID,
+	}
+	if userinfo, ok := auth.NewUserInfoFromContext(ctx); ok {
+		entry.Username = userinfo.Username
+		entry.Roles = userinfo.Claims.Roles
+	}
+
+	req, resp, err := handler()
+
+	st, _ := status.FromError(err)
+	entry.Code = int32(st.Code())
+	entry.Message = st.Message()
+	msgs := make([]proto.Message, 0, 2)
+	if msg, ok := req.(proto.Message); ok {
+		msgs = append(msgs, msg)
+		if b, merr := protojson.Marshal(audit.Redact(msg)); merr == nil {
+			entry.Request = string(b)
+		}
+	}
+	if msg, ok := resp.(proto.Message); ok && err == nil {
+		msgs = append(msgs, msg)
+	}
+	entry.ResourceIds = audit.ResourceIds(msgs...)
+
+	if werr := s.config.AuditSink.Write(entry); werr != nil {
+		logrus.Errorf("Unable to write %s request to the audit log: %v", fullMethod, werr)
+	}
+
+	return err
+}
+
+// This interceptor records the mutating requests in the audit log
+func (s *sdkGrpcServer) auditServerUnaryInterceptor(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (interface{}, error) {
+	var resp interface{}
+	err := s.auditRequest(ctx, info.FullMethod, func() (interface{}, interface{}, error) {
+		var err error
+		resp, err = handler(ctx, req)
+		return req, resp, err
+	})
+	return resp, err
+}
+
+// auditServerStream keeps the first message received on a stream, which is
+// the request of the server streaming methods
+type auditServerStream struct {
+	grpc.ServerStream
+	req interface{}
+}
+
+func (a *auditServerStream) RecvMsg(m interface{}) error {
+	err := a.ServerStream.RecvMsg(m)
+	if err == nil && a.req == nil {
+		a.req = m
+	}
+	return err
+}
+
+// This interceptor records the mutating streaming requests in the audit log
+// when their stream ends
+func (s *sdkGrpcServer) auditServerStreamInterceptor(
+	srv interface{},
+	stream grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	auditStream := &auditServerStream{ServerStream: stream}
+	return s.auditRequest(stream.Context(), info.FullMethod, func() (interface{}, interface{}, error) {
+		err := handler(srv, auditStream)
+		return auditStream.req, nil, err
+	})
+}
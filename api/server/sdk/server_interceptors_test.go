@@ -21,10 +21,16 @@ import (
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestAuthorizationServerInterceptorCreateVolume(t *testing.T) {
@@ -245,3 +251,195 @@ func TestAuthorizationServerInterceptorStreamingCreateAlert(t *testing.T) {
 		}
 	}
 }
+
+func TestIdempotencyServerInterceptorReplay(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	name := "myvol"
+	id := "myid"
+	s.MockDriver().
+		EXPECT().
+		Inspect(gomock.Any(), []string{name}).
+		Return(nil, fmt.Errorf("not found")).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{Name: name}, nil).
+		Return(nil, fmt.Errorf("not found")).
+		Times(1)
+	// Marshalling the request to hash it leaves internal state in the
+	// messages, so they cannot be compared with the expected ones
+	s.MockDriver().
+		EXPECT().
+		Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(id, nil).
+		Times(1)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ContextMetadataIdempotencyKey, "key1")
+	req := &api.SdkVolumeCreateRequest{
+		Name: name,
+		Spec: &api.VolumeSpec{Size: 1234},
+	}
+
+	// The retry gets the response of the first request without calling the driver
+	for i := 0; i < 2; i++ {
+		r, err := c.Create(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, id, r.GetVolumeId())
+	}
+
+	// The key cannot be reused for a different request
+	_, err := c.Create(ctx, &api.SdkVolumeCreateRequest{
+		Name: "othervol",
+		Spec: &api.VolumeSpec{Size: 1234},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestIdempotencyServerInterceptorFailedRequest(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	name := "myvol"
+	s.MockDriver().
+		EXPECT().
+		Inspect(gomock.Any(), []string{name}).
+		Return(nil, fmt.Errorf("not found")).
+		Times(2)
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{Name: name}, nil).
+		Return(nil, fmt.Errorf("not found")).
+		Times(2)
+	gomock.InOrder(
+		s.MockDriver().
+			EXPECT().
+			Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return("", fmt.Errorf("no space")).
+			Times(1),
+		s.MockDriver().
+			EXPECT().
+			Create(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+			Return("myid", nil).
+			Times(1),
+	)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ContextMetadataIdempotencyKey, "key1")
+	req := &api.SdkVolumeCreateRequest{
+		Name: name,
+		Spec: &api.VolumeSpec{Size: 1234},
+	}
+
+	// Failed requests are not saved, so the retry runs again
+	_, err := c.Create(ctx, req)
+	assert.Error(t, err)
+	r, err := c.Create(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "myid", r.GetVolumeId())
+}
+
+func TestIdempotencyServerInterceptorInProgress(t *testing.T) {
+	// Create server, which sets up kvdb
+	s := newTestServer(t)
+	defer s.Stop()
+
+	info := &grpc.UnaryServerInfo{
+		FullMethod: "/openstorage.api.OpenStorageVolume/Create",
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ContextMetadataIdempotencyKey, "key1"))
+	req := &api.SdkVolumeCreateRequest{Name: "myvol"}
+
+	// A retry while the first request is still running is aborted
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		_, err := s.server.netServer.idempotencyServerUnaryInterceptor(ctx, req, info,
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				close(started)
+				<-release
+				return &api.SdkVolumeCreateResponse{VolumeId: "myid"}, nil
+			})
+		done <- err
+	}()
+	<-started
+	_, err := s.server.netServer.idempotencyServerUnaryInterceptor(ctx, req, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Fatal("handler must not be called for a request in progress")
+			return nil, nil
+		})
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	close(release)
+	require.NoError(t, <-done)
+
+	// Methods without idempotency support always call the handler
+	calls := 0
+	for i := 0; i < 2; i++ {
+		_, err = s.server.netServer.idempotencyServerUnaryInterceptor(ctx, &api.SdkVolumeDeleteRequest{VolumeId: "myid"},
+			&grpc.UnaryServerInfo{FullMethod: "/openstorage.api.OpenStorageVolume/Delete"},
+			func(ctx context.Context, req interface{}) (interface{}, error) {
+				calls++
+				return &api.SdkVolumeDeleteResponse{}, nil
+			})
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestIdempotencyServerInterceptorLostRequest(t *testing.T) {
+	// Create server, which sets up kvdb
+	s := newTestServer(t)
+	defer s.Stop()
+
+	info := &grpc.UnaryServerInfo{
+		FullMethod: "/openstorage.api.OpenStorageVolume/Create",
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(ContextMetadataIdempotencyKey, "key1"))
+	req := &api.SdkVolumeCreateRequest{Name: "myvol"}
+	hash, err := idempotencyRequestHash(req)
+	require.NoError(t, err)
+
+	// Save the record of a request which was lost before it completed
+	kv := s.server.netServer.config.Kvdb
+	path := idempotencyKey(ctx, info.FullMethod, "key1")
+	_, err = kv.Put(path, &idempotencyRecord{
+		Method:      info.FullMethod,
+		RequestHash: hash,
+		Started:     time.Now().Add(-2 * idempotencyPendingTimeout),
+	}, 0)
+	require.NoError(t, err)
+
+	// The retry takes over the lost request, and other retries are aborted
+	// while it runs
+	calls := 0
+	resp, err := s.server.netServer.idempotencyServerUnaryInterceptor(ctx, req, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			calls++
+			_, err := s.server.netServer.idempotencyServerUnaryInterceptor(ctx, req, info,
+				func(ctx context.Context, req interface{}) (interface{}, error) {
+					calls++
+					return &api.SdkVolumeCreateResponse{VolumeId: "other"}, nil
+				})
+			assert.Equal(t, codes.Aborted, status.Code(err))
+			return &api.SdkVolumeCreateResponse{VolumeId: "myid"}, nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "myid", resp.(*api.SdkVolumeCreateResponse).GetVolumeId())
+	assert.Equal(t, 1, calls)
+
+	// The response of the retry is replayed
+	resp, err = s.server.netServer.idempotencyServerUnaryInterceptor(ctx, req, info,
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Fatal("handler must not be called for a completed request")
+			return nil, nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, "myid", resp.(*api.SdkVolumeCreateResponse).GetVolumeId())
+}
@@ -25,6 +25,7 @@ import (
 
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/parser"
 	"github.com/libopenstorage/openstorage/pkg/sched"
 	"github.com/libopenstorage/openstorage/volume"
 
@@ -304,3 +305,12 @@ func enumerateVolumeIdsAsMap(d volume.VolumeDriver, locator *api.VolumeLocator)
 	return volMap, nil
 
 }
+
+// parseLabelSelector parses the label selector of an enumerate request
+func parseLabelSelector(selector string) ([]*api.LabelSelectorRequirement, error) {
+	reqs, err := parser.ParseLabelSelector(selector)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return reqs, nil
+}
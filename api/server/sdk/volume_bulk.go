@@ -0,0 +1,254 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/parser"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// defaultBulkConcurrency is the number of volumes a bulk request
+	// operates on at the same time if max_concurrency is not set
+	defaultBulkConcurrency = 10
+	// maxBulkConcurrency is the upper limit of max_concurrency
+	maxBulkConcurrency = 100
+
+	// Methods of the requests made for each volume of a bulk request
+	bulkDeleteMethod   = "/openstorage.api.OpenStorageVolume/Delete"
+	bulkUpdateMethod   = "/openstorage.api.OpenStorageVolume/Update"
+	bulkSnapshotMethod = "/openstorage.api.OpenStorageVolume/SnapshotCreate"
+	bulkDetachMethod   = "/openstorage.api.OpenStorageMountAttach/Detach"
+)
+
+// bulkOperation runs the operation of a bulk request on one volume and
+// returns the id of the snapshot it created, if any
+type bulkOperation func(ctx context.Context, volumeId string) (string, error)
+
+// Bulk runs the same operation on many volumes. Each volume goes through the
+// same interceptors and handler as the single volume request, so every volume
+// is authorized, audited, rate limited and checked for ownership on its own.
+func (s *VolumeServer) Bulk(
+	ctx context.Context,
+	req *api.SdkVolumeBulkRequest,
+) (*api.SdkVolumeBulkResponse, error) {
+	if s.cluster() == nil || s.driver(ctx) == nil {
+		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+
+	if len(req.GetVolumeIds()) == 0 && len(req.GetLabels()) == 0 && len(req.GetLabelSelector()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply volume ids, labels or a label selector")
+	}
+
+	op, err := s.bulkOperation(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ids, err := s.bulkVolumeIds(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := int(req.GetMaxConcurrency())
+	if concurrency == 0 {
+		concurrency = defaultBulkConcurrency
+	} else if concurrency > maxBulkConcurrency {
+		concurrency = maxBulkConcurrency
+	}
+
+	results := make([]*api.SdkVolumeBulkResult, len(ids))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, id string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			result := &api.SdkVolumeBulkResult{VolumeId: id}
+			results[i] = result
+			if ctx.Err() != nil {
+				st := status.FromContextError(ctx.Err())
+				result.Code = int32(st.Code())
+				result.Message = st.Message()
+				return
+			}
+
+			snapshotId, err := op(bulkItemContext(ctx, id), id)
+			if err != nil {
+				st, _ := status.FromError(err)
+				result.Code = int32(st.Code())
+				result.Message = st.Message()
+				return
+			}
+			result.SnapshotId = snapshotId
+		}(i, id)
+	}
+	wg.Wait()
+
+	return &api.SdkVolumeBulkResponse{
+		Results: results,
+	}, nil
+}
+
+// bulkItemContext returns the context of the request on one volume of a bulk
+// request. The idempotency key of the bulk request is made unique to the
+// volume, so that a retried bulk request gets the saved result of each volume
+// instead of a conflict between the volumes.
+func bulkItemContext(ctx context.Context, volumeId string) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	keys := md.Get(ContextMetadataIdempotencyKey)
+	if len(keys) == 0 || len(keys[0]) == 0 {
+		return ctx
+	}
+	sum := sha256.Sum256([]byte(keys[0] + "\x00" + volumeId))
+	md = md.Copy()
+	md.Set(ContextMetadataIdempotencyKey, hex.EncodeToString(sum[:]))
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+// bulkOperation validates the arguments of the operation of a bulk request
+// and returns the function running it on a volume
+func (s *VolumeServer) bulkOperation(req *api.SdkVolumeBulkRequest) (bulkOperation, error) {
+	switch req.GetOperation() {
+	case api.SdkVolumeBulkRequest_DELETE:
+		return func(ctx context.Context, volumeId string) (string, error) {
+			_, err := s.server.invoke(ctx, bulkDeleteMethod, &api.SdkVolumeDeleteRequest{
+				VolumeId: volumeId,
+			}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.Delete(ctx, req.(*api.SdkVolumeDeleteRequest))
+			})
+			return "", err
+		}, nil
+
+	case api.SdkVolumeBulkRequest_UPDATE:
+		if len(req.GetUpdateLabels()) == 0 && req.GetSpec() == nil {
+			return nil, status.Error(codes.InvalidArgument, "Must supply labels or spec to update")
+		}
+		return func(ctx context.Context, volumeId string) (string, error) {
+			_, err := s.server.invoke(ctx, bulkUpdateMethod, &api.SdkVolumeUpdateRequest{
+				VolumeId: volumeId,
+				Labels:   req.GetUpdateLabels(),
+				Spec:     req.GetSpec(),
+			}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.Update(ctx, req.(*api.SdkVolumeUpdateRequest))
+			})
+			return "", err
+		}, nil
+
+	case api.SdkVolumeBulkRequest_SNAPSHOT:
+		if len(req.GetSnapshotName()) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "Must supply a snapshot name")
+		}
+		return func(ctx context.Context, volumeId string) (string, error) {
+			resp, err := s.server.invoke(ctx, bulkSnapshotMethod, &api.SdkVolumeSnapshotCreateRequest{
+				VolumeId: volumeId,
+				Name:     fmt.Sprintf("%s-%s", req.GetSnapshotName(), volumeId),
+				Labels:   req.GetSnapshotLabels(),
+			}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.SnapshotCreate(ctx, req.(*api.SdkVolumeSnapshotCreateRequest))
+			})
+			if err != nil {
+				return "", err
+			}
+			return resp.(*api.SdkVolumeSnapshotCreateResponse).GetSnapshotId(), nil
+		}, nil
+
+	case api.SdkVolumeBulkRequest_DETACH:
+		return func(ctx context.Context, volumeId string) (string, error) {
+			_, err := s.server.invoke(ctx, bulkDetachMethod, &api.SdkVolumeDetachRequest{
+				VolumeId: volumeId,
+				Options:  req.GetDetachOptions(),
+			}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return s.Detach(ctx, req.(*api.SdkVolumeDetachRequest))
+			})
+			return "", err
+		}, nil
+	}
+
+	return nil, status.Errorf(
+		codes.InvalidArgument,
+		"Unsupported bulk operation %v",
+		req.GetOperation())
+}
+
+// bulkVolumeIds returns the ids of the volumes of a bulk request without
+// duplicates: the volume ids of the request, followed by the volumes the
+// caller can read which match the labels and the label selector of the
+// request and are not in the trash
+func (s *VolumeServer) bulkVolumeIds(
+	ctx context.Context,
+	req *api.SdkVolumeBulkRequest,
+) ([]string, error) {
+	seen := make(map[string]bool)
+	ids := make([]string, 0, len(req.GetVolumeIds()))
+	for _, id := range req.GetVolumeIds() {
+		if len(id) == 0 {
+			return nil, status.Error(codes.InvalidArgument, "Volume ids must not be empty")
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if len(req.GetLabels()) == 0 && len(req.GetLabelSelector()) == 0 {
+		return ids, nil
+	}
+	selector, err := parseLabelSelector(req.GetLabelSelector())
+	if err != nil {
+		return nil, err
+	}
+
+	vols, err := s.driver(ctx).Enumerate(&api.VolumeLocator{
+		VolumeLabels:  req.GetLabels(),
+		LabelSelector: selector,
+	}, nil)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal,
+			"Failed to enumerate volumes: %v",
+			err.Error())
+	}
+	for _, vol := range vols {
+		// The label selector is checked again for the drivers which do not
+		// support it
+		if !seen[vol.GetId()] && vol.IsPermitted(ctx, api.Ownership_Read) && !isVolumeInTrash(vol) &&
+			parser.MatchLabelSelector(vol.GetLocator().GetVolumeLabels(), selector) {
+			seen[vol.GetId()] = true
+			ids = append(ids, vol.GetId())
+		}
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,496 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kubernetes-csi/csi-test/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	mockcluster "github.com/libopenstorage/openstorage/cluster/mock"
+	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/parser"
+	"github.com/libopenstorage/openstorage/volume"
+	mockdriver "github.com/libopenstorage/openstorage/volume/drivers/mock"
+)
+
+// inspectBulkVolumes returns a mock Enumerate which finds the volume
+// inspected by the handler of each volume in vols, or returns a public volume
+// if it is not there. Bulk requests inspect volumes concurrently, so the
+// expectations cannot be set for each volume in order.
+func inspectBulkVolumes(vols ...*api.Volume) func(*api.VolumeLocator, map[string]string) ([]*api.Volume, error) {
+	return func(locator *api.VolumeLocator, labels map[string]string) ([]*api.Volume, error) {
+		id := locator.GetVolumeIds()[0]
+		for _, vol := range vols {
+			if vol.GetId() == id {
+				return []*api.Volume{vol}, nil
+			}
+		}
+		return []*api.Volume{{Id: id}}, nil
+	}
+}
+
+func TestSdkVolumeBulkBadArguments(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	tests := []*api.SdkVolumeBulkRequest{
+		// No volumes
+		{
+			Operation: api.SdkVolumeBulkRequest_DELETE,
+		},
+		// No operation
+		{
+			VolumeIds: []string{"myvol"},
+		},
+		// Nothing to update
+		{
+			Operation: api.SdkVolumeBulkRequest_UPDATE,
+			VolumeIds: []string{"myvol"},
+		},
+		// No snapshot name
+		{
+			Operation: api.SdkVolumeBulkRequest_SNAPSHOT,
+			VolumeIds: []string{"myvol"},
+		},
+		// Empty volume id
+		{
+			Operation: api.SdkVolumeBulkRequest_DELETE,
+			VolumeIds: []string{"myvol", ""},
+		},
+	}
+	for _, req := range tests {
+		_, err := c.Bulk(context.Background(), req)
+		assert.Error(t, err)
+		serverError, ok := status.FromError(err)
+		assert.True(t, ok)
+		assert.Equal(t, codes.InvalidArgument, serverError.Code())
+	}
+}
+
+func TestSdkVolumeBulkDelete(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	labels := map[string]string{"app": "db"}
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeLabels: labels}, nil).
+		Return([]*api.Volume{{Id: "vol2"}, {Id: "vol3"}}, nil).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		DoAndReturn(inspectBulkVolumes()).
+		Times(3)
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "vol1").
+		Return(nil).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "vol2").
+		Return(nil).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "vol3").
+		Return(fmt.Errorf("busy")).
+		Times(1)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	r, err := c.Bulk(context.Background(), &api.SdkVolumeBulkRequest{
+		Operation:      api.SdkVolumeBulkRequest_DELETE,
+		VolumeIds:      []string{"vol1", "vol2", "vol1"},
+		Labels:         labels,
+		MaxConcurrency: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 3)
+	for i, id := range []string{"vol1", "vol2", "vol3"} {
+		assert.Equal(t, id, r.GetResults()[i].GetVolumeId())
+	}
+	assert.Equal(t, int32(codes.OK), r.GetResults()[0].GetCode())
+	assert.Equal(t, int32(codes.OK), r.GetResults()[1].GetCode())
+	assert.Equal(t, int32(codes.Internal), r.GetResults()[2].GetCode())
+	assert.Contains(t, r.GetResults()[2].GetMessage(), "busy")
+}
+
+func TestSdkVolumeBulkRateLimitInFlight(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	// The requests on each volume do not count against the requests in
+	// flight of the caller, as its bulk request is already in flight
+	limiter, err := newRateLimiter([]RateLimit{{Role: RateLimitAnyRole, MaxInFlight: 1}})
+	require.NoError(t, err)
+	s.server.netServer.rateLimiter = limiter
+
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		DoAndReturn(inspectBulkVolumes()).
+		Times(2)
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(2)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	r, err := c.Bulk(context.Background(), &api.SdkVolumeBulkRequest{
+		Operation:      api.SdkVolumeBulkRequest_DELETE,
+		VolumeIds:      []string{"vol1", "vol2"},
+		MaxConcurrency: 2,
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 2)
+	for _, result := range r.GetResults() {
+		assert.Equal(t, int32(codes.OK), result.GetCode(), result.GetMessage())
+	}
+}
+
+func TestSdkVolumeBulkSkipsTrash(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	labels := map[string]string{"app": "db"}
+	trashed := &api.Volume{
+		Id: "vol3",
+		Locator: &api.VolumeLocator{
+			VolumeLabels: map[string]string{
+				"app":                   "db",
+				volumeTrashDeletedLabel: "2026-10-18T00:00:00Z",
+			},
+		},
+	}
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeLabels: labels}, nil).
+		Return([]*api.Volume{{Id: "vol2"}, trashed}, nil).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		DoAndReturn(inspectBulkVolumes()).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "vol2").
+		Return(nil).
+		Times(1)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	// The volumes in the trash are not selected by their labels
+	r, err := c.Bulk(context.Background(), &api.SdkVolumeBulkRequest{
+		Operation: api.SdkVolumeBulkRequest_DELETE,
+		Labels:    labels,
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 1)
+	assert.Equal(t, "vol2", r.GetResults()[0].GetVolumeId())
+	assert.Equal(t, int32(codes.OK), r.GetResults()[0].GetCode())
+}
+
+func TestSdkVolumeBulkLabelSelector(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	selector, err := parser.ParseLabelSelector("env in (prod,qa)")
+	require.NoError(t, err)
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{LabelSelector: selector}, nil).
+		Return([]*api.Volume{
+			{
+				Id:      "vol1",
+				Locator: &api.VolumeLocator{VolumeLabels: map[string]string{"env": "prod"}},
+			},
+			{
+				Id:      "vol2",
+				Locator: &api.VolumeLocator{VolumeLabels: map[string]string{"env": "dev"}},
+			},
+		}, nil).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		DoAndReturn(inspectBulkVolumes()).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "vol1").
+		Return(nil).
+		Times(1)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	// The selector is checked again for the drivers which ignore it
+	r, err := c.Bulk(context.Background(), &api.SdkVolumeBulkRequest{
+		Operation:     api.SdkVolumeBulkRequest_DELETE,
+		LabelSelector: "env in (prod,qa)",
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 1)
+	assert.Equal(t, "vol1", r.GetResults()[0].GetVolumeId())
+	assert.Equal(t, int32(codes.OK), r.GetResults()[0].GetCode())
+
+	_, err = c.Bulk(context.Background(), &api.SdkVolumeBulkRequest{
+		Operation:     api.SdkVolumeBulkRequest_DELETE,
+		LabelSelector: "env in (prod",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestSdkVolumeBulkSnapshot(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	snapLabels := map[string]string{"backup": "nightly"}
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		DoAndReturn(inspectBulkVolumes()).
+		Times(2)
+	s.MockDriver().
+		EXPECT().
+		Snapshot(gomock.Any(), gomock.Any(), true, gomock.Any(), false).
+		DoAndReturn(func(
+			ctx context.Context,
+			volumeID string,
+			readonly bool,
+			locator *api.VolumeLocator,
+			noRetry bool,
+		) (string, error) {
+			assert.Equal(t, "nightly-"+volumeID, locator.GetName())
+			assert.Equal(t, snapLabels, locator.GetVolumeLabels())
+			return "snap-" + volumeID, nil
+		}).
+		Times(2)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	r, err := c.Bulk(context.Background(), &api.SdkVolumeBulkRequest{
+		Operation:      api.SdkVolumeBulkRequest_SNAPSHOT,
+		VolumeIds:      []string{"vol1", "vol2"},
+		SnapshotName:   "nightly",
+		SnapshotLabels: snapLabels,
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 2)
+	for i, id := range []string{"vol1", "vol2"} {
+		assert.Equal(t, id, r.GetResults()[i].GetVolumeId())
+		assert.Equal(t, int32(codes.OK), r.GetResults()[i].GetCode())
+		assert.Equal(t, "snap-"+id, r.GetResults()[i].GetSnapshotId())
+	}
+}
+
+func TestSdkVolumeBulkOwnership(t *testing.T) {
+	// This test does not use the gRPC server
+	mc := gomock.NewController(&utils.SafeGoroutineTester{})
+	mv := mockdriver.NewMockVolumeDriver(mc)
+	mcluster := mockcluster.NewMockCluster(mc)
+	s := VolumeServer{
+		server: &sdkGrpcServer{
+			volumeDriverHandlers: map[string]volume.VolumeDriver{
+				"mock":            mv,
+				DefaultDriverName: mv,
+			},
+			clusterHandler:  mcluster,
+			accessLogOutput: ioutil.Discard,
+		},
+	}
+
+	owned := &api.Volume{
+		Id: "owned",
+		Spec: &api.VolumeSpec{
+			Ownership: &api.Ownership{Owner: "me"},
+		},
+	}
+	readOnly := &api.Volume{
+		Id: "readonly",
+		Spec: &api.VolumeSpec{
+			Ownership: &api.Ownership{
+				Owner: "someoneelse",
+				Acls: &api.Ownership_AccessControl{
+					Collaborators: map[string]api.Ownership_AccessType{
+						"me": api.Ownership_Read,
+					},
+				},
+			},
+		},
+	}
+	private := &api.Volume{
+		Id: "private",
+		Spec: &api.VolumeSpec{
+			Ownership: &api.Ownership{Owner: "someoneelse"},
+		},
+	}
+	ctx := auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{
+		Username: "me",
+	})
+
+	// Volumes matching the labels which cannot be read are not selected
+	labels := map[string]string{"team": "a"}
+	mv.
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeLabels: labels}, nil).
+		Return([]*api.Volume{owned, readOnly, private}, nil).
+		Times(1)
+	mv.
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		DoAndReturn(inspectBulkVolumes(owned, readOnly)).
+		Times(2)
+	mv.
+		EXPECT().
+		Delete(gomock.Any(), owned.GetId()).
+		Return(nil).
+		Times(1)
+
+	r, err := s.Bulk(ctx, &api.SdkVolumeBulkRequest{
+		Operation: api.SdkVolumeBulkRequest_DELETE,
+		Labels:    labels,
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 2)
+	assert.Equal(t, owned.GetId(), r.GetResults()[0].GetVolumeId())
+	assert.Equal(t, int32(codes.OK), r.GetResults()[0].GetCode())
+	assert.Equal(t, readOnly.GetId(), r.GetResults()[1].GetVolumeId())
+	assert.Equal(t, int32(codes.PermissionDenied), r.GetResults()[1].GetCode())
+
+	// Volumes requested by id are checked one by one
+	mv.
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{private.GetId()}}, nil).
+		Return([]*api.Volume{private}, nil).
+		Times(1)
+	r, err = s.Bulk(ctx, &api.SdkVolumeBulkRequest{
+		Operation: api.SdkVolumeBulkRequest_DELETE,
+		VolumeIds: []string{private.GetId()},
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 1)
+	assert.Equal(t, int32(codes.PermissionDenied), r.GetResults()[0].GetCode())
+}
+
+func TestSdkVolumeBulkAuthorization(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServerAuth(t)
+	defer s.Stop()
+
+	// A role allowed to make bulk requests and to delete volumes, but not
+	// to update them
+	adminCtx, err := contextWithToken(context.Background(), "admin", "system.admin", testSharedSecret)
+	require.NoError(t, err)
+	rc := api.NewOpenStorageRoleClient(s.Conn())
+	_, err = rc.Create(adminCtx, &api.SdkRoleCreateRequest{
+		Role: &api.SdkRole{
+			Name: "test.bulk",
+			Rules: []*api.SdkRule{
+				{
+					Services: []string{"volume"},
+					Apis:     []string{"bulk", "delete"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	ctx, err := contextWithToken(context.Background(), "bulkuser", "test.bulk", testSharedSecret)
+	require.NoError(t, err)
+
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		DoAndReturn(inspectBulkVolumes()).
+		Times(1)
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "vol1").
+		Return(nil).
+		Times(1)
+
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	r, err := c.Bulk(ctx, &api.SdkVolumeBulkRequest{
+		Operation: api.SdkVolumeBulkRequest_DELETE,
+		VolumeIds: []string{"vol1"},
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 1)
+	assert.Equal(t, int32(codes.OK), r.GetResults()[0].GetCode())
+
+	// Each volume is authorized as the request on that volume alone
+	r, err = c.Bulk(ctx, &api.SdkVolumeBulkRequest{
+		Operation:    api.SdkVolumeBulkRequest_UPDATE,
+		VolumeIds:    []string{"vol1"},
+		UpdateLabels: map[string]string{"app": "db"},
+	})
+	require.NoError(t, err)
+	require.Len(t, r.GetResults(), 1)
+	assert.Equal(t, int32(codes.PermissionDenied), r.GetResults()[0].GetCode())
+}
+
+func TestBulkItemContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, ctx, bulkItemContext(ctx, "vol1"))
+
+	// Each volume gets its own idempotency key
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(ContextMetadataIdempotencyKey, "key"))
+	key := func(ctx context.Context) string {
+		md, _ := metadata.FromIncomingContext(ctx)
+		return md.Get(ContextMetadataIdempotencyKey)[0]
+	}
+	assert.Equal(t, key(bulkItemContext(ctx, "vol1")), key(bulkItemContext(ctx, "vol1")))
+	assert.NotEqual(t, key(bulkItemContext(ctx, "vol1")), key(bulkItemContext(ctx, "vol2")))
+	assert.NotEqual(t, "key", key(bulkItemContext(ctx, "vol1")))
+	assert.Equal(t, "key", key(ctx))
+}
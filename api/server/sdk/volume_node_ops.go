@@ -45,6 +45,11 @@ func (s *VolumeServer) Attach(
 		return nil, err
 	}
 
+	// Volumes cannot be attached while the node is drained
+	if err := s.checkAttachmentsCordon(); err != nil {
+		return nil, err
+	}
+
 	// Check options
 	options := req.GetDriverOptions()
 	if options == nil {
@@ -79,6 +84,27 @@ func (s *VolumeServer) Attach(
 	return &api.SdkVolumeAttachResponse{DevicePath: devPath}, nil
 }
 
+// checkAttachmentsCordon returns an error if volume attachments are cordoned
+// on this node
+func (s *VolumeServer) checkAttachmentsCordon() error {
+	cordon := s.server.attachmentsCordon()
+	if cordon == nil {
+		return nil
+	}
+
+	c, err := s.cluster().Enumerate()
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to determine node id: %v", err)
+	}
+	cordoned, err := cordon.AttachmentsCordoned(c.NodeId)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Failed to check if attachments are cordoned on node %s: %v", c.NodeId, err)
+	} else if cordoned {
+		return status.Errorf(codes.FailedPrecondition, "Volume attachments are cordoned on node %s", c.NodeId)
+	}
+	return nil
+}
+
 // Detach function for volume node detach
 func (s *VolumeServer) Detach(
 	ctx context.Context,
@@ -133,6 +133,47 @@ func TestSdkVolumeAttachFailed(t *testing.T) {
 	assert.Contains(t, serverError.Message(), "Failed to Attach device")
 }
 
+type fakeAttachmentsCordon map[string]bool
+
+func (f fakeAttachmentsCordon) AttachmentsCordoned(nodeID string) (bool, error) {
+	return f[nodeID], nil
+}
+
+func TestSdkVolumeAttachCordoned(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	s.server.netServer.config.AttachmentsCordon = fakeAttachmentsCordon{"node1": true}
+
+	id := "myid"
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{
+			VolumeIds: []string{id},
+		}, nil).
+		Return([]*api.Volume{
+			&api.Volume{
+				Id: id,
+			},
+		}, nil)
+	s.MockCluster().
+		EXPECT().
+		Enumerate().
+		Return(api.Cluster{NodeId: "node1"}, nil)
+
+	// Setup client
+	c := api.NewOpenStorageMountAttachClient(s.Conn())
+
+	// The volume is not attached
+	_, err := c.Attach(context.Background(), &api.SdkVolumeAttachRequest{VolumeId: id})
+	assert.Error(t, err)
+
+	serverError, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, serverError.Code(), codes.FailedPrecondition)
+}
+
 func TestSdkVolumeAttachBadArgument(t *testing.T) {
 
 	// Create server and client connection
@@ -28,12 +28,15 @@ import (
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/pkg/auth"
 	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/pkg/parser"
+	"github.com/libopenstorage/openstorage/pkg/quota"
 	policy "github.com/libopenstorage/openstorage/pkg/storagepolicy"
 	"github.com/libopenstorage/openstorage/pkg/util"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/portworx/kvdb"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 // When create is called for an existing volume, this function is called to make sure
@@ -169,12 +172,24 @@ func (s *VolumeServer) create(
 			return "", status.Errorf(codes.PermissionDenied, "Access denied to volume %s", parent.GetId())
 		}
 
+		// The clone is charged once to its creator. The update of its
+		// ownership below does not charge it again.
+		cloneOwnership, _ := parent.GetSpec().GetCloneCreatorOwnership(ctx)
+		revertQuota, err := s.chargeQuota(nil, nil, cloneOwnership, &api.SdkQuotaUsage{
+			Volumes:          1,
+			ProvisionedBytes: parent.GetSpec().GetSize(),
+		})
+		if err != nil {
+			return "", err
+		}
+
 		// Create a snapshot from the parent
 		id, err = s.driver(ctx).Snapshot(ctx, parent.GetId(), false, &api.VolumeLocator{
 			Name:         volName,
 			VolumeLabels: locator.GetVolumeLabels(),
 		}, false)
 		if err != nil {
+			revertQuota()
 			if err == kvdb.ErrNotFound {
 				return "", status.Errorf(
 					codes.Aborted,
@@ -189,11 +204,21 @@ func (s *VolumeServer) create(
 				err.Error())
 		}
 
+		// Remove the clone and release its usage if it cannot be set up
+		driverCtx := ctx
+		deleteClone := func() {
+			revertQuota()
+			if err := s.driver(driverCtx).Delete(driverCtx, id); err != nil {
+				logrus.Warnf("Failed to delete clone %s: %v", id, err)
+			}
+		}
+
 		// If this is a different owner, make adjust the clone to this owner
 		clone, err := s.Inspect(ctx, &api.SdkVolumeInspectRequest{
 			VolumeId: id,
 		})
 		if err != nil {
+			deleteClone()
 			return "", err
 		}
 
@@ -234,17 +259,26 @@ func (s *VolumeServer) create(
 
 		// Only update if required.
 		if ownershipUpdateNeeded || additionalLabelsNeeded {
-			_, err = s.Update(ctx, updateReq)
+			_, err = s.update(ctx, updateReq, false)
 			if err != nil {
+				deleteClone()
 				return "", err
 			}
 		}
 	} else {
 		// New volume, set ownership
 		spec.Ownership = api.OwnershipSetUsernameFromContext(ctx, spec.Ownership)
+		revertQuota, err := s.chargeQuota(nil, nil, spec.GetOwnership(), &api.SdkQuotaUsage{
+			Volumes:          1,
+			ProvisionedBytes: spec.GetSize(),
+		})
+		if err != nil {
+			return "", err
+		}
 		// Create the volume
 		id, err = s.driver(ctx).Create(ctx, locator, source, spec)
 		if err != nil {
+			revertQuota()
 			if spec.IsPureVolume() &&
 				strings.Contains(err.Error(), "no storage backends were able to meet the request specification") {
 				return "", status.Errorf(
@@ -275,22 +309,39 @@ func (s *VolumeServer) Create(
 		return nil, status.Error(
 			codes.InvalidArgument,
 			"Must supply a unique name")
-	} else if req.GetSpec() == nil {
+	} else if req.GetSpec() == nil && req.GetTemplate() == nil {
 		return nil, status.Error(
 			codes.InvalidArgument,
 			"Must supply spec object")
+	} else if req.GetSpec() != nil && req.GetTemplate() != nil {
+		return nil, status.Error(
+			codes.InvalidArgument,
+			"Must supply either a spec or a volume template, not both")
+	} else if req.GetSpecOverrides() != nil && req.GetTemplate() == nil {
+		return nil, status.Error(
+			codes.InvalidArgument,
+			"Spec overrides can only be used with a volume template")
+	}
+
+	reqSpec, labels := req.GetSpec(), req.GetLabels()
+	if req.GetTemplate() != nil {
+		var err error
+		reqSpec, labels, err = s.templateVolumeSpec(ctx, req.GetTemplate(), req.GetSpecOverrides(), labels)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	locator := &api.VolumeLocator{
 		Name:         req.GetName(),
-		VolumeLabels: req.GetLabels(),
+		VolumeLabels: labels,
 	}
 	source := &api.Source{}
 
 	// Validate/Update given spec according to default storage policy set
 	// In case policy is not set, should fall back to default way
 	// of creating volume
-	spec, err := GetDefaultVolSpecs(ctx, req.GetSpec(), false)
+	spec, err := GetDefaultVolSpecs(ctx, reqSpec, false)
 	if err != nil {
 		return nil, err
 	}
@@ -394,18 +445,44 @@ func (s *VolumeServer) Delete(
 		return nil, status.Errorf(codes.PermissionDenied, "Cannot delete volume %v", vol.GetId())
 	}
 
+	// Keep the volume in the trash if it is enabled
+	if s.server.volumeTrash() != nil {
+		if err := s.trashVolume(ctx, vol); err != nil {
+			return nil, err
+		}
+		s.auditLog(ctx, "volume.delete", "Volume %s moved to the trash", req.GetVolumeId())
+		return &api.SdkVolumeDeleteResponse{}, nil
+	}
+
+	if err := s.deleteVolume(ctx, req.GetVolumeId(), vol); err != nil {
+		return nil, err
+	}
+
+	s.auditLog(ctx, "volume.delete", "Volume %s deleted", req.GetVolumeId())
+	return &api.SdkVolumeDeleteResponse{}, nil
+}
+
+// deleteVolume deletes the volume from the driver and releases its usage of
+// the quotas. The caller must check the access to the volume.
+func (s *VolumeServer) deleteVolume(ctx context.Context, volumeId string, vol *api.Volume) error {
+	// Release the usage of the volume first so it can be reused as soon as
+	// the volume is deleted
+	revertQuota, err := s.chargeQuota(vol.GetSpec().GetOwnership(), quota.VolumeUsage(vol), nil, nil)
+	if err != nil {
+		return err
+	}
+
 	// Delete the volume
-	err = s.driver(ctx).Delete(ctx, req.GetVolumeId())
+	err = s.driver(ctx).Delete(ctx, volumeId)
 	if err != nil {
-		return nil, status.Errorf(
+		revertQuota()
+		return status.Errorf(
 			codes.Internal,
 			"Failed to delete volume %s: %v",
-			req.GetVolumeId(),
+			volumeId,
 			err.Error())
 	}
-
-	s.auditLog(ctx, "volume.delete", "Volume %s deleted", req.GetVolumeId())
-	return &api.SdkVolumeDeleteResponse{}, nil
+	return nil
 }
 
 // InspectWithFilters is a helper function returning information about volumes which match a filter
@@ -440,7 +517,7 @@ func (s *VolumeServer) InspectWithFilters(
 	vols := make([]*api.SdkVolumeInspectResponse, 0, len(enumVols))
 	for _, vol := range enumVols {
 		// Check access
-		if vol.IsPermitted(ctx, api.Ownership_Read) {
+		if vol.IsPermitted(ctx, api.Ownership_Read) && !isVolumeInTrash(vol) {
 
 			// Check if the caller wants more information
 			if req.GetOptions().GetDeep() {
@@ -518,6 +595,14 @@ func (s *VolumeServer) Inspect(
 		v = vols[0]
 	}
 
+	// Deleted volumes are only visible in the trash
+	if isVolumeInTrash(v) {
+		return nil, status.Errorf(
+			codes.NotFound,
+			"Volume id %s not found",
+			req.GetVolumeId())
+	}
+
 	// Check ownership
 	if !v.IsPermitted(ctx, api.Ownership_Read) {
 		return nil, status.Errorf(codes.PermissionDenied, "Access denied to volume %s", v.GetId())
@@ -562,15 +647,22 @@ func (s *VolumeServer) EnumerateWithFilters(
 		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
 	}
 
+	selector, err := parseLabelSelector(req.GetLabelSelector())
+	if err != nil {
+		return nil, err
+	}
+
 	var locator *api.VolumeLocator
 	if len(req.GetName()) != 0 ||
 		len(req.GetLabels()) != 0 ||
-		req.GetOwnership() != nil {
+		req.GetOwnership() != nil ||
+		len(selector) != 0 {
 
 		locator = &api.VolumeLocator{
-			Name:         req.GetName(),
-			VolumeLabels: req.GetLabels(),
-			Ownership:    req.GetOwnership(),
+			Name:          req.GetName(),
+			VolumeLabels:  req.GetLabels(),
+			Ownership:     req.GetOwnership(),
+			LabelSelector: selector,
 		}
 	}
 
@@ -582,16 +674,32 @@ func (s *VolumeServer) EnumerateWithFilters(
 			err.Error())
 	}
 
-	ids := make([]string, 0)
+	permitted := make([]*api.Volume, 0, len(vols))
 	for _, vol := range vols {
-		// Check access
-		if vol.IsPermitted(ctx, api.Ownership_Read) {
-			ids = append(ids, vol.GetId())
+		// Check access. The label selector is checked again for the
+		// drivers which do not support it.
+		if vol.IsPermitted(ctx, api.Ownership_Read) &&
+			!isVolumeInTrash(vol) &&
+			parser.MatchLabelSelector(vol.GetLocator().GetVolumeLabels(), selector) {
+			permitted = append(permitted, vol)
 		}
 	}
 
+	page, next, err := paginate(req, len(permitted), func(i int, key api.SdkSortOrder_Key) (pageItem, bool) {
+		return volumePageItem(permitted[i], key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(page))
+	for _, i := range page {
+		ids = append(ids, permitted[i].GetId())
+	}
+
 	return &api.SdkVolumeEnumerateWithFiltersResponse{
-		VolumeIds: ids,
+		VolumeIds:     ids,
+		NextPageToken: next,
 	}, nil
 }
 
@@ -671,6 +779,17 @@ func maskUnModified(spec *api.VolumeSpec, req *api.VolumeSpecUpdate) {
 func (s *VolumeServer) Update(
 	ctx context.Context,
 	req *api.SdkVolumeUpdateRequest,
+) (*api.SdkVolumeUpdateResponse, error) {
+	return s.update(ctx, req, true)
+}
+
+// update changes the volume specification. If charge is not set, the
+// changes of size and ownership are not charged to the quotas, which is used
+// for a clone already charged to its creator.
+func (s *VolumeServer) update(
+	ctx context.Context,
+	req *api.SdkVolumeUpdateRequest,
+	charge bool,
 ) (*api.SdkVolumeUpdateResponse, error) {
 	if s.cluster() == nil || s.driver(ctx) == nil {
 		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
@@ -704,6 +823,9 @@ func (s *VolumeServer) Update(
 	if req.GetSpec().GetOwnership() != nil {
 		if spec.Ownership == nil {
 			spec.Ownership = &api.Ownership{}
+		} else {
+			// Keep the original ownership to release its quota usage
+			spec.Ownership = proto.Clone(spec.Ownership).(*api.Ownership)
 		}
 
 		user, _ := auth.NewUserInfoFromContext(ctx)
@@ -725,12 +847,30 @@ func (s *VolumeServer) Update(
 		return nil, err
 	}
 
+	// Charge the new size and ownership of the volume to the quotas
+	oldVol := resp.GetVolume()
+	newVol := &api.Volume{
+		Source:   oldVol.GetSource(),
+		Readonly: oldVol.GetReadonly(),
+		Spec:     updatedSpec,
+	}
+	revertQuota := func() {}
+	if charge {
+		revertQuota, err = s.chargeQuota(
+			oldVol.GetSpec().GetOwnership(), quota.VolumeUsage(oldVol),
+			updatedSpec.GetOwnership(), quota.VolumeUsage(newVol))
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	// avoid side effect while applying with stale config by masking
 	// other parts of the spec.
 	maskUnModified(updatedSpec, req.GetSpec())
 
 	// Send to driver
 	if err := s.driver(ctx).Set(ctx, req.GetVolumeId(), locator, updatedSpec); err != nil {
+		revertQuota()
 		return nil, status.Errorf(codes.Internal, "Failed to update volume: %v", err)
 	}
 
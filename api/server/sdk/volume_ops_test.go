@@ -29,6 +29,7 @@ import (
 	"github.com/libopenstorage/openstorage/api"
 	mockcluster "github.com/libopenstorage/openstorage/cluster/mock"
 	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/parser"
 	policy "github.com/libopenstorage/openstorage/pkg/storagepolicy"
 	"github.com/libopenstorage/openstorage/volume"
 	mockdriver "github.com/libopenstorage/openstorage/volume/drivers/mock"
@@ -622,6 +623,90 @@ func TestSdkVolumeEnumerateWithFilters(t *testing.T) {
 	assert.Equal(t, r.GetVolumeIds()[0], id)
 }
 
+func TestSdkVolumeEnumerateWithFiltersPaginated(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	s.MockDriver().
+		EXPECT().
+		Enumerate(nil, nil).
+		Return([]*api.Volume{
+			{Id: "vol1", Spec: &api.VolumeSpec{Size: 30}},
+			{Id: "vol2", Spec: &api.VolumeSpec{Size: 10}},
+			{Id: "vol3", Spec: &api.VolumeSpec{Size: 20}},
+		}, nil).
+		Times(2)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	req := &api.SdkVolumeEnumerateWithFiltersRequest{
+		PageSize: 2,
+		Sort: &api.SdkSortOrder{
+			Key:        api.SdkSortOrder_SIZE,
+			Descending: true,
+		},
+	}
+	r, err := c.EnumerateWithFilters(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vol1", "vol3"}, r.GetVolumeIds())
+	assert.NotEmpty(t, r.GetNextPageToken())
+
+	req.PageToken = r.GetNextPageToken()
+	r, err = c.EnumerateWithFilters(context.Background(), req)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vol2"}, r.GetVolumeIds())
+	assert.Empty(t, r.GetNextPageToken())
+}
+
+func TestSdkVolumeEnumerateWithFiltersLabelSelector(t *testing.T) {
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	selector, err := parser.ParseLabelSelector("env in (prod,qa),!legacy")
+	assert.NoError(t, err)
+
+	// The driver ignores the selector, the server must still filter
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		DoAndReturn(func(locator *api.VolumeLocator, labels map[string]string) ([]*api.Volume, error) {
+			assert.Equal(t, selector, locator.GetLabelSelector())
+			return []*api.Volume{
+				{Id: "vol1", Locator: &api.VolumeLocator{VolumeLabels: map[string]string{"env": "prod"}}},
+				{Id: "vol2", Locator: &api.VolumeLocator{VolumeLabels: map[string]string{"env": "dev"}}},
+				{Id: "vol3", Locator: &api.VolumeLocator{VolumeLabels: map[string]string{"env": "qa", "legacy": "true"}}},
+			}, nil
+		}).
+		Times(1)
+
+	// Setup client
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+
+	r, err := c.EnumerateWithFilters(
+		context.Background(),
+		&api.SdkVolumeEnumerateWithFiltersRequest{
+			LabelSelector: "env in (prod,qa),!legacy",
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"vol1"}, r.GetVolumeIds())
+
+	// Invalid selector
+	_, err = c.EnumerateWithFilters(
+		context.Background(),
+		&api.SdkVolumeEnumerateWithFiltersRequest{
+			LabelSelector: "env in (prod",
+		})
+	assert.Error(t, err)
+	serverError, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, serverError.Code(), codes.InvalidArgument)
+}
+
 func TestSdkVolumeUpdate(t *testing.T) {
 
 	// Create server and client connection
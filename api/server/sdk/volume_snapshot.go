@@ -20,6 +20,7 @@ import (
 	"context"
 
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/parser"
 	"github.com/libopenstorage/openstorage/pkg/sched"
 	"github.com/portworx/kvdb"
 	"google.golang.org/grpc/codes"
@@ -42,7 +43,21 @@ func (s *VolumeServer) SnapshotCreate(
 	}
 
 	// Get access rights
-	if err := s.checkAccessForVolumeId(ctx, req.GetVolumeId(), api.Ownership_Read); err != nil {
+	resp, err := s.Inspect(ctx, &api.SdkVolumeInspectRequest{
+		VolumeId: req.GetVolumeId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !resp.GetVolume().IsPermitted(ctx, api.Ownership_Read) {
+		return nil, status.Errorf(codes.PermissionDenied, "Access denied to volume %v", resp.GetVolume().GetId())
+	}
+
+	// The snapshot has the ownership of its volume
+	revertQuota, err := s.chargeQuota(nil, nil, resp.GetVolume().GetSpec().GetOwnership(), &api.SdkQuotaUsage{
+		Snapshots: 1,
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -52,6 +67,7 @@ func (s *VolumeServer) SnapshotCreate(
 		VolumeLabels: req.GetLabels(),
 	}, false)
 	if err != nil {
+		revertQuota()
 		if err == kvdb.ErrNotFound {
 			return nil, status.Errorf(
 				codes.NotFound,
@@ -140,6 +156,11 @@ func (s *VolumeServer) SnapshotEnumerateWithFilters(
 		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
 	}
 
+	selector, err := parseLabelSelector(req.GetLabelSelector())
+	if err != nil {
+		return nil, err
+	}
+
 	// Get access rights
 	var volReq []string
 	if len(req.GetVolumeId()) != 0 {
@@ -160,16 +181,31 @@ func (s *VolumeServer) SnapshotEnumerateWithFilters(
 			err.Error())
 	}
 
-	ids := make([]string, 0)
+	permitted := make([]*api.Volume, 0, len(snapshots))
 	for _, snapshot := range snapshots {
 		// Check access
-		if snapshot.IsPermitted(ctx, api.Ownership_Read) {
-			ids = append(ids, snapshot.GetId())
+		if snapshot.IsPermitted(ctx, api.Ownership_Read) &&
+			!isVolumeInTrash(snapshot) &&
+			parser.MatchLabelSelector(snapshot.GetLocator().GetVolumeLabels(), selector) {
+			permitted = append(permitted, snapshot)
 		}
 	}
 
+	page, next, err := paginate(req, len(permitted), func(i int, key api.SdkSortOrder_Key) (pageItem, bool) {
+		return volumePageItem(permitted[i], key)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(page))
+	for _, i := range page {
+		ids = append(ids, permitted[i].GetId())
+	}
+
 	return &api.SdkVolumeSnapshotEnumerateWithFiltersResponse{
 		VolumeSnapshotIds: ids,
+		NextPageToken:     next,
 	}, nil
 }
 
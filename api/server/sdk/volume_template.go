@@ -0,0 +1,81 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+
+	"github.com/libopenstorage/openstorage/api"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// templateVolumeSpec returns the spec and the labels of a volume created from
+// a volume template. The overrides are applied to the spec of the template and
+// the labels of the request are added to the labels of the template.
+func (s *VolumeServer) templateVolumeSpec(
+	ctx context.Context,
+	ref *api.SdkVolumeTemplateReference,
+	overrides *api.VolumeSpecUpdate,
+	labels map[string]string,
+) (*api.VolumeSpec, map[string]string, error) {
+	if s.server.volumeTemplate() == nil {
+		return nil, nil, status.Error(codes.Unavailable, "Volume templates are not enabled")
+	}
+	if len(ref.GetName()) == 0 {
+		return nil, nil, status.Error(codes.InvalidArgument, "Must supply a volume template name")
+	}
+
+	// Inspect checks that the user has access to the template
+	resp, err := s.server.volumeTemplate().Inspect(ctx, &api.SdkVolumeTemplateInspectRequest{
+		Name:    ref.GetName(),
+		Version: ref.GetVersion(),
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	template := resp.GetTemplate()
+	tspec := template.GetSpec()
+	if tspec == nil {
+		tspec = &api.VolumeSpec{}
+	}
+
+	// mergeVolumeSpecs only returns the fields which can be updated, so the
+	// other fields of the template are copied as they are
+	spec := s.mergeVolumeSpecs(tspec, overrides)
+	updatable := s.mergeVolumeSpecs(tspec, nil).ProtoReflect()
+	dst := spec.ProtoReflect()
+	tspec.ProtoReflect().Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if !updatable.Has(fd) {
+			dst.Set(fd, v)
+		}
+		return true
+	})
+	spec.Template = template.GetName()
+	spec.TemplateVersion = template.GetVersion()
+
+	merged := make(map[string]string, len(template.GetLabels())+len(labels))
+	for k, v := range template.GetLabels() {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+
+	return spec, merged, nil
+}
@@ -0,0 +1,133 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/volumetemplate"
+)
+
+func TestSdkVolumeCreateFromTemplate(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	name := "myvol"
+	req := &api.SdkVolumeCreateRequest{
+		Name:     name,
+		Template: &api.SdkVolumeTemplateReference{Name: "db"},
+		SpecOverrides: &api.VolumeSpecUpdate{
+			SizeOpt: &api.VolumeSpecUpdate_Size{Size: 200},
+		},
+		Labels: map[string]string{"tier": "silver"},
+	}
+
+	// Templates are not enabled
+	_, err := c.Create(context.Background(), req)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+
+	kv, err := kvdb.New(mem.Name, "volumetemplate", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	tm, err := volumetemplate.NewSdkVolumeTemplateManager(kv)
+	require.NoError(t, err)
+	s.server.netServer.volumeTemplateServer = tm
+
+	// Spec and template cannot both be used
+	_, err = c.Create(context.Background(), &api.SdkVolumeCreateRequest{
+		Name:     name,
+		Spec:     &api.VolumeSpec{},
+		Template: &api.SdkVolumeTemplateReference{Name: "db"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = c.Create(context.Background(), &api.SdkVolumeCreateRequest{
+		Name:          name,
+		Spec:          &api.VolumeSpec{},
+		SpecOverrides: &api.VolumeSpecUpdate{},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Template does not exist
+	_, err = c.Create(context.Background(), req)
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = tm.Create(context.Background(), &api.SdkVolumeTemplateCreateRequest{
+		Template: &api.SdkVolumeTemplate{
+			Name: "db",
+			Spec: &api.VolumeSpec{
+				Size:      100,
+				HaLevel:   2,
+				Format:    api.FSType_FS_TYPE_XFS,
+				BlockSize: 4096,
+			},
+			Labels: map[string]string{"app": "db", "tier": "gold"},
+		},
+	})
+	require.NoError(t, err)
+	_, err = tm.Update(context.Background(), &api.SdkVolumeTemplateUpdateRequest{
+		Name: "db",
+		Spec: &api.VolumeSpec{
+			Size:      100,
+			HaLevel:   3,
+			Format:    api.FSType_FS_TYPE_XFS,
+			BlockSize: 4096,
+		},
+		Labels: map[string]string{"app": "db", "tier": "gold"},
+	})
+	require.NoError(t, err)
+
+	id := "myid"
+	s.MockDriver().
+		EXPECT().
+		Inspect(gomock.Any(), []string{name}).
+		Return(nil, fmt.Errorf("not found"))
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{Name: name}, nil).
+		Return(nil, fmt.Errorf("not found"))
+	s.MockDriver().
+		EXPECT().
+		Create(gomock.Any(), gomock.Any(), &api.Source{}, gomock.Any()).
+		DoAndReturn(func(ctx context.Context, locator *api.VolumeLocator, source *api.Source, spec *api.VolumeSpec) (string, error) {
+			assert.Equal(t, map[string]string{"app": "db", "tier": "silver"}, locator.GetVolumeLabels())
+
+			// The latest version of the template is used with the overrides
+			assert.Equal(t, uint64(200), spec.GetSize())
+			assert.Equal(t, int64(3), spec.GetHaLevel())
+			assert.Equal(t, api.FSType_FS_TYPE_XFS, spec.GetFormat())
+			assert.Equal(t, int64(4096), spec.GetBlockSize())
+			assert.Equal(t, "db", spec.GetTemplate())
+			assert.Equal(t, uint64(2), spec.GetTemplateVersion())
+			return id, nil
+		})
+
+	r, err := c.Create(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, id, r.GetVolumeId())
+}
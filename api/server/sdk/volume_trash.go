@@ -0,0 +1,437 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+	"github.com/libopenstorage/openstorage/pkg/sched"
+	"github.com/libopenstorage/openstorage/pkg/util"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// volumeTrashNameLabel is the label of a volume in the trash with the
+	// name of the volume before it was deleted
+	volumeTrashNameLabel = "openstorage.io/trash-name"
+	// volumeTrashDeletedLabel is the label of a volume in the trash with the
+	// time it was deleted, in RFC3339 format
+	volumeTrashDeletedLabel = "openstorage.io/trash-deleted"
+	// volumeTrashNamePrefix is prepended to the id of a volume in the trash
+	// to rename it, so the original name can be reused
+	volumeTrashNamePrefix = "trash-"
+	// defaultVolumeTrashPurgeInterval is how often the trash is purged if
+	// not configured
+	defaultVolumeTrashPurgeInterval = 10 * time.Minute
+	// volumeTrashPurgeLockKey is the kvdb lock of the node purging the trash
+	volumeTrashPurgeLockKey = "cluster/volume_trash/purge_lock"
+	// volumeTrashPurgeLockTryDuration is how long a node waits for the lock
+	// of the purge before leaving the purge to the node holding it
+	volumeTrashPurgeLockTryDuration = time.Second
+)
+
+// VolumeTrashConfig enables the soft delete of the volumes. Deleted volumes
+// are detached, renamed and kept in the trash until the end of the retention
+// period, and can be restored until then. Volumes in the trash are still
+// charged to the quotas of their owners.
+type VolumeTrashConfig struct {
+	// Retention is how long deleted volumes are kept in the trash
+	Retention time.Duration
+	// (optional) PurgeInterval is how often the volumes at the end of their
+	// retention period are purged from the trash. Defaults to 10 minutes.
+	PurgeInterval time.Duration
+}
+
+// Validate returns an error if the configuration is not valid
+func (c *VolumeTrashConfig) Validate() error {
+	if c.Retention <= 0 {
+		return fmt.Errorf("Retention of the volume trash must be greater than zero")
+	}
+	if c.PurgeInterval < 0 {
+		return fmt.Errorf("Purge interval of the volume trash cannot be negative")
+	}
+	return nil
+}
+
+// isVolumeInTrash returns true if the volume has been deleted and is kept in
+// the trash. Some drivers keep the labels set to empty values.
+func isVolumeInTrash(vol *api.Volume) bool {
+	return len(vol.GetLocator().GetVolumeLabels()[volumeTrashDeletedLabel]) != 0
+}
+
+// copyLocator returns a copy of the locator of a volume with its labels, as
+// most drivers replace the whole locator in Set
+func copyLocator(vol *api.Volume) *api.VolumeLocator {
+	locator := &api.VolumeLocator{}
+	if vol.GetLocator() != nil {
+		locator = proto.Clone(vol.GetLocator()).(*api.VolumeLocator)
+	}
+	if locator.VolumeLabels == nil {
+		locator.VolumeLabels = make(map[string]string)
+	}
+	return locator
+}
+
+// volumeTrashDeleted returns the time the volume in the trash was deleted
+func volumeTrashDeleted(vol *api.Volume) (time.Time, error) {
+	return time.Parse(time.RFC3339, vol.GetLocator().GetVolumeLabels()[volumeTrashDeletedLabel])
+}
+
+// trashVolume detaches the volume and moves it to the trash. The caller must
+// check the access to the volume.
+func (s *VolumeServer) trashVolume(ctx context.Context, vol *api.Volume) error {
+	if vol.IsAttached() {
+		if _, err := s.Detach(ctx, &api.SdkVolumeDetachRequest{
+			VolumeId: vol.GetId(),
+		}); err != nil {
+			return err
+		}
+	}
+
+	// Keep the labels of the volume to restore them
+	locator := copyLocator(vol)
+	locator.Name = volumeTrashNamePrefix + vol.GetId()
+	locator.VolumeLabels[volumeTrashNameLabel] = vol.GetLocator().GetName()
+	locator.VolumeLabels[volumeTrashDeletedLabel] = time.Now().UTC().Format(time.RFC3339)
+	if err := s.driver(ctx).Set(ctx, vol.GetId(), locator, nil); err != nil {
+		return status.Errorf(
+			codes.Internal,
+			"Failed to move volume %s to the trash: %v",
+			vol.GetId(),
+			err.Error())
+	}
+	return nil
+}
+
+// enumerateTrash returns all the volumes in the trash
+func (s *VolumeServer) enumerateTrash(ctx context.Context) ([]*api.Volume, error) {
+	vols, err := s.driver(ctx).Enumerate(&api.VolumeLocator{
+		LabelSelector: []*api.LabelSelectorRequirement{
+			{
+				Key:      volumeTrashDeletedLabel,
+				Operator: api.LabelSelectorRequirement_Exists,
+			},
+		},
+	}, nil)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal,
+			"Failed to enumerate volumes: %v",
+			err.Error())
+	}
+
+	// The label selector is checked again for the drivers which do not
+	// support it
+	trash := make([]*api.Volume, 0, len(vols))
+	for _, vol := range vols {
+		if isVolumeInTrash(vol) {
+			trash = append(trash, vol)
+		}
+	}
+	return trash, nil
+}
+
+// inspectTrash returns the volume in the trash if the caller has the
+// access to it
+func (s *VolumeServer) inspectTrash(
+	ctx context.Context,
+	volumeId string,
+	accessType api.Ownership_AccessType,
+) (*api.Volume, error) {
+	vols, err := s.driver(ctx).Enumerate(&api.VolumeLocator{
+		VolumeIds: []string{volumeId},
+	}, nil)
+	if err != nil {
+		return nil, status.Errorf(
+			codes.Internal,
+			"Failed to inspect volume %s: %v",
+			volumeId, err)
+	}
+	if len(vols) == 0 || !isVolumeInTrash(vols[0]) {
+		return nil, status.Errorf(
+			codes.NotFound,
+			"Volume id %s not found in the trash",
+			volumeId)
+	}
+
+	vol := vols[0]
+	if !vol.IsPermitted(ctx, accessType) {
+		return nil, status.Errorf(codes.PermissionDenied, "Access denied to volume %s", volumeId)
+	}
+	return vol, nil
+}
+
+// TrashEnumerate returns the volumes in the trash
+func (s *VolumeServer) TrashEnumerate(
+	ctx context.Context,
+	req *api.SdkVolumeTrashEnumerateRequest,
+) (*api.SdkVolumeTrashEnumerateResponse, error) {
+	if s.cluster() == nil || s.driver(ctx) == nil {
+		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+
+	vols, err := s.enumerateTrash(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	trash := s.server.volumeTrash()
+	infos := make([]*api.SdkVolumeTrashInfo, 0, len(vols))
+	for _, vol := range vols {
+		// Check access
+		if !vol.IsPermitted(ctx, api.Ownership_Read) {
+			continue
+		}
+
+		info := &api.SdkVolumeTrashInfo{
+			VolumeId: vol.GetId(),
+			Name:     vol.GetLocator().GetVolumeLabels()[volumeTrashNameLabel],
+		}
+		if deleted, err := volumeTrashDeleted(vol); err == nil {
+			info.Deleted = prototime.TimeToTimestamp(deleted)
+			// The volumes are kept until the trash is enabled again
+			if trash != nil {
+				info.Expires = prototime.TimeToTimestamp(deleted.Add(trash.Retention))
+			}
+		}
+		infos = append(infos, info)
+	}
+
+	return &api.SdkVolumeTrashEnumerateResponse{
+		Volumes: infos,
+	}, nil
+}
+
+// TrashRestore restores a volume from the trash
+func (s *VolumeServer) TrashRestore(
+	ctx context.Context,
+	req *api.SdkVolumeTrashRestoreRequest,
+) (*api.SdkVolumeTrashRestoreResponse, error) {
+	if s.cluster() == nil || s.driver(ctx) == nil {
+		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply volume id")
+	}
+
+	// Only the owner or the admin can restore
+	vol, err := s.inspectTrash(ctx, req.GetVolumeId(), api.Ownership_Admin)
+	if err != nil {
+		return nil, err
+	}
+
+	name := req.GetName()
+	if len(name) == 0 {
+		name = vol.GetLocator().GetVolumeLabels()[volumeTrashNameLabel]
+	}
+	if len(name) == 0 {
+		return nil, status.Errorf(
+			codes.InvalidArgument,
+			"Must supply a name to restore volume %s",
+			req.GetVolumeId())
+	}
+
+	// The name may have been reused while the volume was in the trash
+	if _, err := util.VolumeFromName(correlation.TODO(), s.driver(ctx), name); err == nil {
+		return nil, status.Errorf(
+			codes.AlreadyExists,
+			"Volume with name %s already exists",
+			name)
+	}
+
+	// Restore the locator of the volume without the labels of the trash
+	locator := copyLocator(vol)
+	locator.Name = name
+	delete(locator.VolumeLabels, volumeTrashNameLabel)
+	delete(locator.VolumeLabels, volumeTrashDeletedLabel)
+	if err := s.driver(ctx).Set(ctx, req.GetVolumeId(), locator, nil); err != nil {
+		return nil, status.Errorf(
+			codes.Internal,
+			"Failed to restore volume %s: %v",
+			req.GetVolumeId(),
+			err.Error())
+	}
+
+	// The drivers which merge the labels remove those set to empty values
+	if _, err := s.inspectTrash(ctx, req.GetVolumeId(), api.Ownership_Admin); err == nil {
+		err = s.driver(ctx).Set(ctx, req.GetVolumeId(), &api.VolumeLocator{
+			VolumeLabels: map[string]string{
+				volumeTrashNameLabel:    "",
+				volumeTrashDeletedLabel: "",
+			},
+		}, nil)
+		if err != nil {
+			return nil, status.Errorf(
+				codes.Internal,
+				"Failed to remove the trash labels of volume %s: %v",
+				req.GetVolumeId(),
+				err.Error())
+		}
+	} else if status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+
+	s.auditLog(ctx, "volume.trash.restore", "Volume %s restored from the trash as %s", req.GetVolumeId(), name)
+	return &api.SdkVolumeTrashRestoreResponse{}, nil
+}
+
+// TrashPurge deletes a volume in the trash
+func (s *VolumeServer) TrashPurge(
+	ctx context.Context,
+	req *api.SdkVolumeTrashPurgeRequest,
+) (*api.SdkVolumeTrashPurgeResponse, error) {
+	if s.cluster() == nil || s.driver(ctx) == nil {
+		return nil, status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+
+	if len(req.GetVolumeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply volume id")
+	}
+
+	// Only the owner or the admin can purge
+	vol, err := s.inspectTrash(ctx, req.GetVolumeId(), api.Ownership_Admin)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.deleteVolume(ctx, req.GetVolumeId(), vol); err != nil {
+		return nil, err
+	}
+
+	s.auditLog(ctx, "volume.trash.purge", "Volume %s purged from the trash", req.GetVolumeId())
+	return &api.SdkVolumeTrashPurgeResponse{}, nil
+}
+
+// purgeExpiredTrash deletes the volumes which have been in the trash for
+// longer than the retention period
+func (s *VolumeServer) purgeExpiredTrash(now time.Time) {
+	trash := s.server.volumeTrash()
+	if trash == nil {
+		return
+	}
+
+	ctx := context.Background()
+	vols, err := s.enumerateTrash(ctx)
+	if err != nil {
+		logrus.Warnf("Failed to purge the volume trash: %v", err)
+		return
+	}
+
+	for _, vol := range vols {
+		deleted, err := volumeTrashDeleted(vol)
+		if err != nil {
+			logrus.Warnf("Volume %s in the trash has an invalid deletion time: %v", vol.GetId(), err)
+			continue
+		}
+		if now.Before(deleted.Add(trash.Retention)) {
+			continue
+		}
+
+		if err := s.deleteVolume(ctx, vol.GetId(), vol); err != nil {
+			logrus.Warnf("Failed to purge volume %s from the trash: %v", vol.GetId(), err)
+			continue
+		}
+		logrus.Infof("Volume %s purged from the trash", vol.GetId())
+	}
+}
+
+// lockVolumeTrashPurge takes the kvdb lock of the purge of the trash for
+// owner. It returns false if another node holds the lock, and is purging the
+// trash. Otherwise it returns the function which releases the lock. Without
+// kvdb, the node is alone and always gets the lock.
+func lockVolumeTrashPurge(kv kvdb.Kvdb, owner string) (func(), bool, error) {
+	if kv == nil {
+		return func() {}, true, nil
+	}
+
+	// The lock is held for as long as the purge takes
+	kvp, err := kv.LockWithTimeout(volumeTrashPurgeLockKey, owner, volumeTrashPurgeLockTryDuration, 0)
+	if err == kvdb.ErrExist {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+	return func() {
+		if err := kv.Unlock(kvp); err != nil {
+			logrus.Warnf("Failed to unlock the purge of the volume trash: %v", err)
+		}
+	}, true, nil
+}
+
+// startVolumeTrashPurger schedules the purge of the expired volumes of the
+// trash if it is enabled
+func (s *Server) startVolumeTrashPurger() error {
+	trash := s.config.VolumeTrash
+	if trash == nil {
+		return nil
+	}
+	if sched.Instance() == nil {
+		return fmt.Errorf("sched instance is not initialized")
+	}
+
+	interval := trash.PurgeInterval
+	if interval == 0 {
+		interval = defaultVolumeTrashPurgeInterval
+	}
+	// Only one node purges the trash at a time. The volumes are deleted
+	// from the trash once, so the nodes which purge it after that one find
+	// nothing to purge.
+	owner := uuid.New()
+	taskId, err := sched.Instance().Schedule(
+		func(sched.Interval) {
+			unlock, ok, err := lockVolumeTrashPurge(s.config.Kvdb, owner)
+			if err != nil {
+				logrus.Warnf("Failed to lock the purge of the volume trash: %v", err)
+				return
+			} else if !ok {
+				return
+			}
+			defer unlock()
+			s.netServer.volumeServer.purgeExpiredTrash(time.Now())
+		},
+		sched.Periodic(interval),
+		time.Now().Add(interval),
+		false,
+	)
+	if err != nil {
+		return fmt.Errorf("Failed to schedule the purge of the volume trash: %v", err)
+	}
+	s.trashPurgeTask = taskId
+	return nil
+}
+
+// stopVolumeTrashPurger cancels the purge of the trash
+func (s *Server) stopVolumeTrashPurger() {
+	if !sched.ValidTaskID(s.trashPurgeTask) {
+		return
+	}
+	if err := sched.Instance().Cancel(s.trashPurgeTask); err != nil {
+		logrus.Warnf("Failed to cancel the purge of the volume trash: %v", err)
+	}
+	s.trashPurgeTask = sched.TaskNone
+}
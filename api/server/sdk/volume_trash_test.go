@@ -0,0 +1,326 @@
+/*
+Package sdk is the gRPC implementation of the SDK gRPC server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/vfs"
+)
+
+func trashedVolume(id, name string, deleted time.Time) *api.Volume {
+	return &api.Volume{
+		Id: id,
+		Locator: &api.VolumeLocator{
+			Name: volumeTrashNamePrefix + id,
+			VolumeLabels: map[string]string{
+				volumeTrashNameLabel:    name,
+				volumeTrashDeletedLabel: deleted.UTC().Format(time.RFC3339),
+			},
+		},
+		Spec: &api.VolumeSpec{},
+	}
+}
+
+func TestSdkVolumeDeleteToTrash(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	s.server.netServer.config.VolumeTrash = &VolumeTrashConfig{Retention: time.Hour}
+
+	id := "myvol"
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{id}}, nil).
+		Return([]*api.Volume{
+			{
+				Id:         id,
+				Locator:    &api.VolumeLocator{Name: "myname"},
+				State:      api.VolumeState_VOLUME_STATE_ATTACHED,
+				AttachedOn: "node1",
+			},
+		}, nil).
+		Times(2)
+	s.MockDriver().
+		EXPECT().
+		Detach(gomock.Any(), id, gomock.Any()).
+		Return(nil)
+	s.MockDriver().
+		EXPECT().
+		Set(gomock.Any(), id, gomock.Any(), nil).
+		DoAndReturn(func(ctx context.Context, volumeId string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+			assert.Equal(t, "trash-myvol", locator.GetName())
+			assert.Equal(t, "myname", locator.GetVolumeLabels()[volumeTrashNameLabel])
+			assert.NotEmpty(t, locator.GetVolumeLabels()[volumeTrashDeletedLabel])
+			return nil
+		})
+
+	// The volume is not deleted from the driver
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	_, err := c.Delete(context.Background(), &api.SdkVolumeDeleteRequest{VolumeId: id})
+	assert.NoError(t, err)
+}
+
+func TestSdkVolumeInspectInTrash(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	id := "myvol"
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{id}}, nil).
+		Return([]*api.Volume{trashedVolume(id, "myname", time.Now())}, nil)
+
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	_, err := c.Inspect(context.Background(), &api.SdkVolumeInspectRequest{VolumeId: id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestSdkVolumeTrashEnumerate(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	s.server.netServer.config.VolumeTrash = &VolumeTrashConfig{Retention: time.Hour}
+
+	deleted := time.Now().Add(-time.Minute).Truncate(time.Second)
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		Return([]*api.Volume{
+			trashedVolume("vol1", "name1", deleted),
+			{Id: "vol2", Locator: &api.VolumeLocator{Name: "name2"}},
+		}, nil)
+
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	r, err := c.TrashEnumerate(context.Background(), &api.SdkVolumeTrashEnumerateRequest{})
+	require.NoError(t, err)
+	require.Len(t, r.GetVolumes(), 1)
+	assert.Equal(t, "vol1", r.GetVolumes()[0].GetVolumeId())
+	assert.Equal(t, "name1", r.GetVolumes()[0].GetName())
+	assert.Equal(t, deleted.Unix(), r.GetVolumes()[0].GetDeleted().GetSeconds())
+	assert.Equal(t, deleted.Add(time.Hour).Unix(), r.GetVolumes()[0].GetExpires().GetSeconds())
+}
+
+func TestSdkVolumeTrashRestore(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	id := "myvol"
+	trashed := trashedVolume(id, "myname", time.Now())
+	trashed.Locator.VolumeLabels["app"] = "db"
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{id}}, nil).
+		Return([]*api.Volume{trashed}, nil).
+		Times(2)
+
+	// The name has been reused
+	s.MockDriver().
+		EXPECT().
+		Inspect(gomock.Any(), []string{"myname"}).
+		Return([]*api.Volume{{Id: "other"}}, nil)
+
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	_, err := c.TrashRestore(context.Background(), &api.SdkVolumeTrashRestoreRequest{VolumeId: id})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// Restore with a new name, the labels of the volume are kept
+	s.MockDriver().
+		EXPECT().
+		Inspect(gomock.Any(), []string{"newname"}).
+		Return(nil, fmt.Errorf("not found"))
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{Name: "newname"}, nil).
+		Return(nil, nil)
+	s.MockDriver().
+		EXPECT().
+		Set(gomock.Any(), id, gomock.Any(), nil).
+		DoAndReturn(func(ctx context.Context, volumeId string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+			assert.Equal(t, "newname", locator.GetName())
+			assert.Equal(t, map[string]string{"app": "db"}, locator.GetVolumeLabels())
+			return nil
+		})
+
+	// The driver merges the labels, the trash labels are removed with
+	// empty values
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{id}}, nil).
+		Return([]*api.Volume{trashed}, nil)
+	s.MockDriver().
+		EXPECT().
+		Set(gomock.Any(), id, gomock.Any(), nil).
+		DoAndReturn(func(ctx context.Context, volumeId string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+			assert.Equal(t, map[string]string{
+				volumeTrashNameLabel:    "",
+				volumeTrashDeletedLabel: "",
+			}, locator.GetVolumeLabels())
+			return nil
+		})
+
+	_, err = c.TrashRestore(context.Background(), &api.SdkVolumeTrashRestoreRequest{
+		VolumeId: id,
+		Name:     "newname",
+	})
+	assert.NoError(t, err)
+}
+
+func TestSdkVolumeTrashVfs(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("The vfs driver requires root")
+	}
+
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	s.server.netServer.config.VolumeTrash = &VolumeTrashConfig{Retention: time.Hour}
+
+	require.NoError(t, os.MkdirAll(volume.VolumeBase, 0755))
+	d, err := vfs.Init(map[string]string{})
+	require.NoError(t, err)
+	defer d.Shutdown()
+	s.server.UseVolumeDrivers(map[string]volume.VolumeDriver{DefaultDriverName: d})
+
+	id, err := d.Create(context.Background(), &api.VolumeLocator{
+		Name:         "trashvol",
+		VolumeLabels: map[string]string{"app": "db"},
+	}, nil, &api.VolumeSpec{Size: 1024 * 1024, Format: api.FSType_FS_TYPE_VFS})
+	require.NoError(t, err)
+	defer d.Delete(context.Background(), id)
+
+	inspect := func() *api.Volume {
+		vols, err := d.Inspect(context.Background(), []string{id})
+		require.NoError(t, err)
+		require.Len(t, vols, 1)
+		return vols[0]
+	}
+
+	// The labels are kept in the trash
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	_, err = c.Delete(context.Background(), &api.SdkVolumeDeleteRequest{VolumeId: id})
+	require.NoError(t, err)
+	vol := inspect()
+	assert.True(t, isVolumeInTrash(vol))
+	assert.Equal(t, "db", vol.GetLocator().GetVolumeLabels()["app"])
+	_, err = c.Inspect(context.Background(), &api.SdkVolumeInspectRequest{VolumeId: id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The volume is visible again after the restore, with its labels
+	_, err = c.TrashRestore(context.Background(), &api.SdkVolumeTrashRestoreRequest{VolumeId: id})
+	require.NoError(t, err)
+	vol = inspect()
+	assert.False(t, isVolumeInTrash(vol))
+	assert.Equal(t, "trashvol", vol.GetLocator().GetName())
+	assert.Equal(t, map[string]string{"app": "db"}, vol.GetLocator().GetVolumeLabels())
+	r, err := c.Inspect(context.Background(), &api.SdkVolumeInspectRequest{VolumeId: id})
+	require.NoError(t, err)
+	assert.Equal(t, "trashvol", r.GetName())
+}
+
+func TestVolumeTrashPurgeLock(t *testing.T) {
+	kv, err := kvdb.New(mem.Name, "trash", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+
+	// Only one node purges the trash at a time
+	unlock, ok, err := lockVolumeTrashPurge(kv, "node1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	_, ok, err = lockVolumeTrashPurge(kv, "node2")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// Another node purges the trash once the purge is done
+	unlock()
+	unlock, ok, err = lockVolumeTrashPurge(kv, "node2")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	unlock()
+
+	// Without kvdb the node is alone
+	_, ok, err = lockVolumeTrashPurge(nil, "node1")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestSdkVolumeTrashPurge(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+
+	// Not in the trash
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{"vol1"}}, nil).
+		Return([]*api.Volume{{Id: "vol1"}}, nil)
+
+	c := api.NewOpenStorageVolumeClient(s.Conn())
+	_, err := c.TrashPurge(context.Background(), &api.SdkVolumeTrashPurgeRequest{VolumeId: "vol1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	s.MockDriver().
+		EXPECT().
+		Enumerate(&api.VolumeLocator{VolumeIds: []string{"vol2"}}, nil).
+		Return([]*api.Volume{trashedVolume("vol2", "name2", time.Now())}, nil)
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "vol2").
+		Return(nil)
+
+	_, err = c.TrashPurge(context.Background(), &api.SdkVolumeTrashPurgeRequest{VolumeId: "vol2"})
+	assert.NoError(t, err)
+}
+
+func TestSdkVolumePurgeExpiredTrash(t *testing.T) {
+	// Create server and client connection
+	s := newTestServer(t)
+	defer s.Stop()
+	s.server.netServer.config.VolumeTrash = &VolumeTrashConfig{Retention: time.Hour}
+
+	now := time.Now()
+	s.MockDriver().
+		EXPECT().
+		Enumerate(gomock.Any(), nil).
+		Return([]*api.Volume{
+			trashedVolume("expired", "name1", now.Add(-2*time.Hour)),
+			trashedVolume("recent", "name2", now.Add(-time.Minute)),
+		}, nil)
+
+	// Only the expired volume is deleted
+	s.MockDriver().
+		EXPECT().
+		Delete(gomock.Any(), "expired").
+		Return(nil)
+
+	s.server.netServer.volumeServer.purgeExpiredTrash(now)
+}
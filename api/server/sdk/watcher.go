@@ -22,9 +22,13 @@ const (
 
 type WatcherServer struct {
 	volumeServer *VolumeServer
+	server       serverAccessor
 
 	watchConnections map[string][]*watchConnection
 	volumeChannel    chan *api.Volume
+
+	// eventLogs hold the revisioned events of the polled event types
+	eventLogs *watchEventLogs
 	sync.RWMutex
 }
 
@@ -33,12 +37,24 @@ type WatcherServer struct {
 //
 // Implementation note: the flow of data starts from porx ETCD changes, then the data will be send to openstorage via a golang channel.
 // Once the event object arrives at openstorage, it will be redistributed to a list of watch connections via another set of channels.
+//
+// Node, alert, cloud backup and job events are found by polling their state and are kept with a revision, so that a client
+// can resume watching after the last revision it received.
 func (w *WatcherServer) Watch(req *api.SdkWatchRequest, stream api.OpenStorageWatch_WatchServer) error {
 	if w.volumeServer.cluster() == nil || w.volumeServer.driver(stream.Context()) == nil {
 		return status.Error(codes.Unavailable, "Resource has not been initialized")
 	}
-	if req.GetVolumeEvent() != nil {
+	switch {
+	case req.GetVolumeEvent() != nil:
 		return w.volumeWatch(req.GetVolumeEvent(), stream)
+	case req.GetNodeEvent() != nil:
+		return w.nodeWatch(req.GetNodeEvent(), stream)
+	case req.GetAlertEvent() != nil:
+		return w.alertWatch(req.GetAlertEvent(), stream)
+	case req.GetCloudBackupEvent() != nil:
+		return w.cloudBackupWatch(req.GetCloudBackupEvent(), stream)
+	case req.GetJobEvent() != nil:
+		return w.jobWatch(req.GetJobEvent(), stream)
 	}
 	return status.Errorf(codes.InvalidArgument, "invalid request type for watcher %v", req)
 }
@@ -86,6 +102,7 @@ func (s *WatcherServer) removeWatcher(name string, eventType string) {
 
 func (s *WatcherServer) stopWatcher(ctx context.Context) {
 	s.volumeServer.driver(ctx).StopVolumeWatcher()
+	s.stopEventWatchers()
 }
 
 func (s *WatcherServer) startWatcher(ctx context.Context) error {
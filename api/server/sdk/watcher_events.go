@@ -0,0 +1,956 @@
+package sdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/libopenstorage/openstorage/alerts"
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/job"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	nodeEventType        = "Node"
+	alertEventType       = "Alert"
+	cloudBackupEventType = "CloudBackup"
+	jobEventType         = "Job"
+
+	// watchKvdbPrefix is the kvdb prefix of the event logs
+	watchKvdbPrefix = "sdk/watch/"
+)
+
+var (
+	// watchEventPollInterval is how often nodes, alerts, cloud backups and
+	// jobs are listed and compared to the state saved in their event log.
+	watchEventPollInterval = 2 * time.Second
+	// watchEventRetention is how long events are kept in the log for the
+	// clients resuming a watch, and the state of deleted objects is kept.
+	watchEventRetention = time.Hour
+	// watchTrimInterval is how often the events older than the retention
+	// are trimmed from the log.
+	watchTrimInterval = time.Minute
+)
+
+// watchEvent is a change of a single object at a revision.
+type watchEvent struct {
+	revision uint64
+	key      string
+	deleted  bool
+	object   proto.Message
+}
+
+// watchRecord is saved in kvdb with the state of an object, and with each
+// event of the log.
+type watchRecord struct {
+	// Key of the object
+	Key string `json:"key"`
+	// Deleted is set once the object no longer exists
+	Deleted bool `json:"deleted"`
+	// Time of the event
+	Time time.Time `json:"time"`
+	// Object is the object marshalled with protojson
+	Object json.RawMessage `json:"object"`
+	// Revision of the event which logged the state, zero until it is
+	// logged. Unused in the log.
+	Revision uint64 `json:"revision,omitempty"`
+}
+
+// watchListFunc lists the current objects of an event type keyed by id.
+type watchListFunc func(ctx context.Context) (map[string]proto.Message, error)
+
+// watchSameFunc returns true if no event should be raised for an object
+// going from prev to cur.
+type watchSameFunc func(prev, cur proto.Message) bool
+
+// watchEventSource lists the objects of an event type.
+type watchEventSource struct {
+	list watchListFunc
+	same watchSameFunc
+	// newObject returns an empty object to unmarshal the saved ones into.
+	newObject func() proto.Message
+	// kvdbPrefix, if set, is where the objects are saved in kvdb. They are
+	// listed as soon as it changes instead of at the next poll.
+	kvdbPrefix string
+}
+
+// watchEventLog turns the listings of an event type into events saved in
+// kvdb. The state of each object is saved under its own key, which is
+// compared and set on every change, so that a change seen by several nodes
+// is saved by the first one. Each change is then appended to the log under a
+// key named after the index of the state, so that the nodes which append it
+// create a single entry. The revision of an event is the kvdb index of its
+// entry, so revisions are shared by all the nodes, kept across restarts and
+// increase in the order the events are logged. A client resuming from a
+// revision gets every event logged after it, and is notified of the new
+// events by a kvdb watch.
+//
+// Events are trimmed from the log once older than watchEventRetention, after
+// which the revisions before them are rejected.
+type watchEventLog struct {
+	kv        kvdb.Kvdb
+	eventType string
+	source    *watchEventSource
+	// ready is closed once the first listing is saved.
+	ready chan struct{}
+	// trimmed is when the log was last trimmed by this node.
+	trimmed time.Time
+}
+
+// savedEvent is a state or an event loaded from kvdb.
+type savedEvent struct {
+	*watchEvent
+	kvp  *kvdb.KVPair
+	time time.Time
+}
+
+func newWatchEventLog(kv kvdb.Kvdb, eventType string, source *watchEventSource) *watchEventLog {
+	return &watchEventLog{
+		kv:        kv,
+		eventType: eventType,
+		source:    source,
+		ready:     make(chan struct{}),
+	}
+}
+
+func (l *watchEventLog) objectsPrefix() string {
+	return watchKvdbPrefix + l.eventType + "/objects/"
+}
+
+func (l *watchEventLog) objectKey(key string) string {
+	return l.objectsPrefix() + url.PathEscape(key)
+}
+
+func (l *watchEventLog) logPrefix() string {
+	return watchKvdbPrefix + l.eventType + "/log/"
+}
+
+// logKey returns the key of the event which logs the state of an object
+// saved at the given index.
+func (l *watchEventLog) logKey(key string, stateIndex uint64) string {
+	return l.logPrefix() + url.PathEscape(key) + "/" + strconv.FormatUint(stateIndex, 10)
+}
+
+func (l *watchEventLog) compactedKey() string {
+	return watchKvdbPrefix + l.eventType + "/compacted"
+}
+
+func (l *watchEventLog) run(ctx context.Context) {
+	l.poll(ctx)
+	close(l.ready)
+
+	changed := make(chan struct{}, 1)
+	if len(l.source.kvdbPrefix) != 0 {
+		err := l.kv.WatchTree(l.source.kvdbPrefix, 0, nil,
+			func(prefix string, opaque interface{}, kvp *kvdb.KVPair, err error) error {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				} else if err != nil {
+					logrus.Warnf("Stopped watching %s for %v watcher: %v", prefix, l.eventType, err)
+					return err
+				}
+				select {
+				case changed <- struct{}{}:
+				default:
+				}
+				return nil
+			})
+		if err != nil {
+			logrus.Warnf("Failed to watch %s for %v watcher: %v", l.source.kvdbPrefix, l.eventType, err)
+		}
+	}
+
+	ticker := time.NewTicker(watchEventPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			l.poll(ctx)
+		case <-changed:
+			l.poll(ctx)
+		case <-ctx.Done():
+			logrus.Infof("Exiting %v watcher", l.eventType)
+			return
+		}
+	}
+}
+
+func (l *watchEventLog) poll(ctx context.Context) {
+	if ctx.Err() != nil {
+		return
+	}
+	objects, err := l.source.list(ctx)
+	if err != nil {
+		logrus.Warnf("Failed to list objects for %v watcher: %v", l.eventType, err)
+		return
+	}
+	if err := l.update(objects); err != nil {
+		logrus.Warnf("Failed to save events for %v watcher: %v", l.eventType, err)
+	}
+	if time.Since(l.trimmed) >= watchTrimInterval {
+		l.trimmed = time.Now()
+		if err := l.trim(); err != nil {
+			logrus.Warnf("Failed to trim the events of %v watcher: %v", l.eventType, err)
+		}
+	}
+}
+
+// update saves and logs an event for every object which is new or changed
+// in objects and for every saved object which is missing from objects. The
+// changes saved by a node which stopped before logging them are logged, and
+// the states of the objects deleted for longer than watchEventRetention are
+// purged.
+func (l *watchEventLog) update(objects map[string]proto.Message) error {
+	saved, err := l.load()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(objects))
+	for key := range objects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prev, ok := saved[key]
+		if !ok {
+			err = l.save(nil, key, false, objects[key])
+		} else if prev.deleted || !l.source.same(prev.object, objects[key]) {
+			err = l.save(prev.kvp, key, false, objects[key])
+		} else if prev.revision == 0 {
+			err = l.append(key, prev.kvp)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	keys = keys[:0]
+	for key := range saved {
+		if _, ok := objects[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		prev := saved[key]
+		if !prev.deleted {
+			err = l.save(prev.kvp, key, true, prev.object)
+		} else if prev.revision == 0 {
+			err = l.append(key, prev.kvp)
+		} else if time.Since(prev.time) > watchEventRetention {
+			err = l.purge(prev.kvp)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// load returns the saved states keyed by object.
+func (l *watchEventLog) load() (map[string]*savedEvent, error) {
+	kvps, err := l.kv.Enumerate(l.objectsPrefix())
+	if err != nil && err != kvdb.ErrNotFound {
+		return nil, err
+	}
+	saved := make(map[string]*savedEvent, len(kvps))
+	for _, kvp := range kvps {
+		event, err := l.decode(kvp, false)
+		if err != nil {
+			logrus.Warnf("Skipping %v state %s: %v", l.eventType, kvp.Key, err)
+			continue
+		}
+		saved[event.key] = event
+	}
+	return saved, nil
+}
+
+// logged returns the events of the log sorted by revision.
+func (l *watchEventLog) logged() ([]*savedEvent, error) {
+	kvps, err := l.kv.Enumerate(l.logPrefix())
+	if err != nil && err != kvdb.ErrNotFound {
+		return nil, err
+	}
+	events := make([]*savedEvent, 0, len(kvps))
+	for _, kvp := range kvps {
+		event, err := l.decode(kvp, true)
+		if err != nil {
+			logrus.Warnf("Skipping %v event %s: %v", l.eventType, kvp.Key, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].revision < events[j].revision
+	})
+	return events, nil
+}
+
+// decode decodes a state, or an event of the log whose revision is its
+// index.
+func (l *watchEventLog) decode(kvp *kvdb.KVPair, logged bool) (*savedEvent, error) {
+	var r watchRecord
+	if err := json.Unmarshal(kvp.Value, &r); err != nil {
+		return nil, err
+	}
+	object := l.source.newObject()
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(r.Object, object); err != nil {
+		return nil, err
+	}
+	revision := r.Revision
+	if logged {
+		revision = kvp.ModifiedIndex
+	}
+	return &savedEvent{
+		watchEvent: &watchEvent{
+			revision: revision,
+			key:      r.Key,
+			deleted:  r.Deleted,
+			object:   object,
+		},
+		kvp:  kvp,
+		time: r.Time,
+	}, nil
+}
+
+// save saves the state of an object over its previous state prev, if any,
+// and logs it. Nothing is saved if another node saved a state first.
+func (l *watchEventLog) save(prev *kvdb.KVPair, key string, deleted bool, object proto.Message) error {
+	b, err := protojson.Marshal(object)
+	if err != nil {
+		return err
+	}
+	value, err := json.Marshal(&watchRecord{
+		Key:     key,
+		Deleted: deleted,
+		Time:    time.Now(),
+		Object:  b,
+	})
+	if err != nil {
+		return err
+	}
+	var kvp *kvdb.KVPair
+	if prev == nil {
+		kvp, err = l.kv.Create(l.objectKey(key), value, 0)
+	} else {
+		prev.Value = value
+		kvp, err = l.kv.CompareAndSet(prev, kvdb.KVModifiedIndex, nil)
+	}
+	if err == kvdb.ErrExist || err == kvdb.ErrModified || err == kvdb.ErrValueMismatch {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	kvp.Value = value
+	return l.append(key, kvp)
+}
+
+// append logs the state of an object saved in kvp, and saves the revision
+// of its event in the state.
+func (l *watchEventLog) append(key string, kvp *kvdb.KVPair) error {
+	logKey := l.logKey(key, kvp.ModifiedIndex)
+	event, err := l.kv.Create(logKey, kvp.Value, 0)
+	if err == kvdb.ErrExist {
+		// Logged by another node
+		event, err = l.kv.Get(logKey)
+	}
+	if err != nil {
+		return err
+	}
+
+	var r watchRecord
+	if err := json.Unmarshal(kvp.Value, &r); err != nil {
+		return err
+	}
+	r.Revision = event.ModifiedIndex
+	if kvp.Value, err = json.Marshal(&r); err != nil {
+		return err
+	}
+	_, err = l.kv.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil)
+	if err == kvdb.ErrNotFound || err == kvdb.ErrModified || err == kvdb.ErrValueMismatch {
+		return nil
+	}
+	return err
+}
+
+// purge deletes the state of a deleted object.
+func (l *watchEventLog) purge(kvp *kvdb.KVPair) error {
+	_, err := l.kv.CompareAndDelete(kvp, kvdb.KVModifiedIndex)
+	if err == kvdb.ErrNotFound || err == kvdb.ErrModified || err == kvdb.ErrValueMismatch {
+		return nil
+	}
+	return err
+}
+
+// trim deletes the events logged for longer than watchEventRetention. The
+// revisions up to them are rejected first, so that no client resuming from
+// them misses one.
+func (l *watchEventLog) trim() error {
+	events, err := l.logged()
+	if err != nil {
+		return err
+	}
+	n := sort.Search(len(events), func(i int) bool {
+		return time.Since(events[i].time) <= watchEventRetention
+	})
+	if n == 0 {
+		return nil
+	}
+	if err := l.compact(events[n-1].revision); err != nil {
+		return err
+	}
+	for _, event := range events[:n] {
+		_, err := l.kv.CompareAndDelete(event.kvp, kvdb.KVModifiedIndex)
+		if err != nil && err != kvdb.ErrNotFound && err != kvdb.ErrModified &&
+			err != kvdb.ErrValueMismatch {
+			return err
+		}
+	}
+	return nil
+}
+
+// compact rejects the revisions before revision.
+func (l *watchEventLog) compact(revision uint64) error {
+	for {
+		compacted, kvp, err := l.compacted()
+		if err != nil || compacted >= revision {
+			return err
+		}
+		value := []byte(strconv.FormatUint(revision, 10))
+		if kvp == nil {
+			_, err = l.kv.Create(l.compactedKey(), value, 0)
+		} else {
+			kvp.Value = value
+			_, err = l.kv.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil)
+		}
+		if err != kvdb.ErrExist && err != kvdb.ErrModified && err != kvdb.ErrValueMismatch {
+			return err
+		}
+	}
+}
+
+// compacted returns the revision of the last event trimmed, before which the
+// revisions are rejected.
+func (l *watchEventLog) compacted() (uint64, *kvdb.KVPair, error) {
+	kvp, err := l.kv.Get(l.compactedKey())
+	if err == kvdb.ErrNotFound {
+		return 0, nil, nil
+	} else if err != nil {
+		return 0, nil, err
+	}
+	compacted, err := strconv.ParseUint(string(kvp.Value), 10, 64)
+	return compacted, kvp, err
+}
+
+// since returns the events logged after revision, sorted by revision, along
+// with the last revision of the log. A zero revision returns the latest
+// state of every existing object instead, at the revision of its last event.
+func (l *watchEventLog) since(revision uint64) ([]*watchEvent, uint64, error) {
+	logged, err := l.logged()
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "Failed to get the %v events: %v", l.eventType, err)
+	}
+	var last uint64
+	if len(logged) != 0 {
+		last = logged[len(logged)-1].revision
+	}
+	if revision == 0 {
+		events, err := l.current(logged)
+		return events, last, err
+	}
+
+	// Checked after loading the events, since the events up to the
+	// compacted revision are trimmed after it is saved
+	compacted, _, err := l.compacted()
+	if err != nil {
+		return nil, 0, status.Errorf(codes.Internal, "Failed to get the %v events: %v", l.eventType, err)
+	}
+	if revision < compacted {
+		return nil, 0, status.Errorf(codes.OutOfRange,
+			"revision %d of %v events has been compacted, watch again from revision 0",
+			revision, l.eventType)
+	}
+
+	events := make([]*watchEvent, 0, len(logged))
+	for _, event := range logged {
+		if event.revision > revision {
+			events = append(events, event.watchEvent)
+		}
+	}
+	return events, last, nil
+}
+
+// current returns the states of the existing objects, at the revision of
+// their last event in logged, sorted by revision. The states are loaded after
+// the log, so they include every change logged.
+func (l *watchEventLog) current(logged []*savedEvent) ([]*watchEvent, error) {
+	saved, err := l.load()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the %v events: %v", l.eventType, err)
+	}
+	revisions := make(map[string]uint64, len(saved))
+	for _, event := range logged {
+		revisions[event.key] = event.revision
+	}
+	events := make([]*watchEvent, 0, len(saved))
+	for key, state := range saved {
+		if state.deleted {
+			continue
+		}
+		event := *state.watchEvent
+		if revisions[key] > event.revision {
+			event.revision = revisions[key]
+		}
+		events = append(events, &event)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].revision < events[j].revision
+	})
+	return events, nil
+}
+
+// watch queues the events logged from now on until ctx is done.
+func (l *watchEventLog) watch(ctx context.Context) (*watchQueue, error) {
+	q := &watchQueue{changed: make(chan struct{}, 1)}
+	err := l.kv.WatchTree(l.logPrefix(), 0, nil,
+		func(prefix string, opaque interface{}, kvp *kvdb.KVPair, err error) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			q.push(kvp, err)
+			return err
+		})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to watch the %v events: %v", l.eventType, err)
+	}
+	return q, nil
+}
+
+// watchQueue holds the changes of an event log until they are sent.
+type watchQueue struct {
+	sync.Mutex
+	kvps []*kvdb.KVPair
+	err  error
+	// changed is signalled when a change or an error is queued.
+	changed chan struct{}
+}
+
+func (q *watchQueue) push(kvp *kvdb.KVPair, err error) {
+	q.Lock()
+	defer q.Unlock()
+	if err != nil {
+		q.err = err
+	} else {
+		q.kvps = append(q.kvps, kvp)
+	}
+	select {
+	case q.changed <- struct{}{}:
+	default:
+	}
+}
+
+func (q *watchQueue) pop() ([]*kvdb.KVPair, error) {
+	q.Lock()
+	defer q.Unlock()
+	kvps := q.kvps
+	q.kvps = nil
+	return kvps, q.err
+}
+
+// watchEventLogs are the event logs of the polled event types. They are
+// shared by the network and unix domain socket servers, so that only one
+// poller runs per event type.
+type watchEventLogs struct {
+	sync.Mutex
+	logs    map[string]*watchEventLog
+	ctx     context.Context
+	cancel  context.CancelFunc
+	pollers sync.WaitGroup
+}
+
+func newWatchEventLogs() *watchEventLogs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &watchEventLogs{
+		logs:   make(map[string]*watchEventLog),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// get returns the event log of the given event type. The log starts polling
+// on first use so that nothing is polled until a client watches it.
+func (e *watchEventLogs) get(eventType string, source *watchEventSource) (*watchEventLog, error) {
+	e.Lock()
+	defer e.Unlock()
+	if e.ctx.Err() != nil {
+		return nil, status.Error(codes.Unavailable, "Watcher has been stopped")
+	}
+	l, ok := e.logs[eventType]
+	if !ok {
+		kv := kvdb.Instance()
+		if kv == nil {
+			return nil, status.Errorf(codes.Unavailable, "Watching %v events needs kvdb", eventType)
+		}
+		l = newWatchEventLog(kv, eventType, source)
+		e.logs[eventType] = l
+		e.pollers.Add(1)
+		go func() {
+			defer e.pollers.Done()
+			l.run(e.ctx)
+		}()
+	}
+	return l, nil
+}
+
+// stop stops the pollers and waits for them to exit.
+func (e *watchEventLogs) stop() {
+	e.Lock()
+	e.cancel()
+	e.Unlock()
+	e.pollers.Wait()
+}
+
+func (w *WatcherServer) eventLog(eventType string, source *watchEventSource) (*watchEventLog, error) {
+	return w.eventLogs.get(eventType, source)
+}
+
+func (w *WatcherServer) stopEventWatchers() {
+	w.eventLogs.stop()
+}
+
+// streamEvents sends the events of l logged after revision until the stream
+// is done. send is expected to skip the events which do not match the filters
+// of the client.
+func (w *WatcherServer) streamEvents(
+	stream api.OpenStorageWatch_WatchServer,
+	l *watchEventLog,
+	revision uint64,
+	send func(event *watchEvent) error,
+) error {
+	ctx := stream.Context()
+	select {
+	case <-l.ready:
+	case <-ctx.Done():
+		return status.Error(codes.DeadlineExceeded,
+			"deadline is reached, server side func exiting")
+	}
+
+	// The new events are watched before the saved ones are read, so that
+	// none is missed in between
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	queue, err := l.watch(watchCtx)
+	if err != nil {
+		return err
+	}
+	events, last, err := l.since(revision)
+	if err != nil {
+		return err
+	}
+	for _, event := range events {
+		if err := send(event); err != nil {
+			logrus.Warnf("Error sending stream: %v", err)
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-queue.changed:
+		case <-ctx.Done():
+			return status.Error(codes.DeadlineExceeded,
+				"deadline is reached, server side func exiting")
+		}
+
+		kvps, err := queue.pop()
+		for _, kvp := range kvps {
+			// Trimmed events are skipped, as are the events already read
+			if kvp.Action == kvdb.KVDelete || kvp.Action == kvdb.KVExpire ||
+				kvp.ModifiedIndex <= last || kvp.ModifiedIndex <= revision {
+				continue
+			}
+			last = kvp.ModifiedIndex
+			event, derr := l.decode(kvp, true)
+			if derr != nil {
+				logrus.Warnf("Skipping %v event %s: %v", l.eventType, kvp.Key, derr)
+				continue
+			}
+			if err := send(event.watchEvent); err != nil {
+				logrus.Warnf("Error sending stream: %v", err)
+				return err
+			}
+		}
+		if err != nil {
+			return status.Errorf(codes.Unavailable,
+				"Watch of %v events stopped: %v, watch again from the last revision received",
+				l.eventType, err)
+		}
+	}
+}
+
+func (w *WatcherServer) nodeWatch(
+	req *api.SdkNodeWatchRequest,
+	stream api.OpenStorageWatch_WatchServer,
+) error {
+	if w.server.cluster() == nil {
+		return status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+
+	nodeIds := make(map[string]bool)
+	for _, id := range req.GetNodeIds() {
+		nodeIds[id] = true
+	}
+	l, err := w.eventLog(nodeEventType, &watchEventSource{
+		list: w.listNodes,
+		same: func(prev, cur proto.Message) bool {
+			p, c := prev.(*api.StorageNode), cur.(*api.StorageNode)
+			return p.GetStatus() == c.GetStatus() &&
+				(len(p.GetNodeLabels()) == 0 && len(c.GetNodeLabels()) == 0 ||
+					reflect.DeepEqual(p.GetNodeLabels(), c.GetNodeLabels()))
+		},
+		newObject: func() proto.Message { return &api.StorageNode{} },
+	})
+	if err != nil {
+		return err
+	}
+	return w.streamEvents(stream, l, req.GetStartRevision(), func(event *watchEvent) error {
+		node := event.object.(*api.StorageNode)
+		if len(nodeIds) != 0 && !nodeIds[node.GetId()] {
+			return nil
+		}
+		for k, v := range req.GetLabels() {
+			if node.GetNodeLabels()[k] != v {
+				return nil
+			}
+		}
+		return stream.Send(&api.SdkWatchResponse{
+			EventType: &api.SdkWatchResponse_NodeEvent{
+				NodeEvent: &api.SdkNodeWatchResponse{
+					Node:     node,
+					Deleted:  event.deleted,
+					Revision: event.revision,
+				},
+			},
+		})
+	})
+}
+
+func (w *WatcherServer) listNodes(ctx context.Context) (map[string]proto.Message, error) {
+	if w.server.cluster() == nil {
+		return nil, fmt.Errorf("cluster has not been initialized")
+	}
+	c, err := w.server.cluster().Enumerate()
+	if err != nil {
+		return nil, err
+	}
+	nodes := make(map[string]proto.Message, len(c.Nodes))
+	for _, node := range c.Nodes {
+		nodes[node.Id] = node.ToStorageNode()
+	}
+	return nodes, nil
+}
+
+func (w *WatcherServer) alertWatch(
+	req *api.SdkAlertWatchRequest,
+	stream api.OpenStorageWatch_WatchServer,
+) error {
+	if w.server.alert() == nil {
+		return status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+
+	minSeverity := alerts.NewMinSeverityFilter(req.GetMinSeverity())
+	l, err := w.eventLog(alertEventType, &watchEventSource{
+		list: w.listAlerts,
+		same: func(prev, cur proto.Message) bool {
+			p, c := prev.(*api.Alert), cur.(*api.Alert)
+			return p.GetCleared() == c.GetCleared() &&
+				p.GetCount() == c.GetCount() &&
+				p.GetSeverity() == c.GetSeverity()
+		},
+		newObject:  func() proto.Message { return &api.Alert{} },
+		kvdbPrefix: alerts.KvdbPrefix,
+	})
+	if err != nil {
+		return err
+	}
+	return w.streamEvents(stream, l, req.GetStartRevision(), func(event *watchEvent) error {
+		alert := event.object.(*api.Alert)
+		if req.GetResourceType() != api.ResourceType_RESOURCE_TYPE_NONE &&
+			alert.GetResource() != req.GetResourceType() {
+			return nil
+		}
+		if len(req.GetResourceId()) != 0 && alert.GetResourceId() != req.GetResourceId() {
+			return nil
+		}
+		if ok, err := minSeverity.Match(alert); err != nil || !ok {
+			return nil
+		}
+		if event.deleted {
+			// Alerts are removed once cleared and expired
+			alert = proto.Clone(alert).(*api.Alert)
+			alert.Cleared = true
+		}
+		return stream.Send(&api.SdkWatchResponse{
+			EventType: &api.SdkWatchResponse_AlertEvent{
+				AlertEvent: &api.SdkAlertWatchResponse{
+					Alert:    alert,
+					Revision: event.revision,
+				},
+			},
+		})
+	})
+}
+
+func (w *WatcherServer) listAlerts(ctx context.Context) (map[string]proto.Message, error) {
+	if w.server.alert() == nil {
+		return nil, fmt.Errorf("alerts have not been initialized")
+	}
+	list, err := w.server.alert().Enumerate()
+	if err != nil {
+		return nil, err
+	}
+	objects := make(map[string]proto.Message, len(list))
+	for _, alert := range list {
+		key := fmt.Sprintf("%v/%s/%d", alert.GetResource(), alert.GetResourceId(), alert.GetAlertType())
+		objects[key] = alert
+	}
+	return objects, nil
+}
+
+func (w *WatcherServer) cloudBackupWatch(
+	req *api.SdkCloudBackupWatchRequest,
+	stream api.OpenStorageWatch_WatchServer,
+) error {
+	ctx := stream.Context()
+	if w.server.driver(ctx) == nil {
+		return status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+
+	volumeIds := make(map[string]bool)
+	for _, id := range req.GetVolumeIds() {
+		volumeIds[id] = true
+	}
+	l, err := w.eventLog(cloudBackupEventType, &watchEventSource{
+		list: w.listCloudBackups,
+		same: func(prev, cur proto.Message) bool {
+			p, c := prev.(*api.SdkCloudBackupStatus), cur.(*api.SdkCloudBackupStatus)
+			return p.GetStatus() == c.GetStatus() && p.GetOptype() == c.GetOptype()
+		},
+		newObject: func() proto.Message { return &api.SdkCloudBackupStatus{} },
+	})
+	if err != nil {
+		return err
+	}
+	return w.streamEvents(stream, l, req.GetStartRevision(), func(event *watchEvent) error {
+		sts := event.object.(*api.SdkCloudBackupStatus)
+		if len(volumeIds) != 0 && !volumeIds[sts.GetSrcVolumeId()] {
+			return nil
+		}
+		if req.GetOpType() != api.SdkCloudBackupOpType_SdkCloudBackupOpTypeUnknown &&
+			sts.GetOptype() != req.GetOpType() {
+			return nil
+		}
+		if len(sts.GetSrcVolumeId()) != 0 {
+			if err := checkAccessFromDriverForVolumeIds(ctx, w.server.driver(ctx),
+				[]string{sts.GetSrcVolumeId()}, api.Ownership_Read); err != nil {
+				return nil
+			}
+		}
+		return stream.Send(&api.SdkWatchResponse{
+			EventType: &api.SdkWatchResponse_CloudBackupEvent{
+				CloudBackupEvent: &api.SdkCloudBackupWatchResponse{
+					TaskId:   event.key,
+					Status:   sts,
+					Deleted:  event.deleted,
+					Revision: event.revision,
+				},
+			},
+		})
+	})
+}
+
+func (w *WatcherServer) listCloudBackups(ctx context.Context) (map[string]proto.Message, error) {
+	if w.server.driver(ctx) == nil {
+		return nil, fmt.Errorf("driver has not been initialized")
+	}
+	r, err := w.server.driver(ctx).CloudBackupStatus(&api.CloudBackupStatusRequest{})
+	if err != nil {
+		return nil, err
+	}
+	statuses := make(map[string]proto.Message, len(r.Statuses))
+	for id, sts := range r.ToSdkCloudBackupStatusResponse().GetStatuses() {
+		statuses[id] = sts
+	}
+	return statuses, nil
+}
+
+func (w *WatcherServer) jobWatch(
+	req *api.SdkJobWatchRequest,
+	stream api.OpenStorageWatch_WatchServer,
+) error {
+	if w.server.cluster() == nil {
+		return status.Error(codes.Unavailable, "Resource has not been initialized")
+	}
+
+	jobIds := make(map[string]bool)
+	for _, id := range req.GetJobIds() {
+		jobIds[id] = true
+	}
+	l, err := w.eventLog(jobEventType, &watchEventSource{
+		list: w.listJobs,
+		same: func(prev, cur proto.Message) bool {
+			return prev.(*api.Job).GetState() == cur.(*api.Job).GetState()
+		},
+		newObject:  func() proto.Message { return &api.Job{} },
+		kvdbPrefix: job.RecordsKvdbPrefix,
+	})
+	if err != nil {
+		return err
+	}
+	return w.streamEvents(stream, l, req.GetStartRevision(), func(event *watchEvent) error {
+		job := event.object.(*api.Job)
+		if len(jobIds) != 0 && !jobIds[job.GetId()] {
+			return nil
+		}
+		if req.GetType() != api.Job_UNSPECIFIED_TYPE && job.GetType() != req.GetType() {
+			return nil
+		}
+		return stream.Send(&api.SdkWatchResponse{
+			EventType: &api.SdkWatchResponse_JobEvent{
+				JobEvent: &api.SdkJobWatchResponse{
+					Job:      job,
+					Deleted:  event.deleted,
+					Revision: event.revision,
+				},
+			},
+		})
+	})
+}
+
+func (w *WatcherServer) listJobs(ctx context.Context) (map[string]proto.Message, error) {
+	if w.server.cluster() == nil {
+		return nil, fmt.Errorf("cluster has not been initialized")
+	}
+	r, err := w.server.cluster().EnumerateJobs(ctx, &api.SdkEnumerateJobsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	jobs := make(map[string]proto.Message, len(r.GetJobs()))
+	for _, job := range r.GetJobs() {
+		jobs[job.GetId()] = job
+	}
+	return jobs, nil
+}
@@ -2,11 +2,21 @@ package sdk
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/golang/mock/gomock"
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestSdkVolumeWatch(t *testing.T) {
@@ -56,3 +66,369 @@ func TestSdkVolumeWatch(t *testing.T) {
 	v := r.GetVolumeEvent()
 	assert.Equal(t, v.GetName(), "myid")
 }
+
+func TestWatchEventLog(t *testing.T) {
+	kv, err := kvdb.New(mem.Name, "watch", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	source := &watchEventSource{
+		same: func(prev, cur proto.Message) bool {
+			return prev.(*api.Job).GetState() == cur.(*api.Job).GetState()
+		},
+		newObject: func() proto.Message { return &api.Job{} },
+	}
+	l := newWatchEventLog(kv, "Test", source)
+	running := &api.Job{Id: "a", State: api.Job_RUNNING}
+	done := &api.Job{Id: "a", State: api.Job_DONE}
+
+	require.NoError(t, l.update(map[string]proto.Message{"a": running}))
+	events, first, err := l.since(0)
+	assert.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, first, events[0].revision)
+
+	require.NoError(t, l.update(map[string]proto.Message{"a": running, "b": running}))
+	require.NoError(t, l.update(map[string]proto.Message{"a": done, "b": running}))
+	require.NoError(t, l.update(map[string]proto.Message{"a": done}))
+
+	// Revision 0 returns the latest state of the existing objects
+	events, last, err := l.since(0)
+	assert.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "a", events[0].key)
+	assert.Equal(t, api.Job_DONE, events[0].object.(*api.Job).GetState())
+	changed := events[0].revision
+	assert.True(t, last > changed)
+
+	// A client resuming from a revision gets every event logged after it
+	events, _, err = l.since(first)
+	assert.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "b", events[0].key)
+	assert.False(t, events[0].deleted)
+	assert.Equal(t, "a", events[1].key)
+	assert.Equal(t, changed, events[1].revision)
+	assert.Equal(t, "b", events[2].key)
+	assert.True(t, events[2].deleted)
+	assert.Equal(t, last, events[2].revision)
+
+	events, _, err = l.since(last)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+
+	// Revisions are valid on the other nodes and after a restart, and a
+	// change seen by several nodes is logged once
+	other := newWatchEventLog(kv, "Test", source)
+	events, _, err = other.since(first)
+	assert.NoError(t, err)
+	assert.Len(t, events, 3)
+	require.NoError(t, other.update(map[string]proto.Message{"a": done}))
+	_, again, err := other.since(0)
+	assert.NoError(t, err)
+	assert.Equal(t, last, again)
+
+	// A state saved by a node which stopped before logging it is logged
+	value, err := json.Marshal(&watchRecord{Key: "c", Time: time.Now(), Object: []byte("{}")})
+	require.NoError(t, err)
+	_, err = kv.Create(l.objectKey("c"), value, 0)
+	require.NoError(t, err)
+	require.NoError(t, other.update(map[string]proto.Message{"a": done, "c": &api.Job{}}))
+	events, _, err = l.since(last)
+	assert.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "c", events[0].key)
+	last = events[0].revision
+
+	// Events are trimmed after the retention, after which the revisions
+	// before them are rejected
+	prev := watchEventRetention
+	watchEventRetention = 0
+	defer func() { watchEventRetention = prev }()
+	require.NoError(t, l.trim())
+	_, _, err = l.since(changed)
+	assert.Equal(t, codes.OutOfRange, status.Code(err))
+	events, _, err = l.since(last)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+	events, _, err = l.since(0)
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestWatchEventLogsStop(t *testing.T) {
+	setWatchEventPollInterval(t, 10*time.Millisecond)
+	kv, err := kvdb.New(mem.Name, "watch", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	require.NoError(t, kvdb.SetInstance(kv))
+	e := newWatchEventLogs()
+	source := &watchEventSource{
+		list:      func(ctx context.Context) (map[string]proto.Message, error) { return nil, nil },
+		same:      func(prev, cur proto.Message) bool { return true },
+		newObject: func() proto.Message { return &api.Job{} },
+	}
+
+	l, err := e.get("Test", source)
+	assert.NoError(t, err)
+	again, err := e.get("Test", source)
+	assert.NoError(t, err)
+	assert.True(t, l == again)
+
+	// Stop waits for the pollers, after which no log can be watched
+	e.stop()
+	_, err = e.get("Test", source)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func setWatchEventPollInterval(t *testing.T, interval time.Duration) {
+	prev := watchEventPollInterval
+	watchEventPollInterval = interval
+	t.Cleanup(func() { watchEventPollInterval = prev })
+}
+
+func TestSdkNodeWatch(t *testing.T) {
+	setWatchEventPollInterval(t, 10*time.Millisecond)
+	s := newTestServer(t)
+	defer s.Stop()
+
+	var lock sync.Mutex
+	cluster := api.Cluster{
+		Nodes: []*api.Node{
+			{Id: "node1", Status: api.Status_STATUS_OK, NodeLabels: map[string]string{"zone": "a"}},
+			{Id: "node2", Status: api.Status_STATUS_OK, NodeLabels: map[string]string{"zone": "b"}},
+		},
+	}
+	setStatus := func(status api.Status) {
+		lock.Lock()
+		defer lock.Unlock()
+		cluster.Nodes[0].Status = status
+		cluster.Nodes[1].Status = status
+	}
+	s.MockCluster().
+		EXPECT().
+		Enumerate().
+		DoAndReturn(func() (api.Cluster, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			c := cluster
+			c.Nodes = make([]*api.Node, len(cluster.Nodes))
+			for i, n := range cluster.Nodes {
+				copied := *n
+				c.Nodes[i] = &copied
+			}
+			return c, nil
+		}).
+		AnyTimes()
+
+	c := api.NewOpenStorageWatchClient(s.Conn())
+	ctx, cancel := context.WithCancel(context.Background())
+	client, err := c.Watch(ctx, &api.SdkWatchRequest{
+		EventType: &api.SdkWatchRequest_NodeEvent{
+			NodeEvent: &api.SdkNodeWatchRequest{
+				Labels: map[string]string{"zone": "a"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	r, err := client.Recv()
+	assert.NoError(t, err)
+	node := r.GetNodeEvent()
+	assert.Equal(t, "node1", node.GetNode().GetId())
+	assert.Equal(t, api.Status_STATUS_OK, node.GetNode().GetStatus())
+
+	setStatus(api.Status_STATUS_OFFLINE)
+	r, err = client.Recv()
+	assert.NoError(t, err)
+	node = r.GetNodeEvent()
+	assert.Equal(t, "node1", node.GetNode().GetId())
+	assert.Equal(t, api.Status_STATUS_OFFLINE, node.GetNode().GetStatus())
+	cancel()
+
+	// Resume after the last received revision and get the missed events
+	setStatus(api.Status_STATUS_OK)
+	client, err = c.Watch(context.Background(), &api.SdkWatchRequest{
+		EventType: &api.SdkWatchRequest_NodeEvent{
+			NodeEvent: &api.SdkNodeWatchRequest{
+				NodeIds:       []string{"node2"},
+				StartRevision: node.GetRevision(),
+			},
+		},
+	})
+	assert.NoError(t, err)
+	for _, expected := range []api.Status{api.Status_STATUS_OFFLINE, api.Status_STATUS_OK} {
+		r, err = client.Recv()
+		assert.NoError(t, err)
+		node = r.GetNodeEvent()
+		assert.Equal(t, "node2", node.GetNode().GetId())
+		assert.Equal(t, expected, node.GetNode().GetStatus())
+	}
+}
+
+func TestSdkAlertWatch(t *testing.T) {
+	setWatchEventPollInterval(t, 10*time.Millisecond)
+	s := newTestServer(t)
+	defer s.Stop()
+
+	var lock sync.Mutex
+	alerts := []*api.Alert{
+		{Resource: api.ResourceType_RESOURCE_TYPE_VOLUME, ResourceId: "vol1", AlertType: 1,
+			Severity: api.SeverityType_SEVERITY_TYPE_ALARM, Count: 1},
+		{Resource: api.ResourceType_RESOURCE_TYPE_NODE, ResourceId: "node1", AlertType: 1,
+			Severity: api.SeverityType_SEVERITY_TYPE_ALARM, Count: 1},
+	}
+	setAlerts := func(a []*api.Alert) {
+		lock.Lock()
+		defer lock.Unlock()
+		alerts = a
+	}
+	s.MockFilterDeleter().
+		EXPECT().
+		Enumerate().
+		DoAndReturn(func(filters ...interface{}) ([]*api.Alert, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return alerts, nil
+		}).
+		AnyTimes()
+
+	c := api.NewOpenStorageWatchClient(s.Conn())
+	client, err := c.Watch(context.Background(), &api.SdkWatchRequest{
+		EventType: &api.SdkWatchRequest_AlertEvent{
+			AlertEvent: &api.SdkAlertWatchRequest{
+				ResourceType: api.ResourceType_RESOURCE_TYPE_VOLUME,
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	r, err := client.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "vol1", r.GetAlertEvent().GetAlert().GetResourceId())
+	assert.False(t, r.GetAlertEvent().GetAlert().GetCleared())
+
+	// Deleted alerts are returned as cleared
+	setAlerts(alerts[1:])
+	r, err = client.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "vol1", r.GetAlertEvent().GetAlert().GetResourceId())
+	assert.True(t, r.GetAlertEvent().GetAlert().GetCleared())
+}
+
+func TestSdkCloudBackupWatch(t *testing.T) {
+	setWatchEventPollInterval(t, 10*time.Millisecond)
+	s := newTestServer(t)
+	defer s.Stop()
+
+	var lock sync.Mutex
+	sts := api.CloudBackupStatus{
+		ID:          "backup1",
+		OpType:      api.CloudBackupOp,
+		Status:      api.CloudBackupStatusActive,
+		SrcVolumeID: "vol1",
+	}
+	s.MockDriver().
+		EXPECT().
+		CloudBackupStatus(&api.CloudBackupStatusRequest{}).
+		DoAndReturn(func(req *api.CloudBackupStatusRequest) (*api.CloudBackupStatusResponse, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			return &api.CloudBackupStatusResponse{
+				Statuses: map[string]api.CloudBackupStatus{"task1": sts},
+			}, nil
+		}).
+		AnyTimes()
+
+	c := api.NewOpenStorageWatchClient(s.Conn())
+	client, err := c.Watch(context.Background(), &api.SdkWatchRequest{
+		EventType: &api.SdkWatchRequest_CloudBackupEvent{
+			CloudBackupEvent: &api.SdkCloudBackupWatchRequest{
+				VolumeIds: []string{"vol1"},
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	r, err := client.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "task1", r.GetCloudBackupEvent().GetTaskId())
+	assert.Equal(t, api.SdkCloudBackupStatusType_SdkCloudBackupStatusTypeActive,
+		r.GetCloudBackupEvent().GetStatus().GetStatus())
+	revision := r.GetCloudBackupEvent().GetRevision()
+
+	// Progress alone does not raise an event
+	lock.Lock()
+	sts.BytesDone = 1024
+	lock.Unlock()
+	time.Sleep(5 * watchEventPollInterval)
+	lock.Lock()
+	sts.Status = api.CloudBackupStatusDone
+	lock.Unlock()
+
+	r, err = client.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, api.SdkCloudBackupStatusType_SdkCloudBackupStatusTypeDone,
+		r.GetCloudBackupEvent().GetStatus().GetStatus())
+	assert.Greater(t, r.GetCloudBackupEvent().GetRevision(), revision)
+
+	// A client resuming after the first revision gets the new status
+	client, err = c.Watch(context.Background(), &api.SdkWatchRequest{
+		EventType: &api.SdkWatchRequest_CloudBackupEvent{
+			CloudBackupEvent: &api.SdkCloudBackupWatchRequest{
+				StartRevision: revision,
+			},
+		},
+	})
+	assert.NoError(t, err)
+	r, err = client.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, api.SdkCloudBackupStatusType_SdkCloudBackupStatusTypeDone,
+		r.GetCloudBackupEvent().GetStatus().GetStatus())
+}
+
+func TestSdkJobWatch(t *testing.T) {
+	setWatchEventPollInterval(t, 10*time.Millisecond)
+	s := newTestServer(t)
+	defer s.Stop()
+
+	var lock sync.Mutex
+	jobs := []*api.Job{
+		{Id: "job1", Type: api.Job_DRAIN_ATTACHMENTS, State: api.Job_RUNNING},
+		{Id: "job2", Type: api.Job_DEFRAG, State: api.Job_RUNNING},
+	}
+	s.MockCluster().
+		EXPECT().
+		EnumerateJobs(gomock.Any(), &api.SdkEnumerateJobsRequest{}).
+		DoAndReturn(func(ctx context.Context, req *api.SdkEnumerateJobsRequest) (*api.SdkEnumerateJobsResponse, error) {
+			lock.Lock()
+			defer lock.Unlock()
+			resp := &api.SdkEnumerateJobsResponse{}
+			for _, job := range jobs {
+				resp.Jobs = append(resp.Jobs, &api.Job{Id: job.Id, Type: job.Type, State: job.State})
+			}
+			return resp, nil
+		}).
+		AnyTimes()
+
+	c := api.NewOpenStorageWatchClient(s.Conn())
+	client, err := c.Watch(context.Background(), &api.SdkWatchRequest{
+		EventType: &api.SdkWatchRequest_JobEvent{
+			JobEvent: &api.SdkJobWatchRequest{
+				Type: api.Job_DEFRAG,
+			},
+		},
+	})
+	assert.NoError(t, err)
+
+	r, err := client.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "job2", r.GetJobEvent().GetJob().GetId())
+
+	lock.Lock()
+	jobs[0].State = api.Job_DONE
+	jobs = jobs[:1]
+	lock.Unlock()
+
+	r, err = client.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, "job2", r.GetJobEvent().GetJob().GetId())
+	assert.True(t, r.GetJobEvent().GetDeleted())
+}
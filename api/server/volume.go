@@ -1971,7 +1971,7 @@ func (vd *volAPI) Routes() []*Route {
 	routes = append(routes, vd.backupRoutes()...)
 	routes = append(routes, vd.credsRoutes()...)
 	routes = append(routes, vd.migrateRoutes()...)
-	return routes
+	return auditRoutes(routes)
 }
 
 func (vd *volAPI) SetupRoutesWithAuth(
@@ -1994,21 +1994,21 @@ func (vd *volAPI) SetupRoutesWithAuth(
 	nCreate.Use(negroni.HandlerFunc(authM.createWithAuth))
 	createRoute := vd.volumeCreateRoute()
 	nCreate.UseHandlerFunc(createRoute.fn)
-	router.Methods(createRoute.verb).Path(createRoute.path).Handler(nCreate)
+	router.Methods(createRoute.verb).Path(createRoute.path).Handler(auditRoute(createRoute, nCreate))
 
 	// Setup middleware for Delete
 	nDelete := negroni.New()
 	nDelete.Use(negroni.HandlerFunc(authM.deleteWithAuth))
 	deleteRoute := vd.volumeDeleteRoute()
 	nDelete.UseHandlerFunc(deleteRoute.fn)
-	router.Methods(deleteRoute.verb).Path(deleteRoute.path).Handler(nDelete)
+	router.Methods(deleteRoute.verb).Path(deleteRoute.path).Handler(auditRoute(deleteRoute, nDelete))
 
 	// Setup middleware for Set
 	nSet := negroni.New()
 	nSet.Use(negroni.HandlerFunc(authM.setWithAuth))
 	setRoute := vd.volumeSetRoute()
 	nSet.UseHandlerFunc(setRoute.fn)
-	router.Methods(setRoute.verb).Path(setRoute.path).Handler(nSet)
+	router.Methods(setRoute.verb).Path(setRoute.path).Handler(auditRoute(setRoute, nSet))
 
 	// Setup middleware for Inspect
 	nInspect := negroni.New()
@@ -2029,7 +2029,7 @@ func (vd *volAPI) SetupRoutesWithAuth(
 	routes = append(routes, vd.snapRoutes()...)
 	routes = append(routes, vd.backupRoutes()...)
 	routes = append(routes, vd.migrateRoutes()...)
-	for _, v := range routes {
+	for _, v := range auditRoutes(routes) {
 		router.Methods(v.verb).Path(v.path).HandlerFunc(v.fn)
 	}
 
@@ -2041,7 +2041,7 @@ func (vd *volAPI) decorateCredRoutes(authenticators map[string]auth.Authenticato
 	credRoutes := vd.credsRoutes()
 	securityMiddleware := newSecurityMiddleware(authenticators)
 	for _, route := range credRoutes {
-		router.Methods(route.GetVerb()).Path(route.GetPath()).HandlerFunc(securityMiddleware(route.fn))
+		router.Methods(route.GetVerb()).Path(route.GetPath()).Handler(auditRoute(route, securityMiddleware(route.fn)))
 	}
 
 	return router
@@ -2105,21 +2105,21 @@ func GetVolumeAPIRoutesWithAuth(
 	nCreate.Use(negroni.HandlerFunc(authM.createWithAuth))
 	createRoute := vd.volumeCreateRoute()
 	nCreate.UseHandlerFunc(serverRegisterRoute(createRoute.fn, preRouteCheckFn))
-	router.Methods(createRoute.verb).Path(createRoute.path).Handler(nCreate)
+	router.Methods(createRoute.verb).Path(createRoute.path).Handler(auditRoute(createRoute, nCreate))
 
 	// Setup middleware for Delete
 	nDelete := negroni.New()
 	nDelete.Use(negroni.HandlerFunc(authM.deleteWithAuth))
 	deleteRoute := vd.volumeDeleteRoute()
 	nDelete.UseHandlerFunc(serverRegisterRoute(deleteRoute.fn, preRouteCheckFn))
-	router.Methods(deleteRoute.verb).Path(deleteRoute.path).Handler(nDelete)
+	router.Methods(deleteRoute.verb).Path(deleteRoute.path).Handler(auditRoute(deleteRoute, nDelete))
 
 	// Setup middleware for Set
 	nSet := negroni.New()
 	nSet.Use(negroni.HandlerFunc(authM.setWithAuth))
 	setRoute := vd.volumeSetRoute()
 	nSet.UseHandlerFunc(serverRegisterRoute(setRoute.fn, preRouteCheckFn))
-	router.Methods(setRoute.verb).Path(setRoute.path).Handler(nSet)
+	router.Methods(setRoute.verb).Path(setRoute.path).Handler(auditRoute(setRoute, nSet))
 
 	// Setup middleware for Inspect
 	nInspect := negroni.New()
@@ -2140,7 +2140,7 @@ func GetVolumeAPIRoutesWithAuth(
 	routes = append(routes, vd.snapRoutes()...)
 	routes = append(routes, vd.backupRoutes()...)
 	routes = append(routes, vd.migrateRoutes()...)
-	for _, v := range routes {
+	for _, v := range auditRoutes(routes) {
 		router.Methods(v.verb).Path(v.path).HandlerFunc(serverRegisterRoute(v.fn, preRouteCheckFn))
 	}
 	return router, nil
@@ -1018,6 +1018,10 @@ func TestVolumeAttachSuccess(t *testing.T) {
 	_, err = driverclient.Attach(context.TODO(), id, map[string]string{})
 	assert.Nil(t, err)
 
+	// Detach
+	err = driverclient.Detach(context.TODO(), id, map[string]string{})
+	assert.Nil(t, err)
+
 	// Assert volume information is correct
 	volumes := api.NewOpenStorageVolumeClient(testVolDriver.Conn())
 	ctx, err := contextWithToken(context.Background(), "test", "system.admin", testSharedSecret)
@@ -1177,6 +1181,10 @@ func TestVolumeDetachFailed(t *testing.T) {
 	// Detach must not fail on non-existing volume
 	assert.Nil(t, res)
 
+	// Detach
+	err = driverclient.Detach(context.TODO(), id, map[string]string{})
+	assert.Nil(t, err)
+
 	// Assert volume information is correct
 	volumes := api.NewOpenStorageVolumeClient(testVolDriver.Conn())
 	ctx, err := contextWithToken(context.Background(), "test", "system.admin", testSharedSecret)
@@ -1224,9 +1232,21 @@ func TestVolumeMountSuccess(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotEmpty(t, id)
 
+	// Attach
+	_, err = driverclient.Attach(context.TODO(), id, map[string]string{})
+	assert.Nil(t, err)
+
 	res := driverclient.Mount(context.TODO(), id, "/mnt", map[string]string{})
 	assert.Nil(t, res)
 
+	// Unmount
+	err = driverclient.Unmount(context.TODO(), id, "/mnt", map[string]string{})
+	assert.Nil(t, err)
+
+	// Detach
+	err = driverclient.Detach(context.TODO(), id, map[string]string{})
+	assert.Nil(t, err)
+
 	// Assert volume information is correct
 	volumes := api.NewOpenStorageVolumeClient(testVolDriver.Conn())
 	ctx, err := contextWithToken(context.Background(), "test", "system.admin", testSharedSecret)
@@ -1425,6 +1445,10 @@ func TestVolumeUnmountSuccess(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotEmpty(t, id)
 
+	// Attach
+	_, err = driverclient.Attach(context.TODO(), id, map[string]string{})
+	assert.Nil(t, err)
+
 	// Mount
 	res := driverclient.Mount(context.TODO(), id, "/mnt", map[string]string{})
 	assert.Nil(t, res)
@@ -1433,6 +1457,10 @@ func TestVolumeUnmountSuccess(t *testing.T) {
 	res2 := driverclient.Unmount(context.TODO(), id, "/mnt", map[string]string{})
 	assert.Nil(t, res2)
 
+	// Detach
+	err = driverclient.Detach(context.TODO(), id, map[string]string{})
+	assert.Nil(t, err)
+
 	// Assert volume information is correct
 	volumes := api.NewOpenStorageVolumeClient(testVolDriver.Conn())
 	ctx, err := contextWithToken(context.Background(), "test", "system.admin", testSharedSecret)
@@ -1480,6 +1508,10 @@ func TestVolumeUnmountFailed(t *testing.T) {
 	assert.Nil(t, err)
 	assert.NotEmpty(t, id)
 
+	// Attach
+	_, err = driverclient.Attach(context.TODO(), id, map[string]string{})
+	assert.Nil(t, err)
+
 	// Mount
 	res := driverclient.Mount(context.TODO(), id, "/mnt", map[string]string{})
 	assert.Nil(t, res)
@@ -1488,6 +1520,14 @@ func TestVolumeUnmountFailed(t *testing.T) {
 	err = driverclient.Unmount(context.TODO(), "doesnotexist", "/mnt", map[string]string{})
 	assert.NotNil(t, err)
 
+	// Unmount
+	err = driverclient.Unmount(context.TODO(), id, "/mnt", map[string]string{})
+	assert.Nil(t, err)
+
+	// Detach
+	err = driverclient.Detach(context.TODO(), id, map[string]string{})
+	assert.Nil(t, err)
+
 	// Assert volume information is correct
 	volumes := api.NewOpenStorageVolumeClient(testVolDriver.Conn())
 	ctx, err := contextWithToken(context.Background(), "test", "system.admin", testSharedSecret)
@@ -15,6 +15,7 @@ import (
 	"github.com/libopenstorage/openstorage/pkg/clusterdomain"
 	"github.com/libopenstorage/openstorage/pkg/defrag"
 	"github.com/libopenstorage/openstorage/pkg/diags"
+	"github.com/libopenstorage/openstorage/pkg/fstrim"
 	"github.com/libopenstorage/openstorage/pkg/job"
 	"github.com/libopenstorage/openstorage/pkg/nodedrain"
 	"github.com/libopenstorage/openstorage/pkg/schedule"
@@ -65,6 +66,9 @@ type ClusterServerConfiguration struct {
 	ConfigDiagsProvider diags.Provider
 	// holds the implementation to the SDK OpenStorageDefrag interface
 	ConfigDefragProvider defrag.Provider
+	// holds the implementation to the filesystem trim schedules of the SDK
+	// OpenStorageFilesystemTrim interface
+	ConfigFilesystemTrimProvider fstrim.Provider
 }
 
 // NodeEntry is used to discover other nodes in the cluster
@@ -87,6 +91,10 @@ type NodeEntry struct {
 
 	// Determine if the node is secure with authentication and authorization
 	SecurityStatus api.StorageNode_SecurityStatus
+
+	// AttachmentsCordoned is set while new volume attachments are disabled
+	// on the node, such as when it is drained
+	AttachmentsCordoned bool
 }
 
 // ClusterInfo is the basic info about the cluster and its nodes
@@ -412,6 +420,7 @@ type Cluster interface {
 	nodedrain.Provider
 	diags.Provider
 	defrag.Provider
+	fstrim.Provider
 }
 
 // NullClusterListener is a NULL implementation of ClusterListener functions
@@ -10,6 +10,7 @@ import (
 	"github.com/libopenstorage/openstorage/pkg/clusterdomain"
 	"github.com/libopenstorage/openstorage/pkg/defrag"
 	"github.com/libopenstorage/openstorage/pkg/diags"
+	"github.com/libopenstorage/openstorage/pkg/fstrim"
 	"github.com/libopenstorage/openstorage/pkg/job"
 	"github.com/libopenstorage/openstorage/pkg/nodedrain"
 	"github.com/libopenstorage/openstorage/pkg/schedule"
@@ -38,6 +39,7 @@ type NullClusterManager struct {
 	nodedrain.UnsupportedNodeDrainProvider
 	diags.UnsupportedDiagsProvider
 	defrag.UnsupportedDefragProvider
+	fstrim.UnsupportedFilesystemTrimProvider
 }
 
 func NewDefaultClusterManager() Cluster {
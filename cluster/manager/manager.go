@@ -34,6 +34,7 @@ import (
 	"github.com/libopenstorage/openstorage/pkg/dbg"
 	"github.com/libopenstorage/openstorage/pkg/defrag"
 	"github.com/libopenstorage/openstorage/pkg/diags"
+	"github.com/libopenstorage/openstorage/pkg/fstrim"
 	"github.com/libopenstorage/openstorage/pkg/job"
 	"github.com/libopenstorage/openstorage/pkg/nodedrain"
 	"github.com/libopenstorage/openstorage/pkg/schedule"
@@ -96,6 +97,7 @@ type ClusterManager struct {
 	nodeDrainProvider    nodedrain.Provider
 	diagsProvider        diags.Provider
 	defragProvider       defrag.Provider
+	fstrimProvider       fstrim.Provider
 	snapshotPrefixes     []string
 	selfClusterDomain    string
 	// kvdbWatchIndex stores the kvdb index to start the watch
@@ -373,6 +375,35 @@ func (c *ClusterManager) UpdateSchedulerNodeName(schedulerNodeName string) error
 	return updateDB("update-scheduler-name", c.selfNode.Id, updateCallbackFn)
 }
 
+// AttachmentsCordoned returns true if new volume attachments are disabled on
+// the node, as saved on its entry in the cluster database
+func (c *ClusterManager) AttachmentsCordoned(nodeID string) (bool, error) {
+	db, _, err := readClusterInfo()
+	if err != nil {
+		return false, err
+	}
+	return db.NodeEntries[nodeID].AttachmentsCordoned, nil
+}
+
+// SetAttachmentsCordoned disables or re-enables new volume attachments on the
+// node by saving the cordon on its entry in the cluster database
+func (c *ClusterManager) SetAttachmentsCordoned(nodeID string, cordoned bool) error {
+	updateCallbackFn := func(db *cluster.ClusterInfo) (bool, error) {
+		nodeEntry, ok := db.NodeEntries[nodeID]
+		if !ok {
+			return false, fmt.Errorf("Node %s not found in cluster database", nodeID)
+		}
+		if nodeEntry.AttachmentsCordoned == cordoned {
+			return false, nil
+		}
+		nodeEntry.AttachmentsCordoned = cordoned
+		db.NodeEntries[nodeID] = nodeEntry
+		return true, nil
+	}
+
+	return updateDB("set-attachments-cordoned", c.selfNode.Id, updateCallbackFn)
+}
+
 // GetData returns self node's data
 func (c *ClusterManager) GetData() (map[string]*api.Node, error) {
 	nodes := make(map[string]*api.Node)
@@ -581,7 +612,7 @@ func (c *ClusterManager) getLatestNodeConfig(nodeId string) *cluster.NodeEntry {
 }
 
 func (c *ClusterManager) initNode(db *cluster.ClusterInfo) (*api.Node, bool) {
-	_, exists := db.NodeEntries[c.selfNode.Id]
+	prevEntry, exists := db.NodeEntries[c.selfNode.Id]
 
 	// Add us into the database.
 	labels := make(map[string]string)
@@ -601,6 +632,8 @@ func (c *ClusterManager) initNode(db *cluster.ClusterInfo) (*api.Node, bool) {
 		HWType:            c.config.HWType,
 		SecurityStatus:    c.selfNode.SecurityStatus,
 	}
+	// The node is still cordoned after a restart
+	nodeEntry.AttachmentsCordoned = prevEntry.AttachmentsCordoned
 
 	db.NodeEntries[c.config.NodeId] = nodeEntry
 
@@ -1435,6 +1468,12 @@ func (c *ClusterManager) setupManagers(config *cluster.ClusterServerConfiguratio
 	} else {
 		c.defragProvider = config.ConfigDefragProvider
 	}
+
+	if config.ConfigFilesystemTrimProvider == nil {
+		c.fstrimProvider = fstrim.NewDefaultFilesystemTrimProvider()
+	} else {
+		c.fstrimProvider = config.ConfigFilesystemTrimProvider
+	}
 }
 
 // Start initiates the cluster manager and the cluster state machine
@@ -2223,6 +2262,14 @@ func (c *ClusterManager) Collect(ctx context.Context, in *api.SdkDiagsCollectReq
 	return c.diagsProvider.Collect(ctx, in)
 }
 
+func (c *ClusterManager) CollectNode(ctx context.Context, in *api.SdkDiagsCollectNodeRequest) (*api.SdkDiagsCollectNodeResponse, error) {
+	return c.diagsProvider.CollectNode(ctx, in)
+}
+
+func (c *ClusterManager) CollectStream(in *api.SdkDiagsCollectRequest, stream api.OpenStorageDiags_CollectStreamServer) error {
+	return c.diagsProvider.CollectStream(in, stream)
+}
+
 func (c *ClusterManager) DrainAttachments(ctx context.Context, in *api.SdkNodeDrainAttachmentsRequest) (*api.SdkJobResponse, error) {
 	return c.nodeDrainProvider.DrainAttachments(ctx, in)
 }
@@ -2283,6 +2330,13 @@ func (c *ClusterManager) DeleteSchedule(
 	return c.scheduleProvider.DeleteSchedule(ctx, req)
 }
 
+func (c *ClusterManager) UpdateSchedule(
+	ctx context.Context,
+	req *api.SdkUpdateScheduleRequest,
+) (*api.SdkUpdateScheduleResponse, error) {
+	return c.scheduleProvider.UpdateSchedule(ctx, req)
+}
+
 func (c *ClusterManager) CreateDefragSchedule(
 	ctx context.Context, req *api.SdkCreateDefragScheduleRequest,
 ) (*api.SdkCreateDefragScheduleResponse, error) {
@@ -2306,3 +2360,9 @@ func (c *ClusterManager) EnumerateDefragStatus(
 ) (*api.SdkEnumerateDefragStatusResponse, error) {
 	return c.defragProvider.EnumerateDefragStatus(ctx, req)
 }
+
+func (c *ClusterManager) CreateFilesystemTrimSchedule(
+	ctx context.Context, req *api.SdkCreateFilesystemTrimScheduleRequest,
+) (*api.SdkCreateFilesystemTrimScheduleResponse, error) {
+	return c.fstrimProvider.CreateFilesystemTrimSchedule(ctx, req)
+}
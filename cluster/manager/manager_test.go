@@ -108,6 +108,25 @@ func TestUpdateSchedulerNodeName(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "new-sched-name", node.SchedulerNodeName)
 
+	// The attachments cordon is saved on the node entry
+	cordoned, err := inst.AttachmentsCordoned(nodeID)
+	assert.NoError(t, err)
+	assert.False(t, cordoned)
+	err = inst.SetAttachmentsCordoned(nodeID, true)
+	assert.NoError(t, err)
+	cordoned, err = inst.AttachmentsCordoned(nodeID)
+	assert.NoError(t, err)
+	assert.True(t, cordoned)
+	db, _, err := readClusterInfo()
+	assert.NoError(t, err)
+	assert.True(t, db.NodeEntries[nodeID].AttachmentsCordoned)
+	err = inst.SetAttachmentsCordoned(nodeID, false)
+	assert.NoError(t, err)
+	cordoned, err = inst.AttachmentsCordoned(nodeID)
+	assert.NoError(t, err)
+	assert.False(t, cordoned)
+	assert.Error(t, inst.SetAttachmentsCordoned("node-unknown", true))
+
 	tokenResp, err := inst.GetPairToken(false)
 	assert.NoError(t, err)
 	assert.True(t, auth.IsJwtToken(tokenResp.Token))
@@ -114,6 +114,35 @@ func (mr *MockClusterMockRecorder) Collect(arg0, arg1 interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Collect", reflect.TypeOf((*MockCluster)(nil).Collect), arg0, arg1)
 }
 
+// CollectNode mocks base method.
+func (m *MockCluster) CollectNode(arg0 context.Context, arg1 *api.SdkDiagsCollectNodeRequest) (*api.SdkDiagsCollectNodeResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectNode", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkDiagsCollectNodeResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CollectNode indicates an expected call of CollectNode.
+func (mr *MockClusterMockRecorder) CollectNode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectNode", reflect.TypeOf((*MockCluster)(nil).CollectNode), arg0, arg1)
+}
+
+// CollectStream mocks base method.
+func (m *MockCluster) CollectStream(arg0 *api.SdkDiagsCollectRequest, arg1 api.OpenStorageDiags_CollectStreamServer) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CollectStream", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CollectStream indicates an expected call of CollectStream.
+func (mr *MockClusterMockRecorder) CollectStream(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CollectStream", reflect.TypeOf((*MockCluster)(nil).CollectStream), arg0, arg1)
+}
+
 // CordonAttachments mocks base method.
 func (m *MockCluster) CordonAttachments(arg0 context.Context, arg1 *api.SdkNodeCordonAttachmentsRequest) (*api.SdkNodeCordonAttachmentsResponse, error) {
 	m.ctrl.T.Helper()
@@ -144,6 +173,21 @@ func (mr *MockClusterMockRecorder) CreateDefragSchedule(arg0, arg1 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDefragSchedule", reflect.TypeOf((*MockCluster)(nil).CreateDefragSchedule), arg0, arg1)
 }
 
+// CreateFilesystemTrimSchedule mocks base method.
+func (m *MockCluster) CreateFilesystemTrimSchedule(arg0 context.Context, arg1 *api.SdkCreateFilesystemTrimScheduleRequest) (*api.SdkCreateFilesystemTrimScheduleResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateFilesystemTrimSchedule", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkCreateFilesystemTrimScheduleResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateFilesystemTrimSchedule indicates an expected call of CreateFilesystemTrimSchedule.
+func (mr *MockClusterMockRecorder) CreateFilesystemTrimSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateFilesystemTrimSchedule", reflect.TypeOf((*MockCluster)(nil).CreateFilesystemTrimSchedule), arg0, arg1)
+}
+
 // CreatePair mocks base method.
 func (m *MockCluster) CreatePair(arg0 *api.ClusterPairCreateRequest) (*api.ClusterPairCreateResponse, error) {
 	m.ctrl.T.Helper()
@@ -1180,6 +1224,21 @@ func (mr *MockClusterMockRecorder) UpdateRebalanceJobState(arg0, arg1 interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRebalanceJobState", reflect.TypeOf((*MockCluster)(nil).UpdateRebalanceJobState), arg0, arg1)
 }
 
+// UpdateSchedule mocks base method.
+func (m *MockCluster) UpdateSchedule(arg0 context.Context, arg1 *api.SdkUpdateScheduleRequest) (*api.SdkUpdateScheduleResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateSchedule", arg0, arg1)
+	ret0, _ := ret[0].(*api.SdkUpdateScheduleResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateSchedule indicates an expected call of UpdateSchedule.
+func (mr *MockClusterMockRecorder) UpdateSchedule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateSchedule", reflect.TypeOf((*MockCluster)(nil).UpdateSchedule), arg0, arg1)
+}
+
 // UpdateSchedulerNodeName mocks base method.
 func (m *MockCluster) UpdateSchedulerNodeName(arg0 string) error {
 	m.ctrl.T.Helper()
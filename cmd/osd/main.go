@@ -54,12 +54,22 @@ import (
 	"github.com/libopenstorage/openstorage/csi"
 	graphdrivers "github.com/libopenstorage/openstorage/graph/drivers"
 	"github.com/libopenstorage/openstorage/objectstore"
+	"github.com/libopenstorage/openstorage/pkg/audit"
 	"github.com/libopenstorage/openstorage/pkg/auth"
 	"github.com/libopenstorage/openstorage/pkg/auth/systemtoken"
+	"github.com/libopenstorage/openstorage/pkg/defrag"
+	"github.com/libopenstorage/openstorage/pkg/diags"
+	"github.com/libopenstorage/openstorage/pkg/fstrim"
+	"github.com/libopenstorage/openstorage/pkg/job"
 	"github.com/libopenstorage/openstorage/pkg/loadbalancer"
+	"github.com/libopenstorage/openstorage/pkg/nodedrain"
+	"github.com/libopenstorage/openstorage/pkg/quota"
 	"github.com/libopenstorage/openstorage/pkg/role"
 	"github.com/libopenstorage/openstorage/pkg/sched"
+	"github.com/libopenstorage/openstorage/pkg/schedule"
 	policy "github.com/libopenstorage/openstorage/pkg/storagepolicy"
+	"github.com/libopenstorage/openstorage/pkg/storagepool"
+	"github.com/libopenstorage/openstorage/pkg/volumetemplate"
 	"github.com/libopenstorage/openstorage/schedpolicy"
 	"github.com/libopenstorage/openstorage/volume"
 	volumedrivers "github.com/libopenstorage/openstorage/volume/drivers"
@@ -199,6 +209,34 @@ func main() {
 			Usage: "Secrets manager type. For example \"k8s\"",
 			Value: "",
 		},
+		cli.StringSliceFlag{
+			Name:  "diags-log-file",
+			Usage: "osd log file to add to the diagnostics bundles. Can be repeated",
+			Value: new(cli.StringSlice),
+		},
+		cli.StringFlag{
+			Name:  "sdk-audit-sink",
+			Usage: "Record the mutating SDK requests, including those through its REST Gateway, in an audit log: \"file:<path>\", \"syslog\" or \"kvdb\". The mutating requests to the volume management REST API are recorded too, but not those to the docker volume plugin. The audit log is disabled if not set",
+		},
+		cli.StringSliceFlag{
+			Name:  "sdk-rate-limit",
+			Usage: "Limit the SDK requests of each caller with a role, as \"role=<role>,rps=<requests per second>,burst=<n>,inflight=<n>\", optionally limited to some services and methods with \"services=<a>|<b>,methods=<c>|<d>\". Role \"*\" applies to all callers, including those which are not authenticated. The first matching limit applies. Can be repeated",
+			Value: new(cli.StringSlice),
+		},
+		cli.DurationFlag{
+			Name:  "volume-trash-retention",
+			Usage: "Keep deleted volumes in the trash for this long before purging them. For example \"24h\". Volumes are deleted immediately if not set",
+		},
+		cli.IntFlag{
+			Name:  "defrag-max-volumes-per-node",
+			Usage: "Maximum number of volumes defragmented in parallel on this node",
+			Value: defrag.DefaultMaxVolumesPerNode,
+		},
+		cli.IntFlag{
+			Name:  "defrag-max-volumes-per-pool",
+			Usage: "Maximum number of volumes defragmented in parallel on a pool of this node",
+			Value: defrag.DefaultMaxVolumesPerPool,
+		},
 	}
 	app.Action = wrapAction(start)
 	app.Commands = []cli.Command{
@@ -419,6 +457,37 @@ func start(c *cli.Context) error {
 		clusterInit = true
 	}
 
+	// Run the jobs and the schedules from kvdb on this node
+	var jp *job.KvdbProvider
+	var schedules *schedule.KvdbRegistry
+	var nodeDrain *nodedrain.AttachmentsProvider
+	var diagsCollector *diags.Collector
+	var defragEngine *defrag.Engine
+	var fstrimScheduler *fstrim.Scheduler
+	var rebalanceScheduler *storagepool.RebalanceScheduler
+	if clusterInit {
+		jp, err = job.NewKvdbProvider(kv, cfg.Osd.ClusterConfig.NodeId)
+		if err != nil {
+			return fmt.Errorf("Failed to create a job provider: %v", err)
+		}
+		schedules, err = schedule.NewKvdbRegistry(kv, cfg.Osd.ClusterConfig.NodeId)
+		if err != nil {
+			return fmt.Errorf("Failed to create a schedule registry: %v", err)
+		}
+		rebalanceScheduler, err = storagepool.NewRebalanceScheduler(
+			storagepool.NewDefaultStoragePoolProvider(), schedules)
+		if err != nil {
+			return fmt.Errorf("Failed to create a rebalance scheduler: %v", err)
+		}
+	}
+
+	// The audit log is shared by the SDK and REST servers of all the drivers
+	auditSink, err := setupSdkAuditSink(c, kv)
+	if err != nil {
+		return err
+	}
+	server.AuditSink = auditSink
+
 	isDefaultSet := false
 	// Start the volume drivers.
 	for d, v := range cfg.Osd.Drivers {
@@ -430,6 +499,21 @@ func start(c *cli.Context) error {
 			return fmt.Errorf("Unable to start volume driver: %v, %v", d, err)
 		}
 
+		// The drivers running their background work as jobs share the job
+		// provider of the cluster
+		vd, err := volumedrivers.Get(d)
+		if err != nil {
+			return fmt.Errorf("Unable to get volume driver %s: %v", d, err)
+		}
+		if consumer, ok := vd.(job.Consumer); ok {
+			if jp == nil {
+				logrus.Warnf("Volume driver %s runs its background work as jobs, "+
+					"which is disabled without cluster mode", d)
+			} else if err := consumer.SetJobProvider(jp); err != nil {
+				return fmt.Errorf("Unable to set the job provider of volume driver %s: %v", d, err)
+			}
+		}
+
 		var mgmtPort, pluginPort uint64
 		if port, ok := v[config.MgmtPortKey]; ok {
 			mgmtPort, err = strconv.ParseUint(port, 10, 16)
@@ -555,21 +639,117 @@ func start(c *cli.Context) error {
 			return fmt.Errorf("Failed to create a role manager")
 		}
 
+		// Create a quota manager
+		qm, err := quota.NewSdkQuotaManager(kv)
+		if err != nil {
+			return fmt.Errorf("Failed to create a quota manager: %v", err)
+		}
+
+		// Create a volume template manager
+		vtm, err := volumetemplate.NewSdkVolumeTemplateManager(kv)
+		if err != nil {
+			return fmt.Errorf("Failed to create a volume template manager: %v", err)
+		}
+
 		sp, err := policy.Init()
 		if err != nil {
 			return fmt.Errorf("Unable to Initialise Storage Policy Manager Instances %v", err)
 		}
 
+		// Limit the rate of the SDK requests
+		var rateLimits []sdk.RateLimit
+		for _, limit := range c.StringSlice("sdk-rate-limit") {
+			rateLimit, err := sdk.ParseRateLimit(limit)
+			if err != nil {
+				return err
+			}
+			rateLimits = append(rateLimits, rateLimit)
+		}
+
+		// Enable the volume trash
+		var volumeTrash *sdk.VolumeTrashConfig
+		if retention := c.Duration("volume-trash-retention"); retention > 0 {
+			volumeTrash = &sdk.VolumeTrashConfig{Retention: retention}
+		}
+
+		// Drain the volume attachments of the nodes with the default driver
+		var attachmentsCordon nodedrain.AttachmentsCordon
+		if jp != nil && d == cfg.Osd.ClusterConfig.DefaultDriver {
+			cordons, ok := cm.(nodedrain.AttachmentsCordonStore)
+			if !ok {
+				return fmt.Errorf("Cluster manager does not save the cordon of the nodes")
+			}
+			nodeDrain, err = nodedrain.NewAttachmentsProvider(cordons, vd, jp)
+			if err != nil {
+				return fmt.Errorf("Failed to create a node drain provider: %v", err)
+			}
+			attachmentsCordon = nodeDrain
+
+			// The other nodes are called with the TLS certificate of the SDK
+			var diagsTlsCertFile string
+			if tlsConfig != nil {
+				diagsTlsCertFile = tlsConfig.CertFile
+			}
+			diagsCollector, err = diags.NewCollector(&diags.CollectorConfig{
+				Cluster:        cm,
+				Driver:         vd,
+				Kvdb:           kv,
+				Jobs:           jp,
+				LogFiles:       c.StringSlice("diags-log-file"),
+				SdkPort:        sdkPort,
+				TlsCertFile:    diagsTlsCertFile,
+				TokenGenerator: auth.SystemTokenManagerInst(),
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to create a diags collector: %v", err)
+			}
+
+			defragEngine, err = defrag.NewEngine(&defrag.EngineConfig{
+				NodeID:            cfg.Osd.ClusterConfig.NodeId,
+				Cluster:           cm,
+				Driver:            vd,
+				Kvdb:              kv,
+				Schedules:         schedules,
+				MaxVolumesPerNode: c.Int("defrag-max-volumes-per-node"),
+				MaxVolumesPerPool: c.Int("defrag-max-volumes-per-pool"),
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to create a defrag engine: %v", err)
+			}
+
+			fstrimScheduler, err = fstrim.NewScheduler(&fstrim.SchedulerConfig{
+				Driver:    vd,
+				Jobs:      jp,
+				Schedules: schedules,
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to create a filesystem trim scheduler: %v", err)
+			}
+
+			// The cloud backup schedules are run by the driver
+			if err := schedules.RegisterSource(api.Job_CLOUD_BACKUP,
+				sdk.NewCloudBackupScheduleSource(vd, kv)); err != nil {
+				return fmt.Errorf("Failed to register the cloud backup schedules: %v", err)
+			}
+		}
+
 		// Start SDK Server for this driver
 		os.Remove(sdksocket)
 		sdkServer, err := sdk.New(&sdk.ServerConfig{
-			Net:           "tcp",
-			Address:       ":" + sdkPort,
-			RestPort:      c.String("sdkrestport"),
-			Socket:        sdksocket,
-			DriverName:    d,
-			Cluster:       cm,
-			StoragePolicy: sp,
+			Net:               "tcp",
+			Address:           ":" + sdkPort,
+			RestPort:          c.String("sdkrestport"),
+			Socket:            sdksocket,
+			DriverName:        d,
+			Cluster:           cm,
+			StoragePolicy:     sp,
+			Quota:             qm,
+			VolumeTrash:       volumeTrash,
+			VolumeTemplate:    vtm,
+			AttachmentsCordon: attachmentsCordon,
+			Kvdb:              kv,
+			RateLimits:        rateLimits,
+			AuditSink:         auditSink,
 			Security: &sdk.SecurityConfig{
 				Role:           rm,
 				Tls:            tlsConfig,
@@ -620,16 +800,48 @@ func start(c *cli.Context) error {
 		if err != nil {
 			return fmt.Errorf("Unable to find cluster instance: %v", err)
 		}
+
+		if err := jp.Start(); err != nil {
+			return fmt.Errorf("Failed to start the job provider: %v", err)
+		}
+		if err := schedules.Start(); err != nil {
+			return fmt.Errorf("Failed to start the schedule registry: %v", err)
+		}
+		if defragEngine != nil {
+			if err := defragEngine.Start(); err != nil {
+				return fmt.Errorf("Failed to start the defrag engine: %v", err)
+			}
+		}
+
+		clusterServerConfig := &cluster.ClusterServerConfiguration{
+			ConfigSchedManager:       schedpolicy.NewFakeScheduler(),
+			ConfigObjectStoreManager: objectstore.NewfakeObjectstore(),
+			ConfigSystemTokenManager: auth.SystemTokenManagerInst(),
+			ConfigJobProvider:        jp,
+			ConfigScheduleProvider:   schedules,
+		}
+		if nodeDrain != nil {
+			clusterServerConfig.ConfigNodeDrainProvider = nodeDrain
+		}
+		if diagsCollector != nil {
+			clusterServerConfig.ConfigDiagsProvider = diagsCollector
+		}
+		if defragEngine != nil {
+			clusterServerConfig.ConfigDefragProvider = defragEngine
+		}
+		if fstrimScheduler != nil {
+			clusterServerConfig.ConfigFilesystemTrimProvider = fstrimScheduler
+		}
+		if rebalanceScheduler != nil {
+			clusterServerConfig.ConfigStoragePoolProvider = rebalanceScheduler
+		}
+
 		if err := cm.StartWithConfiguration(
 			false,
 			"9002",
 			[]string{},
 			c.String("clusterdomain"),
-			&cluster.ClusterServerConfiguration{
-				ConfigSchedManager:       schedpolicy.NewFakeScheduler(),
-				ConfigObjectStoreManager: objectstore.NewfakeObjectstore(),
-				ConfigSystemTokenManager: auth.SystemTokenManagerInst(),
-			},
+			clusterServerConfig,
 			"",
 		); err != nil {
 			return fmt.Errorf("Unable to start cluster manager: %v", err)
@@ -731,6 +943,21 @@ func setupSdkTls(c *cli.Context) (*sdk.TLSConfig, error) {
 	return nil, nil
 }
 
+func setupSdkAuditSink(c *cli.Context, kv kvdb.Kvdb) (audit.Sink, error) {
+	sink := c.String("sdk-audit-sink")
+	switch {
+	case sink == "":
+		return nil, nil
+	case strings.HasPrefix(sink, "file:"):
+		return audit.NewFileSink(strings.TrimPrefix(sink, "file:"), 0, 0)
+	case sink == "syslog":
+		return audit.NewSyslogSink("openstorage-audit")
+	case sink == "kvdb":
+		return audit.NewKvdbSink(kv, "", 0), nil
+	}
+	return nil, fmt.Errorf("Invalid SDK audit sink %q", sink)
+}
+
 func getConfigVar(envVar, cliVar string) string {
 	if len(envVar) != 0 {
 		return envVar
@@ -0,0 +1,319 @@
+/*
+Package audit records the mutating requests made to the SDK and REST servers
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/libopenstorage/openstorage/api"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// Redacted replaces the values of secrets in the requests saved in the
+	// audit log
+	Redacted = "[REDACTED]"
+
+	// DefaultMaxEntries is the number of entries returned by Enumerate when
+	// the request does not set max_entries
+	DefaultMaxEntries = 100
+)
+
+var (
+	// secretFields are the parts of the names of the fields and labels
+	// whose values are redacted, compared without separators
+	secretFields = []string{
+		"secret",
+		"passw",
+		"passphrase",
+		"token",
+		"private_key",
+		"access_key",
+		"account_key",
+		"json_key",
+		"encryption_key",
+		// Passphrase of the credentials of the REST API
+		api.OptCredEncrKey,
+	}
+	// publicFields are the fields which match secretFields but are not
+	// secrets
+	publicFields = map[string]bool{
+		"page_token":         true,
+		"next_page_token":    true,
+		"continuation_token": true,
+	}
+)
+
+// Sink saves the entries of the audit log
+type Sink interface {
+	// Write saves an entry
+	Write(entry *api.SdkAuditEntry) error
+}
+
+// Enumerator is implemented by the sinks which can return the entries they
+// saved
+type Enumerator interface {
+	// Enumerate returns the entries matching the request, most recent first
+	Enumerate(req *api.SdkAuditEnumerateRequest) ([]*api.SdkAuditEntry, error)
+}
+
+// Matches returns true if the entry matches the filters of the request
+func Matches(req *api.SdkAuditEnumerateRequest, entry *api.SdkAuditEntry) bool {
+	if len(req.GetUsername()) != 0 && req.GetUsername() != entry.GetUsername() {
+		return false
+	}
+	if len(req.GetResourceId()) != 0 {
+		found := false
+		for _, id := range entry.GetResourceIds() {
+			if id == req.GetResourceId() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	t := entry.GetTime().AsTime()
+	if req.GetStartTime() != nil && t.Before(req.GetStartTime().AsTime()) {
+		return false
+	}
+	if req.GetEndTime() != nil && !t.Before(req.GetEndTime().AsTime()) {
+		return false
+	}
+	return true
+}
+
+// Filter returns the entries matching the request, most recent first, up
+// to the maximum number of entries of the request
+func Filter(req *api.SdkAuditEnumerateRequest, entries []*api.SdkAuditEntry) []*api.SdkAuditEntry {
+	max := int(req.GetMaxEntries())
+	if max == 0 {
+		max = DefaultMaxEntries
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].GetTime().AsTime().After(entries[j].GetTime().AsTime())
+	})
+	matches := make([]*api.SdkAuditEntry, 0)
+	for _, entry := range entries {
+		if len(matches) == max {
+			break
+		}
+		if Matches(req, entry) {
+			matches = append(matches, entry)
+		}
+	}
+	return matches
+}
+
+// normalizeName returns the name in lower case without separators, so that
+// snake case, camel case and labels are compared alike
+func normalizeName(name string) string {
+	return strings.NewReplacer("_", "", "-", "", ".", "").Replace(strings.ToLower(name))
+}
+
+// isSecret returns true if the value of a field or label called name must
+// be redacted
+func isSecret(name string) bool {
+	if publicFields[strings.ToLower(name)] {
+		return false
+	}
+	name = normalizeName(name)
+	for _, s := range secretFields {
+		if strings.Contains(name, normalizeName(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redact returns a copy of the message with the values of the fields and
+// labels which may contain secrets replaced by Redacted
+func Redact(msg proto.Message) proto.Message {
+	c := proto.Clone(msg)
+	redact(c.ProtoReflect())
+	return c
+}
+
+func redact(m protoreflect.Message) {
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		secret := isSecret(string(fd.Name()))
+		switch {
+		case fd.IsMap():
+			mp := m.Mutable(fd).Map()
+			mp.Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				switch fd.MapValue().Kind() {
+				case protoreflect.StringKind:
+					if secret || isSecret(k.String()) {
+						mp.Set(k, protoreflect.ValueOfString(Redacted))
+					}
+				case protoreflect.MessageKind:
+					redact(mv.Message())
+				}
+				return true
+			})
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			for i := 0; i < list.Len(); i++ {
+				switch fd.Kind() {
+				case protoreflect.StringKind:
+					if secret {
+						list.Set(i, protoreflect.ValueOfString(Redacted))
+					}
+				case protoreflect.MessageKind:
+					redact(list.Get(i).Message())
+				}
+			}
+		case fd.Kind() == protoreflect.MessageKind:
+			redact(m.Mutable(fd).Message())
+		case fd.Kind() == protoreflect.StringKind:
+			if secret {
+				m.Set(fd, protoreflect.ValueOfString(Redacted))
+			}
+		case fd.Kind() == protoreflect.BytesKind:
+			if secret {
+				m.Set(fd, protoreflect.ValueOfBytes([]byte(Redacted)))
+			}
+		}
+		return true
+	})
+}
+
+// ResourceIds returns the ids found in the top level fields of the messages
+// without duplicates. These are the string fields called id or ending in
+// _id, and the repeated string fields ending in _ids.
+func ResourceIds(msgs ...proto.Message) []string {
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if len(id) != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, msg := range msgs {
+		if msg == nil {
+			continue
+		}
+		m := msg.ProtoReflect()
+		if !m.IsValid() {
+			continue
+		}
+		// Walk the fields in declaration order; Range visits them randomly
+		fields := m.Descriptor().Fields()
+		for i := 0; i < fields.Len(); i++ {
+			fd := fields.Get(i)
+			if fd.Kind() != protoreflect.StringKind || fd.IsMap() || !m.Has(fd) {
+				continue
+			}
+			name := string(fd.Name())
+			v := m.Get(fd)
+			if fd.IsList() {
+				if strings.HasSuffix(name, "_ids") {
+					list := v.List()
+					for j := 0; j < list.Len(); j++ {
+						add(list.Get(j).String())
+					}
+				}
+			} else if name == "id" || strings.HasSuffix(name, "_id") {
+				add(v.String())
+			}
+		}
+	}
+	return ids
+}
+
+// RedactJSON returns the JSON document with the values of the fields which
+// may contain secrets replaced by Redacted. It returns an empty string if
+// data is not a JSON document.
+func RedactJSON(data []byte) string {
+	var doc interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&doc); err != nil {
+		return ""
+	}
+	b, err := json.Marshal(redactJSON(doc, false))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// redactJSON redacts the values of the secret fields of v, or all its
+// values if secret is set
+func redactJSON(v interface{}, secret bool) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, fv := range value {
+			value[k] = redactJSON(fv, secret || isSecret(k))
+		}
+	case []interface{}:
+		for i, fv := range value {
+			value[i] = redactJSON(fv, secret)
+		}
+	case nil:
+	default:
+		if secret {
+			return Redacted
+		}
+	}
+	return v
+}
+
+// JSONResourceIds returns the ids found in the top level fields of the JSON
+// documents without duplicates, like ResourceIds. The fields of each
+// document are visited in the order of their names.
+func JSONResourceIds(docs ...[]byte) []string {
+	ids := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(v interface{}) {
+		if id, ok := v.(string); ok && len(id) != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	for _, data := range docs {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			continue
+		}
+		keys := make([]string, 0, len(doc))
+		for k := range doc {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			name := strings.ToLower(k)
+			if list, ok := doc[k].([]interface{}); ok && strings.HasSuffix(name, "_ids") {
+				for _, v := range list {
+					add(v)
+				}
+			} else if name == "id" || strings.HasSuffix(name, "_id") {
+				add(doc[k])
+			}
+		}
+	}
+	return ids
+}
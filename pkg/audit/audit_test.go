@@ -0,0 +1,260 @@
+/*
+Package audit records the mutating requests made to the SDK server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+func newEntry(t time.Time, username string, ids ...string) *api.SdkAuditEntry {
+	return &api.SdkAuditEntry{
+		Time:        timestamppb.New(t),
+		Username:    username,
+		Method:      "/openstorage.api.OpenStorageVolume/Delete",
+		ResourceIds: ids,
+	}
+}
+
+func entryIds(entries []*api.SdkAuditEntry) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.GetResourceIds()[0]
+	}
+	return ids
+}
+
+func TestRedact(t *testing.T) {
+	req := &api.SdkCredentialCreateRequest{
+		Name: "cred",
+		CredentialType: &api.SdkCredentialCreateRequest_AwsCredential{
+			AwsCredential: &api.SdkAwsCredentialRequest{
+				AccessKey: "access",
+				SecretKey: "secret",
+				Endpoint:  "endpoint",
+			},
+		},
+	}
+
+	redacted := Redact(req).(*api.SdkCredentialCreateRequest)
+	assert.Equal(t, "cred", redacted.GetName())
+	assert.Equal(t, Redacted, redacted.GetAwsCredential().GetAccessKey())
+	assert.Equal(t, Redacted, redacted.GetAwsCredential().GetSecretKey())
+	assert.Equal(t, "endpoint", redacted.GetAwsCredential().GetEndpoint())
+
+	// The request is not changed
+	assert.Equal(t, "secret", req.GetAwsCredential().GetSecretKey())
+
+	// Labels which look like secrets are redacted too
+	vol := Redact(&api.SdkVolumeCreateRequest{
+		Name: "vol",
+		Spec: &api.VolumeSpec{
+			Passphrase: "passphrase",
+		},
+		Labels: map[string]string{
+			"app":            "db",
+			"db-password":    "password",
+			"pvc-secret-key": "key",
+		},
+	}).(*api.SdkVolumeCreateRequest)
+	assert.Equal(t, Redacted, vol.GetSpec().GetPassphrase())
+	assert.Equal(t, map[string]string{
+		"app":            "db",
+		"db-password":    Redacted,
+		"pvc-secret-key": Redacted,
+	}, vol.GetLabels())
+
+	// Page tokens are not secrets
+	enum := Redact(&api.SdkVolumeEnumerateWithFiltersRequest{
+		PageToken: "page",
+	}).(*api.SdkVolumeEnumerateWithFiltersRequest)
+	assert.Equal(t, "page", enum.GetPageToken())
+}
+
+func TestResourceIds(t *testing.T) {
+	ids := ResourceIds(
+		&api.SdkCloudBackupCreateRequest{
+			VolumeId:     "vol",
+			CredentialId: "cred",
+		},
+		&api.SdkCloudBackupCreateResponse{
+			TaskId: "task",
+		},
+		&api.SdkVolumeBulkRequest{
+			VolumeIds: []string{"vol", "vol2"},
+		},
+		(*api.SdkVolumeDeleteResponse)(nil),
+	)
+	assert.Equal(t, []string{"vol", "cred", "task", "vol2"}, ids)
+}
+
+func TestRedactJSON(t *testing.T) {
+	redacted := RedactJSON([]byte(`{
+		"InputParams": {"CredType": "s3", "CredSecretKey": "secret", "CredEncrypt": "passphrase"},
+		"spec": {"size": 1024, "passphrase": "passphrase", "volume_labels": {"db-password": "password"}},
+		"secrets": ["secret"]
+	}`))
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(redacted), &doc))
+	assert.Equal(t, map[string]interface{}{
+		"CredType":      "s3",
+		"CredSecretKey": Redacted,
+		"CredEncrypt":   Redacted,
+	}, doc["InputParams"])
+	spec := doc["spec"].(map[string]interface{})
+	assert.Equal(t, float64(1024), spec["size"])
+	assert.Equal(t, Redacted, spec["passphrase"])
+	assert.Equal(t, map[string]interface{}{"db-password": Redacted}, spec["volume_labels"])
+	assert.Equal(t, []interface{}{Redacted}, doc["secrets"])
+
+	assert.Empty(t, RedactJSON([]byte("not json")))
+}
+
+func TestJSONResourceIds(t *testing.T) {
+	ids := JSONResourceIds(
+		[]byte(`{"volume_id": "vol", "credential_id": "cred", "name": "name"}`),
+		[]byte(`{"id": "snap", "volume_ids": ["vol", "vol2"]}`),
+		[]byte("not json"),
+	)
+	assert.Equal(t, []string{"cred", "vol", "snap", "vol2"}, ids)
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+	entries := []*api.SdkAuditEntry{
+		newEntry(now.Add(-3*time.Hour), "user1", "a"),
+		newEntry(now.Add(-1*time.Hour), "user2", "b"),
+		newEntry(now.Add(-2*time.Hour), "user1", "c", "b"),
+		newEntry(now, "user1", "d"),
+	}
+
+	tests := []struct {
+		req      *api.SdkAuditEnumerateRequest
+		expected []string
+	}{
+		{&api.SdkAuditEnumerateRequest{}, []string{"d", "b", "c", "a"}},
+		{&api.SdkAuditEnumerateRequest{MaxEntries: 2}, []string{"d", "b"}},
+		{&api.SdkAuditEnumerateRequest{Username: "user1"}, []string{"d", "c", "a"}},
+		{&api.SdkAuditEnumerateRequest{ResourceId: "b"}, []string{"b", "c"}},
+		{
+			&api.SdkAuditEnumerateRequest{
+				StartTime: timestamppb.New(now.Add(-2 * time.Hour)),
+				EndTime:   timestamppb.New(now),
+			},
+			[]string{"b", "c"},
+		},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, entryIds(Filter(test.req, entries)), "request %v", test.req)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Rotate after each entry, keeping two rotated files
+	path := filepath.Join(dir, "audit.log")
+	f, err := NewFileSink(path, 1, 2)
+	require.NoError(t, err)
+	defer f.Close()
+
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, f.Write(newEntry(now.Add(time.Duration(i)*time.Second), "user", fmt.Sprintf("%d", i))))
+	}
+	_, err = os.Stat(path + ".2")
+	assert.NoError(t, err)
+	_, err = os.Stat(path + ".3")
+	assert.True(t, os.IsNotExist(err))
+
+	entries, err := f.Enumerate(&api.SdkAuditEnumerateRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4", "3", "2"}, entryIds(entries))
+
+	// Entries written before a restart are kept
+	require.NoError(t, f.Close())
+	f, err = NewFileSink(path, 1024*1024, 2)
+	require.NoError(t, err)
+	require.NoError(t, f.Write(newEntry(now.Add(time.Minute), "user", "5")))
+	entries, err = f.Enumerate(&api.SdkAuditEnumerateRequest{MaxEntries: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"5", "4"}, entryIds(entries))
+}
+
+func TestFileSinkRotateFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// The file cannot be renamed over a directory which is not empty
+	path := filepath.Join(dir, "audit.log")
+	require.NoError(t, os.MkdirAll(filepath.Join(path+".1", "busy"), 0755))
+	f, err := NewFileSink(path, 1, 1)
+	require.NoError(t, err)
+	defer f.Close()
+
+	// Entries are still written to the current file
+	now := time.Now()
+	require.NoError(t, f.Write(newEntry(now, "user", "0")))
+	require.NoError(t, f.Write(newEntry(now.Add(time.Second), "user", "1")))
+
+	// The file is rotated once it can be
+	require.NoError(t, os.RemoveAll(path+".1"))
+	require.NoError(t, f.Write(newEntry(now.Add(2*time.Second), "user", "2")))
+	entries, err := f.Enumerate(&api.SdkAuditEnumerateRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"2", "1", "0"}, entryIds(entries))
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "\n"))
+}
+
+func TestKvdbSink(t *testing.T) {
+	kv, err := kvdb.New(mem.Name, "audit_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+
+	// Only the last three entries are kept
+	k := NewKvdbSink(kv, "", 3)
+	now := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, k.Write(newEntry(now.Add(time.Duration(i)*time.Second), "user", fmt.Sprintf("%d", i))))
+	}
+
+	entries, err := k.Enumerate(&api.SdkAuditEnumerateRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"4", "3", "2"}, entryIds(entries))
+
+	kvps, err := kv.Enumerate(DefaultKvdbPrefix + "/" + kvdbEntriesKey)
+	require.NoError(t, err)
+	assert.Len(t, kvps, 3)
+}
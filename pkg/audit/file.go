@@ -0,0 +1,238 @@
+/*
+Package audit records the mutating requests made to the SDK server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package audit
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// DefaultFileMaxSize is the size at which the audit log file is rotated
+	// if no size is given
+	DefaultFileMaxSize = 100 * 1024 * 1024
+	// DefaultFileMaxBackups is the number of rotated audit log files kept
+	// if no number is given
+	DefaultFileMaxBackups = 5
+
+	// fileMaxEntrySize is the largest entry which can be read back
+	fileMaxEntrySize = 16 * 1024 * 1024
+)
+
+// FileSink writes the audit log to a file with one JSON entry per line.
+// Once the file reaches its maximum size it is renamed to <path>.1, the
+// previous <path>.1 to <path>.2 and so on, keeping a maximum number of
+// rotated files.
+type FileSink struct {
+	lock       sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// Check interfaces
+var _ Sink = &FileSink{}
+var _ Enumerator = &FileSink{}
+
+// NewFileSink returns a sink writing to the file at path. If maxSize or
+// maxBackups are zero the defaults are used.
+func NewFileSink(path string, maxSize int64, maxBackups int) (*FileSink, error) {
+	if maxSize == 0 {
+		maxSize = DefaultFileMaxSize
+	}
+	if maxBackups == 0 {
+		maxBackups = DefaultFileMaxBackups
+	}
+	f := &FileSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *FileSink) open() error {
+	file, err := os.OpenFile(f.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Unable to open audit log %s: %v", f.path, err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("Unable to open audit log %s: %v", f.path, err)
+	}
+	f.file = file
+	f.size = info.Size()
+	return nil
+}
+
+func (f *FileSink) backup(n int) string {
+	return fmt.Sprintf("%s.%d", f.path, n)
+}
+
+// rotate must be called with the lock held. If the files cannot be renamed
+// the current file is reopened, so that entries are still written to it.
+func (f *FileSink) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+	err := f.rename()
+	if oerr := f.open(); err == nil {
+		err = oerr
+	}
+	return err
+}
+
+func (f *FileSink) rename() error {
+	os.Remove(f.backup(f.maxBackups))
+	for n := f.maxBackups - 1; n > 0; n-- {
+		if err := os.Rename(f.backup(n), f.backup(n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(f.path, f.backup(1))
+}
+
+// Write appends an entry to the file, rotating it first if it would grow
+// past its maximum size. If the file cannot be rotated the entry is written
+// to it anyway.
+func (f *FileSink) Write(entry *api.SdkAuditEntry) error {
+	b, err := protojson.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return err
+		}
+	}
+	if f.size > 0 && f.size+int64(len(b)) > f.maxSize {
+		if err := f.rotate(); err != nil {
+			if f.file == nil {
+				return fmt.Errorf("Unable to rotate audit log %s: %v", f.path, err)
+			}
+			logrus.Warnf("Unable to rotate audit log %s, writing past its maximum size: %v", f.path, err)
+		}
+	}
+	n, err := f.file.Write(b)
+	f.size += int64(n)
+	return err
+}
+
+// Enumerate reads the entries of the file and of the rotated files. The
+// files are opened with the lock held, so that they are not rotated in the
+// meantime, and read without it, up to their size when they were opened.
+func (f *FileSink) Enumerate(req *api.SdkAuditEnumerateRequest) ([]*api.SdkAuditEntry, error) {
+	readers, err := f.openAll()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, r := range readers {
+			r.file.Close()
+		}
+	}()
+
+	entries := make([]*api.SdkAuditEntry, 0)
+	for _, r := range readers {
+		scanner := bufio.NewScanner(io.LimitReader(r.file, r.size))
+		scanner.Buffer(make([]byte, 64*1024), fileMaxEntrySize)
+		for scanner.Scan() {
+			entry := &api.SdkAuditEntry{}
+			if err := protojson.Unmarshal(scanner.Bytes(), entry); err != nil {
+				// Skip lines partially written on a crash
+				continue
+			}
+			entries = append(entries, entry)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	return Filter(req, entries), nil
+}
+
+// fileReader is a file of the audit log with its size when it was opened
+type fileReader struct {
+	file *os.File
+	size int64
+}
+
+// openAll opens the rotated files, oldest first, followed by the file
+func (f *FileSink) openAll() ([]fileReader, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	readers := make([]fileReader, 0, f.maxBackups+1)
+	for n := f.maxBackups; n >= 0; n-- {
+		path := f.path
+		if n > 0 {
+			path = f.backup(n)
+		}
+		file, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		var info os.FileInfo
+		if err == nil {
+			info, err = file.Stat()
+			if err != nil {
+				file.Close()
+			}
+		}
+		if err != nil {
+			for _, r := range readers {
+				r.file.Close()
+			}
+			return nil, err
+		}
+		readers = append(readers, fileReader{file: file, size: info.Size()})
+	}
+	return readers, nil
+}
+
+// Close closes the file
+func (f *FileSink) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
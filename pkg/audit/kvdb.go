@@ -0,0 +1,132 @@
+/*
+Package audit records the mutating requests made to the SDK server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package audit
+
+import (
+	"strconv"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+const (
+	// DefaultKvdbPrefix is the kvdb prefix of the audit log
+	DefaultKvdbPrefix = "audit"
+	// DefaultKvdbSize is the number of entries kept in kvdb if no size is
+	// given
+	DefaultKvdbSize = 10000
+
+	kvdbNextIdKey  = "next_id"
+	kvdbEntriesKey = "entries/"
+)
+
+// KvdbSink keeps the most recent entries of the audit log in kvdb as a ring
+// buffer. Entries get increasing ids shared by all the nodes using the same
+// prefix, and each entry replaces the one written size entries before it.
+type KvdbSink struct {
+	kv     kvdb.Kvdb
+	prefix string
+	size   uint64
+}
+
+// Check interfaces
+var _ Sink = &KvdbSink{}
+var _ Enumerator = &KvdbSink{}
+
+// NewKvdbSink returns a sink keeping the last size entries under prefix. If
+// prefix or size are not set the defaults are used.
+func NewKvdbSink(kv kvdb.Kvdb, prefix string, size uint64) *KvdbSink {
+	if len(prefix) == 0 {
+		prefix = DefaultKvdbPrefix
+	}
+	if size == 0 {
+		size = DefaultKvdbSize
+	}
+	return &KvdbSink{
+		kv:     kv,
+		prefix: prefix + "/",
+		size:   size,
+	}
+}
+
+// nextId returns the id of the next entry
+func (k *KvdbSink) nextId() (uint64, error) {
+	key := k.prefix + kvdbNextIdKey
+	if _, err := k.kv.Create(key, strconv.FormatUint(1, 10), 0); err == nil {
+		return 0, nil
+	} else if err != kvdb.ErrExist {
+		return 0, err
+	}
+
+	for {
+		kvp, err := k.kv.Get(key)
+		if err != nil {
+			return 0, err
+		}
+		id, err := strconv.ParseUint(string(kvp.Value), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		prevValue := kvp.Value
+		newKvp := *kvp
+		newKvp.Value = []byte(strconv.FormatUint(id+1, 10))
+		_, err = k.kv.CompareAndSet(&newKvp, kvdb.KVFlags(0), prevValue)
+		if err == kvdb.ErrValueMismatch || err == kvdb.ErrModified {
+			// Another writer got this id, retry
+			continue
+		} else if err != nil {
+			return 0, err
+		}
+		return id, nil
+	}
+}
+
+// Write saves an entry in the next slot of the ring buffer
+func (k *KvdbSink) Write(entry *api.SdkAuditEntry) error {
+	b, err := protojson.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	id, err := k.nextId()
+	if err != nil {
+		return err
+	}
+	_, err = k.kv.Put(k.prefix+kvdbEntriesKey+strconv.FormatUint(id%k.size, 10), b, 0)
+	return err
+}
+
+// Enumerate reads the entries in the ring buffer
+func (k *KvdbSink) Enumerate(req *api.SdkAuditEnumerateRequest) ([]*api.SdkAuditEntry, error) {
+	kvps, err := k.kv.Enumerate(k.prefix + kvdbEntriesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]*api.SdkAuditEntry, 0, len(kvps))
+	for _, kvp := range kvps {
+		entry := &api.SdkAuditEntry{}
+		if err := protojson.Unmarshal(kvp.Value, entry); err != nil {
+			logrus.Warnf("Skipping invalid audit log entry %s: %v", kvp.Key, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	return Filter(req, entries), nil
+}
@@ -0,0 +1,57 @@
+/*
+Package audit records the mutating requests made to the SDK server
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package audit
+
+import (
+	"log/syslog"
+
+	"github.com/libopenstorage/openstorage/api"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// SyslogSink sends the audit log to the local syslog daemon, which is
+// responsible for storing it. Its entries cannot be enumerated.
+type SyslogSink struct {
+	writer *syslog.Writer
+}
+
+// Check interface
+var _ Sink = &SyslogSink{}
+
+// NewSyslogSink returns a sink sending each entry in JSON to syslog with
+// the given tag, using the auth facility
+func NewSyslogSink(tag string) (*SyslogSink, error) {
+	w, err := syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{writer: w}, nil
+}
+
+// Write sends an entry to syslog
+func (s *SyslogSink) Write(entry *api.SdkAuditEntry) error {
+	b, err := protojson.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	return s.writer.Info(string(b))
+}
+
+// Close closes the connection to syslog
+func (s *SyslogSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,988 @@
+package defrag
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/exec"
+	"github.com/libopenstorage/openstorage/pkg/parser"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+	"github.com/libopenstorage/openstorage/pkg/schedule"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+const (
+	defragPrefix      = "cluster/defrag/"
+	assignmentsPrefix = defragPrefix + "assignments/"
+	nodeStatusPrefix  = defragPrefix + "nodes/"
+	volumesPrefix     = defragPrefix + "volumes/"
+
+	// unknownPool is the pool of the volumes whose pool on this node is
+	// unknown
+	unknownPool = "unknown"
+
+	// DefaultPollInterval is how often a node looks for the defrag
+	// assigned to it
+	DefaultPollInterval = 10 * time.Second
+	// DefaultMaxVolumesPerNode is the number of volumes defragmented in
+	// parallel on a node
+	DefaultMaxVolumesPerNode = 2
+	// DefaultMaxVolumesPerPool is the number of volumes defragmented in
+	// parallel on a pool
+	DefaultMaxVolumesPerPool = 1
+	// DefaultMaxDuration is the time window of the runs of the schedules
+	// without a max duration
+	DefaultMaxDuration = 24 * time.Hour
+)
+
+// Cluster returns the nodes of the cluster. It is implemented by
+// cluster.Cluster.
+type Cluster interface {
+	Enumerate() (api.Cluster, error)
+}
+
+// EngineConfig is the configuration of the defrag engine
+type EngineConfig struct {
+	// NodeID is the id of this node
+	NodeID string
+	// Cluster is used to select the nodes of the runs and report their
+	// status
+	Cluster Cluster
+	// Driver is the volume driver of this node
+	Driver volume.VolumeDriver
+	// Kvdb saves the assignments of the nodes and their status
+	Kvdb kvdb.Kvdb
+	// Schedules runs the DEFRAG schedules
+	Schedules *schedule.KvdbRegistry
+	// (optional) MaxVolumesPerNode is the number of volumes defragmented in
+	// parallel on a node. Defaults to DefaultMaxVolumesPerNode.
+	MaxVolumesPerNode int
+	// (optional) MaxVolumesPerPool is the number of volumes defragmented in
+	// parallel on a pool. Defaults to DefaultMaxVolumesPerPool.
+	MaxVolumesPerPool int
+	// (optional) MaxDuration is the time window of the runs of the
+	// schedules without a max duration. Defaults to DefaultMaxDuration.
+	MaxDuration time.Duration
+}
+
+// VolumeStatus is the outcome of the last defrag of a volume
+type VolumeStatus struct {
+	// VolumeID is the id of the volume
+	VolumeID string `json:"volume_id"`
+	// NodeID is the node which defragmented the volume
+	NodeID string `json:"node_id"`
+	// PoolUUID is the pool of the volume on the node
+	PoolUUID string `json:"pool_uuid"`
+	// ScheduleID is the schedule which defragmented the volume
+	ScheduleID string `json:"schedule_id"`
+	// Running is true while the volume is defragmented
+	Running bool `json:"running"`
+	// Success is true if the defrag finished
+	Success bool `json:"success"`
+	// Message describes why the defrag did not finish
+	Message string `json:"message,omitempty"`
+	// FragmentationBefore is the fragmentation of the filesystem in
+	// percent before the defrag, or -1 if it is unknown
+	FragmentationBefore float64 `json:"fragmentation_before"`
+	// FragmentationAfter is the fragmentation of the filesystem in percent
+	// after the defrag, or -1 if it is unknown
+	FragmentationAfter float64 `json:"fragmentation_after"`
+	// StartTime is when the defrag started
+	StartTime time.Time `json:"start_time"`
+	// CompleteTime is when the defrag stopped
+	CompleteTime time.Time `json:"complete_time,omitempty"`
+}
+
+// assignment is the defrag assigned to a node by the node running the
+// schedule
+type assignment struct {
+	// ScheduleID is the schedule of the run
+	ScheduleID string `json:"schedule_id"`
+	// Deadline is the end of the time window of the run, if any
+	Deadline time.Time `json:"deadline,omitempty"`
+}
+
+// Engine is a Provider which defragments the ext4 and xfs volumes mounted on
+// the nodes with DEFRAG schedules. The node which runs a schedule assigns the
+// defrag to the selected nodes through kvdb, a few nodes at a time, and each
+// node defragments its volumes until they are done or the time window of
+// the schedule ends. A volume interrupted at the end of a window is
+// defragmented first in the next run.
+type Engine struct {
+	config       EngineConfig
+	pollInterval time.Duration
+	stopTimeout  time.Duration
+	run          runFunc
+
+	lock            sync.Mutex
+	cancel          context.CancelFunc
+	runningSchedule string
+	stop            chan struct{}
+	wg              sync.WaitGroup
+
+	// statusLock serializes the updates of the status of this node
+	statusLock sync.Mutex
+}
+
+// Check interfaces
+var _ Provider = &Engine{}
+var _ schedule.Runner = &Engine{}
+
+// NewEngine returns a defrag provider which defragments the volumes of the
+// nodes. It registers the runner of the defrag schedules with the schedule
+// registry.
+func NewEngine(config *EngineConfig) (*Engine, error) {
+	if config == nil {
+		return nil, fmt.Errorf("Must supply a configuration for the defrag engine")
+	} else if len(config.NodeID) == 0 {
+		return nil, fmt.Errorf("Must supply a node id for the defrag engine")
+	} else if config.Cluster == nil {
+		return nil, fmt.Errorf("Must supply a cluster for the defrag engine")
+	} else if config.Driver == nil {
+		return nil, fmt.Errorf("Must supply a volume driver for the defrag engine")
+	} else if config.Kvdb == nil {
+		return nil, fmt.Errorf("Must supply a kvdb instance for the defrag engine")
+	} else if config.Schedules == nil {
+		return nil, fmt.Errorf("Must supply a schedule registry for the defrag engine")
+	}
+
+	e := &Engine{
+		config:       *config,
+		pollInterval: DefaultPollInterval,
+		stopTimeout:  exec.DefaultStopTimeout,
+		run:          exec.RunContext,
+	}
+	if e.config.MaxVolumesPerNode <= 0 {
+		e.config.MaxVolumesPerNode = DefaultMaxVolumesPerNode
+	}
+	if e.config.MaxVolumesPerPool <= 0 {
+		e.config.MaxVolumesPerPool = DefaultMaxVolumesPerPool
+	}
+	if e.config.MaxDuration <= 0 {
+		e.config.MaxDuration = DefaultMaxDuration
+	}
+	if err := config.Schedules.RegisterRunner(api.Job_DEFRAG, e); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func assignmentKey(nodeID string) string {
+	return assignmentsPrefix + nodeID
+}
+
+func nodeStatusKey(nodeID string) string {
+	return nodeStatusPrefix + nodeID
+}
+
+func volumeKey(volumeID string) string {
+	return volumesPrefix + volumeID
+}
+
+// CreateDefragSchedule creates a DEFRAG schedule which runs the defrag task
+// on the selected nodes at the start time, for at most the max duration of
+// the task
+func (e *Engine) CreateDefragSchedule(
+	ctx context.Context,
+	in *api.SdkCreateDefragScheduleRequest,
+) (*api.SdkCreateDefragScheduleResponse, error) {
+	task := in.GetDefragTask()
+	if task == nil {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a defrag task")
+	} else if len(task.GetIncludeNodes()) > 0 &&
+		(len(task.GetExcludeNodes()) > 0 || len(task.GetNodeSelector()) > 0) {
+		return nil, status.Error(codes.InvalidArgument,
+			"Include nodes cannot be used with exclude nodes or a node selector")
+	} else if len(in.GetStartTime()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a start time")
+	}
+
+	task = proto.Clone(task).(*api.DefragJob)
+	task.ScheduleId = uuid.New()
+	task.CurrentRunningNodes = nil
+	s, err := e.config.Schedules.CreateSchedule(&api.Schedule{
+		Id:                 task.ScheduleId,
+		StartTime:          in.GetStartTime(),
+		MaxDurationMinutes: task.GetMaxDurationMinutes(),
+		Type:               api.Job_DEFRAG,
+		Tasks: []*api.Job{
+			{
+				Type: api.Job_DEFRAG,
+				Job: &api.Job_Defrag{
+					Defrag: task,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SdkCreateDefragScheduleResponse{
+		Schedule: s,
+	}, nil
+}
+
+// CleanUpDefragSchedules deletes all the DEFRAG schedules, and stops the
+// defrag on all the nodes
+func (e *Engine) CleanUpDefragSchedules(
+	ctx context.Context,
+	in *api.SdkCleanUpDefragSchedulesRequest,
+) (*api.SdkCleanUpDefragSchedulesResponse, error) {
+	resp, err := e.config.Schedules.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{
+		Type: api.Job_DEFRAG.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range resp.GetSchedules() {
+		if _, err := e.config.Schedules.DeleteSchedule(ctx, &api.SdkDeleteScheduleRequest{
+			Id:   s.GetId(),
+			Type: api.Job_DEFRAG.String(),
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	// The nodes stop when their assignment is deleted
+	if err := e.config.Kvdb.DeleteTree(assignmentsPrefix); err != nil && err != kvdb.ErrNotFound {
+		return nil, status.Errorf(codes.Internal, "Failed to stop the defrag of the nodes: %v", err)
+	}
+	e.lock.Lock()
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.lock.Unlock()
+
+	logrus.Infof("Defrag schedules are deleted")
+	return &api.SdkCleanUpDefragSchedulesResponse{}, nil
+}
+
+// GetDefragNodeStatus returns the defrag status of a node
+func (e *Engine) GetDefragNodeStatus(
+	ctx context.Context,
+	in *api.SdkGetDefragNodeStatusRequest,
+) (*api.SdkGetDefragNodeStatusResponse, error) {
+	if len(in.GetNodeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a node id")
+	}
+	cl, err := e.config.Cluster.Enumerate()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the nodes of the cluster: %v", err)
+	}
+	n := cl.FindNode(in.GetNodeId())
+	if n == nil {
+		return nil, status.Errorf(codes.NotFound, "Node %s not found in the cluster", in.GetNodeId())
+	}
+	volumes, err := e.EnumerateVolumeStatus()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the defrag status of the volumes: %v", err)
+	}
+	return e.nodeStatusResponse(n, volumes)
+}
+
+// EnumerateDefragStatus returns the defrag status of all the nodes of the
+// cluster
+func (e *Engine) EnumerateDefragStatus(
+	ctx context.Context,
+	in *api.SdkEnumerateDefragStatusRequest,
+) (*api.SdkEnumerateDefragStatusResponse, error) {
+	cl, err := e.config.Cluster.Enumerate()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the nodes of the cluster: %v", err)
+	}
+
+	volumes, err := e.EnumerateVolumeStatus()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the defrag status of the volumes: %v", err)
+	}
+
+	statuses := make(map[string]*api.SdkGetDefragNodeStatusResponse, len(cl.Nodes))
+	for _, n := range cl.Nodes {
+		resp, err := e.nodeStatusResponse(n, volumes)
+		if err != nil {
+			return nil, err
+		}
+		statuses[n.Id] = resp
+	}
+	return &api.SdkEnumerateDefragStatusResponse{
+		Status: statuses,
+	}, nil
+}
+
+// nodeStatusResponse returns the defrag status of a node with the status of
+// the volumes it defragmented
+func (e *Engine) nodeStatusResponse(
+	n *api.Node,
+	volumes []*VolumeStatus,
+) (*api.SdkGetDefragNodeStatusResponse, error) {
+	nodeStatus, err := e.getNodeStatus(n.Id)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the defrag status of node %s: %v", n.Id, err)
+	}
+
+	usage := make(map[string]uint32, len(n.Pools))
+	for i := range n.Pools {
+		pool := &n.Pools[i]
+		if pool.GetTotalSize() > 0 {
+			usage[pool.GetUuid()] = uint32(pool.GetUsed() * 100 / pool.GetTotalSize())
+		}
+	}
+	volumeStatus := make([]*api.DefragVolumeStatus, 0)
+	for _, vs := range volumes {
+		if vs.NodeID == n.Id {
+			volumeStatus = append(volumeStatus, vs.toSdk())
+		}
+	}
+	return &api.SdkGetDefragNodeStatusResponse{
+		DefragNodeStatus:  nodeStatus,
+		DataIp:            n.DataIp,
+		SchedulerNodeName: n.SchedulerNodeName,
+		PoolUsage:         usage,
+		VolumeStatus:      volumeStatus,
+	}, nil
+}
+
+// getNodeStatus returns the saved defrag status of a node, or an empty
+// status if the node has not run defrag yet
+func (e *Engine) getNodeStatus(nodeID string) (*api.DefragNodeStatus, error) {
+	kvp, err := e.config.Kvdb.Get(nodeStatusKey(nodeID))
+	if err == kvdb.ErrNotFound {
+		return &api.DefragNodeStatus{
+			PoolStatus: make(map[string]*api.DefragPoolStatus),
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	nodeStatus := &api.DefragNodeStatus{}
+	if err := jsonpb.UnmarshalString(string(kvp.Value), nodeStatus); err != nil {
+		return nil, err
+	}
+	if nodeStatus.PoolStatus == nil {
+		nodeStatus.PoolStatus = make(map[string]*api.DefragPoolStatus)
+	}
+	return nodeStatus, nil
+}
+
+// updateNodeStatus changes the status of this node with fn and saves it
+func (e *Engine) updateNodeStatus(fn func(nodeStatus *api.DefragNodeStatus)) {
+	e.statusLock.Lock()
+	defer e.statusLock.Unlock()
+
+	nodeStatus, err := e.getNodeStatus(e.config.NodeID)
+	if err != nil {
+		logrus.Warnf("Failed to get the defrag status of node %s: %v", e.config.NodeID, err)
+		return
+	}
+	fn(nodeStatus)
+	nodeStatus.LastUpdateTime = prototime.Now()
+
+	marshaler := jsonpb.Marshaler{OrigName: true}
+	value, err := marshaler.MarshalToString(nodeStatus)
+	if err == nil {
+		_, err = e.config.Kvdb.Put(nodeStatusKey(e.config.NodeID), value, 0)
+	}
+	if err != nil {
+		logrus.Warnf("Failed to save the defrag status of node %s: %v", e.config.NodeID, err)
+	}
+}
+
+// EnumerateVolumeStatus returns the outcome of the last defrag of each
+// volume, sorted by volume id
+func (e *Engine) EnumerateVolumeStatus() ([]*VolumeStatus, error) {
+	kvps, err := e.config.Kvdb.Enumerate(volumesPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*VolumeStatus, 0, len(kvps))
+	for _, kvp := range kvps {
+		vs := &VolumeStatus{}
+		if err := json.Unmarshal(kvp.Value, vs); err != nil {
+			logrus.Warnf("Failed to unmarshal the defrag status %s: %v", kvp.Key, err)
+			continue
+		}
+		statuses = append(statuses, vs)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].VolumeID < statuses[j].VolumeID
+	})
+	return statuses, nil
+}
+
+// toSdk returns the SDK message of the status of a volume
+func (vs *VolumeStatus) toSdk() *api.DefragVolumeStatus {
+	sdk := &api.DefragVolumeStatus{
+		VolumeId:            vs.VolumeID,
+		PoolUuid:            vs.PoolUUID,
+		ScheduleId:          vs.ScheduleID,
+		Running:             vs.Running,
+		Success:             vs.Success,
+		Message:             vs.Message,
+		FragmentationBefore: vs.FragmentationBefore,
+		FragmentationAfter:  vs.FragmentationAfter,
+		StartTime:           prototime.TimeToTimestamp(vs.StartTime),
+	}
+	if !vs.CompleteTime.IsZero() {
+		sdk.CompleteTime = prototime.TimeToTimestamp(vs.CompleteTime)
+	}
+	return sdk
+}
+
+func (e *Engine) saveVolumeStatus(vs *VolumeStatus) {
+	value, err := json.Marshal(vs)
+	if err == nil {
+		_, err = e.config.Kvdb.Put(volumeKey(vs.VolumeID), value, 0)
+	}
+	if err != nil {
+		logrus.Warnf("Failed to save the defrag status of volume %s: %v", vs.VolumeID, err)
+	}
+}
+
+// selectNodes returns the sorted ids of the nodes selected by a defrag task.
+// The nodes which are not up are skipped.
+func selectNodes(task *api.DefragJob, nodes []*api.Node) []string {
+	include := make(map[string]bool, len(task.GetIncludeNodes()))
+	for _, id := range task.GetIncludeNodes() {
+		include[id] = true
+	}
+	exclude := make(map[string]bool, len(task.GetExcludeNodes()))
+	for _, id := range task.GetExcludeNodes() {
+		exclude[id] = true
+	}
+
+	selected := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		if n.Status != api.Status_STATUS_OK ||
+			(len(include) > 0 && !include[n.Id]) ||
+			exclude[n.Id] ||
+			(len(task.GetNodeSelector()) > 0 && !parser.MatchLabelSelector(n.NodeLabels, task.GetNodeSelector())) {
+			continue
+		}
+		selected = append(selected, n.Id)
+	}
+	sort.Strings(selected)
+	return selected
+}
+
+// RunSchedule runs the defrag task of a schedule on the selected nodes, at
+// most max nodes in parallel at a time, until they are done or the time
+// window of the schedule ends. The nodes which have not started at the end
+// of the window are skipped until the next run. The window of the schedules
+// without a max duration is MaxDuration, so that a node which is down does
+// not block the run.
+func (e *Engine) RunSchedule(ctx context.Context, s *api.Schedule) error {
+	if len(s.GetTasks()) == 0 || s.GetTasks()[0].GetDefrag() == nil {
+		return fmt.Errorf("schedule %s has no defrag task", s.GetId())
+	}
+	task := s.GetTasks()[0].GetDefrag()
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, e.config.MaxDuration)
+		defer cancel()
+	}
+
+	cl, err := e.config.Cluster.Enumerate()
+	if err != nil {
+		return fmt.Errorf("failed to get the nodes of the cluster: %v", err)
+	}
+	nodes := selectNodes(task, cl.Nodes)
+	deadline, _ := ctx.Deadline()
+	a := &assignment{
+		ScheduleID: s.GetId(),
+		Deadline:   deadline,
+	}
+	parallel := int(task.GetMaxNodesInParallel())
+	if parallel <= 0 {
+		parallel = 1
+	}
+
+	var (
+		failedLock sync.Mutex
+		failed     []string
+		wg         sync.WaitGroup
+	)
+	sem := make(chan struct{}, parallel)
+	for _, id := range nodes {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := e.runOnNode(ctx, id, a); err != nil {
+				logrus.Warnf("Defrag of schedule %s failed on node %s: %v", s.GetId(), id, err)
+				failedLock.Lock()
+				failed = append(failed, id)
+				failedLock.Unlock()
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		logrus.Infof("Time window of defrag schedule %s ended", s.GetId())
+	}
+	if task.GetScheduleOneIterationOnly() {
+		if _, err := e.config.Schedules.DeleteSchedule(context.Background(), &api.SdkDeleteScheduleRequest{
+			Id:   s.GetId(),
+			Type: api.Job_DEFRAG.String(),
+		}); err != nil {
+			logrus.Warnf("Failed to delete defrag schedule %s: %v", s.GetId(), err)
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("defrag failed on nodes %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// runOnNode assigns the defrag to a node and waits until the node is done,
+// or the time window ends. It returns an error if the defrag of a pool of
+// the node did not succeed in this run.
+func (e *Engine) runOnNode(ctx context.Context, nodeID string, a *assignment) error {
+	value, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	assigned := time.Now()
+	kvp, err := e.config.Kvdb.Create(assignmentKey(nodeID), value, 0)
+	if err == kvdb.ErrExist {
+		return fmt.Errorf("node is already running defrag")
+	} else if err != nil {
+		return fmt.Errorf("failed to assign defrag to the node: %v", err)
+	}
+
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+	for {
+		_, err := e.config.Kvdb.Get(assignmentKey(nodeID))
+		if err == kvdb.ErrNotFound {
+			break
+		} else if err != nil {
+			logrus.Warnf("Failed to get the defrag assignment of node %s: %v", nodeID, err)
+		}
+		select {
+		case <-ctx.Done():
+			// The node stops by itself at the deadline, this stops it if
+			// its clock is late or it is down
+			e.unassign(nodeID, kvp)
+			return nil
+		case <-ticker.C:
+		}
+	}
+
+	nodeStatus, err := e.getNodeStatus(nodeID)
+	if err != nil {
+		return err
+	}
+	// The pools stopped at the end of the time window have not completed,
+	// and the pools which did not start were not run in this run
+	var failed []string
+	for poolID, pool := range nodeStatus.GetPoolStatus() {
+		started := prototime.TimestampToTime(pool.GetLastStartTime())
+		completed := prototime.TimestampToTime(pool.GetLastCompleteTime())
+		if started.Before(assigned) {
+			continue
+		}
+		if !pool.GetLastSuccess() && !completed.Before(started) {
+			failed = append(failed, poolID)
+		}
+	}
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		return fmt.Errorf("defrag failed on pools %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// unassign deletes the assignment of a node, unless it has been replaced by
+// another run
+func (e *Engine) unassign(nodeID string, kvp *kvdb.KVPair) {
+	_, err := e.config.Kvdb.CompareAndDelete(kvp, kvdb.KVModifiedIndex)
+	if err != nil && err != kvdb.ErrNotFound && err != kvdb.ErrModified && err != kvdb.ErrValueMismatch {
+		logrus.Warnf("Failed to delete the defrag assignment of node %s: %v", nodeID, err)
+	}
+}
+
+// Start looks for the defrag assigned to this node periodically
+func (e *Engine) Start() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.stop != nil {
+		return fmt.Errorf("Defrag engine is already started")
+	}
+	stop := make(chan struct{})
+	e.stop = stop
+
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		ticker := time.NewTicker(e.pollInterval)
+		defer ticker.Stop()
+		for {
+			e.poll()
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops looking for defrag assignments and stops the defrag in
+// progress on this node
+func (e *Engine) Stop() {
+	e.lock.Lock()
+	if e.stop != nil {
+		close(e.stop)
+		e.stop = nil
+	}
+	if e.cancel != nil {
+		e.cancel()
+	}
+	e.lock.Unlock()
+
+	e.wg.Wait()
+}
+
+// poll starts the defrag assigned to this node, or stops the defrag in
+// progress if its assignment has been deleted
+func (e *Engine) poll() {
+	var a *assignment
+	kvp, err := e.config.Kvdb.Get(assignmentKey(e.config.NodeID))
+	if err == nil {
+		a = &assignment{}
+		if err := json.Unmarshal(kvp.Value, a); err != nil {
+			logrus.Warnf("Failed to unmarshal the defrag assignment of node %s: %v", e.config.NodeID, err)
+			return
+		}
+	} else if err != kvdb.ErrNotFound {
+		logrus.Warnf("Failed to get the defrag assignment of node %s: %v", e.config.NodeID, err)
+		return
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.cancel != nil {
+		if a == nil || a.ScheduleID != e.runningSchedule {
+			logrus.Infof("Defrag of schedule %s is stopped on node %s", e.runningSchedule, e.config.NodeID)
+			e.cancel()
+		}
+		return
+	} else if a == nil || e.stop == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if !a.Deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(context.Background(), a.Deadline)
+	}
+	e.cancel = cancel
+	e.runningSchedule = a.ScheduleID
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer cancel()
+
+		e.runNode(ctx, a.ScheduleID)
+
+		e.lock.Lock()
+		e.cancel = nil
+		e.runningSchedule = ""
+		e.lock.Unlock()
+		if _, err := e.config.Kvdb.Delete(assignmentKey(e.config.NodeID)); err != nil && err != kvdb.ErrNotFound {
+			logrus.Warnf("Failed to delete the defrag assignment of node %s: %v", e.config.NodeID, err)
+		}
+	}()
+}
+
+// poolRun is the defrag of the volumes of a pool in one run
+type poolRun struct {
+	uuid    string
+	volumes []*api.Volume
+	total   uint64
+
+	lock    sync.Mutex
+	done    uint64
+	failed  bool
+	stopped bool
+	// pending is the volume saved as the last volume of the pool, which is
+	// defragmented first in the next run if it is interrupted
+	pending string
+}
+
+// localVolumes returns the ext4 and xfs volumes mounted on this node,
+// grouped by pool
+func (e *Engine) localVolumes() (map[string]*poolRun, error) {
+	cl, err := e.config.Cluster.Enumerate()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the nodes of the cluster: %v", err)
+	}
+	self := cl.FindNode(e.config.NodeID)
+	if self == nil {
+		self = &api.Node{Id: e.config.NodeID}
+	}
+	vols, err := e.config.Driver.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the volumes: %v", err)
+	}
+
+	pools := make(map[string]*poolRun)
+	for _, v := range vols {
+		attachedOn := v.GetAttachedOn()
+		if len(attachedOn) == 0 ||
+			(attachedOn != self.Id && attachedOn != self.MgmtIp && attachedOn != self.DataIp) ||
+			len(mountPath(v)) == 0 ||
+			getFilesystem(v, e.run, e.stopTimeout) == nil {
+			continue
+		}
+
+		poolID := volumePool(v, self.Id)
+		p, ok := pools[poolID]
+		if !ok {
+			p = &poolRun{uuid: poolID}
+			pools[poolID] = p
+		}
+		p.volumes = append(p.volumes, v)
+		p.total += volumeWeight(v)
+	}
+	return pools, nil
+}
+
+// volumePool returns the pool of a volume on a node
+func volumePool(v *api.Volume, nodeID string) string {
+	for _, rs := range v.GetReplicaSets() {
+		for i, id := range rs.GetNodes() {
+			if id == nodeID && i < len(rs.GetPoolUuids()) {
+				return rs.GetPoolUuids()[i]
+			}
+		}
+	}
+	return unknownPool
+}
+
+// volumeWeight returns the bytes of a volume used to compute the progress
+// of its pool
+func volumeWeight(v *api.Volume) uint64 {
+	if v.GetUsage() > 0 {
+		return v.GetUsage()
+	}
+	return 1
+}
+
+// runNode defragments the volumes of this node until they are done or the
+// context is done. Each pool resumes with the volume interrupted in the last
+// run, and the volumes of a pool are defragmented in order of their ids.
+func (e *Engine) runNode(ctx context.Context, scheduleID string) {
+	pools, err := e.localVolumes()
+	if err != nil {
+		logrus.Warnf("Defrag of schedule %s failed on node %s: %v", scheduleID, e.config.NodeID, err)
+		return
+	}
+
+	lastVolumes := make(map[string]string, len(pools))
+	e.updateNodeStatus(func(nodeStatus *api.DefragNodeStatus) {
+		nodeStatus.RunningSchedule = scheduleID
+		for poolID := range pools {
+			pool := poolStatus(nodeStatus, poolID)
+			lastVolumes[poolID] = pool.GetLastVolumeId()
+			pool.NumIterations++
+			pool.Running = true
+			pool.LastSuccess = false
+			pool.LastStartTime = prototime.Now()
+			pool.ProgressPercentage = 0
+		}
+	})
+	for poolID, p := range pools {
+		sortVolumes(p.volumes, lastVolumes[poolID])
+	}
+	logrus.Infof("Defrag of schedule %s started on node %s with %d pools", scheduleID, e.config.NodeID, len(pools))
+
+	nodeSem := make(chan struct{}, e.config.MaxVolumesPerNode)
+	var wg sync.WaitGroup
+	for _, p := range pools {
+		volumes := make(chan *api.Volume, len(p.volumes))
+		for _, v := range p.volumes {
+			volumes <- v
+		}
+		close(volumes)
+
+		for i := 0; i < e.config.MaxVolumesPerPool; i++ {
+			wg.Add(1)
+			go func(p *poolRun) {
+				defer wg.Done()
+				for v := range volumes {
+					select {
+					case nodeSem <- struct{}{}:
+					case <-ctx.Done():
+					}
+					if ctx.Err() != nil {
+						p.lock.Lock()
+						p.stopped = true
+						p.lock.Unlock()
+						return
+					}
+					e.defragVolume(ctx, scheduleID, p, v)
+					<-nodeSem
+				}
+			}(p)
+		}
+	}
+	wg.Wait()
+
+	e.updateNodeStatus(func(nodeStatus *api.DefragNodeStatus) {
+		nodeStatus.RunningSchedule = ""
+		for poolID, p := range pools {
+			pool := poolStatus(nodeStatus, poolID)
+			pool.Running = false
+			if !p.stopped {
+				pool.LastSuccess = !p.failed
+				pool.LastCompleteTime = prototime.Now()
+			}
+		}
+	})
+	logrus.Infof("Defrag of schedule %s stopped on node %s", scheduleID, e.config.NodeID)
+}
+
+// poolStatus returns the status of the pool in the status of the node, which
+// is created if missing, e.g. when the status was removed during a run
+func poolStatus(nodeStatus *api.DefragNodeStatus, poolID string) *api.DefragPoolStatus {
+	pool, ok := nodeStatus.PoolStatus[poolID]
+	if !ok {
+		pool = &api.DefragPoolStatus{LastOffset: -1}
+		nodeStatus.PoolStatus[poolID] = pool
+	}
+	return pool
+}
+
+// sortVolumes sorts the volumes by id, starting with the pending volume
+func sortVolumes(volumes []*api.Volume, pending string) {
+	sort.Slice(volumes, func(i, j int) bool {
+		if volumes[i].GetId() == pending || volumes[j].GetId() == pending {
+			return volumes[i].GetId() == pending
+		}
+		return volumes[i].GetId() < volumes[j].GetId()
+	})
+}
+
+// defragVolume measures the fragmentation of a volume, defragments it and
+// measures it again. A volume interrupted by the end of the time window
+// stays the pending volume of its pool.
+func (e *Engine) defragVolume(ctx context.Context, scheduleID string, p *poolRun, v *api.Volume) {
+	fs := getFilesystem(v, e.run, e.stopTimeout)
+	vs := &VolumeStatus{
+		VolumeID:            v.GetId(),
+		NodeID:              e.config.NodeID,
+		PoolUUID:            p.uuid,
+		ScheduleID:          scheduleID,
+		Running:             true,
+		FragmentationBefore: -1,
+		FragmentationAfter:  -1,
+		StartTime:           time.Now(),
+	}
+	e.saveVolumeStatus(vs)
+	// The pool has one last volume, which is claimed by the first of the
+	// volumes defragmented in parallel
+	if p.claim(v.GetId()) {
+		e.updateNodeStatus(func(nodeStatus *api.DefragNodeStatus) {
+			pool := poolStatus(nodeStatus, p.uuid)
+			pool.LastVolumeId = v.GetId()
+			pool.LastOffset = 0
+		})
+	}
+
+	if frag, err := fs.fragmentation(ctx, v); err != nil {
+		logrus.Warnf("Failed to measure the fragmentation of volume %s: %v", v.GetId(), err)
+	} else {
+		vs.FragmentationBefore = frag
+	}
+
+	err := fs.defrag(ctx, v)
+	interrupted := err != nil && ctx.Err() != nil
+	vs.Running = false
+	vs.CompleteTime = time.Now()
+	p.lock.Lock()
+	if err == nil {
+		vs.Success = true
+		p.done += volumeWeight(v)
+	} else if interrupted {
+		vs.Message = "Stopped at the end of the time window"
+		p.stopped = true
+		if len(p.pending) == 0 {
+			p.pending = v.GetId()
+		}
+	} else {
+		vs.Message = err.Error()
+		p.failed = true
+		p.done += volumeWeight(v)
+	}
+	progress := uint32(p.done * 100 / p.total)
+	pending := p.pending == v.GetId()
+	if pending && !interrupted {
+		p.pending = ""
+	}
+	p.lock.Unlock()
+
+	if err == nil {
+		if frag, err := fs.fragmentation(ctx, v); err != nil {
+			logrus.Warnf("Failed to measure the fragmentation of volume %s: %v", v.GetId(), err)
+		} else {
+			vs.FragmentationAfter = frag
+		}
+		logrus.Infof("Defrag of volume %s is done, fragmentation %.2f%% -> %.2f%%",
+			v.GetId(), vs.FragmentationBefore, vs.FragmentationAfter)
+	} else {
+		logrus.Warnf("Defrag of volume %s stopped: %s", v.GetId(), vs.Message)
+	}
+	e.saveVolumeStatus(vs)
+
+	e.updateNodeStatus(func(nodeStatus *api.DefragNodeStatus) {
+		pool := poolStatus(nodeStatus, p.uuid)
+		pool.ProgressPercentage = progress
+		if interrupted && pending {
+			pool.LastVolumeId = v.GetId()
+			pool.LastOffset = 0
+		} else if !interrupted && pool.LastVolumeId == v.GetId() {
+			pool.LastVolumeId = ""
+			pool.LastOffset = -1
+		}
+	})
+}
+
+// claim makes a volume the last volume of the pool, unless another volume
+// defragmented in parallel is
+func (p *poolRun) claim(volumeID string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if len(p.pending) != 0 {
+		return false
+	}
+	p.pending = volumeID
+	return true
+}
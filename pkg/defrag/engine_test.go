@@ -0,0 +1,509 @@
+package defrag
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+	"github.com/libopenstorage/openstorage/pkg/schedule"
+	"github.com/libopenstorage/openstorage/volume/drivers/mock"
+)
+
+// testCluster returns a fixed list of nodes
+type testCluster struct {
+	cluster api.Cluster
+}
+
+func (c *testCluster) Enumerate() (api.Cluster, error) {
+	return c.cluster, nil
+}
+
+// testFilesystems fakes e4defrag, xfs_fsr and xfs_db on the mount paths of
+// the volumes. The filesystems are fragmented until they are defragmented.
+type testFilesystems struct {
+	lock    sync.Mutex
+	defrags []string
+	done    map[string]bool
+	// block is the path whose defrag runs until the context is done
+	block string
+	// active is the number of defrags in progress, by pool and in total
+	active    map[string]int
+	maxActive map[string]int
+	pools     map[string]string
+}
+
+func newTestFilesystems() *testFilesystems {
+	return &testFilesystems{
+		done:      make(map[string]bool),
+		active:    make(map[string]int),
+		maxActive: make(map[string]int),
+		pools:     make(map[string]string),
+	}
+}
+
+func (f *testFilesystems) run(ctx context.Context, stopTimeout time.Duration, bin string, args ...string) ([]byte, error) {
+	path := args[len(args)-1]
+	f.lock.Lock()
+	done := f.done[strings.TrimPrefix(path, "/dev")]
+	f.lock.Unlock()
+
+	switch {
+	case bin == e4defragBin && args[0] == "-c":
+		if done {
+			return []byte("Fragmentation score\t\t\t5"), nil
+		}
+		return []byte("Fragmentation score\t\t\t40"), nil
+	case bin == xfsDbBin:
+		if done {
+			return []byte("actual 10, ideal 10, fragmentation factor 0.00%"), nil
+		}
+		return []byte("actual 12, ideal 10, fragmentation factor 16.67%"), nil
+	case bin == e4defragBin || bin == xfsFsrBin:
+		f.begin(path)
+		defer f.end(path)
+		if path == f.block {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		time.Sleep(10 * time.Millisecond)
+		f.lock.Lock()
+		f.done[path] = true
+		f.lock.Unlock()
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unexpected command %s %v", bin, args)
+}
+
+func (f *testFilesystems) begin(path string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.defrags = append(f.defrags, path)
+	for _, key := range []string{"", f.pools[path]} {
+		f.active[key]++
+		if f.active[key] > f.maxActive[key] {
+			f.maxActive[key] = f.active[key]
+		}
+	}
+}
+
+func (f *testFilesystems) end(path string) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.active[""]--
+	f.active[f.pools[path]]--
+}
+
+func (f *testFilesystems) getDefrags() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string{}, f.defrags...)
+}
+
+// testVolume returns a volume mounted on /<id> and /dev/<id>
+func testVolume(id, attachedOn, pool string, format api.FSType) *api.Volume {
+	return &api.Volume{
+		Id:          id,
+		Format:      format,
+		AttachedOn:  attachedOn,
+		DevicePath:  "/dev/" + id,
+		AttachPath:  []string{"/" + id},
+		ReplicaSets: []*api.ReplicaSet{{Nodes: []string{"node1", "node2"}, PoolUuids: []string{pool, pool}}},
+	}
+}
+
+func newTestKvdb(t *testing.T) kvdb.Kvdb {
+	kv, err := kvdb.New(mem.Name, "defrag_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	return kv
+}
+
+func newTestEngine(
+	t *testing.T,
+	ctrl *gomock.Controller,
+	kv kvdb.Kvdb,
+	nodeID string,
+	nodes []*api.Node,
+	vols []*api.Volume,
+	fs *testFilesystems,
+) *Engine {
+	schedules, err := schedule.NewKvdbRegistry(kv, nodeID)
+	require.NoError(t, err)
+	driver := mock.NewMockVolumeDriver(ctrl)
+	driver.EXPECT().Enumerate(&api.VolumeLocator{}, nil).Return(vols, nil).AnyTimes()
+
+	e, err := NewEngine(&EngineConfig{
+		NodeID: nodeID,
+		Cluster: &testCluster{
+			cluster: api.Cluster{NodeId: nodeID, Nodes: nodes},
+		},
+		Driver:    driver,
+		Kvdb:      kv,
+		Schedules: schedules,
+	})
+	require.NoError(t, err)
+	e.pollInterval = 10 * time.Millisecond
+	e.run = fs.run
+	return e
+}
+
+func nodeStatus(t *testing.T, e *Engine, nodeID string) *api.SdkGetDefragNodeStatusResponse {
+	resp, err := e.GetDefragNodeStatus(context.Background(), &api.SdkGetDefragNodeStatusRequest{
+		NodeId: nodeID,
+	})
+	require.NoError(t, err)
+	return resp
+}
+
+func TestEngineSchedules(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kv := newTestKvdb(t)
+	e := newTestEngine(t, ctrl, kv, "node1", nil, nil, newTestFilesystems())
+	ctx := context.Background()
+
+	_, err := e.CreateDefragSchedule(ctx, &api.SdkCreateDefragScheduleRequest{StartTime: "daily=19:15"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = e.CreateDefragSchedule(ctx, &api.SdkCreateDefragScheduleRequest{
+		DefragTask: &api.DefragJob{
+			IncludeNodes: []string{"node1"},
+			ExcludeNodes: []string{"node2"},
+		},
+		StartTime: "daily=19:15",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := e.CreateDefragSchedule(ctx, &api.SdkCreateDefragScheduleRequest{
+		DefragTask: &api.DefragJob{
+			MaxDurationMinutes: 60,
+			ExcludeNodes:       []string{"node2"},
+		},
+		StartTime: "daily=19:15",
+	})
+	require.NoError(t, err)
+	s := resp.GetSchedule()
+	assert.Equal(t, api.Job_DEFRAG, s.GetType())
+	assert.Equal(t, uint32(60), s.GetMaxDurationMinutes())
+	require.Len(t, s.GetTasks(), 1)
+	assert.Equal(t, s.GetId(), s.GetTasks()[0].GetDefrag().GetScheduleId())
+
+	// Clean up deletes the schedules and the assignments of the nodes
+	_, err = kv.Create(assignmentKey("node2"), "{}", 0)
+	require.NoError(t, err)
+	_, err = e.CleanUpDefragSchedules(ctx, &api.SdkCleanUpDefragSchedulesRequest{})
+	require.NoError(t, err)
+	enumResp, err := e.config.Schedules.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{Type: "DEFRAG"})
+	require.NoError(t, err)
+	assert.Empty(t, enumResp.GetSchedules())
+	_, err = kv.Get(assignmentKey("node2"))
+	assert.Equal(t, kvdb.ErrNotFound, err)
+}
+
+func TestEngineRunNode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	nodes := []*api.Node{
+		{
+			Id:     "node1",
+			MgmtIp: "10.0.0.1",
+			DataIp: "10.0.1.1",
+			Pools:  []api.StoragePool{{Uuid: "pool1", Used: 50, TotalSize: 100}},
+		},
+		{Id: "node2"},
+	}
+	vols := []*api.Volume{
+		testVolume("v1", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+		testVolume("v2", "10.0.0.1", "pool1", api.FSType_FS_TYPE_XFS),
+		testVolume("v3", "node1", "pool2", api.FSType_FS_TYPE_EXT4),
+		// Not attached on this node
+		testVolume("v4", "node2", "pool1", api.FSType_FS_TYPE_EXT4),
+		// Not ext4 or xfs
+		testVolume("v5", "node1", "pool1", api.FSType_FS_TYPE_BTRFS),
+		// Not mounted
+		{Id: "v6", Format: api.FSType_FS_TYPE_EXT4, AttachedOn: "node1"},
+	}
+	fs := newTestFilesystems()
+	fs.pools = map[string]string{"/v1": "pool1", "/v2": "pool1", "/v3": "pool2"}
+	e := newTestEngine(t, ctrl, newTestKvdb(t), "node1", nodes, vols, fs)
+
+	e.runNode(context.Background(), "schedule1")
+	assert.ElementsMatch(t, []string{"/v1", "/v2", "/v3"}, fs.getDefrags())
+	assert.Equal(t, 1, fs.maxActive["pool1"])
+	assert.Equal(t, 2, fs.maxActive[""])
+
+	resp := nodeStatus(t, e, "node1")
+	assert.Equal(t, "10.0.1.1", resp.GetDataIp())
+	assert.Equal(t, map[string]uint32{"pool1": 50}, resp.GetPoolUsage())
+	assert.Empty(t, resp.GetDefragNodeStatus().GetRunningSchedule())
+	require.Len(t, resp.GetDefragNodeStatus().GetPoolStatus(), 2)
+	pool := resp.GetDefragNodeStatus().GetPoolStatus()["pool1"]
+	assert.Equal(t, uint32(1), pool.GetNumIterations())
+	assert.False(t, pool.GetRunning())
+	assert.True(t, pool.GetLastSuccess())
+	assert.NotNil(t, pool.GetLastCompleteTime())
+	assert.Equal(t, uint32(100), pool.GetProgressPercentage())
+	assert.Empty(t, pool.GetLastVolumeId())
+	assert.Equal(t, int64(-1), pool.GetLastOffset())
+
+	statuses, err := e.EnumerateVolumeStatus()
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	assert.Equal(t, "v1", statuses[0].VolumeID)
+	assert.True(t, statuses[0].Success)
+	assert.Equal(t, float64(40), statuses[0].FragmentationBefore)
+	assert.Equal(t, float64(5), statuses[0].FragmentationAfter)
+	assert.Equal(t, "pool1", statuses[1].PoolUUID)
+	assert.Equal(t, 16.67, statuses[1].FragmentationBefore)
+	assert.Equal(t, float64(0), statuses[1].FragmentationAfter)
+	volumeStatus := nodeStatus(t, e, "node1").GetVolumeStatus()
+	require.Len(t, volumeStatus, 3)
+	assert.Equal(t, "v1", volumeStatus[0].GetVolumeId())
+	assert.Equal(t, "pool1", volumeStatus[0].GetPoolUuid())
+	assert.True(t, volumeStatus[0].GetSuccess())
+	assert.Equal(t, float64(40), volumeStatus[0].GetFragmentationBefore())
+	assert.Equal(t, float64(5), volumeStatus[0].GetFragmentationAfter())
+	assert.NotNil(t, volumeStatus[0].GetStartTime())
+	assert.NotNil(t, volumeStatus[0].GetCompleteTime())
+
+	// The other nodes have no status until they run defrag
+	resp = nodeStatus(t, e, "node2")
+	assert.Empty(t, resp.GetDefragNodeStatus().GetPoolStatus())
+	assert.Empty(t, resp.GetVolumeStatus())
+	enumResp, err := e.EnumerateDefragStatus(context.Background(), &api.SdkEnumerateDefragStatusRequest{})
+	require.NoError(t, err)
+	assert.Len(t, enumResp.GetStatus(), 2)
+	assert.Len(t, enumResp.GetStatus()["node1"].GetVolumeStatus(), 3)
+	assert.Empty(t, enumResp.GetStatus()["node2"].GetVolumeStatus())
+	_, err = e.GetDefragNodeStatus(context.Background(), &api.SdkGetDefragNodeStatusRequest{NodeId: "node3"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestEngineRunNodeWindowEnd(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	vols := []*api.Volume{
+		testVolume("v1", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+		testVolume("v2", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+		testVolume("v3", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+	}
+	fs := newTestFilesystems()
+	fs.block = "/v2"
+	e := newTestEngine(t, ctrl, newTestKvdb(t), "node1", []*api.Node{{Id: "node1"}}, vols, fs)
+
+	// The window ends during the defrag of v2
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	e.runNode(ctx, "schedule1")
+	assert.Equal(t, []string{"/v1", "/v2"}, fs.getDefrags())
+
+	pool := nodeStatus(t, e, "node1").GetDefragNodeStatus().GetPoolStatus()["pool1"]
+	assert.False(t, pool.GetRunning())
+	assert.False(t, pool.GetLastSuccess())
+	assert.Nil(t, pool.GetLastCompleteTime())
+	assert.Equal(t, uint32(33), pool.GetProgressPercentage())
+	assert.Equal(t, "v2", pool.GetLastVolumeId())
+	assert.Equal(t, int64(0), pool.GetLastOffset())
+
+	statuses, err := e.EnumerateVolumeStatus()
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.False(t, statuses[1].Success)
+	assert.Contains(t, statuses[1].Message, "end of the time window")
+	assert.Equal(t, float64(-1), statuses[1].FragmentationAfter)
+
+	// The next run resumes with v2
+	fs.block = ""
+	e.runNode(context.Background(), "schedule1")
+	assert.Equal(t, []string{"/v2", "/v1", "/v3"}, fs.getDefrags()[2:])
+	pool = nodeStatus(t, e, "node1").GetDefragNodeStatus().GetPoolStatus()["pool1"]
+	assert.Equal(t, uint32(2), pool.GetNumIterations())
+	assert.True(t, pool.GetLastSuccess())
+	assert.Empty(t, pool.GetLastVolumeId())
+}
+
+func TestEngineRunSchedule(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kv := newTestKvdb(t)
+	nodes := []*api.Node{
+		{Id: "node1", Status: api.Status_STATUS_OK},
+		{Id: "node2", Status: api.Status_STATUS_OK},
+		{Id: "node3", Status: api.Status_STATUS_OFFLINE},
+	}
+	fs := newTestFilesystems()
+	fs.pools = map[string]string{"/v1": "node1", "/v2": "node2"}
+	e1 := newTestEngine(t, ctrl, kv, "node1", nodes, []*api.Volume{
+		testVolume("v1", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+	}, fs)
+	e2 := newTestEngine(t, ctrl, kv, "node2", nodes, []*api.Volume{
+		testVolume("v2", "node2", "pool1", api.FSType_FS_TYPE_XFS),
+	}, fs)
+	require.NoError(t, e1.Start())
+	defer e1.Stop()
+	require.NoError(t, e2.Start())
+	defer e2.Stop()
+
+	resp, err := e1.CreateDefragSchedule(context.Background(), &api.SdkCreateDefragScheduleRequest{
+		DefragTask: &api.DefragJob{
+			MaxNodesInParallel:       1,
+			ScheduleOneIterationOnly: true,
+		},
+		StartTime: "daily=19:15",
+	})
+	require.NoError(t, err)
+	s := resp.GetSchedule()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, e1.RunSchedule(ctx, s))
+	assert.Equal(t, []string{"/v1", "/v2"}, fs.getDefrags())
+	assert.Equal(t, 1, fs.maxActive[""])
+	for _, id := range []string{"node1", "node2"} {
+		pool := nodeStatus(t, e2, id).GetDefragNodeStatus().GetPoolStatus()["pool1"]
+		assert.True(t, pool.GetLastSuccess(), id)
+	}
+
+	// The schedule of one iteration is deleted after its run
+	_, err = e1.config.Schedules.InspectSchedule(context.Background(), &api.SdkInspectScheduleRequest{
+		Id:   s.GetId(),
+		Type: "DEFRAG",
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestEngineCleanUpStopsNodes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kv := newTestKvdb(t)
+	fs := newTestFilesystems()
+	fs.block = "/v1"
+	e := newTestEngine(t, ctrl, kv, "node1", []*api.Node{{Id: "node1"}}, []*api.Volume{
+		testVolume("v1", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+	}, fs)
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	_, err := kv.Create(assignmentKey("node1"), `{"schedule_id":"schedule1"}`, 0)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return len(fs.getDefrags()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "schedule1", nodeStatus(t, e, "node1").GetDefragNodeStatus().GetRunningSchedule())
+
+	_, err = e.CleanUpDefragSchedules(context.Background(), &api.SdkCleanUpDefragSchedulesRequest{})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return !nodeStatus(t, e, "node1").GetDefragNodeStatus().GetPoolStatus()["pool1"].GetRunning()
+	}, 5*time.Second, 10*time.Millisecond)
+	pool := nodeStatus(t, e, "node1").GetDefragNodeStatus().GetPoolStatus()["pool1"]
+	assert.Equal(t, "v1", pool.GetLastVolumeId())
+	assert.Empty(t, nodeStatus(t, e, "node1").GetDefragNodeStatus().GetRunningSchedule())
+}
+
+func TestEngineRunNodeParallelPool(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	vols := []*api.Volume{
+		testVolume("v1", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+		testVolume("v2", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+		testVolume("v3", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+	}
+	fs := newTestFilesystems()
+	fs.block = "/v2"
+	e := newTestEngine(t, ctrl, newTestKvdb(t), "node1", []*api.Node{{Id: "node1"}}, vols, fs)
+	e.config.MaxVolumesPerPool = 2
+
+	// The defrag of v1 and v3 ends while v2 is the interrupted volume
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	e.runNode(ctx, "schedule1")
+	assert.ElementsMatch(t, []string{"/v1", "/v2", "/v3"}, fs.getDefrags())
+
+	pool := nodeStatus(t, e, "node1").GetDefragNodeStatus().GetPoolStatus()["pool1"]
+	assert.Equal(t, "v2", pool.GetLastVolumeId())
+	assert.Equal(t, int64(0), pool.GetLastOffset())
+}
+
+func TestEngineRunNodeStatusRemoved(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kv := newTestKvdb(t)
+	vols := []*api.Volume{testVolume("v1", "node1", "pool1", api.FSType_FS_TYPE_EXT4)}
+	fs := newTestFilesystems()
+	fs.block = "/v1"
+	e := newTestEngine(t, ctrl, kv, "node1", []*api.Node{{Id: "node1"}}, vols, fs)
+
+	// The status of the node is removed while v1 is defragmented
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	go func() {
+		for len(fs.getDefrags()) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		_, err := kv.Delete(nodeStatusKey("node1"))
+		assert.NoError(t, err)
+	}()
+	e.runNode(ctx, "schedule1")
+
+	pool := nodeStatus(t, e, "node1").GetDefragNodeStatus().GetPoolStatus()["pool1"]
+	require.NotNil(t, pool)
+	assert.False(t, pool.GetRunning())
+	assert.Equal(t, "v1", pool.GetLastVolumeId())
+}
+
+func TestEngineRunScheduleNodeDown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	kv := newTestKvdb(t)
+	nodes := []*api.Node{
+		{Id: "node1", Status: api.Status_STATUS_OK},
+		{Id: "node2", Status: api.Status_STATUS_OK},
+	}
+	fs := newTestFilesystems()
+	e := newTestEngine(t, ctrl, kv, "node1", nodes, []*api.Volume{
+		testVolume("v1", "node1", "pool1", api.FSType_FS_TYPE_EXT4),
+	}, fs)
+	e.config.MaxDuration = 200 * time.Millisecond
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	// The pool of node1 failed in an older run
+	e.updateNodeStatus(func(nodeStatus *api.DefragNodeStatus) {
+		started := time.Now().Add(-time.Hour)
+		nodeStatus.PoolStatus["pool2"] = &api.DefragPoolStatus{
+			LastStartTime:    prototime.TimeToTimestamp(started),
+			LastCompleteTime: prototime.TimeToTimestamp(started.Add(time.Minute)),
+		}
+	})
+
+	s := &api.Schedule{
+		Id:   "schedule1",
+		Type: api.Job_DEFRAG,
+		Tasks: []*api.Job{{
+			Type: api.Job_DEFRAG,
+			Job:  &api.Job_Defrag{Defrag: &api.DefragJob{MaxNodesInParallel: 2}},
+		}},
+	}
+
+	// node2 is down, the run ends at the default deadline and its
+	// assignment is removed
+	require.NoError(t, e.RunSchedule(context.Background(), s))
+	assert.Equal(t, []string{"/v1"}, fs.getDefrags())
+	_, err := kv.Get(assignmentKey("node2"))
+	assert.Equal(t, kvdb.ErrNotFound, err)
+
+	// The next run is not blocked by the assignment of the last run
+	require.NoError(t, e.RunSchedule(context.Background(), s))
+}
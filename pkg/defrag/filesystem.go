@@ -0,0 +1,136 @@
+package defrag
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	// e4defragBin defragments ext4 filesystems
+	e4defragBin = "e4defrag"
+	// xfsFsrBin defragments xfs filesystems
+	xfsFsrBin = "xfs_fsr"
+	// xfsDbBin reports the fragmentation of xfs filesystems
+	xfsDbBin = "xfs_db"
+)
+
+var (
+	// e4defragScoreRegex matches the score in the output of e4defrag -c
+	e4defragScoreRegex = regexp.MustCompile(`Fragmentation score\s+(\d+)`)
+	// xfsFragRegex matches the fragmentation factor in the output of
+	// xfs_db -c frag
+	xfsFragRegex = regexp.MustCompile(`fragmentation factor\s+([\d.]+)%`)
+)
+
+// runFunc runs a command until the context is done, see exec.RunContext
+type runFunc func(ctx context.Context, stopTimeout time.Duration, bin string, args ...string) ([]byte, error)
+
+// filesystem measures and defragments one type of filesystem
+type filesystem interface {
+	// fragmentation returns the fragmentation of the volume in percent
+	fragmentation(ctx context.Context, v *api.Volume) (float64, error)
+	// defrag defragments the volume until it is done or the context is done
+	defrag(ctx context.Context, v *api.Volume) error
+}
+
+// getFilesystem returns the filesystem of a volume, or nil if the filesystem
+// cannot be defragmented
+func getFilesystem(v *api.Volume, run runFunc, stopTimeout time.Duration) filesystem {
+	format := v.GetFormat()
+	if format == api.FSType_FS_TYPE_NONE {
+		format = v.GetSpec().GetFormat()
+	}
+	switch format {
+	case api.FSType_FS_TYPE_EXT4:
+		return &ext4{run: run, stopTimeout: stopTimeout}
+	case api.FSType_FS_TYPE_XFS:
+		return &xfs{run: run, stopTimeout: stopTimeout}
+	}
+	return nil
+}
+
+// mountPath returns the path where a volume is mounted on this node
+func mountPath(v *api.Volume) string {
+	if len(v.GetAttachPath()) == 0 {
+		return ""
+	}
+	return v.GetAttachPath()[0]
+}
+
+// ext4 defragments with e4defrag. The fragmentation is the score of
+// e4defrag, where 0 to 30 means no defragmentation is needed.
+type ext4 struct {
+	run         runFunc
+	stopTimeout time.Duration
+}
+
+func (fs *ext4) fragmentation(ctx context.Context, v *api.Volume) (float64, error) {
+	out, err := fs.run(ctx, fs.stopTimeout, e4defragBin, "-c", mountPath(v))
+	if err != nil {
+		return 0, fmt.Errorf("%s -c failed: %v: %s", e4defragBin, err, out)
+	}
+	return parseFragmentation(e4defragScoreRegex, out)
+}
+
+func (fs *ext4) defrag(ctx context.Context, v *api.Volume) error {
+	out, err := fs.run(ctx, fs.stopTimeout, e4defragBin, mountPath(v))
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%s failed: %v: %s", e4defragBin, err, out)
+	}
+	return nil
+}
+
+// xfs defragments with xfs_fsr. The fragmentation is the fragmentation
+// factor reported by xfs_db.
+type xfs struct {
+	run         runFunc
+	stopTimeout time.Duration
+}
+
+func (fs *xfs) fragmentation(ctx context.Context, v *api.Volume) (float64, error) {
+	out, err := fs.run(ctx, fs.stopTimeout, xfsDbBin, "-r", "-c", "frag", v.GetDevicePath())
+	if err != nil {
+		return 0, fmt.Errorf("%s -c frag failed: %v: %s", xfsDbBin, err, out)
+	}
+	return parseFragmentation(xfsFragRegex, out)
+}
+
+func (fs *xfs) defrag(ctx context.Context, v *api.Volume) error {
+	args := []string{"-v"}
+	// xfs_fsr stops by itself at the end of the time window, and saves where
+	// it stopped
+	if deadline, ok := ctx.Deadline(); ok {
+		seconds := int64(math.Ceil(time.Until(deadline).Seconds()))
+		if seconds <= 0 {
+			return context.DeadlineExceeded
+		}
+		args = append(args, "-t", strconv.FormatInt(seconds, 10))
+	}
+	args = append(args, mountPath(v))
+
+	out, err := fs.run(ctx, fs.stopTimeout, xfsFsrBin, args...)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("%s failed: %v: %s", xfsFsrBin, err, out)
+	}
+	return nil
+}
+
+func parseFragmentation(re *regexp.Regexp, out []byte) (float64, error) {
+	match := re.FindSubmatch(out)
+	if match == nil {
+		return 0, fmt.Errorf("fragmentation not found in %q", out)
+	}
+	return strconv.ParseFloat(string(match[1]), 64)
+}
@@ -0,0 +1,647 @@
+package diags
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/libopenstorage/openstorage/alerts"
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/pkg/dbg"
+	"github.com/libopenstorage/openstorage/pkg/grpcserver"
+	"github.com/libopenstorage/openstorage/pkg/job"
+	"github.com/libopenstorage/openstorage/pkg/mount"
+	"github.com/libopenstorage/openstorage/pkg/parser"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+const (
+	// clusterDBKey is the kvdb key of the cluster database saved in the
+	// bundles, manager.ClusterDBKey, which cannot be imported as the cluster
+	// manager depends on this package. The other keys under cluster/ hold
+	// secrets such as the pair tokens and are never saved.
+	clusterDBKey = "cluster/database"
+
+	// DefaultTimeout is the timeout of a diags job when the request has no
+	// timeout
+	DefaultTimeout = 30 * time.Minute
+	// DefaultAlertsWindow is how far back the alerts saved in the bundles go
+	DefaultAlertsWindow = 24 * time.Hour
+	// DefaultDir is the directory where the bundles are saved on each node
+	DefaultDir = dbg.Path + "diags"
+)
+
+// StreamInterval is how often CollectStream checks the job for changes
+var StreamInterval = time.Second
+
+// Cluster returns the nodes of the cluster. It is implemented by
+// cluster.Cluster.
+type Cluster interface {
+	Enumerate() (api.Cluster, error)
+}
+
+// CollectorConfig is the configuration of the diags collector
+type CollectorConfig struct {
+	// Cluster is used to select the nodes and find this node
+	Cluster Cluster
+	// Driver is the volume driver of this node
+	Driver volume.VolumeDriver
+	// Kvdb is the cluster database saved in the bundles
+	Kvdb kvdb.Kvdb
+	// Jobs runs the COLLECT_DIAGS jobs
+	Jobs *job.KvdbProvider
+	// (optional) Alerts are the alerts saved in the bundles
+	Alerts alerts.FilterDeleter
+	// (optional) Dir is the directory where the bundles are saved. Defaults
+	// to DefaultDir.
+	Dir string
+	// (optional) LogFiles are the osd log files saved in the bundles
+	LogFiles []string
+	// SdkPort is the gRPC port of the SDK on the nodes, used to collect
+	// the diags of the other nodes
+	SdkPort string
+	// (optional) TlsCertFile is the certificate of the SDK server. When it
+	// is set, the SDK of the other nodes is called over TLS, trusting the
+	// certificate.
+	TlsCertFile string
+	// (optional) TokenGenerator creates the tokens used to call the SDK of
+	// the other nodes when authentication is enabled
+	TokenGenerator auth.TokenGenerator
+}
+
+// Collector is a Provider which collects the diags of the selected nodes
+// with a COLLECT_DIAGS job. Each node saves its diags in a tarball on the
+// node.
+type Collector struct {
+	config      CollectorConfig
+	dialOptions []grpc.DialOption
+}
+
+// Check interfaces
+var _ Provider = &Collector{}
+var _ job.Runner = &Collector{}
+
+// NewCollector returns a diags provider which collects the bundles of the
+// nodes. It registers the runner of the diags jobs with the job provider.
+func NewCollector(config *CollectorConfig) (*Collector, error) {
+	if config == nil {
+		return nil, fmt.Errorf("Must supply a configuration for the diags collector")
+	} else if config.Cluster == nil {
+		return nil, fmt.Errorf("Must supply a cluster for the diags collector")
+	} else if config.Driver == nil {
+		return nil, fmt.Errorf("Must supply a volume driver for the diags collector")
+	} else if config.Kvdb == nil {
+		return nil, fmt.Errorf("Must supply a kvdb instance for the diags collector")
+	} else if config.Jobs == nil {
+		return nil, fmt.Errorf("Must supply a job provider for the diags collector")
+	}
+
+	// The calls to the other nodes keep the correlation id of the job
+	c := &Collector{
+		config: *config,
+		dialOptions: []grpc.DialOption{
+			grpc.WithUnaryInterceptor(correlation.ContextUnaryClientInterceptor),
+		},
+	}
+	if len(c.config.Dir) == 0 {
+		c.config.Dir = DefaultDir
+	}
+	if len(c.config.TlsCertFile) != 0 {
+		cert, err := ioutil.ReadFile(c.config.TlsCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read the TLS certificate of the diags collector: %v", err)
+		}
+		tlsDialOptions, err := grpcserver.GetTlsDialOptions(cert)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to setup TLS for the diags collector: %v", err)
+		}
+		c.dialOptions = append(c.dialOptions, tlsDialOptions...)
+	} else {
+		c.dialOptions = append(c.dialOptions, grpc.WithInsecure())
+	}
+	if err := config.Jobs.RegisterRunner(api.Job_COLLECT_DIAGS, c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Collect creates a job which collects the diags of the nodes selected by the
+// request. The progress of each node is saved in the statuses of the job.
+func (c *Collector) Collect(
+	ctx context.Context,
+	in *api.SdkDiagsCollectRequest,
+) (*api.SdkDiagsCollectResponse, error) {
+	nodes, err := c.selectNodes(in)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*api.DiagsCollectionStatus, 0, len(nodes))
+	for _, id := range nodes {
+		statuses = append(statuses, &api.DiagsCollectionStatus{
+			Node:    id,
+			State:   api.DiagsCollectionStatus_PENDING,
+			Message: "Waiting to collect diags",
+		})
+	}
+
+	j, err := c.config.Jobs.CreateJob(&api.Job{
+		Type:       api.Job_COLLECT_DIAGS,
+		CreateTime: prototime.Now(),
+		Job: &api.Job_CollectDiags{
+			CollectDiags: &api.CollectDiagsJob{
+				Request:  in,
+				Statuses: statuses,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SdkDiagsCollectResponse{
+		Job: j,
+	}, nil
+}
+
+// CollectStream creates a job like Collect and sends the job each time the
+// collection status of a node changes, until the job is finished. The job
+// keeps running if the stream is closed.
+func (c *Collector) CollectStream(
+	in *api.SdkDiagsCollectRequest,
+	stream api.OpenStorageDiags_CollectStreamServer,
+) error {
+	ctx := stream.Context()
+	resp, err := c.Collect(ctx, in)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(resp); err != nil {
+		return err
+	}
+
+	last := resp.GetJob()
+	ticker := time.NewTicker(StreamInterval)
+	defer ticker.Stop()
+	for {
+		switch last.GetState() {
+		case api.Job_DONE, api.Job_FAILED, api.Job_CANCELLED:
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		r, err := c.config.Jobs.GetJobStatus(ctx, &api.SdkGetJobStatusRequest{
+			Id: last.GetId(),
+		})
+		if err != nil {
+			return err
+		}
+		if proto.Equal(r.GetJob(), last) {
+			continue
+		}
+		last = r.GetJob()
+		if err := stream.Send(&api.SdkDiagsCollectResponse{Job: last}); err != nil {
+			return err
+		}
+	}
+}
+
+// selectNodes returns the sorted ids of the nodes selected by the node and
+// volume selectors of the request
+func (c *Collector) selectNodes(in *api.SdkDiagsCollectRequest) ([]string, error) {
+	nodeSel := in.GetNode()
+	volSel := in.GetVolume()
+	if !nodeSel.GetAll() &&
+		len(nodeSel.GetNodeIds()) == 0 &&
+		len(nodeSel.GetNodeLabelSelector()) == 0 &&
+		len(volSel.GetVolumeIds()) == 0 &&
+		len(volSel.GetVolumeLabelSelector()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a node or volume selector")
+	}
+
+	cl, err := c.config.Cluster.Enumerate()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the nodes of the cluster: %v", err)
+	}
+
+	selected := make(map[string]bool)
+	for _, id := range nodeSel.GetNodeIds() {
+		n := cl.FindNode(id)
+		if n == nil {
+			return nil, status.Errorf(codes.NotFound, "Node %s not found in the cluster", id)
+		}
+		selected[n.Id] = true
+	}
+	for _, n := range cl.Nodes {
+		if nodeSel.GetAll() ||
+			(len(nodeSel.GetNodeLabelSelector()) > 0 &&
+				parser.MatchLabelSelector(n.NodeLabels, nodeSel.GetNodeLabelSelector())) {
+			selected[n.Id] = true
+		}
+	}
+
+	if len(volSel.GetVolumeIds()) > 0 || len(volSel.GetVolumeLabelSelector()) > 0 {
+		vols, err := c.config.Driver.Enumerate(&api.VolumeLocator{}, nil)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to get the volumes: %v", err)
+		}
+		ids := make(map[string]bool, len(volSel.GetVolumeIds()))
+		for _, id := range volSel.GetVolumeIds() {
+			ids[id] = true
+		}
+		for _, v := range vols {
+			if !ids[v.GetId()] &&
+				(len(volSel.GetVolumeLabelSelector()) == 0 ||
+					!parser.MatchLabelSelector(v.GetLocator().GetVolumeLabels(), volSel.GetVolumeLabelSelector())) {
+				continue
+			}
+			// The diags are collected from the replica and attached nodes
+			// of the volume
+			nodes := []string{v.GetAttachedOn()}
+			for _, rs := range v.GetReplicaSets() {
+				nodes = append(nodes, rs.GetNodes()...)
+			}
+			for _, id := range nodes {
+				if n := cl.FindNode(id); n != nil {
+					selected[n.Id] = true
+				}
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, status.Error(codes.NotFound, "No nodes matched the selectors")
+	}
+	nodes := make([]string, 0, len(selected))
+	for id := range selected {
+		nodes = append(nodes, id)
+	}
+	sort.Strings(nodes)
+	return nodes, nil
+}
+
+// Run collects the diags of the nodes of a COLLECT_DIAGS job in parallel.
+// The nodes which are already done are skipped when the job is resumed.
+func (c *Collector) Run(ctx context.Context, j *api.Job, reporter job.Reporter) error {
+	in := j.GetCollectDiags().GetRequest()
+	statuses := j.GetCollectDiags().GetStatuses()
+	if len(statuses) == 0 {
+		return fmt.Errorf("job %s has no nodes to collect diags from", j.GetId())
+	}
+
+	timeout := DefaultTimeout
+	if in.GetTimeoutMins() > 0 {
+		timeout = time.Duration(in.GetTimeoutMins()) * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cl, err := c.config.Cluster.Enumerate()
+	if err != nil {
+		return fmt.Errorf("failed to get the nodes of the cluster: %v", err)
+	}
+
+	var (
+		lock   sync.Mutex
+		wg     sync.WaitGroup
+		done   int
+		failed int
+	)
+	for _, st := range statuses {
+		if st.GetState() == api.DiagsCollectionStatus_DONE {
+			done++
+		}
+	}
+	total := len(statuses)
+
+	for _, st := range statuses {
+		if st.GetState() == api.DiagsCollectionStatus_DONE {
+			continue
+		}
+
+		wg.Add(1)
+		go func(nodeID string) {
+			defer wg.Done()
+
+			c.setStatus(reporter, nodeID, api.DiagsCollectionStatus_RUNNING, "Collecting diags")
+			resp, err := c.collectNode(ctx, cl, nodeID, &api.SdkDiagsCollectNodeRequest{
+				Request: in,
+				JobId:   j.GetId(),
+			})
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				logrus.Warnf("Failed to collect diags of node %s for job %s: %v", nodeID, j.GetId(), err)
+				c.setStatus(reporter, nodeID, api.DiagsCollectionStatus_FAILED, err.Error())
+				failed++
+			} else {
+				c.setStatus(reporter, nodeID, api.DiagsCollectionStatus_DONE,
+					fmt.Sprintf("Saved %s (%d bytes) on node %s", resp.GetPath(), resp.GetSize(), nodeID))
+				done++
+			}
+			if err := reporter.SetProgress(uint32(done*100/total),
+				fmt.Sprintf("Collected diags of %d of %d nodes", done, total)); err != nil {
+				logrus.Warnf("Failed to save the progress of job %s: %v", j.GetId(), err)
+			}
+		}(st.GetNode())
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to collect diags of %d nodes", failed)
+	}
+	return nil
+}
+
+// setStatus saves the diags collection status of a node in the job
+func (c *Collector) setStatus(
+	reporter job.Reporter,
+	nodeID string,
+	state api.DiagsCollectionStatus_State,
+	message string,
+) {
+	if err := reporter.UpdateJob(func(j *api.Job) {
+		for _, st := range j.GetCollectDiags().GetStatuses() {
+			if st.GetNode() == nodeID {
+				st.State = state
+				st.Message = message
+			}
+		}
+	}); err != nil {
+		logrus.Warnf("Failed to save the diags status of node %s: %v", nodeID, err)
+	}
+}
+
+// collectNode collects the diags of this node, or calls the SDK of another
+// node to collect its diags
+func (c *Collector) collectNode(
+	ctx context.Context,
+	cl api.Cluster,
+	nodeID string,
+	in *api.SdkDiagsCollectNodeRequest,
+) (*api.SdkDiagsCollectNodeResponse, error) {
+	if nodeID == cl.NodeId {
+		return c.CollectNode(ctx, in)
+	}
+
+	n := cl.FindNode(nodeID)
+	if n == nil {
+		return nil, fmt.Errorf("node %s is no longer in the cluster", nodeID)
+	}
+	conn, err := grpcserver.ConnectWithTimeout(
+		fmt.Sprintf("%s:%s", n.MgmtIp, c.config.SdkPort),
+		c.dialOptions, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to node %s: %v", nodeID, err)
+	}
+	defer conn.Close()
+
+	if c.config.TokenGenerator != nil {
+		token, err := c.config.TokenGenerator.GetToken(&auth.Options{
+			Expiration: time.Now().Add(time.Hour).Unix(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get a token for node %s: %v", nodeID, err)
+		}
+		if len(token) > 0 {
+			ctx = metadata.NewOutgoingContext(ctx, metadata.New(map[string]string{
+				"authorization": "bearer " + token,
+			}))
+		}
+	}
+	return api.NewOpenStorageDiagsClient(conn).CollectNode(ctx, in)
+}
+
+// CollectNode saves the diags of this node in a tarball. The components
+// which cannot be collected are listed in the errors.txt file of the tarball.
+func (c *Collector) CollectNode(
+	ctx context.Context,
+	in *api.SdkDiagsCollectNodeRequest,
+) (*api.SdkDiagsCollectNodeResponse, error) {
+	cl, err := c.config.Cluster.Enumerate()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the cluster: %v", err)
+	}
+
+	name := filepath.Base(in.GetRequest().GetFilename())
+	if len(in.GetRequest().GetFilename()) == 0 {
+		suffix := in.GetJobId()
+		if len(suffix) == 0 {
+			suffix = dbg.GetTimeStamp()
+		}
+		name = fmt.Sprintf("%s-%s-%s.tar.gz", dbg.GetHostNamePrefix(), cl.NodeId, suffix)
+	}
+	if err := os.MkdirAll(c.config.Dir, 0755); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to create %s: %v", c.config.Dir, err)
+	}
+	path := filepath.Join(c.config.Dir, name)
+
+	if err := c.writeBundle(path, in.GetRequest()); err != nil {
+		os.Remove(path)
+		return nil, status.Errorf(codes.Internal, "Failed to collect diags of node %s: %v", cl.NodeId, err)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get the size of %s: %v", path, err)
+	}
+
+	logrus.Infof("Diags of node %s saved to %s", cl.NodeId, path)
+	return &api.SdkDiagsCollectNodeResponse{
+		NodeId: cl.NodeId,
+		Path:   path,
+		Size:   uint64(fi.Size()),
+	}, nil
+}
+
+// writeBundle writes the diags of this node to a tarball readable only by
+// its owner
+func (c *Collector) writeBundle(path string, in *api.SdkDiagsCollectRequest) error {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	b := newBundle(f)
+
+	// Profiles are dumped to files by dbg
+	tmp, err := ioutil.TempDir(c.config.Dir, ".diags-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+	stack := filepath.Join(tmp, "goroutines.stack")
+	if err := dbg.DumpGoProfileTo(stack); err != nil {
+		b.fail("goroutines.stack", err)
+	} else {
+		b.addFile("goroutines.stack", stack)
+	}
+	heap := filepath.Join(tmp, "heap.pprof")
+	dbg.HeapDumpTo(heap)
+	b.addFile("heap.pprof", heap)
+
+	if !in.GetProfileOnly() {
+		c.addClusterDB(b)
+		c.addMounts(b)
+		c.addDriverStatus(b)
+		c.addAlerts(b)
+		for _, l := range c.config.LogFiles {
+			b.addFile(filepath.Join("logs", filepath.Base(l)), l)
+		}
+	}
+
+	return b.close()
+}
+
+// addClusterDB saves a snapshot of the cluster database in kvdb
+func (c *Collector) addClusterDB(b *bundle) {
+	kvp, err := c.config.Kvdb.Get(clusterDBKey)
+	if err != nil {
+		b.fail("kvdb.json", err)
+		return
+	}
+	db := map[string]string{kvp.Key: string(kvp.Value)}
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		b.fail("kvdb.json", err)
+		return
+	}
+	b.add("kvdb.json", data)
+}
+
+// addMounts saves the mount table of the node
+func (c *Collector) addMounts(b *bundle) {
+	mounts, err := mount.GetMounts()
+	if err != nil {
+		b.fail("mounts.txt", err)
+		return
+	}
+	var buf bytes.Buffer
+	for _, m := range mounts {
+		fmt.Fprintf(&buf, "%s %s %s %s\n", m.Source, m.Mountpoint, m.FSType, m.Options)
+	}
+	b.add("mounts.txt", buf.Bytes())
+}
+
+// addDriverStatus saves the status of the volume driver
+func (c *Collector) addDriverStatus(b *bundle) {
+	var buf bytes.Buffer
+	for _, s := range c.config.Driver.Status() {
+		fmt.Fprintf(&buf, "%s: %s\n", s[0], s[1])
+	}
+	b.add("driver-status.txt", buf.Bytes())
+}
+
+// addAlerts saves the alerts raised during the DefaultAlertsWindow
+func (c *Collector) addAlerts(b *bundle) {
+	if c.config.Alerts == nil {
+		return
+	}
+	now := time.Now()
+	list, err := c.config.Alerts.Enumerate(alerts.NewTimeSpanFilter(now.Add(-DefaultAlertsWindow), now))
+	if err != nil {
+		b.fail("alerts.json", err)
+		return
+	}
+	marshaler := jsonpb.Marshaler{OrigName: true, Indent: "  "}
+	data, err := marshaler.MarshalToString(&api.SdkAlertsEnumerateWithFiltersResponse{Alerts: list})
+	if err != nil {
+		b.fail("alerts.json", err)
+		return
+	}
+	b.add("alerts.json", []byte(data))
+}
+
+// bundle writes the files of a gzipped tarball. The errors of the components
+// are saved in errors.txt, so a bundle is still written when a component
+// fails.
+type bundle struct {
+	gz   *gzip.Writer
+	tw   *tar.Writer
+	errs bytes.Buffer
+	err  error
+}
+
+func newBundle(w io.Writer) *bundle {
+	gz := gzip.NewWriter(w)
+	return &bundle{
+		gz: gz,
+		tw: tar.NewWriter(gz),
+	}
+}
+
+// fail records the error of a component
+func (b *bundle) fail(name string, err error) {
+	logrus.Warnf("Failed to collect %s: %v", name, err)
+	fmt.Fprintf(&b.errs, "%s: %v\n", name, err)
+}
+
+// add writes a file to the tarball
+func (b *bundle) add(name string, data []byte) {
+	if b.err != nil {
+		return
+	}
+	if b.err = b.tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); b.err != nil {
+		return
+	}
+	_, b.err = b.tw.Write(data)
+}
+
+// addFile writes a file of the node to the tarball
+func (b *bundle) addFile(name, path string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		b.fail(name, err)
+		return
+	}
+	b.add(name, data)
+}
+
+// close writes the errors of the components and flushes the tarball
+func (b *bundle) close() error {
+	if b.errs.Len() > 0 {
+		b.add("errors.txt", b.errs.Bytes())
+	}
+	if b.err != nil {
+		return b.err
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return b.gz.Close()
+}
@@ -0,0 +1,408 @@
+package diags
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/job"
+	"github.com/libopenstorage/openstorage/pkg/job/jobtest"
+	"github.com/libopenstorage/openstorage/volume/drivers/mock"
+)
+
+// testCluster returns a fixed list of nodes
+type testCluster struct {
+	cluster api.Cluster
+}
+
+func (c *testCluster) Enumerate() (api.Cluster, error) {
+	return c.cluster, nil
+}
+
+// testStream keeps the messages sent by CollectStream
+type testStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	onSend func()
+	sent   []*api.SdkDiagsCollectResponse
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testStream) Send(resp *api.SdkDiagsCollectResponse) error {
+	s.sent = append(s.sent, resp)
+	if s.onSend != nil {
+		s.onSend()
+	}
+	return nil
+}
+
+// writeTestCert writes a self signed certificate and its key for 127.0.0.1
+func writeTestCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "diags_test"},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, ioutil.WriteFile(keyFile,
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600))
+	return certFile, keyFile
+}
+
+func newTestCollector(
+	t *testing.T,
+	ctrl *gomock.Controller,
+	nodeID string,
+	nodes []*api.Node,
+) (*Collector, *mock.MockVolumeDriver) {
+	kv, err := kvdb.New(mem.Name, "diags_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	_, err = kv.Put("cluster/database", "db-"+nodeID, 0)
+	require.NoError(t, err)
+	_, err = kv.Put("cluster/pair/token", "secret-"+nodeID, 0)
+	require.NoError(t, err)
+	jobs, err := job.NewKvdbProvider(kv, nodeID)
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "diags_test")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	logFile := filepath.Join(dir, "osd.log")
+	require.NoError(t, ioutil.WriteFile(logFile, []byte("log of "+nodeID), 0644))
+
+	driver := mock.NewMockVolumeDriver(ctrl)
+	c, err := NewCollector(&CollectorConfig{
+		Cluster: &testCluster{
+			cluster: api.Cluster{NodeId: nodeID, Nodes: nodes},
+		},
+		Driver:   driver,
+		Kvdb:     kv,
+		Jobs:     jobs,
+		Dir:      filepath.Join(dir, "bundles"),
+		LogFiles: []string{logFile},
+	})
+	require.NoError(t, err)
+	return c, driver
+}
+
+// readBundle returns the files of a bundle
+func readBundle(t *testing.T, path string) map[string]string {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+
+	files := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err != nil {
+			break
+		}
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		files[hdr.Name] = string(data)
+	}
+	return files
+}
+
+func TestCollectorSelectNodes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	nodes := []*api.Node{
+		{Id: "node1", NodeLabels: map[string]string{"zone": "a"}},
+		{Id: "node2", MgmtIp: "10.0.0.2", NodeLabels: map[string]string{"zone": "b"}},
+		{Id: "node3", NodeLabels: map[string]string{"zone": "b"}},
+	}
+	c, driver := newTestCollector(t, ctrl, "node1", nodes)
+
+	_, err := c.selectNodes(&api.SdkDiagsCollectRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = c.selectNodes(&api.SdkDiagsCollectRequest{
+		Node: &api.DiagsNodeSelector{NodeIds: []string{"missing"}},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	selected, err := c.selectNodes(&api.SdkDiagsCollectRequest{
+		Node: &api.DiagsNodeSelector{All: true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node1", "node2", "node3"}, selected)
+
+	selected, err = c.selectNodes(&api.SdkDiagsCollectRequest{
+		Node: &api.DiagsNodeSelector{
+			NodeIds: []string{"node1", "node3"},
+			NodeLabelSelector: []*api.LabelSelectorRequirement{
+				{Key: "zone", Operator: api.LabelSelectorRequirement_In, Values: []string{"b"}},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node1", "node2", "node3"}, selected)
+
+	// Volumes select their attached and replica nodes
+	driver.EXPECT().
+		Enumerate(&api.VolumeLocator{}, nil).
+		Return([]*api.Volume{
+			{
+				Id:          "vol1",
+				AttachedOn:  "10.0.0.2",
+				ReplicaSets: []*api.ReplicaSet{{Nodes: []string{"node3"}}},
+			},
+			{
+				Id:          "vol2",
+				AttachedOn:  "node1",
+				Locator:     &api.VolumeLocator{VolumeLabels: map[string]string{"app": "db"}},
+				ReplicaSets: []*api.ReplicaSet{{Nodes: []string{"node1"}}},
+			},
+		}, nil)
+	selected, err = c.selectNodes(&api.SdkDiagsCollectRequest{
+		Volume: &api.DiagsVolumeSelector{VolumeIds: []string{"vol1"}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node2", "node3"}, selected)
+}
+
+func TestCollectorCollectNode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	c, driver := newTestCollector(t, ctrl, "node1", []*api.Node{{Id: "node1"}})
+
+	driver.EXPECT().Status().Return([][2]string{{"State", "up"}})
+	resp, err := c.CollectNode(context.Background(), &api.SdkDiagsCollectNodeRequest{
+		Request: &api.SdkDiagsCollectRequest{},
+		JobId:   "job1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "node1", resp.GetNodeId())
+	assert.Contains(t, resp.GetPath(), "-node1-job1.tar.gz")
+	assert.NotZero(t, resp.GetSize())
+
+	files := readBundle(t, resp.GetPath())
+	assert.Contains(t, files["goroutines.stack"], "goroutine")
+	assert.NotEmpty(t, files["heap.pprof"])
+	assert.Contains(t, files["kvdb.json"], "db-node1")
+	assert.NotContains(t, files["kvdb.json"], "secret-node1")
+	assert.Contains(t, files, "mounts.txt")
+	assert.Equal(t, "State: up\n", files["driver-status.txt"])
+	assert.Equal(t, "log of node1", files["logs/osd.log"])
+
+	// Only the profiles are collected
+	resp, err = c.CollectNode(context.Background(), &api.SdkDiagsCollectNodeRequest{
+		Request: &api.SdkDiagsCollectRequest{
+			ProfileOnly: true,
+			Filename:    "../profile.tar.gz",
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(c.config.Dir, "profile.tar.gz"), resp.GetPath())
+	files = readBundle(t, resp.GetPath())
+	assert.Len(t, files, 2)
+	assert.Contains(t, files, "goroutines.stack")
+	assert.Contains(t, files, "heap.pprof")
+}
+
+func TestCollectorRun(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	// node2 serves its diags over the SDK
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	host, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+	nodes := []*api.Node{
+		{Id: "node1"},
+		{Id: "node2", MgmtIp: host},
+		{Id: "node3"},
+	}
+	peer, peerDriver := newTestCollector(t, ctrl, "node2", nodes)
+	server := grpc.NewServer()
+	api.RegisterOpenStorageDiagsServer(server, peer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	c, driver := newTestCollector(t, ctrl, "node1", nodes)
+	c.config.SdkPort = port
+
+	resp, err := c.Collect(context.Background(), &api.SdkDiagsCollectRequest{
+		Node: &api.DiagsNodeSelector{All: true},
+	})
+	require.NoError(t, err)
+	j := resp.GetJob()
+	assert.Equal(t, api.Job_COLLECT_DIAGS, j.GetType())
+	require.Len(t, j.GetCollectDiags().GetStatuses(), 3)
+	for _, st := range j.GetCollectDiags().GetStatuses() {
+		assert.Equal(t, api.DiagsCollectionStatus_PENDING, st.GetState())
+	}
+
+	// node3 leaves the cluster before the job runs
+	c.config.Cluster = &testCluster{
+		cluster: api.Cluster{NodeId: "node1", Nodes: nodes[:2]},
+	}
+	driver.EXPECT().Status().Return(nil)
+	peerDriver.EXPECT().Status().Return(nil)
+	reporter := jobtest.NewReporter(j)
+	err = c.Run(context.Background(), j, reporter)
+	assert.Error(t, err)
+
+	statuses := reporter.Job.GetCollectDiags().GetStatuses()
+	assert.Equal(t, api.DiagsCollectionStatus_DONE, statuses[0].GetState())
+	assert.Equal(t, api.DiagsCollectionStatus_DONE, statuses[1].GetState())
+	assert.Contains(t, statuses[1].GetMessage(), "on node node2")
+	assert.Equal(t, api.DiagsCollectionStatus_FAILED, statuses[2].GetState())
+	assert.Equal(t, uint32(66), reporter.JobSummary.GetProgressPercent())
+
+	// The job is resumed by node3 when it is back, and the nodes which are
+	// done are skipped
+	c.config.Cluster = &testCluster{
+		cluster: api.Cluster{NodeId: "node3", Nodes: nodes},
+	}
+	driver.EXPECT().Status().Return(nil)
+	require.NoError(t, c.Run(context.Background(), j, reporter))
+	assert.Equal(t, api.DiagsCollectionStatus_DONE, statuses[2].GetState())
+	assert.Equal(t, uint32(100), reporter.JobSummary.GetProgressPercent())
+}
+
+func TestCollectorCollectNodeTLS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dir, err := ioutil.TempDir("", "diags_tls_test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	certFile, keyFile := writeTestCert(t, dir)
+
+	// node2 serves its diags over the SDK with TLS
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	host, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+	nodes := []*api.Node{
+		{Id: "node1"},
+		{Id: "node2", MgmtIp: host},
+	}
+	peer, peerDriver := newTestCollector(t, ctrl, "node2", nodes)
+	creds, err := credentials.NewServerTLSFromFile(certFile, keyFile)
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.Creds(creds))
+	api.RegisterOpenStorageDiagsServer(server, peer)
+	go server.Serve(lis)
+	defer server.Stop()
+
+	c, _ := newTestCollector(t, ctrl, "node1", nodes)
+	config := c.config
+	config.SdkPort = port
+	config.TlsCertFile = certFile
+	config.Jobs, err = job.NewKvdbProvider(config.Kvdb, "node1")
+	require.NoError(t, err)
+	c, err = NewCollector(&config)
+	require.NoError(t, err)
+
+	peerDriver.EXPECT().Status().Return(nil)
+	cl, err := c.config.Cluster.Enumerate()
+	require.NoError(t, err)
+	resp, err := c.collectNode(context.Background(), cl, "node2", &api.SdkDiagsCollectNodeRequest{
+		Request: &api.SdkDiagsCollectRequest{},
+		JobId:   "job1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "node2", resp.GetNodeId())
+
+	// The certificate must be readable
+	config.TlsCertFile = filepath.Join(dir, "missing.pem")
+	config.Jobs, err = job.NewKvdbProvider(config.Kvdb, "node1")
+	require.NoError(t, err)
+	_, err = NewCollector(&config)
+	assert.Error(t, err)
+}
+
+func TestCollectorCollectStream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	interval := StreamInterval
+	StreamInterval = 10 * time.Millisecond
+	defer func() { StreamInterval = interval }()
+
+	c, driver := newTestCollector(t, ctrl, "node1", []*api.Node{{Id: "node1"}})
+	defer c.config.Jobs.Stop()
+
+	// The job is started once it is sent
+	driver.EXPECT().Status().Return(nil)
+	stream := &testStream{ctx: context.Background()}
+	stream.onSend = func() {
+		if len(stream.sent) == 1 {
+			require.NoError(t, c.config.Jobs.Start())
+		}
+	}
+	require.NoError(t, c.CollectStream(&api.SdkDiagsCollectRequest{
+		Node: &api.DiagsNodeSelector{All: true},
+	}, stream))
+
+	require.True(t, len(stream.sent) > 1)
+	first := stream.sent[0].GetJob()
+	assert.Equal(t, api.Job_PENDING, first.GetState())
+	assert.Equal(t, api.DiagsCollectionStatus_PENDING, first.GetCollectDiags().GetStatuses()[0].GetState())
+	last := stream.sent[len(stream.sent)-1].GetJob()
+	assert.Equal(t, first.GetId(), last.GetId())
+	assert.Equal(t, api.Job_DONE, last.GetState())
+	assert.Equal(t, api.DiagsCollectionStatus_DONE, last.GetCollectDiags().GetStatuses()[0].GetState())
+
+	// The stream stops when it is closed, but the job is kept
+	ctx, cancel := context.WithCancel(context.Background())
+	stream = &testStream{ctx: ctx, onSend: cancel}
+	driver.EXPECT().Status().Return(nil).AnyTimes()
+	err := c.CollectStream(&api.SdkDiagsCollectRequest{
+		Node: &api.DiagsNodeSelector{All: true},
+	}, stream)
+	assert.Equal(t, context.Canceled, err)
+	require.Len(t, stream.sent, 1)
+	_, err = c.config.Jobs.GetJobStatus(context.Background(), &api.SdkGetJobStatusRequest{
+		Id: stream.sent[0].GetJob().GetId(),
+	})
+	assert.NoError(t, err)
+}
@@ -38,3 +38,11 @@ type UnsupportedDiagsProvider struct {
 func (u *UnsupportedDiagsProvider) Collect(_ context.Context, _ *api.SdkDiagsCollectRequest) (*api.SdkDiagsCollectResponse, error) {
 	return nil, &errors.ErrNotSupported{}
 }
+
+func (u *UnsupportedDiagsProvider) CollectNode(_ context.Context, _ *api.SdkDiagsCollectNodeRequest) (*api.SdkDiagsCollectNodeResponse, error) {
+	return nil, &errors.ErrNotSupported{}
+}
+
+func (u *UnsupportedDiagsProvider) CollectStream(_ *api.SdkDiagsCollectRequest, _ api.OpenStorageDiags_CollectStreamServer) error {
+	return &errors.ErrNotSupported{}
+}
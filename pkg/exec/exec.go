@@ -0,0 +1,59 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// DefaultStopTimeout is how long a command has to exit after it is asked to
+// stop, before it is killed
+const DefaultStopTimeout = 30 * time.Second
+
+// RunContext runs bin with args and returns its combined output. The
+// absolute path of bin is found with Which. When the context is done, the
+// command is sent SIGTERM so that it can stop cleanly, and it is killed if it
+// has not exited after stopTimeout. The error of the context is returned if
+// the command was stopped.
+func RunContext(
+	ctx context.Context,
+	stopTimeout time.Duration,
+	bin string,
+	args ...string,
+) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var out bytes.Buffer
+	cmd := exec.Command(Which(bin), args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	select {
+	case err := <-done:
+		return out.Bytes(), err
+	case <-ctx.Done():
+	}
+
+	cmd.Process.Signal(syscall.SIGTERM)
+	timer := time.NewTimer(stopTimeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		cmd.Process.Kill()
+		<-done
+	}
+	return out.Bytes(), ctx.Err()
+}
@@ -0,0 +1,27 @@
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunContext(t *testing.T) {
+	out, err := RunContext(context.Background(), time.Second, "echo", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, "hello\n", string(out))
+
+	_, err = RunContext(context.Background(), time.Second, "false")
+	assert.Error(t, err)
+
+	// The command is stopped when the context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = RunContext(ctx, time.Second, "sleep", "10")
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
@@ -0,0 +1,33 @@
+package fstrim
+
+import (
+	"context"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/api/errors"
+)
+
+// Provider is a collection of APIs for scheduling the filesystem trim of
+// the volumes
+type Provider interface {
+	// Create a schedule to trim the filesystems of the volumes periodically
+	CreateFilesystemTrimSchedule(ctx context.Context, req *api.SdkCreateFilesystemTrimScheduleRequest) (
+		*api.SdkCreateFilesystemTrimScheduleResponse, error,
+	)
+}
+
+// NewDefaultFilesystemTrimProvider does not schedule any filesystem trim
+func NewDefaultFilesystemTrimProvider() Provider {
+	return &UnsupportedFilesystemTrimProvider{}
+}
+
+// UnsupportedFilesystemTrimProvider unsupported implementation of the
+// filesystem trim schedules.
+type UnsupportedFilesystemTrimProvider struct {
+}
+
+func (u *UnsupportedFilesystemTrimProvider) CreateFilesystemTrimSchedule(
+	ctx context.Context, req *api.SdkCreateFilesystemTrimScheduleRequest,
+) (*api.SdkCreateFilesystemTrimScheduleResponse, error) {
+	return nil, &errors.ErrNotSupported{}
+}
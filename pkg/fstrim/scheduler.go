@@ -0,0 +1,335 @@
+package fstrim
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/job"
+	"github.com/libopenstorage/openstorage/pkg/schedule"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+const (
+	// DefaultPollInterval is how often the status of the trims and of the
+	// jobs of a run is checked
+	DefaultPollInterval = 10 * time.Second
+)
+
+// SchedulerConfig is the configuration of the filesystem trim scheduler
+type SchedulerConfig struct {
+	// Driver is the volume driver of this node
+	Driver volume.VolumeDriver
+	// Jobs runs the trims of each node as a FILESYSTEM_TRIM job
+	Jobs *job.KvdbProvider
+	// Schedules runs the FILESYSTEM_TRIM schedules
+	Schedules *schedule.KvdbRegistry
+}
+
+// Scheduler is a Provider which trims the filesystems of the volumes with
+// FILESYSTEM_TRIM schedules. The node which runs a schedule creates a
+// FILESYSTEM_TRIM job for each node the selected volumes are attached on,
+// and waits for the jobs until they are done or the time window of the
+// schedule ends. Each job trims the volumes of its node one at a time.
+type Scheduler struct {
+	config       SchedulerConfig
+	pollInterval time.Duration
+}
+
+// Check interfaces
+var _ Provider = &Scheduler{}
+var _ schedule.Runner = &Scheduler{}
+var _ job.NodeRunner = &Scheduler{}
+
+// NewScheduler returns a filesystem trim provider which trims the volumes
+// of the driver. It registers the runner of the trim schedules with the
+// schedule registry, and the runner of the trim jobs with the job provider.
+func NewScheduler(config *SchedulerConfig) (*Scheduler, error) {
+	if config == nil {
+		return nil, fmt.Errorf("Must supply a configuration for the filesystem trim scheduler")
+	} else if config.Driver == nil {
+		return nil, fmt.Errorf("Must supply a volume driver for the filesystem trim scheduler")
+	} else if config.Jobs == nil {
+		return nil, fmt.Errorf("Must supply a job provider for the filesystem trim scheduler")
+	} else if config.Schedules == nil {
+		return nil, fmt.Errorf("Must supply a schedule registry for the filesystem trim scheduler")
+	}
+
+	s := &Scheduler{
+		config:       *config,
+		pollInterval: DefaultPollInterval,
+	}
+	if err := config.Schedules.RegisterRunner(api.Job_FILESYSTEM_TRIM, s); err != nil {
+		return nil, err
+	}
+	if err := config.Jobs.RegisterRunner(api.Job_FILESYSTEM_TRIM, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CreateFilesystemTrimSchedule creates a FILESYSTEM_TRIM schedule which
+// trims the selected volumes at the start time, for at most the max
+// duration of the task
+func (s *Scheduler) CreateFilesystemTrimSchedule(
+	ctx context.Context,
+	in *api.SdkCreateFilesystemTrimScheduleRequest,
+) (*api.SdkCreateFilesystemTrimScheduleResponse, error) {
+	task := in.GetTrimTask()
+	if task == nil {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a trim task")
+	} else if len(task.GetNodeId()) != 0 || len(task.GetScheduleId()) != 0 {
+		return nil, status.Error(codes.InvalidArgument,
+			"Node id and schedule id are set on the trim jobs of each node")
+	}
+
+	sched, err := s.config.Schedules.CreateSchedule(&api.Schedule{
+		StartTime:          in.GetStartTime(),
+		MaxDurationMinutes: task.GetMaxDurationMinutes(),
+		Type:               api.Job_FILESYSTEM_TRIM,
+		Tasks: []*api.Job{
+			{
+				Type: api.Job_FILESYSTEM_TRIM,
+				Job: &api.Job_FilesystemTrim{
+					FilesystemTrim: task,
+				},
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SdkCreateFilesystemTrimScheduleResponse{
+		Schedule: sched,
+	}, nil
+}
+
+// volumesByNode returns the ids of the selected volumes by the node they are
+// attached on. All the attached volumes are selected if no volume is.
+func (s *Scheduler) volumesByNode(ctx context.Context, volumeIDs []string) (map[string][]string, error) {
+	var vols []*api.Volume
+	var err error
+	if len(volumeIDs) != 0 {
+		vols, err = s.config.Driver.Inspect(ctx, volumeIDs)
+	} else {
+		vols, err = s.config.Driver.Enumerate(&api.VolumeLocator{}, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make(map[string][]string)
+	for _, v := range vols {
+		if !v.IsAttached() {
+			continue
+		}
+		nodes[v.GetAttachedOn()] = append(nodes[v.GetAttachedOn()], v.GetId())
+	}
+	for _, ids := range nodes {
+		sort.Strings(ids)
+	}
+	return nodes, nil
+}
+
+// RunSchedule creates the trim jobs of each task of a FILESYSTEM_TRIM
+// schedule and waits for them. The jobs which are still running at the end
+// of the time window of the schedule are cancelled.
+func (s *Scheduler) RunSchedule(ctx context.Context, sched *api.Schedule) error {
+	var jobIDs []string
+	for _, t := range sched.GetTasks() {
+		task := t.GetFilesystemTrim()
+		nodes, err := s.volumesByNode(ctx, task.GetVolumeIds())
+		if err != nil {
+			return fmt.Errorf("failed to get the volumes to trim: %v", err)
+		}
+		for nodeID, ids := range nodes {
+			j, err := s.config.Jobs.CreateJob(&api.Job{
+				Type: api.Job_FILESYSTEM_TRIM,
+				Job: &api.Job_FilesystemTrim{
+					FilesystemTrim: &api.FilesystemTrimJob{
+						VolumeIds:          ids,
+						MaxDurationMinutes: task.GetMaxDurationMinutes(),
+						NodeId:             nodeID,
+						ScheduleId:         sched.GetId(),
+					},
+				},
+			})
+			if err != nil {
+				s.cancel(jobIDs)
+				return fmt.Errorf("failed to create the trim job of node %s: %v", nodeID, err)
+			}
+			jobIDs = append(jobIDs, j.GetId())
+		}
+	}
+	return s.wait(ctx, jobIDs)
+}
+
+// wait waits for the jobs of a run until they are finished, and cancels them
+// when the context is done
+func (s *Scheduler) wait(ctx context.Context, jobIDs []string) error {
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		var running []string
+		failed := 0
+		for _, id := range jobIDs {
+			resp, err := s.config.Jobs.GetJobStatus(context.Background(), &api.SdkGetJobStatusRequest{
+				Id:   id,
+				Type: api.Job_FILESYSTEM_TRIM,
+			})
+			if err != nil {
+				s.cancel(jobIDs)
+				return fmt.Errorf("failed to get the status of trim job %s: %v", id, err)
+			}
+			switch resp.GetJob().GetState() {
+			case api.Job_DONE:
+			case api.Job_FAILED, api.Job_CANCELLED:
+				failed++
+			default:
+				running = append(running, id)
+			}
+		}
+		if len(running) == 0 {
+			if failed > 0 {
+				return fmt.Errorf("%d of %d trim jobs did not finish", failed, len(jobIDs))
+			}
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			s.cancel(running)
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// cancel cancels the trim jobs
+func (s *Scheduler) cancel(jobIDs []string) {
+	for _, id := range jobIDs {
+		if _, err := s.config.Jobs.UpdateJobState(context.Background(), &api.SdkUpdateJobRequest{
+			Id:    id,
+			Type:  api.Job_FILESYSTEM_TRIM,
+			State: api.Job_CANCELLED,
+		}); err != nil {
+			logrus.Warnf("Failed to cancel trim job %s: %v", id, err)
+		}
+	}
+}
+
+// RunsOn returns the node of a FILESYSTEM_TRIM job, since the volumes are
+// trimmed where they are mounted
+func (s *Scheduler) RunsOn(j *api.Job) string {
+	return j.GetFilesystemTrim().GetNodeId()
+}
+
+// trim trims the filesystem of a volume mounted on this node until it is
+// finished. The trim is stopped if the context is done.
+func (s *Scheduler) trim(ctx context.Context, v *api.Volume) error {
+	if len(v.GetAttachPath()) == 0 {
+		return fmt.Errorf("volume is not mounted")
+	}
+	path := v.GetAttachPath()[0]
+	if _, err := s.config.Driver.FilesystemTrimStart(&api.SdkFilesystemTrimStartRequest{
+		VolumeId:  v.GetId(),
+		MountPath: path,
+	}); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if _, err := s.config.Driver.FilesystemTrimStop(&api.SdkFilesystemTrimStopRequest{
+				VolumeId:  v.GetId(),
+				MountPath: path,
+			}); err != nil {
+				logrus.Warnf("Failed to stop the trim of volume %s: %v", v.GetId(), err)
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		resp, err := s.config.Driver.FilesystemTrimStatus(&api.SdkFilesystemTrimStatusRequest{
+			VolumeId:  v.GetId(),
+			MountPath: path,
+		})
+		if err != nil {
+			return err
+		}
+		switch resp.GetStatus() {
+		case api.FilesystemTrim_FS_TRIM_COMPLETED:
+			return nil
+		case api.FilesystemTrim_FS_TRIM_FAILED, api.FilesystemTrim_FS_TRIM_STOPPED,
+			api.FilesystemTrim_FS_TRIM_NOT_RUNNING:
+			return fmt.Errorf("trim is %s: %s", resp.GetStatus(), resp.GetMessage())
+		}
+	}
+}
+
+// Run trims the volumes of a FILESYSTEM_TRIM job on their node, one at a
+// time. Each volume is a task of the job. A resumed job only trims the
+// volumes which were not trimmed by the previous runs.
+func (s *Scheduler) Run(ctx context.Context, j *api.Job, reporter job.Reporter) error {
+	in := j.GetFilesystemTrim()
+	trimmed := make(map[string]bool)
+	for _, t := range reporter.Summary().GetTasks() {
+		if t.GetState() == api.Job_DONE {
+			trimmed[t.GetId()] = true
+		}
+	}
+	total := len(in.GetVolumeIds())
+
+	done, failed := len(trimmed), 0
+	for _, id := range in.GetVolumeIds() {
+		if trimmed[id] {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		task := &api.JobTask{Id: id, State: api.Job_DONE, Status: "Trimmed"}
+		vols, err := s.config.Driver.Inspect(ctx, []string{id})
+		if err == nil && (len(vols) == 0 || vols[0].GetAttachedOn() != in.GetNodeId()) {
+			err = fmt.Errorf("volume is not attached on node %s", in.GetNodeId())
+		}
+		if err == nil {
+			err = s.trim(ctx, vols[0])
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			logrus.Warnf("Failed to trim volume %s on node %s: %v", id, in.GetNodeId(), err)
+			task.State = api.Job_FAILED
+			task.Status = err.Error()
+			failed++
+		} else {
+			done++
+		}
+		if err := reporter.SetTask(task); err != nil {
+			return err
+		}
+		if err := reporter.SetProgress(uint32(done*100/total),
+			fmt.Sprintf("Trimmed %d of %d volumes on node %s", done, total, in.GetNodeId())); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to trim %d volumes on node %s", failed, in.GetNodeId())
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package fstrim
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/job"
+	"github.com/libopenstorage/openstorage/pkg/job/jobtest"
+	"github.com/libopenstorage/openstorage/pkg/schedule"
+	"github.com/libopenstorage/openstorage/volume/drivers/mock"
+)
+
+func newTestScheduler(t *testing.T) (*Scheduler, *mock.MockVolumeDriver, *gomock.Controller) {
+	kv, err := kvdb.New(mem.Name, "fstrim_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	jobs, err := job.NewKvdbProvider(kv, "node1")
+	require.NoError(t, err)
+	schedules, err := schedule.NewKvdbRegistry(kv, "node1")
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	driver := mock.NewMockVolumeDriver(ctrl)
+	s, err := NewScheduler(&SchedulerConfig{
+		Driver:    driver,
+		Jobs:      jobs,
+		Schedules: schedules,
+	})
+	require.NoError(t, err)
+	s.pollInterval = time.Millisecond
+	return s, driver, ctrl
+}
+
+func attachedVolume(id, node string) *api.Volume {
+	return &api.Volume{
+		Id:            id,
+		AttachedOn:    node,
+		AttachPath:    []string{"/mnt/" + id},
+		State:         api.VolumeState_VOLUME_STATE_ATTACHED,
+		AttachedState: api.AttachState_ATTACH_STATE_EXTERNAL,
+	}
+}
+
+func TestCreateFilesystemTrimSchedule(t *testing.T) {
+	s, _, ctrl := newTestScheduler(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	_, err := s.CreateFilesystemTrimSchedule(ctx, &api.SdkCreateFilesystemTrimScheduleRequest{
+		StartTime: "daily=01:00",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = s.CreateFilesystemTrimSchedule(ctx, &api.SdkCreateFilesystemTrimScheduleRequest{
+		TrimTask:  &api.FilesystemTrimJob{NodeId: "node1"},
+		StartTime: "daily=01:00",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	resp, err := s.CreateFilesystemTrimSchedule(ctx, &api.SdkCreateFilesystemTrimScheduleRequest{
+		TrimTask:  &api.FilesystemTrimJob{VolumeIds: []string{"vol1"}, MaxDurationMinutes: 30},
+		StartTime: "daily=01:00",
+	})
+	require.NoError(t, err)
+	sched := resp.GetSchedule()
+	assert.Equal(t, api.Job_FILESYSTEM_TRIM, sched.GetType())
+	assert.Equal(t, uint32(30), sched.GetMaxDurationMinutes())
+	require.Len(t, sched.GetTasks(), 1)
+	assert.Equal(t, []string{"vol1"}, sched.GetTasks()[0].GetFilesystemTrim().GetVolumeIds())
+
+	enumResp, err := s.config.Schedules.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{
+		Type: "FILESYSTEM_TRIM",
+	})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetSchedules(), 1)
+	assert.Equal(t, sched.GetId(), enumResp.GetSchedules()[0].GetId())
+}
+
+func TestRunFilesystemTrimSchedule(t *testing.T) {
+	s, driver, ctrl := newTestScheduler(t)
+	defer ctrl.Finish()
+
+	driver.EXPECT().Enumerate(gomock.Any(), gomock.Any()).Return([]*api.Volume{
+		attachedVolume("vol1", "node1"),
+		attachedVolume("vol2", "node2"),
+		attachedVolume("vol3", "node1"),
+		{Id: "vol4", State: api.VolumeState_VOLUME_STATE_DETACHED},
+	}, nil)
+
+	// The jobs still running at the end of the run are cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := s.RunSchedule(ctx, &api.Schedule{
+		Id:   "trim",
+		Type: api.Job_FILESYSTEM_TRIM,
+		Tasks: []*api.Job{
+			{Type: api.Job_FILESYSTEM_TRIM, Job: &api.Job_FilesystemTrim{FilesystemTrim: &api.FilesystemTrimJob{}}},
+		},
+	})
+	assert.Equal(t, context.Canceled, err)
+
+	resp, err := s.config.Jobs.EnumerateJobs(context.Background(), &api.SdkEnumerateJobsRequest{
+		Type: api.Job_FILESYSTEM_TRIM,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.GetJobs(), 2)
+	volumes := make(map[string][]string)
+	for _, j := range resp.GetJobs() {
+		assert.Equal(t, api.Job_CANCELLED, j.GetState())
+		assert.Equal(t, "trim", j.GetFilesystemTrim().GetScheduleId())
+		volumes[s.RunsOn(j)] = j.GetFilesystemTrim().GetVolumeIds()
+	}
+	assert.Equal(t, map[string][]string{
+		"node1": {"vol1", "vol3"},
+		"node2": {"vol2"},
+	}, volumes)
+}
+
+func TestRunFilesystemTrimJob(t *testing.T) {
+	s, driver, ctrl := newTestScheduler(t)
+	defer ctrl.Finish()
+
+	driver.EXPECT().Inspect(gomock.Any(), []string{"vol2"}).Return([]*api.Volume{attachedVolume("vol2", "node1")}, nil)
+	driver.EXPECT().FilesystemTrimStart(&api.SdkFilesystemTrimStartRequest{
+		VolumeId:  "vol2",
+		MountPath: "/mnt/vol2",
+	}).Return(&api.SdkFilesystemTrimStartResponse{}, nil)
+	gomock.InOrder(
+		driver.EXPECT().FilesystemTrimStatus(gomock.Any()).Return(&api.SdkFilesystemTrimStatusResponse{
+			Status: api.FilesystemTrim_FS_TRIM_INPROGRESS,
+		}, nil),
+		driver.EXPECT().FilesystemTrimStatus(gomock.Any()).Return(&api.SdkFilesystemTrimStatusResponse{
+			Status: api.FilesystemTrim_FS_TRIM_COMPLETED,
+		}, nil),
+	)
+	driver.EXPECT().Inspect(gomock.Any(), []string{"vol3"}).Return([]*api.Volume{attachedVolume("vol3", "node2")}, nil)
+
+	// vol1 was trimmed by the previous run of the job
+	reporter := jobtest.NewReporter(nil)
+	reporter.JobSummary.Tasks = []*api.JobTask{{Id: "vol1", State: api.Job_DONE}}
+	err := s.Run(context.Background(), &api.Job{
+		Type: api.Job_FILESYSTEM_TRIM,
+		Job: &api.Job_FilesystemTrim{FilesystemTrim: &api.FilesystemTrimJob{
+			VolumeIds: []string{"vol1", "vol2", "vol3"},
+			NodeId:    "node1",
+		}},
+	}, reporter)
+	assert.Error(t, err)
+
+	require.Len(t, reporter.JobSummary.Tasks, 3)
+	assert.Equal(t, api.Job_DONE, reporter.JobSummary.Tasks[1].GetState())
+	assert.Equal(t, api.Job_FAILED, reporter.JobSummary.Tasks[2].GetState())
+	assert.Equal(t, uint32(66), reporter.JobSummary.ProgressPercent)
+}
@@ -12,7 +12,7 @@ type Provider interface {
 	// UpdateJobState updates an existing job
 	// Only acceptable values are
 	// JobState_PAUSED - acceptable only from running state
-	// JobState_CANCELLED - acceptable only from running/pause state
+	// JobState_CANCELLED - acceptable only from pending/running/pause state
 	// JobState_RUNNING - acceptable only from pause state
 	UpdateJobState(ctx context.Context, in *api.SdkUpdateJobRequest) (*api.SdkUpdateJobResponse, error)
 	// GetJobStatus gets the status of a job
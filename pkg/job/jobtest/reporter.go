@@ -0,0 +1,57 @@
+// Package jobtest provides helpers to test the runners of jobs
+package jobtest
+
+import (
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/job"
+)
+
+// Reporter is a job.Reporter which keeps the job and its summary in memory
+type Reporter struct {
+	// Job is changed by UpdateJob, which does nothing if it is nil
+	Job *api.Job
+	// JobSummary is changed by SetProgress and SetTask
+	JobSummary *api.JobSummary
+}
+
+var _ job.Reporter = &Reporter{}
+
+// NewReporter returns a reporter of the job with an empty summary
+func NewReporter(j *api.Job) *Reporter {
+	return &Reporter{
+		Job:        j,
+		JobSummary: &api.JobSummary{},
+	}
+}
+
+// Summary returns the summary of the job
+func (r *Reporter) Summary() *api.JobSummary {
+	return r.JobSummary
+}
+
+// SetProgress saves the progress in percent and the status of the job
+func (r *Reporter) SetProgress(percent uint32, status string) error {
+	r.JobSummary.ProgressPercent = percent
+	r.JobSummary.Status = status
+	return nil
+}
+
+// SetTask replaces the task with the same id, or adds the task
+func (r *Reporter) SetTask(task *api.JobTask) error {
+	for i, t := range r.JobSummary.Tasks {
+		if t.GetId() == task.GetId() {
+			r.JobSummary.Tasks[i] = task
+			return nil
+		}
+	}
+	r.JobSummary.Tasks = append(r.JobSummary.Tasks, task)
+	return nil
+}
+
+// UpdateJob changes the job with fn
+func (r *Reporter) UpdateJob(fn func(job *api.Job)) error {
+	if r.Job != nil {
+		fn(r.Job)
+	}
+	return nil
+}
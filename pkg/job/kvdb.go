@@ -0,0 +1,728 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+)
+
+const (
+	recordsPrefix = "cluster/jobs/records/"
+	leasesPrefix  = "cluster/jobs/leases/"
+
+	// RecordsKvdbPrefix is the kvdb prefix of the jobs, which changes when a
+	// job is created, updated or deleted
+	RecordsKvdbPrefix = recordsPrefix
+
+	// DefaultLeaseDuration is how long a node keeps the lease of a job
+	// without renewing it
+	DefaultLeaseDuration = 30 * time.Second
+	// DefaultScheduleInterval is how often a node looks for jobs to run and
+	// renews the leases of its running jobs
+	DefaultScheduleInterval = 10 * time.Second
+	// DefaultRetention is how long the jobs which are done, failed or
+	// cancelled are kept after their last update
+	DefaultRetention = 7 * 24 * time.Hour
+)
+
+// Runner runs the jobs of one type
+type Runner interface {
+	// Run runs a job until it is done. Run is called again to resume the job
+	// after it is paused, or when the node running it stops, so it must be
+	// able to continue from the summary saved by the reporter. The context
+	// is cancelled when the job is paused or cancelled, or when the node
+	// loses the lease of the job.
+	Run(ctx context.Context, job *api.Job, reporter Reporter) error
+}
+
+// NodeRunner is a Runner whose jobs must run on a given node, such as the jobs
+// acting on the volumes attached to the node
+type NodeRunner interface {
+	Runner
+	// RunsOn returns the id of the node which must run the job, or an empty
+	// string if the job may run on any node
+	RunsOn(job *api.Job) string
+}
+
+// Consumer is implemented by the components, such as the volume drivers, which
+// run their work as jobs of the job provider of the cluster
+type Consumer interface {
+	// SetJobProvider registers the runners of the component with the job
+	// provider of the cluster, before the provider is started
+	SetJobProvider(p *KvdbProvider) error
+}
+
+// Reporter saves the progress of a running job
+type Reporter interface {
+	// Summary returns the last saved summary of the job
+	Summary() *api.JobSummary
+	// SetProgress saves the progress in percent and the status of the job
+	SetProgress(percent uint32, status string) error
+	// SetTask saves the status of a task of the job
+	SetTask(task *api.JobTask) error
+	// UpdateJob saves the changes made by fn to the type specific fields of
+	// the job, such as the status of each node of the job
+	UpdateJob(fn func(job *api.Job)) error
+}
+
+// lease is saved in kvdb by the node running a job. Expires is compared with
+// the clock of the other nodes, so the clocks must be kept in sync.
+type lease struct {
+	NodeID  string    `json:"node_id"`
+	Expires time.Time `json:"expires"`
+}
+
+// KvdbProvider is a Provider which saves the jobs in kvdb. Each job is run by
+// the node holding its lease, using the runner registered for the type of
+// the job. When that node stops renewing the lease, the job is resumed by
+// another node.
+type KvdbProvider struct {
+	kv               kvdb.Kvdb
+	nodeID           string
+	leaseDuration    time.Duration
+	scheduleInterval time.Duration
+	retention        time.Duration
+
+	lock    sync.Mutex
+	runners map[api.Job_Type]Runner
+	running map[string]context.CancelFunc
+	stop    chan struct{}
+	stopped bool
+	wg      sync.WaitGroup
+
+	// scheduleLock serializes the passes of schedule, so that a job is
+	// started once
+	scheduleLock sync.Mutex
+}
+
+// Check interface
+var _ Provider = &KvdbProvider{}
+
+// NewKvdbProvider returns a job provider which saves the jobs in kvdb and
+// runs them on the node nodeID
+func NewKvdbProvider(kv kvdb.Kvdb, nodeID string) (*KvdbProvider, error) {
+	if kv == nil {
+		return nil, fmt.Errorf("Must supply a kvdb instance for the job provider")
+	} else if len(nodeID) == 0 {
+		return nil, fmt.Errorf("Must supply a node id for the job provider")
+	}
+	return &KvdbProvider{
+		kv:               kv,
+		nodeID:           nodeID,
+		leaseDuration:    DefaultLeaseDuration,
+		scheduleInterval: DefaultScheduleInterval,
+		retention:        DefaultRetention,
+		runners:          make(map[api.Job_Type]Runner),
+		running:          make(map[string]context.CancelFunc),
+	}, nil
+}
+
+// NodeID returns the id of the node running the jobs of this provider
+func (p *KvdbProvider) NodeID() string {
+	return p.nodeID
+}
+
+func recordKey(id string) string {
+	return recordsPrefix + id
+}
+
+func leaseKey(id string) string {
+	return leasesPrefix + id
+}
+
+// The job and its summary are saved together as a SdkGetJobStatusResponse.
+// Job has oneof fields, so it is marshalled with jsonpb.
+func marshalRecord(r *api.SdkGetJobStatusResponse) (string, error) {
+	marshaler := jsonpb.Marshaler{OrigName: true}
+	return marshaler.MarshalToString(r)
+}
+
+func unmarshalRecord(kvp *kvdb.KVPair) (*api.SdkGetJobStatusResponse, error) {
+	r := &api.SdkGetJobStatusResponse{}
+	if err := jsonpb.UnmarshalString(string(kvp.Value), r); err != nil {
+		return nil, status.Errorf(codes.Internal, "Json Unmarshal failed for job %s: %v", kvp.Key, err)
+	}
+	if r.Job == nil {
+		r.Job = &api.Job{}
+	}
+	if r.Summary == nil {
+		r.Summary = &api.JobSummary{Id: r.Job.GetId()}
+	}
+	return r, nil
+}
+
+// RegisterRunner sets the runner of the jobs of a type on this node
+func (p *KvdbProvider) RegisterRunner(jobType api.Job_Type, runner Runner) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if _, ok := p.runners[jobType]; ok {
+		return fmt.Errorf("Runner for jobs of type %s is already registered", jobType)
+	}
+	p.runners[jobType] = runner
+	return nil
+}
+
+// CreateJob saves a new job in the PENDING state. It is started by one of the
+// nodes with a runner for its type. The ID of the job is generated if it is
+// not set.
+func (p *KvdbProvider) CreateJob(job *api.Job) (*api.Job, error) {
+	if job.GetType() == api.Job_UNSPECIFIED_TYPE || job.GetType() == api.Job_NONE {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a job type")
+	} else if strings.Contains(job.GetId(), "/") {
+		return nil, status.Errorf(codes.InvalidArgument, "Job id %s must not contain '/'", job.GetId())
+	}
+
+	job = proto.Clone(job).(*api.Job)
+	if len(job.Id) == 0 {
+		job.Id = uuid.New()
+	}
+	job.State = api.Job_PENDING
+	job.CreateTime = prototime.Now()
+	job.LastUpdateTime = job.CreateTime
+
+	value, err := marshalRecord(&api.SdkGetJobStatusResponse{
+		Job:     job,
+		Summary: &api.JobSummary{Id: job.Id},
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to marshal job %s: %v", job.Id, err)
+	}
+	if _, err := p.kv.Create(recordKey(job.Id), value, 0); err == kvdb.ErrExist {
+		return nil, status.Errorf(codes.AlreadyExists, "Job %s already exists", job.Id)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to save job %s: %v", job.Id, err)
+	}
+
+	logrus.Infof("Job %s of type %s is created", job.Id, job.Type)
+	return job, nil
+}
+
+// update reads a job, calls fn to change it and saves it, retrying if the job
+// has been updated in the meantime. The update is abandoned if fn fails.
+func (p *KvdbProvider) update(
+	id string,
+	fn func(r *api.SdkGetJobStatusResponse) error,
+) (*api.SdkGetJobStatusResponse, error) {
+	for {
+		kvp, err := p.kv.Get(recordKey(id))
+		if err == kvdb.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "Job %s not found", id)
+		} else if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to get job %s: %v", id, err)
+		}
+		r, err := unmarshalRecord(kvp)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(r); err != nil {
+			return nil, err
+		}
+		r.Job.LastUpdateTime = prototime.Now()
+
+		value, err := marshalRecord(r)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to marshal job %s: %v", id, err)
+		}
+		kvp.Value = []byte(value)
+		_, err = p.kv.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil)
+		if err == kvdb.ErrValueMismatch || err == kvdb.ErrModified {
+			continue
+		} else if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to save job %s: %v", id, err)
+		}
+		return r, nil
+	}
+}
+
+// acquireLease takes or renews the lease of a job for this node. It returns
+// false if another node holds a lease which has not expired.
+func (p *KvdbProvider) acquireLease(id string) (bool, error) {
+	now := time.Now()
+	value, err := json.Marshal(&lease{
+		NodeID:  p.nodeID,
+		Expires: now.Add(p.leaseDuration),
+	})
+	if err != nil {
+		return false, err
+	}
+
+	kvp, err := p.kv.Get(leaseKey(id))
+	if err == kvdb.ErrNotFound {
+		_, err = p.kv.Create(leaseKey(id), string(value), 0)
+		if err == kvdb.ErrExist {
+			return false, nil
+		}
+		return err == nil, err
+	} else if err != nil {
+		return false, err
+	}
+
+	var l lease
+	if err := json.Unmarshal(kvp.Value, &l); err != nil {
+		return false, err
+	}
+	if l.NodeID != p.nodeID && now.Before(l.Expires) {
+		return false, nil
+	}
+
+	kvp.Value = value
+	_, err = p.kv.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil)
+	if err == kvdb.ErrValueMismatch || err == kvdb.ErrModified {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// holdsLease returns true if this node holds a lease of a job which has not
+// expired
+func (p *KvdbProvider) holdsLease(id string) (bool, error) {
+	kvp, err := p.kv.Get(leaseKey(id))
+	if err == kvdb.ErrNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	var l lease
+	if err := json.Unmarshal(kvp.Value, &l); err != nil {
+		return false, err
+	}
+	return l.NodeID == p.nodeID && time.Now().Before(l.Expires), nil
+}
+
+// releaseLease deletes the lease of a job if it is held by this node
+func (p *KvdbProvider) releaseLease(id string) {
+	kvp, err := p.kv.Get(leaseKey(id))
+	if err != nil {
+		return
+	}
+	var l lease
+	if err := json.Unmarshal(kvp.Value, &l); err != nil || l.NodeID != p.nodeID {
+		return
+	}
+	if _, err := p.kv.CompareAndDelete(kvp, kvdb.KVModifiedIndex); err != nil {
+		logrus.Warnf("Failed to release the lease of job %s: %v", id, err)
+	}
+}
+
+// Start starts running the jobs of the registered types on this node. The
+// jobs which were running on this node before it stopped are resumed first.
+func (p *KvdbProvider) Start() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.stop != nil {
+		return fmt.Errorf("Job provider is already started")
+	}
+	stop := make(chan struct{})
+	p.stop = stop
+	p.stopped = false
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.scheduleInterval)
+		defer ticker.Stop()
+		for {
+			p.schedule()
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops the jobs running on this node, so that they are resumed by
+// another node or when this node is started again
+func (p *KvdbProvider) Stop() {
+	p.lock.Lock()
+	if p.stop != nil {
+		close(p.stop)
+		p.stop = nil
+	}
+	p.stopped = true
+	for _, cancel := range p.running {
+		cancel()
+	}
+	p.lock.Unlock()
+
+	p.wg.Wait()
+}
+
+// Schedule starts the jobs of the registered types which are not running on
+// any node now, instead of at the next schedule interval. It is used after
+// creating a job which should start without delay.
+func (p *KvdbProvider) Schedule() {
+	p.lock.Lock()
+	started := p.stop != nil
+	p.lock.Unlock()
+	if started {
+		p.schedule()
+	}
+}
+
+// schedule starts the jobs which are not running on any node, renews the
+// leases of the jobs running on this node, stops the jobs which have been
+// paused or cancelled and deletes the finished jobs past their retention
+func (p *KvdbProvider) schedule() {
+	p.scheduleLock.Lock()
+	defer p.scheduleLock.Unlock()
+
+	kvps, err := p.kv.Enumerate(recordsPrefix)
+	if err != nil {
+		logrus.Warnf("Failed to get jobs: %v", err)
+		return
+	}
+
+	for _, kvp := range kvps {
+		r, err := unmarshalRecord(kvp)
+		if err != nil {
+			logrus.Warnf("%v", err)
+			continue
+		}
+		job := r.GetJob()
+		active := job.GetState() == api.Job_PENDING || job.GetState() == api.Job_RUNNING
+
+		p.lock.Lock()
+		cancel, running := p.running[job.GetId()]
+		runner := p.runners[job.GetType()]
+		p.lock.Unlock()
+
+		if running {
+			if !active {
+				cancel()
+			} else if ok, err := p.acquireLease(job.GetId()); err != nil {
+				logrus.Warnf("Failed to renew the lease of job %s, stopping it: %v", job.GetId(), err)
+				cancel()
+			} else if !ok {
+				logrus.Warnf("Lease of job %s was taken by another node, stopping it", job.GetId())
+				cancel()
+			}
+			continue
+		}
+		if p.expired(job) {
+			p.deleteJob(kvp)
+			continue
+		}
+		if !active || runner == nil {
+			continue
+		}
+		if nr, ok := runner.(NodeRunner); ok {
+			if nodeID := nr.RunsOn(job); len(nodeID) != 0 && nodeID != p.nodeID {
+				continue
+			}
+		}
+		if ok, err := p.acquireLease(job.GetId()); err != nil {
+			logrus.Warnf("Failed to get the lease of job %s: %v", job.GetId(), err)
+			continue
+		} else if !ok {
+			continue
+		}
+		p.start(job.GetId(), runner)
+	}
+}
+
+// expired returns true if a job is done, failed or cancelled for longer than
+// the retention period
+func (p *KvdbProvider) expired(job *api.Job) bool {
+	switch job.GetState() {
+	case api.Job_DONE, api.Job_FAILED, api.Job_CANCELLED:
+		return time.Since(job.GetLastUpdateTime().AsTime()) > p.retention
+	}
+	return false
+}
+
+// deleteJob deletes the record of a job and its lease, unless the job has
+// been updated since it was read
+func (p *KvdbProvider) deleteJob(kvp *kvdb.KVPair) {
+	id := strings.TrimPrefix(kvp.Key, recordsPrefix)
+	if _, err := p.kv.CompareAndDelete(kvp, kvdb.KVModifiedIndex); err != nil {
+		if err != kvdb.ErrNotFound && err != kvdb.ErrModified && err != kvdb.ErrValueMismatch {
+			logrus.Warnf("Failed to delete job %s: %v", id, err)
+		}
+		return
+	}
+	if _, err := p.kv.Delete(leaseKey(id)); err != nil && err != kvdb.ErrNotFound {
+		logrus.Warnf("Failed to delete the lease of job %s: %v", id, err)
+	}
+	logrus.Infof("Job %s is deleted after its retention period", id)
+}
+
+// start runs a job on this node. The lease of the job must be held.
+func (p *KvdbProvider) start(id string, runner Runner) {
+	r, err := p.update(id, func(r *api.SdkGetJobStatusResponse) error {
+		if r.Job.State != api.Job_PENDING && r.Job.State != api.Job_RUNNING {
+			return status.Errorf(codes.FailedPrecondition, "Job %s is %s", id, r.Job.State)
+		}
+		r.Job.State = api.Job_RUNNING
+		return nil
+	})
+	if err != nil {
+		logrus.Warnf("Failed to start job %s: %v", id, err)
+		p.releaseLease(id)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p.lock.Lock()
+	if p.stopped {
+		p.lock.Unlock()
+		cancel()
+		p.releaseLease(id)
+		return
+	}
+	p.running[id] = cancel
+	p.wg.Add(1)
+	p.lock.Unlock()
+
+	rep := &reporter{
+		p:       p,
+		id:      id,
+		cancel:  cancel,
+		started: time.Now(),
+		runtime: r.GetSummary().GetTotalRuntimeSeconds(),
+		summary: r.GetSummary(),
+	}
+	logrus.Infof("Job %s of type %s is running on node %s", id, r.GetJob().GetType(), p.nodeID)
+
+	go func() {
+		defer p.wg.Done()
+		defer cancel()
+
+		err := runner.Run(ctx, r.GetJob(), rep)
+		p.finish(ctx, id, rep, err)
+	}()
+}
+
+// finish saves the state of a job after its runner has returned. Nothing is
+// saved if the node has lost the lease of the job, which may be run by
+// another node.
+func (p *KvdbProvider) finish(ctx context.Context, id string, rep *reporter, runErr error) {
+	defer func() {
+		p.lock.Lock()
+		delete(p.running, id)
+		p.lock.Unlock()
+	}()
+
+	err := rep.save(func(r *api.SdkGetJobStatusResponse) {
+		// The job has been paused, cancelled or stopped on this node
+		if r.Job.State != api.Job_RUNNING || ctx.Err() != nil {
+			return
+		}
+		if runErr != nil {
+			r.Job.State = api.Job_FAILED
+			r.Summary.Status = runErr.Error()
+			logrus.Warnf("Job %s failed: %v", id, runErr)
+		} else {
+			r.Job.State = api.Job_DONE
+			r.Summary.ProgressPercent = 100
+			logrus.Infof("Job %s is done", id)
+		}
+	})
+	if err != nil {
+		logrus.Warnf("Failed to save the state of job %s: %v", id, err)
+		return
+	}
+	p.releaseLease(id)
+}
+
+// UpdateJobState updates the state of a job. The job is stopped on the node
+// running it when it is paused or cancelled.
+func (p *KvdbProvider) UpdateJobState(
+	ctx context.Context,
+	in *api.SdkUpdateJobRequest,
+) (*api.SdkUpdateJobResponse, error) {
+	if len(in.GetId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a job id")
+	}
+
+	_, err := p.update(in.GetId(), func(r *api.SdkGetJobStatusResponse) error {
+		if in.GetType() != api.Job_UNSPECIFIED_TYPE && in.GetType() != r.Job.Type {
+			return status.Errorf(codes.NotFound, "Job %s of type %s not found", in.GetId(), in.GetType())
+		}
+
+		current := r.Job.State
+		switch in.GetState() {
+		case api.Job_PAUSED:
+			if current != api.Job_RUNNING {
+				return status.Errorf(codes.FailedPrecondition, "Cannot pause job %s which is %s", in.GetId(), current)
+			}
+		case api.Job_CANCELLED:
+			if current != api.Job_PENDING && current != api.Job_RUNNING && current != api.Job_PAUSED {
+				return status.Errorf(codes.FailedPrecondition, "Cannot cancel job %s which is %s", in.GetId(), current)
+			}
+		case api.Job_RUNNING:
+			if current != api.Job_PAUSED {
+				return status.Errorf(codes.FailedPrecondition, "Cannot resume job %s which is %s", in.GetId(), current)
+			}
+		default:
+			return status.Errorf(codes.InvalidArgument, "Cannot update job %s to state %s", in.GetId(), in.GetState())
+		}
+		r.Job.State = in.GetState()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Stop the job now if it is running on this node. It is stopped by the
+	// other nodes when they see its new state.
+	if in.GetState() != api.Job_RUNNING {
+		p.lock.Lock()
+		if cancel, ok := p.running[in.GetId()]; ok {
+			cancel()
+		}
+		p.lock.Unlock()
+	}
+
+	logrus.Infof("Job %s is updated to state %s", in.GetId(), in.GetState())
+	return &api.SdkUpdateJobResponse{}, nil
+}
+
+// GetJobStatus returns a job and its summary
+func (p *KvdbProvider) GetJobStatus(
+	ctx context.Context,
+	in *api.SdkGetJobStatusRequest,
+) (*api.SdkGetJobStatusResponse, error) {
+	if len(in.GetId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a job id")
+	}
+
+	kvp, err := p.kv.Get(recordKey(in.GetId()))
+	if err == kvdb.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "Job %s not found", in.GetId())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get job %s: %v", in.GetId(), err)
+	}
+	r, err := unmarshalRecord(kvp)
+	if err != nil {
+		return nil, err
+	}
+	if in.GetType() != api.Job_UNSPECIFIED_TYPE && in.GetType() != r.GetJob().GetType() {
+		return nil, status.Errorf(codes.NotFound, "Job %s of type %s not found", in.GetId(), in.GetType())
+	}
+	return r, nil
+}
+
+// EnumerateJobs returns the jobs, oldest first
+func (p *KvdbProvider) EnumerateJobs(
+	ctx context.Context,
+	in *api.SdkEnumerateJobsRequest,
+) (*api.SdkEnumerateJobsResponse, error) {
+	kvps, err := p.kv.Enumerate(recordsPrefix)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get jobs from database: %v", err)
+	}
+
+	jobs := make([]*api.Job, 0, len(kvps))
+	for _, kvp := range kvps {
+		r, err := unmarshalRecord(kvp)
+		if err != nil {
+			return nil, err
+		}
+		if in.GetType() == api.Job_UNSPECIFIED_TYPE || in.GetType() == r.GetJob().GetType() {
+			jobs = append(jobs, r.GetJob())
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].GetCreateTime().AsTime().Before(jobs[j].GetCreateTime().AsTime())
+	})
+
+	return &api.SdkEnumerateJobsResponse{
+		Jobs: jobs,
+	}, nil
+}
+
+// reporter saves the progress of a job running on this node
+type reporter struct {
+	p  *KvdbProvider
+	id string
+	// cancel stops the runner of the job when the lease of the job is lost
+	cancel  context.CancelFunc
+	started time.Time
+	// runtime is the runtime in seconds of the previous runs
+	runtime uint64
+
+	lock    sync.Mutex
+	summary *api.JobSummary
+}
+
+func (r *reporter) Summary() *api.JobSummary {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return proto.Clone(r.summary).(*api.JobSummary)
+}
+
+func (r *reporter) SetProgress(percent uint32, status string) error {
+	if percent > 100 {
+		percent = 100
+	}
+	return r.save(func(rec *api.SdkGetJobStatusResponse) {
+		rec.Summary.ProgressPercent = percent
+		rec.Summary.Status = status
+	})
+}
+
+func (r *reporter) SetTask(task *api.JobTask) error {
+	task = proto.Clone(task).(*api.JobTask)
+	task.LastUpdateTime = prototime.Now()
+	return r.save(func(rec *api.SdkGetJobStatusResponse) {
+		for i, t := range rec.Summary.Tasks {
+			if t.GetId() == task.GetId() {
+				rec.Summary.Tasks[i] = task
+				return
+			}
+		}
+		rec.Summary.Tasks = append(rec.Summary.Tasks, task)
+	})
+}
+
+func (r *reporter) UpdateJob(fn func(job *api.Job)) error {
+	return r.save(func(rec *api.SdkGetJobStatusResponse) {
+		fn(rec.Job)
+	})
+}
+
+// save updates the job and its runtime. The job is only updated while this
+// node holds its lease, so that a node which has lost the lease of the job
+// does not overwrite the progress of the node which took it over. The runner
+// is stopped when the lease is lost.
+func (r *reporter) save(fn func(rec *api.SdkGetJobStatusResponse)) error {
+	rec, err := r.p.update(r.id, func(rec *api.SdkGetJobStatusResponse) error {
+		if ok, err := r.p.holdsLease(r.id); err != nil {
+			return status.Errorf(codes.Internal, "Failed to get the lease of job %s: %v", r.id, err)
+		} else if !ok {
+			r.cancel()
+			return status.Errorf(codes.Aborted, "Lease of job %s was lost", r.id)
+		}
+		fn(rec)
+		rec.Summary.TotalRuntimeSeconds = r.runtime + uint64(time.Since(r.started).Seconds())
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.lock.Lock()
+	r.summary = rec.GetSummary()
+	r.lock.Unlock()
+	return nil
+}
@@ -0,0 +1,424 @@
+package job
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+// testRunner runs each job with a task for every run. It blocks until the
+// job is stopped, unless done is closed.
+type testRunner struct {
+	lock sync.Mutex
+	runs int
+	done chan struct{}
+	err  error
+}
+
+func newTestRunner() *testRunner {
+	return &testRunner{done: make(chan struct{})}
+}
+
+func (r *testRunner) Run(ctx context.Context, job *api.Job, reporter Reporter) error {
+	r.lock.Lock()
+	r.runs++
+	run := r.runs
+	r.lock.Unlock()
+
+	if err := reporter.SetTask(&api.JobTask{
+		Id:     fmt.Sprintf("run-%d", run),
+		State:  api.Job_RUNNING,
+		Status: fmt.Sprintf("resumed with %d tasks", len(reporter.Summary().GetTasks())),
+	}); err != nil {
+		return err
+	}
+	if err := reporter.SetProgress(50, "half way"); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-r.done:
+		return r.err
+	}
+}
+
+func (r *testRunner) getRuns() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.runs
+}
+
+// nodeRunner is a testRunner whose jobs run on one node
+type nodeRunner struct {
+	*testRunner
+	nodeID string
+}
+
+func (r *nodeRunner) RunsOn(job *api.Job) string {
+	return r.nodeID
+}
+
+func newTestProvider(t *testing.T, kv kvdb.Kvdb, nodeID string, runner Runner) *KvdbProvider {
+	p, err := NewKvdbProvider(kv, nodeID)
+	require.NoError(t, err)
+	require.NoError(t, p.RegisterRunner(api.Job_DEFRAG, runner))
+	return p
+}
+
+func newTestKvdb(t *testing.T) kvdb.Kvdb {
+	kv, err := kvdb.New(mem.Name, "job_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	return kv
+}
+
+func jobStatus(t *testing.T, p *KvdbProvider, id string) *api.SdkGetJobStatusResponse {
+	resp, err := p.GetJobStatus(context.Background(), &api.SdkGetJobStatusRequest{Id: id})
+	require.NoError(t, err)
+	return resp
+}
+
+func waitForState(t *testing.T, p *KvdbProvider, id string, state api.Job_State) {
+	require.Eventually(t, func() bool {
+		return jobStatus(t, p, id).GetJob().GetState() == state
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func isRunning(p *KvdbProvider, id string) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	_, ok := p.running[id]
+	return ok
+}
+
+func TestKvdbProviderRunJob(t *testing.T) {
+	runner := newTestRunner()
+	p := newTestProvider(t, newTestKvdb(t), "node1", runner)
+	defer p.Stop()
+
+	_, err := p.CreateJob(&api.Job{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = p.CreateJob(&api.Job{Id: "a/b", Type: api.Job_DEFRAG})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	job, err := p.CreateJob(&api.Job{
+		Type: api.Job_DEFRAG,
+		Job: &api.Job_Defrag{
+			Defrag: &api.DefragJob{MaxNodesInParallel: 2},
+		},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, job.GetId())
+	assert.Equal(t, api.Job_PENDING, job.GetState())
+
+	_, err = p.CreateJob(job)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	// Jobs of other types are not run
+	other, err := p.CreateJob(&api.Job{Type: api.Job_COLLECT_DIAGS})
+	require.NoError(t, err)
+
+	p.schedule()
+	waitForState(t, p, job.GetId(), api.Job_RUNNING)
+	require.Eventually(t, func() bool {
+		return jobStatus(t, p, job.GetId()).GetSummary().GetProgressPercent() == 50
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, api.Job_PENDING, jobStatus(t, p, other.GetId()).GetJob().GetState())
+
+	close(runner.done)
+	waitForState(t, p, job.GetId(), api.Job_DONE)
+
+	resp := jobStatus(t, p, job.GetId())
+	assert.Equal(t, uint32(2), resp.GetJob().GetDefrag().GetMaxNodesInParallel())
+	assert.Equal(t, uint32(100), resp.GetSummary().GetProgressPercent())
+	require.Len(t, resp.GetSummary().GetTasks(), 1)
+	assert.Equal(t, "run-1", resp.GetSummary().GetTasks()[0].GetId())
+
+	_, err = p.GetJobStatus(context.Background(), &api.SdkGetJobStatusRequest{
+		Id:   job.GetId(),
+		Type: api.Job_COLLECT_DIAGS,
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	enumResp, err := p.EnumerateJobs(context.Background(), &api.SdkEnumerateJobsRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetJobs(), 2)
+	assert.Equal(t, job.GetId(), enumResp.GetJobs()[0].GetId())
+	enumResp, err = p.EnumerateJobs(context.Background(), &api.SdkEnumerateJobsRequest{
+		Type: api.Job_COLLECT_DIAGS,
+	})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetJobs(), 1)
+	assert.Equal(t, other.GetId(), enumResp.GetJobs()[0].GetId())
+}
+
+func TestKvdbProviderJobFailed(t *testing.T) {
+	runner := newTestRunner()
+	runner.err = fmt.Errorf("no space left")
+	close(runner.done)
+	p := newTestProvider(t, newTestKvdb(t), "node1", runner)
+	defer p.Stop()
+
+	job, err := p.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+
+	p.schedule()
+	waitForState(t, p, job.GetId(), api.Job_FAILED)
+	assert.Equal(t, "no space left", jobStatus(t, p, job.GetId()).GetSummary().GetStatus())
+}
+
+func TestKvdbProviderSchedule(t *testing.T) {
+	runner := newTestRunner()
+	close(runner.done)
+	p := newTestProvider(t, newTestKvdb(t), "node1", runner)
+	p.scheduleInterval = time.Hour
+	defer p.Stop()
+
+	// Jobs are not run before the provider is started
+	job, err := p.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+	p.Schedule()
+	assert.Equal(t, api.Job_PENDING, jobStatus(t, p, job.GetId()).GetJob().GetState())
+
+	require.NoError(t, p.Start())
+	waitForState(t, p, job.GetId(), api.Job_DONE)
+
+	// Jobs created after the first pass start without waiting for the next
+	job, err = p.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+	p.Schedule()
+	waitForState(t, p, job.GetId(), api.Job_DONE)
+	assert.Equal(t, 2, runner.getRuns())
+}
+
+func TestKvdbProviderUpdateJobState(t *testing.T) {
+	runner := newTestRunner()
+	p := newTestProvider(t, newTestKvdb(t), "node1", runner)
+	defer p.Stop()
+	ctx := context.Background()
+
+	job, err := p.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+
+	// Only a running job can be paused
+	_, err = p.UpdateJobState(ctx, &api.SdkUpdateJobRequest{Id: job.GetId(), State: api.Job_PAUSED})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = p.UpdateJobState(ctx, &api.SdkUpdateJobRequest{Id: job.GetId(), State: api.Job_DONE})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = p.UpdateJobState(ctx, &api.SdkUpdateJobRequest{Id: "missing", State: api.Job_PAUSED})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	p.schedule()
+	waitForState(t, p, job.GetId(), api.Job_RUNNING)
+
+	_, err = p.UpdateJobState(ctx, &api.SdkUpdateJobRequest{Id: job.GetId(), State: api.Job_PAUSED})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return !isRunning(p, job.GetId())
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, api.Job_PAUSED, jobStatus(t, p, job.GetId()).GetJob().GetState())
+
+	// A paused job is not started
+	p.schedule()
+	assert.Equal(t, 1, runner.getRuns())
+
+	// The job is resumed with the tasks of the previous run
+	_, err = p.UpdateJobState(ctx, &api.SdkUpdateJobRequest{Id: job.GetId(), State: api.Job_RUNNING})
+	require.NoError(t, err)
+	p.schedule()
+	require.Eventually(t, func() bool {
+		return len(jobStatus(t, p, job.GetId()).GetSummary().GetTasks()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 2, runner.getRuns())
+	assert.Equal(t, "resumed with 1 tasks", jobStatus(t, p, job.GetId()).GetSummary().GetTasks()[1].GetStatus())
+
+	_, err = p.UpdateJobState(ctx, &api.SdkUpdateJobRequest{Id: job.GetId(), State: api.Job_CANCELLED})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return !isRunning(p, job.GetId())
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, api.Job_CANCELLED, jobStatus(t, p, job.GetId()).GetJob().GetState())
+
+	_, err = p.UpdateJobState(ctx, &api.SdkUpdateJobRequest{Id: job.GetId(), State: api.Job_RUNNING})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestKvdbProviderLease(t *testing.T) {
+	kv := newTestKvdb(t)
+	runner := newTestRunner()
+	p1 := newTestProvider(t, kv, "node1", runner)
+	p2 := newTestProvider(t, kv, "node2", runner)
+	defer p2.Stop()
+
+	job, err := p1.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+
+	// Only one node runs the job
+	p1.schedule()
+	waitForState(t, p1, job.GetId(), api.Job_RUNNING)
+	p2.schedule()
+	assert.False(t, isRunning(p2, job.GetId()))
+	assert.Equal(t, 1, runner.getRuns())
+
+	// The job is resumed by the other node when the node stops
+	p1.Stop()
+	p2.schedule()
+	assert.True(t, isRunning(p2, job.GetId()))
+	require.Eventually(t, func() bool {
+		return len(jobStatus(t, p2, job.GetId()).GetSummary().GetTasks()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// An expired lease can be taken by another node
+	p1.leaseDuration = 10 * time.Millisecond
+	ok, err := p1.acquireLease("other")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = p2.acquireLease("other")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	time.Sleep(20 * time.Millisecond)
+	ok, err = p2.acquireLease("other")
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestKvdbProviderNodeRunner(t *testing.T) {
+	kv := newTestKvdb(t)
+	runner := &nodeRunner{testRunner: newTestRunner(), nodeID: "node2"}
+	p1 := newTestProvider(t, kv, "node1", runner)
+	defer p1.Stop()
+	p2 := newTestProvider(t, kv, "node2", runner)
+	defer p2.Stop()
+
+	job, err := p1.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+
+	// The job is not run by the other nodes, even when its lease is free
+	p1.schedule()
+	assert.False(t, isRunning(p1, job.GetId()))
+	_, err = kv.Get(leaseKey(job.GetId()))
+	assert.Equal(t, kvdb.ErrNotFound, err)
+
+	p2.schedule()
+	assert.True(t, isRunning(p2, job.GetId()))
+	waitForState(t, p2, job.GetId(), api.Job_RUNNING)
+	assert.Equal(t, 1, runner.getRuns())
+}
+
+func TestKvdbProviderLostLease(t *testing.T) {
+	kv := newTestKvdb(t)
+	runner := newTestRunner()
+	p := newTestProvider(t, kv, "node1", runner)
+	defer p.Stop()
+
+	job, err := p.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+	p.schedule()
+	waitForState(t, p, job.GetId(), api.Job_RUNNING)
+
+	// Another node takes the lease before the runner returns, the job is
+	// not marked as done
+	value, err := json.Marshal(&lease{NodeID: "node2", Expires: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = kv.Put(leaseKey(job.GetId()), string(value), 0)
+	require.NoError(t, err)
+	close(runner.done)
+	require.Eventually(t, func() bool {
+		return !isRunning(p, job.GetId())
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, api.Job_RUNNING, jobStatus(t, p, job.GetId()).GetJob().GetState())
+	ok, err := p.holdsLease(job.GetId())
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestKvdbProviderReporterLostLease(t *testing.T) {
+	kv := newTestKvdb(t)
+	p := newTestProvider(t, kv, "node1", newTestRunner())
+	defer p.Stop()
+
+	job, err := p.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+	ok, err := p.acquireLease(job.GetId())
+	require.NoError(t, err)
+	require.True(t, ok)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	rep := &reporter{p: p, id: job.GetId(), cancel: cancel, started: time.Now(), summary: &api.JobSummary{}}
+	require.NoError(t, rep.SetProgress(10, "started"))
+
+	// Another node takes the lease, the progress of this node is not saved
+	// and its runner is stopped
+	value, err := json.Marshal(&lease{NodeID: "node2", Expires: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = kv.Put(leaseKey(job.GetId()), string(value), 0)
+	require.NoError(t, err)
+	err = rep.SetProgress(50, "half way")
+	assert.Equal(t, codes.Aborted, status.Code(err))
+	assert.Error(t, ctx.Err())
+	assert.Equal(t, uint32(10), jobStatus(t, p, job.GetId()).GetSummary().GetProgressPercent())
+}
+
+func TestKvdbProviderRetention(t *testing.T) {
+	runner := newTestRunner()
+	close(runner.done)
+	p := newTestProvider(t, newTestKvdb(t), "node1", runner)
+	defer p.Stop()
+
+	done, err := p.CreateJob(&api.Job{Type: api.Job_DEFRAG})
+	require.NoError(t, err)
+	p.schedule()
+	waitForState(t, p, done.GetId(), api.Job_DONE)
+
+	// A pending job is kept
+	pending, err := p.CreateJob(&api.Job{Type: api.Job_COLLECT_DIAGS})
+	require.NoError(t, err)
+
+	// The finished job is kept during the retention period
+	p.schedule()
+	jobStatus(t, p, done.GetId())
+
+	p.retention = time.Millisecond
+	time.Sleep(10 * time.Millisecond)
+	p.schedule()
+	_, err = p.GetJobStatus(context.Background(), &api.SdkGetJobStatusRequest{Id: done.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	jobStatus(t, p, pending.GetId())
+}
+
+func TestKvdbProviderUpdateJob(t *testing.T) {
+	runner := newTestRunner()
+	p := newTestProvider(t, newTestKvdb(t), "node1", runner)
+	defer p.Stop()
+
+	job, err := p.CreateJob(&api.Job{
+		Type: api.Job_DEFRAG,
+		Job: &api.Job_Defrag{
+			Defrag: &api.DefragJob{MaxNodesInParallel: 1},
+		},
+	})
+	require.NoError(t, err)
+
+	// The runner changes the type specific fields of the job
+	ok, err := p.acquireLease(job.GetId())
+	require.NoError(t, err)
+	require.True(t, ok)
+	rep := &reporter{p: p, id: job.GetId(), cancel: func() {}, started: time.Now(), summary: &api.JobSummary{}}
+	require.NoError(t, rep.UpdateJob(func(job *api.Job) {
+		job.GetDefrag().MaxNodesInParallel = 3
+	}))
+	assert.Equal(t, uint32(3), jobStatus(t, p, job.GetId()).GetJob().GetDefrag().GetMaxNodesInParallel())
+}
@@ -0,0 +1,257 @@
+package nodedrain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/job"
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/libopenstorage/openstorage/pkg/parser"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+// AttachmentsCordonStore saves the cordon of the volume attachments of the
+// nodes, such as on the node entries of the cluster database
+type AttachmentsCordonStore interface {
+	AttachmentsCordon
+	// SetAttachmentsCordoned disables or re-enables new volume attachments
+	// on the node
+	SetAttachmentsCordoned(nodeID string, cordoned bool) error
+}
+
+// AttachmentsProvider is a Provider which saves the cordoned nodes in a
+// cordon store and drains the volume attachments of a node with a
+// DRAIN_ATTACHMENTS job run on that node
+type AttachmentsProvider struct {
+	cordons AttachmentsCordonStore
+	driver  volume.VolumeDriver
+	jobs    *job.KvdbProvider
+}
+
+// Check interfaces
+var _ Provider = &AttachmentsProvider{}
+var _ AttachmentsCordon = &AttachmentsProvider{}
+var _ job.NodeRunner = &AttachmentsProvider{}
+
+// NewAttachmentsProvider returns a node drain provider which detaches the
+// volumes of the driver. It registers the runner of the drain jobs with the
+// job provider.
+func NewAttachmentsProvider(
+	cordons AttachmentsCordonStore,
+	driver volume.VolumeDriver,
+	jobs *job.KvdbProvider,
+) (*AttachmentsProvider, error) {
+	if cordons == nil {
+		return nil, fmt.Errorf("Must supply a cordon store for the node drain provider")
+	} else if driver == nil {
+		return nil, fmt.Errorf("Must supply a volume driver for the node drain provider")
+	} else if jobs == nil {
+		return nil, fmt.Errorf("Must supply a job provider for the node drain provider")
+	}
+
+	p := &AttachmentsProvider{
+		cordons: cordons,
+		driver:  driver,
+		jobs:    jobs,
+	}
+	if err := jobs.RegisterRunner(api.Job_DRAIN_ATTACHMENTS, p); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// AttachmentsCordoned returns true if new volume attachments are disabled on
+// the node
+func (p *AttachmentsProvider) AttachmentsCordoned(nodeID string) (bool, error) {
+	return p.cordons.AttachmentsCordoned(nodeID)
+}
+
+// DrainAttachments cordons the node and creates a job which detaches the
+// selected volumes from the node
+func (p *AttachmentsProvider) DrainAttachments(
+	ctx context.Context,
+	in *api.SdkNodeDrainAttachmentsRequest,
+) (*api.SdkJobResponse, error) {
+	if len(in.GetNodeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a node id")
+	}
+
+	// Volumes must not be attached to the node again while it is drained
+	if _, err := p.CordonAttachments(ctx, &api.SdkNodeCordonAttachmentsRequest{
+		NodeId: in.GetNodeId(),
+	}); err != nil {
+		return nil, err
+	}
+
+	now := prototime.Now()
+	j, err := p.jobs.CreateJob(&api.Job{
+		Type: api.Job_DRAIN_ATTACHMENTS,
+		Job: &api.Job_DrainAttachments{
+			DrainAttachments: &api.NodeDrainAttachmentsJob{
+				NodeId:         in.GetNodeId(),
+				Issuer:         in.GetIssuer(),
+				Parameters:     in,
+				CreateTime:     now,
+				LastUpdateTime: now,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SdkJobResponse{
+		Job: j,
+	}, nil
+}
+
+// CordonAttachments disables new volume attachments on the node
+func (p *AttachmentsProvider) CordonAttachments(
+	ctx context.Context,
+	in *api.SdkNodeCordonAttachmentsRequest,
+) (*api.SdkNodeCordonAttachmentsResponse, error) {
+	if len(in.GetNodeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a node id")
+	}
+
+	if err := p.cordons.SetAttachmentsCordoned(in.GetNodeId(), true); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to cordon attachments on node %s: %v", in.GetNodeId(), err)
+	}
+
+	logrus.Infof("Volume attachments are cordoned on node %s", in.GetNodeId())
+	return &api.SdkNodeCordonAttachmentsResponse{}, nil
+}
+
+// UncordonAttachments re-enables volume attachments on the node
+func (p *AttachmentsProvider) UncordonAttachments(
+	ctx context.Context,
+	in *api.SdkNodeUncordonAttachmentsRequest,
+) (*api.SdkNodeUncordonAttachmentsResponse, error) {
+	if len(in.GetNodeId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a node id")
+	}
+
+	if err := p.cordons.SetAttachmentsCordoned(in.GetNodeId(), false); err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to uncordon attachments on node %s: %v", in.GetNodeId(), err)
+	}
+
+	logrus.Infof("Volume attachments are uncordoned on node %s", in.GetNodeId())
+	return &api.SdkNodeUncordonAttachmentsResponse{}, nil
+}
+
+// volumesToDrain returns the volumes attached to the node which are selected
+// by the drain request. It fails if the driver does not report the node its
+// volumes are attached on, rather than leaving them attached to the node.
+func (p *AttachmentsProvider) volumesToDrain(in *api.SdkNodeDrainAttachmentsRequest) ([]*api.Volume, error) {
+	vols, err := p.driver.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	drain := make([]*api.Volume, 0, len(vols))
+	for _, v := range vols {
+		if len(v.GetAttachedOn()) == 0 &&
+			(v.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED || len(v.GetAttachPath()) != 0) {
+			return nil, fmt.Errorf("volume driver %s does not report the node volume %s is attached on",
+				p.driver.Name(), v.GetId())
+		}
+		if v.GetAttachedOn() != in.GetNodeId() || !v.IsAttached() {
+			continue
+		}
+		if in.GetOnlySharedv4() && !v.GetSpec().GetSharedv4() {
+			continue
+		}
+		if !parser.MatchLabelSelector(v.GetLocator().GetVolumeLabels(), in.GetSelector()) {
+			continue
+		}
+		drain = append(drain, v)
+	}
+	sort.Slice(drain, func(i, j int) bool {
+		return drain[i].GetId() < drain[j].GetId()
+	})
+	return drain, nil
+}
+
+// RunsOn returns the node drained by a DRAIN_ATTACHMENTS job, since the
+// volumes are unmounted and detached on that node
+func (p *AttachmentsProvider) RunsOn(j *api.Job) string {
+	return j.GetDrainAttachments().GetParameters().GetNodeId()
+}
+
+// drain unmounts the volume from all its mount paths and detaches it from the
+// node
+func (p *AttachmentsProvider) drain(ctx context.Context, v *api.Volume) error {
+	for _, path := range v.GetAttachPath() {
+		if err := p.driver.Unmount(ctx, v.GetId(), path, nil); err != nil {
+			return fmt.Errorf("failed to unmount %s: %v", path, err)
+		}
+	}
+	return p.driver.Detach(ctx, v.GetId(), map[string]string{
+		options.OptionsRedirectDetach: "true",
+	})
+}
+
+// Run unmounts and detaches the volumes of a DRAIN_ATTACHMENTS job on the
+// drained node. Each volume is a task of the job. The volumes detached by the
+// previous runs are no longer attached to the node, so a resumed job only
+// drains the remaining volumes. The detach is redirected to the node, so
+// sharedv4 volumes can be moved to another node by the driver.
+func (p *AttachmentsProvider) Run(ctx context.Context, j *api.Job, reporter job.Reporter) error {
+	in := j.GetDrainAttachments().GetParameters()
+	if len(in.GetNodeId()) == 0 {
+		return fmt.Errorf("job %s has no node to drain", j.GetId())
+	}
+
+	vols, err := p.volumesToDrain(in)
+	if err != nil {
+		return fmt.Errorf("failed to get the volumes attached to node %s: %v", in.GetNodeId(), err)
+	}
+
+	attached := make(map[string]bool, len(vols))
+	for _, v := range vols {
+		attached[v.GetId()] = true
+	}
+	drained := 0
+	for _, t := range reporter.Summary().GetTasks() {
+		if t.GetState() == api.Job_DONE && !attached[t.GetId()] {
+			drained++
+		}
+	}
+	total := drained + len(vols)
+
+	failed := 0
+	for _, v := range vols {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		task := &api.JobTask{Id: v.GetId(), State: api.Job_DONE, Status: "Detached"}
+		if err := p.drain(ctx, v); err != nil {
+			logrus.Warnf("Failed to drain volume %s from node %s: %v", v.GetId(), in.GetNodeId(), err)
+			task.State = api.Job_FAILED
+			task.Status = err.Error()
+			failed++
+		} else {
+			drained++
+		}
+		if err := reporter.SetTask(task); err != nil {
+			return err
+		}
+		if err := reporter.SetProgress(uint32(drained*100/total),
+			fmt.Sprintf("Drained %d of %d volumes from node %s", drained, total, in.GetNodeId())); err != nil {
+			return err
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("failed to drain %d volumes from node %s", failed, in.GetNodeId())
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package nodedrain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/job"
+	"github.com/libopenstorage/openstorage/pkg/job/jobtest"
+	"github.com/libopenstorage/openstorage/pkg/options"
+	"github.com/libopenstorage/openstorage/volume/drivers/mock"
+)
+
+// testCordonStore keeps the cordoned nodes in memory
+type testCordonStore map[string]bool
+
+func (s testCordonStore) AttachmentsCordoned(nodeID string) (bool, error) {
+	return s[nodeID], nil
+}
+
+func (s testCordonStore) SetAttachmentsCordoned(nodeID string, cordoned bool) error {
+	s[nodeID] = cordoned
+	return nil
+}
+
+func newTestProvider(t *testing.T) (*AttachmentsProvider, *mock.MockVolumeDriver, *gomock.Controller) {
+	kv, err := kvdb.New(mem.Name, "nodedrain_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	jobs, err := job.NewKvdbProvider(kv, "node1")
+	require.NoError(t, err)
+
+	ctrl := gomock.NewController(t)
+	driver := mock.NewMockVolumeDriver(ctrl)
+	p, err := NewAttachmentsProvider(testCordonStore{}, driver, jobs)
+	require.NoError(t, err)
+	return p, driver, ctrl
+}
+
+func attachedVolume(id, node string, sharedv4 bool, labels map[string]string) *api.Volume {
+	return &api.Volume{
+		Id:            id,
+		AttachedOn:    node,
+		AttachPath:    []string{"/mnt/" + id},
+		State:         api.VolumeState_VOLUME_STATE_ATTACHED,
+		AttachedState: api.AttachState_ATTACH_STATE_EXTERNAL,
+		Locator:       &api.VolumeLocator{VolumeLabels: labels},
+		Spec:          &api.VolumeSpec{Sharedv4: sharedv4},
+	}
+}
+
+func TestCordonAttachments(t *testing.T) {
+	p, _, ctrl := newTestProvider(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	_, err := p.CordonAttachments(ctx, &api.SdkNodeCordonAttachmentsRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	cordoned, err := p.AttachmentsCordoned("node1")
+	require.NoError(t, err)
+	assert.False(t, cordoned)
+
+	_, err = p.CordonAttachments(ctx, &api.SdkNodeCordonAttachmentsRequest{NodeId: "node1"})
+	require.NoError(t, err)
+	cordoned, err = p.AttachmentsCordoned("node1")
+	require.NoError(t, err)
+	assert.True(t, cordoned)
+	cordoned, err = p.AttachmentsCordoned("node2")
+	require.NoError(t, err)
+	assert.False(t, cordoned)
+
+	_, err = p.UncordonAttachments(ctx, &api.SdkNodeUncordonAttachmentsRequest{NodeId: "node1"})
+	require.NoError(t, err)
+	cordoned, err = p.AttachmentsCordoned("node1")
+	require.NoError(t, err)
+	assert.False(t, cordoned)
+
+	// Uncordon is idempotent
+	_, err = p.UncordonAttachments(ctx, &api.SdkNodeUncordonAttachmentsRequest{NodeId: "node1"})
+	assert.NoError(t, err)
+}
+
+func TestDrainAttachments(t *testing.T) {
+	p, driver, ctrl := newTestProvider(t)
+	defer ctrl.Finish()
+	ctx := context.Background()
+
+	req := &api.SdkNodeDrainAttachmentsRequest{
+		NodeId: "node1",
+		Issuer: "admin",
+		Selector: []*api.LabelSelectorRequirement{
+			{Key: "app", Operator: api.LabelSelectorRequirement_In, Values: []string{"db"}},
+		},
+	}
+	resp, err := p.DrainAttachments(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, api.Job_DRAIN_ATTACHMENTS, resp.GetJob().GetType())
+	assert.Equal(t, api.Job_PENDING, resp.GetJob().GetState())
+	assert.Equal(t, "node1", resp.GetJob().GetDrainAttachments().GetNodeId())
+	assert.Equal(t, "admin", resp.GetJob().GetDrainAttachments().GetIssuer())
+	assert.Equal(t, "node1", p.RunsOn(resp.GetJob()))
+
+	// The node is cordoned
+	cordoned, err := p.AttachmentsCordoned("node1")
+	require.NoError(t, err)
+	assert.True(t, cordoned)
+
+	db := map[string]string{"app": "db"}
+	driver.EXPECT().
+		Enumerate(&api.VolumeLocator{}, nil).
+		Return([]*api.Volume{
+			attachedVolume("vol1", "node1", false, db),
+			attachedVolume("vol2", "node1", false, map[string]string{"app": "web"}),
+			attachedVolume("vol3", "node2", false, db),
+			attachedVolume("vol4", "node1", true, db),
+		}, nil)
+	redirect := map[string]string{options.OptionsRedirectDetach: "true"}
+	gomock.InOrder(
+		driver.EXPECT().Unmount(gomock.Any(), "vol1", "/mnt/vol1", nil).Return(nil),
+		driver.EXPECT().Detach(gomock.Any(), "vol1", redirect).Return(nil),
+	)
+	driver.EXPECT().Unmount(gomock.Any(), "vol4", "/mnt/vol4", nil).Return(fmt.Errorf("busy"))
+
+	reporter := jobtest.NewReporter(nil)
+	err = p.Run(ctx, resp.GetJob(), reporter)
+	assert.Error(t, err)
+	require.Len(t, reporter.JobSummary.GetTasks(), 2)
+	assert.Equal(t, api.Job_DONE, reporter.JobSummary.GetTasks()[0].GetState())
+	assert.Equal(t, api.Job_FAILED, reporter.JobSummary.GetTasks()[1].GetState())
+	assert.Equal(t, uint32(50), reporter.JobSummary.GetProgressPercent())
+
+	// The job is resumed with the volume which is still attached
+	driver.EXPECT().
+		Enumerate(&api.VolumeLocator{}, nil).
+		Return([]*api.Volume{attachedVolume("vol4", "node1", true, db)}, nil)
+	gomock.InOrder(
+		driver.EXPECT().Unmount(gomock.Any(), "vol4", "/mnt/vol4", nil).Return(nil),
+		driver.EXPECT().Detach(gomock.Any(), "vol4", redirect).Return(nil),
+	)
+
+	err = p.Run(ctx, resp.GetJob(), reporter)
+	require.NoError(t, err)
+	require.Len(t, reporter.JobSummary.GetTasks(), 2)
+	assert.Equal(t, api.Job_DONE, reporter.JobSummary.GetTasks()[1].GetState())
+	assert.Equal(t, uint32(100), reporter.JobSummary.GetProgressPercent())
+}
+
+func TestDrainAttachmentsOnlySharedv4(t *testing.T) {
+	p, driver, ctrl := newTestProvider(t)
+	defer ctrl.Finish()
+
+	j := &api.Job{
+		Type: api.Job_DRAIN_ATTACHMENTS,
+		Job: &api.Job_DrainAttachments{
+			DrainAttachments: &api.NodeDrainAttachmentsJob{
+				Parameters: &api.SdkNodeDrainAttachmentsRequest{
+					NodeId:       "node1",
+					OnlySharedv4: true,
+				},
+			},
+		},
+	}
+	driver.EXPECT().
+		Enumerate(&api.VolumeLocator{}, nil).
+		Return([]*api.Volume{
+			attachedVolume("vol1", "node1", false, nil),
+			attachedVolume("vol2", "node1", true, nil),
+		}, nil)
+	driver.EXPECT().Unmount(gomock.Any(), "vol2", "/mnt/vol2", nil).Return(nil)
+	driver.EXPECT().Detach(gomock.Any(), "vol2", gomock.Any()).Return(nil)
+
+	reporter := jobtest.NewReporter(nil)
+	require.NoError(t, p.Run(context.Background(), j, reporter))
+	require.Len(t, reporter.JobSummary.GetTasks(), 1)
+	assert.Equal(t, "vol2", reporter.JobSummary.GetTasks()[0].GetId())
+}
+
+func TestDrainAttachmentsUnknownNode(t *testing.T) {
+	p, driver, ctrl := newTestProvider(t)
+	defer ctrl.Finish()
+
+	j := &api.Job{
+		Type: api.Job_DRAIN_ATTACHMENTS,
+		Job: &api.Job_DrainAttachments{
+			DrainAttachments: &api.NodeDrainAttachmentsJob{
+				Parameters: &api.SdkNodeDrainAttachmentsRequest{NodeId: "node1"},
+			},
+		},
+	}
+
+	// The job fails when the driver does not report the node of the
+	// attached volumes, rather than leaving them attached
+	driver.EXPECT().
+		Enumerate(&api.VolumeLocator{}, nil).
+		Return([]*api.Volume{attachedVolume("vol1", "", false, nil)}, nil)
+	driver.EXPECT().Name().Return("loop")
+
+	reporter := jobtest.NewReporter(nil)
+	err := p.Run(context.Background(), j, reporter)
+	assert.Error(t, err)
+	assert.Empty(t, reporter.JobSummary.GetTasks())
+}
@@ -22,6 +22,13 @@ type Provider interface {
 	UncordonAttachments(ctx context.Context, in *api.SdkNodeUncordonAttachmentsRequest) (*api.SdkNodeUncordonAttachmentsResponse, error)
 }
 
+// AttachmentsCordon tells if new volume attachments are disabled on a node
+type AttachmentsCordon interface {
+	// AttachmentsCordoned returns true if new volume attachments are
+	// disabled on the node
+	AttachmentsCordoned(nodeID string) (bool, error)
+}
+
 // NewDefaultNodeDrainProvider does not any node drain related operations
 func NewDefaultNodeDrainProvider() Provider {
 	return &UnsupportedNodeDrainProvider{}
@@ -0,0 +1,208 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	// Characters which cannot be part of a label key or value in a
+	// label selector
+	selectorReservedChars = `\s!=<>(),`
+)
+
+var (
+	selectorKeyRegex = regexp.MustCompile(`^[^` + selectorReservedChars + `]+$`)
+	selectorSetRegex = regexp.MustCompile(
+		`^([^` + selectorReservedChars + `]+)\s+(in|notin)\s*\(([^()]*)\)$`)
+	selectorOpRegex = regexp.MustCompile(
+		`^([^` + selectorReservedChars + `]+)\s*(==|!=|=|>|<)\s*([^` + selectorReservedChars + `]*)$`)
+)
+
+// ParseLabelSelector parses a Kubernetes style label selector like
+// "env in (prod,qa),tier notin (web),app,!legacy,zone!=east,replicas>2"
+// into label selector requirements. The requirements are separated by
+// commas and must all match. The supported requirements are:
+//
+//	key               key exists
+//	!key              key does not exist
+//	key=value         key has the value, also key==value
+//	key!=value        key does not exist or does not have the value
+//	key in (v1,v2)    key has one of the values
+//	key notin (v1,v2) key does not exist or has none of the values
+//	key>value         key has an integer value greater than value
+//	key<value         key has an integer value less than value
+func ParseLabelSelector(selector string) ([]*api.LabelSelectorRequirement, error) {
+	if len(strings.TrimSpace(selector)) == 0 {
+		return nil, nil
+	}
+
+	terms, err := splitLabelSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	reqs := make([]*api.LabelSelectorRequirement, 0, len(terms))
+	for _, term := range terms {
+		req, err := parseLabelSelectorRequirement(strings.TrimSpace(term))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid label selector %q: %v", selector, err)
+		}
+		reqs = append(reqs, req)
+	}
+	return reqs, nil
+}
+
+// splitLabelSelector splits a selector on the commas which are not part of
+// a set of values
+func splitLabelSelector(selector string) ([]string, error) {
+	terms := make([]string, 0)
+	depth := 0
+	start := 0
+	for i, c := range selector {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+		if depth < 0 || depth > 1 {
+			return nil, fmt.Errorf("Invalid label selector %q: unbalanced parentheses", selector)
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("Invalid label selector %q: unbalanced parentheses", selector)
+	}
+	return append(terms, selector[start:]), nil
+}
+
+func parseLabelSelectorRequirement(term string) (*api.LabelSelectorRequirement, error) {
+	if len(term) == 0 {
+		return nil, fmt.Errorf("empty requirement")
+	}
+
+	// !key
+	if strings.HasPrefix(term, "!") {
+		key := strings.TrimSpace(term[1:])
+		if !selectorKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid key in %q", term)
+		}
+		return &api.LabelSelectorRequirement{
+			Key:      key,
+			Operator: api.LabelSelectorRequirement_DoesNotExist,
+		}, nil
+	}
+
+	// key in (values), key notin (values)
+	if m := selectorSetRegex.FindStringSubmatch(term); m != nil {
+		values := make([]string, 0)
+		for _, v := range strings.Split(m[3], ",") {
+			v = strings.TrimSpace(v)
+			if !selectorKeyRegex.MatchString(v) {
+				return nil, fmt.Errorf("invalid value %q in %q", v, term)
+			}
+			values = append(values, v)
+		}
+		op := api.LabelSelectorRequirement_In
+		if m[2] == "notin" {
+			op = api.LabelSelectorRequirement_NotIn
+		}
+		return &api.LabelSelectorRequirement{
+			Key:      m[1],
+			Operator: op,
+			Values:   values,
+		}, nil
+	}
+
+	// key=value, key==value, key!=value, key>value, key<value
+	if m := selectorOpRegex.FindStringSubmatch(term); m != nil {
+		req := &api.LabelSelectorRequirement{
+			Key:    m[1],
+			Values: []string{m[3]},
+		}
+		switch m[2] {
+		case "=", "==":
+			req.Operator = api.LabelSelectorRequirement_In
+		case "!=":
+			req.Operator = api.LabelSelectorRequirement_NotIn
+		case ">", "<":
+			if _, err := strconv.ParseInt(m[3], 10, 64); err != nil {
+				return nil, fmt.Errorf("value of %q must be an integer", term)
+			}
+			req.Operator = api.LabelSelectorRequirement_Gt
+			if m[2] == "<" {
+				req.Operator = api.LabelSelectorRequirement_Lt
+			}
+		}
+		return req, nil
+	}
+
+	// key
+	if selectorKeyRegex.MatchString(term) {
+		return &api.LabelSelectorRequirement{
+			Key:      term,
+			Operator: api.LabelSelectorRequirement_Exists,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unable to parse %q", term)
+}
+
+// MatchLabelSelector returns true if the labels match all the label
+// selector requirements
+func MatchLabelSelector(labels map[string]string, reqs []*api.LabelSelectorRequirement) bool {
+	for _, req := range reqs {
+		if !matchLabelSelectorRequirement(labels, req) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchLabelSelectorRequirement(labels map[string]string, req *api.LabelSelectorRequirement) bool {
+	value, exists := labels[req.GetKey()]
+	switch req.GetOperator() {
+	case api.LabelSelectorRequirement_In:
+		return exists && listContains(req.GetValues(), value)
+	case api.LabelSelectorRequirement_NotIn:
+		return !exists || !listContains(req.GetValues(), value)
+	case api.LabelSelectorRequirement_Exists:
+		return exists
+	case api.LabelSelectorRequirement_DoesNotExist:
+		return !exists
+	case api.LabelSelectorRequirement_Gt, api.LabelSelectorRequirement_Lt:
+		if !exists || len(req.GetValues()) != 1 {
+			return false
+		}
+		labelValue, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return false
+		}
+		reqValue, err := strconv.ParseInt(req.GetValues()[0], 10, 64)
+		if err != nil {
+			return false
+		}
+		if req.GetOperator() == api.LabelSelectorRequirement_Gt {
+			return labelValue > reqValue
+		}
+		return labelValue < reqValue
+	}
+	return false
+}
+
+func listContains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	reqs, err := ParseLabelSelector("")
+	require.NoError(t, err)
+	require.Empty(t, reqs)
+
+	reqs, err = ParseLabelSelector(
+		"env in (prod, qa),tier notin (web),app, !legacy,zone!=east,team==storage,owner=,replicas>2,size<10")
+	require.NoError(t, err)
+	require.Equal(t, []*api.LabelSelectorRequirement{
+		{Key: "env", Operator: api.LabelSelectorRequirement_In, Values: []string{"prod", "qa"}},
+		{Key: "tier", Operator: api.LabelSelectorRequirement_NotIn, Values: []string{"web"}},
+		{Key: "app", Operator: api.LabelSelectorRequirement_Exists},
+		{Key: "legacy", Operator: api.LabelSelectorRequirement_DoesNotExist},
+		{Key: "zone", Operator: api.LabelSelectorRequirement_NotIn, Values: []string{"east"}},
+		{Key: "team", Operator: api.LabelSelectorRequirement_In, Values: []string{"storage"}},
+		{Key: "owner", Operator: api.LabelSelectorRequirement_In, Values: []string{""}},
+		{Key: "replicas", Operator: api.LabelSelectorRequirement_Gt, Values: []string{"2"}},
+		{Key: "size", Operator: api.LabelSelectorRequirement_Lt, Values: []string{"10"}},
+	}, reqs)
+
+	for _, bad := range []string{
+		"env in (prod",
+		"env in prod)",
+		"env in ()",
+		"env in (a,,b)",
+		"a,,b",
+		"!",
+		"key=a=b",
+		"replicas>two",
+		"two words",
+	} {
+		_, err := ParseLabelSelector(bad)
+		require.Error(t, err, bad)
+	}
+}
+
+func TestMatchLabelSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "app": "db", "replicas": "3"}
+
+	tests := []struct {
+		selector string
+		match    bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=qa", false},
+		{"env in (qa,prod)", true},
+		{"env notin (qa,prod)", false},
+		{"tier notin (web)", true},
+		{"tier!=web", true},
+		{"env!=prod", false},
+		{"app", true},
+		{"tier", false},
+		{"!tier", true},
+		{"!app", false},
+		{"replicas>2", true},
+		{"replicas<3", false},
+		{"env>1", false},
+		{"env=prod,app=db,!tier", true},
+		{"env=prod,app=web", false},
+	}
+	for _, test := range tests {
+		reqs, err := ParseLabelSelector(test.selector)
+		require.NoError(t, err, test.selector)
+		require.Equal(t, test.match, MatchLabelSelector(labels, reqs), test.selector)
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Package quota manages the quotas of the users and groups
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package quota
+
+import (
+	"github.com/libopenstorage/openstorage/api"
+)
+
+// Manager provides an implementation of the SDK quota service and keeps
+// track of the resources used by the users and groups
+type Manager interface {
+	api.OpenStorageQuotaServer
+
+	// Charge moves the usage of a resource from fromUsage, charged to the
+	// owner and groups of from, to toUsage, charged to the owner and groups
+	// of to. Use nil usages for a resource which is created or deleted.
+	// If the usage of a user or group would grow past its quota, Charge
+	// returns a ResourceExhausted error without changing any usage.
+	Charge(
+		from *api.Ownership,
+		fromUsage *api.SdkQuotaUsage,
+		to *api.Ownership,
+		toUsage *api.SdkQuotaUsage,
+	) error
+
+	// Recompute replaces the usage of all the users and groups with the
+	// usage of the volumes returned by enumerate. It is run by only one
+	// node at a time and keeps the charges made while it runs.
+	Recompute(enumerate func() ([]*api.Volume, error)) error
+}
+
+// VolumeUsage returns the resources used by a volume
+func VolumeUsage(v *api.Volume) *api.SdkQuotaUsage {
+	if v.IsSnapshot() {
+		return &api.SdkQuotaUsage{
+			Snapshots: 1,
+		}
+	}
+	return &api.SdkQuotaUsage{
+		Volumes:          1,
+		ProvisionedBytes: v.GetSpec().GetSize(),
+	}
+}
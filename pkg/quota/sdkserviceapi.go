@@ -0,0 +1,456 @@
+/*
+Package quota manages the quotas of the users and groups
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	quotaPrefix  = "cluster/quotas"
+	usersPath    = "users"
+	groupsPath   = "groups"
+	recomputeKey = "cluster/quotas_recompute"
+
+	// anyGroup is the group of the ownership acls which gives access to
+	// all the groups. It does not have a quota.
+	anyGroup = "*"
+)
+
+// RecomputeInterval is how long the usage of the quotas recomputed by a node
+// is not recomputed again by the other nodes
+var RecomputeInterval = 5 * time.Minute
+
+// record is the value saved in kvdb for each user and group. The usage is
+// kept even if there is no quota, so a new quota starts with the right
+// usage.
+type record struct {
+	Quota *api.SdkQuota      `json:"quota,omitempty"`
+	Usage *api.SdkQuotaUsage `json:"usage,omitempty"`
+}
+
+// delta is a change of usage
+type delta struct {
+	volumes          int64
+	provisionedBytes int64
+	snapshots        int64
+}
+
+func (d *delta) add(u *api.SdkQuotaUsage, sign int64) {
+	d.volumes += sign * int64(u.GetVolumes())
+	d.provisionedBytes += sign * int64(u.GetProvisionedBytes())
+	d.snapshots += sign * int64(u.GetSnapshots())
+}
+
+func (d *delta) isZero() bool {
+	return d.volumes == 0 && d.provisionedBytes == 0 && d.snapshots == 0
+}
+
+func (d *delta) negate() *delta {
+	return &delta{
+		volumes:          -d.volumes,
+		provisionedBytes: -d.provisionedBytes,
+		snapshots:        -d.snapshots,
+	}
+}
+
+// apply adds d to a usage, without going below zero
+func apply(usage uint64, d int64) uint64 {
+	if d < 0 && uint64(-d) > usage {
+		return 0
+	}
+	return uint64(int64(usage) + d)
+}
+
+// SdkQuotaManager is an implementation of the quota Manager which saves
+// the quotas and the usage of the users and groups in Kvdb
+type SdkQuotaManager struct {
+	kv kvdb.Kvdb
+}
+
+// Check interface
+var _ Manager = &SdkQuotaManager{}
+
+// NewSdkQuotaManager returns a new SDK quota manager
+func NewSdkQuotaManager(kv kvdb.Kvdb) (*SdkQuotaManager, error) {
+	if kv == nil {
+		return nil, fmt.Errorf("Must supply a kvdb instance for the quota manager")
+	}
+	return &SdkQuotaManager{
+		kv: kv,
+	}, nil
+}
+
+// Simple function which creates the key for Kvdb
+func quotaKey(ownerType api.SdkQuota_OwnerType, name string) string {
+	path := usersPath
+	if ownerType == api.SdkQuota_GROUP {
+		path = groupsPath
+	}
+	return quotaPrefix + "/" + path + "/" + url.PathEscape(name)
+}
+
+// subject returns a description of the user or group of a key for the
+// error messages
+func subject(key string) string {
+	parts := strings.SplitN(strings.TrimPrefix(key, quotaPrefix+"/"), "/", 2)
+	name, _ := url.PathUnescape(parts[1])
+	if parts[0] == groupsPath {
+		return "group " + name
+	}
+	return "user " + name
+}
+
+func validateOwner(ownerType api.SdkQuota_OwnerType, name string) error {
+	if _, ok := api.SdkQuota_OwnerType_name[int32(ownerType)]; !ok {
+		return status.Errorf(codes.InvalidArgument, "Unknown owner type %v", ownerType)
+	} else if len(name) == 0 {
+		return status.Error(codes.InvalidArgument, "Must supply the name of the user or group")
+	} else if ownerType == api.SdkQuota_GROUP && name == anyGroup {
+		return status.Errorf(codes.InvalidArgument, "Group %s cannot have a quota", anyGroup)
+	}
+	return nil
+}
+
+// update applies fn to the record at key, creating it if needed. It retries
+// if the record is changed by someone else at the same time.
+func (m *SdkQuotaManager) update(key string, fn func(r *record) error) error {
+	for {
+		r := &record{}
+		kvp, err := m.kv.GetVal(key, r)
+		if err == kvdb.ErrNotFound {
+			if err := fn(r); err != nil {
+				return err
+			}
+			_, err = m.kv.Create(key, r, 0)
+			if err == kvdb.ErrExist {
+				continue
+			}
+			return err
+		} else if err != nil {
+			return err
+		}
+
+		if err := fn(r); err != nil {
+			return err
+		}
+		value, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		prevValue := kvp.Value
+		newKvp := *kvp
+		newKvp.Value = value
+		_, err = m.kv.CompareAndSet(&newKvp, kvdb.KVFlags(0), prevValue)
+		if err == kvdb.ErrValueMismatch || err == kvdb.ErrModified {
+			continue
+		}
+		return err
+	}
+}
+
+// addDelta adds a change of usage to the record at key. If check is set
+// and the change makes the usage grow past the quota, it returns a
+// ResourceExhausted error.
+func (m *SdkQuotaManager) addDelta(key string, d *delta, check bool) error {
+	return m.update(key, func(r *record) error {
+		usage := r.Usage
+		if usage == nil {
+			usage = &api.SdkQuotaUsage{}
+		}
+		next := &api.SdkQuotaUsage{
+			Volumes:          apply(usage.GetVolumes(), d.volumes),
+			ProvisionedBytes: apply(usage.GetProvisionedBytes(), d.provisionedBytes),
+			Snapshots:        apply(usage.GetSnapshots(), d.snapshots),
+		}
+
+		if check && r.Quota != nil {
+			q := r.Quota
+			if d.volumes > 0 && q.GetMaxVolumes() != 0 && next.GetVolumes() > q.GetMaxVolumes() {
+				return status.Errorf(codes.ResourceExhausted,
+					"Quota of %s exceeded: %d of %d volumes used",
+					subject(key), usage.GetVolumes(), q.GetMaxVolumes())
+			}
+			if d.provisionedBytes > 0 && q.GetMaxProvisionedBytes() != 0 &&
+				next.GetProvisionedBytes() > q.GetMaxProvisionedBytes() {
+				return status.Errorf(codes.ResourceExhausted,
+					"Quota of %s exceeded: %d more bytes requested with %d of %d bytes provisioned",
+					subject(key), d.provisionedBytes, usage.GetProvisionedBytes(), q.GetMaxProvisionedBytes())
+			}
+			if d.snapshots > 0 && q.GetMaxSnapshots() != 0 && next.GetSnapshots() > q.GetMaxSnapshots() {
+				return status.Errorf(codes.ResourceExhausted,
+					"Quota of %s exceeded: %d of %d snapshots used",
+					subject(key), usage.GetSnapshots(), q.GetMaxSnapshots())
+			}
+		}
+
+		r.Usage = next
+		return nil
+	})
+}
+
+// ownershipKeys returns the keys of the owner and groups of an ownership
+func ownershipKeys(o *api.Ownership) []string {
+	keys := make([]string, 0)
+	if o == nil {
+		return keys
+	}
+	if len(o.GetOwner()) != 0 {
+		keys = append(keys, quotaKey(api.SdkQuota_USER, o.GetOwner()))
+	}
+	for group := range o.GetGroups() {
+		if group != anyGroup {
+			keys = append(keys, quotaKey(api.SdkQuota_GROUP, group))
+		}
+	}
+	return keys
+}
+
+// Charge moves the usage of a resource between ownerships
+func (m *SdkQuotaManager) Charge(
+	from *api.Ownership,
+	fromUsage *api.SdkQuotaUsage,
+	to *api.Ownership,
+	toUsage *api.SdkQuotaUsage,
+) error {
+	deltas := make(map[string]*delta)
+	keys := make([]string, 0)
+	addUsage := func(o *api.Ownership, u *api.SdkQuotaUsage, sign int64) {
+		if u == nil {
+			return
+		}
+		for _, key := range ownershipKeys(o) {
+			d, ok := deltas[key]
+			if !ok {
+				d = &delta{}
+				deltas[key] = d
+				keys = append(keys, key)
+			}
+			d.add(u, sign)
+		}
+	}
+	addUsage(from, fromUsage, -1)
+	addUsage(to, toUsage, 1)
+
+	applied := make([]string, 0, len(keys))
+	for _, key := range keys {
+		d := deltas[key]
+		if d.isZero() {
+			continue
+		}
+		if err := m.addDelta(key, d, true); err != nil {
+			// Revert the usage already changed
+			for _, prev := range applied {
+				if rerr := m.addDelta(prev, deltas[prev].negate(), false); rerr != nil {
+					logrus.Warnf("Failed to revert usage of %s: %v", subject(prev), rerr)
+				}
+			}
+			if _, ok := status.FromError(err); !ok {
+				err = status.Errorf(codes.Internal, "Failed to update usage of %s: %v", subject(key), err)
+			}
+			return err
+		}
+		applied = append(applied, key)
+	}
+
+	return nil
+}
+
+// Recompute replaces the usage of all the users and groups with the usage
+// of the volumes. Only one node recomputes the usage within
+// RecomputeInterval, the others return without doing anything. The charges
+// made while the volumes are enumerated are kept.
+func (m *SdkQuotaManager) Recompute(enumerate func() ([]*api.Volume, error)) error {
+	// Take the lease of the recompute. It is not released, so the nodes
+	// starting at the same time do not recompute the usage again.
+	_, err := m.kv.Create(recomputeKey, time.Now().String(), uint64(RecomputeInterval.Seconds()))
+	if err == kvdb.ErrExist {
+		logrus.Infof("Usage of the quotas recomputed by another node, skipping")
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("Failed to take the lease of the recompute: %v", err)
+	}
+
+	// Keep the usage before the volumes are enumerated, to find the charges
+	// made since
+	kvps, err := m.kv.Enumerate(quotaPrefix + "/")
+	if err != nil {
+		return err
+	}
+	before := make(map[string]*api.SdkQuotaUsage)
+	for _, kvp := range kvps {
+		r := &record{}
+		if err := json.Unmarshal(kvp.Value, r); err != nil {
+			return fmt.Errorf("Failed to decode usage of %s: %v", subject(kvp.Key), err)
+		}
+		before[kvp.Key] = r.Usage
+	}
+
+	volumes, err := enumerate()
+	if err != nil {
+		return err
+	}
+	usages := make(map[string]*api.SdkQuotaUsage)
+	for _, v := range volumes {
+		u := VolumeUsage(v)
+		for _, key := range ownershipKeys(v.GetSpec().GetOwnership()) {
+			total, ok := usages[key]
+			if !ok {
+				total = &api.SdkQuotaUsage{}
+				usages[key] = total
+			}
+			total.Volumes += u.GetVolumes()
+			total.ProvisionedBytes += u.GetProvisionedBytes()
+			total.Snapshots += u.GetSnapshots()
+		}
+	}
+
+	// Reset the usage of the users and groups which no longer own volumes
+	for key := range before {
+		if _, ok := usages[key]; !ok {
+			usages[key] = &api.SdkQuotaUsage{}
+		}
+	}
+
+	for key, usage := range usages {
+		if err := m.update(key, func(r *record) error {
+			// Add the charges made since the usage was read
+			d := &delta{}
+			d.add(r.Usage, 1)
+			d.add(before[key], -1)
+			r.Usage = &api.SdkQuotaUsage{
+				Volumes:          apply(usage.GetVolumes(), d.volumes),
+				ProvisionedBytes: apply(usage.GetProvisionedBytes(), d.provisionedBytes),
+				Snapshots:        apply(usage.GetSnapshots(), d.snapshots),
+			}
+			return nil
+		}); err != nil {
+			return fmt.Errorf("Failed to update usage of %s: %v", subject(key), err)
+		}
+	}
+
+	return nil
+}
+
+// Set creates or replaces the quota of a user or group
+func (m *SdkQuotaManager) Set(
+	ctx context.Context,
+	req *api.SdkQuotaSetRequest,
+) (*api.SdkQuotaSetResponse, error) {
+	q := req.GetQuota()
+	if q == nil {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a quota")
+	} else if err := validateOwner(q.GetOwnerType(), q.GetName()); err != nil {
+		return nil, err
+	}
+
+	err := m.update(quotaKey(q.GetOwnerType(), q.GetName()), func(r *record) error {
+		r.Quota = q
+		return nil
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to save quota: %v", err)
+	}
+
+	return &api.SdkQuotaSetResponse{}, nil
+}
+
+// Inspect returns the quota of a user or group and its usage
+func (m *SdkQuotaManager) Inspect(
+	ctx context.Context,
+	req *api.SdkQuotaInspectRequest,
+) (*api.SdkQuotaInspectResponse, error) {
+	if err := validateOwner(req.GetOwnerType(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	key := quotaKey(req.GetOwnerType(), req.GetName())
+	r := &record{}
+	_, err := m.kv.GetVal(key, r)
+	if err != nil && err != kvdb.ErrNotFound {
+		return nil, status.Errorf(codes.Internal, "Failed to get quota of %s: %v", subject(key), err)
+	} else if err == kvdb.ErrNotFound || r.Quota == nil {
+		return nil, status.Errorf(codes.NotFound, "Quota of %s not found", subject(key))
+	}
+	if r.Usage == nil {
+		r.Usage = &api.SdkQuotaUsage{}
+	}
+
+	return &api.SdkQuotaInspectResponse{
+		Quota: r.Quota,
+		Usage: r.Usage,
+	}, nil
+}
+
+// Enumerate returns the quotas of all the users and groups
+func (m *SdkQuotaManager) Enumerate(
+	ctx context.Context,
+	req *api.SdkQuotaEnumerateRequest,
+) (*api.SdkQuotaEnumerateResponse, error) {
+	kvps, err := m.kv.Enumerate(quotaPrefix + "/")
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to access quotas from database: %v", err)
+	}
+
+	quotas := make([]*api.SdkQuota, 0)
+	for _, kvp := range kvps {
+		r := &record{}
+		if err := json.Unmarshal(kvp.Value, r); err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to decode quota %s: %v", kvp.Key, err)
+		}
+		if r.Quota != nil {
+			quotas = append(quotas, r.Quota)
+		}
+	}
+
+	return &api.SdkQuotaEnumerateResponse{
+		Quotas: quotas,
+	}, nil
+}
+
+// Delete removes the quota of a user or group. Its usage is still tracked.
+func (m *SdkQuotaManager) Delete(
+	ctx context.Context,
+	req *api.SdkQuotaDeleteRequest,
+) (*api.SdkQuotaDeleteResponse, error) {
+	if err := validateOwner(req.GetOwnerType(), req.GetName()); err != nil {
+		return nil, err
+	}
+
+	key := quotaKey(req.GetOwnerType(), req.GetName())
+	err := m.update(key, func(r *record) error {
+		r.Quota = nil
+		return nil
+	})
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to delete quota of %s: %v", subject(key), err)
+	}
+
+	return &api.SdkQuotaDeleteResponse{}, nil
+}
@@ -0,0 +1,207 @@
+/*
+Package quota manages the quotas of the users and groups
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package quota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+func newTestManager(t *testing.T) *SdkQuotaManager {
+	kv, err := kvdb.New(mem.Name, "quota_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	m, err := NewSdkQuotaManager(kv)
+	require.NoError(t, err)
+	return m
+}
+
+func inspectUsage(t *testing.T, m *SdkQuotaManager, ownerType api.SdkQuota_OwnerType, name string) *api.SdkQuotaUsage {
+	resp, err := m.Inspect(context.Background(), &api.SdkQuotaInspectRequest{
+		OwnerType: ownerType,
+		Name:      name,
+	})
+	require.NoError(t, err)
+	return resp.GetUsage()
+}
+
+func TestQuotaSetInspectEnumerateDelete(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+
+	// Bad arguments
+	_, err := m.Set(ctx, &api.SdkQuotaSetRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = m.Set(ctx, &api.SdkQuotaSetRequest{Quota: &api.SdkQuota{}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = m.Set(ctx, &api.SdkQuotaSetRequest{Quota: &api.SdkQuota{
+		OwnerType: api.SdkQuota_GROUP,
+		Name:      "*",
+	}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = m.Inspect(ctx, &api.SdkQuotaInspectRequest{Name: "user1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	userQuota := &api.SdkQuota{
+		OwnerType:  api.SdkQuota_USER,
+		Name:       "user1",
+		MaxVolumes: 10,
+	}
+	groupQuota := &api.SdkQuota{
+		OwnerType:    api.SdkQuota_GROUP,
+		Name:         "dev/ops",
+		MaxSnapshots: 5,
+	}
+	for _, q := range []*api.SdkQuota{userQuota, groupQuota} {
+		_, err = m.Set(ctx, &api.SdkQuotaSetRequest{Quota: q})
+		require.NoError(t, err)
+	}
+
+	resp, err := m.Inspect(ctx, &api.SdkQuotaInspectRequest{
+		OwnerType: api.SdkQuota_GROUP,
+		Name:      "dev/ops",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), resp.GetQuota().GetMaxSnapshots())
+	assert.Equal(t, uint64(0), resp.GetUsage().GetSnapshots())
+
+	enumResp, err := m.Enumerate(ctx, &api.SdkQuotaEnumerateRequest{})
+	require.NoError(t, err)
+	assert.Len(t, enumResp.GetQuotas(), 2)
+
+	_, err = m.Delete(ctx, &api.SdkQuotaDeleteRequest{Name: "user1"})
+	require.NoError(t, err)
+	_, err = m.Inspect(ctx, &api.SdkQuotaInspectRequest{Name: "user1"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	enumResp, err = m.Enumerate(ctx, &api.SdkQuotaEnumerateRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetQuotas(), 1)
+	assert.Equal(t, "dev/ops", enumResp.GetQuotas()[0].GetName())
+}
+
+func TestQuotaCharge(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+
+	_, err := m.Set(ctx, &api.SdkQuotaSetRequest{Quota: &api.SdkQuota{
+		Name:                "user1",
+		MaxVolumes:          2,
+		MaxProvisionedBytes: 100,
+	}})
+	require.NoError(t, err)
+	_, err = m.Set(ctx, &api.SdkQuotaSetRequest{Quota: &api.SdkQuota{
+		OwnerType:  api.SdkQuota_GROUP,
+		Name:       "group1",
+		MaxVolumes: 1,
+	}})
+	require.NoError(t, err)
+
+	owner := &api.Ownership{Owner: "user1"}
+	shared := &api.Ownership{
+		Owner: "user1",
+		Acls: &api.Ownership_AccessControl{
+			Groups: map[string]api.Ownership_AccessType{
+				"group1": api.Ownership_Read,
+			},
+		},
+	}
+	vol := &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 40}
+
+	// No ownership, no quota
+	require.NoError(t, m.Charge(nil, nil, nil, vol))
+
+	require.NoError(t, m.Charge(nil, nil, owner, vol))
+	assert.Equal(t, uint64(40), inspectUsage(t, m, api.SdkQuota_USER, "user1").GetProvisionedBytes())
+
+	// Too many bytes
+	err = m.Charge(nil, nil, owner, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 61})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Resize in place only charges the difference
+	require.NoError(t, m.Charge(owner, vol, owner, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 60}))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 60}, inspectUsage(t, m, api.SdkQuota_USER, "user1"))
+
+	// Sharing the volume with the group charges the group only
+	require.NoError(t, m.Charge(owner, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 60}, shared, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 60}))
+	assert.Equal(t, uint64(1), inspectUsage(t, m, api.SdkQuota_USER, "user1").GetVolumes())
+	assert.Equal(t, uint64(1), inspectUsage(t, m, api.SdkQuota_GROUP, "group1").GetVolumes())
+
+	// The group quota is exceeded, the usage of the user must not change
+	err = m.Charge(nil, nil, shared, &api.SdkQuotaUsage{Volumes: 1})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, uint64(1), inspectUsage(t, m, api.SdkQuota_USER, "user1").GetVolumes())
+
+	// Delete
+	require.NoError(t, m.Charge(shared, &api.SdkQuotaUsage{Volumes: 1, ProvisionedBytes: 60}, nil, nil))
+	assert.Equal(t, &api.SdkQuotaUsage{}, inspectUsage(t, m, api.SdkQuota_USER, "user1"))
+	assert.Equal(t, &api.SdkQuotaUsage{}, inspectUsage(t, m, api.SdkQuota_GROUP, "group1"))
+}
+
+func TestQuotaRecompute(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+
+	_, err := m.Set(ctx, &api.SdkQuotaSetRequest{Quota: &api.SdkQuota{
+		Name:       "user1",
+		MaxVolumes: 10,
+	}})
+	require.NoError(t, err)
+	_, err = m.Set(ctx, &api.SdkQuotaSetRequest{Quota: &api.SdkQuota{
+		Name:       "user2",
+		MaxVolumes: 10,
+	}})
+	require.NoError(t, err)
+	require.NoError(t, m.Charge(nil, nil, &api.Ownership{Owner: "user2"}, &api.SdkQuotaUsage{Volumes: 3}))
+
+	spec := &api.VolumeSpec{
+		Size:      100,
+		Ownership: &api.Ownership{Owner: "user1"},
+	}
+	volumes := []*api.Volume{
+		{Id: "vol1", Spec: spec},
+		{Id: "vol2", Spec: spec},
+		{Id: "snap1", Spec: spec, Readonly: true, Source: &api.Source{Parent: "vol1"}},
+		{Id: "public", Spec: &api.VolumeSpec{Size: 100}},
+	}
+	require.NoError(t, m.Recompute(func() ([]*api.Volume, error) {
+		// A volume created while the volumes are enumerated is kept
+		require.NoError(t, m.Charge(nil, nil, &api.Ownership{Owner: "user2"}, &api.SdkQuotaUsage{Volumes: 1}))
+		return volumes, nil
+	}))
+
+	assert.Equal(t, &api.SdkQuotaUsage{
+		Volumes:          2,
+		ProvisionedBytes: 200,
+		Snapshots:        1,
+	}, inspectUsage(t, m, api.SdkQuota_USER, "user1"))
+	assert.Equal(t, &api.SdkQuotaUsage{Volumes: 1}, inspectUsage(t, m, api.SdkQuota_USER, "user2"))
+
+	// Another node does not recompute the usage again
+	require.NoError(t, m.Recompute(func() ([]*api.Volume, error) {
+		t.Fatal("usage recomputed twice")
+		return nil, nil
+	}))
+}
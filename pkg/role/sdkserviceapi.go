@@ -62,9 +62,14 @@ var (
 			Mutable: false,
 		},
 
-		// system:view role can only run read-only commands
+		// system:view role can only run read-only commands. The audit log
+		// records the requests of all users, so only admins can read it.
 		SystemViewRoleName: &DefaultRole{
 			Rules: []*api.SdkRule{
+				&api.SdkRule{
+					Services: []string{"!audit"},
+					Apis:     []string{"*"},
+				},
 				&api.SdkRule{
 					Services: []string{"*"},
 					Apis: []string{
@@ -96,6 +101,7 @@ var (
 						"schedulepolicy",
 						"mountattach",
 						"migrate",
+						"volumetemplate",
 					},
 					Apis: []string{"*"},
 				},
@@ -741,6 +741,36 @@ func TestSdkRoleVerifyRules(t *testing.T) {
 			fullmethod: "/openstorage.api.OpenStorageNode/FutureCall",
 			roles:      []string{"system.user"},
 		},
+		{
+			denied:     false,
+			fullmethod: "/openstorage.api.OpenStorageVolumeTemplate/Create",
+			roles:      []string{"system.user"},
+		},
+		{
+			denied:     false,
+			fullmethod: "/openstorage.api.OpenStorageVolumeTemplate/Update",
+			roles:      []string{"system.user"},
+		},
+		{
+			denied:     false,
+			fullmethod: "/openstorage.api.OpenStorageVolumeTemplate/Inspect",
+			roles:      []string{"system.view"},
+		},
+		{
+			denied:     false,
+			fullmethod: "/openstorage.api.OpenStorageVolumeTemplate/Enumerate",
+			roles:      []string{"system.view"},
+		},
+		{
+			denied:     true,
+			fullmethod: "/openstorage.api.OpenStorageVolumeTemplate/Create",
+			roles:      []string{"system.view"},
+		},
+		{
+			denied:     true,
+			fullmethod: "/openstorage.api.OpenStorageVolumeTemplate/Update",
+			roles:      []string{"system.view"},
+		},
 	}
 
 	kv, err := kvdb.New(mem.Name, "role", []string{}, nil, kvdb.LogFatalErrorCB)
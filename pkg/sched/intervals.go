@@ -182,6 +182,11 @@ func Monthly(day int, hour int, minute int) Interval {
 	return monthly{day, daily{hour, minute}}
 }
 
+// NextAfter returns the first time of the interval after t
+func NextAfter(interval Interval, t time.Time) time.Time {
+	return interval.nextAfter(t)
+}
+
 func parseSpec(spec *IntervalSpec) (Interval, error) {
 	switch spec.Freq {
 	case PeriodicType:
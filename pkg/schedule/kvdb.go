@@ -0,0 +1,635 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+	"github.com/libopenstorage/openstorage/pkg/sched"
+)
+
+const (
+	schedulesPrefix = "cluster/schedules/"
+
+	// DefaultCheckInterval is how often a node looks for schedules to run
+	DefaultCheckInterval = time.Minute
+	// MaxRuns is the number of runs kept in the history of a schedule
+	MaxRuns = 10
+)
+
+// errNotDue is returned when a schedule is no longer due to run
+var errNotDue = fmt.Errorf("schedule is not due")
+
+// Runner runs the tasks of the schedules of one type
+type Runner interface {
+	// RunSchedule runs the tasks of a schedule. The context is cancelled
+	// after the max duration of the schedule, or when the node stops.
+	RunSchedule(ctx context.Context, schedule *api.Schedule) error
+}
+
+// Source is a subsystem which keeps and runs its schedules itself, such as
+// the volume driver for the cloud backup schedules. The registry lists its
+// schedules along with the others.
+type Source interface {
+	// Schedules returns the schedules of the source, with the time of their
+	// last run and the outcome of their most recent runs
+	Schedules() ([]*api.Schedule, error)
+	// DeleteSchedule deletes a schedule of the source by ID. It returns a
+	// gRPC status error.
+	DeleteSchedule(id string) error
+	// PauseSchedule pauses or resumes a schedule of the source by ID, and
+	// returns the updated schedule. It returns a gRPC status error.
+	PauseSchedule(id string, paused bool) (*api.Schedule, error)
+}
+
+// KvdbRegistry is a Provider which saves in kvdb the schedules of the
+// subsystems built on the schedule framework, such as defrag, rebalance and
+// trim. Each subsystem registers the runner of its type of schedule, and
+// creates its schedules with the registry. When a schedule is due, it is run
+// once in the cluster by the first node which claims it, and the outcome of
+// the run is saved with the schedule. The subsystems which run their
+// schedules themselves register as the source of their type of schedule
+// instead, so that their schedules are listed, inspected and deleted through
+// the registry too.
+type KvdbRegistry struct {
+	kv            kvdb.Kvdb
+	nodeID        string
+	checkInterval time.Duration
+
+	lock    sync.Mutex
+	runners map[api.Job_Type]Runner
+	sources map[api.Job_Type]Source
+	running map[string]context.CancelFunc
+	stop    chan struct{}
+	stopped bool
+	wg      sync.WaitGroup
+}
+
+// Check interface
+var _ Provider = &KvdbRegistry{}
+
+// NewKvdbRegistry returns a schedule provider which saves the schedules in
+// kvdb and runs them on the node nodeID
+func NewKvdbRegistry(kv kvdb.Kvdb, nodeID string) (*KvdbRegistry, error) {
+	if kv == nil {
+		return nil, fmt.Errorf("Must supply a kvdb instance for the schedule registry")
+	} else if len(nodeID) == 0 {
+		return nil, fmt.Errorf("Must supply a node id for the schedule registry")
+	}
+	return &KvdbRegistry{
+		kv:            kv,
+		nodeID:        nodeID,
+		checkInterval: DefaultCheckInterval,
+		runners:       make(map[api.Job_Type]Runner),
+		sources:       make(map[api.Job_Type]Source),
+		running:       make(map[string]context.CancelFunc),
+	}, nil
+}
+
+func scheduleKey(scheduleType api.Job_Type, id string) string {
+	return schedulesPrefix + scheduleType.String() + "/" + id
+}
+
+// parseType returns the job type of a schedule type sent as a string
+func parseType(scheduleType string) (api.Job_Type, error) {
+	t, ok := api.Job_Type_value[strings.ToUpper(scheduleType)]
+	if !ok || api.Job_Type(t) == api.Job_UNSPECIFIED_TYPE || api.Job_Type(t) == api.Job_NONE {
+		return api.Job_UNSPECIFIED_TYPE, status.Errorf(codes.InvalidArgument, "Invalid schedule type %q", scheduleType)
+	}
+	return api.Job_Type(t), nil
+}
+
+// nextRun returns the first time of the start time of a schedule after t
+func nextRun(startTime string, t time.Time) (time.Time, error) {
+	intervals, err := sched.ParseSchedule(startTime)
+	if err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "Invalid start time %q: %v", startTime, err)
+	} else if len(intervals) != 1 {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "Start time %q must have one interval", startTime)
+	}
+	return sched.NextAfter(intervals[0], t), nil
+}
+
+func marshalSchedule(s *api.Schedule) (string, error) {
+	marshaler := jsonpb.Marshaler{OrigName: true}
+	return marshaler.MarshalToString(s)
+}
+
+func unmarshalSchedule(kvp *kvdb.KVPair) (*api.Schedule, error) {
+	s := &api.Schedule{}
+	if err := jsonpb.UnmarshalString(string(kvp.Value), s); err != nil {
+		return nil, status.Errorf(codes.Internal, "Json Unmarshal failed for schedule %s: %v", kvp.Key, err)
+	}
+	return s, nil
+}
+
+// RegisterRunner sets the runner of the schedules of a type on this node
+func (r *KvdbRegistry) RegisterRunner(scheduleType api.Job_Type, runner Runner) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.runners[scheduleType]; ok {
+		return fmt.Errorf("Runner for schedules of type %s is already registered", scheduleType)
+	} else if _, ok := r.sources[scheduleType]; ok {
+		return fmt.Errorf("Source of schedules of type %s is already registered", scheduleType)
+	}
+	r.runners[scheduleType] = runner
+	return nil
+}
+
+// RegisterSource sets the source of the schedules of a type, which are kept
+// and run by another subsystem
+func (r *KvdbRegistry) RegisterSource(scheduleType api.Job_Type, source Source) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.sources[scheduleType]; ok {
+		return fmt.Errorf("Source of schedules of type %s is already registered", scheduleType)
+	} else if _, ok := r.runners[scheduleType]; ok {
+		return fmt.Errorf("Runner for schedules of type %s is already registered", scheduleType)
+	}
+	r.sources[scheduleType] = source
+	return nil
+}
+
+// source returns the source of the schedules of a type, if they are kept by
+// another subsystem
+func (r *KvdbRegistry) source(scheduleType api.Job_Type) Source {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.sources[scheduleType]
+}
+
+// sourceSchedules returns the schedules of the sources of all types. The
+// schedules of a source which fails are left out, so that the others are
+// still listed.
+func (r *KvdbRegistry) sourceSchedules() []*api.Schedule {
+	r.lock.Lock()
+	sources := make(map[api.Job_Type]Source, len(r.sources))
+	for scheduleType, source := range r.sources {
+		sources[scheduleType] = source
+	}
+	r.lock.Unlock()
+
+	var schedules []*api.Schedule
+	for scheduleType, source := range sources {
+		s, err := source.Schedules()
+		if err != nil {
+			logrus.Warnf("Failed to get schedules of type %s: %v", scheduleType, err)
+			continue
+		}
+		schedules = append(schedules, s...)
+	}
+	return schedules
+}
+
+// findSchedule returns a schedule of a source by ID
+func findSchedule(source Source, scheduleType api.Job_Type, id string) (*api.Schedule, error) {
+	schedules, err := source.Schedules()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get schedules of type %s: %v", scheduleType, err)
+	}
+	for _, s := range schedules {
+		if s.GetId() == id {
+			return s, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "Schedule %s of type %s not found", id, scheduleType)
+}
+
+// CreateSchedule saves a new schedule. The ID of the schedule is generated if
+// it is not set.
+func (r *KvdbRegistry) CreateSchedule(s *api.Schedule) (*api.Schedule, error) {
+	if s.GetType() == api.Job_UNSPECIFIED_TYPE || s.GetType() == api.Job_NONE {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a schedule type")
+	} else if strings.Contains(s.GetId(), "/") {
+		return nil, status.Errorf(codes.InvalidArgument, "Schedule id %s must not contain '/'", s.GetId())
+	} else if r.source(s.GetType()) != nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"Schedules of type %s are created by the subsystem which runs them", s.GetType())
+	}
+	for _, task := range s.GetTasks() {
+		if task.GetType() != s.GetType() {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"Task of type %s does not match the schedule type %s", task.GetType(), s.GetType())
+		}
+	}
+	next, err := nextRun(s.GetStartTime(), time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	s = proto.Clone(s).(*api.Schedule)
+	if len(s.Id) == 0 {
+		s.Id = uuid.New()
+	}
+	s.CreateTime = prototime.Now()
+	s.LastUpdateTime = s.CreateTime
+	s.LastRunTime = nil
+	s.NextRunTime = prototime.TimeToTimestamp(next)
+	s.Runs = nil
+
+	value, err := marshalSchedule(s)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to marshal schedule %s: %v", s.Id, err)
+	}
+	if _, err := r.kv.Create(scheduleKey(s.Type, s.Id), value, 0); err == kvdb.ErrExist {
+		return nil, status.Errorf(codes.AlreadyExists, "Schedule %s of type %s already exists", s.Id, s.Type)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to save schedule %s: %v", s.Id, err)
+	}
+
+	logrus.Infof("Schedule %s of type %s is created, next run at %v", s.Id, s.Type, next)
+	return s, nil
+}
+
+// update reads a schedule, calls fn to change it and saves it, retrying if
+// the schedule has been updated in the meantime. The update is abandoned if
+// fn fails.
+func (r *KvdbRegistry) update(
+	scheduleType api.Job_Type,
+	id string,
+	fn func(s *api.Schedule) error,
+) (*api.Schedule, error) {
+	for {
+		kvp, err := r.kv.Get(scheduleKey(scheduleType, id))
+		if err == kvdb.ErrNotFound {
+			return nil, status.Errorf(codes.NotFound, "Schedule %s of type %s not found", id, scheduleType)
+		} else if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to get schedule %s: %v", id, err)
+		}
+		s, err := unmarshalSchedule(kvp)
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(s); err != nil {
+			return nil, err
+		}
+		s.LastUpdateTime = prototime.Now()
+
+		value, err := marshalSchedule(s)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to marshal schedule %s: %v", id, err)
+		}
+		kvp.Value = []byte(value)
+		_, err = r.kv.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil)
+		if err == kvdb.ErrValueMismatch || err == kvdb.ErrModified {
+			continue
+		} else if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to save schedule %s: %v", id, err)
+		}
+		return s, nil
+	}
+}
+
+// InspectSchedule returns a schedule by type and ID
+func (r *KvdbRegistry) InspectSchedule(
+	ctx context.Context,
+	in *api.SdkInspectScheduleRequest,
+) (*api.SdkInspectScheduleResponse, error) {
+	if len(in.GetId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a schedule id")
+	}
+	scheduleType, err := parseType(in.GetType())
+	if err != nil {
+		return nil, err
+	}
+	if source := r.source(scheduleType); source != nil {
+		s, err := findSchedule(source, scheduleType, in.GetId())
+		if err != nil {
+			return nil, err
+		}
+		return &api.SdkInspectScheduleResponse{
+			Schedule: s,
+		}, nil
+	}
+
+	kvp, err := r.kv.Get(scheduleKey(scheduleType, in.GetId()))
+	if err == kvdb.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound, "Schedule %s of type %s not found", in.GetId(), scheduleType)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get schedule %s: %v", in.GetId(), err)
+	}
+	s, err := unmarshalSchedule(kvp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SdkInspectScheduleResponse{
+		Schedule: s,
+	}, nil
+}
+
+// EnumerateSchedules returns the schedules of a type, or all the schedules if
+// the type is not set, including those of the sources, sorted by creation
+// time
+func (r *KvdbRegistry) EnumerateSchedules(
+	ctx context.Context,
+	in *api.SdkEnumerateSchedulesRequest,
+) (*api.SdkEnumerateSchedulesResponse, error) {
+	var schedules []*api.Schedule
+	var err error
+	if len(in.GetType()) != 0 {
+		scheduleType, err := parseType(in.GetType())
+		if err != nil {
+			return nil, err
+		}
+		if source := r.source(scheduleType); source != nil {
+			schedules, err = source.Schedules()
+		} else {
+			schedules, err = r.enumerate(schedulesPrefix + scheduleType.String() + "/")
+		}
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to get schedules: %v", err)
+		}
+	} else {
+		schedules, err = r.enumerate(schedulesPrefix)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Failed to get schedules: %v", err)
+		}
+		schedules = append(schedules, r.sourceSchedules()...)
+	}
+
+	sortSchedules(schedules)
+	return &api.SdkEnumerateSchedulesResponse{
+		Schedules: schedules,
+	}, nil
+}
+
+func (r *KvdbRegistry) enumerate(prefix string) ([]*api.Schedule, error) {
+	kvps, err := r.kv.Enumerate(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	schedules := make([]*api.Schedule, 0, len(kvps))
+	for _, kvp := range kvps {
+		s, err := unmarshalSchedule(kvp)
+		if err != nil {
+			logrus.Warnf("%v", err)
+			continue
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, nil
+}
+
+// sortSchedules sorts schedules by creation time, then by ID
+func sortSchedules(schedules []*api.Schedule) {
+	sort.Slice(schedules, func(i, j int) bool {
+		ti := prototime.TimestampToTime(schedules[i].GetCreateTime())
+		tj := prototime.TimestampToTime(schedules[j].GetCreateTime())
+		if ti.Equal(tj) {
+			return schedules[i].GetId() < schedules[j].GetId()
+		}
+		return ti.Before(tj)
+	})
+}
+
+// DeleteSchedule deletes a schedule by type and ID. A run of the schedule in
+// progress is not stopped.
+func (r *KvdbRegistry) DeleteSchedule(
+	ctx context.Context,
+	in *api.SdkDeleteScheduleRequest,
+) (*api.SdkDeleteScheduleResponse, error) {
+	if len(in.GetId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a schedule id")
+	}
+	scheduleType, err := parseType(in.GetType())
+	if err != nil {
+		return nil, err
+	}
+	if source := r.source(scheduleType); source != nil {
+		if err := source.DeleteSchedule(in.GetId()); err != nil {
+			return nil, err
+		}
+		logrus.Infof("Schedule %s of type %s is deleted", in.GetId(), scheduleType)
+		return &api.SdkDeleteScheduleResponse{}, nil
+	}
+
+	if _, err := r.kv.Delete(scheduleKey(scheduleType, in.GetId())); err != nil && err != kvdb.ErrNotFound {
+		return nil, status.Errorf(codes.Internal, "Failed to delete schedule %s: %v", in.GetId(), err)
+	}
+
+	logrus.Infof("Schedule %s of type %s is deleted", in.GetId(), scheduleType)
+	return &api.SdkDeleteScheduleResponse{}, nil
+}
+
+// UpdateSchedule pauses or resumes a schedule by type and ID. A resumed
+// schedule skips the runs missed while it was paused. The schedules of the
+// sources are paused by their source.
+func (r *KvdbRegistry) UpdateSchedule(
+	ctx context.Context,
+	in *api.SdkUpdateScheduleRequest,
+) (*api.SdkUpdateScheduleResponse, error) {
+	if len(in.GetId()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a schedule id")
+	}
+	scheduleType, err := parseType(in.GetType())
+	if err != nil {
+		return nil, err
+	}
+	if source := r.source(scheduleType); source != nil {
+		s, err := source.PauseSchedule(in.GetId(), in.GetPaused())
+		if err != nil {
+			return nil, err
+		}
+		logrus.Infof("Schedule %s of type %s is paused: %v", s.Id, s.Type, s.Paused)
+		return &api.SdkUpdateScheduleResponse{
+			Schedule: s,
+		}, nil
+	}
+
+	s, err := r.update(scheduleType, in.GetId(), func(s *api.Schedule) error {
+		if s.Paused && !in.GetPaused() {
+			next, err := nextRun(s.StartTime, time.Now())
+			if err != nil {
+				return err
+			}
+			s.NextRunTime = prototime.TimeToTimestamp(next)
+		}
+		s.Paused = in.GetPaused()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Schedule %s of type %s is paused: %v", s.Id, s.Type, s.Paused)
+	return &api.SdkUpdateScheduleResponse{
+		Schedule: s,
+	}, nil
+}
+
+// Start looks for the schedules to run on this node periodically
+func (r *KvdbRegistry) Start() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.stop != nil {
+		return fmt.Errorf("Schedule registry is already started")
+	}
+	stop := make(chan struct{})
+	r.stop = stop
+	r.stopped = false
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.checkInterval)
+		defer ticker.Stop()
+		for {
+			r.check(time.Now())
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops looking for schedules to run and cancels the runs in progress
+// on this node
+func (r *KvdbRegistry) Stop() {
+	r.lock.Lock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+	r.stopped = true
+	for _, cancel := range r.running {
+		cancel()
+	}
+	r.lock.Unlock()
+
+	r.wg.Wait()
+}
+
+// check starts the schedules which are due at now and have a runner on this
+// node
+func (r *KvdbRegistry) check(now time.Time) {
+	schedules, err := r.enumerate(schedulesPrefix)
+	if err != nil {
+		logrus.Warnf("Failed to get schedules: %v", err)
+		return
+	}
+
+	for _, s := range schedules {
+		key := scheduleKey(s.GetType(), s.GetId())
+		r.lock.Lock()
+		_, running := r.running[key]
+		runner := r.runners[s.GetType()]
+		r.lock.Unlock()
+
+		if running || runner == nil || !due(s, now) {
+			continue
+		}
+		r.start(s, runner, now)
+	}
+}
+
+// due returns true if a schedule is not paused and its next run is at or
+// before now
+func due(s *api.Schedule, now time.Time) bool {
+	return !s.GetPaused() &&
+		s.GetNextRunTime() != nil &&
+		!prototime.TimestampToTime(s.GetNextRunTime()).After(now)
+}
+
+// start claims the run of a schedule and runs it. Only the node which saves
+// the next run time of the schedule first runs it.
+func (r *KvdbRegistry) start(s *api.Schedule, runner Runner, now time.Time) {
+	claimed, err := r.update(s.GetType(), s.GetId(), func(s *api.Schedule) error {
+		if !due(s, now) {
+			return errNotDue
+		}
+		next, err := nextRun(s.StartTime, now)
+		if err != nil {
+			return err
+		}
+		s.LastRunTime = prototime.TimeToTimestamp(now)
+		s.NextRunTime = prototime.TimeToTimestamp(next)
+		return nil
+	})
+	if err == errNotDue {
+		return
+	} else if err != nil {
+		logrus.Warnf("Failed to start schedule %s of type %s: %v", s.GetId(), s.GetType(), err)
+		return
+	}
+	s = claimed
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if s.GetMaxDurationMinutes() > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(),
+			time.Duration(s.GetMaxDurationMinutes())*time.Minute)
+	}
+	key := scheduleKey(s.GetType(), s.GetId())
+	r.lock.Lock()
+	if r.stopped {
+		r.lock.Unlock()
+		cancel()
+		return
+	}
+	r.running[key] = cancel
+	r.wg.Add(1)
+	r.lock.Unlock()
+
+	logrus.Infof("Schedule %s of type %s is running on node %s", s.GetId(), s.GetType(), r.nodeID)
+	go func() {
+		defer r.wg.Done()
+		defer cancel()
+
+		started := time.Now()
+		runErr := runner.RunSchedule(ctx, s)
+		r.finish(s, started, runErr)
+
+		r.lock.Lock()
+		delete(r.running, key)
+		r.lock.Unlock()
+	}()
+}
+
+// finish saves the outcome of a run of a schedule
+func (r *KvdbRegistry) finish(s *api.Schedule, started time.Time, runErr error) {
+	run := &api.ScheduleRun{
+		StartTime: prototime.TimeToTimestamp(started),
+		EndTime:   prototime.Now(),
+		State:     api.Job_DONE,
+		Message:   "Run succeeded",
+		NodeId:    r.nodeID,
+	}
+	if runErr != nil {
+		logrus.Warnf("Run of schedule %s of type %s failed: %v", s.GetId(), s.GetType(), runErr)
+		run.State = api.Job_FAILED
+		run.Message = runErr.Error()
+	}
+
+	_, err := r.update(s.GetType(), s.GetId(), func(s *api.Schedule) error {
+		s.Runs = append(s.Runs, run)
+		if len(s.Runs) > MaxRuns {
+			s.Runs = s.Runs[len(s.Runs)-MaxRuns:]
+		}
+		return nil
+	})
+	if status.Code(err) == codes.NotFound {
+		return
+	} else if err != nil {
+		logrus.Warnf("Failed to save the run of schedule %s of type %s: %v", s.GetId(), s.GetType(), err)
+	}
+}
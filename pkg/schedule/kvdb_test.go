@@ -0,0 +1,303 @@
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+)
+
+// testRunner counts the runs of the schedules
+type testRunner struct {
+	lock sync.Mutex
+	runs int
+	err  error
+}
+
+func (r *testRunner) RunSchedule(ctx context.Context, schedule *api.Schedule) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.runs++
+	return r.err
+}
+
+func (r *testRunner) getRuns() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.runs
+}
+
+func newTestKvdb(t *testing.T) kvdb.Kvdb {
+	kv, err := kvdb.New(mem.Name, "schedule_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	return kv
+}
+
+func newTestRegistry(t *testing.T, kv kvdb.Kvdb, nodeID string, runner Runner) *KvdbRegistry {
+	r, err := NewKvdbRegistry(kv, nodeID)
+	require.NoError(t, err)
+	require.NoError(t, r.RegisterRunner(api.Job_DEFRAG, runner))
+	return r
+}
+
+func inspect(t *testing.T, r *KvdbRegistry, id string) *api.Schedule {
+	resp, err := r.InspectSchedule(context.Background(), &api.SdkInspectScheduleRequest{
+		Id:   id,
+		Type: "DEFRAG",
+	})
+	require.NoError(t, err)
+	return resp.GetSchedule()
+}
+
+func nextRunTime(t *testing.T, r *KvdbRegistry, id string) time.Time {
+	return prototime.TimestampToTime(inspect(t, r, id).GetNextRunTime())
+}
+
+func TestKvdbRegistrySchedules(t *testing.T) {
+	r := newTestRegistry(t, newTestKvdb(t), "node1", &testRunner{})
+	ctx := context.Background()
+
+	_, err := r.CreateSchedule(&api.Schedule{StartTime: "daily=19:15"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = r.CreateSchedule(&api.Schedule{Type: api.Job_DEFRAG, StartTime: "hourly"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = r.CreateSchedule(&api.Schedule{
+		Type:      api.Job_DEFRAG,
+		StartTime: "daily=19:15",
+		Tasks:     []*api.Job{{Type: api.Job_COLLECT_DIAGS}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	s, err := r.CreateSchedule(&api.Schedule{
+		Type:      api.Job_DEFRAG,
+		StartTime: "daily=19:15",
+		Tasks:     []*api.Job{{Type: api.Job_DEFRAG}},
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, s.GetId())
+	next := prototime.TimestampToTime(s.GetNextRunTime())
+	assert.Equal(t, 19, next.Hour())
+	assert.Equal(t, 15, next.Minute())
+	assert.True(t, next.After(time.Now()))
+
+	_, err = r.CreateSchedule(s)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	other, err := r.CreateSchedule(&api.Schedule{
+		Id:        "other",
+		Type:      api.Job_COLLECT_DIAGS,
+		StartTime: "weekly=Sunday@01:00",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, s.GetId(), inspect(t, r, s.GetId()).GetId())
+	_, err = r.InspectSchedule(ctx, &api.SdkInspectScheduleRequest{Id: s.GetId(), Type: "COLLECT_DIAGS"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = r.InspectSchedule(ctx, &api.SdkInspectScheduleRequest{Id: s.GetId(), Type: "unknown"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	enumResp, err := r.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetSchedules(), 2)
+	assert.Equal(t, s.GetId(), enumResp.GetSchedules()[0].GetId())
+	enumResp, err = r.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{Type: "collect_diags"})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetSchedules(), 1)
+	assert.Equal(t, other.GetId(), enumResp.GetSchedules()[0].GetId())
+
+	_, err = r.DeleteSchedule(ctx, &api.SdkDeleteScheduleRequest{Id: s.GetId(), Type: "DEFRAG"})
+	require.NoError(t, err)
+	_, err = r.InspectSchedule(ctx, &api.SdkInspectScheduleRequest{Id: s.GetId(), Type: "DEFRAG"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Delete is idempotent
+	_, err = r.DeleteSchedule(ctx, &api.SdkDeleteScheduleRequest{Id: s.GetId(), Type: "DEFRAG"})
+	assert.NoError(t, err)
+}
+
+func TestKvdbRegistryRun(t *testing.T) {
+	kv := newTestKvdb(t)
+	runner := &testRunner{}
+	r1 := newTestRegistry(t, kv, "node1", runner)
+	r2 := newTestRegistry(t, kv, "node2", runner)
+	defer r1.Stop()
+	defer r2.Stop()
+
+	s, err := r1.CreateSchedule(&api.Schedule{
+		Type:      api.Job_DEFRAG,
+		StartTime: "periodic=60",
+	})
+	require.NoError(t, err)
+	next := prototime.TimestampToTime(s.GetNextRunTime())
+
+	// The schedule is not due yet
+	r1.check(next.Add(-time.Second))
+	assert.Equal(t, 0, runner.getRuns())
+
+	// Only one node runs the schedule
+	r1.check(next)
+	r2.check(next)
+	require.Eventually(t, func() bool {
+		return len(inspect(t, r1, s.GetId()).GetRuns()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, runner.getRuns())
+
+	s = inspect(t, r1, s.GetId())
+	assert.Equal(t, next, prototime.TimestampToTime(s.GetLastRunTime()))
+	assert.Equal(t, next.Add(time.Hour), prototime.TimestampToTime(s.GetNextRunTime()))
+	assert.Equal(t, api.Job_DONE, s.GetRuns()[0].GetState())
+	assert.Equal(t, "node1", s.GetRuns()[0].GetNodeId())
+
+	// A failed run is recorded
+	runner.lock.Lock()
+	runner.err = fmt.Errorf("no space left")
+	runner.lock.Unlock()
+	r2.check(next.Add(time.Hour))
+	require.Eventually(t, func() bool {
+		return len(inspect(t, r1, s.GetId()).GetRuns()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	run := inspect(t, r1, s.GetId()).GetRuns()[1]
+	assert.Equal(t, api.Job_FAILED, run.GetState())
+	assert.Equal(t, "no space left", run.GetMessage())
+	assert.Equal(t, "node2", run.GetNodeId())
+}
+
+func TestKvdbRegistryPause(t *testing.T) {
+	runner := &testRunner{}
+	r := newTestRegistry(t, newTestKvdb(t), "node1", runner)
+	defer r.Stop()
+	ctx := context.Background()
+
+	s, err := r.CreateSchedule(&api.Schedule{
+		Type:      api.Job_DEFRAG,
+		StartTime: "periodic=60",
+	})
+	require.NoError(t, err)
+
+	_, err = r.UpdateSchedule(ctx, &api.SdkUpdateScheduleRequest{Id: "missing", Type: "DEFRAG", Paused: true})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	resp, err := r.UpdateSchedule(ctx, &api.SdkUpdateScheduleRequest{Id: s.GetId(), Type: "DEFRAG", Paused: true})
+	require.NoError(t, err)
+	assert.True(t, resp.GetSchedule().GetPaused())
+
+	// A paused schedule is not run
+	r.check(time.Now().Add(24 * time.Hour))
+	assert.Equal(t, 0, runner.getRuns())
+
+	// A resumed schedule skips the missed runs
+	before := nextRunTime(t, r, s.GetId())
+	time.Sleep(10 * time.Millisecond)
+	resp, err = r.UpdateSchedule(ctx, &api.SdkUpdateScheduleRequest{Id: s.GetId(), Type: "DEFRAG"})
+	require.NoError(t, err)
+	assert.False(t, resp.GetSchedule().GetPaused())
+	assert.True(t, nextRunTime(t, r, s.GetId()).After(before))
+}
+
+// testSource keeps the schedules of a source in memory
+type testSource struct {
+	schedules []*api.Schedule
+	err       error
+}
+
+func (s *testSource) Schedules() ([]*api.Schedule, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.schedules, nil
+}
+
+func (s *testSource) DeleteSchedule(id string) error {
+	for i, schedule := range s.schedules {
+		if schedule.GetId() == id {
+			s.schedules = append(s.schedules[:i], s.schedules[i+1:]...)
+			return nil
+		}
+	}
+	return status.Errorf(codes.NotFound, "Schedule %s not found", id)
+}
+
+func (s *testSource) PauseSchedule(id string, paused bool) (*api.Schedule, error) {
+	for _, schedule := range s.schedules {
+		if schedule.GetId() == id {
+			schedule.Paused = paused
+			return schedule, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "Schedule %s not found", id)
+}
+
+func TestKvdbRegistrySource(t *testing.T) {
+	r := newTestRegistry(t, newTestKvdb(t), "node1", &testRunner{})
+	ctx := context.Background()
+
+	source := &testSource{
+		schedules: []*api.Schedule{{
+			Id:         "backup",
+			Type:       api.Job_CLOUD_BACKUP,
+			StartTime:  "daily=01:00",
+			CreateTime: prototime.Now(),
+		}},
+	}
+	assert.Error(t, r.RegisterSource(api.Job_DEFRAG, source))
+	require.NoError(t, r.RegisterSource(api.Job_CLOUD_BACKUP, source))
+	assert.Error(t, r.RegisterSource(api.Job_CLOUD_BACKUP, source))
+	assert.Error(t, r.RegisterRunner(api.Job_CLOUD_BACKUP, &testRunner{}))
+
+	// The schedules of a source are created by the source
+	_, err := r.CreateSchedule(&api.Schedule{
+		Type:      api.Job_CLOUD_BACKUP,
+		StartTime: "daily=01:00",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	s, err := r.CreateSchedule(&api.Schedule{
+		Type:      api.Job_DEFRAG,
+		StartTime: "daily=19:15",
+	})
+	require.NoError(t, err)
+
+	enumResp, err := r.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetSchedules(), 2)
+	assert.Equal(t, "backup", enumResp.GetSchedules()[0].GetId())
+	assert.Equal(t, s.GetId(), enumResp.GetSchedules()[1].GetId())
+	enumResp, err = r.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{Type: "CLOUD_BACKUP"})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetSchedules(), 1)
+
+	inspectResp, err := r.InspectSchedule(ctx, &api.SdkInspectScheduleRequest{Id: "backup", Type: "CLOUD_BACKUP"})
+	require.NoError(t, err)
+	assert.Equal(t, "daily=01:00", inspectResp.GetSchedule().GetStartTime())
+	_, err = r.InspectSchedule(ctx, &api.SdkInspectScheduleRequest{Id: "other", Type: "CLOUD_BACKUP"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	updateResp, err := r.UpdateSchedule(ctx, &api.SdkUpdateScheduleRequest{Id: "backup", Type: "CLOUD_BACKUP", Paused: true})
+	require.NoError(t, err)
+	assert.True(t, updateResp.GetSchedule().GetPaused())
+	assert.True(t, source.schedules[0].GetPaused())
+	_, err = r.UpdateSchedule(ctx, &api.SdkUpdateScheduleRequest{Id: "other", Type: "CLOUD_BACKUP", Paused: true})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// The schedules of the other types are listed when a source fails
+	source.err = fmt.Errorf("driver is down")
+	enumResp, err = r.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetSchedules(), 1)
+	assert.Equal(t, s.GetId(), enumResp.GetSchedules()[0].GetId())
+	source.err = nil
+
+	_, err = r.DeleteSchedule(ctx, &api.SdkDeleteScheduleRequest{Id: "backup", Type: "CLOUD_BACKUP"})
+	require.NoError(t, err)
+	assert.Empty(t, source.schedules)
+}
@@ -15,6 +15,8 @@ type Provider interface {
 	EnumerateSchedules(ctx context.Context, in *api.SdkEnumerateSchedulesRequest) (*api.SdkEnumerateSchedulesResponse, error)
 	// DeleteSchedule deletes a schedule by type and ID
 	DeleteSchedule(ctx context.Context, in *api.SdkDeleteScheduleRequest) (*api.SdkDeleteScheduleResponse, error)
+	// UpdateSchedule pauses or resumes a schedule by type and ID
+	UpdateSchedule(ctx context.Context, in *api.SdkUpdateScheduleRequest) (*api.SdkUpdateScheduleResponse, error)
 }
 
 // NewDefaultScheduleProvider does not support schedules
@@ -43,3 +45,9 @@ func (u *UnsupportedScheduleProvider) DeleteSchedule(
 ) (*api.SdkDeleteScheduleResponse, error) {
 	return nil, &errors.ErrNotSupported{}
 }
+
+func (u *UnsupportedScheduleProvider) UpdateSchedule(
+	ctx context.Context, in *api.SdkUpdateScheduleRequest,
+) (*api.SdkUpdateScheduleResponse, error) {
+	return nil, &errors.ErrNotSupported{}
+}
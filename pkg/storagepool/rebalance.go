@@ -0,0 +1,150 @@
+package storagepool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/schedule"
+)
+
+const (
+	// RebalanceScheduleID is the id of the rebalance schedule of the
+	// cluster in the schedule registry
+	RebalanceScheduleID = "rebalance"
+)
+
+// RebalanceScheduler is a storage pool provider which saves the rebalance
+// schedule of the cluster in the schedule registry, and runs it with the
+// Rebalance of the storage pool provider it wraps. The other storage pool
+// APIs are served by the wrapped provider.
+type RebalanceScheduler struct {
+	api.OpenStoragePoolServer
+	schedules *schedule.KvdbRegistry
+}
+
+// Check interfaces
+var _ api.OpenStoragePoolServer = &RebalanceScheduler{}
+var _ schedule.Runner = &RebalanceScheduler{}
+
+// NewRebalanceScheduler returns a storage pool provider which schedules the
+// rebalances of the provider with the schedule registry. It registers the
+// runner of the STORAGE_REBALANCE schedules with the registry.
+func NewRebalanceScheduler(
+	provider api.OpenStoragePoolServer,
+	schedules *schedule.KvdbRegistry,
+) (*RebalanceScheduler, error) {
+	if provider == nil {
+		return nil, fmt.Errorf("Must supply a storage pool provider for the rebalance scheduler")
+	} else if schedules == nil {
+		return nil, fmt.Errorf("Must supply a schedule registry for the rebalance scheduler")
+	}
+
+	s := &RebalanceScheduler{
+		OpenStoragePoolServer: provider,
+		schedules:             schedules,
+	}
+	if err := schedules.RegisterRunner(api.Job_STORAGE_REBALANCE, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func toRebalanceScheduleInfo(s *api.Schedule) *api.RebalanceScheduleInfo {
+	info := &api.RebalanceScheduleInfo{
+		Schedule:   s.GetStartTime(),
+		CreateTime: s.GetCreateTime(),
+	}
+	for _, task := range s.GetTasks() {
+		info.RebalanceRequests = append(info.RebalanceRequests, task.GetStorageRebalance())
+	}
+	return info
+}
+
+// CreateRebalanceSchedule creates the STORAGE_REBALANCE schedule of the
+// cluster, which runs the rebalance requests in order at the scheduled time
+func (s *RebalanceScheduler) CreateRebalanceSchedule(
+	ctx context.Context,
+	in *api.SdkCreateRebalanceScheduleRequest,
+) (*api.SdkCreateRebalanceScheduleResponse, error) {
+	if len(in.GetRebalanceRequests()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a rebalance request")
+	}
+
+	tasks := make([]*api.Job, 0, len(in.GetRebalanceRequests()))
+	for _, req := range in.GetRebalanceRequests() {
+		tasks = append(tasks, &api.Job{
+			Type: api.Job_STORAGE_REBALANCE,
+			Job: &api.Job_StorageRebalance{
+				StorageRebalance: req,
+			},
+		})
+	}
+	sched, err := s.schedules.CreateSchedule(&api.Schedule{
+		Id:        RebalanceScheduleID,
+		StartTime: in.GetSchedule(),
+		Type:      api.Job_STORAGE_REBALANCE,
+		Tasks:     tasks,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SdkCreateRebalanceScheduleResponse{
+		ScheduleInfo: toRebalanceScheduleInfo(sched),
+	}, nil
+}
+
+// GetRebalanceSchedule returns the rebalance schedule of the cluster
+func (s *RebalanceScheduler) GetRebalanceSchedule(
+	ctx context.Context,
+	in *api.SdkGetRebalanceScheduleRequest,
+) (*api.SdkGetRebalanceScheduleResponse, error) {
+	resp, err := s.schedules.InspectSchedule(ctx, &api.SdkInspectScheduleRequest{
+		Id:   RebalanceScheduleID,
+		Type: api.Job_STORAGE_REBALANCE.String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SdkGetRebalanceScheduleResponse{
+		ScheduleInfo: toRebalanceScheduleInfo(resp.GetSchedule()),
+	}, nil
+}
+
+// DeleteRebalanceSchedule deletes the rebalance schedule of the cluster
+func (s *RebalanceScheduler) DeleteRebalanceSchedule(
+	ctx context.Context,
+	in *api.SdkDeleteRebalanceScheduleRequest,
+) (*api.SdkDeleteRebalanceScheduleResponse, error) {
+	if _, err := s.schedules.DeleteSchedule(ctx, &api.SdkDeleteScheduleRequest{
+		Id:   RebalanceScheduleID,
+		Type: api.Job_STORAGE_REBALANCE.String(),
+	}); err != nil {
+		return nil, err
+	}
+	return &api.SdkDeleteRebalanceScheduleResponse{}, nil
+}
+
+// RunSchedule starts the rebalances of a STORAGE_REBALANCE schedule in order
+// with the wrapped provider. The rebalances run as jobs of the provider.
+func (s *RebalanceScheduler) RunSchedule(ctx context.Context, sched *api.Schedule) error {
+	for _, task := range sched.GetTasks() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		resp, err := s.Rebalance(ctx, task.GetStorageRebalance())
+		if err != nil {
+			return fmt.Errorf("failed to start rebalance: %v", err)
+		}
+		if j := resp.GetJob(); j != nil {
+			logrus.Infof("Rebalance job %s is started by schedule %s", j.GetId(), sched.GetId())
+		}
+	}
+	return nil
+}
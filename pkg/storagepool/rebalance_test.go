@@ -0,0 +1,81 @@
+package storagepool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/schedule"
+)
+
+// testPoolProvider records the rebalances it starts
+type testPoolProvider struct {
+	UnsupportedPoolProvider
+	rebalances []*api.SdkStorageRebalanceRequest
+}
+
+func (p *testPoolProvider) Rebalance(
+	c context.Context, request *api.SdkStorageRebalanceRequest) (*api.SdkStorageRebalanceResponse, error) {
+	p.rebalances = append(p.rebalances, request)
+	return &api.SdkStorageRebalanceResponse{Job: &api.StorageRebalanceJob{Id: "job"}}, nil
+}
+
+func TestRebalanceScheduler(t *testing.T) {
+	kv, err := kvdb.New(mem.Name, "storagepool_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	schedules, err := schedule.NewKvdbRegistry(kv, "node1")
+	require.NoError(t, err)
+	provider := &testPoolProvider{}
+	s, err := NewRebalanceScheduler(provider, schedules)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	_, err = s.GetRebalanceSchedule(ctx, &api.SdkGetRebalanceScheduleRequest{})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = s.CreateRebalanceSchedule(ctx, &api.SdkCreateRebalanceScheduleRequest{
+		Schedule: "daily=01:00",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	req := &api.SdkCreateRebalanceScheduleRequest{
+		Schedule: "weekly=Sunday@01:00",
+		RebalanceRequests: []*api.SdkStorageRebalanceRequest{
+			{Mode: api.SdkStorageRebalanceRequest_STORAGE_REBALANCE},
+			{Mode: api.SdkStorageRebalanceRequest_POOL_DRAIN},
+		},
+	}
+	createResp, err := s.CreateRebalanceSchedule(ctx, req)
+	require.NoError(t, err)
+	assert.Equal(t, "weekly=Sunday@01:00", createResp.GetScheduleInfo().GetSchedule())
+	assert.Len(t, createResp.GetScheduleInfo().GetRebalanceRequests(), 2)
+	_, err = s.CreateRebalanceSchedule(ctx, req)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	getResp, err := s.GetRebalanceSchedule(ctx, &api.SdkGetRebalanceScheduleRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, createResp.GetScheduleInfo().GetCreateTime().AsTime(),
+		getResp.GetScheduleInfo().GetCreateTime().AsTime())
+
+	// The schedule is listed by the schedule registry
+	enumResp, err := schedules.EnumerateSchedules(ctx, &api.SdkEnumerateSchedulesRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetSchedules(), 1)
+	sched := enumResp.GetSchedules()[0]
+	assert.Equal(t, api.Job_STORAGE_REBALANCE, sched.GetType())
+
+	require.NoError(t, s.RunSchedule(ctx, sched))
+	require.Len(t, provider.rebalances, 2)
+	assert.Equal(t, api.SdkStorageRebalanceRequest_POOL_DRAIN, provider.rebalances[1].GetMode())
+
+	_, err = s.DeleteRebalanceSchedule(ctx, &api.SdkDeleteRebalanceScheduleRequest{})
+	require.NoError(t, err)
+	_, err = s.GetRebalanceSchedule(ctx, &api.SdkGetRebalanceScheduleRequest{})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
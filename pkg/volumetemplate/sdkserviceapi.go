@@ -0,0 +1,391 @@
+/*
+Package volumetemplate manages the named and versioned volume templates
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package volumetemplate
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/auth"
+	"github.com/libopenstorage/openstorage/pkg/proto/time"
+)
+
+const (
+	templatePrefix = "cluster/volumetemplates"
+	// lastVersionPrefix keeps the last version of each template name. It is
+	// kept when the template is deleted, so that the versions of a name are
+	// never reused.
+	lastVersionPrefix = "cluster/volumetemplate_versions"
+)
+
+// SdkVolumeTemplateManager is an implementation of the SDK volume template
+// service which saves each version of the templates in Kvdb
+type SdkVolumeTemplateManager struct {
+	kv kvdb.Kvdb
+}
+
+// Check interface
+var _ api.OpenStorageVolumeTemplateServer = &SdkVolumeTemplateManager{}
+
+// NewSdkVolumeTemplateManager returns a new SDK volume template manager
+func NewSdkVolumeTemplateManager(kv kvdb.Kvdb) (*SdkVolumeTemplateManager, error) {
+	if kv == nil {
+		return nil, fmt.Errorf("Must supply a kvdb instance for the volume template manager")
+	}
+	return &SdkVolumeTemplateManager{
+		kv: kv,
+	}, nil
+}
+
+// Simple functions which create the keys for Kvdb. All the versions of a
+// template are saved under the same prefix.
+func versionsPrefix(name string) string {
+	return templatePrefix + "/" + url.PathEscape(name) + "/"
+}
+
+func versionKey(name string, version uint64) string {
+	return versionsPrefix(name) + strconv.FormatUint(version, 10)
+}
+
+func lastVersionKey(name string) string {
+	return lastVersionPrefix + "/" + url.PathEscape(name)
+}
+
+// newVersion returns a copy of the template to save as a new version
+func newVersion(
+	name string,
+	version uint64,
+	spec *api.VolumeSpec,
+	labels map[string]string,
+	ownership *api.Ownership,
+) *api.SdkVolumeTemplate {
+	spec = proto.Clone(spec).(*api.VolumeSpec)
+	// The template of a volume is set when the volume is created from it
+	spec.Template = ""
+	spec.TemplateVersion = 0
+
+	return &api.SdkVolumeTemplate{
+		Name:      name,
+		Version:   version,
+		Spec:      spec,
+		Labels:    labels,
+		Ownership: ownership,
+		Created:   prototime.Now(),
+	}
+}
+
+// nextVersion reserves the version after the latest version of a template,
+// or after the last version of a template of the same name which was deleted.
+// It returns kvdb.ErrModified if another version was reserved at the same
+// time.
+func (m *SdkVolumeTemplateManager) nextVersion(name string, latest uint64) (uint64, error) {
+	key := lastVersionKey(name)
+	kvp, err := m.kv.Get(key)
+	if err == kvdb.ErrNotFound {
+		// The templates saved before the last versions were kept have none
+		next := strconv.FormatUint(latest+1, 10)
+		if _, err := m.kv.Create(key, next, 0); err == kvdb.ErrExist {
+			return 0, kvdb.ErrModified
+		} else if err != nil {
+			return 0, err
+		}
+		return latest + 1, nil
+	} else if err != nil {
+		return 0, err
+	}
+
+	last, err := strconv.ParseUint(string(kvp.Value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid last version %q of volume template %s: %v", kvp.Value, name, err)
+	}
+	if last < latest {
+		last = latest
+	}
+	kvp.Value = []byte(strconv.FormatUint(last+1, 10))
+	if _, err := m.kv.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil); err == kvdb.ErrValueMismatch {
+		return 0, kvdb.ErrModified
+	} else if err != nil {
+		return 0, err
+	}
+	return last + 1, nil
+}
+
+// put saves a new version of a template. It returns kvdb.ErrExist if the
+// version has already been saved.
+func (m *SdkVolumeTemplateManager) put(t *api.SdkVolumeTemplate) error {
+	// VolumeSpec has oneof fields, so it is marshalled with jsonpb
+	marshaler := jsonpb.Marshaler{OrigName: true}
+	value, err := marshaler.MarshalToString(t)
+	if err != nil {
+		return err
+	}
+	_, err = m.kv.Create(versionKey(t.GetName(), t.GetVersion()), value, 0)
+	return err
+}
+
+func unmarshalTemplate(kvp *kvdb.KVPair) (*api.SdkVolumeTemplate, error) {
+	t := &api.SdkVolumeTemplate{}
+	if err := jsonpb.UnmarshalString(string(kvp.Value), t); err != nil {
+		return nil, status.Errorf(codes.Internal, "Json Unmarshal failed for volume template %s: %v", kvp.Key, err)
+	}
+	return t, nil
+}
+
+// latest returns the latest version of a template
+func (m *SdkVolumeTemplateManager) latest(name string) (*api.SdkVolumeTemplate, error) {
+	kvps, err := m.kv.Enumerate(versionsPrefix(name))
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get volume template %s: %v", name, err)
+	}
+
+	var latest *api.SdkVolumeTemplate
+	for _, kvp := range kvps {
+		t, err := unmarshalTemplate(kvp)
+		if err != nil {
+			return nil, err
+		}
+		if latest == nil || t.GetVersion() > latest.GetVersion() {
+			latest = t
+		}
+	}
+	if latest == nil {
+		return nil, status.Errorf(codes.NotFound, "Volume template %s not found", name)
+	}
+	return latest, nil
+}
+
+// Create creates the first version of a volume template
+func (m *SdkVolumeTemplateManager) Create(
+	ctx context.Context,
+	req *api.SdkVolumeTemplateCreateRequest,
+) (*api.SdkVolumeTemplateCreateResponse, error) {
+	if len(req.GetTemplate().GetName()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a volume template name")
+	} else if req.GetTemplate().GetSpec() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a volume spec")
+	}
+
+	name := req.GetTemplate().GetName()
+	if _, err := m.latest(name); err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "Volume template %s already exists", name)
+	} else if status.Code(err) != codes.NotFound {
+		return nil, err
+	}
+	version, err := m.nextVersion(name, 0)
+	if err == kvdb.ErrModified {
+		return nil, status.Errorf(codes.Aborted,
+			"Volume template %s was changed at the same time, try again", name)
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to save volume template %s: %v", name, err)
+	}
+
+	// The user creating the template owns it
+	t := newVersion(
+		name,
+		version,
+		req.GetTemplate().GetSpec(),
+		req.GetTemplate().GetLabels(),
+		api.OwnershipSetUsernameFromContext(ctx, req.GetTemplate().GetOwnership()),
+	)
+	if err := m.put(t); err == kvdb.ErrExist {
+		return nil, status.Errorf(codes.AlreadyExists, "Volume template %s already exists", t.GetName())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to save volume template %s: %v", t.GetName(), err)
+	}
+
+	logrus.Infof("Volume template %s is created", t.GetName())
+	return &api.SdkVolumeTemplateCreateResponse{
+		Template: t,
+	}, nil
+}
+
+// Update creates a new version of a volume template
+func (m *SdkVolumeTemplateManager) Update(
+	ctx context.Context,
+	req *api.SdkVolumeTemplateUpdateRequest,
+) (*api.SdkVolumeTemplateUpdateResponse, error) {
+	if len(req.GetName()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a volume template name")
+	} else if req.GetSpec() == nil {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a volume spec")
+	}
+
+	latest, err := m.latest(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if !latest.IsPermitted(ctx, api.Ownership_Write) {
+		return nil, status.Errorf(codes.PermissionDenied, "Cannot update volume template %s", req.GetName())
+	}
+
+	ownership := latest.GetOwnership()
+	if req.GetOwnership() != nil {
+		if ownership == nil {
+			ownership = &api.Ownership{}
+		} else {
+			ownership = proto.Clone(ownership).(*api.Ownership)
+		}
+		user, _ := auth.NewUserInfoFromContext(ctx)
+		if err := ownership.Update(req.GetOwnership(), user); err != nil {
+			return nil, err
+		}
+	}
+
+	version, err := m.nextVersion(req.GetName(), latest.GetVersion())
+	if err == kvdb.ErrModified {
+		return nil, status.Errorf(codes.Aborted,
+			"Volume template %s was updated at the same time, try again", req.GetName())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to save volume template %s: %v", req.GetName(), err)
+	}
+
+	t := newVersion(req.GetName(), version, req.GetSpec(), req.GetLabels(), ownership)
+	if err := m.put(t); err == kvdb.ErrExist {
+		return nil, status.Errorf(codes.Aborted,
+			"Volume template %s was updated at the same time, try again", req.GetName())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to save volume template %s: %v", req.GetName(), err)
+	}
+
+	logrus.Infof("Volume template %s is updated to version %d", t.GetName(), t.GetVersion())
+	return &api.SdkVolumeTemplateUpdateResponse{
+		Template: t,
+	}, nil
+}
+
+// Inspect returns a version of a volume template. The access is checked with
+// the ownership of the latest version.
+func (m *SdkVolumeTemplateManager) Inspect(
+	ctx context.Context,
+	req *api.SdkVolumeTemplateInspectRequest,
+) (*api.SdkVolumeTemplateInspectResponse, error) {
+	if len(req.GetName()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a volume template name")
+	}
+
+	latest, err := m.latest(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if !latest.IsPermitted(ctx, api.Ownership_Read) {
+		return nil, status.Errorf(codes.PermissionDenied, "Access denied to volume template %s", req.GetName())
+	}
+	if req.GetVersion() == 0 || req.GetVersion() == latest.GetVersion() {
+		return &api.SdkVolumeTemplateInspectResponse{
+			Template: latest,
+		}, nil
+	}
+
+	kvp, err := m.kv.Get(versionKey(req.GetName(), req.GetVersion()))
+	if err == kvdb.ErrNotFound {
+		return nil, status.Errorf(codes.NotFound,
+			"Version %d of volume template %s not found", req.GetVersion(), req.GetName())
+	} else if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get volume template %s: %v", req.GetName(), err)
+	}
+	t, err := unmarshalTemplate(kvp)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SdkVolumeTemplateInspectResponse{
+		Template: t,
+	}, nil
+}
+
+// Enumerate returns the latest version of the volume templates
+func (m *SdkVolumeTemplateManager) Enumerate(
+	ctx context.Context,
+	req *api.SdkVolumeTemplateEnumerateRequest,
+) (*api.SdkVolumeTemplateEnumerateResponse, error) {
+	kvps, err := m.kv.Enumerate(templatePrefix)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get volume templates from database: %v", err)
+	}
+
+	latest := make(map[string]*api.SdkVolumeTemplate)
+	for _, kvp := range kvps {
+		// Skip the keys which are not versions of a template
+		if strings.Count(strings.TrimPrefix(kvp.Key, templatePrefix+"/"), "/") != 1 {
+			continue
+		}
+		t, err := unmarshalTemplate(kvp)
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := latest[t.GetName()]; !ok || t.GetVersion() > l.GetVersion() {
+			latest[t.GetName()] = t
+		}
+	}
+
+	templates := make([]*api.SdkVolumeTemplate, 0, len(latest))
+	for _, t := range latest {
+		// Only return the templates the user has read access to
+		if t.IsPermitted(ctx, api.Ownership_Read) {
+			templates = append(templates, t)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].GetName() < templates[j].GetName()
+	})
+
+	return &api.SdkVolumeTemplateEnumerateResponse{
+		Templates: templates,
+	}, nil
+}
+
+// Delete deletes all the versions of a volume template. The last version is
+// kept, so that a template created again with the same name starts after it.
+func (m *SdkVolumeTemplateManager) Delete(
+	ctx context.Context,
+	req *api.SdkVolumeTemplateDeleteRequest,
+) (*api.SdkVolumeTemplateDeleteResponse, error) {
+	if len(req.GetName()) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Must supply a volume template name")
+	}
+
+	latest, err := m.latest(req.GetName())
+	if status.Code(err) == codes.NotFound {
+		return &api.SdkVolumeTemplateDeleteResponse{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// Only the owner or the admin can delete
+	if !latest.IsPermitted(ctx, api.Ownership_Admin) {
+		return nil, status.Errorf(codes.PermissionDenied, "Cannot delete volume template %s", req.GetName())
+	}
+
+	if err := m.kv.DeleteTree(versionsPrefix(req.GetName())); err != nil && err != kvdb.ErrNotFound {
+		return nil, status.Errorf(codes.Internal, "Failed to delete volume template %s: %v", req.GetName(), err)
+	}
+
+	logrus.Infof("Volume template %s is deleted", req.GetName())
+	return &api.SdkVolumeTemplateDeleteResponse{}, nil
+}
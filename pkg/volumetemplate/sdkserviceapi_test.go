@@ -0,0 +1,208 @@
+/*
+Package volumetemplate manages the named and versioned volume templates
+Copyright 2026 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package volumetemplate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/portworx/kvdb"
+	"github.com/portworx/kvdb/mem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/auth"
+)
+
+func newTestManager(t *testing.T) *SdkVolumeTemplateManager {
+	kv, err := kvdb.New(mem.Name, "volumetemplate_test", []string{}, nil, kvdb.LogFatalErrorCB)
+	require.NoError(t, err)
+	m, err := NewSdkVolumeTemplateManager(kv)
+	require.NoError(t, err)
+	return m
+}
+
+func userContext(name string) context.Context {
+	return auth.ContextSaveUserInfo(context.Background(), &auth.UserInfo{
+		Username: name,
+		Claims: auth.Claims{
+			Roles: []string{"system.user"},
+		},
+	})
+}
+
+func TestVolumeTemplateVersions(t *testing.T) {
+	m := newTestManager(t)
+	ctx := context.Background()
+
+	// Bad arguments
+	_, err := m.Create(ctx, &api.SdkVolumeTemplateCreateRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = m.Create(ctx, &api.SdkVolumeTemplateCreateRequest{
+		Template: &api.SdkVolumeTemplate{Name: "db"},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = m.Update(ctx, &api.SdkVolumeTemplateUpdateRequest{
+		Name: "db",
+		Spec: &api.VolumeSpec{},
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	createResp, err := m.Create(ctx, &api.SdkVolumeTemplateCreateRequest{
+		Template: &api.SdkVolumeTemplate{
+			Name:    "db",
+			Version: 10,
+			Spec: &api.VolumeSpec{
+				Size:     100,
+				HaLevel:  2,
+				Template: "other",
+			},
+			Labels: map[string]string{"app": "db"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), createResp.GetTemplate().GetVersion())
+	assert.Empty(t, createResp.GetTemplate().GetSpec().GetTemplate())
+	assert.NotNil(t, createResp.GetTemplate().GetCreated())
+
+	_, err = m.Create(ctx, &api.SdkVolumeTemplateCreateRequest{
+		Template: &api.SdkVolumeTemplate{Name: "db", Spec: &api.VolumeSpec{}},
+	})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	updateResp, err := m.Update(ctx, &api.SdkVolumeTemplateUpdateRequest{
+		Name: "db",
+		Spec: &api.VolumeSpec{Size: 200, HaLevel: 3},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), updateResp.GetTemplate().GetVersion())
+
+	// Latest version
+	resp, err := m.Inspect(ctx, &api.SdkVolumeTemplateInspectRequest{Name: "db"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), resp.GetTemplate().GetVersion())
+	assert.Equal(t, uint64(200), resp.GetTemplate().GetSpec().GetSize())
+	assert.Empty(t, resp.GetTemplate().GetLabels())
+
+	// Previous version
+	resp, err = m.Inspect(ctx, &api.SdkVolumeTemplateInspectRequest{Name: "db", Version: 1})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), resp.GetTemplate().GetSpec().GetSize())
+	assert.Equal(t, "db", resp.GetTemplate().GetLabels()["app"])
+
+	_, err = m.Inspect(ctx, &api.SdkVolumeTemplateInspectRequest{Name: "db", Version: 3})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// A template with a name sharing the same prefix
+	_, err = m.Create(ctx, &api.SdkVolumeTemplateCreateRequest{
+		Template: &api.SdkVolumeTemplate{Name: "db/large", Spec: &api.VolumeSpec{Size: 1000}},
+	})
+	require.NoError(t, err)
+
+	enumResp, err := m.Enumerate(ctx, &api.SdkVolumeTemplateEnumerateRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetTemplates(), 2)
+	assert.Equal(t, "db", enumResp.GetTemplates()[0].GetName())
+	assert.Equal(t, uint64(2), enumResp.GetTemplates()[0].GetVersion())
+	assert.Equal(t, "db/large", enumResp.GetTemplates()[1].GetName())
+
+	// All the versions are deleted
+	_, err = m.Delete(ctx, &api.SdkVolumeTemplateDeleteRequest{Name: "db"})
+	require.NoError(t, err)
+	_, err = m.Inspect(ctx, &api.SdkVolumeTemplateInspectRequest{Name: "db", Version: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = m.Delete(ctx, &api.SdkVolumeTemplateDeleteRequest{Name: "db"})
+	assert.NoError(t, err)
+
+	enumResp, err = m.Enumerate(ctx, &api.SdkVolumeTemplateEnumerateRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetTemplates(), 1)
+	assert.Equal(t, "db/large", enumResp.GetTemplates()[0].GetName())
+
+	// The versions of a deleted template are not reused
+	createResp, err = m.Create(ctx, &api.SdkVolumeTemplateCreateRequest{
+		Template: &api.SdkVolumeTemplate{Name: "db", Spec: &api.VolumeSpec{Size: 300}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), createResp.GetTemplate().GetVersion())
+	_, err = m.Inspect(ctx, &api.SdkVolumeTemplateInspectRequest{Name: "db", Version: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestVolumeTemplateOwnership(t *testing.T) {
+	m := newTestManager(t)
+	user1 := userContext("user1")
+	user2 := userContext("user2")
+
+	_, err := m.Create(user1, &api.SdkVolumeTemplateCreateRequest{
+		Template: &api.SdkVolumeTemplate{
+			Name: "private",
+			Spec: &api.VolumeSpec{Size: 100},
+		},
+	})
+	require.NoError(t, err)
+	_, err = m.Create(user1, &api.SdkVolumeTemplateCreateRequest{
+		Template: &api.SdkVolumeTemplate{
+			Name: "shared",
+			Spec: &api.VolumeSpec{Size: 100},
+			Ownership: &api.Ownership{
+				Acls: &api.Ownership_AccessControl{
+					Collaborators: map[string]api.Ownership_AccessType{
+						"user2": api.Ownership_Read,
+					},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+
+	resp, err := m.Inspect(user1, &api.SdkVolumeTemplateInspectRequest{Name: "private"})
+	require.NoError(t, err)
+	assert.Equal(t, "user1", resp.GetTemplate().GetOwnership().GetOwner())
+
+	_, err = m.Inspect(user2, &api.SdkVolumeTemplateInspectRequest{Name: "private"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = m.Inspect(user2, &api.SdkVolumeTemplateInspectRequest{Name: "shared"})
+	assert.NoError(t, err)
+
+	enumResp, err := m.Enumerate(user2, &api.SdkVolumeTemplateEnumerateRequest{})
+	require.NoError(t, err)
+	require.Len(t, enumResp.GetTemplates(), 1)
+	assert.Equal(t, "shared", enumResp.GetTemplates()[0].GetName())
+
+	// Read access is not enough to update or delete
+	_, err = m.Update(user2, &api.SdkVolumeTemplateUpdateRequest{
+		Name: "shared",
+		Spec: &api.VolumeSpec{Size: 200},
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = m.Delete(user2, &api.SdkVolumeTemplateDeleteRequest{Name: "shared"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// The ownership is kept by the new versions
+	updateResp, err := m.Update(user1, &api.SdkVolumeTemplateUpdateRequest{
+		Name: "shared",
+		Spec: &api.VolumeSpec{Size: 200},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "user1", updateResp.GetTemplate().GetOwnership().GetOwner())
+	assert.Equal(t, api.Ownership_Read,
+		updateResp.GetTemplate().GetOwnership().GetAcls().GetCollaborators()["user2"])
+}
@@ -0,0 +1,165 @@
+package btrfs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	streamSuffix = ".send"
+	metaSuffix   = ".json"
+)
+
+// backupMeta describes a cloud backup. It is stored in the target next to
+// the send stream of the backup, once the stream is complete.
+type backupMeta struct {
+	// ID of the backup, <volume id>/<snapshot name>.
+	ID         string
+	VolumeID   string
+	VolumeName string
+	// Parent is the ID of the backup this one is incremental to. Full
+	// backups have none.
+	Parent    string
+	Timestamp time.Time
+	// Size of the send stream.
+	Size   uint64
+	Spec   *api.VolumeSpec
+	Labels map[string]string
+}
+
+func backupID(volumeID, snapshot string) string {
+	return volumeID + "/" + snapshot
+}
+
+// snapshot returns the name of the snapshot the backup was sent from, which
+// is also the name of the subvolume receiving it creates.
+func (m *backupMeta) snapshot() string {
+	return path.Base(m.ID)
+}
+
+func (m *backupMeta) info() api.CloudBackupInfo {
+	return api.CloudBackupInfo{
+		ID:            m.ID,
+		SrcVolumeID:   m.VolumeID,
+		SrcVolumeName: m.VolumeName,
+		Timestamp:     m.Timestamp,
+		Metadata:      m.Labels,
+		Status:        string(api.CloudBackupStatusDone),
+	}
+}
+
+func putMeta(t ObjectTarget, m *backupMeta) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	_, err = t.Put(m.ID+metaSuffix, bytes.NewReader(data))
+	return err
+}
+
+func getMeta(t ObjectTarget, id string) (*backupMeta, error) {
+	r, err := t.Get(id + metaSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("Backup %s not found: %v", id, err)
+	}
+	defer r.Close()
+	var m backupMeta
+	if err := json.NewDecoder(r).Decode(&m); err != nil {
+		return nil, fmt.Errorf("Invalid metadata of backup %s: %v", id, err)
+	}
+	return &m, nil
+}
+
+// listMeta returns the backups of the volume, or of all volumes if volumeID
+// is empty, oldest first.
+func listMeta(t ObjectTarget, volumeID string) ([]*backupMeta, error) {
+	prefix := ""
+	if volumeID != "" {
+		prefix = volumeID + "/"
+	}
+	names, err := t.List(prefix)
+	if err != nil {
+		return nil, err
+	}
+	metas := make([]*backupMeta, 0, len(names))
+	for _, name := range names {
+		if !strings.HasSuffix(name, metaSuffix) {
+			continue
+		}
+		m, err := getMeta(t, strings.TrimSuffix(name, metaSuffix))
+		if err != nil {
+			return nil, err
+		}
+		metas = append(metas, m)
+	}
+	sort.SliceStable(metas, func(i, j int) bool {
+		return metas[i].Timestamp.Before(metas[j].Timestamp)
+	})
+	return metas, nil
+}
+
+// backupChain returns the backups to receive to restore backup id, from its
+// full backup to id itself.
+func backupChain(t ObjectTarget, id string) ([]*backupMeta, error) {
+	var chain []*backupMeta
+	seen := make(map[string]bool)
+	for id != "" {
+		if seen[id] {
+			return nil, fmt.Errorf("Backup %s is its own ancestor", id)
+		}
+		seen[id] = true
+		m, err := getMeta(t, id)
+		if err != nil {
+			return nil, err
+		}
+		chain = append([]*backupMeta{m}, chain...)
+		id = m.Parent
+	}
+	return chain, nil
+}
+
+// incrementalsSince returns how many incremental backups were taken since
+// the last full backup in metas, which are oldest first.
+func incrementalsSince(metas []*backupMeta) int {
+	n := 0
+	for i := len(metas) - 1; i >= 0 && metas[i].Parent != ""; i-- {
+		n++
+	}
+	return n
+}
+
+// deleteBackup removes the backup from the target. Backups that others are
+// incremental to are only removed with force, along with their dependents.
+func deleteBackup(t ObjectTarget, id string, force bool) error {
+	m, err := getMeta(t, id)
+	if err != nil {
+		return err
+	}
+	metas, err := listMeta(t, m.VolumeID)
+	if err != nil {
+		return err
+	}
+	for _, other := range metas {
+		if other.Parent != id {
+			continue
+		}
+		if !force {
+			return fmt.Errorf("Backup %s is needed to restore backup %s", id, other.ID)
+		}
+		if err := deleteBackup(t, other.ID, force); err != nil {
+			return err
+		}
+	}
+	// Remove the metadata first so a partially deleted backup is not listed.
+	if err := t.Delete(id + metaSuffix); err != nil {
+		return err
+	}
+	return t.Delete(id + streamSuffix)
+}
@@ -0,0 +1,162 @@
+package btrfs
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+func TestParseQgroupShow(t *testing.T) {
+	out := `qgroupid         rfer         excl
+--------         ----         ----
+0/5             16384        16384
+0/257         1048576       65536
+0/2570           4096         4096
+`
+	rfer, excl, err := parseQgroupShow(out, 257)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1048576), rfer)
+	assert.Equal(t, uint64(65536), excl)
+
+	_, _, err = parseQgroupShow(out, 258)
+	assert.Error(t, err)
+
+	_, _, err = parseQgroupShow("0/257 1M 64K\n", 257)
+	assert.Error(t, err)
+}
+
+func TestDirTarget(t *testing.T) {
+	target, err := NewDirTarget(t.TempDir())
+	require.NoError(t, err)
+	defer target.Close()
+
+	n, err := target.Put("vol/a.send", strings.NewReader("stream"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), n)
+	_, err = target.Put("vol/a.json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	_, err = target.Put("other/b.send", strings.NewReader(""))
+	require.NoError(t, err)
+
+	r, err := target.Get("vol/a.send")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	r.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "stream", string(data))
+
+	names, err := target.List("vol/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vol/a.json", "vol/a.send"}, names)
+	names, err = target.List("")
+	require.NoError(t, err)
+	assert.Len(t, names, 3)
+
+	require.NoError(t, target.Delete("vol/a.send"))
+	require.NoError(t, target.Delete("vol/a.send"))
+	_, err = target.Get("vol/a.send")
+	assert.Error(t, err)
+}
+
+func TestNewObjectTarget(t *testing.T) {
+	_, err := newObjectTarget(map[string]string{api.OptCredType: "unknown"})
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	RegisterObjectTarget("dir", func(params map[string]string) (ObjectTarget, error) {
+		return NewDirTarget(dir)
+	})
+	target, err := newObjectTarget(map[string]string{api.OptCredType: "dir"})
+	require.NoError(t, err)
+	assert.NoError(t, target.Close())
+}
+
+// putBackup stores a backup of vol with a stream and metadata, as
+// CloudBackupCreate does.
+func putBackup(t *testing.T, target ObjectTarget, vol, snapshot, parent string, ts time.Time) *backupMeta {
+	m := &backupMeta{
+		ID:        backupID(vol, snapshot),
+		VolumeID:  vol,
+		Parent:    parent,
+		Timestamp: ts,
+	}
+	_, err := target.Put(m.ID+streamSuffix, strings.NewReader(snapshot))
+	require.NoError(t, err)
+	require.NoError(t, putMeta(target, m))
+	return m
+}
+
+func TestBackupChain(t *testing.T) {
+	target, err := NewDirTarget(t.TempDir())
+	require.NoError(t, err)
+
+	now := time.Now()
+	full := putBackup(t, target, "vol", "s1", "", now)
+	inc1 := putBackup(t, target, "vol", "s2", full.ID, now.Add(time.Minute))
+	inc2 := putBackup(t, target, "vol", "s3", inc1.ID, now.Add(2*time.Minute))
+	putBackup(t, target, "other", "s1", "", now)
+
+	metas, err := listMeta(target, "vol")
+	require.NoError(t, err)
+	require.Len(t, metas, 3)
+	assert.Equal(t, []string{full.ID, inc1.ID, inc2.ID},
+		[]string{metas[0].ID, metas[1].ID, metas[2].ID})
+	assert.Equal(t, "s3", metas[2].snapshot())
+	assert.Equal(t, 2, incrementalsSince(metas))
+
+	all, err := listMeta(target, "")
+	require.NoError(t, err)
+	assert.Len(t, all, 4)
+
+	chain, err := backupChain(target, inc2.ID)
+	require.NoError(t, err)
+	require.Len(t, chain, 3)
+	assert.Equal(t, full.ID, chain[0].ID)
+	assert.Equal(t, inc2.ID, chain[2].ID)
+
+	full2 := putBackup(t, target, "vol", "s4", "", now.Add(3*time.Minute))
+	metas, err = listMeta(target, "vol")
+	require.NoError(t, err)
+	assert.Equal(t, 0, incrementalsSince(metas))
+	chain, err = backupChain(target, full2.ID)
+	require.NoError(t, err)
+	assert.Len(t, chain, 1)
+
+	_, err = backupChain(target, "vol/missing")
+	assert.Error(t, err)
+
+	putBackup(t, target, "loop", "a", "loop/b", now)
+	putBackup(t, target, "loop", "b", "loop/a", now)
+	_, err = backupChain(target, "loop/a")
+	assert.Error(t, err)
+}
+
+func TestDeleteBackup(t *testing.T) {
+	target, err := NewDirTarget(t.TempDir())
+	require.NoError(t, err)
+
+	now := time.Now()
+	full := putBackup(t, target, "vol", "s1", "", now)
+	inc1 := putBackup(t, target, "vol", "s2", full.ID, now.Add(time.Minute))
+	inc2 := putBackup(t, target, "vol", "s3", inc1.ID, now.Add(2*time.Minute))
+
+	// Backups others are incremental to need force.
+	assert.Error(t, deleteBackup(target, full.ID, false))
+	require.NoError(t, deleteBackup(target, inc2.ID, false))
+	names, err := target.List("vol/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"vol/s1.json", "vol/s1.send", "vol/s2.json", "vol/s2.send"}, names)
+
+	require.NoError(t, deleteBackup(target, full.ID, true))
+	names, err = target.List("vol/")
+	require.NoError(t, err)
+	assert.Empty(t, names)
+
+	assert.Error(t, deleteBackup(target, full.ID, true))
+}
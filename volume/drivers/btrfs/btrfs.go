@@ -6,19 +6,21 @@ package btrfs
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 
-	prototime "github.com/libopenstorage/openstorage/pkg/proto/time"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
 
-	"github.com/docker/docker/daemon/graphdriver"
-	"github.com/docker/docker/daemon/graphdriver/btrfs"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/pkg/chaos"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/common"
-	"github.com/pborman/uuid"
-	"github.com/portworx/kvdb"
 )
 
 const (
@@ -26,6 +28,13 @@ const (
 	Type      = api.DriverType_DRIVER_TYPE_FILE
 	RootParam = "home"
 	Volumes   = "volumes"
+	// Backups holds the read-only snapshots incremental cloud backups of
+	// each volume are sent relative to.
+	Backups = "backups"
+	// Restores holds the subvolumes received while restoring cloud backups.
+	Restores = "restores"
+
+	btrfsSuperMagic = 0x9123683E
 )
 
 var (
@@ -33,30 +42,65 @@ var (
 	koStrayDelete chaos.ID
 )
 
+// driver provisions volumes as btrfs subvolumes. Their size is enforced with
+// qgroups, and they are backed up to the cloud with btrfs send streams.
 type driver struct {
 	volume.StoreEnumerator
 	volume.IODriver
 	volume.BlockDriver
-	btrfs graphdriver.Driver
-	root  string
+	volume.StatsDriver
+	volume.QuiesceDriver
+	volume.CredsDriver
+	volume.CloudBackupDriver
+	volume.CloudMigrateDriver
+	volume.FilesystemTrimDriver
+	volume.FilesystemCheckDriver
+	volume.VerifyChecksumDriver
+	kv   kvdb.Kvdb
+	root string
+	// backupLocks serialize the cloud backups of each volume.
+	backupLocksLock sync.Mutex
+	backupLocks     map[string]*sync.Mutex
 }
 
+// Init initializes the driver on the btrfs filesystem mounted at the
+// RootParam directory, and enables quotas on it.
 func Init(params map[string]string) (volume.VolumeDriver, error) {
 	root, ok := params[RootParam]
 	if !ok {
 		return nil, fmt.Errorf("Root directory should be specified with key %q", RootParam)
 	}
-	home := filepath.Join(root, "volumes")
-	d, err := btrfs.Init(home, nil, nil, nil)
-	if err != nil {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(root, &st); err != nil {
 		return nil, err
 	}
+	if st.Type != btrfsSuperMagic {
+		return nil, fmt.Errorf("%s is not on a btrfs filesystem", root)
+	}
+	for _, dir := range []string{Volumes, Backups, Restores} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			return nil, err
+		}
+	}
+	if err := quotaEnable(root); err != nil {
+		return nil, err
+	}
+	kv := kvdb.Instance()
 	return &driver{
-		common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
-		common.IONotSupported,
-		common.BlockNotSupported,
-		d,
-		root,
+		StoreEnumerator:       common.NewDefaultStoreEnumerator(Name, kv),
+		IODriver:              volume.IONotSupported,
+		BlockDriver:           volume.BlockNotSupported,
+		StatsDriver:           volume.StatsNotSupported,
+		QuiesceDriver:         volume.QuiesceNotSupported,
+		CredsDriver:           common.NewDefaultCredsDriver(Name, kv),
+		CloudBackupDriver:     volume.CloudBackupNotSupported,
+		CloudMigrateDriver:    volume.CloudMigrateNotSupported,
+		FilesystemTrimDriver:  volume.FilesystemTrimNotSupported,
+		FilesystemCheckDriver: volume.FilesystemCheckNotSupported,
+		VerifyChecksumDriver:  volume.VerifyChecksumNotSupported,
+		kv:                    kv,
+		root:                  root,
+		backupLocks:           make(map[string]*sync.Mutex),
 	}, nil
 }
 
@@ -65,7 +109,7 @@ func (d *driver) Name() string {
 }
 
 func (d *driver) Status() [][2]string {
-	return d.btrfs.Status()
+	return [][2]string{}
 }
 
 func (d *driver) Type() api.DriverType {
@@ -79,7 +123,24 @@ func (d *driver) Version() (*api.StorageVersion, error) {
 	}, nil
 }
 
-// Create a new subvolume. The volume spec is not taken into account.
+func (d *driver) StartVolumeWatcher() {
+	return
+}
+
+func (d *driver) GetVolumeWatcher(locator *api.VolumeLocator, labels map[string]string) (chan *api.Volume, error) {
+	return nil, nil
+}
+
+func (d *driver) StopVolumeWatcher() {
+	return
+}
+
+func (d *driver) volumePath(volumeID string) string {
+	return filepath.Join(d.root, Volumes, volumeID)
+}
+
+// Create a new subvolume, or a snapshot of the source parent. Its size is
+// limited to the spec size with a qgroup.
 func (d *driver) Create(
 	ctx context.Context,
 	locator *api.VolumeLocator,
@@ -89,108 +150,275 @@ func (d *driver) Create(
 	if spec.Format != api.FSType_FS_TYPE_BTRFS && spec.Format != api.FSType_FS_TYPE_NONE {
 		return "", fmt.Errorf("Filesystem format (%v) must be %v", spec.Format.SimpleString(), api.FSType_FS_TYPE_BTRFS.SimpleString())
 	}
-	volume := common.NewVolume(
-		uuid.New(),
+	v := common.NewVolume(
+		strings.TrimSuffix(uuid.New(), "\n"),
 		api.FSType_FS_TYPE_BTRFS,
 		locator,
 		source,
 		spec,
 	)
-	if err := d.CreateVol(volume); err != nil {
+	p := d.volumePath(v.Id)
+	var err error
+	if parent := source.GetParent(); parent != "" {
+		err = subvolumeSnapshot(d.volumePath(parent), p, false)
+	} else {
+		err = subvolumeCreate(p)
+	}
+	if err != nil {
 		return "", err
 	}
-	if err := d.btrfs.Create(volume.Id, "", "", nil); err != nil {
+	if err := qgroupLimit(p, spec.Size); err != nil {
+		subvolumeDelete(p)
 		return "", err
 	}
-	devicePath, err := d.btrfs.Get(volume.Id, "")
-	if err != nil {
-		return volume.Id, err
+	v.DevicePath = p
+	if err := d.CreateVol(v); err != nil {
+		subvolumeDelete(p)
+		return "", err
 	}
-	volume.DevicePath = devicePath
-	return volume.Id, d.UpdateVol(volume)
+	return v.Id, nil
 }
 
+// Delete removes the subvolume of the volume and the local snapshots of its
+// cloud backups.
 func (d *driver) Delete(ctx context.Context, volumeID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) > 0 {
+		return volume.ErrVolAttached
+	}
 	if err := d.DeleteVol(volumeID); err != nil {
 		return err
 	}
 	chaos.Now(koStrayDelete)
-	return d.btrfs.Remove(volumeID)
+	if err := d.deleteBackupSnapshots(volumeID, ""); err != nil {
+		logrus.Warnf("Failed to delete backup snapshots of volume %v: %v", volumeID, err)
+	}
+	return subvolumeDelete(d.volumePath(volumeID))
 }
 
-func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string) error {
+func (d *driver) MountedAt(ctx context.Context, mountpath string) string {
+	return ""
+}
+
+// Mount bind mounts the subvolume at mountpath.
+func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
-	if err := syscall.Mount(v.DevicePath, mountpath, v.Format.SimpleString(), syscall.MS_BIND, ""); err != nil {
+	if len(v.AttachPath) > 0 && len(v.AttachPath[0]) > 0 {
+		return fmt.Errorf("Volume %q already mounted at %q", volumeID, v.AttachPath[0])
+	}
+	if v.Status == api.VolumeStatus_VOLUME_STATUS_DOWN {
+		return fmt.Errorf("Volume %q is being restored", volumeID)
+	}
+	if err := syscall.Mount(v.DevicePath, mountpath, "", syscall.MS_BIND, ""); err != nil {
 		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
 	}
-	v.AttachPath = mountpath
+	v.AttachPath = []string{mountpath}
 	return d.UpdateVol(v)
 }
 
-func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string) error {
+func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
-	if v.AttachPath == "" {
+	if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
 		return fmt.Errorf("Device %v not mounted", volumeID)
 	}
-	if err := syscall.Unmount(v.AttachPath, 0); err != nil {
+	if err := syscall.Unmount(v.AttachPath[0], 0); err != nil {
 		return err
 	}
-	v.AttachPath = ""
+	v.AttachPath = nil
 	return d.UpdateVol(v)
 }
 
+// Set updates the locator of the volume or its size, which is the qgroup
+// limit of its subvolume.
 func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	if spec != nil {
-		return volume.ErrNotSupported
-	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
+	if spec != nil {
+		if spec.Size == v.Spec.Size {
+			return volume.ErrNotSupported
+		}
+		if spec.Size != 0 {
+			used, _, err := qgroupUsage(v.DevicePath)
+			if err != nil {
+				return err
+			}
+			if spec.Size < used {
+				return fmt.Errorf("Cannot shrink volume %v to %v bytes, it uses %v bytes",
+					volumeID, spec.Size, used)
+			}
+		}
+		if err := qgroupLimit(v.DevicePath, spec.Size); err != nil {
+			return err
+		}
+		v.Spec.Size = spec.Size
+	}
 	if locator != nil {
 		v.Locator = locator
 	}
 	return d.UpdateVol(v)
 }
 
-// Snapshot create new subvolume from volume
+// Snapshot creates a snapshot subvolume of the volume.
 func (d *driver) Snapshot(ctx context.Context, volumeID string, readonly bool, locator *api.VolumeLocator, noRetry bool) (string, error) {
-	vols, err := d.Inspect([]string{volumeID})
+	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return "", err
 	}
-	if len(vols) != 1 {
-		return "", fmt.Errorf("Failed to inspect %v len %v", volumeID, len(vols))
-	}
-	snapID := uuid.New()
-	vols[0].Id = snapID
-	vols[0].Source = &api.Source{Parent: volumeID}
-	vols[0].Locator = locator
-	vols[0].Ctime = prototime.Now()
+	snap := common.NewVolume(
+		strings.TrimSuffix(uuid.New(), "\n"),
+		v.Format,
+		locator,
+		&api.Source{Parent: volumeID},
+		v.Spec,
+	)
+	snap.Readonly = readonly
+	snap.DevicePath = d.volumePath(snap.Id)
 
-	if err := d.CreateVol(vols[0]); err != nil {
+	if err := d.CreateVol(snap); err != nil {
 		return "", err
 	}
 	chaos.Now(koStrayCreate)
-	err = d.btrfs.Create(snapID, volumeID, "", nil)
-	if err != nil {
+	if err := subvolumeSnapshot(v.DevicePath, snap.DevicePath, readonly); err != nil {
+		d.DeleteVol(snap.Id)
 		return "", err
 	}
-	return vols[0].Id, nil
+	if err := qgroupLimit(snap.DevicePath, v.Spec.Size); err != nil {
+		logrus.Warnf("Failed to limit size of snapshot %v: %v", snap.Id, err)
+	}
+	return snap.Id, nil
 }
 
-func (d *driver) Stats(volumeID string) (*api.Stats, error) {
-	return nil, nil
+// Restore replaces the subvolume of the volume with a snapshot of snapID.
+// The volume must not be mounted.
+func (d *driver) Restore(volumeID string, snapID string) error {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	snap, err := d.GetVol(snapID)
+	if err != nil {
+		return err
+	}
+	if snap.GetSource().GetParent() != volumeID {
+		return fmt.Errorf("Volume %v is not a snapshot of volume %v", snapID, volumeID)
+	}
+	if len(v.AttachPath) > 0 {
+		return volume.ErrVolAttached
+	}
+	// Snapshot first, so that a failure leaves the volume untouched.
+	restored := v.DevicePath + ".restore"
+	if err := subvolumeSnapshot(snap.DevicePath, restored, false); err != nil {
+		return err
+	}
+	if err := subvolumeDelete(v.DevicePath); err != nil {
+		subvolumeDelete(restored)
+		return err
+	}
+	if err := os.Rename(restored, v.DevicePath); err != nil {
+		return err
+	}
+	return qgroupLimit(v.DevicePath, v.Spec.Size)
 }
 
-func (d *driver) Alerts(volumeID string) (*api.Alerts, error) {
-	return nil, nil
+func (d *driver) SnapshotGroup(groupID string, labels map[string]string, volumeIDs []string, deleteOnFailure bool) (*api.GroupSnapCreateResponse, error) {
+	return common.SnapshotGroup(
+		d,
+		groupID,
+		labels,
+		volumeIDs,
+		deleteOnFailure,
+		func(v *api.Volume) (string, error) {
+			locator := &api.VolumeLocator{
+				Name:         v.GetLocator().GetName() + "-" + groupID + "-snap",
+				VolumeLabels: labels,
+			}
+			return d.Snapshot(correlation.TODO(), v.Id, true, locator, false)
+		},
+		func(snapID string) error {
+			return d.Delete(correlation.TODO(), snapID)
+		},
+	)
+}
+
+// Stats reports the bytes referenced by the subvolume.
+func (d *driver) Stats(ctx context.Context, volumeID string, cumulative bool) (*api.Stats, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	referenced, _, err := qgroupUsage(v.DevicePath)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Stats{BytesUsed: referenced}, nil
+}
+
+// UsedSize returns the bytes referenced by the subvolume, which is what its
+// qgroup limit applies to.
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	referenced, _, err := qgroupUsage(v.DevicePath)
+	return referenced, err
+}
+
+// CapacityUsage returns the bytes referenced by the subvolume, split in
+// those only it references and those shared with its snapshots.
+func (d *driver) CapacityUsage(ID string) (*api.CapacityUsageResponse, error) {
+	v, err := d.GetVol(ID)
+	if err != nil {
+		return nil, err
+	}
+	referenced, exclusive, err := qgroupUsage(v.DevicePath)
+	if err != nil {
+		return nil, err
+	}
+	return &api.CapacityUsageResponse{
+		CapacityUsageInfo: &api.CapacityUsageInfo{
+			ExclusiveBytes: int64(exclusive),
+			SharedBytes:    int64(referenced - exclusive),
+			TotalBytes:     int64(referenced),
+		},
+	}, nil
+}
+
+// VolumeUsageByNode returns the referenced and exclusive bytes of the qgroup
+// of every subvolume. The subvolumes are on the btrfs filesystem of this
+// node, so they all belong to it.
+func (d *driver) VolumeUsageByNode(ctx context.Context, nodeID string) (*api.VolumeUsageByNode, error) {
+	vols, err := d.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.VolumeUsageByNode{
+		VolumeUsage: make([]*api.VolumeUsage, 0, len(vols)),
+	}
+	for _, v := range vols {
+		referenced, exclusive, err := qgroupUsage(v.DevicePath)
+		if err != nil {
+			return nil, err
+		}
+		resp.VolumeUsage = append(resp.VolumeUsage, &api.VolumeUsage{
+			VolumeId:       v.GetId(),
+			VolumeName:     v.GetLocator().GetName(),
+			ExclusiveBytes: exclusive,
+			TotalBytes:     referenced,
+		})
+	}
+	return resp, nil
 }
 
 func (d *driver) Shutdown() {}
@@ -198,3 +426,7 @@ func (d *driver) Shutdown() {}
 func (d *driver) Catalog(volumeID, path, depth string) (api.CatalogResponse, error) {
 	return api.CatalogResponse{}, volume.ErrNotSupported
 }
+
+func (d *driver) VolService(volumeID string, vtreq *api.VolumeServiceRequest) (*api.VolumeServiceResponse, error) {
+	return nil, volume.ErrNotSupported
+}
@@ -0,0 +1,500 @@
+//go:build linux && have_btrfs
+// +build linux,have_btrfs
+
+package btrfs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/parser"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+)
+
+const (
+	cloudBackupsKeyPrefix = "/btrfs/cloudbackups"
+)
+
+// target returns the object target of the credential. Callers must close it.
+func (d *driver) target(credUUID string) (ObjectTarget, error) {
+	params, err := common.CredsParams(d, credUUID)
+	if err != nil {
+		return nil, err
+	}
+	return newObjectTarget(params)
+}
+
+// CredsValidate checks that the target of the credential can be reached.
+func (d *driver) CredsValidate(credUUID string) error {
+	t, err := d.target(credUUID)
+	if err != nil {
+		return err
+	}
+	return t.Close()
+}
+
+func (d *driver) backupSnapshotPath(volumeID, snapshot string) string {
+	return filepath.Join(d.root, Backups, volumeID, snapshot)
+}
+
+// deleteBackupSnapshots deletes the local snapshots cloud backups of the
+// volume were sent from, except keep.
+func (d *driver) deleteBackupSnapshots(volumeID, keep string) error {
+	dir := filepath.Join(d.root, Backups, volumeID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	for _, e := range entries {
+		if e.Name() == keep {
+			continue
+		}
+		if err := subvolumeDelete(filepath.Join(dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	if keep == "" {
+		return os.Remove(dir)
+	}
+	return nil
+}
+
+func (d *driver) putTask(name string, status *api.CloudBackupStatus) error {
+	_, err := d.kv.Put(cloudBackupsKeyPrefix+"/"+name, status, 0)
+	return err
+}
+
+// newTask records a task, failing if one with the same name exists.
+func (d *driver) newTask(name string, status *api.CloudBackupStatus) error {
+	if _, err := d.kv.Create(cloudBackupsKeyPrefix+"/"+name, status, 0); err != nil {
+		if err == kvdb.ErrExist {
+			return volume.ErrExist
+		}
+		return err
+	}
+	return nil
+}
+
+// endTask records the outcome of a task.
+func (d *driver) endTask(name string, status *api.CloudBackupStatus, err error) {
+	status.CompletedTime = time.Now()
+	if err != nil {
+		logrus.Errorf("Cloud %s %s of volume %s failed: %v",
+			strings.ToLower(string(status.OpType)), name, status.SrcVolumeID, err)
+		status.Status = api.CloudBackupStatusFailed
+		status.Info = []string{err.Error()}
+	} else {
+		status.Status = api.CloudBackupStatusDone
+	}
+	if err := d.putTask(name, status); err != nil {
+		logrus.Errorf("Failed to record status of task %s: %v", name, err)
+	}
+}
+
+// backupLock returns the lock serializing the cloud backups of the volume.
+// Backups of a volume depend on the snapshot of the previous one, which
+// each backup deletes once it is sent.
+func (d *driver) backupLock(volumeID string) *sync.Mutex {
+	d.backupLocksLock.Lock()
+	defer d.backupLocksLock.Unlock()
+	l, ok := d.backupLocks[volumeID]
+	if !ok {
+		l = &sync.Mutex{}
+		d.backupLocks[volumeID] = l
+	}
+	return l
+}
+
+// CloudBackupCreate sends a read-only snapshot of the volume to the target
+// of the credential. The backup is incremental to the previous one unless a
+// full backup is requested, FullBackupFrequency incremental backups were
+// taken since the last full one, or the snapshot of the previous backup is
+// gone. Backups of a volume run one at a time, in the order they were
+// requested.
+func (d *driver) CloudBackupCreate(input *api.CloudBackupCreateRequest) (*api.CloudBackupCreateResponse, error) {
+	v, err := d.GetVol(input.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	t, err := d.target(input.CredentialUUID)
+	if err != nil {
+		return nil, err
+	}
+
+	name := input.Name
+	if name == "" {
+		name = uuid.New()
+	}
+	snapshot := uuid.New()
+	m := &backupMeta{
+		ID:         backupID(v.Id, snapshot),
+		VolumeID:   v.Id,
+		VolumeName: v.GetLocator().GetName(),
+		Spec:       v.Spec,
+		Labels:     input.Labels,
+	}
+	status := &api.CloudBackupStatus{
+		ID:             m.ID,
+		OpType:         api.CloudBackupOp,
+		Status:         api.CloudBackupStatusActive,
+		StartTime:      time.Now(),
+		SrcVolumeID:    v.Id,
+		CredentialUUID: input.CredentialUUID,
+	}
+	if err := d.newTask(name, status); err != nil {
+		t.Close()
+		return nil, err
+	}
+
+	lock := d.backupLock(v.Id)
+	go func() {
+		defer t.Close()
+		lock.Lock()
+		defer lock.Unlock()
+		err := d.backup(t, v, m, input)
+		if err == nil {
+			status.BytesDone = m.Size
+			status.BytesTotal = m.Size
+		}
+		d.endTask(name, status, err)
+	}()
+	return &api.CloudBackupCreateResponse{Name: name}, nil
+}
+
+// backup snapshots the volume and sends the snapshot to the target as the
+// backup m.
+func (d *driver) backup(t ObjectTarget, v *api.Volume, m *backupMeta, input *api.CloudBackupCreateRequest) error {
+	metas, err := listMeta(t, v.Id)
+	if err != nil {
+		return err
+	}
+	full := input.Full ||
+		(input.FullBackupFrequency > 0 && uint32(incrementalsSince(metas)) >= input.FullBackupFrequency)
+	if len(metas) > 0 && !full {
+		last := metas[len(metas)-1]
+		if _, err := os.Stat(d.backupSnapshotPath(v.Id, last.snapshot())); err == nil {
+			m.Parent = last.ID
+		}
+	}
+
+	snapPath := d.backupSnapshotPath(v.Id, m.snapshot())
+	if err := os.MkdirAll(filepath.Dir(snapPath), 0755); err != nil {
+		return err
+	}
+	if err := subvolumeSnapshot(v.DevicePath, snapPath, true); err != nil {
+		return err
+	}
+	return d.sendBackup(t, m, input.DeleteLocal)
+}
+
+// sendBackup streams the snapshot of the backup to the target, then its
+// metadata. The snapshot is kept for the next incremental backup, unless
+// deleteLocal is set.
+func (d *driver) sendBackup(t ObjectTarget, m *backupMeta, deleteLocal bool) error {
+	snapPath := d.backupSnapshotPath(m.VolumeID, m.snapshot())
+	parent := ""
+	if m.Parent != "" {
+		parent = d.backupSnapshotPath(m.VolumeID, path.Base(m.Parent))
+	}
+
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(send(snapPath, parent, w))
+	}()
+	n, err := t.Put(m.ID+streamSuffix, r)
+	r.CloseWithError(err)
+	if err != nil {
+		subvolumeDelete(snapPath)
+		t.Delete(m.ID + streamSuffix)
+		return err
+	}
+	m.Size = uint64(n)
+	m.Timestamp = time.Now()
+	if err := putMeta(t, m); err != nil {
+		subvolumeDelete(snapPath)
+		t.Delete(m.ID + streamSuffix)
+		return err
+	}
+
+	keep := m.snapshot()
+	if deleteLocal {
+		keep = ""
+	}
+	return d.deleteBackupSnapshots(m.VolumeID, keep)
+}
+
+// CloudBackupRestore receives the chain of send streams of the backup and
+// creates a new volume from the result. The volume is down until the
+// restore is done, and deleted if the restore fails.
+func (d *driver) CloudBackupRestore(input *api.CloudBackupRestoreRequest) (*api.CloudBackupRestoreResponse, error) {
+	t, err := d.target(input.CredentialUUID)
+	if err != nil {
+		return nil, err
+	}
+	chain, err := backupChain(t, input.ID)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	last := chain[len(chain)-1]
+
+	locator := input.Locator
+	if locator == nil {
+		locator = &api.VolumeLocator{}
+	}
+	if input.RestoreVolumeName != "" {
+		locator.Name = input.RestoreVolumeName
+	}
+	if locator.Name == "" {
+		locator.Name = last.VolumeName + "-restore-" + last.snapshot()
+	}
+	spec := last.Spec
+	if spec == nil {
+		spec = &api.VolumeSpec{}
+	}
+	v := common.NewVolume(
+		strings.TrimSuffix(uuid.New(), "\n"),
+		api.FSType_FS_TYPE_BTRFS,
+		locator,
+		&api.Source{},
+		spec,
+	)
+	v.DevicePath = d.volumePath(v.Id)
+	v.Status = api.VolumeStatus_VOLUME_STATUS_DOWN
+
+	name := input.Name
+	if name == "" {
+		name = uuid.New()
+	}
+	status := &api.CloudBackupStatus{
+		ID:             input.ID,
+		OpType:         api.CloudRestoreOp,
+		Status:         api.CloudBackupStatusActive,
+		StartTime:      time.Now(),
+		SrcVolumeID:    v.Id,
+		CredentialUUID: input.CredentialUUID,
+	}
+	for _, m := range chain {
+		status.BytesTotal += m.Size
+	}
+	if err := d.newTask(name, status); err != nil {
+		t.Close()
+		return nil, err
+	}
+	if err := d.CreateVol(v); err != nil {
+		d.endTask(name, status, err)
+		t.Close()
+		return nil, err
+	}
+
+	go func() {
+		defer t.Close()
+		err := d.receiveBackup(t, name, chain, v, status)
+		if err == nil {
+			v.Status = api.VolumeStatus_VOLUME_STATUS_UP
+			err = d.UpdateVol(v)
+		}
+		if err != nil {
+			d.deleteRestoredVolume(v)
+		}
+		d.endTask(name, status, err)
+	}()
+	return &api.CloudBackupRestoreResponse{
+		RestoreVolumeID: v.Id,
+		Name:            name,
+	}, nil
+}
+
+// deleteRestoredVolume deletes the volume of a failed restore.
+func (d *driver) deleteRestoredVolume(v *api.Volume) {
+	if _, err := os.Stat(v.DevicePath); err == nil {
+		if err := subvolumeDelete(v.DevicePath); err != nil {
+			logrus.Warnf("Failed to delete subvolume of volume %s: %v", v.Id, err)
+		}
+	}
+	if err := d.DeleteVol(v.Id); err != nil {
+		logrus.Warnf("Failed to delete volume %s of failed restore: %v", v.Id, err)
+	}
+}
+
+// receiveBackup receives the chain of backups in a scratch directory of the
+// task, then snapshots the last one as the subvolume of the volume.
+func (d *driver) receiveBackup(
+	t ObjectTarget,
+	name string,
+	chain []*backupMeta,
+	v *api.Volume,
+	status *api.CloudBackupStatus,
+) error {
+	dir := filepath.Join(d.root, Restores, name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	defer func() {
+		for _, m := range chain {
+			p := filepath.Join(dir, m.snapshot())
+			if _, err := os.Stat(p); err == nil {
+				subvolumeDelete(p)
+			}
+		}
+		os.Remove(dir)
+	}()
+	for _, m := range chain {
+		r, err := t.Get(m.ID + streamSuffix)
+		if err != nil {
+			return err
+		}
+		err = receive(dir, r)
+		r.Close()
+		if err != nil {
+			return err
+		}
+		status.BytesDone += m.Size
+		if err := d.putTask(name, status); err != nil {
+			logrus.Warnf("Failed to record progress of task %s: %v", name, err)
+		}
+	}
+	received := filepath.Join(dir, chain[len(chain)-1].snapshot())
+	if err := subvolumeSnapshot(received, v.DevicePath, false); err != nil {
+		return err
+	}
+	return qgroupLimit(v.DevicePath, v.Spec.Size)
+}
+
+// CloudBackupEnumerate lists the backups in the target of the credential.
+func (d *driver) CloudBackupEnumerate(input *api.CloudBackupEnumerateRequest) (*api.CloudBackupEnumerateResponse, error) {
+	t, err := d.target(input.CredentialUUID)
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+	volumeID := input.SrcVolumeID
+	if input.All {
+		volumeID = ""
+	}
+	metas, err := listMeta(t, volumeID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.CloudBackupEnumerateResponse{
+		Backups: make([]api.CloudBackupInfo, 0, len(metas)),
+	}
+	for _, m := range metas {
+		if input.CloudBackupID != "" && m.ID != input.CloudBackupID {
+			continue
+		}
+		if !parser.HasLabels(m.Labels, input.MetadataFilter) {
+			continue
+		}
+		resp.Backups = append(resp.Backups, m.info())
+	}
+	return resp, nil
+}
+
+// CloudBackupDelete deletes the backup. Backups that others are incremental
+// to are only deleted with Force, along with those others.
+func (d *driver) CloudBackupDelete(input *api.CloudBackupDeleteRequest) error {
+	t, err := d.target(input.CredentialUUID)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	return deleteBackup(t, input.ID, input.Force)
+}
+
+// CloudBackupDeleteAll deletes all backups of the volume, newest first so
+// that no backup is left without its parent.
+func (d *driver) CloudBackupDeleteAll(input *api.CloudBackupDeleteAllRequest) error {
+	if input.SrcVolumeID == "" && !input.All {
+		return fmt.Errorf("Source volume ID must be set")
+	}
+	t, err := d.target(input.CredentialUUID)
+	if err != nil {
+		return err
+	}
+	defer t.Close()
+	volumeID := input.SrcVolumeID
+	if input.All {
+		volumeID = ""
+	}
+	metas, err := listMeta(t, volumeID)
+	if err != nil {
+		return err
+	}
+	for i := len(metas) - 1; i >= 0; i-- {
+		if err := deleteBackup(t, metas[i].ID, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloudBackupStatus returns the status of the task with the ID of the
+// request, or of the tasks of the source volume.
+func (d *driver) CloudBackupStatus(input *api.CloudBackupStatusRequest) (*api.CloudBackupStatusResponse, error) {
+	resp := &api.CloudBackupStatusResponse{
+		Statuses: make(map[string]api.CloudBackupStatus),
+	}
+	if input.ID != "" {
+		var status api.CloudBackupStatus
+		if _, err := d.kv.GetVal(cloudBackupsKeyPrefix+"/"+input.ID, &status); err != nil {
+			return nil, fmt.Errorf("Cloud backup task %s not found", input.ID)
+		}
+		resp.Statuses[input.ID] = status
+		return resp, nil
+	}
+	kvp, err := d.kv.Enumerate(cloudBackupsKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range kvp {
+		var status api.CloudBackupStatus
+		if err := json.Unmarshal(p.Value, &status); err != nil {
+			return nil, err
+		}
+		if input.SrcVolumeID != "" && status.SrcVolumeID != input.SrcVolumeID {
+			continue
+		}
+		resp.Statuses[filepath.Base(p.Key)] = status
+	}
+	return resp, nil
+}
+
+// CloudBackupSize returns the size of the send streams needed to restore
+// the backup.
+func (d *driver) CloudBackupSize(input *api.SdkCloudBackupSizeRequest) (*api.SdkCloudBackupSizeResponse, error) {
+	t, err := d.target(input.GetCredentialId())
+	if err != nil {
+		return nil, err
+	}
+	defer t.Close()
+	chain, err := backupChain(t, input.GetBackupId())
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.SdkCloudBackupSizeResponse{}
+	for _, m := range chain {
+		resp.TotalDownloadBytes += m.Size
+	}
+	resp.Size = chain[len(chain)-1].Size
+	if s := chain[len(chain)-1].Spec; s != nil {
+		resp.CapacityRequiredForRestore = s.Size
+	}
+	return resp, nil
+}
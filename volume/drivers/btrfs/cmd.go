@@ -0,0 +1,138 @@
+package btrfs
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// btrfsBin is the btrfs-progs command the driver runs.
+var btrfsBin = "btrfs"
+
+// run runs btrfs with args and returns its output.
+func run(args ...string) (string, error) {
+	var stdout bytes.Buffer
+	err := runIO(nil, &stdout, args...)
+	return stdout.String(), err
+}
+
+// runIO runs btrfs with args, feeding it stdin and writing its output to
+// stdout. Errors include what the command printed on stderr.
+func runIO(stdin io.Reader, stdout io.Writer, args ...string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(btrfsBin, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s %s failed: %v: %s",
+			btrfsBin, strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+func subvolumeCreate(path string) error {
+	_, err := run("subvolume", "create", path)
+	return err
+}
+
+func subvolumeDelete(path string) error {
+	_, err := run("subvolume", "delete", path)
+	return err
+}
+
+// subvolumeSnapshot snapshots the subvolume at src to dst.
+func subvolumeSnapshot(src, dst string, readonly bool) error {
+	args := []string{"subvolume", "snapshot"}
+	if readonly {
+		args = append(args, "-r")
+	}
+	_, err := run(append(args, src, dst)...)
+	return err
+}
+
+// subvolumeID returns the id of the subvolume at path, which is also the id
+// of its level 0 qgroup.
+func subvolumeID(path string) (uint64, error) {
+	out, err := run("inspect-internal", "rootid", path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(out), 10, 64)
+}
+
+func quotaEnable(path string) error {
+	_, err := run("quota", "enable", path)
+	return err
+}
+
+// qgroupLimit limits the space referenced by the subvolume at path to size
+// bytes. A size of zero removes the limit.
+func qgroupLimit(path string, size uint64) error {
+	limit := "none"
+	if size != 0 {
+		limit = strconv.FormatUint(size, 10)
+	}
+	_, err := run("qgroup", "limit", limit, path)
+	return err
+}
+
+// qgroupUsage returns the bytes referenced by the subvolume at path and the
+// bytes only it references.
+func qgroupUsage(path string) (referenced uint64, exclusive uint64, err error) {
+	id, err := subvolumeID(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	out, err := run("qgroup", "show", "--raw", "-f", path)
+	if err != nil {
+		return 0, 0, err
+	}
+	return parseQgroupShow(out, id)
+}
+
+// parseQgroupShow returns the usage of the level 0 qgroup of subvolume id
+// from the output of btrfs qgroup show --raw:
+//
+//	qgroupid         rfer         excl
+//	--------         ----         ----
+//	0/257           16384        16384
+func parseQgroupShow(out string, id uint64) (uint64, uint64, error) {
+	qgroup := fmt.Sprintf("0/%d", id)
+	for _, line := range strings.Split(out, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 3 || fields[0] != qgroup {
+			continue
+		}
+		rfer, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid qgroup usage %q: %v", line, err)
+		}
+		excl, err := strconv.ParseUint(fields[2], 10, 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Invalid qgroup usage %q: %v", line, err)
+		}
+		return rfer, excl, nil
+	}
+	return 0, 0, fmt.Errorf("No qgroup %s, are quotas enabled?", qgroup)
+}
+
+// send writes the send stream of the read-only snapshot at path to w. With a
+// parent snapshot the stream only holds the changes since the parent.
+func send(path, parent string, w io.Writer) error {
+	args := []string{"send"}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	return runIO(nil, w, append(args, path)...)
+}
+
+// receive creates the snapshot of the send stream r in dir. The snapshot has
+// the name of the one sent. Incremental streams need their parent received
+// in dir first.
+func receive(dir string, r io.Reader) error {
+	return runIO(r, nil, "receive", "-e", dir)
+}
@@ -0,0 +1,183 @@
+package btrfs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+// ObjectTarget stores the send streams and metadata of cloud backups as
+// named objects. Names are slash separated paths.
+type ObjectTarget interface {
+	// Put stores the content of r as the object name and returns its size.
+	Put(name string, r io.Reader) (int64, error)
+	// Get returns the content of the object name.
+	Get(name string) (io.ReadCloser, error)
+	// Delete removes the object name. Deleting a missing object succeeds.
+	Delete(name string) error
+	// List returns the names of the objects starting with prefix, sorted.
+	List(prefix string) ([]string, error)
+	// Close releases the target.
+	Close() error
+}
+
+// ObjectTargetInit returns the target described by the parameters of a
+// credential.
+type ObjectTargetInit func(params map[string]string) (ObjectTarget, error)
+
+var (
+	targetsLock sync.Mutex
+	targets     = map[string]ObjectTargetInit{
+		"nfs": newNFSTarget,
+	}
+)
+
+// RegisterObjectTarget makes cloud backups with credentials of credType,
+// the api.OptCredType of the credential, use targets returned by init.
+func RegisterObjectTarget(credType string, init ObjectTargetInit) {
+	targetsLock.Lock()
+	defer targetsLock.Unlock()
+	targets[credType] = init
+}
+
+// newObjectTarget returns the target of a credential.
+func newObjectTarget(params map[string]string) (ObjectTarget, error) {
+	credType := params[api.OptCredType]
+	targetsLock.Lock()
+	init, ok := targets[credType]
+	targetsLock.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("Cloud backups to %q credentials are not supported", credType)
+	}
+	return init(params)
+}
+
+// dirTarget stores objects as files under a directory.
+type dirTarget struct {
+	dir string
+}
+
+// NewDirTarget returns a target storing objects as files under dir.
+func NewDirTarget(dir string) (ObjectTarget, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &dirTarget{dir: dir}, nil
+}
+
+func (t *dirTarget) path(name string) string {
+	return filepath.Join(t.dir, filepath.FromSlash(name))
+}
+
+// Put writes the object to a temporary file first, so that partial objects
+// are never visible.
+func (t *dirTarget) Put(name string, r io.Reader) (int64, error) {
+	p := t.path(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return 0, err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".put-")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	n, err := io.Copy(f, r)
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, os.Rename(f.Name(), p)
+}
+
+func (t *dirTarget) Get(name string) (io.ReadCloser, error) {
+	return os.Open(t.path(name))
+}
+
+func (t *dirTarget) Delete(name string) error {
+	if err := os.Remove(t.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (t *dirTarget) List(prefix string) ([]string, error) {
+	var names []string
+	err := filepath.Walk(t.dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() || strings.HasPrefix(fi.Name(), ".put-") {
+			return nil
+		}
+		rel, err := filepath.Rel(t.dir, p)
+		if err != nil {
+			return err
+		}
+		if name := filepath.ToSlash(rel); strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+		return nil
+	})
+	sort.Strings(names)
+	return names, err
+}
+
+func (t *dirTarget) Close() error {
+	return nil
+}
+
+// nfsTarget is a dirTarget on an NFS export mounted for the lifetime of the
+// target. Objects are stored under the bucket of the credential.
+type nfsTarget struct {
+	*dirTarget
+	mountpath string
+}
+
+func newNFSTarget(params map[string]string) (ObjectTarget, error) {
+	server := params[api.OptCredNFSServer]
+	if server == "" {
+		return nil, fmt.Errorf("NFS server of the credential is not set")
+	}
+	mountpath, err := os.MkdirTemp("", "btrfs-backup-")
+	if err != nil {
+		return nil, err
+	}
+	src := server + ":/" + strings.TrimPrefix(params[api.OptCredNFSSubPath], "/")
+	opts := "nolock,addr=" + server
+	if o := params[api.OptCredNFSMountOpts]; o != "" {
+		opts += "," + o
+	}
+	if err := syscall.Mount(src, mountpath, "nfs", 0, opts); err != nil {
+		os.Remove(mountpath)
+		return nil, fmt.Errorf("Failed to mount %s: %v", src, err)
+	}
+	dt, err := NewDirTarget(filepath.Join(mountpath, params[api.OptCredBucket]))
+	if err != nil {
+		syscall.Unmount(mountpath, 0)
+		os.Remove(mountpath)
+		return nil, err
+	}
+	return &nfsTarget{dirTarget: dt.(*dirTarget), mountpath: mountpath}, nil
+}
+
+func (t *nfsTarget) Close() error {
+	if err := syscall.Unmount(t.mountpath, 0); err != nil {
+		return err
+	}
+	return os.Remove(t.mountpath)
+}
@@ -3,11 +3,10 @@ package buse
 import (
 	"context"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
-	"path"
 	"strings"
+	"sync"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
@@ -45,6 +44,8 @@ type driver struct {
 	volume.FilesystemTrimDriver
 	volume.FilesystemCheckDriver
 	volume.VerifyChecksumDriver
+	devLock     sync.Mutex
+	store       *extentStore
 	buseDevices map[string]*buseDev
 	cl          cluster.ClusterListener
 }
@@ -53,46 +54,27 @@ type clusterListener struct {
 	cluster.NullClusterListener
 }
 
-// Implements the Device interface.
+// Implements the Device interface on top of the volume's extent map.
 type buseDev struct {
-	file string
-	f    *os.File
-	nbd  *NBD
+	id    string
+	store *extentStore
+	nbd   *NBD
 }
 
 func (d *buseDev) ReadAt(b []byte, off int64) (n int, err error) {
-	return d.f.ReadAt(b, off)
+	return d.store.ReadAt(d.id, b, off)
 }
 
 func (d *buseDev) WriteAt(b []byte, off int64) (n int, err error) {
-	return d.f.WriteAt(b, off)
+	return d.store.WriteAt(d.id, b, off)
 }
 
-func copyFile(source string, dest string) (err error) {
-	sourcefile, err := os.Open(source)
-	if err != nil {
-		return err
-	}
-
-	defer sourcefile.Close()
-
-	destfile, err := os.Create(dest)
-	if err != nil {
-		return err
-	}
-
-	defer destfile.Close()
-
-	_, err = io.Copy(destfile, sourcefile)
-	if err == nil {
-		sourceinfo, err := os.Stat(source)
-		if err != nil {
-			err = os.Chmod(dest, sourceinfo.Mode())
-		}
-
-	}
+func (d *buseDev) Trim(off int64, length int64) error {
+	return d.store.Trim(d.id, off, length)
+}
 
-	return
+func (d *buseDev) Flush() error {
+	return d.store.Sync(d.id)
 }
 
 // Init intialized the buse driver
@@ -116,14 +98,26 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 	if err := os.MkdirAll(BuseMountPath, 0744); err != nil {
 		return nil, err
 	}
+	store, err := newExtentStore(BuseMountPath)
+	if err != nil {
+		return nil, err
+	}
+	inst.store = store
 	volumeInfo, err := inst.StoreEnumerator.Enumerate(
 		&api.VolumeLocator{},
 		nil,
 	)
 	if err == nil {
 		for _, info := range volumeInfo {
-			if info.Status == api.VolumeStatus_VOLUME_STATUS_NONE {
+			// The NBD devices and mounts do not survive a restart.
+			if info.Status == api.VolumeStatus_VOLUME_STATUS_NONE ||
+				info.DevicePath != "" ||
+				info.State == api.VolumeState_VOLUME_STATE_ATTACHED ||
+				len(info.AttachPath) > 0 {
 				info.Status = api.VolumeStatus_VOLUME_STATUS_UP
+				info.DevicePath = ""
+				info.AttachPath = nil
+				info.State = api.VolumeState_VOLUME_STATE_DETACHED
 				inst.UpdateVol(info)
 			}
 		}
@@ -198,30 +192,20 @@ func (d *driver) Create(
 	if spec.Format == api.FSType_FS_TYPE_NONE {
 		return "", fmt.Errorf("Missing volume format: buse")
 	}
-	// Create a file on the local buse path with this UUID.
-	buseFile := path.Join(BuseMountPath, volumeID)
-	f, err := os.Create(buseFile)
-	if err != nil {
-		logrus.Println(err)
-		return "", err
+	// A volume created from a parent is a writable clone sharing its extents.
+	if source != nil && source.Parent != "" {
+		return d.snapshot(source.Parent, volumeID, locator, source, spec, false)
 	}
 
-	if err := f.Truncate(int64(spec.Size)); err != nil {
+	if err := d.store.Create(volumeID, int64(spec.Size)); err != nil {
 		logrus.Println(err)
 		return "", err
 	}
 
-	bd := &buseDev{
-		file: buseFile,
-		f:    f,
-	}
-	nbd := Create(bd, volumeID, int64(spec.Size))
-	bd.nbd = nbd
-
-	logrus.Infof("Connecting to NBD...")
-	dev, err := bd.nbd.Connect()
+	dev, err := d.connect(volumeID, int64(spec.Size))
 	if err != nil {
 		logrus.Println(err)
+		d.store.Delete(volumeID)
 		return "", err
 	}
 
@@ -230,11 +214,13 @@ func (d *driver) Create(
 	o, err := exec.Command(cmd, dev).Output()
 	if err != nil {
 		logrus.Warnf("Failed to run command %v %v: %v", cmd, dev, o)
+		d.disconnect(volumeID)
+		d.store.Delete(volumeID)
 		return "", err
 	}
 
-	logrus.Infof("BUSE mapped NBD device %s (size=%v) to block file %s", dev,
-		spec.Size, buseFile)
+	logrus.Infof("BUSE mapped NBD device %s (size=%v) to volume %s", dev,
+		spec.Size, volumeID)
 
 	v := common.NewVolume(
 		volumeID,
@@ -244,16 +230,106 @@ func (d *driver) Create(
 		spec,
 	)
 	v.DevicePath = dev
-
-	d.buseDevices[dev] = bd
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
 
 	err = d.CreateVol(v)
 	if err != nil {
+		d.disconnect(volumeID)
+		d.store.Delete(volumeID)
 		return "", err
 	}
 	return v.Id, err
 }
 
+// snapshot creates newID as a copy-on-write copy of parentID. No device is
+// connected until the new volume is attached.
+func (d *driver) snapshot(
+	parentID string,
+	newID string,
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+	readonly bool,
+) (string, error) {
+	parent, err := d.GetVol(parentID)
+	if err != nil {
+		return "", err
+	}
+	if spec == nil {
+		spec = parent.Spec.Copy()
+	}
+	if spec.Size < parent.Spec.Size {
+		return "", fmt.Errorf("Volume %v cannot be smaller than its parent %v of %v bytes",
+			newID, parent.Id, parent.Spec.Size)
+	}
+
+	// Flush dirty pages of a mounted parent so the snapshot is consistent.
+	syscall.Sync()
+	if err := d.store.Snapshot(parent.Id, newID); err != nil {
+		return "", err
+	}
+	// A clone larger than its parent is grown like a resize.
+	if spec.Size > parent.Spec.Size {
+		if err := d.store.Resize(newID, int64(spec.Size)); err != nil {
+			d.store.Delete(newID)
+			return "", err
+		}
+	}
+
+	v := common.NewVolume(
+		newID,
+		parent.Format,
+		locator,
+		source,
+		spec,
+	)
+	v.Readonly = readonly
+	if err := d.CreateVol(v); err != nil {
+		d.store.Delete(newID)
+		return "", err
+	}
+
+	logrus.Infof("BUSE created volume %v from %v", newID, parent.Id)
+	return newID, nil
+}
+
+// connect exposes the volume through a free NBD device.
+func (d *driver) connect(volumeID string, size int64) (string, error) {
+	d.devLock.Lock()
+	defer d.devLock.Unlock()
+
+	if bd, ok := d.buseDevices[volumeID]; ok {
+		return bd.nbd.devicePath, nil
+	}
+	bd := &buseDev{
+		id:    volumeID,
+		store: d.store,
+	}
+	bd.nbd = Create(bd, volumeID, size)
+	if bd.nbd == nil {
+		return "", fmt.Errorf("Failed to create NBD device for %v", volumeID)
+	}
+
+	logrus.Infof("Connecting to NBD...")
+	dev, err := bd.nbd.Connect()
+	if err != nil {
+		return "", err
+	}
+	d.buseDevices[volumeID] = bd
+	return dev, nil
+}
+
+// disconnect tears down the NBD device of the volume, if any.
+func (d *driver) disconnect(volumeID string) {
+	d.devLock.Lock()
+	defer d.devLock.Unlock()
+
+	if bd, ok := d.buseDevices[volumeID]; ok {
+		bd.nbd.Disconnect()
+		delete(d.buseDevices, volumeID)
+	}
+}
+
 func (d *driver) Delete(ctx context.Context, volumeID string) error {
 	v, err := d.GetVol(volumeID)
 	if err != nil {
@@ -261,17 +337,16 @@ func (d *driver) Delete(ctx context.Context, volumeID string) error {
 		return err
 	}
 
-	bd, ok := d.buseDevices[v.DevicePath]
-	if !ok {
-		err = fmt.Errorf("Cannot locate a BUSE device for %s", v.DevicePath)
-		logrus.Println(err)
-		return err
+	if len(v.AttachPath) > 0 && len(v.AttachPath[0]) > 0 {
+		return volume.ErrVolAttached
 	}
 
-	// Clean up buse block file and close the NBD connection.
-	os.Remove(bd.file)
-	bd.f.Close()
-	bd.nbd.Disconnect()
+	// Close the NBD connection and release the volume's extents. Extents
+	// shared with snapshots or clones stay allocated.
+	d.disconnect(volumeID)
+	if err := d.store.Delete(v.Id); err != nil {
+		logrus.Warnf("Failed to release extents of volume %v: %v", volumeID, err)
+	}
 
 	logrus.Infof("BUSE deleted volume %v at NBD device %s", volumeID,
 		v.DevicePath)
@@ -293,10 +368,17 @@ func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, o
 	if err != nil {
 		return fmt.Errorf("Failed to locate volume %q", volumeID)
 	}
-	if len(v.AttachPath) > 0 && len(v.AttachPath) > 0 {
+	if len(v.AttachPath) > 0 && len(v.AttachPath[0]) > 0 {
 		return fmt.Errorf("Volume %q already mounted at %q", volumeID, v.AttachPath[0])
 	}
-	if err := syscall.Mount(v.DevicePath, mountpath, v.Spec.Format.SimpleString(), 0, ""); err != nil {
+	if v.DevicePath == "" {
+		return volume.ErrVolDetached
+	}
+	var flags uintptr
+	if v.Readonly {
+		flags |= syscall.MS_RDONLY
+	}
+	if err := syscall.Mount(v.DevicePath, mountpath, v.Spec.Format.SimpleString(), flags, ""); err != nil {
 		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
 	}
 
@@ -325,51 +407,85 @@ func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string,
 }
 
 func (d *driver) Snapshot(ctx context.Context, volumeID string, readonly bool, locator *api.VolumeLocator, noRetry bool) (string, error) {
-	volIDs := make([]string, 1)
-	volIDs[0] = volumeID
-	vols, err := d.Inspect(nil, volIDs)
+	v, err := d.GetVol(volumeID)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 
-	source := &api.Source{Parent: volumeID}
-	newVolumeID, err := d.Create(correlation.TODO(), locator, source, vols[0].Spec)
+	source := &api.Source{Parent: v.Id}
+	return d.snapshot(v.Id, strings.TrimSuffix(uuid.New(), "\n"), locator, source, nil, readonly)
+}
+
+func (d *driver) Restore(volumeID string, snapID string) error {
+	v, err := d.GetVol(volumeID)
 	if err != nil {
-		return "", nil
+		return err
 	}
-
-	// BUSE does not support snapshots, so just copy the block files.
-	err = copyFile(BuseMountPath+volumeID, BuseMountPath+newVolumeID)
+	snap, err := d.GetVol(snapID)
 	if err != nil {
-		d.Delete(correlation.TODO(), newVolumeID)
-		return "", nil
+		return err
+	}
+	if len(v.AttachPath) > 0 && len(v.AttachPath[0]) > 0 {
+		return fmt.Errorf("Volume %q must be unmounted to restore, mounted at %q",
+			volumeID, v.AttachPath[0])
+	}
+	// The extent map cannot be rewound under a connected NBD device.
+	if v.DevicePath != "" {
+		return fmt.Errorf("Volume %q must be detached to restore, attached at %q",
+			volumeID, v.DevicePath)
 	}
 
-	return newVolumeID, nil
-}
-
-func (d *driver) Restore(volumeID string, snapID string) error {
-	if _, err := d.Inspect(correlation.TODO(), []string{volumeID, snapID}); err != nil {
+	// Rewind the extent map; the snapshot keeps its own references.
+	if err := d.store.Restore(v.Id, snap.Id); err != nil {
 		return err
 	}
-
-	// BUSE does not support restore, so just copy the block files.
-	return copyFile(BuseMountPath+snapID, BuseMountPath+volumeID)
+	logrus.Infof("BUSE restored volume %v from snapshot %v", v.Id, snap.Id)
+	return nil
 }
 
 func (d *driver) SnapshotGroup(groupID string, labels map[string]string, volumeIDs []string, deleteOnFailure bool) (*api.GroupSnapCreateResponse, error) {
-
-	return nil, volume.ErrNotSupported
+	return common.SnapshotGroup(
+		d,
+		groupID,
+		labels,
+		volumeIDs,
+		deleteOnFailure,
+		func(v *api.Volume) (string, error) {
+			locator := &api.VolumeLocator{
+				Name:         v.GetLocator().GetName() + "-" + groupID + "-snap",
+				VolumeLabels: labels,
+			}
+			return d.Snapshot(correlation.TODO(), v.Id, true, locator, false)
+		},
+		func(snapID string) error {
+			return d.Delete(correlation.TODO(), snapID)
+		},
+	)
 }
 
 func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	if spec != nil {
-		return volume.ErrNotSupported
-	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
+	if spec != nil {
+		// Only growing the volume is supported; the extent map is thin so
+		// the new space is not allocated until written.
+		if spec.Size == 0 || spec.Size == v.Spec.Size {
+			return volume.ErrNotSupported
+		}
+		if spec.Size < v.Spec.Size {
+			return fmt.Errorf("Cannot shrink volume %v from %v to %v bytes",
+				volumeID, v.Spec.Size, spec.Size)
+		}
+		if v.DevicePath != "" {
+			return fmt.Errorf("Volume %v must be detached to resize", volumeID)
+		}
+		if err := d.store.Resize(v.Id, int64(spec.Size)); err != nil {
+			return err
+		}
+		v.Spec.Size = spec.Size
+	}
 	if locator != nil {
 		v.Locator = locator
 	}
@@ -377,17 +493,111 @@ func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLo
 }
 
 func (d *driver) Attach(ctx context.Context, volumeID string, attachOptions map[string]string) (string, error) {
-	// Nothing to do on attach.
-	return path.Join(BuseMountPath, volumeID), nil
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	dev, err := d.connect(v.Id, int64(v.Spec.Size))
+	if err != nil {
+		return "", err
+	}
+	v.DevicePath = dev
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		return "", err
+	}
+	return dev, nil
 }
 
 func (d *driver) Detach(ctx context.Context, volumeID string, options map[string]string) error {
-	// Nothing to do on detach.
-	return nil
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) > 0 && len(v.AttachPath[0]) > 0 {
+		return fmt.Errorf("Volume %q is mounted at %q", volumeID, v.AttachPath[0])
+	}
+	d.disconnect(v.Id)
+	if err := d.store.Sync(v.Id); err != nil {
+		logrus.Warnf("Failed to sync volume %v on detach: %v", volumeID, err)
+	}
+	v.DevicePath = ""
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	return d.UpdateVol(v)
+}
+
+// Stats returns the I/O counters and allocated bytes of the volume.
+func (d *driver) Stats(ctx context.Context, volumeID string, cumulative bool) (*api.Stats, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	s, err := d.store.Stats(v.Id)
+	if err != nil {
+		return nil, err
+	}
+	exclusive, shared, err := d.store.Usage(v.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &api.Stats{
+		Reads:        s.reads,
+		ReadMs:       s.readMs,
+		ReadBytes:    s.readBytes,
+		Writes:       s.writes,
+		WriteMs:      s.writeMs,
+		WriteBytes:   s.writeBytes,
+		IoMs:         s.readMs + s.writeMs,
+		BytesUsed:    exclusive + shared,
+		Discards:     s.discards,
+		DiscardBytes: s.discardBytes,
+		UniqueBlocks: exclusive / extentSize,
+	}, nil
+}
+
+// UsedSize returns the bytes allocated to the volume, including extents
+// shared with its snapshots.
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	exclusive, shared, err := d.store.Usage(v.Id)
+	if err != nil {
+		return 0, err
+	}
+	return exclusive + shared, nil
+}
+
+// CapacityUsage splits the allocated bytes of a volume or snapshot into those
+// it references exclusively and those shared with its parent or children.
+func (d *driver) CapacityUsage(ID string) (*api.CapacityUsageResponse, error) {
+	v, err := d.GetVol(ID)
+	if err != nil {
+		return nil, err
+	}
+	exclusive, shared, err := d.store.Usage(v.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &api.CapacityUsageResponse{
+		CapacityUsageInfo: &api.CapacityUsageInfo{
+			ExclusiveBytes: int64(exclusive),
+			SharedBytes:    int64(shared),
+			TotalBytes:     int64(exclusive + shared),
+		},
+	}, nil
 }
 
 func (d *driver) Shutdown() {
 	logrus.Printf("%s Shutting down", Name)
+	d.devLock.Lock()
+	for id, bd := range d.buseDevices {
+		bd.nbd.Disconnect()
+		delete(d.buseDevices, id)
+	}
+	d.devLock.Unlock()
+	d.store.Close()
 	syscall.Unmount(BuseMountPath, 0)
 }
 
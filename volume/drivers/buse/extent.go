@@ -0,0 +1,638 @@
+package buse
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// extentSize is the granularity at which backing storage is allocated
+	// and shared between a volume and its snapshots.
+	extentSize = 64 * 1024
+	// extentDataFile holds the data of every extent in the store.
+	extentDataFile = "extents.dat"
+	// extentMapSuffix is the suffix of a volume's extent map journal.
+	extentMapSuffix = ".map"
+	// unmapped marks a logical extent that was discarded in the journal.
+	unmapped = ^uint64(0)
+)
+
+// extentMap maps the logical extents of one volume to physical extents in the
+// shared data file. Extents that are not mapped read back as zeroes. The lock
+// of the map serializes the I/O of the volume, and is taken before the lock
+// of the store.
+type extentMap struct {
+	lock    sync.Mutex
+	id      string
+	size    int64
+	extents map[uint64]uint64
+	journal *os.File
+	stats   ioStats
+	deleted bool
+}
+
+// ioStats are cumulative I/O counters for a volume.
+type ioStats struct {
+	reads        uint64
+	readBytes    uint64
+	readMs       uint64
+	writes       uint64
+	writeBytes   uint64
+	writeMs      uint64
+	discards     uint64
+	discardBytes uint64
+}
+
+// extentStore is a thin provisioned, copy-on-write store for buse volumes.
+// Physical extents are reference counted, so snapshots and clones share
+// every extent with their parent until either side overwrites it. The lock
+// of the store protects the maps, the reference counts and the free list.
+type extentStore struct {
+	sync.Mutex
+	dir  string
+	data *os.File
+	refs []uint32
+	free []uint64
+	maps map[string]*extentMap
+}
+
+// newExtentStore opens the store under dir and rebuilds the reference counts
+// from the extent maps found there.
+func newExtentStore(dir string) (*extentStore, error) {
+	data, err := os.OpenFile(path.Join(dir, extentDataFile), os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	s := &extentStore{
+		dir:  dir,
+		data: data,
+		maps: make(map[string]*extentMap),
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		data.Close()
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), extentMapSuffix) {
+			continue
+		}
+		id := strings.TrimSuffix(e.Name(), extentMapSuffix)
+		m, err := s.loadMap(id)
+		if err != nil {
+			logrus.Warnf("Failed to load extent map for %v: %v", id, err)
+			continue
+		}
+		s.maps[id] = m
+		for _, p := range m.extents {
+			s.ref(p)
+		}
+	}
+	for p, r := range s.refs {
+		if r == 0 {
+			s.free = append(s.free, uint64(p))
+		}
+	}
+	return s, nil
+}
+
+func (s *extentStore) mapPath(id string) string {
+	return path.Join(s.dir, id+extentMapSuffix)
+}
+
+// loadMap replays the journal of a volume. The journal starts with the
+// volume size followed by (logical, physical) records, where later records
+// win and a physical value of unmapped removes the logical extent.
+func (s *extentStore) loadMap(id string) (*extentMap, error) {
+	f, err := os.OpenFile(s.mapPath(id), os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	m := &extentMap{
+		id:      id,
+		extents: make(map[uint64]uint64),
+		journal: f,
+	}
+	r := bufio.NewReader(f)
+	var size int64
+	if err := binary.Read(r, binary.LittleEndian, &size); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("corrupt extent map header: %v", err)
+	}
+	m.size = size
+	var rec [2]uint64
+	for {
+		if err := binary.Read(r, binary.LittleEndian, &rec); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				// A torn trailing record is dropped.
+				break
+			}
+			f.Close()
+			return nil, err
+		}
+		if rec[1] == unmapped {
+			delete(m.extents, rec[0])
+		} else {
+			m.extents[rec[0]] = rec[1]
+		}
+	}
+	return m, nil
+}
+
+// writeMap rewrites the journal of m so that it only holds the live extents.
+func (s *extentStore) writeMap(m *extentMap) error {
+	tmp := s.mapPath(m.id) + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	binary.Write(w, binary.LittleEndian, m.size)
+	for l, p := range m.extents {
+		binary.Write(w, binary.LittleEndian, [2]uint64{l, p})
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	f.Close()
+	if err := os.Rename(tmp, s.mapPath(m.id)); err != nil {
+		return err
+	}
+	if m.journal != nil {
+		m.journal.Close()
+	}
+	m.journal, err = os.OpenFile(s.mapPath(m.id), os.O_RDWR|os.O_APPEND, 0600)
+	return err
+}
+
+func (s *extentStore) record(m *extentMap, logical, physical uint64) error {
+	var rec [16]byte
+	binary.LittleEndian.PutUint64(rec[0:8], logical)
+	binary.LittleEndian.PutUint64(rec[8:16], physical)
+	_, err := m.journal.Write(rec[:])
+	return err
+}
+
+func (s *extentStore) ref(p uint64) {
+	for uint64(len(s.refs)) <= p {
+		s.refs = append(s.refs, 0)
+	}
+	s.refs[p]++
+}
+
+// unref drops a reference on p and returns the extent to the free list,
+// punching a hole in the data file, once nothing references it.
+func (s *extentStore) unref(p uint64) {
+	s.refs[p]--
+	if s.refs[p] > 0 {
+		return
+	}
+	if err := unix.Fallocate(
+		int(s.data.Fd()),
+		unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE,
+		int64(p)*extentSize,
+		extentSize,
+	); err != nil {
+		// Without hole punching a reused extent could leak stale data.
+		s.zero(p)
+	}
+	s.free = append(s.free, p)
+}
+
+func (s *extentStore) zero(p uint64) {
+	s.data.WriteAt(make([]byte, extentSize), int64(p)*extentSize)
+}
+
+func (s *extentStore) alloc() uint64 {
+	var p uint64
+	if n := len(s.free); n > 0 {
+		p = s.free[n-1]
+		s.free = s.free[:n-1]
+	} else {
+		p = uint64(len(s.refs))
+	}
+	s.ref(p)
+	return p
+}
+
+// lock returns the extent map of a volume with its lock held.
+func (s *extentStore) lock(id string) (*extentMap, error) {
+	s.Lock()
+	m, ok := s.maps[id]
+	s.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("No extent map for volume %v", id)
+	}
+	m.lock.Lock()
+	if m.deleted {
+		m.lock.Unlock()
+		return nil, fmt.Errorf("No extent map for volume %v", id)
+	}
+	return m, nil
+}
+
+// Create creates an empty, fully thin volume of the given size.
+func (s *extentStore) Create(id string, size int64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.maps[id]; ok {
+		return fmt.Errorf("Extent map for volume %v already exists", id)
+	}
+	m := &extentMap{
+		id:      id,
+		size:    size,
+		extents: make(map[uint64]uint64),
+	}
+	if err := s.writeMap(m); err != nil {
+		return err
+	}
+	s.maps[id] = m
+	return nil
+}
+
+// Delete releases every extent referenced by the volume.
+func (s *extentStore) Delete(id string) error {
+	m, err := s.lock(id)
+	if err != nil {
+		return err
+	}
+	defer m.lock.Unlock()
+
+	s.Lock()
+	for _, p := range m.extents {
+		s.unref(p)
+	}
+	delete(s.maps, id)
+	s.Unlock()
+
+	m.deleted = true
+	m.journal.Close()
+	return os.Remove(s.mapPath(id))
+}
+
+// Snapshot creates snapID sharing every extent of volumeID. Snapshots and
+// clones are identical at this layer.
+func (s *extentStore) Snapshot(volumeID, snapID string) error {
+	src, err := s.lock(volumeID)
+	if err != nil {
+		return err
+	}
+	defer src.lock.Unlock()
+
+	s.Lock()
+	defer s.Unlock()
+	if _, ok := s.maps[snapID]; ok {
+		return fmt.Errorf("Extent map for volume %v already exists", snapID)
+	}
+	m := &extentMap{
+		id:      snapID,
+		size:    src.size,
+		extents: make(map[uint64]uint64, len(src.extents)),
+	}
+	for l, p := range src.extents {
+		m.extents[l] = p
+		s.ref(p)
+	}
+	if err := s.writeMap(m); err != nil {
+		for _, p := range m.extents {
+			s.unref(p)
+		}
+		return err
+	}
+	s.maps[snapID] = m
+	return nil
+}
+
+// Restore rewinds the extent map of volumeID to that of snapID.
+func (s *extentStore) Restore(volumeID, snapID string) error {
+	snap, err := s.lock(snapID)
+	if err != nil {
+		return err
+	}
+	extents := make(map[uint64]uint64, len(snap.extents))
+	s.Lock()
+	for l, p := range snap.extents {
+		extents[l] = p
+		s.ref(p)
+	}
+	s.Unlock()
+	snap.lock.Unlock()
+
+	m, err := s.lock(volumeID)
+	if err != nil {
+		s.unrefAll(extents)
+		return err
+	}
+	defer m.lock.Unlock()
+
+	old := m.extents
+	m.extents = extents
+	if err := s.writeMap(m); err != nil {
+		m.extents = old
+		s.unrefAll(extents)
+		return err
+	}
+	s.unrefAll(old)
+	return nil
+}
+
+func (s *extentStore) unrefAll(extents map[uint64]uint64) {
+	s.Lock()
+	defer s.Unlock()
+	for _, p := range extents {
+		s.unref(p)
+	}
+}
+
+// Resize changes the logical size of the volume. When shrinking, extents
+// past the new end are released and the tail of the last extent is zeroed,
+// so that growing the volume again does not expose old data.
+func (s *extentStore) Resize(id string, size int64) error {
+	m, err := s.lock(id)
+	if err != nil {
+		return err
+	}
+	defer m.lock.Unlock()
+
+	if size < m.size {
+		if err := s.zeroRange(m, size, minInt64(m.size, roundUp(size))); err != nil {
+			return err
+		}
+		s.Lock()
+		for l, p := range m.extents {
+			if int64(l)*extentSize >= size {
+				delete(m.extents, l)
+				s.unref(p)
+			}
+		}
+		s.Unlock()
+	}
+	m.size = size
+	return s.writeMap(m)
+}
+
+// ReadAt reads from the logical address space of a volume.
+func (s *extentStore) ReadAt(id string, b []byte, off int64) (int, error) {
+	start := time.Now()
+	m, err := s.lock(id)
+	if err != nil {
+		return 0, err
+	}
+	defer m.lock.Unlock()
+
+	n := 0
+	for n < len(b) {
+		pos := off + int64(n)
+		if pos >= m.size {
+			break
+		}
+		l, within := uint64(pos/extentSize), pos%extentSize
+		chunk := b[n:minInt(len(b), n+int(extentSize-within))]
+		// The extents of the volume stay referenced while its lock is held.
+		if p, ok := m.extents[l]; ok {
+			r, err := s.data.ReadAt(chunk, int64(p)*extentSize+within)
+			if err != nil && err != io.EOF {
+				return n, err
+			}
+			// The tail of the data file may be sparse and unwritten.
+			zeroFill(chunk[r:])
+		} else {
+			zeroFill(chunk)
+		}
+		n += len(chunk)
+	}
+	m.stats.reads++
+	m.stats.readBytes += uint64(n)
+	m.stats.readMs += uint64(time.Since(start).Milliseconds())
+	return n, nil
+}
+
+// WriteAt writes to the logical address space of a volume, allocating
+// extents on first write and copying shared extents before modifying them.
+func (s *extentStore) WriteAt(id string, b []byte, off int64) (int, error) {
+	start := time.Now()
+	m, err := s.lock(id)
+	if err != nil {
+		return 0, err
+	}
+	defer m.lock.Unlock()
+
+	if off+int64(len(b)) > m.size {
+		return 0, fmt.Errorf("Write past end of volume %v", id)
+	}
+	n, err := s.write(m, b, off)
+	m.stats.writes++
+	m.stats.writeBytes += uint64(n)
+	m.stats.writeMs += uint64(time.Since(start).Milliseconds())
+	return n, err
+}
+
+// write writes b at off in a volume whose lock is held.
+func (s *extentStore) write(m *extentMap, b []byte, off int64) (int, error) {
+	n := 0
+	for n < len(b) {
+		pos := off + int64(n)
+		l, within := uint64(pos/extentSize), pos%extentSize
+		chunk := b[n:minInt(len(b), n+int(extentSize-within))]
+		p, ok := m.extents[l]
+
+		// An extent cannot become shared while the lock of the volume is
+		// held, since only a snapshot of the volume adds references to it.
+		s.Lock()
+		shared := ok && s.refs[p] > 1
+		var np uint64
+		if !ok || shared {
+			np = s.alloc()
+		}
+		s.Unlock()
+
+		switch {
+		case !ok:
+			if err := s.record(m, l, np); err != nil {
+				s.unrefAll(map[uint64]uint64{l: np})
+				return n, err
+			}
+			m.extents[l] = np
+			p = np
+		case shared:
+			if len(chunk) < extentSize {
+				buf := make([]byte, extentSize)
+				if _, err := s.data.ReadAt(buf, int64(p)*extentSize); err != nil && err != io.EOF {
+					s.unrefAll(map[uint64]uint64{l: np})
+					return n, err
+				}
+				if _, err := s.data.WriteAt(buf, int64(np)*extentSize); err != nil {
+					s.unrefAll(map[uint64]uint64{l: np})
+					return n, err
+				}
+			}
+			if err := s.record(m, l, np); err != nil {
+				s.unrefAll(map[uint64]uint64{l: np})
+				return n, err
+			}
+			m.extents[l] = np
+			s.unrefAll(map[uint64]uint64{l: p})
+			p = np
+		}
+		if _, err := s.data.WriteAt(chunk, int64(p)*extentSize+within); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+	}
+	return n, nil
+}
+
+// zeroRange zeroes the mapped extents between off and end in a volume
+// whose lock is held. Unmapped extents already read back as zeroes.
+func (s *extentStore) zeroRange(m *extentMap, off, end int64) error {
+	for off < end {
+		l := uint64(off / extentSize)
+		next := minInt64(end, int64(l+1)*extentSize)
+		if _, ok := m.extents[l]; ok {
+			if _, err := s.write(m, make([]byte, next-off), off); err != nil {
+				return err
+			}
+		}
+		off = next
+	}
+	return nil
+}
+
+// Trim unmaps the extents fully covered by the range. Partially covered
+// extents are zeroed, copying them first if they are shared.
+func (s *extentStore) Trim(id string, off, length int64) error {
+	m, err := s.lock(id)
+	if err != nil {
+		return err
+	}
+	defer m.lock.Unlock()
+
+	end := minInt64(off+length, m.size)
+	first := roundUp(off)
+	last := (end / extentSize) * extentSize
+	if first >= last {
+		if err := s.zeroRange(m, off, end); err != nil {
+			return err
+		}
+	} else {
+		if err := s.zeroRange(m, off, first); err != nil {
+			return err
+		}
+		if err := s.zeroRange(m, last, end); err != nil {
+			return err
+		}
+		for l := uint64(first / extentSize); l < uint64(last/extentSize); l++ {
+			p, ok := m.extents[l]
+			if !ok {
+				continue
+			}
+			if err := s.record(m, l, unmapped); err != nil {
+				return err
+			}
+			delete(m.extents, l)
+			s.unrefAll(map[uint64]uint64{l: p})
+		}
+	}
+	m.stats.discards++
+	m.stats.discardBytes += uint64(length)
+	return nil
+}
+
+// Sync flushes the data file and the journal of the volume.
+func (s *extentStore) Sync(id string) error {
+	m, err := s.lock(id)
+	if err != nil {
+		return err
+	}
+	defer m.lock.Unlock()
+
+	if err := s.data.Sync(); err != nil {
+		return err
+	}
+	return m.journal.Sync()
+}
+
+// Usage returns the bytes allocated to the volume, split into bytes only it
+// references and bytes shared with snapshots or clones.
+func (s *extentStore) Usage(id string) (exclusive uint64, shared uint64, err error) {
+	m, err := s.lock(id)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer m.lock.Unlock()
+
+	s.Lock()
+	defer s.Unlock()
+	for _, p := range m.extents {
+		if s.refs[p] > 1 {
+			shared += extentSize
+		} else {
+			exclusive += extentSize
+		}
+	}
+	return exclusive, shared, nil
+}
+
+// Stats returns the I/O counters of the volume.
+func (s *extentStore) Stats(id string) (ioStats, error) {
+	m, err := s.lock(id)
+	if err != nil {
+		return ioStats{}, err
+	}
+	defer m.lock.Unlock()
+
+	return m.stats, nil
+}
+
+// Close closes the data file and all journals.
+func (s *extentStore) Close() {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, m := range s.maps {
+		m.journal.Close()
+	}
+	s.data.Close()
+}
+
+// roundUp returns off rounded up to the start of the next extent.
+func roundUp(off int64) int64 {
+	return (off + extentSize - 1) / extentSize * extentSize
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func zeroFill(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
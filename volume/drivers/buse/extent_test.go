@@ -0,0 +1,169 @@
+package buse
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtentStoreCopyOnWrite(t *testing.T) {
+	dir := t.TempDir()
+	s, err := newExtentStore(dir)
+	require.NoError(t, err)
+
+	size := int64(4 * extentSize)
+	require.NoError(t, s.Create("vol", size))
+
+	// Unwritten extents read back as zeroes and use no space.
+	buf := bytes.Repeat([]byte{0xff}, 100)
+	_, err = s.ReadAt("vol", buf, 10)
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, 100), buf)
+	excl, shared, err := s.Usage("vol")
+	require.NoError(t, err)
+	assert.Zero(t, excl+shared)
+
+	// A write spanning two extents allocates both.
+	orig := bytes.Repeat([]byte{'a'}, 1024)
+	_, err = s.WriteAt("vol", orig, extentSize-512)
+	require.NoError(t, err)
+	excl, shared, err = s.Usage("vol")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2*extentSize), excl)
+	assert.Zero(t, shared)
+
+	// The snapshot shares every extent with its parent.
+	require.NoError(t, s.Snapshot("vol", "snap"))
+	excl, shared, err = s.Usage("vol")
+	require.NoError(t, err)
+	assert.Zero(t, excl)
+	assert.Equal(t, uint64(2*extentSize), shared)
+
+	// Overwriting the parent copies the extent and leaves the snapshot intact.
+	_, err = s.WriteAt("vol", []byte("b"), extentSize)
+	require.NoError(t, err)
+	got := make([]byte, 1024)
+	_, err = s.ReadAt("snap", got, extentSize-512)
+	require.NoError(t, err)
+	assert.Equal(t, orig, got)
+	_, err = s.ReadAt("vol", got, extentSize-512)
+	require.NoError(t, err)
+	assert.Equal(t, byte('b'), got[512])
+	assert.Equal(t, byte('a'), got[513])
+	excl, shared, err = s.Usage("vol")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(extentSize), excl)
+	assert.Equal(t, uint64(extentSize), shared)
+
+	// Restore rewinds the parent to the snapshot.
+	require.NoError(t, s.Restore("vol", "snap"))
+	_, err = s.ReadAt("vol", got, extentSize-512)
+	require.NoError(t, err)
+	assert.Equal(t, orig, got)
+
+	// Trimming an extent unmaps it in the parent only.
+	require.NoError(t, s.Trim("vol", 0, extentSize))
+	excl, shared, err = s.Usage("vol")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(extentSize), shared)
+	assert.Zero(t, excl)
+
+	// Reopening the store replays the journals.
+	s.Close()
+	s, err = newExtentStore(dir)
+	require.NoError(t, err)
+	defer s.Close()
+	_, err = s.ReadAt("vol", got, extentSize-512)
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, 512), got[:512])
+	assert.Equal(t, orig[512:], got[512:])
+	_, err = s.ReadAt("snap", got, extentSize-512)
+	require.NoError(t, err)
+	assert.Equal(t, orig, got)
+
+	// Freed extents are reused.
+	require.NoError(t, s.Delete("snap"))
+	assert.Len(t, s.free, 1)
+	require.NoError(t, s.Delete("vol"))
+	assert.Len(t, s.free, 2)
+}
+
+func TestExtentStoreTrimAndResize(t *testing.T) {
+	s, err := newExtentStore(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	size := int64(4 * extentSize)
+	require.NoError(t, s.Create("vol", size))
+	data := bytes.Repeat([]byte{'a'}, int(size))
+	_, err = s.WriteAt("vol", data, 0)
+	require.NoError(t, err)
+	require.NoError(t, s.Snapshot("vol", "snap"))
+
+	// The partially covered extents of a trim read back as zeroes, and the
+	// snapshot sharing them keeps its data.
+	require.NoError(t, s.Trim("vol", extentSize/2, 2*extentSize))
+	got := make([]byte, size)
+	_, err = s.ReadAt("vol", got, 0)
+	require.NoError(t, err)
+	assert.Equal(t, data[:extentSize/2], got[:extentSize/2])
+	assert.Equal(t, make([]byte, 2*extentSize), got[extentSize/2:5*extentSize/2])
+	assert.Equal(t, data[5*extentSize/2:], got[5*extentSize/2:])
+	_, err = s.ReadAt("snap", got, 0)
+	require.NoError(t, err)
+	assert.Equal(t, data, got)
+
+	// A trim within one extent zeroes the range only.
+	require.NoError(t, s.Trim("vol", 10, 20))
+	_, err = s.ReadAt("vol", got[:40], 0)
+	require.NoError(t, err)
+	assert.Equal(t, data[:10], got[:10])
+	assert.Equal(t, make([]byte, 20), got[10:30])
+	assert.Equal(t, data[30:40], got[30:40])
+
+	// Growing a volume after shrinking it does not expose the old tail.
+	require.NoError(t, s.Resize("vol", size-100))
+	require.NoError(t, s.Resize("vol", size))
+	_, err = s.ReadAt("vol", got[:100], size-100)
+	require.NoError(t, err)
+	assert.Equal(t, make([]byte, 100), got[:100])
+	_, err = s.WriteAt("vol", []byte("b"), size-1)
+	require.NoError(t, err)
+}
+
+func TestExtentStoreParallelVolumes(t *testing.T) {
+	s, err := newExtentStore(t.TempDir())
+	require.NoError(t, err)
+	defer s.Close()
+
+	size := int64(8 * extentSize)
+	ids := []string{"vol1", "vol2", "vol3"}
+	for _, id := range ids {
+		require.NoError(t, s.Create(id, size))
+	}
+
+	// The volumes are written in parallel, each with its own byte.
+	var wg sync.WaitGroup
+	for i, id := range ids {
+		wg.Add(1)
+		go func(id string, b byte) {
+			defer wg.Done()
+			chunk := bytes.Repeat([]byte{b}, extentSize/2)
+			for off := int64(0); off < size; off += int64(len(chunk)) {
+				_, err := s.WriteAt(id, chunk, off)
+				assert.NoError(t, err)
+			}
+		}(id, byte('a'+i))
+	}
+	wg.Wait()
+
+	got := make([]byte, size)
+	for i, id := range ids {
+		_, err := s.ReadAt(id, got, 0)
+		require.NoError(t, err)
+		assert.Equal(t, bytes.Repeat([]byte{byte('a' + i)}, int(size)), got)
+	}
+}
@@ -68,6 +68,16 @@ type Device interface {
 	WriteAt(b []byte, off int64) (n int, err error)
 }
 
+// Trimmer is implemented by devices that can discard a range.
+type Trimmer interface {
+	Trim(off int64, length int64) error
+}
+
+// Flusher is implemented by devices that can flush writes to stable storage.
+type Flusher interface {
+	Flush() error
+}
+
 type request struct {
 	magic  uint32
 	typus  uint32
@@ -185,7 +195,7 @@ func (nbd *NBD) Connect() (dev string, err error) {
 	// Setup.
 	if err = nbd.Size(nbd.size); err != nil {
 		// Already set by nbd.Size().
-	} else if err = ioctl(nbd.deviceFile.Fd(), NBD_SET_FLAGS, 1); err != nil {
+	} else if err = ioctl(nbd.deviceFile.Fd(), NBD_SET_FLAGS, nbd.flags()); err != nil {
 		err = &os.PathError{
 			Op:   nbd.deviceFile.Name(),
 			Path: "ioctl NBD_SET_FLAGS",
@@ -230,6 +240,29 @@ func (nbd *NBD) Disconnect() {
 	logrus.Infof("Disconnected device %v", nbd.devicePath)
 }
 
+// flags advertises the optional commands the backing device supports.
+func (nbd *NBD) flags() uintptr {
+	flags := uintptr(NBD_FLAG_HAS_FLAGS)
+	if _, ok := nbd.device.(Trimmer); ok {
+		flags |= NBD_FLAG_SEND_TRIM
+	}
+	if _, ok := nbd.device.(Flusher); ok {
+		flags |= NBD_FLAG_SEND_FLUSH
+	}
+	return flags
+}
+
+// reply writes a reply header for the request in buf with the given error.
+func (nbd *NBD) reply(buf []byte, err error) {
+	binary.BigEndian.PutUint32(buf[0:4], NBD_REPLY_MAGIC)
+	if err != nil {
+		binary.BigEndian.PutUint32(buf[4:8], 1)
+	} else {
+		binary.BigEndian.PutUint32(buf[4:8], 0)
+	}
+	syscall.Write(nbd.socket, buf[0:16])
+}
+
 func (nbd *NBD) connect() {
 	runtime.LockOSThread()
 	defer runtime.UnlockOSThread()
@@ -289,11 +322,17 @@ func (nbd *NBD) handle() {
 				nbd.Disconnect()
 				return
 			case NBD_CMD_FLUSH:
-				fallthrough
+				if f, ok := nbd.device.(Flusher); ok {
+					nbd.reply(buf, f.Flush())
+				} else {
+					nbd.reply(buf, syscall.ENOTSUP)
+				}
 			case NBD_CMD_TRIM:
-				binary.BigEndian.PutUint32(buf[0:4], NBD_REPLY_MAGIC)
-				binary.BigEndian.PutUint32(buf[4:8], 1)
-				syscall.Write(nbd.socket, buf[0:16])
+				if t, ok := nbd.device.(Trimmer); ok {
+					nbd.reply(buf, t.Trim(int64(x.from), int64(x.len)))
+				} else {
+					nbd.reply(buf, syscall.ENOTSUP)
+				}
 			default:
 				logrus.Errorf("Unknown command received on device %s", nbd.devicePath)
 				nbd.Disconnect()
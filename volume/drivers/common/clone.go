@@ -0,0 +1,267 @@
+package common
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// CloneTree recreates the directory tree at src under dst, which must not
+// exist. Regular files are cloned with a reflink where the filesystem supports
+// it and copied otherwise. If hardlink is set, files are hardlinked instead;
+// callers must only do so when neither tree is modified in place afterwards,
+// since both names then share the same inode.
+func CloneTree(src, dst string, hardlink bool) error {
+	return walkTree(src, dst, func(from, to string) error {
+		if hardlink {
+			return os.Link(from, to)
+		}
+		return CloneFile(from, to)
+	})
+}
+
+// CopyTree recreates the directory tree at src under dst, which must not
+// exist, copying the data of every regular file. Holes in sparse files are
+// preserved. progress, if set, is called with the number of bytes copied by
+// each step; the copy stops with its error if it returns one.
+func CopyTree(src, dst string, progress func(int64) error) error {
+	return walkTree(src, dst, func(from, to string) error {
+		return CopyFile(from, to, progress)
+	})
+}
+
+// walkTree recreates the tree at src under dst, using copyFn for regular
+// files and preserving ownership, modes and modification times.
+func walkTree(src, dst string, copyFn func(from, to string) error) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return fmt.Errorf("Clone destination %s already exists", dst)
+	}
+	// Directory attributes are applied once their contents are in place, so
+	// that read-only directories can be populated.
+	type dir struct {
+		path string
+		fi   os.FileInfo
+	}
+	var dirs []dir
+	err := filepath.Walk(src, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, p)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch mode := fi.Mode(); {
+		case mode.IsDir():
+			if err := os.Mkdir(target, 0700); err != nil {
+				return err
+			}
+			dirs = append(dirs, dir{target, fi})
+			return nil
+		case mode&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case mode.IsRegular():
+			if err := copyFn(p, target); err != nil {
+				return err
+			}
+		default:
+			logrus.Warnf("Skipping %s of unsupported type %v", p, mode.Type())
+			return nil
+		}
+		return copyAttributes(target, fi)
+	})
+	if err != nil {
+		return err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := copyAttributes(dirs[i].path, dirs[i].fi); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CloneFile copies the regular file src to dst, which is created. It tries a
+// reflink first and falls back to CopyFile.
+func CloneFile(src, dst string) error {
+	return openPair(src, dst, func(out, in *os.File, size int64) error {
+		if err := unix.IoctlFileClone(int(out.Fd()), int(in.Fd())); err == nil {
+			return nil
+		} else if !reflinkUnsupported(err) {
+			return err
+		}
+		return copyContents(out, in, size, nil)
+	})
+}
+
+// CopyFile copies the data of the regular file src to dst, which is created,
+// preserving holes. It prefers copy_file_range so that filesystems such as
+// NFS v4.2 can copy server side. progress, if set, is called with the number
+// of bytes copied by each step; the copy stops with its error if it returns
+// one.
+func CopyFile(src, dst string, progress func(int64) error) error {
+	return openPair(src, dst, func(out, in *os.File, size int64) error {
+		return copyContents(out, in, size, progress)
+	})
+}
+
+// Reflink returns nil if the filesystem at dir can share extents between
+// files, which it probes by cloning a scratch file.
+func Reflink(dir string) error {
+	src, err := os.CreateTemp(dir, ".reflink-probe-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(src.Name())
+	defer src.Close()
+	if _, err := src.Write([]byte("probe")); err != nil {
+		return err
+	}
+	dst, err := os.CreateTemp(dir, ".reflink-probe-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(dst.Name())
+	defer dst.Close()
+	return unix.IoctlFileClone(int(dst.Fd()), int(src.Fd()))
+}
+
+func openPair(src, dst string, fn func(out, in *os.File, size int64) error) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	fi, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if err := fn(out, in, fi.Size()); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// reflinkUnsupported returns true if err indicates that the filesystem, or
+// the pair of filesystems, cannot share extents or copy in kernel.
+func reflinkUnsupported(err error) bool {
+	switch err {
+	case unix.EOPNOTSUPP, unix.EXDEV, unix.EINVAL, unix.ENOTTY, unix.ENOSYS:
+		return true
+	}
+	return false
+}
+
+// copyContents copies the data regions of in to out, leaving holes in
+// place, and sizes out to size.
+func copyContents(out, in *os.File, size int64, progress func(int64) error) error {
+	if err := out.Truncate(size); err != nil {
+		return err
+	}
+	var off int64
+	for off < size {
+		data, err := unix.Seek(int(in.Fd()), off, unix.SEEK_DATA)
+		if err == unix.ENXIO {
+			// Only a hole remains.
+			return nil
+		} else if err != nil {
+			// No hole detection, copy the rest.
+			return copyRange(out, in, off, size-off, progress)
+		}
+		hole, err := unix.Seek(int(in.Fd()), data, unix.SEEK_HOLE)
+		if err != nil || hole > size {
+			hole = size
+		}
+		if err := copyRange(out, in, data, hole-data, progress); err != nil {
+			return err
+		}
+		off = hole
+	}
+	return nil
+}
+
+// copyRange copies length bytes at off from in to the same offset in out.
+func copyRange(out, in *os.File, off, length int64, progress func(int64) error) error {
+	for length > 0 {
+		roff, woff := off, off
+		n, err := unix.CopyFileRange(int(in.Fd()), &roff, int(out.Fd()), &woff, int(length), 0)
+		if err == unix.EINTR {
+			continue
+		} else if err != nil {
+			if !reflinkUnsupported(err) {
+				return err
+			}
+			break
+		} else if n == 0 {
+			break
+		}
+		off += int64(n)
+		length -= int64(n)
+		if progress != nil {
+			if err := progress(int64(n)); err != nil {
+				return err
+			}
+		}
+	}
+	if length <= 0 {
+		return nil
+	}
+
+	// Fall back to a userspace copy of the remainder.
+	buf := make([]byte, 1024*1024)
+	for length > 0 {
+		chunk := buf
+		if int64(len(chunk)) > length {
+			chunk = chunk[:length]
+		}
+		n, err := in.ReadAt(chunk, off)
+		if n > 0 {
+			if _, err := out.WriteAt(chunk[:n], off); err != nil {
+				return err
+			}
+			off += int64(n)
+			length -= int64(n)
+			if progress != nil {
+				if err := progress(int64(n)); err != nil {
+					return err
+				}
+			}
+		}
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyAttributes copies ownership, mode and timestamps from fi to p.
+func copyAttributes(p string, fi os.FileInfo) error {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		if err := os.Lchown(p, int(st.Uid), int(st.Gid)); err != nil && !os.IsPermission(err) {
+			return err
+		}
+	}
+	if err := os.Chmod(p, fi.Mode().Perm()|fi.Mode()&(os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+		return err
+	}
+	return os.Chtimes(p, fi.ModTime(), fi.ModTime())
+}
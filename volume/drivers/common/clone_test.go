@@ -0,0 +1,64 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneTree(t *testing.T) {
+	root := t.TempDir()
+	src := filepath.Join(root, "src")
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "a", "b"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "a", "b", "file"), []byte("hello"), 0640))
+	require.NoError(t, os.Symlink("b/file", filepath.Join(src, "a", "link")))
+	require.NoError(t, os.Chmod(filepath.Join(src, "a"), 0555))
+	defer os.Chmod(filepath.Join(src, "a"), 0755)
+
+	for _, hardlink := range []bool{false, true} {
+		dst := filepath.Join(root, "dst")
+		require.NoError(t, CloneTree(src, dst, hardlink))
+
+		data, err := os.ReadFile(filepath.Join(dst, "a", "link"))
+		require.NoError(t, err)
+		assert.Equal(t, "hello", string(data))
+
+		fi, err := os.Stat(filepath.Join(dst, "a"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0555), fi.Mode().Perm())
+
+		srcFi, err := os.Stat(filepath.Join(src, "a", "b", "file"))
+		require.NoError(t, err)
+		dstFi, err := os.Stat(filepath.Join(dst, "a", "b", "file"))
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0640), dstFi.Mode().Perm())
+		assert.Equal(t, hardlink, os.SameFile(srcFi, dstFi))
+
+		// Copies are independent of the source.
+		if !hardlink {
+			require.NoError(t, os.WriteFile(filepath.Join(dst, "a", "b", "file"), []byte("bye"), 0640))
+			data, err = os.ReadFile(filepath.Join(src, "a", "b", "file"))
+			require.NoError(t, err)
+			assert.Equal(t, "hello", string(data))
+		}
+
+		assert.Error(t, CloneTree(src, dst, hardlink), "destination exists")
+		require.NoError(t, os.Chmod(filepath.Join(dst, "a"), 0755))
+		require.NoError(t, os.RemoveAll(dst))
+	}
+}
+
+func TestDiskUsage(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "file"), make([]byte, 8192), 0644))
+	require.NoError(t, os.Link(filepath.Join(root, "file"), filepath.Join(root, "link")))
+
+	bytes, inodes, err := DiskUsage(root)
+	require.NoError(t, err)
+	// The directory and the file; the hardlink is only counted once.
+	assert.Equal(t, uint64(2), inodes)
+	assert.True(t, bytes >= 8192)
+}
@@ -0,0 +1,791 @@
+package common
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/sirupsen/logrus"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+const (
+	// CloudBackupChunkSize is the default size of the chunks the files of
+	// volumes are split in.
+	CloudBackupChunkSize = 4 * 1024 * 1024
+	// CloudBackupChunkGracePeriod is the default time the chunks no backup
+	// refers to are kept since they were last touched by a backup.
+	CloudBackupChunkGracePeriod = 24 * time.Hour
+)
+
+var errCloudBackupStopped = errors.New("Stopped by request")
+
+// CloudBackupVolumes gives the cloud backup engine access to the content of
+// the volumes of a driver.
+type CloudBackupVolumes interface {
+	// BackupSnapshot returns the directory of a point in time copy of the
+	// volume to back up, and a function releasing it.
+	BackupSnapshot(v *api.Volume) (string, func(), error)
+	// RestoreVolume creates the volume a backup is restored to, and returns
+	// it with the directory to write the content of the backup to.
+	RestoreVolume(locator *api.VolumeLocator, spec *api.VolumeSpec) (*api.Volume, string, error)
+	// RestoreDone is called once the content of a restored volume is
+	// written, with the error that stopped the restore if any.
+	RestoreDone(v *api.Volume, err error)
+}
+
+// CloudBackupEngine implements the cloud backups of filesystem volumes. A
+// backup splits the files of a snapshot of the volume in chunks, and uploads
+// those not in the bucket of the s3 credential yet. Incremental backups also
+// reuse the chunks of the files unchanged since the previous backup without
+// reading them. The chunks no backup refers to are deleted with the backups,
+// once no backup has touched them for the grace period, since backups from
+// other nodes and clusters may share the bucket. Group backups and schedules
+// are not supported.
+type CloudBackupEngine struct {
+	volume.CloudBackupDriver
+	// ChunkSize is the size of the chunks files are split in.
+	ChunkSize int
+	// ChunkGracePeriod is the time the chunks no backup refers to are kept
+	// since they were last touched. Backups which run longer than half of
+	// it touch their chunks again before they complete.
+	ChunkGracePeriod time.Duration
+
+	driver     string
+	kvdb       kvdb.Kvdb
+	enumerator volume.Enumerator
+	creds      volume.CredsDriver
+	volumes    CloudBackupVolumes
+
+	lock  sync.Mutex
+	tasks map[string]*cloudBackupTask
+}
+
+// cloudBackupTask controls a backup or restore in progress.
+type cloudBackupTask struct {
+	name   string
+	status *api.CloudBackupStatus
+	lock   sync.Mutex
+	cond   *sync.Cond
+	paused bool
+	stop   bool
+}
+
+// NewCloudBackupEngine returns the cloud backup engine of driver, keeping
+// the status of its tasks in kvdb. Volumes are looked up with enumerator
+// and credentials with creds.
+func NewCloudBackupEngine(
+	driver string,
+	kvdb kvdb.Kvdb,
+	enumerator volume.Enumerator,
+	creds volume.CredsDriver,
+	volumes CloudBackupVolumes,
+) *CloudBackupEngine {
+	return &CloudBackupEngine{
+		CloudBackupDriver: volume.CloudBackupNotSupported,
+		ChunkSize:         CloudBackupChunkSize,
+		ChunkGracePeriod:  CloudBackupChunkGracePeriod,
+		driver:            driver,
+		kvdb:              kvdb,
+		enumerator:        enumerator,
+		creds:             creds,
+		volumes:           volumes,
+		tasks:             make(map[string]*cloudBackupTask),
+	}
+}
+
+func (e *CloudBackupEngine) taskKeyPrefix() string {
+	return fmt.Sprintf("%s/%s/cloudbackups/", keyBase, e.driver)
+}
+
+func (e *CloudBackupEngine) store(credID string) (*cloudStore, error) {
+	params, err := CredsParams(e.creds, credID)
+	if err != nil {
+		return nil, err
+	}
+	return newCloudStore(params)
+}
+
+func (e *CloudBackupEngine) inspect(volumeID string) (*api.Volume, error) {
+	vols, err := e.enumerator.Inspect(correlation.TODO(), []string{volumeID})
+	if err != nil {
+		return nil, err
+	}
+	if len(vols) != 1 {
+		return nil, fmt.Errorf("Volume %s not found", volumeID)
+	}
+	return vols[0], nil
+}
+
+// startTask records a new task and runs fn for it in the background.
+func (e *CloudBackupEngine) startTask(
+	name string,
+	status *api.CloudBackupStatus,
+	fn func(t *cloudBackupTask) error,
+) error {
+	status.Status = api.CloudBackupStatusActive
+	status.StartTime = time.Now()
+	if _, err := e.kvdb.Create(e.taskKeyPrefix()+name, status, 0); err != nil {
+		if err == kvdb.ErrExist {
+			return volume.ErrExist
+		}
+		return err
+	}
+	t := &cloudBackupTask{name: name, status: status}
+	t.cond = sync.NewCond(&t.lock)
+	e.lock.Lock()
+	e.tasks[name] = t
+	e.lock.Unlock()
+
+	go func() {
+		err := fn(t)
+		e.lock.Lock()
+		delete(e.tasks, name)
+		e.lock.Unlock()
+
+		t.lock.Lock()
+		defer t.lock.Unlock()
+		t.status.CompletedTime = time.Now()
+		switch {
+		case err == errCloudBackupStopped:
+			t.status.Status = api.CloudBackupStatusStopped
+		case err != nil:
+			logrus.Errorf("Cloud %s %s of volume %s failed: %v",
+				strings.ToLower(string(t.status.OpType)), name, t.status.SrcVolumeID, err)
+			t.status.Status = api.CloudBackupStatusFailed
+			t.status.Info = []string{err.Error()}
+		default:
+			t.status.Status = api.CloudBackupStatusDone
+		}
+		e.saveTask(t)
+	}()
+	return nil
+}
+
+// saveTask records the status of the task. The task must be locked.
+func (e *CloudBackupEngine) saveTask(t *cloudBackupTask) {
+	if _, err := e.kvdb.Put(e.taskKeyPrefix()+t.name, t.status, 0); err != nil {
+		logrus.Warnf("Failed to record status of cloud backup task %s: %v", t.name, err)
+	}
+}
+
+// progress adds n to the bytes done by the task, then waits while the task
+// is paused. It returns errCloudBackupStopped once the task is stopped.
+func (e *CloudBackupEngine) progress(t *cloudBackupTask, n uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if n > 0 {
+		t.status.BytesDone += n
+		e.saveTask(t)
+	}
+	for t.paused && !t.stop {
+		t.cond.Wait()
+	}
+	if t.stop {
+		return errCloudBackupStopped
+	}
+	return nil
+}
+
+// CloudBackupCreate starts a backup of the volume. It is incremental to the
+// previous backup of the volume in the bucket unless a full backup is
+// requested, or FullBackupFrequency incremental backups were taken since the
+// last full one.
+func (e *CloudBackupEngine) CloudBackupCreate(
+	input *api.CloudBackupCreateRequest,
+) (*api.CloudBackupCreateResponse, error) {
+	v, err := e.inspect(input.VolumeID)
+	if err != nil {
+		return nil, err
+	}
+	store, err := e.store(input.CredentialUUID)
+	if err != nil {
+		return nil, err
+	}
+	infos, err := store.listInfos(v.Id)
+	if err != nil {
+		return nil, err
+	}
+	info := &cloudBackupInfo{
+		ID:         v.Id + "/" + uuid.New(),
+		VolumeID:   v.Id,
+		VolumeName: v.GetLocator().GetName(),
+		Labels:     input.Labels,
+		Spec:       v.Spec,
+	}
+	if len(infos) > 0 && !input.Full {
+		incrementals := 0
+		for i := len(infos) - 1; i >= 0 && infos[i].Parent != ""; i-- {
+			incrementals++
+		}
+		if input.FullBackupFrequency == 0 || uint32(incrementals) < input.FullBackupFrequency {
+			info.Parent = infos[len(infos)-1].ID
+		}
+	}
+
+	name := input.Name
+	if name == "" {
+		name = uuid.New()
+	}
+	status := &api.CloudBackupStatus{
+		ID:             info.ID,
+		OpType:         api.CloudBackupOp,
+		SrcVolumeID:    v.Id,
+		CredentialUUID: input.CredentialUUID,
+	}
+	if err := e.startTask(name, status, func(t *cloudBackupTask) error {
+		return e.backup(t, store, v, info)
+	}); err != nil {
+		return nil, err
+	}
+	return &api.CloudBackupCreateResponse{Name: name}, nil
+}
+
+// backup uploads the files of a snapshot of the volume, then the manifest
+// and the information of the backup. The chunks reused from the parent are
+// touched, and the files whose chunks were collected meanwhile are uploaded
+// again.
+func (e *CloudBackupEngine) backup(
+	t *cloudBackupTask,
+	store *cloudStore,
+	v *api.Volume,
+	info *cloudBackupInfo,
+) error {
+	start := time.Now()
+	parentFiles := make(map[string]*cloudBackupFile)
+	if info.Parent != "" {
+		parent, err := store.getManifest(info.Parent)
+		if err != nil {
+			return err
+		}
+		for i := range parent.Files {
+			parentFiles[parent.Files[i].Path] = &parent.Files[i]
+		}
+	}
+
+	dir, release, err := e.volumes.BackupSnapshot(v)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	files, err := scanFiles(dir)
+	if err != nil {
+		return err
+	}
+	t.lock.Lock()
+	for _, f := range files {
+		if f.Mode.IsRegular() {
+			t.status.BytesTotal += uint64(f.Size)
+		}
+	}
+	e.saveTask(t)
+	t.lock.Unlock()
+
+	buf := make([]byte, e.ChunkSize)
+	for i := range files {
+		f := &files[i]
+		if !f.Mode.IsRegular() {
+			continue
+		}
+		info.Size += uint64(f.Size)
+		if p, ok := parentFiles[f.Path]; ok && p.Mode == f.Mode &&
+			p.Size == f.Size && p.ModTime.Equal(f.ModTime) {
+			reused, err := touchChunks(store, p.Chunks)
+			if err != nil {
+				return err
+			}
+			if reused {
+				f.Chunks = p.Chunks
+				if err := e.progress(t, uint64(f.Size)); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if err := e.backupFile(t, store, filepath.Join(dir, filepath.FromSlash(f.Path)), f, info, buf); err != nil {
+			return err
+		}
+	}
+
+	// The chunks touched at the start of a long backup may be collected
+	// before its manifest is written.
+	if time.Since(start) > e.ChunkGracePeriod/2 {
+		for i := range files {
+			ok, err := touchChunks(store, files[i].Chunks)
+			if err != nil {
+				return err
+			} else if !ok {
+				return fmt.Errorf("Chunks of file %s were deleted during the backup, "+
+					"which took longer than the grace period of the unused chunks", files[i].Path)
+			}
+		}
+	}
+	if err := store.putManifest(info.ID, &cloudBackupManifest{Files: files}); err != nil {
+		return err
+	}
+	info.Timestamp = time.Now()
+	return store.putInfo(info)
+}
+
+// touchChunks touches the chunks, and returns false if one of them does not
+// exist.
+func touchChunks(store *cloudStore, chunks []string) (bool, error) {
+	for _, id := range chunks {
+		if ok, err := store.touchChunk(id); err != nil || !ok {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// backupFile uploads the chunks of the regular file at p.
+func (e *CloudBackupEngine) backupFile(
+	t *cloudBackupTask,
+	store *cloudStore,
+	p string,
+	f *cloudBackupFile,
+	info *cloudBackupInfo,
+	buf []byte,
+) error {
+	file, err := os.Open(p)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	for {
+		n, err := io.ReadFull(file, buf)
+		if n > 0 {
+			id, stored, err := store.putChunk(buf[:n])
+			if err != nil {
+				return err
+			}
+			f.Chunks = append(f.Chunks, id)
+			info.StoredBytes += uint64(stored)
+			if err := e.progress(t, uint64(n)); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// scanFiles returns the files under dir, parents first.
+func scanFiles(dir string) ([]cloudBackupFile, error) {
+	var files []cloudBackupFile
+	err := filepath.Walk(dir, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil || rel == "." {
+			return err
+		}
+		f := cloudBackupFile{
+			Path:    filepath.ToSlash(rel),
+			Mode:    fi.Mode(),
+			ModTime: fi.ModTime(),
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			f.Uid = int(st.Uid)
+			f.Gid = int(st.Gid)
+		}
+		switch mode := fi.Mode(); {
+		case mode.IsRegular():
+			f.Size = fi.Size()
+		case mode&os.ModeSymlink != 0:
+			if f.Link, err = os.Readlink(p); err != nil {
+				return err
+			}
+		case !mode.IsDir():
+			logrus.Warnf("Skipping %s of unsupported type %v", p, mode.Type())
+			return nil
+		}
+		files = append(files, f)
+		return nil
+	})
+	return files, err
+}
+
+// CloudBackupRestore creates a volume from the backup and starts writing
+// the content of the backup to it.
+func (e *CloudBackupEngine) CloudBackupRestore(
+	input *api.CloudBackupRestoreRequest,
+) (*api.CloudBackupRestoreResponse, error) {
+	store, err := e.store(input.CredentialUUID)
+	if err != nil {
+		return nil, err
+	}
+	info, err := store.getInfo(input.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	locator := input.Locator
+	if locator == nil {
+		locator = &api.VolumeLocator{}
+	}
+	if input.RestoreVolumeName != "" {
+		locator.Name = input.RestoreVolumeName
+	}
+	if locator.Name == "" {
+		locator.Name = info.VolumeName + "-restore-" + path.Base(info.ID)
+	}
+	spec := info.Spec
+	if spec == nil {
+		spec = &api.VolumeSpec{}
+	}
+	name := input.Name
+	if name == "" {
+		name = uuid.New()
+	}
+	v, dir, err := e.volumes.RestoreVolume(locator, spec)
+	if err != nil {
+		return nil, err
+	}
+	status := &api.CloudBackupStatus{
+		ID:             info.ID,
+		OpType:         api.CloudRestoreOp,
+		BytesTotal:     info.Size,
+		SrcVolumeID:    v.Id,
+		CredentialUUID: input.CredentialUUID,
+	}
+	if err := e.startTask(name, status, func(t *cloudBackupTask) error {
+		err := e.restore(t, store, info.ID, dir)
+		e.volumes.RestoreDone(v, err)
+		return err
+	}); err != nil {
+		e.volumes.RestoreDone(v, err)
+		return nil, err
+	}
+	return &api.CloudBackupRestoreResponse{
+		RestoreVolumeID: v.Id,
+		Name:            name,
+	}, nil
+}
+
+// restore writes the files of the backup under dir.
+func (e *CloudBackupEngine) restore(t *cloudBackupTask, store *cloudStore, id, dir string) error {
+	m, err := store.getManifest(id)
+	if err != nil {
+		return err
+	}
+	for _, f := range m.Files {
+		p := filepath.Join(dir, filepath.FromSlash(f.Path))
+		switch {
+		case f.Mode.IsDir():
+			if err := os.MkdirAll(p, 0700); err != nil {
+				return err
+			}
+			continue
+		case f.Mode&os.ModeSymlink != 0:
+			if err := os.Symlink(f.Link, p); err != nil {
+				return err
+			}
+			os.Lchown(p, f.Uid, f.Gid)
+			continue
+		}
+		if err := e.restoreFile(t, store, p, &f); err != nil {
+			return err
+		}
+	}
+	// Directory attributes are applied once their contents are in place,
+	// deepest first.
+	for i := len(m.Files) - 1; i >= 0; i-- {
+		if f := m.Files[i]; f.Mode.IsDir() {
+			if err := setAttributes(filepath.Join(dir, filepath.FromSlash(f.Path)), &f); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (e *CloudBackupEngine) restoreFile(t *cloudBackupTask, store *cloudStore, p string, f *cloudBackupFile) error {
+	file, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	for _, c := range f.Chunks {
+		data, err := store.getChunk(c)
+		if err == nil {
+			_, err = file.Write(data)
+		}
+		if err == nil {
+			err = e.progress(t, uint64(len(data)))
+		}
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return setAttributes(p, f)
+}
+
+func setAttributes(p string, f *cloudBackupFile) error {
+	if err := os.Lchown(p, f.Uid, f.Gid); err != nil {
+		logrus.Debugf("Failed to restore ownership of %s: %v", p, err)
+	}
+	if err := os.Chmod(p, f.Mode.Perm()); err != nil {
+		return err
+	}
+	return os.Chtimes(p, f.ModTime, f.ModTime)
+}
+
+// CloudBackupEnumerate lists the complete backups in the bucket of the
+// credential.
+func (e *CloudBackupEngine) CloudBackupEnumerate(
+	input *api.CloudBackupEnumerateRequest,
+) (*api.CloudBackupEnumerateResponse, error) {
+	store, err := e.store(input.CredentialUUID)
+	if err != nil {
+		return nil, err
+	}
+	volumeID := input.SrcVolumeID
+	if input.All {
+		volumeID = ""
+	}
+	infos, err := store.listInfos(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.CloudBackupEnumerateResponse{
+		Backups: make([]api.CloudBackupInfo, 0, len(infos)),
+	}
+	if input.StatusFilter != "" && input.StatusFilter != api.CloudBackupStatusDone {
+		return resp, nil
+	}
+	for _, info := range infos {
+		if input.CloudBackupID != "" && info.ID != input.CloudBackupID {
+			continue
+		}
+		if !hasSubset(info.Labels, input.MetadataFilter) {
+			continue
+		}
+		resp.Backups = append(resp.Backups, info.toInfo())
+	}
+	if input.MaxBackups > 0 && uint64(len(resp.Backups)) > input.MaxBackups {
+		// Keep the most recent ones.
+		resp.Backups = resp.Backups[uint64(len(resp.Backups))-input.MaxBackups:]
+	}
+	return resp, nil
+}
+
+// CloudBackupDelete deletes the backup. Backups do not depend on each other,
+// so Force is not needed. Chunks no backup refers to anymore are deleted
+// once no backup has touched them for the grace period.
+func (e *CloudBackupEngine) CloudBackupDelete(input *api.CloudBackupDeleteRequest) error {
+	store, err := e.store(input.CredentialUUID)
+	if err != nil {
+		return err
+	}
+	if _, err := store.getInfo(input.ID); err != nil {
+		return err
+	}
+	if err := store.deleteBackup(input.ID); err != nil {
+		return err
+	}
+	return e.collectChunks(store)
+}
+
+// CloudBackupDeleteAll deletes the backups of the source volume, or all
+// backups in the bucket.
+func (e *CloudBackupEngine) CloudBackupDeleteAll(input *api.CloudBackupDeleteAllRequest) error {
+	if input.SrcVolumeID == "" && !input.All {
+		return fmt.Errorf("Source volume ID must be set")
+	}
+	store, err := e.store(input.CredentialUUID)
+	if err != nil {
+		return err
+	}
+	volumeID := input.SrcVolumeID
+	if input.All {
+		volumeID = ""
+	}
+	infos, err := store.listInfos(volumeID)
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		if err := store.deleteBackup(info.ID); err != nil {
+			return err
+		}
+	}
+	return e.collectChunks(store)
+}
+
+func (e *CloudBackupEngine) collectChunks(store *cloudStore) error {
+	return store.collectChunks(e.ChunkGracePeriod)
+}
+
+// statuses returns the recorded status of each task.
+func (e *CloudBackupEngine) statuses() (map[string]api.CloudBackupStatus, error) {
+	kvp, err := e.kvdb.Enumerate(e.taskKeyPrefix())
+	if err != nil {
+		return nil, err
+	}
+	statuses := make(map[string]api.CloudBackupStatus, len(kvp))
+	for _, p := range kvp {
+		var status api.CloudBackupStatus
+		if err := json.Unmarshal(p.Value, &status); err != nil {
+			return nil, err
+		}
+		statuses[path.Base(p.Key)] = status
+	}
+	return statuses, nil
+}
+
+// CloudBackupStatus returns the status of the task with the ID of the
+// request, or of the tasks of the source volume.
+func (e *CloudBackupEngine) CloudBackupStatus(
+	input *api.CloudBackupStatusRequest,
+) (*api.CloudBackupStatusResponse, error) {
+	resp := &api.CloudBackupStatusResponse{
+		Statuses: make(map[string]api.CloudBackupStatus),
+	}
+	if input.ID != "" {
+		var status api.CloudBackupStatus
+		if _, err := e.kvdb.GetVal(e.taskKeyPrefix()+input.ID, &status); err != nil {
+			return nil, fmt.Errorf("Cloud backup task %s not found", input.ID)
+		}
+		resp.Statuses[input.ID] = status
+		return resp, nil
+	}
+	statuses, err := e.statuses()
+	if err != nil {
+		return nil, err
+	}
+	for name, status := range statuses {
+		if input.SrcVolumeID == "" || status.SrcVolumeID == input.SrcVolumeID {
+			resp.Statuses[name] = status
+		}
+	}
+	return resp, nil
+}
+
+// CloudBackupCatalog lists the files in the backup.
+func (e *CloudBackupEngine) CloudBackupCatalog(
+	input *api.CloudBackupCatalogRequest,
+) (*api.CloudBackupCatalogResponse, error) {
+	store, err := e.store(input.CredentialUUID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := store.getManifest(input.ID)
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.CloudBackupCatalogResponse{
+		Contents: make([]string, 0, len(m.Files)),
+	}
+	for _, f := range m.Files {
+		resp.Contents = append(resp.Contents, "/"+f.Path)
+	}
+	return resp, nil
+}
+
+// CloudBackupHistory returns the backups taken of the source volume, or of
+// all volumes, oldest first.
+func (e *CloudBackupEngine) CloudBackupHistory(
+	input *api.CloudBackupHistoryRequest,
+) (*api.CloudBackupHistoryResponse, error) {
+	statuses, err := e.statuses()
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.CloudBackupHistoryResponse{
+		HistoryList: make([]api.CloudBackupHistoryItem, 0),
+	}
+	for _, status := range statuses {
+		if status.OpType != api.CloudBackupOp {
+			continue
+		}
+		if input.SrcVolumeID != "" && status.SrcVolumeID != input.SrcVolumeID {
+			continue
+		}
+		resp.HistoryList = append(resp.HistoryList, api.CloudBackupHistoryItem{
+			SrcVolumeID: status.SrcVolumeID,
+			Timestamp:   status.StartTime,
+			Status:      string(status.Status),
+		})
+	}
+	sort.SliceStable(resp.HistoryList, func(i, j int) bool {
+		return resp.HistoryList[i].Timestamp.Before(resp.HistoryList[j].Timestamp)
+	})
+	return resp, nil
+}
+
+// CloudBackupStateChange pauses, resumes or stops a backup or restore in
+// progress.
+func (e *CloudBackupEngine) CloudBackupStateChange(input *api.CloudBackupStateChangeRequest) error {
+	e.lock.Lock()
+	t, ok := e.tasks[input.Name]
+	e.lock.Unlock()
+	if !ok {
+		return fmt.Errorf("No cloud backup task %s in progress", input.Name)
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	switch input.RequestedState {
+	case api.CloudBackupRequestedStatePause:
+		t.paused = true
+		t.status.Status = api.CloudBackupStatusPaused
+	case api.CloudBackupRequestedStateResume:
+		t.paused = false
+		t.status.Status = api.CloudBackupStatusActive
+	case api.CloudBackupRequestedStateStop:
+		t.stop = true
+	default:
+		return fmt.Errorf("Invalid requested state %q", input.RequestedState)
+	}
+	e.saveTask(t)
+	t.cond.Broadcast()
+	return nil
+}
+
+// CloudBackupSize returns the size of the files in the backup.
+func (e *CloudBackupEngine) CloudBackupSize(
+	input *api.SdkCloudBackupSizeRequest,
+) (*api.SdkCloudBackupSizeResponse, error) {
+	store, err := e.store(input.GetCredentialId())
+	if err != nil {
+		return nil, err
+	}
+	info, err := store.getInfo(input.GetBackupId())
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.SdkCloudBackupSizeResponse{
+		Size:                       info.Size,
+		TotalDownloadBytes:         info.Size,
+		CompressedObjectBytes:      info.StoredBytes,
+		CapacityRequiredForRestore: info.Spec.GetSize(),
+	}
+	if resp.CapacityRequiredForRestore < info.Size {
+		resp.CapacityRequiredForRestore = info.Size
+	}
+	return resp, nil
+}
@@ -0,0 +1,488 @@
+package common
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	cloudBackupPrefix = "backups/"
+	cloudChunkPrefix  = "chunks/"
+	cloudInfoObject   = "info.json"
+	cloudManifest     = "manifest"
+)
+
+// cloudBackupInfo describes a backup. It is stored once the chunks and the
+// manifest of the backup are uploaded, so only complete backups have one.
+type cloudBackupInfo struct {
+	// ID of the backup, <volume id>/<uuid>.
+	ID         string
+	VolumeID   string
+	VolumeName string
+	// Parent is the backup this one was taken incrementally to.
+	Parent    string
+	Timestamp time.Time
+	Labels    map[string]string
+	Spec      *api.VolumeSpec
+	// Size of the files of the volume.
+	Size uint64
+	// StoredBytes is the size of the chunks the backup uploaded.
+	StoredBytes uint64
+}
+
+func (i *cloudBackupInfo) toInfo() api.CloudBackupInfo {
+	return api.CloudBackupInfo{
+		ID:            i.ID,
+		SrcVolumeID:   i.VolumeID,
+		SrcVolumeName: i.VolumeName,
+		Timestamp:     i.Timestamp,
+		Metadata:      i.Labels,
+		Status:        string(api.CloudBackupStatusDone),
+	}
+}
+
+// cloudBackupFile is a file of a backed up volume. The content of regular
+// files is the concatenation of their chunks.
+type cloudBackupFile struct {
+	// Path relative to the root of the volume, slash separated.
+	Path    string
+	Mode    os.FileMode
+	Uid     int
+	Gid     int
+	ModTime time.Time
+	Size    int64
+	Link    string   `json:",omitempty"`
+	Chunks  []string `json:",omitempty"`
+}
+
+// cloudBackupManifest lists the files of a backup. Every backup lists all
+// its chunks, so it can be restored or deleted without its parent.
+type cloudBackupManifest struct {
+	Files []cloudBackupFile
+}
+
+// cloudStore keeps the backups of a credential in its S3 bucket. Chunks are
+// named after their content so that equal chunks are stored once across
+// files, backups and volumes. They are compressed, then encrypted with the
+// encryption key of the credential if it has one.
+//
+// Backups from any node or cluster may share the bucket, so the chunks a
+// backup reuses are touched, which renews their modification time, and the
+// chunks no manifest refers to are only collected once they have not been
+// touched for a grace period. Backups must write their manifest within it.
+type cloudStore struct {
+	svc    *s3.S3
+	bucket string
+	key    []byte
+}
+
+// newCloudStore connects to the bucket of an s3 credential, creating the
+// bucket if it does not exist.
+func newCloudStore(params map[string]string) (*cloudStore, error) {
+	if t := params[api.OptCredType]; t != "s3" {
+		return nil, fmt.Errorf("Cloud backups to %q credentials are not supported", t)
+	}
+	bucket := params[api.OptCredBucket]
+	if bucket == "" {
+		return nil, fmt.Errorf("Bucket of the credential is not set")
+	}
+	region := params[api.OptCredRegion]
+	if region == "" {
+		region = "us-east-1"
+	}
+	disableSSL, _ := strconv.ParseBool(params[api.OptCredDisableSSL])
+	disablePathStyle, _ := strconv.ParseBool(params[api.OptCredDisablePathStyle])
+	config := &aws.Config{
+		Region:           aws.String(region),
+		DisableSSL:       aws.Bool(disableSSL),
+		S3ForcePathStyle: aws.Bool(!disablePathStyle),
+		Credentials: credentials.NewStaticCredentials(
+			params[api.OptCredAccessKey],
+			params[api.OptCredSecretKey],
+			"",
+		),
+	}
+	if endpoint := params[api.OptCredEndpoint]; endpoint != "" {
+		config.Endpoint = aws.String(endpoint)
+	}
+	sess, err := session.NewSession(config)
+	if err != nil {
+		return nil, err
+	}
+	s := &cloudStore{
+		svc:    s3.New(sess),
+		bucket: bucket,
+	}
+	if k := params[api.OptCredEncrKey]; k != "" {
+		sum := sha256.Sum256([]byte(k))
+		s.key = sum[:]
+	}
+	if _, err := s.svc.HeadBucket(&s3.HeadBucketInput{Bucket: aws.String(bucket)}); err != nil {
+		if _, err := s.svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)}); err != nil {
+			return nil, fmt.Errorf("Failed to create bucket %s: %v", bucket, err)
+		}
+	}
+	return s, nil
+}
+
+func isNotFound(err error) bool {
+	if aerr, ok := err.(awserr.RequestFailure); ok {
+		return aerr.StatusCode() == 404
+	}
+	return false
+}
+
+func (s *cloudStore) put(key string, data []byte) error {
+	_, err := s.svc.PutObject(&s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *cloudStore) get(key string) ([]byte, error) {
+	out, err := s.svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func (s *cloudStore) delete(key string) error {
+	_, err := s.svc.DeleteObject(&s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// list returns the keys starting with prefix.
+func (s *cloudStore) list(prefix string) ([]string, error) {
+	modified, err := s.listModified(prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(modified))
+	for key := range modified {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// listModified returns the modification time of the keys starting with
+// prefix.
+func (s *cloudStore) listModified(prefix string) (map[string]time.Time, error) {
+	modified := make(map[string]time.Time)
+	err := s.svc.ListObjectsPages(&s3.ListObjectsInput{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}, func(page *s3.ListObjectsOutput, last bool) bool {
+		for _, o := range page.Contents {
+			modified[aws.StringValue(o.Key)] = aws.TimeValue(o.LastModified)
+		}
+		return true
+	})
+	return modified, err
+}
+
+// modified returns the modification time of the object, and false if it
+// does not exist.
+func (s *cloudStore) modified(key string) (time.Time, bool, error) {
+	out, err := s.svc.HeadObject(&s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	return aws.TimeValue(out.LastModified), true, nil
+}
+
+// touch renews the modification time of the object by copying it onto
+// itself. It returns false if the object does not exist.
+func (s *cloudStore) touch(key string) (bool, error) {
+	_, err := s.svc.CopyObject(&s3.CopyObjectInput{
+		Bucket:            aws.String(s.bucket),
+		Key:               aws.String(key),
+		CopySource:        aws.String((&url.URL{Path: s.bucket + "/" + key}).EscapedPath()),
+		MetadataDirective: aws.String(s3.MetadataDirectiveReplace),
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// chunkID names a chunk after its content. With an encryption key the name
+// is keyed too, so that it says nothing about the content.
+func (s *cloudStore) chunkID(data []byte) string {
+	if s.key == nil {
+		sum := sha256.Sum256(data)
+		return hex.EncodeToString(sum[:])
+	}
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// seal compresses data, then encrypts it if the store has a key.
+func (s *cloudStore) seal(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if s.key == nil {
+		return buf.Bytes(), nil
+	}
+	gcm, err := s.gcm()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, buf.Bytes(), nil), nil
+}
+
+// unseal reverses seal.
+func (s *cloudStore) unseal(data []byte) ([]byte, error) {
+	if s.key != nil {
+		gcm, err := s.gcm()
+		if err != nil {
+			return nil, err
+		}
+		if len(data) < gcm.NonceSize() {
+			return nil, fmt.Errorf("Sealed object too short")
+		}
+		data, err = gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to decrypt object, wrong encryption key? %v", err)
+		}
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+func (s *cloudStore) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// putChunk uploads the chunk unless it is stored already, in which case it
+// is touched, and returns its id and the number of bytes uploaded.
+func (s *cloudStore) putChunk(data []byte) (string, int, error) {
+	id := s.chunkID(data)
+	key := cloudChunkPrefix + id
+	if ok, err := s.touch(key); err != nil || ok {
+		return id, 0, err
+	}
+	sealed, err := s.seal(data)
+	if err != nil {
+		return "", 0, err
+	}
+	return id, len(sealed), s.put(key, sealed)
+}
+
+// touchChunk touches the chunk so that it is not collected while a backup
+// refers to it. It returns false if the chunk does not exist.
+func (s *cloudStore) touchChunk(id string) (bool, error) {
+	return s.touch(cloudChunkPrefix + id)
+}
+
+// getChunk downloads the chunk and checks its content against its id.
+func (s *cloudStore) getChunk(id string) ([]byte, error) {
+	sealed, err := s.get(cloudChunkPrefix + id)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get chunk %s: %v", id, err)
+	}
+	data, err := s.unseal(sealed)
+	if err != nil {
+		return nil, err
+	}
+	if s.chunkID(data) != id {
+		return nil, fmt.Errorf("Chunk %s is corrupted", id)
+	}
+	return data, nil
+}
+
+func (s *cloudStore) putManifest(id string, m *cloudBackupManifest) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	sealed, err := s.seal(data)
+	if err != nil {
+		return err
+	}
+	return s.put(cloudBackupPrefix+id+"/"+cloudManifest, sealed)
+}
+
+func (s *cloudStore) getManifest(id string) (*cloudBackupManifest, error) {
+	sealed, err := s.get(cloudBackupPrefix + id + "/" + cloudManifest)
+	if err != nil {
+		return nil, fmt.Errorf("Backup %s not found: %v", id, err)
+	}
+	data, err := s.unseal(sealed)
+	if err != nil {
+		return nil, err
+	}
+	var m cloudBackupManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("Invalid manifest of backup %s: %v", id, err)
+	}
+	return &m, nil
+}
+
+func (s *cloudStore) putInfo(info *cloudBackupInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+	return s.put(cloudBackupPrefix+info.ID+"/"+cloudInfoObject, data)
+}
+
+func (s *cloudStore) getInfo(id string) (*cloudBackupInfo, error) {
+	data, err := s.get(cloudBackupPrefix + id + "/" + cloudInfoObject)
+	if err != nil {
+		return nil, fmt.Errorf("Backup %s not found: %v", id, err)
+	}
+	var info cloudBackupInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("Invalid information of backup %s: %v", id, err)
+	}
+	return &info, nil
+}
+
+// listInfos returns the complete backups of the volume, or of all volumes
+// if volumeID is empty, oldest first.
+func (s *cloudStore) listInfos(volumeID string) ([]*cloudBackupInfo, error) {
+	prefix := cloudBackupPrefix
+	if volumeID != "" {
+		prefix += volumeID + "/"
+	}
+	keys, err := s.list(prefix)
+	if err != nil {
+		return nil, err
+	}
+	var infos []*cloudBackupInfo
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "/"+cloudInfoObject) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(key, cloudBackupPrefix), "/"+cloudInfoObject)
+		info, err := s.getInfo(id)
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, info)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].Timestamp.Before(infos[j].Timestamp)
+	})
+	return infos, nil
+}
+
+// deleteBackup removes the information, then the manifest of the backup.
+// Its chunks are left for collectChunks.
+func (s *cloudStore) deleteBackup(id string) error {
+	if err := s.delete(cloudBackupPrefix + id + "/" + cloudInfoObject); err != nil {
+		return err
+	}
+	return s.delete(cloudBackupPrefix + id + "/" + cloudManifest)
+}
+
+// collectChunks deletes the chunks no manifest in the store refers to, and
+// which were not touched for the grace period. The backups in progress may
+// refer to chunks which are not in a manifest yet, and touch them. The
+// chunks are listed before the manifests, and the modification time of each
+// chunk is read again before it is deleted, so that the chunks touched by
+// backups whose manifest is not listed are kept.
+func (s *cloudStore) collectChunks(grace time.Duration) error {
+	chunks, err := s.listModified(cloudChunkPrefix)
+	if err != nil {
+		return err
+	}
+	keys, err := s.list(cloudBackupPrefix)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, key := range keys {
+		if !strings.HasSuffix(key, "/"+cloudManifest) {
+			continue
+		}
+		id := strings.TrimSuffix(strings.TrimPrefix(key, cloudBackupPrefix), "/"+cloudManifest)
+		m, err := s.getManifest(id)
+		if err != nil {
+			return err
+		}
+		for _, f := range m.Files {
+			for _, c := range f.Chunks {
+				used[c] = true
+			}
+		}
+	}
+	cutoff := time.Now().Add(-grace)
+	for key, modified := range chunks {
+		if used[strings.TrimPrefix(key, cloudChunkPrefix)] || modified.After(cutoff) {
+			continue
+		}
+		modified, ok, err := s.modified(key)
+		if err != nil {
+			return err
+		} else if !ok || modified.After(cutoff) {
+			continue
+		}
+		if err := s.delete(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
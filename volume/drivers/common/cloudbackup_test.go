@@ -0,0 +1,459 @@
+package common
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+// testCloudVolumes keeps the volumes of a test driver in directories under
+// root.
+type testCloudVolumes struct {
+	volume.StoreEnumerator
+	root string
+	// gate, if set, blocks snapshots until it is closed.
+	gate     chan struct{}
+	restored chan error
+}
+
+func (v *testCloudVolumes) create(t *testing.T, name string) (*api.Volume, string) {
+	vol := NewVolume(uuid.New(), api.FSType_FS_TYPE_EXT4, &api.VolumeLocator{Name: name}, &api.Source{}, &api.VolumeSpec{Size: 1 << 20})
+	require.NoError(t, v.CreateVol(vol))
+	dir := filepath.Join(v.root, vol.Id)
+	require.NoError(t, os.Mkdir(dir, 0755))
+	return vol, dir
+}
+
+func (v *testCloudVolumes) BackupSnapshot(vol *api.Volume) (string, func(), error) {
+	if v.gate != nil {
+		<-v.gate
+	}
+	snap := filepath.Join(v.root, vol.Id+"-"+uuid.New())
+	if err := CloneTree(filepath.Join(v.root, vol.Id), snap, false); err != nil {
+		return "", nil, err
+	}
+	return snap, func() { os.RemoveAll(snap) }, nil
+}
+
+func (v *testCloudVolumes) RestoreVolume(locator *api.VolumeLocator, spec *api.VolumeSpec) (*api.Volume, string, error) {
+	vol := NewVolume(uuid.New(), api.FSType_FS_TYPE_EXT4, locator, &api.Source{}, spec)
+	if err := v.CreateVol(vol); err != nil {
+		return nil, "", err
+	}
+	dir := filepath.Join(v.root, vol.Id)
+	return vol, dir, os.Mkdir(dir, 0755)
+}
+
+func (v *testCloudVolumes) RestoreDone(vol *api.Volume, err error) {
+	v.restored <- err
+}
+
+type cloudBackupTest struct {
+	engine  *CloudBackupEngine
+	volumes *testCloudVolumes
+	creds   volume.CredsDriver
+	credID  string
+	backend *s3mem.Backend
+	bucket  string
+	// clock is the time of the objects of the bucket.
+	clock gofakes3.TimeSourceAdvancer
+}
+
+func newCloudBackupTest(t *testing.T) *cloudBackupTest {
+	clock := gofakes3.FixedTimeSource(time.Now())
+	backend := s3mem.New(s3mem.WithTimeSource(clock))
+	server := httptest.NewServer(gofakes3.New(backend).Server())
+	t.Cleanup(server.Close)
+
+	driver := "cloudbackup_test_" + strings.ReplaceAll(uuid.New(), "-", "")
+	kv := kvdb.Instance()
+	volumes := &testCloudVolumes{
+		StoreEnumerator: NewDefaultStoreEnumerator(driver, kv),
+		root:            t.TempDir(),
+		restored:        make(chan error, 1),
+	}
+	creds := NewDefaultCredsDriver(driver, kv)
+	bucket := "backups"
+	credID, err := creds.CredsCreate(map[string]string{
+		api.OptCredType:       "s3",
+		api.OptCredName:       "fake",
+		api.OptCredBucket:     bucket,
+		api.OptCredEndpoint:   server.URL,
+		api.OptCredDisableSSL: "true",
+		api.OptCredAccessKey:  "access",
+		api.OptCredSecretKey:  "secret",
+		api.OptCredEncrKey:    "passphrase",
+	})
+	require.NoError(t, err)
+
+	engine := NewCloudBackupEngine(driver, kv, volumes, creds, volumes)
+	engine.ChunkSize = 4096
+	engine.ChunkGracePeriod = 0
+	return &cloudBackupTest{
+		engine:  engine,
+		volumes: volumes,
+		creds:   creds,
+		credID:  credID,
+		backend: backend,
+		bucket:  bucket,
+		clock:   clock,
+	}
+}
+
+// wait waits for the task to end and returns its status.
+func (c *cloudBackupTest) wait(t *testing.T, name string) api.CloudBackupStatus {
+	var status api.CloudBackupStatus
+	require.Eventually(t, func() bool {
+		resp, err := c.engine.CloudBackupStatus(&api.CloudBackupStatusRequest{ID: name})
+		require.NoError(t, err)
+		status = resp.Statuses[name]
+		return status.Status != api.CloudBackupStatusActive &&
+			status.Status != api.CloudBackupStatusPaused
+	}, 10*time.Second, 10*time.Millisecond)
+	return status
+}
+
+func (c *cloudBackupTest) backup(t *testing.T, req *api.CloudBackupCreateRequest) *cloudBackupInfo {
+	req.CredentialUUID = c.credID
+	resp, err := c.engine.CloudBackupCreate(req)
+	require.NoError(t, err)
+	status := c.wait(t, resp.Name)
+	require.Equal(t, api.CloudBackupStatusDone, status.Status, "%v", status.Info)
+	store, err := c.engine.store(c.credID)
+	require.NoError(t, err)
+	info, err := store.getInfo(status.ID)
+	require.NoError(t, err)
+	return info
+}
+
+func (c *cloudBackupTest) restore(t *testing.T, id string) (string, error) {
+	resp, err := c.engine.CloudBackupRestore(&api.CloudBackupRestoreRequest{
+		ID:             id,
+		CredentialUUID: c.credID,
+	})
+	require.NoError(t, err)
+	err = <-c.volumes.restored
+	c.wait(t, resp.Name)
+	return filepath.Join(c.volumes.root, resp.RestoreVolumeID), err
+}
+
+func (c *cloudBackupTest) chunks(t *testing.T) int {
+	objects, err := c.backend.ListBucket(c.bucket, &gofakes3.Prefix{}, gofakes3.ListBucketPage{})
+	require.NoError(t, err)
+	n := 0
+	for _, o := range objects.Contents {
+		if strings.HasPrefix(o.Key, cloudChunkPrefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func writeFile(t *testing.T, p string, data []byte) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, data, 0640))
+}
+
+func assertSameTree(t *testing.T, expected, actual string) {
+	files, err := scanFiles(expected)
+	require.NoError(t, err)
+	restored, err := scanFiles(actual)
+	require.NoError(t, err)
+	require.Len(t, restored, len(files))
+	for i, f := range files {
+		r := restored[i]
+		assert.Equal(t, f.Path, r.Path)
+		assert.Equal(t, f.Mode, r.Mode, f.Path)
+		assert.Equal(t, f.Link, r.Link, f.Path)
+		if f.Mode.IsRegular() {
+			assert.True(t, f.ModTime.Equal(r.ModTime), f.Path)
+			a, err := os.ReadFile(filepath.Join(expected, f.Path))
+			require.NoError(t, err)
+			b, err := os.ReadFile(filepath.Join(actual, r.Path))
+			require.NoError(t, err)
+			assert.True(t, bytes.Equal(a, b), f.Path)
+		}
+	}
+}
+
+func TestCloudBackupRestore(t *testing.T) {
+	c := newCloudBackupTest(t)
+	vol, dir := c.volumes.create(t, "data")
+
+	big := bytes.Repeat([]byte("0123456789abcdef"), 1024)
+	writeFile(t, filepath.Join(dir, "a", "big"), big)
+	writeFile(t, filepath.Join(dir, "a", "copy"), big)
+	writeFile(t, filepath.Join(dir, "b", "small"), []byte("small"))
+	writeFile(t, filepath.Join(dir, "empty"), nil)
+	require.NoError(t, os.Symlink("a/big", filepath.Join(dir, "link")))
+
+	info := c.backup(t, &api.CloudBackupCreateRequest{
+		VolumeID: vol.Id,
+		Labels:   map[string]string{"app": "db"},
+	})
+	assert.Empty(t, info.Parent)
+	assert.Equal(t, uint64(2*len(big)+5), info.Size)
+	// The copy and the repeated chunks of big are stored once.
+	assert.Equal(t, 2, c.chunks(t))
+
+	enum, err := c.engine.CloudBackupEnumerate(&api.CloudBackupEnumerateRequest{
+		CloudBackupGenericRequest: api.CloudBackupGenericRequest{
+			SrcVolumeID:    vol.Id,
+			CredentialUUID: "fake",
+			MetadataFilter: map[string]string{"app": "db"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, enum.Backups, 1)
+	assert.Equal(t, info.ID, enum.Backups[0].ID)
+	assert.Equal(t, "data", enum.Backups[0].SrcVolumeName)
+
+	catalog, err := c.engine.CloudBackupCatalog(&api.CloudBackupCatalogRequest{
+		ID:             info.ID,
+		CredentialUUID: c.credID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/a", "/a/big", "/a/copy", "/b", "/b/small", "/empty", "/link"}, catalog.Contents)
+
+	restored, err := c.restore(t, info.ID)
+	require.NoError(t, err)
+	assertSameTree(t, dir, restored)
+
+	size, err := c.engine.CloudBackupSize(&api.SdkCloudBackupSizeRequest{
+		BackupId:     info.ID,
+		CredentialId: c.credID,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, info.Size, size.GetSize())
+	assert.Equal(t, uint64(1<<20), size.GetCapacityRequiredForRestore())
+}
+
+func TestCloudBackupIncremental(t *testing.T) {
+	c := newCloudBackupTest(t)
+	vol, dir := c.volumes.create(t, "data")
+
+	writeFile(t, filepath.Join(dir, "same"), bytes.Repeat([]byte("s"), 10000))
+	writeFile(t, filepath.Join(dir, "changed"), bytes.Repeat([]byte("c"), 10000))
+	full := c.backup(t, &api.CloudBackupCreateRequest{VolumeID: vol.Id})
+
+	writeFile(t, filepath.Join(dir, "changed"), bytes.Repeat([]byte("x"), 100))
+	later := time.Now().Add(time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(dir, "changed"), later, later))
+	incremental := c.backup(t, &api.CloudBackupCreateRequest{
+		VolumeID:            vol.Id,
+		FullBackupFrequency: 2,
+	})
+	assert.Equal(t, full.ID, incremental.Parent)
+	assert.Less(t, incremental.StoredBytes, full.StoredBytes)
+
+	second := c.backup(t, &api.CloudBackupCreateRequest{
+		VolumeID:            vol.Id,
+		FullBackupFrequency: 2,
+	})
+	assert.Equal(t, incremental.ID, second.Parent)
+	// FullBackupFrequency incrementals were taken since the full backup.
+	third := c.backup(t, &api.CloudBackupCreateRequest{
+		VolumeID:            vol.Id,
+		FullBackupFrequency: 2,
+	})
+	assert.Empty(t, third.Parent)
+	forced := c.backup(t, &api.CloudBackupCreateRequest{VolumeID: vol.Id, Full: true})
+	assert.Empty(t, forced.Parent)
+
+	// Backups do not depend on their parent.
+	require.NoError(t, c.engine.CloudBackupDelete(&api.CloudBackupDeleteRequest{
+		ID:             full.ID,
+		CredentialUUID: c.credID,
+	}))
+	restored, err := c.restore(t, incremental.ID)
+	require.NoError(t, err)
+	assertSameTree(t, dir, restored)
+
+	history, err := c.engine.CloudBackupHistory(&api.CloudBackupHistoryRequest{SrcVolumeID: vol.Id})
+	require.NoError(t, err)
+	assert.Len(t, history.HistoryList, 5)
+
+	require.NoError(t, c.engine.CloudBackupDeleteAll(&api.CloudBackupDeleteAllRequest{
+		CloudBackupGenericRequest: api.CloudBackupGenericRequest{
+			SrcVolumeID:    vol.Id,
+			CredentialUUID: c.credID,
+		},
+	}))
+	enum, err := c.engine.CloudBackupEnumerate(&api.CloudBackupEnumerateRequest{
+		CloudBackupGenericRequest: api.CloudBackupGenericRequest{
+			All:            true,
+			CredentialUUID: c.credID,
+		},
+	})
+	require.NoError(t, err)
+	assert.Empty(t, enum.Backups)
+	assert.Equal(t, 0, c.chunks(t))
+}
+
+func TestCloudBackupChunkGracePeriod(t *testing.T) {
+	c := newCloudBackupTest(t)
+	c.engine.ChunkGracePeriod = time.Hour
+	vol, dir := c.volumes.create(t, "data")
+	data := bytes.Repeat([]byte("d"), 100)
+	writeFile(t, filepath.Join(dir, "file"), data)
+	c.clock.Advance(-3 * time.Hour)
+	info := c.backup(t, &api.CloudBackupCreateRequest{VolumeID: vol.Id})
+	require.Equal(t, 1, c.chunks(t))
+
+	// A backup from another node reuses the chunk while the only backup
+	// referring to it is deleted.
+	c.clock.Advance(3 * time.Hour)
+	store, err := c.engine.store(c.credID)
+	require.NoError(t, err)
+	_, uploaded, err := store.putChunk(data)
+	require.NoError(t, err)
+	assert.Zero(t, uploaded)
+	require.NoError(t, c.engine.CloudBackupDelete(&api.CloudBackupDeleteRequest{
+		ID:             info.ID,
+		CredentialUUID: c.credID,
+	}))
+	assert.Equal(t, 1, c.chunks(t))
+	c.engine.ChunkGracePeriod = 0
+	require.NoError(t, c.engine.collectChunks(store))
+	assert.Equal(t, 0, c.chunks(t))
+
+	// The files whose chunks were collected are not reused by incrementals.
+	full := c.backup(t, &api.CloudBackupCreateRequest{VolumeID: vol.Id})
+	_, err = c.backend.DeleteObject(c.bucket, cloudChunkPrefix+store.chunkID(data))
+	require.NoError(t, err)
+	incremental := c.backup(t, &api.CloudBackupCreateRequest{
+		VolumeID:            vol.Id,
+		FullBackupFrequency: 2,
+	})
+	assert.Equal(t, full.ID, incremental.Parent)
+	restored, err := c.restore(t, incremental.ID)
+	require.NoError(t, err)
+	assertSameTree(t, dir, restored)
+}
+
+func TestCloudBackupStateChange(t *testing.T) {
+	c := newCloudBackupTest(t)
+	vol, dir := c.volumes.create(t, "data")
+	writeFile(t, filepath.Join(dir, "file"), bytes.Repeat([]byte("0123456789"), 10000))
+
+	c.volumes.gate = make(chan struct{})
+	resp, err := c.engine.CloudBackupCreate(&api.CloudBackupCreateRequest{
+		VolumeID:       vol.Id,
+		CredentialUUID: c.credID,
+		Name:           "paused",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "paused", resp.Name)
+	_, err = c.engine.CloudBackupCreate(&api.CloudBackupCreateRequest{
+		VolumeID:       vol.Id,
+		CredentialUUID: c.credID,
+		Name:           "paused",
+	})
+	assert.Equal(t, volume.ErrExist, err)
+
+	require.NoError(t, c.engine.CloudBackupStateChange(&api.CloudBackupStateChangeRequest{
+		Name:           "paused",
+		RequestedState: api.CloudBackupRequestedStatePause,
+	}))
+	close(c.volumes.gate)
+	status, err := c.engine.CloudBackupStatus(&api.CloudBackupStatusRequest{SrcVolumeID: vol.Id})
+	require.NoError(t, err)
+	assert.Equal(t, api.CloudBackupStatusPaused, status.Statuses["paused"].Status)
+	// The backup stops at its first chunk.
+	require.Eventually(t, func() bool {
+		status, err := c.engine.CloudBackupStatus(&api.CloudBackupStatusRequest{ID: "paused"})
+		require.NoError(t, err)
+		return status.Statuses["paused"].BytesDone > 0
+	}, 10*time.Second, 10*time.Millisecond)
+	status, err = c.engine.CloudBackupStatus(&api.CloudBackupStatusRequest{ID: "paused"})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(c.engine.ChunkSize), status.Statuses["paused"].BytesDone)
+
+	require.NoError(t, c.engine.CloudBackupStateChange(&api.CloudBackupStateChangeRequest{
+		Name:           "paused",
+		RequestedState: api.CloudBackupRequestedStateResume,
+	}))
+	assert.Equal(t, api.CloudBackupStatusDone, c.wait(t, "paused").Status)
+	assert.Error(t, c.engine.CloudBackupStateChange(&api.CloudBackupStateChangeRequest{
+		Name:           "paused",
+		RequestedState: api.CloudBackupRequestedStateStop,
+	}))
+
+	c.volumes.gate = make(chan struct{})
+	_, err = c.engine.CloudBackupCreate(&api.CloudBackupCreateRequest{
+		VolumeID:       vol.Id,
+		CredentialUUID: c.credID,
+		Name:           "stopped",
+		Full:           true,
+	})
+	require.NoError(t, err)
+	assert.Error(t, c.engine.CloudBackupStateChange(&api.CloudBackupStateChangeRequest{
+		Name:           "stopped",
+		RequestedState: "rewind",
+	}))
+	require.NoError(t, c.engine.CloudBackupStateChange(&api.CloudBackupStateChangeRequest{
+		Name:           "stopped",
+		RequestedState: api.CloudBackupRequestedStateStop,
+	}))
+	close(c.volumes.gate)
+	assert.Equal(t, api.CloudBackupStatusStopped, c.wait(t, "stopped").Status)
+
+	history, err := c.engine.CloudBackupHistory(&api.CloudBackupHistoryRequest{SrcVolumeID: vol.Id})
+	require.NoError(t, err)
+	require.Len(t, history.HistoryList, 2)
+	assert.Equal(t, string(api.CloudBackupStatusDone), history.HistoryList[0].Status)
+	assert.Equal(t, string(api.CloudBackupStatusStopped), history.HistoryList[1].Status)
+
+	// The stopped backup is not listed.
+	enum, err := c.engine.CloudBackupEnumerate(&api.CloudBackupEnumerateRequest{
+		CloudBackupGenericRequest: api.CloudBackupGenericRequest{
+			SrcVolumeID:    vol.Id,
+			CredentialUUID: c.credID,
+		},
+	})
+	require.NoError(t, err)
+	assert.Len(t, enum.Backups, 1)
+}
+
+func TestCloudBackupEncryption(t *testing.T) {
+	c := newCloudBackupTest(t)
+	vol, dir := c.volumes.create(t, "data")
+	secret := []byte("secret content of the volume")
+	writeFile(t, filepath.Join(dir, "file"), secret)
+	info := c.backup(t, &api.CloudBackupCreateRequest{VolumeID: vol.Id})
+
+	objects, err := c.backend.ListBucket(c.bucket, &gofakes3.Prefix{}, gofakes3.ListBucketPage{})
+	require.NoError(t, err)
+	for _, o := range objects.Contents {
+		obj, err := c.backend.GetObject(c.bucket, o.Key, nil)
+		require.NoError(t, err)
+		var data bytes.Buffer
+		_, err = data.ReadFrom(obj.Contents)
+		obj.Contents.Close()
+		require.NoError(t, err)
+		assert.NotContains(t, data.String(), string(secret), o.Key)
+		assert.NotContains(t, data.String(), "file", o.Key)
+	}
+
+	// Restoring with another key fails.
+	params, err := CredsParams(c.creds, c.credID)
+	require.NoError(t, err)
+	params[api.OptCredEncrKey] = "wrong"
+	require.NoError(t, c.creds.CredsUpdate(c.credID, params))
+	_, err = c.restore(t, info.ID)
+	assert.Error(t, err)
+}
@@ -1,12 +1,18 @@
 package common
 
 import (
+	"time"
+
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/pkg/proto/time"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/portworx/kvdb"
 )
 
+// UsageRefreshInterval is how often drivers using RefreshUsage recompute the
+// usage of their mounted volumes.
+const UsageRefreshInterval = time.Minute
+
 // NewVolume returns a new api.Volume for a driver Create call.
 func NewVolume(
 	volumeID string,
@@ -32,3 +38,21 @@ func NewVolume(
 func NewDefaultStoreEnumerator(driver string, kvdb kvdb.Kvdb) volume.StoreEnumerator {
 	return newDefaultStoreEnumerator(driver, kvdb)
 }
+
+// UpdateVolFunc changes a volume of the store with fn. Volumes of the
+// default store enumerator are saved with a compare and set, so that
+// concurrent updates of their other fields are not overwritten. Nothing is
+// saved if fn returns false.
+func UpdateVolFunc(store volume.Store, volumeID string, fn func(v *api.Volume) bool) error {
+	if e, ok := store.(*defaultStoreEnumerator); ok {
+		return e.updateVolFunc(volumeID, fn)
+	}
+	v, err := store.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if !fn(v) {
+		return nil
+	}
+	return store.UpdateVol(v)
+}
@@ -0,0 +1,125 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+type defaultCredsDriver struct {
+	driver string
+	kvdb   kvdb.Kvdb
+}
+
+type storedCred struct {
+	ID     string
+	Params map[string]string
+}
+
+// NewDefaultCredsDriver returns a CredsDriver keeping the credentials of the
+// driver in kvdb. Validating a credential only checks that it exists.
+func NewDefaultCredsDriver(driver string, kvdb kvdb.Kvdb) volume.CredsDriver {
+	return &defaultCredsDriver{
+		driver: driver,
+		kvdb:   kvdb,
+	}
+}
+
+func (c *defaultCredsDriver) credKeyPrefix() string {
+	return fmt.Sprintf("%s/%s/credentials/", keyBase, c.driver)
+}
+
+func (c *defaultCredsDriver) CredsCreate(params map[string]string) (string, error) {
+	id := uuid.New()
+	if _, err := c.kvdb.Create(c.credKeyPrefix()+id, &storedCred{
+		ID:     id,
+		Params: params,
+	}, 0); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func (c *defaultCredsDriver) CredsUpdate(name string, params map[string]string) error {
+	if _, err := c.kvdb.Update(c.credKeyPrefix()+name, &storedCred{
+		ID:     name,
+		Params: params,
+	}, 0); err != nil {
+		if err == kvdb.ErrNotFound {
+			return fmt.Errorf("Credential id %s not found", name)
+		}
+		return err
+	}
+	return nil
+}
+
+func (c *defaultCredsDriver) CredsDelete(credUUID string) error {
+	if _, err := c.kvdb.Delete(c.credKeyPrefix() + credUUID); err != nil && err != kvdb.ErrNotFound {
+		return err
+	}
+	return nil
+}
+
+// CredsEnumerate returns the parameters of each credential by id, as
+// map[string]interface{} like the SDK expects.
+func (c *defaultCredsDriver) CredsEnumerate() (map[string]interface{}, error) {
+	kvp, err := c.kvdb.Enumerate(c.credKeyPrefix())
+	if err != nil {
+		return nil, err
+	}
+	creds := make(map[string]interface{}, len(kvp))
+	for _, v := range kvp {
+		var cred storedCred
+		if err := json.Unmarshal(v.Value, &cred); err != nil {
+			return nil, err
+		}
+		params := make(map[string]interface{}, len(cred.Params))
+		for k, v := range cred.Params {
+			params[k] = v
+		}
+		creds[cred.ID] = params
+	}
+	return creds, nil
+}
+
+func (c *defaultCredsDriver) CredsValidate(credUUID string) error {
+	if _, err := c.kvdb.Get(c.credKeyPrefix() + credUUID); err != nil {
+		return fmt.Errorf("Credential id %s not found", credUUID)
+	}
+	return nil
+}
+
+func (c *defaultCredsDriver) CredsDeleteReferences(credUUID string) error {
+	return nil
+}
+
+// CredsParams returns the parameters of the credential with the id or name
+// credID.
+func CredsParams(creds volume.CredsDriver, credID string) (map[string]string, error) {
+	all, err := creds.CredsEnumerate()
+	if err != nil {
+		return nil, err
+	}
+	for id, v := range all {
+		info, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if id != credID && info[api.OptCredName] != credID {
+			continue
+		}
+		params := make(map[string]string, len(info))
+		for k, v := range info {
+			if s, ok := v.(string); ok {
+				params[k] = s
+			}
+		}
+		return params, nil
+	}
+	return nil, fmt.Errorf("Credential id %s not found", credID)
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/portworx/kvdb"
 
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/parser"
 )
 
 const (
@@ -78,6 +79,33 @@ func (e *defaultStoreEnumerator) UpdateVol(vol *api.Volume) error {
 	return err
 }
 
+// updateVolFunc re-reads the volume, changes it with fn and saves it only if
+// it was not updated in the meantime, retrying otherwise, so that concurrent
+// updates of its other fields are kept. Nothing is saved if fn returns
+// false.
+func (e *defaultStoreEnumerator) updateVolFunc(volumeID string, fn func(v *api.Volume) bool) error {
+	for {
+		var v api.Volume
+		kvp, err := e.kvdb.GetVal(e.volKey(volumeID), &v)
+		if err != nil {
+			return err
+		}
+		if !fn(&v) {
+			return nil
+		}
+		value, err := json.Marshal(&v)
+		if err != nil {
+			return err
+		}
+		kvp.Value = value
+		_, err = e.kvdb.CompareAndSet(kvp, kvdb.KVModifiedIndex, nil)
+		if err == kvdb.ErrValueMismatch || err == kvdb.ErrModified {
+			continue
+		}
+		return err
+	}
+}
+
 // DeleteVol. Returns error if volume does not exist.
 func (e *defaultStoreEnumerator) DeleteVol(volumeID string) error {
 	volumeID = e.toID(volumeID)
@@ -234,5 +262,8 @@ func match(
 	if locator.Name != "" && v.Locator.Name != locator.Name {
 		return false
 	}
+	if !parser.MatchLabelSelector(v.GetLocator().GetVolumeLabels(), locator.GetLabelSelector()) {
+		return false
+	}
 	return hasSubset(v.Locator.VolumeLabels, locator.VolumeLabels)
 }
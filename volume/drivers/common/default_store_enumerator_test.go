@@ -11,6 +11,7 @@ import (
 	"github.com/portworx/kvdb"
 	"github.com/portworx/kvdb/mem"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var (
@@ -90,6 +91,21 @@ func TestEnumerate(t *testing.T) {
 	if len(volumes) == 1 {
 		assert.Equal(t, volumes[0].Id, volume.Id, "Invalid volume returned in Enumerate")
 	}
+	volumes, err = testEnumerator.Enumerate(&api.VolumeLocator{
+		LabelSelector: []*api.LabelSelectorRequirement{
+			{Key: "Foo", Operator: api.LabelSelectorRequirement_In, Values: []string{"A", "DEADBEEF"}},
+			{Key: "Bar", Operator: api.LabelSelectorRequirement_DoesNotExist},
+		},
+	}, nil)
+	assert.NoError(t, err, "Failed in Enumerate")
+	assert.Equal(t, len(volumes), 1, "Number of volumes returned in enumerate should be 1")
+	volumes, err = testEnumerator.Enumerate(&api.VolumeLocator{
+		LabelSelector: []*api.LabelSelectorRequirement{
+			{Key: "Foo", Operator: api.LabelSelectorRequirement_NotIn, Values: []string{"DEADBEEF"}},
+		},
+	}, nil)
+	assert.NoError(t, err, "Failed in Enumerate")
+	assert.Equal(t, len(volumes), 0, "Number of volumes returned in enumerate should be 0")
 	err = testEnumerator.DeleteVol(volume.Id)
 	assert.NoError(t, err, "Failed in Delete")
 	volumes, err = testEnumerator.Enumerate(&api.VolumeLocator{Name: volume.Id}, nil)
@@ -141,6 +157,45 @@ func TestSnapEnumerate(t *testing.T) {
 	assert.NoError(t, err, "Failed in Delete")
 }
 
+func TestUpdateVolFunc(t *testing.T) {
+	vol := newTestVolume("TestUpdateVolFunc")
+	require.NoError(t, testEnumerator.CreateVol(vol))
+	defer testEnumerator.DeleteVol(vol.Id)
+
+	// A concurrent update between the read and the write is kept
+	calls := 0
+	err := UpdateVolFunc(testEnumerator, vol.Id, func(v *api.Volume) bool {
+		calls++
+		if calls == 1 {
+			concurrent := newTestVolume(vol.Id)
+			concurrent.AttachPath = []string{"/mnt/test"}
+			require.NoError(t, testEnumerator.UpdateVol(concurrent))
+		}
+		v.Usage = 1024
+		return true
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	v, err := testEnumerator.GetVol(vol.Id)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1024), v.Usage)
+	assert.Equal(t, []string{"/mnt/test"}, v.AttachPath)
+
+	// Nothing is saved if fn returns false
+	err = UpdateVolFunc(testEnumerator, vol.Id, func(v *api.Volume) bool {
+		v.Usage = 0
+		return false
+	})
+	require.NoError(t, err)
+	v, err = testEnumerator.GetVol(vol.Id)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1024), v.Usage)
+
+	assert.Error(t, UpdateVolFunc(testEnumerator, "missing", func(v *api.Volume) bool {
+		return true
+	}))
+}
+
 func newTestVolume(id string) *api.Volume {
 	return &api.Volume{
 		Id:      id,
@@ -0,0 +1,384 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"golang.org/x/sys/unix"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	// CgroupPath is where the cgroup v2 hierarchy is mounted.
+	CgroupPath = "/sys/fs/cgroup"
+	// QosCgroup is the default cgroup, relative to CgroupPath, whose I/O
+	// is throttled. Workloads using throttled volumes must run in it or in
+	// one of its descendants, for instance as part of a systemd slice of
+	// the same name.
+	QosCgroup = "openstorage.slice"
+	// QosCgroupParam is the driver parameter overriding QosCgroup.
+	QosCgroupParam = "qos_cgroup"
+
+	ioMaxFile         = "io.max"
+	ioStatFile        = "io.stat"
+	cgroupControllers = "cgroup.controllers"
+	cgroupSubtreeCtl  = "cgroup.subtree_control"
+	cgroupProcs       = "cgroup.procs"
+	ioControllerName  = "io"
+	ioMaxUnlimited    = "max"
+	bytesPerMegabyte  = 1024 * 1024
+)
+
+// QosEnforcer enforces the IoThrottle of volumes with the cgroup v2 io
+// controller. Limits are set in io.max of a single cgroup, one line per
+// backing device, and only apply to the I/O of processes in that cgroup.
+// The cgroup limits a device, not a volume, so volumes sharing a device,
+// such as the directories of a filesystem driver, must be given the same
+// limits.
+type QosEnforcer struct {
+	root   string
+	cgroup string
+
+	sync.Mutex
+	volumes map[string]*qosVolume
+}
+
+type qosVolume struct {
+	device   string
+	throttle *api.IoThrottle
+	// last are the counters of the device when stats were last reported.
+	last     ioCounters
+	lastTime time.Time
+}
+
+// ioCounters are the counters of a device in io.stat.
+type ioCounters struct {
+	rbytes, wbytes, rios, wios, dbytes, dios uint64
+}
+
+// NewQosEnforcer returns an enforcer throttling I/O of the cgroup at path,
+// relative to CgroupPath. An empty path selects QosCgroup.
+func NewQosEnforcer(path string) *QosEnforcer {
+	if path == "" {
+		path = QosCgroup
+	}
+	return newQosEnforcer(CgroupPath, path)
+}
+
+func newQosEnforcer(root, path string) *QosEnforcer {
+	return &QosEnforcer{
+		root:    root,
+		cgroup:  filepath.Join(root, path),
+		volumes: make(map[string]*qosVolume),
+	}
+}
+
+// Throttled returns true if throttle limits any of read or write IOPS or
+// bandwidth.
+func Throttled(throttle *api.IoThrottle) bool {
+	return throttle.GetReadIops() != 0 || throttle.GetWriteIops() != 0 ||
+		throttle.GetReadBwMbytes() != 0 || throttle.GetWriteBwMbytes() != 0
+}
+
+// Apply limits the I/O of the volume to throttle. source is the block device
+// of the volume or, for volumes that are not block devices, a path whose
+// backing device is throttled. Applying a throttle without limits removes
+// them.
+func (q *QosEnforcer) Apply(volumeID, source string, throttle *api.IoThrottle) error {
+	if !Throttled(throttle) {
+		return q.Remove(volumeID)
+	}
+	device, err := majorMinor(source)
+	if err != nil {
+		return err
+	}
+
+	q.Lock()
+	defer q.Unlock()
+	for id, vol := range q.volumes {
+		if id != volumeID && vol.device == device && !proto.Equal(vol.throttle, throttle) {
+			return fmt.Errorf("Volume %v shares device %v with volume %v, "+
+				"whose I/O limits differ", volumeID, device, id)
+		}
+	}
+	if err := q.setup(); err != nil {
+		return err
+	}
+	old, ok := q.volumes[volumeID]
+	vol := &qosVolume{
+		device:   device,
+		throttle: throttle,
+		lastTime: time.Now(),
+	}
+	if ok && old.device == device {
+		vol.last, vol.lastTime = old.last, old.lastTime
+	} else if counters, err := q.counters(device); err == nil {
+		vol.last = counters
+	}
+	q.volumes[volumeID] = vol
+	if err := q.writeLimits(device); err != nil {
+		if ok {
+			q.volumes[volumeID] = old
+		} else {
+			delete(q.volumes, volumeID)
+		}
+		return err
+	}
+	if ok && old.device != device {
+		return q.writeLimits(old.device)
+	}
+	return nil
+}
+
+// Remove lifts the limits of the volume. It is a no-op for volumes that are
+// not throttled.
+func (q *QosEnforcer) Remove(volumeID string) error {
+	q.Lock()
+	defer q.Unlock()
+	vol, ok := q.volumes[volumeID]
+	if !ok {
+		return nil
+	}
+	delete(q.volumes, volumeID)
+	return q.writeLimits(vol.device)
+}
+
+// Throttle returns the limits applied to the volume, or nil if it is not
+// throttled.
+func (q *QosEnforcer) Throttle(volumeID string) *api.IoThrottle {
+	q.Lock()
+	defer q.Unlock()
+	if vol, ok := q.volumes[volumeID]; ok {
+		return vol.throttle
+	}
+	return nil
+}
+
+// AddProcess moves the process into the throttled cgroup. The pid must come
+// from a trusted caller in this process, never from the options of a
+// request, which would let any caller throttle the processes of the host.
+func (q *QosEnforcer) AddProcess(pid int) error {
+	q.Lock()
+	defer q.Unlock()
+	if err := q.setup(); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(q.cgroup, cgroupProcs), []byte(strconv.Itoa(pid)), 0644); err != nil {
+		return fmt.Errorf("Failed to move process %d to %s: %v", pid, q.cgroup, err)
+	}
+	return nil
+}
+
+// UpdateStats replaces the I/O counters in stats with the I/O the throttled
+// cgroup did on the device of the volume, which is what counts against its
+// limits. Unless cumulative, counters are those since the previous call and
+// IntervalMs is the time they were collected over. Stats of volumes that
+// are not throttled are left alone.
+func (q *QosEnforcer) UpdateStats(volumeID string, cumulative bool, stats *api.Stats) error {
+	q.Lock()
+	defer q.Unlock()
+	vol, ok := q.volumes[volumeID]
+	if !ok {
+		return nil
+	}
+	counters, err := q.counters(vol.device)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	reported := counters
+	if !cumulative {
+		reported = counters.sub(vol.last)
+		stats.IntervalMs = uint64(now.Sub(vol.lastTime) / time.Millisecond)
+		vol.last, vol.lastTime = counters, now
+	}
+	stats.Reads = reported.rios
+	stats.ReadBytes = reported.rbytes
+	stats.Writes = reported.wios
+	stats.WriteBytes = reported.wbytes
+	stats.Discards = reported.dios
+	stats.DiscardBytes = reported.dbytes
+	return nil
+}
+
+// setup creates the cgroup and enables the io controller for it.
+func (q *QosEnforcer) setup() error {
+	data, err := os.ReadFile(filepath.Join(q.root, cgroupControllers))
+	if err != nil {
+		return fmt.Errorf("cgroup v2 is not available at %s: %v", q.root, err)
+	}
+	if !hasField(string(data), ioControllerName) {
+		return fmt.Errorf("cgroup v2 io controller is not available at %s", q.root)
+	}
+	if err := os.MkdirAll(q.cgroup, 0755); err != nil {
+		return err
+	}
+	// The controller must be enabled in every ancestor of the cgroup, from
+	// the root down.
+	var ancestors []string
+	for dir := filepath.Dir(q.cgroup); strings.HasPrefix(dir, q.root); dir = filepath.Dir(dir) {
+		ancestors = append([]string{dir}, ancestors...)
+		if dir == q.root {
+			break
+		}
+	}
+	for _, dir := range ancestors {
+		if err := enableController(dir, ioControllerName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeLimits sets io.max of the device to the strictest limits of the
+// volumes on it, or lifts them if there are none.
+func (q *QosEnforcer) writeLimits(device string) error {
+	limits := &api.IoThrottle{}
+	for _, vol := range q.volumes {
+		if vol.device == device {
+			limits = strictest(limits, vol.throttle)
+		}
+	}
+	line := fmt.Sprintf("%s rbps=%s wbps=%s riops=%s wiops=%s",
+		device,
+		ioMax(uint64(limits.ReadBwMbytes)*bytesPerMegabyte),
+		ioMax(uint64(limits.WriteBwMbytes)*bytesPerMegabyte),
+		ioMax(uint64(limits.ReadIops)),
+		ioMax(uint64(limits.WriteIops)),
+	)
+	if err := os.WriteFile(filepath.Join(q.cgroup, ioMaxFile), []byte(line), 0644); err != nil {
+		return fmt.Errorf("Failed to set %s of %s to %q: %v", ioMaxFile, q.cgroup, line, err)
+	}
+	return nil
+}
+
+// counters returns the io.stat counters of the device in the cgroup. A
+// device without I/O has no line and zero counters.
+func (q *QosEnforcer) counters(device string) (ioCounters, error) {
+	var c ioCounters
+	f, err := os.Open(filepath.Join(q.cgroup, ioStatFile))
+	if err != nil {
+		return c, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] != device {
+			continue
+		}
+		for _, kv := range fields[1:] {
+			parts := strings.SplitN(kv, "=", 2)
+			if len(parts) != 2 {
+				continue
+			}
+			n, err := strconv.ParseUint(parts[1], 10, 64)
+			if err != nil {
+				continue
+			}
+			switch parts[0] {
+			case "rbytes":
+				c.rbytes = n
+			case "wbytes":
+				c.wbytes = n
+			case "rios":
+				c.rios = n
+			case "wios":
+				c.wios = n
+			case "dbytes":
+				c.dbytes = n
+			case "dios":
+				c.dios = n
+			}
+		}
+		break
+	}
+	return c, scanner.Err()
+}
+
+// sub returns the counters accumulated since prev. Counters reset when the
+// device goes away, in which case all of c is new.
+func (c ioCounters) sub(prev ioCounters) ioCounters {
+	if c.rbytes < prev.rbytes || c.wbytes < prev.wbytes ||
+		c.rios < prev.rios || c.wios < prev.wios ||
+		c.dbytes < prev.dbytes || c.dios < prev.dios {
+		return c
+	}
+	return ioCounters{
+		rbytes: c.rbytes - prev.rbytes,
+		wbytes: c.wbytes - prev.wbytes,
+		rios:   c.rios - prev.rios,
+		wios:   c.wios - prev.wios,
+		dbytes: c.dbytes - prev.dbytes,
+		dios:   c.dios - prev.dios,
+	}
+}
+
+// majorMinor returns the MAJ:MIN of the block device at source, or of the
+// device holding source if it is not a block device.
+func majorMinor(source string) (string, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(source, &st); err != nil {
+		return "", err
+	}
+	dev := uint64(st.Dev)
+	if st.Mode&syscall.S_IFMT == syscall.S_IFBLK {
+		dev = uint64(st.Rdev)
+	}
+	return fmt.Sprintf("%d:%d", unix.Major(dev), unix.Minor(dev)), nil
+}
+
+// strictest returns the lowest of the non zero limits of a and b.
+func strictest(a, b *api.IoThrottle) *api.IoThrottle {
+	lowest := func(x, y uint32) uint32 {
+		if x == 0 || (y != 0 && y < x) {
+			return y
+		}
+		return x
+	}
+	return &api.IoThrottle{
+		ReadIops:      lowest(a.ReadIops, b.ReadIops),
+		WriteIops:     lowest(a.WriteIops, b.WriteIops),
+		ReadBwMbytes:  lowest(a.ReadBwMbytes, b.ReadBwMbytes),
+		WriteBwMbytes: lowest(a.WriteBwMbytes, b.WriteBwMbytes),
+	}
+}
+
+func ioMax(v uint64) string {
+	if v == 0 {
+		return ioMaxUnlimited
+	}
+	return strconv.FormatUint(v, 10)
+}
+
+// enableController enables the controller for the children of the cgroup at
+// dir.
+func enableController(dir, controller string) error {
+	p := filepath.Join(dir, cgroupSubtreeCtl)
+	if data, err := os.ReadFile(p); err == nil && hasField(string(data), controller) {
+		return nil
+	}
+	if err := os.WriteFile(p, []byte("+"+controller), 0644); err != nil {
+		return fmt.Errorf("Failed to enable %s controller in %s: %v", controller, dir, err)
+	}
+	return nil
+}
+
+func hasField(s, field string) bool {
+	for _, f := range strings.Fields(s) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,176 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+// newTestQos returns an enforcer on a fake cgroup hierarchy. Writes to
+// io.max replace the file, so it holds the last line written.
+func newTestQos(t *testing.T, controllers string) (*QosEnforcer, string) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, cgroupControllers), []byte(controllers), 0644))
+	return newQosEnforcer(root, "kubepods.slice/qos"), root
+}
+
+func TestQosApply(t *testing.T) {
+	q, root := newTestQos(t, "cpuset cpu io memory pids\n")
+	source := t.TempDir()
+	device, err := majorMinor(source)
+	require.NoError(t, err)
+	ioMaxPath := filepath.Join(root, "kubepods.slice", "qos", ioMaxFile)
+
+	require.NoError(t, q.Apply("vol1", source, &api.IoThrottle{ReadIops: 100, WriteBwMbytes: 2}))
+	data, err := os.ReadFile(ioMaxPath)
+	require.NoError(t, err)
+	assert.Equal(t, device+" rbps=max wbps=2097152 riops=100 wiops=max", string(data))
+
+	// The io controller is enabled from the root down to the cgroup.
+	for _, dir := range []string{root, filepath.Join(root, "kubepods.slice")} {
+		data, err := os.ReadFile(filepath.Join(dir, cgroupSubtreeCtl))
+		require.NoError(t, err)
+		assert.Equal(t, "+io", string(data))
+	}
+
+	// Volumes sharing a device share their limits, which must not differ.
+	assert.Error(t, q.Apply("vol2", source, &api.IoThrottle{ReadIops: 50, WriteIops: 10}))
+	assert.Nil(t, q.Throttle("vol2"))
+	require.NoError(t, q.Apply("vol2", source, &api.IoThrottle{ReadIops: 100, WriteBwMbytes: 2}))
+	data, err = os.ReadFile(ioMaxPath)
+	require.NoError(t, err)
+	assert.Equal(t, device+" rbps=max wbps=2097152 riops=100 wiops=max", string(data))
+
+	require.NoError(t, q.Remove("vol2"))
+	data, err = os.ReadFile(ioMaxPath)
+	require.NoError(t, err)
+	assert.Equal(t, device+" rbps=max wbps=2097152 riops=100 wiops=max", string(data))
+	assert.Nil(t, q.Throttle("vol2"))
+
+	// An empty throttle lifts the limits.
+	require.NoError(t, q.Apply("vol1", source, &api.IoThrottle{}))
+	data, err = os.ReadFile(ioMaxPath)
+	require.NoError(t, err)
+	assert.Equal(t, device+" rbps=max wbps=max riops=max wiops=max", string(data))
+	assert.Nil(t, q.Throttle("vol1"))
+}
+
+func TestQosNoController(t *testing.T) {
+	q, _ := newTestQos(t, "cpu memory\n")
+	assert.Error(t, q.Apply("vol1", t.TempDir(), &api.IoThrottle{ReadIops: 100}))
+	assert.Nil(t, q.Throttle("vol1"))
+
+	// Nothing to enforce without limits.
+	assert.NoError(t, q.Apply("vol1", t.TempDir(), nil))
+}
+
+func TestQosStats(t *testing.T) {
+	q, root := newTestQos(t, "io\n")
+	source := t.TempDir()
+	device, err := majorMinor(source)
+	require.NoError(t, err)
+	ioStatPath := filepath.Join(root, "kubepods.slice", "qos", ioStatFile)
+
+	require.NoError(t, q.Apply("vol1", source, &api.IoThrottle{WriteIops: 10}))
+
+	// Stats of volumes that are not throttled are not changed.
+	stats := &api.Stats{Reads: 7}
+	require.NoError(t, q.UpdateStats("vol2", false, stats))
+	assert.Equal(t, uint64(7), stats.Reads)
+
+	require.NoError(t, os.WriteFile(ioStatPath, []byte(
+		"8:0 rbytes=1 wbytes=1 rios=1 wios=1 dbytes=0 dios=0\n"+
+			device+" rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n"), 0644))
+	stats = &api.Stats{}
+	require.NoError(t, q.UpdateStats("vol1", false, stats))
+	assert.Equal(t, uint64(1), stats.Reads)
+	assert.Equal(t, uint64(4096), stats.ReadBytes)
+	assert.Equal(t, uint64(2), stats.Writes)
+	assert.Equal(t, uint64(8192), stats.WriteBytes)
+
+	// Interval stats are those since the previous call.
+	require.NoError(t, os.WriteFile(ioStatPath, []byte(
+		device+" rbytes=4096 wbytes=20480 rios=1 wios=5 dbytes=0 dios=0\n"), 0644))
+	stats = &api.Stats{}
+	require.NoError(t, q.UpdateStats("vol1", false, stats))
+	assert.Equal(t, uint64(0), stats.Reads)
+	assert.Equal(t, uint64(3), stats.Writes)
+	assert.Equal(t, uint64(12288), stats.WriteBytes)
+
+	stats = &api.Stats{}
+	require.NoError(t, q.UpdateStats("vol1", true, stats))
+	assert.Equal(t, uint64(5), stats.Writes)
+	assert.Equal(t, uint64(0), stats.IntervalMs)
+}
+
+func TestQosAddProcess(t *testing.T) {
+	q, root := newTestQos(t, "io\n")
+	procsPath := filepath.Join(root, "kubepods.slice", "qos", cgroupProcs)
+
+	require.NoError(t, q.AddProcess(1234))
+	data, err := os.ReadFile(procsPath)
+	require.NoError(t, err)
+	assert.Equal(t, "1234", string(data))
+}
+
+// TestQosThrottle checks that writes of a process moved into the cgroup are
+// throttled. It needs root and a cgroup v2 hierarchy with the io controller.
+func TestQosThrottle(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Throttling I/O requires root")
+	}
+	data, err := os.ReadFile(filepath.Join(CgroupPath, cgroupControllers))
+	if err != nil || !hasField(string(data), ioControllerName) {
+		t.Skip("cgroup v2 io controller is not available")
+	}
+	dd, err := exec.LookPath("dd")
+	if err != nil {
+		t.Skip("dd is not available")
+	}
+	cgroup := fmt.Sprintf("openstorage-qos-test-%d", os.Getpid())
+	q := NewQosEnforcer(cgroup)
+	defer os.Remove(filepath.Join(CgroupPath, cgroup))
+
+	dir := t.TempDir()
+	if device, err := majorMinor(dir); err != nil || strings.HasPrefix(device, "0:") {
+		t.Skip("Temporary directory is not on a block device")
+	}
+	const writes, wiops = 40, 10
+	require.NoError(t, q.Apply("vol1", dir, &api.IoThrottle{WriteIops: wiops}))
+	defer q.Remove("vol1")
+
+	// The process is stopped until it is in the cgroup, so that all of its
+	// writes count against the limits.
+	cmd := exec.Command("sh", "-c", fmt.Sprintf("kill -STOP $$; exec %s if=/dev/zero of=%s bs=4k count=%d oflag=direct",
+		dd, filepath.Join(dir, "data"), writes))
+	require.NoError(t, cmd.Start())
+	for {
+		stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", cmd.Process.Pid))
+		require.NoError(t, err)
+		if fields := strings.Fields(string(stat)); len(fields) > 2 && fields[2] == "T" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	require.NoError(t, q.AddProcess(cmd.Process.Pid))
+	start := time.Now()
+	require.NoError(t, cmd.Process.Signal(syscall.SIGCONT))
+	require.NoError(t, cmd.Wait())
+
+	// Unthrottled, the writes take a few milliseconds.
+	assert.True(t, time.Since(start) >= (writes/wiops-1)*time.Second,
+		"%d writes took %v with a limit of %d IOPS", writes, time.Since(start), wiops)
+	stats := &api.Stats{}
+	require.NoError(t, q.UpdateStats("vol1", true, stats))
+	assert.True(t, stats.Writes >= writes, "%d writes counted", stats.Writes)
+}
@@ -0,0 +1,88 @@
+package common
+
+import (
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+// GroupVolumes returns the volumes a group snapshot applies to. Volume IDs take
+// precedence; otherwise volumes are selected by group ID and labels.
+func GroupVolumes(
+	enumerator volume.Enumerator,
+	groupID string,
+	labels map[string]string,
+	volumeIDs []string,
+) ([]*api.Volume, error) {
+	if len(volumeIDs) != 0 {
+		return enumerator.Inspect(correlation.TODO(), volumeIDs)
+	}
+	if groupID == "" && len(labels) == 0 {
+		return nil, fmt.Errorf("One of group ID, labels or volume IDs must be provided")
+	}
+	locator := &api.VolumeLocator{VolumeLabels: labels}
+	if groupID != "" {
+		locator.Group = &api.Group{Id: groupID}
+	}
+	return enumerator.Enumerate(locator, nil)
+}
+
+// SnapshotGroup snapshots every volume selected by GroupVolumes using snap.
+// See SnapshotVolumes for the failure semantics.
+func SnapshotGroup(
+	enumerator volume.Enumerator,
+	groupID string,
+	labels map[string]string,
+	volumeIDs []string,
+	deleteOnFailure bool,
+	snap func(v *api.Volume) (string, error),
+	del func(snapID string) error,
+) (*api.GroupSnapCreateResponse, error) {
+	vols, err := GroupVolumes(enumerator, groupID, labels, volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+	return SnapshotVolumes(vols, deleteOnFailure, snap, del)
+}
+
+// SnapshotVolumes snapshots each of vols using snap. If any snapshot fails
+// and deleteOnFailure is set, the snapshots already taken are removed with
+// del.
+func SnapshotVolumes(
+	vols []*api.Volume,
+	deleteOnFailure bool,
+	snap func(v *api.Volume) (string, error),
+	del func(snapID string) error,
+) (*api.GroupSnapCreateResponse, error) {
+	if len(vols) == 0 {
+		return nil, volume.ErrEnoEnt
+	}
+
+	resp := &api.GroupSnapCreateResponse{
+		Snapshots: make(map[string]*api.SnapCreateResponse, len(vols)),
+	}
+	for _, v := range vols {
+		snapID, err := snap(v)
+		if err != nil {
+			resp.Error = fmt.Sprintf("Failed to snapshot volume %v: %v", v.GetId(), err)
+			if deleteOnFailure {
+				for _, s := range resp.Snapshots {
+					id := s.GetVolumeCreateResponse().GetId()
+					if err := del(id); err != nil {
+						logrus.Warnf("Failed to delete snapshot %v of failed group snapshot: %v", id, err)
+					}
+				}
+				resp.Snapshots = nil
+			}
+			return resp, err
+		}
+		resp.Snapshots[v.GetId()] = &api.SnapCreateResponse{
+			VolumeCreateResponse: &api.VolumeCreateResponse{Id: snapID},
+		}
+	}
+	return resp, nil
+}
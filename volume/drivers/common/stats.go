@@ -0,0 +1,381 @@
+package common
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+)
+
+const (
+	// MountStatsPath is where the kernel reports per mount NFS statistics.
+	MountStatsPath = "/proc/self/mountstats"
+	// SysBlockPath is where the kernel reports block device statistics.
+	SysBlockPath = "/sys/dev/block"
+)
+
+// VolumeSource describes where the data and I/O counters of a volume are.
+type VolumeSource struct {
+	// Path is the directory or file holding the volume data. It is walked
+	// to compute used bytes and inodes.
+	Path string
+	// MountPath is the NFS mount the volume is served from. If set, I/O
+	// counters are read from the mount's NFS statistics. They are kept per
+	// NFS superblock, so all volumes of an export report the same counters.
+	MountPath string
+	// Device is the block device backing the volume. If set, I/O counters
+	// are read from its statistics. Otherwise the counters of the device
+	// holding Path are used.
+	Device string
+	// SharedDevice is set if the device holding Path also holds other
+	// volumes. Its counters would include their I/O, so none are reported.
+	SharedDevice bool
+}
+
+// Usage is the space and inode usage of a volume.
+type Usage struct {
+	// UsedBytes allocated to the volume.
+	UsedBytes uint64
+	// Inodes used by the volume.
+	Inodes uint64
+	// CapacityBytes of the filesystem holding the volume.
+	CapacityBytes uint64
+	// AvailableBytes in the filesystem holding the volume.
+	AvailableBytes uint64
+	// FreeInodes in the filesystem holding the volume.
+	FreeInodes uint64
+}
+
+// StatsCollector computes statistics for filesystem based volume drivers and
+// tracks the requests a driver is serving.
+type StatsCollector struct {
+	mountStatsPath string
+	sysBlockPath   string
+
+	sync.Mutex
+	nextRequest int64
+	requests    map[int64]string
+}
+
+// NewStatsCollector returns a collector reading kernel statistics from their
+// default locations.
+func NewStatsCollector() *StatsCollector {
+	return newStatsCollector(MountStatsPath, SysBlockPath)
+}
+
+func newStatsCollector(mountStatsPath, sysBlockPath string) *StatsCollector {
+	return &StatsCollector{
+		mountStatsPath: mountStatsPath,
+		sysBlockPath:   sysBlockPath,
+		requests:       make(map[int64]string),
+	}
+}
+
+// Begin records that the driver started op on the volume. The returned
+// function must be called when the request completes.
+func (c *StatsCollector) Begin(op, volumeID string) func() {
+	c.Lock()
+	c.nextRequest++
+	id := c.nextRequest
+	c.requests[id] = op + " " + volumeID
+	c.Unlock()
+	return func() {
+		c.Lock()
+		delete(c.requests, id)
+		c.Unlock()
+	}
+}
+
+// ActiveRequests returns the requests started with Begin that have not
+// completed yet.
+func (c *StatsCollector) ActiveRequests() *api.ActiveRequests {
+	c.Lock()
+	defer c.Unlock()
+	resp := &api.ActiveRequests{
+		RequestCount:  int64(len(c.requests)),
+		ActiveRequest: make([]*api.ActiveRequest, 0, len(c.requests)),
+	}
+	for id, req := range c.requests {
+		resp.ActiveRequest = append(resp.ActiveRequest, &api.ActiveRequest{
+			ReqestKV: map[int64]string{id: req},
+		})
+	}
+	return resp
+}
+
+// Usage returns the space and inode usage of the volume.
+func (c *StatsCollector) Usage(src *VolumeSource) (*Usage, error) {
+	used, inodes, err := DiskUsage(src.Path)
+	if err != nil {
+		return nil, err
+	}
+	u := &Usage{
+		UsedBytes: used,
+		Inodes:    inodes,
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(src.Path, &st); err == nil {
+		u.CapacityBytes = st.Blocks * uint64(st.Bsize)
+		u.AvailableBytes = st.Bavail * uint64(st.Bsize)
+		u.FreeInodes = st.Ffree
+	}
+	return u, nil
+}
+
+// Stats returns the usage and I/O counters of the volume. Counters that the
+// kernel does not expose for the volume are left at zero.
+func (c *StatsCollector) Stats(src *VolumeSource) (*api.Stats, error) {
+	u, err := c.Usage(src)
+	if err != nil {
+		return nil, err
+	}
+
+	var stats *api.Stats
+	switch {
+	case src.MountPath != "":
+		stats, err = c.nfsStats(src.MountPath)
+	case src.Device != "":
+		stats, err = c.deviceStats(src.Device)
+	case src.SharedDevice:
+		stats = &api.Stats{}
+	default:
+		stats, err = c.pathDeviceStats(src.Path)
+	}
+	if err != nil {
+		logrus.Debugf("No I/O statistics for %s: %v", src.Path, err)
+		stats = &api.Stats{}
+	}
+	stats.BytesUsed = u.UsedBytes
+	return stats, nil
+}
+
+// CapacityUsage returns the usage of the volume. Filesystem volumes do not
+// share data, so all of it is exclusive.
+func (c *StatsCollector) CapacityUsage(src *VolumeSource) (*api.CapacityUsageResponse, error) {
+	u, err := c.Usage(src)
+	if err != nil {
+		return nil, err
+	}
+	return &api.CapacityUsageResponse{
+		CapacityUsageInfo: &api.CapacityUsageInfo{
+			ExclusiveBytes: int64(u.UsedBytes),
+			TotalBytes:     int64(u.UsedBytes),
+		},
+	}, nil
+}
+
+// VolumeUsageByNode returns the usage of each of the volumes.
+func (c *StatsCollector) VolumeUsageByNode(
+	vols []*api.Volume,
+	source func(v *api.Volume) (*VolumeSource, error),
+) (*api.VolumeUsageByNode, error) {
+	resp := &api.VolumeUsageByNode{
+		VolumeUsage: make([]*api.VolumeUsage, 0, len(vols)),
+	}
+	for _, v := range vols {
+		src, err := source(v)
+		if err != nil {
+			return nil, err
+		}
+		u, err := c.Usage(src)
+		if err != nil {
+			return nil, err
+		}
+		resp.VolumeUsage = append(resp.VolumeUsage, &api.VolumeUsage{
+			VolumeId:       v.GetId(),
+			VolumeName:     v.GetLocator().GetName(),
+			ExclusiveBytes: u.UsedBytes,
+			TotalBytes:     u.UsedBytes,
+		})
+	}
+	return resp, nil
+}
+
+// RefreshUsage updates the Usage of every mounted volume of the store every
+// interval until stop is closed, so that consumers of the volume object such
+// as CSI see current values. Only Usage is updated, so that concurrent
+// mounts, unmounts and updates of the volumes are not lost; see
+// UpdateVolFunc.
+func (c *StatsCollector) RefreshUsage(
+	store volume.StoreEnumerator,
+	source func(v *api.Volume) (*VolumeSource, error),
+	interval time.Duration,
+	stop <-chan struct{},
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+		vols, err := store.Enumerate(&api.VolumeLocator{}, nil)
+		if err != nil {
+			logrus.Warnf("Failed to enumerate volumes to refresh usage: %v", err)
+			continue
+		}
+		for _, v := range vols {
+			if len(v.GetAttachPath()) == 0 {
+				continue
+			}
+			src, err := source(v)
+			if err != nil {
+				continue
+			}
+			u, err := c.Usage(src)
+			if err != nil || u.UsedBytes == v.Usage {
+				continue
+			}
+			err = UpdateVolFunc(store, v.Id, func(v *api.Volume) bool {
+				if v.Usage == u.UsedBytes {
+					return false
+				}
+				v.Usage = u.UsedBytes
+				return true
+			})
+			if err != nil {
+				logrus.Warnf("Failed to update usage of volume %v: %v", v.Id, err)
+			}
+		}
+	}
+}
+
+// nfsStats returns the counters of the NFS mount at mountPath.
+func (c *StatsCollector) nfsStats(mountPath string) (*api.Stats, error) {
+	f, err := os.Open(c.mountStatsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	mountPath = filepath.Clean(mountPath)
+	var (
+		found bool
+		stats = &api.Stats{}
+	)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "device" {
+			// device <source> mounted on <path> with fstype <type> ...
+			if found {
+				break
+			}
+			found = len(fields) >= 8 && fields[3] == "on" &&
+				filepath.Clean(fields[4]) == mountPath &&
+				strings.HasPrefix(fields[7], "nfs")
+			continue
+		}
+		if !found {
+			continue
+		}
+		switch fields[0] {
+		case "bytes:":
+			// normal read, normal write, direct read, direct write, ...
+			v := parseUints(fields[1:])
+			if len(v) >= 4 {
+				stats.ReadBytes = v[0] + v[2]
+				stats.WriteBytes = v[1] + v[3]
+			}
+		case "READ:", "WRITE:":
+			// ops, transmissions, timeouts, bytes sent, bytes received,
+			// queue ms, rtt ms, execute ms, ...
+			v := parseUints(fields[1:])
+			if len(v) < 8 {
+				continue
+			}
+			if fields[0] == "READ:" {
+				stats.Reads, stats.ReadMs = v[0], v[7]
+			} else {
+				stats.Writes, stats.WriteMs = v[0], v[7]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("No NFS statistics for %s", mountPath)
+	}
+	stats.IoMs = stats.ReadMs + stats.WriteMs
+	return stats, nil
+}
+
+// deviceStats returns the counters of the block device node at dev.
+func (c *StatsCollector) deviceStats(dev string) (*api.Stats, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(dev, &st); err != nil {
+		return nil, err
+	}
+	if st.Mode&syscall.S_IFMT != syscall.S_IFBLK {
+		return nil, fmt.Errorf("%s is not a block device", dev)
+	}
+	return c.blockStats(uint64(st.Rdev))
+}
+
+// pathDeviceStats returns the counters of the block device holding p.
+func (c *StatsCollector) pathDeviceStats(p string) (*api.Stats, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(p, &st); err != nil {
+		return nil, err
+	}
+	return c.blockStats(uint64(st.Dev))
+}
+
+func (c *StatsCollector) blockStats(dev uint64) (*api.Stats, error) {
+	p := filepath.Join(c.sysBlockPath,
+		fmt.Sprintf("%d:%d", unix.Major(dev), unix.Minor(dev)), "stat")
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	// See Documentation/block/stat.rst in the kernel tree.
+	v := parseUints(strings.Fields(string(data)))
+	if len(v) < 11 {
+		return nil, fmt.Errorf("Unexpected format of %s", p)
+	}
+	const sectorSize = 512
+	stats := &api.Stats{
+		Reads:      v[0],
+		ReadBytes:  v[2] * sectorSize,
+		ReadMs:     v[3],
+		Writes:     v[4],
+		WriteBytes: v[6] * sectorSize,
+		WriteMs:    v[7],
+		IoProgress: v[8],
+		IoMs:       v[9],
+	}
+	if len(v) >= 15 {
+		stats.Discards = v[11]
+		stats.DiscardBytes = v[13] * sectorSize
+		stats.DiscardMs = v[14]
+	}
+	return stats, nil
+}
+
+func parseUints(fields []string) []uint64 {
+	v := make([]uint64, 0, len(fields))
+	for _, f := range fields {
+		n, err := strconv.ParseUint(f, 10, 64)
+		if err != nil {
+			break
+		}
+		v = append(v, n)
+	}
+	return v
+}
@@ -0,0 +1,140 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const testMountStats = `device rootfs mounted on / with fstype rootfs
+device 10.0.0.1:/export mounted on /var/lib/openstorage/nfs/10.0.0.1 with fstype nfs4 statvers=1.1
+	opts:	rw,vers=4.2,rsize=1048576,wsize=1048576
+	age:	1234
+	bytes:	1000	2000	300	400	0	0	5	6
+	RPC iostats version: 1.1  p/v: 100003/4 (nfs)
+	per-op statistics
+	        NULL: 1 1 0 44 24 0 0 0 0
+	        READ: 10 10 0 1200 40960 3 40 50 0
+	       WRITE: 20 20 0 81920 2400 7 60 70 0
+device 10.0.0.2:/export mounted on /var/lib/openstorage/nfs/10.0.0.2 with fstype nfs4 statvers=1.1
+	bytes:	1	1	1	1	0	0	1	1
+`
+
+func TestStatsCollectorNFS(t *testing.T) {
+	dir := t.TempDir()
+	mountStats := filepath.Join(dir, "mountstats")
+	require.NoError(t, os.WriteFile(mountStats, []byte(testMountStats), 0644))
+	c := newStatsCollector(mountStats, dir)
+
+	stats, err := c.nfsStats("/var/lib/openstorage/nfs/10.0.0.1/")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1300), stats.ReadBytes)
+	assert.Equal(t, uint64(2400), stats.WriteBytes)
+	assert.Equal(t, uint64(10), stats.Reads)
+	assert.Equal(t, uint64(50), stats.ReadMs)
+	assert.Equal(t, uint64(20), stats.Writes)
+	assert.Equal(t, uint64(70), stats.WriteMs)
+	assert.Equal(t, uint64(120), stats.IoMs)
+
+	_, err = c.nfsStats("/mnt/unknown")
+	assert.Error(t, err)
+
+	// Usage comes from the volume data, counters from the mount.
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data"), make([]byte, 4096), 0644))
+	stats, err = c.Stats(&VolumeSource{
+		Path:      dir,
+		MountPath: "/var/lib/openstorage/nfs/10.0.0.1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), stats.Reads)
+	assert.True(t, stats.BytesUsed >= 4096)
+}
+
+func TestStatsCollectorBlock(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "7:3"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "7:3", "stat"),
+		[]byte("  100 0 800 11 200 0 1600 22 1 33 44 5 0 16 6 0 0\n"), 0644))
+	c := newStatsCollector(filepath.Join(dir, "mountstats"), dir)
+
+	stats, err := c.blockStats(unix.Mkdev(7, 3))
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100), stats.Reads)
+	assert.Equal(t, uint64(800*512), stats.ReadBytes)
+	assert.Equal(t, uint64(11), stats.ReadMs)
+	assert.Equal(t, uint64(200), stats.Writes)
+	assert.Equal(t, uint64(1600*512), stats.WriteBytes)
+	assert.Equal(t, uint64(22), stats.WriteMs)
+	assert.Equal(t, uint64(1), stats.IoProgress)
+	assert.Equal(t, uint64(33), stats.IoMs)
+	assert.Equal(t, uint64(5), stats.Discards)
+	assert.Equal(t, uint64(16*512), stats.DiscardBytes)
+
+	_, err = c.blockStats(unix.Mkdev(8, 0))
+	assert.Error(t, err)
+}
+
+func TestStatsCollectorActiveRequests(t *testing.T) {
+	c := NewStatsCollector()
+	end1 := c.Begin("mount", "vol1")
+	end2 := c.Begin("snapshot", "vol2")
+
+	reqs := c.ActiveRequests()
+	assert.Equal(t, int64(2), reqs.RequestCount)
+	assert.Len(t, reqs.ActiveRequest, 2)
+
+	end1()
+	reqs = c.ActiveRequests()
+	require.Len(t, reqs.ActiveRequest, 1)
+	for _, v := range reqs.ActiveRequest[0].ReqestKV {
+		assert.Equal(t, "snapshot vol2", v)
+	}
+	end2()
+	assert.Zero(t, c.ActiveRequests().RequestCount)
+}
+
+func TestStatsCollectorSharedDevice(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data"), make([]byte, 8192), 0644))
+	c := NewStatsCollector()
+
+	// The counters of a shared device are not those of the volume
+	stats, err := c.Stats(&VolumeSource{Path: dir, SharedDevice: true})
+	require.NoError(t, err)
+	assert.NotZero(t, stats.BytesUsed)
+	assert.Zero(t, stats.Reads)
+	assert.Zero(t, stats.Writes)
+	assert.Zero(t, stats.IoMs)
+}
+
+func TestStatsCollectorRefreshUsage(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "data"), make([]byte, 8192), 0644))
+	c := NewStatsCollector()
+
+	vol := newTestVolume("TestRefreshUsage")
+	vol.AttachPath = []string{"/mnt/test"}
+	require.NoError(t, testEnumerator.CreateVol(vol))
+	defer testEnumerator.DeleteVol(vol.Id)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go c.RefreshUsage(testEnumerator, func(v *api.Volume) (*VolumeSource, error) {
+		return &VolumeSource{Path: dir}, nil
+	}, 10*time.Millisecond, stop)
+
+	require.Eventually(t, func() bool {
+		v, err := testEnumerator.GetVol(vol.Id)
+		return err == nil && v.Usage != 0
+	}, 5*time.Second, 10*time.Millisecond)
+	v, err := testEnumerator.GetVol(vol.Id)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"/mnt/test"}, v.AttachPath)
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// DiskUsage walks the tree at root and returns the bytes allocated to it and
+// the number of inodes it uses. Hardlinked files are counted once.
+func DiskUsage(root string) (bytes uint64, inodes uint64, err error) {
+	seen := make(map[uint64]struct{})
+	err = filepath.Walk(root, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// Files may disappear while the tree is in use.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		st, ok := fi.Sys().(*syscall.Stat_t)
+		if !ok {
+			bytes += uint64(fi.Size())
+			inodes++
+			return nil
+		}
+		if st.Nlink > 1 {
+			if _, ok := seen[st.Ino]; ok {
+				return nil
+			}
+			seen[st.Ino] = struct{}{}
+		}
+		// st_blocks is always in 512 byte units.
+		bytes += uint64(st.Blocks) * 512
+		inodes++
+		return nil
+	})
+	return bytes, inodes, err
+}
@@ -8,8 +8,10 @@ import (
 	"github.com/libopenstorage/openstorage/volume/drivers/btrfs"
 	"github.com/libopenstorage/openstorage/volume/drivers/buse"
 	"github.com/libopenstorage/openstorage/volume/drivers/fake"
+	"github.com/libopenstorage/openstorage/volume/drivers/loop"
 	"github.com/libopenstorage/openstorage/volume/drivers/nfs"
 	"github.com/libopenstorage/openstorage/volume/drivers/pwx"
+	"github.com/libopenstorage/openstorage/volume/drivers/tmpfs"
 	"github.com/libopenstorage/openstorage/volume/drivers/vfs"
 )
 
@@ -27,10 +29,14 @@ var (
 		{DriverType: btrfs.Type, Name: btrfs.Name},
 		// BUSE driver provisions storage from local volumes and implements block in user space.
 		{DriverType: buse.Type, Name: buse.Name},
+		// Loop driver provisions block storage from local files attached through loop devices.
+		{DriverType: loop.Type, Name: loop.Name},
 		// NFS driver provisions storage from an NFS server.
 		{DriverType: nfs.Type, Name: nfs.Name},
 		// PWX driver provisions storage from PWX cluster.
 		{DriverType: pwx.Type, Name: pwx.Name},
+		// Tmpfs driver provisions size limited in-memory storage, for ephemeral volumes.
+		{DriverType: tmpfs.Type, Name: tmpfs.Name},
 		// VFS driver provisions storage from local filesystem
 		{DriverType: vfs.Type, Name: vfs.Name},
 		// Fake driver is used to develop and test the API
@@ -41,8 +47,10 @@ var (
 		map[string]func(map[string]string) (volume.VolumeDriver, error){
 			btrfs.Name: btrfs.Init,
 			buse.Name:  buse.Init,
+			loop.Name:  loop.Init,
 			nfs.Name:   nfs.Init,
 			pwx.Name:   pwx.Init,
+			tmpfs.Name: tmpfs.Init,
 			vfs.Name:   vfs.Init,
 			fake.Name:  fake.Init,
 		},
@@ -26,11 +26,13 @@ import (
 	"strings"
 	"time"
 
+	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/timestamp"
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/cluster"
 	clustermanager "github.com/libopenstorage/openstorage/cluster/manager"
 	"github.com/libopenstorage/openstorage/pkg/correlation"
+	mountattachoptions "github.com/libopenstorage/openstorage/pkg/options"
 	"github.com/libopenstorage/openstorage/volume"
 	"github.com/libopenstorage/openstorage/volume/drivers/common"
 	"github.com/pborman/uuid"
@@ -55,13 +57,17 @@ type driver struct {
 	volume.QuiesceDriver
 	volume.CredsDriver
 	volume.CloudBackupDriver
-	volume.CloudMigrateDriver
 	volume.FilesystemTrimDriver
 	volume.FilesystemCheckDriver
-	volume.VerifyChecksumDriver
 	kv            kvdb.Kvdb
 	thisCluster   cluster.Cluster
 	volumeChannel chan *api.Volume
+
+	// State of the simulator, see sim.go.
+	clock                  *clock
+	faults                 faults
+	jobDuration            time.Duration
+	migrationStageDuration time.Duration
 }
 
 type fakeCred struct {
@@ -94,16 +100,27 @@ func newFakeDriver(params map[string]string) (*driver, error) {
 		return nil, err
 	}
 	inst := &driver{
-		IODriver:              volume.IONotSupported,
-		StoreEnumerator:       common.NewDefaultStoreEnumerator(Name, kv),
-		StatsDriver:           volume.StatsNotSupported,
-		QuiesceDriver:         volume.QuiesceNotSupported,
-		CloudMigrateDriver:    volume.CloudMigrateNotSupported,
-		FilesystemTrimDriver:  volume.FilesystemTrimNotSupported,
-		FilesystemCheckDriver: volume.FilesystemCheckNotSupported,
-		VerifyChecksumDriver:  volume.VerifyChecksumNotSupported,
-		kv:                    kv,
-		volumeChannel:         make(chan *api.Volume, 2),
+		IODriver:               volume.IONotSupported,
+		StoreEnumerator:        common.NewDefaultStoreEnumerator(Name, kv),
+		StatsDriver:            volume.StatsNotSupported,
+		QuiesceDriver:          volume.QuiesceNotSupported,
+		FilesystemTrimDriver:   volume.FilesystemTrimNotSupported,
+		FilesystemCheckDriver:  volume.FilesystemCheckNotSupported,
+		kv:                     kv,
+		volumeChannel:          make(chan *api.Volume, 2),
+		clock:                  newClock(params[ClockParam] == ClockManual),
+		jobDuration:            defaultJobDuration,
+		migrationStageDuration: defaultMigrationStageDuration,
+	}
+	if v, ok := params[JobDurationParam]; ok {
+		if inst.jobDuration, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("Invalid %s: %v", JobDurationParam, err)
+		}
+	}
+	if v, ok := params[MigrationStageDurationParam]; ok {
+		if inst.migrationStageDuration, err = time.ParseDuration(v); err != nil {
+			return nil, fmt.Errorf("Invalid %s: %v", MigrationStageDurationParam, err)
+		}
 	}
 
 	inst.thisCluster, err = clustermanager.Inst()
@@ -205,6 +222,9 @@ func (d *driver) Create(
 	source *api.Source,
 	spec *api.VolumeSpec) (string, error) {
 
+	if err := d.faults.check("Create", ""); err != nil {
+		return "", err
+	}
 	if spec.Size == 0 {
 		return "", fmt.Errorf("Volume size cannot be zero")
 	} else if spec.GetHaLevel() == 0 {
@@ -229,6 +249,7 @@ func (d *driver) Create(
 		source,
 		spec,
 	)
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
 
 	if err := d.CreateVol(v); err != nil {
 		return "", err
@@ -237,47 +258,109 @@ func (d *driver) Create(
 }
 
 func (d *driver) Delete(ctx context.Context, volumeID string) error {
-	_, err := d.GetVol(volumeID)
+	if err := d.faults.check("Delete", volumeID); err != nil {
+		return err
+	}
+	v, err := d.GetVol(volumeID)
 	if err != nil {
 		logrus.Println(err)
 		return err
 	}
+	if v.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED {
+		return volume.ErrVolAttached
+	}
+	if d.migrating(volumeID) {
+		return fmt.Errorf("Volume %s is being migrated", volumeID)
+	}
 
 	err = d.DeleteVol(volumeID)
 	if err != nil {
 		logrus.Println(err)
 		return err
 	}
+	d.kv.Delete(statsKeyPrefix + "/" + volumeID)
+	d.deleteJobs(volumeID)
 
 	return nil
 }
 
 func (d *driver) MountedAt(ctx context.Context, mountpath string) string {
+	vols, err := d.StoreEnumerator.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return ""
+	}
+	for _, v := range vols {
+		for _, p := range v.GetAttachPath() {
+			if p == mountpath {
+				return v.GetId()
+			}
+		}
+	}
 	return ""
 }
 
+// Mount mounts an attached volume. A volume can be mounted at several paths
+// of the node it is attached on.
 func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	if err := d.faults.check("Mount", volumeID); err != nil {
+		return err
+	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		logrus.Println(err)
 		return err
 	}
+	if v.GetState() != api.VolumeState_VOLUME_STATE_ATTACHED {
+		return volume.ErrVolDetached
+	}
+	if v.GetAttachedOn() != d.nodeID(nil) {
+		return volume.ErrVolAttachedOnRemoteNode
+	}
+	for _, p := range v.AttachPath {
+		if p == mountpath {
+			return nil
+		}
+	}
 
 	v.AttachPath = append(v.AttachPath, mountpath)
+	if err := d.setMounted(v, true); err != nil {
+		return err
+	}
 	return d.UpdateVol(v)
 }
 
 func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	if err := d.faults.check("Unmount", volumeID); err != nil {
+		return err
+	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
 
-	v.AttachPath = nil
+	paths := make([]string, 0, len(v.AttachPath))
+	for _, p := range v.AttachPath {
+		if p != mountpath {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == len(v.AttachPath) {
+		return fmt.Errorf("Volume %s is not mounted at %s", volumeID, mountpath)
+	}
+	v.AttachPath = paths
+	if len(paths) == 0 {
+		v.AttachPath = nil
+		if err := d.setMounted(v, false); err != nil {
+			return err
+		}
+	}
 	return d.UpdateVol(v)
 }
 
 func (d *driver) Snapshot(ctx context.Context, volumeID string, readonly bool, locator *api.VolumeLocator, noRetry bool) (string, error) {
+	if err := d.faults.check("Snapshot", volumeID); err != nil {
+		return "", err
+	}
 
 	if len(locator.GetName()) == 0 {
 		return "", fmt.Errorf("Name for snapshot must be provided")
@@ -295,48 +378,167 @@ func (d *driver) Snapshot(ctx context.Context, volumeID string, readonly bool, l
 		return "", nil
 	}
 
+	// The snapshot holds the data of the volume at this point in time.
+	snapStats := fakeStats{BytesUsed: d.getStats(vols[0]).BytesUsed}
+	if err := d.putStats(newVolumeID, snapStats); err != nil {
+		return "", err
+	}
+	if readonly {
+		snap, err := d.GetVol(newVolumeID)
+		if err != nil {
+			return "", err
+		}
+		snap.Readonly = true
+		if err := d.UpdateVol(snap); err != nil {
+			return "", err
+		}
+	}
+
 	return newVolumeID, nil
 }
 
+// Restore rolls the volume back to a snapshot taken from it. The volume must
+// not be attached.
 func (d *driver) Restore(volumeID string, snapID string) error {
+	if err := d.faults.check("Restore", volumeID); err != nil {
+		return err
+	}
 	if _, err := d.Inspect(correlation.TODO(), []string{volumeID, snapID}); err != nil {
 		return err
 	}
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	snap, err := d.GetVol(snapID)
+	if err != nil {
+		return err
+	}
+	if snap.GetSource().GetParent() != v.GetId() {
+		return fmt.Errorf("Snapshot %s is not a snapshot of volume %s", snapID, volumeID)
+	}
+	if v.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED {
+		return volume.ErrVolAttached
+	}
 
-	return nil
+	s := d.getStats(v)
+	s.BytesUsed = d.getStats(snap).BytesUsed
+	return d.putStats(v.GetId(), s)
 }
 
 func (d *driver) SnapshotGroup(groupID string, labels map[string]string, volumeIDs []string, deleteOnFailure bool) (*api.GroupSnapCreateResponse, error) {
-
-	// We can return something here.
-	return nil, volume.ErrNotSupported
+	if err := d.faults.check("SnapshotGroup", groupID); err != nil {
+		return nil, err
+	}
+	return common.SnapshotGroup(
+		d.StoreEnumerator,
+		groupID,
+		labels,
+		volumeIDs,
+		deleteOnFailure,
+		func(v *api.Volume) (string, error) {
+			locator := &api.VolumeLocator{
+				Name:         v.GetLocator().GetName() + "-" + groupID + "-snap",
+				VolumeLabels: labels,
+			}
+			return d.Snapshot(correlation.TODO(), v.Id, true, locator, false)
+		},
+		func(snapID string) error {
+			return d.Delete(correlation.TODO(), snapID)
+		},
+	)
 }
 
-func (d *driver) Attach(ctx context.Context, volumeID string, attachOptions map[string]string) (string, error) {
-	return "/dev/fake/" + volumeID, nil
+// nodeID returns the node an attach or detach request comes from.
+func (d *driver) nodeID(options map[string]string) string {
+	if node, ok := options[AttachNodeOption]; ok {
+		return node
+	}
+	if d.thisCluster == nil {
+		return ""
+	}
+	self, err := d.thisCluster.Enumerate()
+	if err != nil {
+		return ""
+	}
+	return self.NodeId
 }
 
-func (d *driver) Detach(ctx context.Context, volumeID string, options map[string]string) error {
-	return nil
-}
+// Attach attaches the volume on the node of the request. Attaching a
+// volume already attached on that node returns the same device; attaching a
+// volume that is attached on another node fails unless it is shared.
+func (d *driver) Attach(ctx context.Context, volumeID string, attachOptions map[string]string) (string, error) {
+	if err := d.faults.check("Attach", volumeID); err != nil {
+		return "", err
+	}
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	node := d.nodeID(attachOptions)
+	if v.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED {
+		if v.GetAttachedOn() == node ||
+			v.GetSpec().GetShared() || v.GetSpec().GetSharedv4() {
+			return v.GetDevicePath(), nil
+		}
+		return "", volume.ErrVolAttachedOnRemoteNode
+	}
 
-func (d *driver) CloudMigrateStart(request *api.CloudMigrateStartRequest) (*api.CloudMigrateStartResponse, error) {
-	return &api.CloudMigrateStartResponse{TaskId: request.TaskId}, nil
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	v.AttachedOn = node
+	v.AttachedState = api.AttachState_ATTACH_STATE_EXTERNAL
+	v.DevicePath = "/dev/fake/" + volumeID
+	v.AttachTime, _ = ptypes.TimestampProto(d.clock.now())
+	v.AttachInfo = attachOptions
+	if err := d.UpdateVol(v); err != nil {
+		return "", err
+	}
+	return v.DevicePath, nil
 }
 
-func (d *driver) CloudMigrateCancel(request *api.CloudMigrateCancelRequest) error {
-	return nil
-}
+// Detach detaches the volume. Mounted volumes are only detached if forced,
+// in which case they are unmounted first. Detaching a volume that is not
+// attached, or does not exist, succeeds.
+func (d *driver) Detach(ctx context.Context, volumeID string, options map[string]string) error {
+	if err := d.faults.check("Detach", volumeID); err != nil {
+		return err
+	}
+	v, err := d.GetVol(volumeID)
+	if err == kvdb.ErrNotFound {
+		// Nothing is attached.
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if v.GetState() != api.VolumeState_VOLUME_STATE_ATTACHED {
+		return nil
+	}
+	force := options[mountattachoptions.OptionsForceDetach] == "true"
+	if node, ok := options[AttachNodeOption]; ok && node != v.GetAttachedOn() && !force {
+		return volume.ErrVolAttachedOnRemoteNode
+	}
+	if len(v.GetAttachPath()) != 0 {
+		if !force {
+			return fmt.Errorf("Volume %s is mounted at %v", volumeID, v.GetAttachPath())
+		}
+		v.AttachPath = nil
+		if err := d.setMounted(v, false); err != nil {
+			return err
+		}
+	}
 
-func (d *driver) CloudMigrateStatus(request *api.CloudMigrateStatusRequest) (*api.CloudMigrateStatusResponse, error) {
-	cml := make(map[string]*api.CloudMigrateInfoList, 0)
-	cml["result"] = &api.CloudMigrateInfoList{}
-	return &api.CloudMigrateStatusResponse{
-		Info: cml,
-	}, nil
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	v.AttachedOn = ""
+	v.DevicePath = ""
+	v.AttachInfo = nil
+	v.DetachTime, _ = ptypes.TimestampProto(d.clock.now())
+	return d.UpdateVol(v)
 }
 
 func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+	if err := d.faults.check("Set", volumeID); err != nil {
+		return err
+	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
@@ -394,17 +596,25 @@ func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLo
 
 func (d *driver) Shutdown() {}
 
-func (d *driver) UsedSize(volumeID string) (uint64, error) {
+// volumeStats returns the simulated I/O counters of the volume.
+func (d *driver) volumeStats(volumeID string) (fakeStats, error) {
 	vols, err := d.Inspect(correlation.TODO(), []string{volumeID})
 	if err == kvdb.ErrNotFound {
-		return 0, fmt.Errorf("Volume not found")
+		return fakeStats{}, fmt.Errorf("Volume not found")
 	} else if err != nil {
-		return 0, err
+		return fakeStats{}, err
 	} else if len(vols) == 0 {
-		return 0, fmt.Errorf("Volume not found")
+		return fakeStats{}, fmt.Errorf("Volume not found")
 	}
+	return d.getStats(vols[0]), nil
+}
 
-	return uint64(12345), nil
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	s, err := d.volumeStats(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	return s.BytesUsed, nil
 }
 
 func (d *driver) VolumeBytesUsedByNode(nodeMID string, volumes []uint64) (*api.VolumeBytesUsedByNode, error) {
@@ -417,48 +627,27 @@ func (d *driver) VolumeBytesUsedByNode(nodeMID string, volumes []uint64) (*api.V
 		VolUsage: volusage,
 	}, nil
 }
+
 func (d *driver) Stats(ctx context.Context, volumeID string, cumulative bool) (*api.Stats, error) {
-	vols, err := d.Inspect(correlation.TODO(), []string{volumeID})
-	if err == kvdb.ErrNotFound {
-		return nil, fmt.Errorf("Volume not found")
-	} else if err != nil {
+	s, err := d.volumeStats(volumeID)
+	if err != nil {
 		return nil, err
-	} else if len(vols) == 0 {
-		return nil, fmt.Errorf("Volume not found")
-	}
-
-	return &api.Stats{
-		Reads:      uint64(12345),
-		ReadMs:     uint64(1),
-		ReadBytes:  uint64(1234567),
-		Writes:     uint64(9876),
-		WriteMs:    uint64(2),
-		WriteBytes: uint64(7654321),
-		IoProgress: uint64(987),
-		IoMs:       uint64(3),
-		BytesUsed:  uint64(1234567890),
-		IntervalMs: uint64(4),
-	}, nil
+	}
+	return s.toAPI(), nil
 }
 
 func (d *driver) CapacityUsage(
 	volumeID string,
 ) (*api.CapacityUsageResponse, error) {
-	vols, err := d.Inspect(correlation.TODO(), []string{volumeID})
-	if err == kvdb.ErrNotFound {
-		return nil, fmt.Errorf("Volume not found")
-	} else if err != nil {
+	s, err := d.volumeStats(volumeID)
+	if err != nil {
 		return nil, err
-	} else if len(vols) == 0 {
-		return nil, fmt.Errorf("Volume not found")
 	}
 
 	return &api.CapacityUsageResponse{CapacityUsageInfo: &api.CapacityUsageInfo{
-		ExclusiveBytes: int64(123456),
-		SharedBytes:    int64(654321),
-		TotalBytes:     int64(653421),
+		ExclusiveBytes: int64(s.BytesUsed),
+		TotalBytes:     int64(s.BytesUsed),
 	}}, nil
-
 }
 
 func (d *driver) CredsCreate(
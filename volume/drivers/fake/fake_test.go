@@ -20,10 +20,12 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/libopenstorage/openstorage/api"
 	clustermanager "github.com/libopenstorage/openstorage/cluster/manager"
 	"github.com/libopenstorage/openstorage/config"
+	"github.com/libopenstorage/openstorage/volume"
 	"github.com/portworx/kvdb"
 	"github.com/portworx/kvdb/mem"
 	"github.com/sirupsen/logrus"
@@ -123,7 +125,7 @@ func TestFakeInspect(t *testing.T) {
 }
 
 func TestFakeCapacityUsage(t *testing.T) {
-	d, err := newFakeDriver(map[string]string{})
+	d, err := newFakeDriver(map[string]string{ClockParam: ClockManual})
 	assert.NoError(t, err)
 	vid, err := d.Create(context.TODO(), &api.VolumeLocator{
 		Name: "myvol",
@@ -137,7 +139,275 @@ func TestFakeCapacityUsage(t *testing.T) {
 	v, err := d.CapacityUsage(vid)
 	assert.NoError(t, err)
 	assert.NotNil(t, v)
-	assert.Equal(t, int64(653421), v.CapacityUsageInfo.TotalBytes)
+	assert.Equal(t, int64(0), v.CapacityUsageInfo.TotalBytes)
+
+	// A mounted volume fills up over time
+	_, err = d.Attach(context.TODO(), vid, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, d.Mount(context.TODO(), vid, "/mnt/myvol", nil))
+	d.Advance(10 * time.Second)
+
+	v, err = d.CapacityUsage(vid)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(10*simWriteIOPS*simIOSize), v.CapacityUsageInfo.TotalBytes)
+	stats, err := d.Stats(context.TODO(), vid, true)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10*simReadIOPS), stats.GetReads())
+	assert.Equal(t, uint64(10*simWriteIOPS), stats.GetWrites())
+
+	// and stops once unmounted
+	assert.NoError(t, d.Unmount(context.TODO(), vid, "/mnt/myvol", nil))
+	d.Advance(10 * time.Second)
+	used, err := d.UsedSize(vid)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(10*simWriteIOPS*simIOSize), used)
+}
+
+func TestFakeAttachState(t *testing.T) {
+	d, err := newFakeDriver(map[string]string{ClockParam: ClockManual})
+	assert.NoError(t, err)
+	vid, err := d.Create(context.TODO(), &api.VolumeLocator{
+		Name: "myvol",
+	}, &api.Source{}, &api.VolumeSpec{
+		Size:    1234,
+		HaLevel: 1,
+	})
+	assert.NoError(t, err)
+
+	// Mount requires an attached volume
+	assert.Error(t, d.Mount(context.TODO(), vid, "/mnt/myvol", nil))
+
+	path, err := d.Attach(context.TODO(), vid, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "/dev/fake/"+vid, path)
+	again, err := d.Attach(context.TODO(), vid, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, path, again)
+	_, err = d.Attach(context.TODO(), vid, map[string]string{AttachNodeOption: "othernode"})
+	assert.Equal(t, volume.ErrVolAttachedOnRemoteNode, err)
+
+	vols, err := d.Inspect(context.TODO(), []string{vid})
+	assert.NoError(t, err)
+	assert.Equal(t, api.VolumeState_VOLUME_STATE_ATTACHED, vols[0].GetState())
+	assert.Equal(t, path, vols[0].GetDevicePath())
+
+	// An attached or mounted volume cannot be deleted or detached
+	assert.NoError(t, d.Mount(context.TODO(), vid, "/mnt/myvol", nil))
+	assert.Error(t, d.Delete(context.TODO(), vid))
+	assert.Error(t, d.Detach(context.TODO(), vid, nil))
+	assert.Equal(t, vid, d.MountedAt(context.TODO(), "/mnt/myvol"))
+
+	assert.NoError(t, d.Unmount(context.TODO(), vid, "/mnt/myvol", nil))
+	assert.Error(t, d.Unmount(context.TODO(), vid, "/mnt/myvol", nil))
+	assert.NoError(t, d.Detach(context.TODO(), vid, nil))
+	assert.NoError(t, d.Detach(context.TODO(), vid, nil))
+
+	vols, err = d.Inspect(context.TODO(), []string{vid})
+	assert.NoError(t, err)
+	assert.Equal(t, api.VolumeState_VOLUME_STATE_DETACHED, vols[0].GetState())
+	assert.Empty(t, vols[0].GetDevicePath())
+
+	// Now it can be attached on another node
+	_, err = d.Attach(context.TODO(), vid, map[string]string{AttachNodeOption: "othernode"})
+	assert.NoError(t, err)
+	assert.Error(t, d.Mount(context.TODO(), vid, "/mnt/myvol", nil))
+	assert.NoError(t, d.Detach(context.TODO(), vid, map[string]string{AttachNodeOption: "othernode"}))
+	assert.NoError(t, d.Delete(context.TODO(), vid))
+}
+
+func TestFakeSnapshotRestore(t *testing.T) {
+	d, err := newFakeDriver(map[string]string{ClockParam: ClockManual})
+	assert.NoError(t, err)
+	spec := &api.VolumeSpec{
+		Size:    1024 * 1024 * 1024,
+		HaLevel: 1,
+	}
+	vid, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "myvol"}, &api.Source{}, spec)
+	assert.NoError(t, err)
+	assert.NoError(t, d.putStats(vid, fakeStats{BytesUsed: 1000}))
+
+	snapID, err := d.Snapshot(context.TODO(), vid, true, &api.VolumeLocator{Name: "mysnap"}, false)
+	assert.NoError(t, err)
+	used, err := d.UsedSize(snapID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000), used)
+
+	assert.NoError(t, d.putStats(vid, fakeStats{BytesUsed: 5000}))
+	assert.NoError(t, d.Restore(vid, snapID))
+	used, err = d.UsedSize(vid)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1000), used)
+
+	// Only snapshots of the volume can be restored
+	other, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "other"}, &api.Source{}, spec)
+	assert.NoError(t, err)
+	assert.Error(t, d.Restore(other, snapID))
+
+	resp, err := d.SnapshotGroup("", nil, []string{vid, other}, true)
+	assert.NoError(t, err)
+	assert.Len(t, resp.GetSnapshots(), 2)
+	for _, r := range resp.GetSnapshots() {
+		assert.Empty(t, r.GetVolumeCreateResponse().GetVolumeResponse().GetError())
+		assert.NotEmpty(t, r.GetVolumeCreateResponse().GetId())
+	}
+}
+
+func TestFakeMigrate(t *testing.T) {
+	d, err := newFakeDriver(map[string]string{
+		ClockParam:                  ClockManual,
+		MigrationStageDurationParam: "10s",
+	})
+	assert.NoError(t, err)
+	vid, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "myvol"}, &api.Source{}, &api.VolumeSpec{
+		Size:    1234,
+		HaLevel: 1,
+	})
+	assert.NoError(t, err)
+
+	_, err = d.CloudMigrateStart(&api.CloudMigrateStartRequest{
+		Operation: api.CloudMigrate_MigrateVolume,
+		TargetId:  vid,
+	})
+	assert.Error(t, err)
+	resp, err := d.CloudMigrateStart(&api.CloudMigrateStartRequest{
+		Operation: api.CloudMigrate_MigrateVolume,
+		ClusterId: "remote",
+		TargetId:  vid,
+		TaskId:    "task",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "task", resp.GetTaskId())
+
+	status := func() *api.CloudMigrateInfo {
+		s, err := d.CloudMigrateStatus(&api.CloudMigrateStatusRequest{TaskId: "task"})
+		assert.NoError(t, err)
+		assert.Len(t, s.GetInfo()["remote"].GetList(), 1)
+		return s.GetInfo()["remote"].GetList()[0]
+	}
+	assert.Equal(t, api.CloudMigrate_Initialized, status().GetStatus())
+	assert.Error(t, d.Delete(context.TODO(), vid))
+
+	d.Advance(15 * time.Second)
+	info := status()
+	assert.Equal(t, api.CloudMigrate_InProgress, info.GetStatus())
+	assert.Equal(t, api.CloudMigrate_Restore, info.GetCurrentStage())
+	assert.Equal(t, vid, info.GetLocalVolumeId())
+
+	d.Advance(20 * time.Second)
+	info = status()
+	assert.Equal(t, api.CloudMigrate_Complete, info.GetStatus())
+	assert.Equal(t, api.CloudMigrate_Done, info.GetCurrentStage())
+	assert.Error(t, d.CloudMigrateCancel(&api.CloudMigrateCancelRequest{TaskId: "task"}))
+
+	// A canceled migration stays at the stage it was canceled in
+	_, err = d.CloudMigrateStart(&api.CloudMigrateStartRequest{
+		Operation: api.CloudMigrate_MigrateCluster,
+		ClusterId: "remote",
+		TaskId:    "task2",
+	})
+	assert.NoError(t, err)
+	d.Advance(5 * time.Second)
+	assert.NoError(t, d.CloudMigrateCancel(&api.CloudMigrateCancelRequest{TaskId: "task2"}))
+	d.Advance(time.Minute)
+	s, err := d.CloudMigrateStatus(&api.CloudMigrateStatusRequest{TaskId: "task2"})
+	assert.NoError(t, err)
+	assert.Equal(t, api.CloudMigrate_Canceled, s.GetInfo()["remote"].GetList()[0].GetStatus())
+	assert.Equal(t, api.CloudMigrate_Backup, s.GetInfo()["remote"].GetList()[0].GetCurrentStage())
+
+	// An injected fault fails the migration
+	d.InjectFault(Fault{Op: FaultCloudMigrateJob, Err: fmt.Errorf("network down")})
+	_, err = d.CloudMigrateStart(&api.CloudMigrateStartRequest{
+		Operation: api.CloudMigrate_MigrateVolume,
+		ClusterId: "remote",
+		TargetId:  vid,
+		TaskId:    "task3",
+	})
+	assert.NoError(t, err)
+	d.Advance(time.Minute)
+	s, err = d.CloudMigrateStatus(&api.CloudMigrateStatusRequest{TaskId: "task3"})
+	assert.NoError(t, err)
+	assert.Equal(t, api.CloudMigrate_Failed, s.GetInfo()["remote"].GetList()[0].GetStatus())
+	assert.Equal(t, "network down", s.GetInfo()["remote"].GetList()[0].GetErrorReason())
+}
+
+func TestFakeJobs(t *testing.T) {
+	d, err := newFakeDriver(map[string]string{
+		ClockParam:       ClockManual,
+		JobDurationParam: "30s",
+	})
+	assert.NoError(t, err)
+	vid, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "myvol"}, &api.Source{}, &api.VolumeSpec{
+		Size:    1234,
+		HaLevel: 1,
+	})
+	assert.NoError(t, err)
+
+	// Trim needs a mounted volume
+	_, err = d.FilesystemTrimStart(&api.SdkFilesystemTrimStartRequest{VolumeId: vid})
+	assert.Error(t, err)
+	_, err = d.Attach(context.TODO(), vid, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, d.Mount(context.TODO(), vid, "/mnt/myvol", nil))
+	_, err = d.FilesystemTrimStart(&api.SdkFilesystemTrimStartRequest{VolumeId: vid})
+	assert.NoError(t, err)
+	_, err = d.FilesystemTrimStart(&api.SdkFilesystemTrimStartRequest{VolumeId: vid})
+	assert.Error(t, err)
+
+	d.Advance(10 * time.Second)
+	trim, err := d.FilesystemTrimStatus(&api.SdkFilesystemTrimStatusRequest{VolumeId: vid})
+	assert.NoError(t, err)
+	assert.Equal(t, api.FilesystemTrim_FS_TRIM_INPROGRESS, trim.GetStatus())
+	d.Advance(30 * time.Second)
+	trim, err = d.FilesystemTrimStatus(&api.SdkFilesystemTrimStatusRequest{VolumeId: vid})
+	assert.NoError(t, err)
+	assert.Equal(t, api.FilesystemTrim_FS_TRIM_COMPLETED, trim.GetStatus())
+
+	// Filesystem check needs an unmounted volume
+	_, err = d.FilesystemCheckStart(&api.SdkFilesystemCheckStartRequest{VolumeId: vid})
+	assert.Error(t, err)
+	assert.NoError(t, d.Unmount(context.TODO(), vid, "/mnt/myvol", nil))
+	d.InjectFault(Fault{Op: FaultFilesystemCheckJob, VolumeID: vid, Count: 1, Err: fmt.Errorf("corrupt")})
+	_, err = d.FilesystemCheckStart(&api.SdkFilesystemCheckStartRequest{VolumeId: vid})
+	assert.NoError(t, err)
+	d.Advance(time.Minute)
+	fsck, err := d.FilesystemCheckStatus(&api.SdkFilesystemCheckStatusRequest{VolumeId: vid})
+	assert.NoError(t, err)
+	assert.Equal(t, api.FilesystemCheck_FS_CHECK_FAILED, fsck.GetStatus())
+
+	// Checksum verification can be stopped
+	_, err = d.VerifyChecksumStart(&api.SdkVerifyChecksumStartRequest{VolumeId: vid})
+	assert.NoError(t, err)
+	_, err = d.VerifyChecksumStop(&api.SdkVerifyChecksumStopRequest{VolumeId: vid})
+	assert.NoError(t, err)
+	sum, err := d.VerifyChecksumStatus(&api.SdkVerifyChecksumStatusRequest{VolumeId: vid})
+	assert.NoError(t, err)
+	assert.Equal(t, api.VerifyChecksum_VERIFY_CHECKSUM_STOPPED, sum.GetStatus())
+}
+
+func TestFakeFaults(t *testing.T) {
+	d, err := newFakeDriver(map[string]string{})
+	assert.NoError(t, err)
+	vid, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "myvol"}, &api.Source{}, &api.VolumeSpec{
+		Size:    1234,
+		HaLevel: 1,
+	})
+	assert.NoError(t, err)
+
+	fault := fmt.Errorf("injected")
+	d.InjectFault(Fault{Op: "Attach", VolumeID: vid, Skip: 1, Count: 2, Err: fault})
+	_, err = d.Attach(context.TODO(), vid, nil)
+	assert.NoError(t, err)
+	_, err = d.Attach(context.TODO(), vid, nil)
+	assert.Equal(t, fault, err)
+	_, err = d.Attach(context.TODO(), vid, nil)
+	assert.Equal(t, fault, err)
+	_, err = d.Attach(context.TODO(), vid, nil)
+	assert.NoError(t, err)
+
+	d.InjectFault(Fault{Op: "Detach", Err: fault})
+	assert.Equal(t, fault, d.Detach(context.TODO(), vid, nil))
+	d.ClearFaults()
+	assert.NoError(t, d.Detach(context.TODO(), vid, nil))
 }
 
 func TestFakeCloudBackupCreate(t *testing.T) {
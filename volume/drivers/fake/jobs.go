@@ -0,0 +1,415 @@
+/*
+Package fake provides an in-memory fake driver implementation
+Copyright 2018 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	jobsKeyPrefix = "/fake/jobs"
+
+	jobTrim     = "trim"
+	jobFsck     = "fsck"
+	jobChecksum = "checksum"
+
+	// jobState* are the states of a background job, mapped to the status
+	// enum of each job type.
+	jobStateStarted    = "started"
+	jobStateInProgress = "inprogress"
+	jobStateStopped    = "stopped"
+	jobStateCompleted  = "completed"
+	jobStateFailed     = "failed"
+
+	fsckModeHealth = "check_health"
+)
+
+// fakeJob is a trim, fsck or checksum job on a volume. Its state is derived
+// from the simulator clock.
+type fakeJob struct {
+	Kind      string
+	VolumeID  string
+	Mode      string
+	StartTime time.Time
+	Duration  time.Duration
+	StopTime  time.Time
+	Error     string
+	// Recorded is set once the result of a completed fsck has been saved
+	// on the volume.
+	Recorded bool
+}
+
+func (j *fakeJob) state(now time.Time) string {
+	if !j.StopTime.IsZero() {
+		return jobStateStopped
+	}
+	elapsed := now.Sub(j.StartTime)
+	switch {
+	case elapsed >= j.Duration && j.Error != "":
+		return jobStateFailed
+	case elapsed >= j.Duration:
+		return jobStateCompleted
+	case elapsed <= 0:
+		return jobStateStarted
+	}
+	return jobStateInProgress
+}
+
+func (j *fakeJob) running(now time.Time) bool {
+	s := j.state(now)
+	return s == jobStateStarted || s == jobStateInProgress
+}
+
+func (j *fakeJob) message(now time.Time) string {
+	switch s := j.state(now); s {
+	case jobStateFailed:
+		return j.Error
+	case jobStateInProgress:
+		pct := int64(now.Sub(j.StartTime) * 100 / j.Duration)
+		return fmt.Sprintf("%s of volume %s is %d%% done", j.Kind, j.VolumeID, pct)
+	default:
+		return fmt.Sprintf("%s of volume %s is %s", j.Kind, j.VolumeID, s)
+	}
+}
+
+func jobKey(kind, volumeID string) string {
+	return jobsKeyPrefix + "/" + kind + "/" + volumeID
+}
+
+func (d *driver) getJob(kind, volumeID string) (*fakeJob, error) {
+	var j fakeJob
+	if _, err := d.kv.GetVal(jobKey(kind, volumeID), &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// startJob starts a job of kind on the volume. Only one job of a kind runs
+// on a volume at a time. A fault for faultOp makes the job fail.
+func (d *driver) startJob(kind, faultOp, volumeID, mode string) (*fakeJob, error) {
+	now := d.clock.now()
+	if j, err := d.getJob(kind, volumeID); err == nil && j.running(now) {
+		return nil, fmt.Errorf("A %s job is already running on volume %s", kind, volumeID)
+	}
+	j := &fakeJob{
+		Kind:      kind,
+		VolumeID:  volumeID,
+		Mode:      mode,
+		StartTime: now,
+		Duration:  d.jobDuration,
+	}
+	if err := d.faults.check(faultOp, volumeID); err != nil {
+		j.Error = err.Error()
+	}
+	if _, err := d.kv.Put(jobKey(kind, volumeID), j, 0); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// stopJob stops the running job of kind on the volume.
+func (d *driver) stopJob(kind, volumeID string) error {
+	j, err := d.getJob(kind, volumeID)
+	if err == kvdb.ErrNotFound {
+		return fmt.Errorf("No %s job found for volume %s", kind, volumeID)
+	} else if err != nil {
+		return err
+	}
+	now := d.clock.now()
+	if !j.running(now) {
+		return fmt.Errorf("The %s job of volume %s is not running", kind, volumeID)
+	}
+	j.StopTime = now
+	_, err = d.kv.Put(jobKey(kind, volumeID), j, 0)
+	return err
+}
+
+func (d *driver) deleteJobs(volumeID string) {
+	for _, kind := range []string{jobTrim, jobFsck, jobChecksum} {
+		d.kv.Delete(jobKey(kind, volumeID))
+	}
+}
+
+func (d *driver) FilesystemTrimStart(request *api.SdkFilesystemTrimStartRequest) (*api.SdkFilesystemTrimStartResponse, error) {
+	if err := d.faults.check("FilesystemTrimStart", request.GetVolumeId()); err != nil {
+		return nil, err
+	}
+	v, err := d.GetVol(request.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	if len(v.GetAttachPath()) == 0 {
+		return nil, fmt.Errorf("Volume %s must be mounted to trim it", v.GetId())
+	}
+	j, err := d.startJob(jobTrim, FaultFilesystemTrimJob, v.GetId(), "")
+	if err != nil {
+		return nil, err
+	}
+	now := d.clock.now()
+	return &api.SdkFilesystemTrimStartResponse{
+		Status:  trimStatus(j.state(now)),
+		Message: j.message(now),
+	}, nil
+}
+
+func (d *driver) FilesystemTrimStatus(request *api.SdkFilesystemTrimStatusRequest) (*api.SdkFilesystemTrimStatusResponse, error) {
+	if _, err := d.GetVol(request.GetVolumeId()); err != nil {
+		return nil, err
+	}
+	j, err := d.getJob(jobTrim, request.GetVolumeId())
+	if err == kvdb.ErrNotFound {
+		return &api.SdkFilesystemTrimStatusResponse{
+			Status: api.FilesystemTrim_FS_TRIM_NOT_RUNNING,
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	now := d.clock.now()
+	return &api.SdkFilesystemTrimStatusResponse{
+		Status:  trimStatus(j.state(now)),
+		Message: j.message(now),
+	}, nil
+}
+
+func (d *driver) FilesystemTrimStop(request *api.SdkFilesystemTrimStopRequest) (*api.SdkFilesystemTrimStopResponse, error) {
+	if err := d.stopJob(jobTrim, request.GetVolumeId()); err != nil {
+		return nil, err
+	}
+	return &api.SdkFilesystemTrimStopResponse{}, nil
+}
+
+// AutoFilesystemTrimStatus returns the status of the trim jobs of all
+// volumes.
+func (d *driver) AutoFilesystemTrimStatus(request *api.SdkAutoFSTrimStatusRequest) (*api.SdkAutoFSTrimStatusResponse, error) {
+	kvps, err := d.kv.Enumerate(jobsKeyPrefix + "/" + jobTrim)
+	if err != nil {
+		return nil, err
+	}
+	now := d.clock.now()
+	status := make(map[string]api.FilesystemTrim_FilesystemTrimStatus, len(kvps))
+	for _, kvp := range kvps {
+		var j fakeJob
+		if err := json.Unmarshal(kvp.Value, &j); err != nil {
+			return nil, err
+		}
+		status[j.VolumeID] = trimStatus(j.state(now))
+	}
+	return &api.SdkAutoFSTrimStatusResponse{TrimStatus: status}, nil
+}
+
+func trimStatus(state string) api.FilesystemTrim_FilesystemTrimStatus {
+	switch state {
+	case jobStateStarted:
+		return api.FilesystemTrim_FS_TRIM_STARTED
+	case jobStateInProgress:
+		return api.FilesystemTrim_FS_TRIM_INPROGRESS
+	case jobStateStopped:
+		return api.FilesystemTrim_FS_TRIM_STOPPED
+	case jobStateCompleted:
+		return api.FilesystemTrim_FS_TRIM_COMPLETED
+	case jobStateFailed:
+		return api.FilesystemTrim_FS_TRIM_FAILED
+	}
+	return api.FilesystemTrim_FS_TRIM_UNKNOWN
+}
+
+func (d *driver) FilesystemCheckStart(request *api.SdkFilesystemCheckStartRequest) (*api.SdkFilesystemCheckStartResponse, error) {
+	if err := d.faults.check("FilesystemCheckStart", request.GetVolumeId()); err != nil {
+		return nil, err
+	}
+	v, err := d.GetVol(request.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	if len(v.GetAttachPath()) != 0 {
+		return nil, fmt.Errorf("Volume %s must be unmounted to check its filesystem", v.GetId())
+	}
+	mode := request.GetMode()
+	if mode == "" {
+		mode = fsckModeHealth
+	}
+	j, err := d.startJob(jobFsck, FaultFilesystemCheckJob, v.GetId(), mode)
+	if err != nil {
+		return nil, err
+	}
+	now := d.clock.now()
+	return &api.SdkFilesystemCheckStartResponse{
+		Status:  fsckStatus(j.state(now)),
+		Message: j.message(now),
+	}, nil
+}
+
+// FilesystemCheckStatus returns the status of the fsck job of the volume.
+// The result of a completed job is saved on the volume the first time it is
+// observed.
+func (d *driver) FilesystemCheckStatus(request *api.SdkFilesystemCheckStatusRequest) (*api.SdkFilesystemCheckStatusResponse, error) {
+	v, err := d.GetVol(request.GetVolumeId())
+	if err != nil {
+		return nil, err
+	}
+	j, err := d.getJob(jobFsck, v.GetId())
+	if err == kvdb.ErrNotFound {
+		return &api.SdkFilesystemCheckStatusResponse{
+			Status:       api.FilesystemCheck_FS_CHECK_NOT_RUNNING,
+			HealthStatus: v.GetLastScanStatus(),
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	now := d.clock.now()
+	state := j.state(now)
+	if !j.Recorded && (state == jobStateCompleted || state == jobStateFailed) {
+		v.LastScan, _ = ptypes.TimestampProto(j.StartTime.Add(j.Duration))
+		if state == jobStateFailed {
+			v.LastScanStatus = api.FilesystemHealthStatus_FS_HEALTH_STATUS_NEEDS_INSPECTION
+		} else {
+			v.LastScanStatus = api.FilesystemHealthStatus_FS_HEALTH_STATUS_HEALTHY
+			if j.Mode != fsckModeHealth {
+				v.LastScanFix = v.LastScan
+			}
+		}
+		if err := d.UpdateVol(v); err != nil {
+			return nil, err
+		}
+		j.Recorded = true
+		if _, err := d.kv.Put(jobKey(jobFsck, v.GetId()), j, 0); err != nil {
+			return nil, err
+		}
+	}
+	return &api.SdkFilesystemCheckStatusResponse{
+		Status:       fsckStatus(state),
+		HealthStatus: v.GetLastScanStatus(),
+		Mode:         j.Mode,
+		Message:      j.message(now),
+	}, nil
+}
+
+func (d *driver) FilesystemCheckStop(request *api.SdkFilesystemCheckStopRequest) (*api.SdkFilesystemCheckStopResponse, error) {
+	if err := d.stopJob(jobFsck, request.GetVolumeId()); err != nil {
+		return nil, err
+	}
+	return &api.SdkFilesystemCheckStopResponse{}, nil
+}
+
+// FilesystemCheckListVolumes lists the volumes whose last fsck found
+// problems.
+func (d *driver) FilesystemCheckListVolumes(request *api.SdkFilesystemCheckListVolumesRequest) (*api.SdkFilesystemCheckListVolumesResponse, error) {
+	vols, err := d.StoreEnumerator.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp := &api.SdkFilesystemCheckListVolumesResponse{
+		Volumes: make(map[string]*api.FilesystemCheckVolInfo),
+	}
+	for _, v := range vols {
+		if v.GetLastScanStatus() != api.FilesystemHealthStatus_FS_HEALTH_STATUS_NEEDS_INSPECTION {
+			continue
+		}
+		resp.Volumes[v.GetId()] = &api.FilesystemCheckVolInfo{
+			VolumeName:   v.GetLocator().GetName(),
+			HealthStatus: v.GetLastScanStatus(),
+			FsStatusMsg:  "Filesystem check failed",
+		}
+	}
+	return resp, nil
+}
+
+func fsckStatus(state string) api.FilesystemCheck_FilesystemCheckStatus {
+	switch state {
+	case jobStateStarted:
+		return api.FilesystemCheck_FS_CHECK_STARTED
+	case jobStateInProgress:
+		return api.FilesystemCheck_FS_CHECK_INPROGRESS
+	case jobStateStopped:
+		return api.FilesystemCheck_FS_CHECK_STOPPED
+	case jobStateCompleted:
+		return api.FilesystemCheck_FS_CHECK_COMPLETED
+	case jobStateFailed:
+		return api.FilesystemCheck_FS_CHECK_FAILED
+	}
+	return api.FilesystemCheck_FS_CHECK_UNKNOWN
+}
+
+func (d *driver) VerifyChecksumStart(request *api.SdkVerifyChecksumStartRequest) (*api.SdkVerifyChecksumStartResponse, error) {
+	if err := d.faults.check("VerifyChecksumStart", request.GetVolumeId()); err != nil {
+		return nil, err
+	}
+	if _, err := d.Inspect(correlation.TODO(), []string{request.GetVolumeId()}); err != nil {
+		return nil, err
+	}
+	j, err := d.startJob(jobChecksum, FaultVerifyChecksumJob, request.GetVolumeId(), "")
+	if err != nil {
+		return nil, err
+	}
+	now := d.clock.now()
+	return &api.SdkVerifyChecksumStartResponse{
+		Status:  checksumStatus(j.state(now)),
+		Message: j.message(now),
+	}, nil
+}
+
+func (d *driver) VerifyChecksumStatus(request *api.SdkVerifyChecksumStatusRequest) (*api.SdkVerifyChecksumStatusResponse, error) {
+	if _, err := d.GetVol(request.GetVolumeId()); err != nil {
+		return nil, err
+	}
+	j, err := d.getJob(jobChecksum, request.GetVolumeId())
+	if err == kvdb.ErrNotFound {
+		return &api.SdkVerifyChecksumStatusResponse{
+			Status: api.VerifyChecksum_VERIFY_CHECKSUM_NOT_RUNNING,
+		}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	now := d.clock.now()
+	return &api.SdkVerifyChecksumStatusResponse{
+		Status:  checksumStatus(j.state(now)),
+		Message: j.message(now),
+	}, nil
+}
+
+func (d *driver) VerifyChecksumStop(request *api.SdkVerifyChecksumStopRequest) (*api.SdkVerifyChecksumStopResponse, error) {
+	if err := d.stopJob(jobChecksum, request.GetVolumeId()); err != nil {
+		return nil, err
+	}
+	return &api.SdkVerifyChecksumStopResponse{
+		Message: fmt.Sprintf("Checksum verification of volume %s stopped", request.GetVolumeId()),
+	}, nil
+}
+
+// checksumStatus maps a job state to the checksum status. The checksum
+// enum has no in progress value, running jobs are reported as started.
+func checksumStatus(state string) api.VerifyChecksum_VerifyChecksumStatus {
+	switch state {
+	case jobStateStarted, jobStateInProgress:
+		return api.VerifyChecksum_VERIFY_CHECKSUM_STARTED
+	case jobStateStopped:
+		return api.VerifyChecksum_VERIFY_CHECKSUM_STOPPED
+	case jobStateCompleted:
+		return api.VerifyChecksum_VERIFY_CHECKSUM_COMPLETED
+	case jobStateFailed:
+		return api.VerifyChecksum_VERIFY_CHECKSUM_FAILED
+	}
+	return api.VerifyChecksum_VERIFY_CHECKSUM_UNKNOWN
+}
@@ -0,0 +1,295 @@
+/*
+Package fake provides an in-memory fake driver implementation
+Copyright 2018 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	migrationsKeyPrefix = "/fake/migrations"
+)
+
+// migrationStages are the stages a migration goes through, each taking the
+// stage duration of the driver.
+var migrationStages = []api.CloudMigrate_Stage{
+	api.CloudMigrate_Backup,
+	api.CloudMigrate_Restore,
+	api.CloudMigrate_VolumeUpdate,
+}
+
+// fakeMigration is a cloud migration task. Its progress is derived from the
+// simulator clock.
+type fakeMigration struct {
+	TaskId        string
+	ClusterId     string
+	Operation     api.CloudMigrate_OperationType
+	Volumes       []fakeMigrationVolume
+	StartTime     time.Time
+	StageDuration time.Duration
+	CancelTime    time.Time
+	// Error makes the migration fail at the end of the backup stage.
+	Error string
+}
+
+type fakeMigrationVolume struct {
+	LocalVolumeId   string
+	LocalVolumeName string
+	RemoteVolumeId  string
+	CloudbackupId   string
+	BytesTotal      uint64
+}
+
+// progress returns the stage and status of the migration at now, and how
+// long it has been running.
+func (m *fakeMigration) progress(now time.Time) (api.CloudMigrate_Stage, api.CloudMigrate_Status, time.Duration) {
+	end := now
+	if !m.CancelTime.IsZero() && m.CancelTime.Before(now) {
+		end = m.CancelTime
+	}
+	elapsed := end.Sub(m.StartTime)
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	idx := int(elapsed / m.StageDuration)
+
+	if m.Error != "" && idx >= 1 {
+		return api.CloudMigrate_Backup, api.CloudMigrate_Failed, m.StageDuration
+	}
+	if idx >= len(migrationStages) {
+		total := m.StageDuration * time.Duration(len(migrationStages))
+		return api.CloudMigrate_Done, api.CloudMigrate_Complete, total
+	}
+	if !m.CancelTime.IsZero() && !m.CancelTime.After(now) {
+		return migrationStages[idx], api.CloudMigrate_Canceled, elapsed
+	}
+	if elapsed == 0 {
+		return migrationStages[idx], api.CloudMigrate_Initialized, elapsed
+	}
+	return migrationStages[idx], api.CloudMigrate_InProgress, elapsed
+}
+
+func (m *fakeMigration) done(now time.Time) bool {
+	_, status, _ := m.progress(now)
+	return status == api.CloudMigrate_Complete ||
+		status == api.CloudMigrate_Failed ||
+		status == api.CloudMigrate_Canceled
+}
+
+func (m *fakeMigration) info(now time.Time) []*api.CloudMigrateInfo {
+	stage, status, elapsed := m.progress(now)
+	total := m.StageDuration * time.Duration(len(migrationStages))
+	start, _ := ptypes.TimestampProto(m.StartTime)
+	last, _ := ptypes.TimestampProto(m.StartTime.Add(elapsed))
+
+	infos := make([]*api.CloudMigrateInfo, 0, len(m.Volumes))
+	for _, v := range m.Volumes {
+		info := &api.CloudMigrateInfo{
+			TaskId:          m.TaskId,
+			ClusterId:       m.ClusterId,
+			LocalVolumeId:   v.LocalVolumeId,
+			LocalVolumeName: v.LocalVolumeName,
+			RemoteVolumeId:  v.RemoteVolumeId,
+			CloudbackupId:   v.CloudbackupId,
+			CurrentStage:    stage,
+			Status:          status,
+			StartTime:       start,
+			LastUpdate:      last,
+			BytesTotal:      v.BytesTotal,
+		}
+		// Data is transferred during the backup stage.
+		if elapsed >= m.StageDuration {
+			info.BytesDone = v.BytesTotal
+		} else {
+			info.BytesDone = uint64(float64(v.BytesTotal) * float64(elapsed) / float64(m.StageDuration))
+		}
+		switch status {
+		case api.CloudMigrate_Complete:
+			info.CompletedTime = last
+		case api.CloudMigrate_Failed:
+			info.ErrorReason = m.Error
+			info.CompletedTime = last
+		case api.CloudMigrate_Canceled:
+			info.CompletedTime = last
+		default:
+			info.EtaSeconds = int64((total - elapsed) / time.Second)
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func (d *driver) getMigration(taskID string) (*fakeMigration, error) {
+	var m fakeMigration
+	if _, err := d.kv.GetVal(migrationsKeyPrefix+"/"+taskID, &m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// migrationVolumes returns the volumes a migration request applies to.
+func (d *driver) migrationVolumes(request *api.CloudMigrateStartRequest) ([]*api.Volume, error) {
+	switch request.GetOperation() {
+	case api.CloudMigrate_MigrateCluster:
+		return d.StoreEnumerator.Enumerate(&api.VolumeLocator{}, nil)
+	case api.CloudMigrate_MigrateVolume:
+		v, err := d.GetVol(request.GetTargetId())
+		if err != nil {
+			return nil, fmt.Errorf("Volume %s not found: %v", request.GetTargetId(), err)
+		}
+		return []*api.Volume{v}, nil
+	case api.CloudMigrate_MigrateVolumeGroup:
+		vols, err := d.StoreEnumerator.Enumerate(&api.VolumeLocator{
+			Group: &api.Group{Id: request.GetTargetId()},
+		}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if len(vols) == 0 {
+			return nil, fmt.Errorf("No volumes found in group %s", request.GetTargetId())
+		}
+		return vols, nil
+	}
+	return nil, fmt.Errorf("Invalid migration operation %v", request.GetOperation())
+}
+
+// CloudMigrateStart starts a migration. The migration goes through the
+// backup, restore and volume update stages as the simulator clock advances.
+func (d *driver) CloudMigrateStart(request *api.CloudMigrateStartRequest) (*api.CloudMigrateStartResponse, error) {
+	if err := d.faults.check("CloudMigrateStart", request.GetTaskId()); err != nil {
+		return nil, err
+	}
+	if len(request.GetClusterId()) == 0 {
+		return nil, fmt.Errorf("Cluster id must be provided")
+	}
+	taskID := request.GetTaskId()
+	if len(taskID) == 0 {
+		taskID = strings.TrimSuffix(uuid.New(), "\n")
+	} else if _, err := d.getMigration(taskID); err == nil {
+		return nil, fmt.Errorf("Migration task %s already exists", taskID)
+	}
+
+	vols, err := d.migrationVolumes(request)
+	if err != nil {
+		return nil, err
+	}
+	m := &fakeMigration{
+		TaskId:        taskID,
+		ClusterId:     request.GetClusterId(),
+		Operation:     request.GetOperation(),
+		Volumes:       make([]fakeMigrationVolume, 0, len(vols)),
+		StartTime:     d.clock.now(),
+		StageDuration: d.migrationStageDuration,
+	}
+	if err := d.faults.check(FaultCloudMigrateJob, taskID); err != nil {
+		m.Error = err.Error()
+	}
+	for _, v := range vols {
+		m.Volumes = append(m.Volumes, fakeMigrationVolume{
+			LocalVolumeId:   v.GetId(),
+			LocalVolumeName: v.GetLocator().GetName(),
+			RemoteVolumeId:  strings.TrimSuffix(uuid.New(), "\n"),
+			CloudbackupId:   strings.TrimSuffix(uuid.New(), "\n"),
+			BytesTotal:      d.getStats(v).BytesUsed,
+		})
+	}
+	if _, err := d.kv.Put(migrationsKeyPrefix+"/"+taskID, m, 0); err != nil {
+		return nil, err
+	}
+	return &api.CloudMigrateStartResponse{TaskId: taskID}, nil
+}
+
+// CloudMigrateCancel cancels a migration that has not completed.
+func (d *driver) CloudMigrateCancel(request *api.CloudMigrateCancelRequest) error {
+	if err := d.faults.check("CloudMigrateCancel", request.GetTaskId()); err != nil {
+		return err
+	}
+	m, err := d.getMigration(request.GetTaskId())
+	if err == kvdb.ErrNotFound {
+		return fmt.Errorf("Migration task %s not found", request.GetTaskId())
+	} else if err != nil {
+		return err
+	}
+	now := d.clock.now()
+	if m.done(now) {
+		return fmt.Errorf("Migration task %s has already finished", request.GetTaskId())
+	}
+	m.CancelTime = now
+	_, err = d.kv.Put(migrationsKeyPrefix+"/"+m.TaskId, m, 0)
+	return err
+}
+
+// CloudMigrateStatus returns the status of the migrations, keyed by the
+// cluster they migrate to.
+func (d *driver) CloudMigrateStatus(request *api.CloudMigrateStatusRequest) (*api.CloudMigrateStatusResponse, error) {
+	kvps, err := d.kv.Enumerate(migrationsKeyPrefix)
+	if err != nil {
+		return nil, err
+	}
+	now := d.clock.now()
+	resp := &api.CloudMigrateStatusResponse{
+		Info: make(map[string]*api.CloudMigrateInfoList),
+	}
+	for _, kvp := range kvps {
+		var m fakeMigration
+		if err := json.Unmarshal(kvp.Value, &m); err != nil {
+			return nil, err
+		}
+		if request.GetTaskId() != "" && request.GetTaskId() != m.TaskId {
+			continue
+		}
+		if request.GetClusterId() != "" && request.GetClusterId() != m.ClusterId {
+			continue
+		}
+		list, ok := resp.Info[m.ClusterId]
+		if !ok {
+			list = &api.CloudMigrateInfoList{}
+			resp.Info[m.ClusterId] = list
+		}
+		list.List = append(list.List, m.info(now)...)
+	}
+	return resp, nil
+}
+
+// migrating returns true if a migration of the volume is running.
+func (d *driver) migrating(volumeID string) bool {
+	kvps, err := d.kv.Enumerate(migrationsKeyPrefix)
+	if err != nil {
+		return false
+	}
+	now := d.clock.now()
+	for _, kvp := range kvps {
+		var m fakeMigration
+		if err := json.Unmarshal(kvp.Value, &m); err != nil || m.done(now) {
+			continue
+		}
+		for _, v := range m.Volumes {
+			if v.LocalVolumeId == volumeID {
+				return true
+			}
+		}
+	}
+	return false
+}
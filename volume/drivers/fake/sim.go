@@ -0,0 +1,226 @@
+/*
+Package fake provides an in-memory fake driver implementation
+Copyright 2018 Portworx
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package fake
+
+import (
+	"sync"
+	"time"
+
+	"github.com/libopenstorage/openstorage/api"
+)
+
+const (
+	// ClockParam selects the clock of the simulator. With the value
+	// ClockManual time only moves when Advance is called, which makes
+	// job and migration progress fully deterministic.
+	ClockParam = "clock"
+	// ClockManual is the value of ClockParam for a manual clock.
+	ClockManual = "manual"
+	// JobDurationParam sets how long trim, fsck and checksum jobs take,
+	// as a time.Duration string.
+	JobDurationParam = "job_duration"
+	// MigrationStageDurationParam sets how long each stage of a cloud
+	// migration takes, as a time.Duration string.
+	MigrationStageDurationParam = "migration_stage_duration"
+	// AttachNodeOption is an attach and detach option naming the node the
+	// request comes from. It defaults to this node and lets tests simulate
+	// requests from other nodes.
+	AttachNodeOption = "fake.node"
+
+	// FaultCloudMigrateJob, FaultFilesystemTrimJob, FaultFilesystemCheckJob
+	// and FaultVerifyChecksumJob are the ops of faults that make a
+	// background job fail when it would otherwise complete. Faults for
+	// other ops use the name of the driver method, such as "Attach".
+	FaultCloudMigrateJob    = "CloudMigrateJob"
+	FaultFilesystemTrimJob  = "FilesystemTrimJob"
+	FaultFilesystemCheckJob = "FilesystemCheckJob"
+	FaultVerifyChecksumJob  = "VerifyChecksumJob"
+
+	statsKeyPrefix = "/fake/stats"
+
+	defaultJobDuration            = 30 * time.Second
+	defaultMigrationStageDuration = 10 * time.Second
+
+	// I/O generated per second by a mounted volume.
+	simReadIOPS  = 100
+	simWriteIOPS = 50
+	simIOSize    = 4096
+)
+
+// Simulator is implemented by the fake driver to let tests drive it.
+type Simulator interface {
+	// InjectFault makes the driver fail the operations matching f.
+	InjectFault(f Fault)
+	// ClearFaults removes all injected faults.
+	ClearFaults()
+	// Advance moves the clock of the simulator forward.
+	Advance(d time.Duration)
+}
+
+// Fault makes the driver fail an operation.
+type Fault struct {
+	// Op is the name of the driver method to fail, or one of the Fault*Job
+	// ops to fail a background job.
+	Op string
+	// VolumeID restricts the fault to a volume or, for migrations, a task.
+	// Empty matches any.
+	VolumeID string
+	// Skip is the number of matching operations that succeed before the
+	// fault fires.
+	Skip int
+	// Count is the number of times the fault fires. Zero fires forever.
+	Count int
+	// Err is the error of the failed operation.
+	Err error
+}
+
+type faults struct {
+	sync.Mutex
+	list []*Fault
+}
+
+// check returns the error of the first fault matching op and id, and
+// accounts for it.
+func (f *faults) check(op, id string) error {
+	f.Lock()
+	defer f.Unlock()
+	for i, fault := range f.list {
+		if fault.Op != op || (fault.VolumeID != "" && fault.VolumeID != id) {
+			continue
+		}
+		if fault.Skip > 0 {
+			fault.Skip--
+			continue
+		}
+		if fault.Count > 0 {
+			fault.Count--
+			if fault.Count == 0 {
+				f.list = append(f.list[:i], f.list[i+1:]...)
+			}
+		}
+		return fault.Err
+	}
+	return nil
+}
+
+// clock is the time source of the simulator.
+type clock struct {
+	sync.Mutex
+	manual bool
+	base   time.Time
+	offset time.Duration
+}
+
+func newClock(manual bool) *clock {
+	return &clock{
+		manual: manual,
+		base:   time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func (c *clock) now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	if c.manual {
+		return c.base.Add(c.offset)
+	}
+	return time.Now().Add(c.offset)
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.offset += d
+}
+
+func (d *driver) InjectFault(f Fault) {
+	d.faults.Lock()
+	defer d.faults.Unlock()
+	d.faults.list = append(d.faults.list, &f)
+}
+
+func (d *driver) ClearFaults() {
+	d.faults.Lock()
+	defer d.faults.Unlock()
+	d.faults.list = nil
+}
+
+func (d *driver) Advance(duration time.Duration) {
+	d.clock.advance(duration)
+}
+
+// fakeStats are the I/O counters of a volume. While the volume is mounted
+// it generates I/O at a fixed rate.
+type fakeStats struct {
+	Reads        uint64
+	Writes       uint64
+	BytesUsed    uint64
+	MountedSince time.Time
+}
+
+// accrue returns the counters at now, including the I/O generated since the
+// volume was mounted. Used bytes never exceed size.
+func (s fakeStats) accrue(now time.Time, size uint64) fakeStats {
+	if s.MountedSince.IsZero() || !now.After(s.MountedSince) {
+		return s
+	}
+	secs := uint64(now.Sub(s.MountedSince) / time.Second)
+	s.Reads += secs * simReadIOPS
+	s.Writes += secs * simWriteIOPS
+	s.BytesUsed += secs * simWriteIOPS * simIOSize
+	if s.BytesUsed > size {
+		s.BytesUsed = size
+	}
+	s.MountedSince = s.MountedSince.Add(time.Duration(secs) * time.Second)
+	return s
+}
+
+func (s fakeStats) toAPI() *api.Stats {
+	return &api.Stats{
+		Reads:      s.Reads,
+		ReadMs:     s.Reads,
+		ReadBytes:  s.Reads * simIOSize,
+		Writes:     s.Writes,
+		WriteMs:    s.Writes,
+		WriteBytes: s.Writes * simIOSize,
+		IoMs:       s.Reads + s.Writes,
+		BytesUsed:  s.BytesUsed,
+	}
+}
+
+func (d *driver) getStats(v *api.Volume) fakeStats {
+	var s fakeStats
+	d.kv.GetVal(statsKeyPrefix+"/"+v.GetId(), &s)
+	return s.accrue(d.clock.now(), v.GetSpec().GetSize())
+}
+
+func (d *driver) putStats(volumeID string, s fakeStats) error {
+	_, err := d.kv.Put(statsKeyPrefix+"/"+volumeID, &s, 0)
+	return err
+}
+
+// setMounted starts or stops the generation of I/O for the volume.
+func (d *driver) setMounted(v *api.Volume, mounted bool) error {
+	s := d.getStats(v)
+	switch {
+	case mounted && s.MountedSince.IsZero():
+		s.MountedSince = d.clock.now()
+	case !mounted:
+		s.MountedSince = time.Time{}
+	}
+	return d.putStats(v.GetId(), s)
+}
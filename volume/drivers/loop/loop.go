@@ -0,0 +1,711 @@
+package loop
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/pkg/mount"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// Name of the driver
+	Name = "loop"
+	// Type of the driver
+	Type = api.DriverType_DRIVER_TYPE_BLOCK
+	// LoopBasePath is the default directory holding the backing files.
+	LoopBasePath = "/var/lib/openstorage/loop/"
+)
+
+// Implements the open storage volume interface on top of sparse files
+// exposed as block devices through the kernel loop driver.
+type driver struct {
+	volume.IODriver
+	volume.StoreEnumerator
+	volume.StatsDriver
+	volume.QuiesceDriver
+	volume.CredsDriver
+	volume.CloudBackupDriver
+	volume.CloudMigrateDriver
+	volume.FilesystemTrimDriver
+	volume.FilesystemCheckDriver
+	volume.VerifyChecksumDriver
+	basePath string
+	mounter  mount.Manager
+	// lock serializes the changes of the volumes, so that they are not
+	// lost by concurrent updates and a loop device is never leaked
+	lock     sync.Mutex
+	stats    *common.StatsCollector
+	qos      *common.QosEnforcer
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// Init initializes the loop driver. The optional "path" parameter overrides
+// the directory holding the backing files, and common.QosCgroupParam the
+// cgroup whose I/O to the volumes is throttled, which workloads must run in.
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	basePath, ok := params["path"]
+	if !ok {
+		basePath = LoopBasePath
+	}
+	if err := os.MkdirAll(basePath, 0744); err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(LoopControlPath); err != nil {
+		return nil, fmt.Errorf("Loop devices are not available: %v", err)
+	}
+
+	mounter, err := mount.New(
+		mount.DeviceMount,
+		nil,
+		[]*regexp.Regexp{regexp.MustCompile(`^/dev/loop[0-9]+$`)},
+		nil,
+		[]string{},
+		"",
+	)
+	if err != nil {
+		logrus.Warnf("Failed to create mount manager for loop devices: %v", err)
+		return nil, err
+	}
+
+	inst := &driver{
+		IODriver:              volume.IONotSupported,
+		StoreEnumerator:       common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
+		StatsDriver:           volume.StatsNotSupported,
+		QuiesceDriver:         volume.QuiesceNotSupported,
+		CredsDriver:           volume.CredsNotSupported,
+		CloudBackupDriver:     volume.CloudBackupNotSupported,
+		CloudMigrateDriver:    volume.CloudMigrateNotSupported,
+		FilesystemTrimDriver:  volume.FilesystemTrimNotSupported,
+		FilesystemCheckDriver: volume.FilesystemCheckNotSupported,
+		VerifyChecksumDriver:  volume.VerifyChecksumNotSupported,
+		basePath:              basePath,
+		mounter:               mounter,
+		stats:                 common.NewStatsCollector(),
+		qos:                   common.NewQosEnforcer(params[common.QosCgroupParam]),
+		stop:                  make(chan struct{}),
+	}
+
+	// Loop devices do not survive a reboot. Mark volumes whose device no
+	// longer refers to their backing file as detached.
+	vols, err := inst.Enumerate(&api.VolumeLocator{}, nil)
+	if err == nil {
+		for _, v := range vols {
+			if v.DevicePath == "" {
+				continue
+			}
+			if loopBackingFile(v.DevicePath) == inst.backingFile(v.Id) {
+				if len(v.AttachPath) > 0 && common.Throttled(v.Spec.IoThrottle) {
+					if err := inst.qos.Apply(v.Id, v.DevicePath, v.Spec.IoThrottle); err != nil {
+						logrus.Warnf("Failed to throttle I/O of volume %v: %v", v.Id, err)
+					}
+				}
+				continue
+			}
+			logrus.Infof("Volume %v is no longer attached at %v", v.Id, v.DevicePath)
+			v.DevicePath = ""
+			v.AttachPath = nil
+			v.State = api.VolumeState_VOLUME_STATE_DETACHED
+			inst.UpdateVol(v)
+		}
+	} else {
+		logrus.Println("Could not enumerate Volumes, ", err)
+	}
+
+	go inst.stats.RefreshUsage(inst.StoreEnumerator, inst.volumeSource, common.UsageRefreshInterval, inst.stop)
+
+	logrus.Println("Loop driver initialized with backing files at: ", basePath)
+	return inst, nil
+}
+
+//
+// These functions below implement the volume driver interface.
+//
+
+func (d *driver) StartVolumeWatcher() {
+	return
+}
+
+func (d *driver) GetVolumeWatcher(locator *api.VolumeLocator, labels map[string]string) (chan *api.Volume, error) {
+	return nil, nil
+}
+
+func (d *driver) StopVolumeWatcher() {
+	return
+}
+
+func (d *driver) String() string {
+	return Name
+}
+
+func (d *driver) Name() string {
+	return Name
+}
+
+func (d *driver) Type() api.DriverType {
+	return Type
+}
+
+func (d *driver) Version() (*api.StorageVersion, error) {
+	return &api.StorageVersion{
+		Driver:  d.Name(),
+		Version: "1.0.0",
+	}, nil
+}
+
+// Status diagnostic information
+func (d *driver) Status() [][2]string {
+	return [][2]string{{"Path", d.basePath}}
+}
+
+func (d *driver) backingFile(volumeID string) string {
+	return filepath.Join(d.basePath, volumeID+".img")
+}
+
+func (d *driver) Create(
+	ctx context.Context,
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+) (string, error) {
+	volumeID := strings.TrimSuffix(uuid.New(), "\n")
+	if source != nil && source.Parent != "" {
+		return d.snapshot(source.Parent, volumeID, false, locator, source)
+	}
+	if spec.Size == 0 {
+		return "", fmt.Errorf("Volume size cannot be zero: loop")
+	}
+
+	// The backing file is sparse, space is allocated as it is written.
+	file := d.backingFile(volumeID)
+	f, err := os.OpenFile(file, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return "", err
+	}
+	err = f.Truncate(int64(spec.Size))
+	f.Close()
+	if err != nil {
+		os.Remove(file)
+		return "", err
+	}
+
+	if spec.Format != api.FSType_FS_TYPE_NONE {
+		if err := d.withDevice(file, func(dev string) error {
+			return format(dev, spec.Format)
+		}); err != nil {
+			os.Remove(file)
+			return "", err
+		}
+	}
+
+	v := common.NewVolume(
+		volumeID,
+		spec.Format,
+		locator,
+		source,
+		spec,
+	)
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	if err := d.CreateVol(v); err != nil {
+		os.Remove(file)
+		return "", err
+	}
+	logrus.Infof("Loop created volume %v (size=%v) at %v", volumeID, spec.Size, file)
+	return volumeID, nil
+}
+
+// snapshot creates newID from a copy of the backing file of parentID. The
+// copy shares extents with the parent where the filesystem supports
+// reflinks.
+func (d *driver) snapshot(
+	parentID string,
+	newID string,
+	readonly bool,
+	locator *api.VolumeLocator,
+	source *api.Source,
+) (string, error) {
+	parent, err := d.GetVol(parentID)
+	if err != nil {
+		return "", err
+	}
+
+	// Flush the filesystem of a mounted parent so the snapshot is
+	// consistent.
+	for _, p := range parent.AttachPath {
+		syncFilesystem(p)
+	}
+	if err := common.CloneFile(d.backingFile(parent.Id), d.backingFile(newID)); err != nil {
+		os.Remove(d.backingFile(newID))
+		return "", err
+	}
+
+	v := common.NewVolume(
+		newID,
+		parent.Format,
+		locator,
+		source,
+		parent.Spec.Copy(),
+	)
+	v.Readonly = readonly
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	if err := d.CreateVol(v); err != nil {
+		os.Remove(d.backingFile(newID))
+		return "", err
+	}
+	logrus.Infof("Loop created volume %v from %v", newID, parent.Id)
+	return newID, nil
+}
+
+func (d *driver) Delete(ctx context.Context, volumeID string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		logrus.Println(err)
+		return err
+	}
+	if len(v.AttachPath) > 0 {
+		return volume.ErrVolAttached
+	}
+	if v.DevicePath != "" {
+		if err := loopDetach(v.DevicePath); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(d.backingFile(v.Id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := d.DeleteVol(volumeID); err != nil {
+		logrus.Println(err)
+		return err
+	}
+	logrus.Infof("Loop deleted volume %v", volumeID)
+	return nil
+}
+
+func (d *driver) MountedAt(ctx context.Context, mountpath string) string {
+	return ""
+}
+
+func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	defer d.stats.Begin("mount", volumeID)()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return fmt.Errorf("Failed to locate volume %q", volumeID)
+	}
+	if v.Spec.Format == api.FSType_FS_TYPE_NONE {
+		return fmt.Errorf("Volume of raw format cannot be mounted")
+	}
+	if v.DevicePath == "" {
+		return volume.ErrVolDetached
+	}
+	var flags uintptr
+	if v.Readonly {
+		flags |= syscall.MS_RDONLY
+	}
+	if err := d.mounter.Mount(
+		0,
+		v.DevicePath,
+		mountpath,
+		v.Spec.Format.SimpleString(),
+		flags,
+		"",
+		0,
+		options,
+	); err != nil {
+		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
+	}
+	logrus.Infof("Loop mounted %s at %s", v.DevicePath, mountpath)
+
+	if err := d.qos.Apply(v.Id, v.DevicePath, v.Spec.IoThrottle); err != nil {
+		d.mounter.Unmount(v.DevicePath, mountpath, 0, 0, options)
+		return fmt.Errorf("Failed to throttle I/O of volume %v: %v", v.Id, err)
+	}
+	v.AttachPath = d.mounter.Mounts(v.DevicePath)
+	return d.UpdateVol(v)
+}
+
+func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	defer d.stats.Begin("unmount", volumeID)()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) == 0 {
+		return fmt.Errorf("Device %v not mounted", volumeID)
+	}
+	if err := d.mounter.Unmount(v.DevicePath, mountpath, 0, 0, options); err != nil {
+		return err
+	}
+	v.AttachPath = d.mounter.Mounts(v.DevicePath)
+	if len(v.AttachPath) == 0 {
+		if err := d.qos.Remove(v.Id); err != nil {
+			logrus.Warnf("Failed to remove I/O limits of volume %v: %v", v.Id, err)
+		}
+	}
+	return d.UpdateVol(v)
+}
+
+func (d *driver) Snapshot(ctx context.Context, volumeID string, readonly bool, locator *api.VolumeLocator, noRetry bool) (string, error) {
+	defer d.stats.Begin("snapshot", volumeID)()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	source := &api.Source{Parent: v.Id}
+	return d.snapshot(v.Id, strings.TrimSuffix(uuid.New(), "\n"), readonly, locator, source)
+}
+
+// Restore replaces the backing file of the volume with a copy of the
+// snapshot's. The volume must be detached.
+func (d *driver) Restore(volumeID string, snapID string) error {
+	defer d.stats.Begin("restore", volumeID)()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	snap, err := d.GetVol(snapID)
+	if err != nil {
+		return err
+	}
+	if v.DevicePath != "" {
+		return fmt.Errorf("Volume %q must be detached to restore, attached at %q",
+			volumeID, v.DevicePath)
+	}
+
+	tmp := d.backingFile(v.Id) + ".restore"
+	os.Remove(tmp)
+	if err := common.CloneFile(d.backingFile(snap.Id), tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, d.backingFile(v.Id)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	logrus.Infof("Loop restored volume %v from snapshot %v", v.Id, snap.Id)
+	return nil
+}
+
+func (d *driver) SnapshotGroup(groupID string, labels map[string]string, volumeIDs []string, deleteOnFailure bool) (*api.GroupSnapCreateResponse, error) {
+	return common.SnapshotGroup(
+		d,
+		groupID,
+		labels,
+		volumeIDs,
+		deleteOnFailure,
+		func(v *api.Volume) (string, error) {
+			locator := &api.VolumeLocator{
+				Name:         v.GetLocator().GetName() + "-" + groupID + "-snap",
+				VolumeLabels: labels,
+			}
+			return d.Snapshot(correlation.TODO(), v.Id, true, locator, false)
+		},
+		func(snapID string) error {
+			return d.Delete(correlation.TODO(), snapID)
+		},
+	)
+}
+
+// Set updates the locator of the volume, grows it or changes its I/O
+// limits. Attached and mounted volumes are resized and throttled online.
+func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if spec != nil {
+		resize := spec.Size != 0 && spec.Size != v.Spec.Size
+		throttle := !proto.Equal(spec.GetIoThrottle(), v.Spec.GetIoThrottle())
+		if !resize && !throttle {
+			return volume.ErrNotSupported
+		}
+		if resize {
+			if spec.Size < v.Spec.Size {
+				return fmt.Errorf("Cannot shrink volume %v from %v to %v bytes",
+					volumeID, v.Spec.Size, spec.Size)
+			}
+			if err := d.resize(v, spec.Size); err != nil {
+				return err
+			}
+			v.Spec.Size = spec.Size
+		}
+		if throttle {
+			if len(v.AttachPath) > 0 {
+				if err := d.qos.Apply(v.Id, v.DevicePath, spec.IoThrottle); err != nil {
+					return err
+				}
+			}
+			v.Spec.IoThrottle = spec.IoThrottle
+		}
+	}
+	if locator != nil {
+		v.Locator = locator
+	}
+	return d.UpdateVol(v)
+}
+
+// resize grows the backing file, the loop device and the filesystem of the
+// volume to size bytes.
+func (d *driver) resize(v *api.Volume, size uint64) error {
+	switch v.Spec.Format {
+	case api.FSType_FS_TYPE_XFS, api.FSType_FS_TYPE_BTRFS:
+		if len(v.AttachPath) == 0 {
+			return fmt.Errorf("Volume %v must be mounted to grow its %v filesystem",
+				v.Id, v.Spec.Format.SimpleString())
+		}
+	}
+	if err := os.Truncate(d.backingFile(v.Id), int64(size)); err != nil {
+		return err
+	}
+	if v.Spec.Format == api.FSType_FS_TYPE_NONE {
+		if v.DevicePath == "" {
+			return nil
+		}
+		return loopSetCapacity(v.DevicePath)
+	}
+	grow := func(dev string) error {
+		if err := loopSetCapacity(dev); err != nil {
+			return err
+		}
+		mountpath := ""
+		if len(v.AttachPath) > 0 {
+			mountpath = v.AttachPath[0]
+		}
+		return growFilesystem(dev, mountpath, v.Spec.Format)
+	}
+	if v.DevicePath != "" {
+		return grow(v.DevicePath)
+	}
+	return d.withDevice(d.backingFile(v.Id), grow)
+}
+
+func (d *driver) Attach(ctx context.Context, volumeID string, attachOptions map[string]string) (string, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+	if v.DevicePath != "" {
+		return v.DevicePath, nil
+	}
+	dev, err := loopAttach(d.backingFile(v.Id), v.Readonly)
+	if err != nil {
+		return "", err
+	}
+	v.DevicePath = dev
+	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	if err := d.UpdateVol(v); err != nil {
+		loopDetach(dev)
+		return "", err
+	}
+	logrus.Infof("Loop attached volume %v at %v", v.Id, dev)
+	return dev, nil
+}
+
+func (d *driver) Detach(ctx context.Context, volumeID string, options map[string]string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if v.DevicePath == "" {
+		return nil
+	}
+	if len(v.AttachPath) > 0 {
+		return fmt.Errorf("Volume %q is mounted at %q", volumeID, v.AttachPath[0])
+	}
+	if err := loopDetach(v.DevicePath); err != nil {
+		return err
+	}
+	logrus.Infof("Loop detached volume %v from %v", v.Id, v.DevicePath)
+	v.DevicePath = ""
+	v.State = api.VolumeState_VOLUME_STATE_DETACHED
+	return d.UpdateVol(v)
+}
+
+// withDevice runs fn with file attached to a temporary loop device.
+func (d *driver) withDevice(file string, fn func(dev string) error) error {
+	dev, err := loopAttach(file, false)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := loopDetach(dev); err != nil {
+			logrus.Warnf("Failed to detach %v: %v", dev, err)
+		}
+	}()
+	return fn(dev)
+}
+
+// volumeSource locates the data and I/O counters of a volume for the stats
+// collector.
+func (d *driver) volumeSource(v *api.Volume) (*common.VolumeSource, error) {
+	return &common.VolumeSource{
+		Path:   d.backingFile(v.GetId()),
+		Device: v.GetDevicePath(),
+	}, nil
+}
+
+func (d *driver) Stats(ctx context.Context, volumeID string, cumulative bool) (*api.Stats, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	src, err := d.volumeSource(v)
+	if err != nil {
+		return nil, err
+	}
+	stats, err := d.stats.Stats(src)
+	if err != nil {
+		return nil, err
+	}
+	// I/O of throttled volumes is reported as counted against the limits.
+	if err := d.qos.UpdateStats(v.Id, cumulative, stats); err != nil {
+		logrus.Debugf("No throttled I/O statistics for %v: %v", v.Id, err)
+	}
+	return stats, nil
+}
+
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	src, err := d.volumeSource(v)
+	if err != nil {
+		return 0, err
+	}
+	u, err := d.stats.Usage(src)
+	if err != nil {
+		return 0, err
+	}
+	return u.UsedBytes, nil
+}
+
+func (d *driver) CapacityUsage(ID string) (*api.CapacityUsageResponse, error) {
+	v, err := d.GetVol(ID)
+	if err != nil {
+		return nil, err
+	}
+	src, err := d.volumeSource(v)
+	if err != nil {
+		return nil, err
+	}
+	return d.stats.CapacityUsage(src)
+}
+
+// VolumeUsageByNode returns the usage of the volumes attached on this node.
+func (d *driver) VolumeUsageByNode(ctx context.Context, nodeID string) (*api.VolumeUsageByNode, error) {
+	vols, err := d.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	local := make([]*api.Volume, 0, len(vols))
+	for _, v := range vols {
+		if v.GetDevicePath() != "" {
+			local = append(local, v)
+		}
+	}
+	return d.stats.VolumeUsageByNode(local, d.volumeSource)
+}
+
+func (d *driver) GetActiveRequests() (*api.ActiveRequests, error) {
+	return d.stats.ActiveRequests(), nil
+}
+
+func (d *driver) Shutdown() {
+	logrus.Printf("%s Shutting down", Name)
+	d.stopOnce.Do(func() { close(d.stop) })
+}
+
+func (d *driver) Catalog(volumeID, path, depth string) (api.CatalogResponse, error) {
+	return api.CatalogResponse{}, volume.ErrNotSupported
+}
+
+func (d *driver) VolService(volumeID string, vtreq *api.VolumeServiceRequest) (*api.VolumeServiceResponse, error) {
+	return nil, volume.ErrNotSupported
+}
+
+// format creates a filesystem of type fs on dev.
+func format(dev string, fs api.FSType) error {
+	cmd := "mkfs." + fs.SimpleString()
+	args := []string{dev}
+	switch fs {
+	case api.FSType_FS_TYPE_EXT4:
+		args = []string{"-F", dev}
+	case api.FSType_FS_TYPE_XFS, api.FSType_FS_TYPE_BTRFS:
+		args = []string{"-f", dev}
+	}
+	logrus.Infof("Formatting %s with %v", dev, fs)
+	if o, err := exec.Command(cmd, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("Failed to run %v %v: %v: %s", cmd, args, err, o)
+	}
+	return nil
+}
+
+// growFilesystem grows the filesystem on dev to the size of the device. If
+// mountpath is set the filesystem is mounted there and is grown online. XFS
+// and btrfs can only be grown online.
+func growFilesystem(dev, mountpath string, fs api.FSType) error {
+	var cmds [][]string
+	switch fs {
+	case api.FSType_FS_TYPE_EXT4:
+		if mountpath == "" {
+			// resize2fs refuses to grow an unmounted filesystem that has
+			// not been checked since it was last mounted.
+			cmds = append(cmds, []string{"e2fsck", "-f", "-p", dev})
+		}
+		cmds = append(cmds, []string{"resize2fs", dev})
+	case api.FSType_FS_TYPE_XFS:
+		cmds = append(cmds, []string{"xfs_growfs", mountpath})
+	case api.FSType_FS_TYPE_BTRFS:
+		cmds = append(cmds, []string{"btrfs", "filesystem", "resize", "max", mountpath})
+	default:
+		return fmt.Errorf("Cannot grow filesystem of type %v", fs)
+	}
+	for _, c := range cmds {
+		if o, err := exec.Command(c[0], c[1:]...).CombinedOutput(); err != nil {
+			return fmt.Errorf("Failed to run %v: %v: %s", c, err, o)
+		}
+	}
+	return nil
+}
+
+func syncFilesystem(p string) {
+	f, err := os.Open(p)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	unix.Syncfs(int(f.Fd()))
+}
@@ -0,0 +1,85 @@
+package loop
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume/drivers/test"
+)
+
+func skipUnlessLoop(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("Loop devices require root")
+	}
+	if _, err := os.Stat(LoopControlPath); err != nil {
+		t.Skipf("Loop devices are not available: %v", err)
+	}
+}
+
+func TestAll(t *testing.T) {
+	skipUnlessLoop(t)
+	d, err := Init(map[string]string{"path": t.TempDir()})
+	require.NoError(t, err)
+	ctx := test.NewContext(d)
+	ctx.Filesystem = api.FSType_FS_TYPE_EXT4
+
+	test.Run(t, ctx)
+}
+
+func TestResize(t *testing.T) {
+	skipUnlessLoop(t)
+	di, err := Init(map[string]string{"path": t.TempDir()})
+	require.NoError(t, err)
+	d := di.(*driver)
+	defer d.Shutdown()
+
+	const size = 64 * 1024 * 1024
+	id, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "resize"}, nil,
+		&api.VolumeSpec{Size: size, Format: api.FSType_FS_TYPE_EXT4})
+	require.NoError(t, err)
+	defer d.Delete(context.TODO(), id)
+
+	dev, err := d.Attach(context.TODO(), id, nil)
+	require.NoError(t, err)
+	assert.Equal(t, d.backingFile(id), loopBackingFile(dev))
+	defer d.Detach(context.TODO(), id, nil)
+
+	// Grow the attached volume, the device and filesystem follow.
+	require.NoError(t, d.Set(context.TODO(), id, nil, &api.VolumeSpec{Size: 2 * size}))
+	assert.Error(t, d.Set(context.TODO(), id, nil, &api.VolumeSpec{Size: size}))
+
+	f, err := os.Open(dev)
+	require.NoError(t, err)
+	devSize, err := unix.IoctlGetInt(int(f.Fd()), unix.BLKGETSIZE64)
+	f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, 2*size, devSize)
+
+	// The mount manager marks mount points immutable, so use a path that
+	// outlives the test like the common driver tests do.
+	mountpath := "/mnt/openstorage/mount/loop-resize"
+	require.NoError(t, os.MkdirAll(mountpath, 0755))
+	require.NoError(t, d.Mount(context.TODO(), id, mountpath, nil))
+	defer d.Unmount(context.TODO(), id, mountpath, nil)
+
+	var st unix.Statfs_t
+	require.NoError(t, unix.Statfs(mountpath, &st))
+	assert.True(t, st.Blocks*uint64(st.Bsize) > size,
+		"expected filesystem larger than %v bytes, got %v", size, st.Blocks*uint64(st.Bsize))
+
+	vols, err := d.Inspect(context.TODO(), []string{id})
+	require.NoError(t, err)
+	require.Len(t, vols, 1)
+	assert.Equal(t, uint64(2*size), vols[0].Spec.Size)
+	assert.Equal(t, []string{mountpath}, vols[0].AttachPath)
+
+	// A mounted volume cannot be detached or deleted.
+	assert.Error(t, d.Detach(context.TODO(), id, nil))
+	assert.Error(t, d.Delete(context.TODO(), id))
+}
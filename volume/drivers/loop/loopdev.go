@@ -0,0 +1,126 @@
+package loop
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// LoopControlPath is the control device used to find free loop devices.
+	LoopControlPath = "/dev/loop-control"
+	// sysBlockPath is where the kernel reports the backing file of a loop
+	// device.
+	sysBlockPath = "/sys/block"
+	// loFlagsReadOnly is LO_FLAGS_READ_ONLY from linux/loop.h.
+	loFlagsReadOnly = 1
+)
+
+// loopAttach binds the file at backingFile to a free loop device and returns
+// the path of the device.
+func loopAttach(backingFile string, readonly bool) (string, error) {
+	flags := os.O_RDWR
+	if readonly {
+		flags = os.O_RDONLY
+	}
+	back, err := os.OpenFile(backingFile, flags, 0)
+	if err != nil {
+		return "", err
+	}
+	defer back.Close()
+
+	ctrl, err := os.OpenFile(LoopControlPath, os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("Could not open %v: %v", LoopControlPath, err)
+	}
+	defer ctrl.Close()
+
+	// Another process may grab the device returned by LOOP_CTL_GET_FREE
+	// before we bind it, in which case LOOP_SET_FD fails with EBUSY.
+	const retries = 8
+	for i := 0; i < retries; i++ {
+		n, _, errno := unix.Syscall(unix.SYS_IOCTL, ctrl.Fd(), unix.LOOP_CTL_GET_FREE, 0)
+		if errno != 0 {
+			return "", fmt.Errorf("Could not get a free loop device: %v", errno)
+		}
+		dev := fmt.Sprintf("/dev/loop%d", n)
+		err := loopSetFd(dev, back, flags, backingFile, readonly)
+		if err == unix.EBUSY {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("Could not attach %v to %v: %v", backingFile, dev, err)
+		}
+		return dev, nil
+	}
+	return "", fmt.Errorf("Could not attach %v: all free loop devices were busy", backingFile)
+}
+
+func loopSetFd(dev string, back *os.File, flags int, backingFile string, readonly bool) error {
+	loopFile, err := os.OpenFile(dev, flags, 0)
+	if err != nil {
+		return err
+	}
+	defer loopFile.Close()
+
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, loopFile.Fd(), unix.LOOP_SET_FD, back.Fd()); errno != 0 {
+		return errno
+	}
+	info := unix.LoopInfo64{}
+	copy(info.File_name[:len(info.File_name)-1], backingFile)
+	if readonly {
+		info.Flags |= loFlagsReadOnly
+	}
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, loopFile.Fd(), unix.LOOP_SET_STATUS64,
+		uintptr(unsafe.Pointer(&info))); errno != 0 {
+		unix.Syscall(unix.SYS_IOCTL, loopFile.Fd(), unix.LOOP_CLR_FD, 0)
+		return errno
+	}
+	return nil
+}
+
+// loopDetach unbinds the loop device at dev from its backing file. The kernel
+// completes the detach once the last opener closes the device.
+func loopDetach(dev string) error {
+	loopFile, err := os.OpenFile(dev, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer loopFile.Close()
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, loopFile.Fd(), unix.LOOP_CLR_FD, 0)
+	if errno != 0 && errno != unix.ENXIO {
+		return fmt.Errorf("Could not detach %v: %v", dev, errno)
+	}
+	return nil
+}
+
+// loopSetCapacity makes the loop device at dev pick up the new size of its
+// backing file.
+func loopSetCapacity(dev string) error {
+	loopFile, err := os.OpenFile(dev, os.O_RDONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer loopFile.Close()
+
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, loopFile.Fd(), unix.LOOP_SET_CAPACITY, 0)
+	if errno != 0 {
+		return fmt.Errorf("Could not set capacity of %v: %v", dev, errno)
+	}
+	return nil
+}
+
+// loopBackingFile returns the file the loop device at dev is bound to, or an
+// empty string if it is not bound.
+func loopBackingFile(dev string) string {
+	data, err := os.ReadFile(filepath.Join(sysBlockPath, filepath.Base(dev), "loop", "backing_file"))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
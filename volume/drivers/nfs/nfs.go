@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"os/exec"
 	"path"
@@ -13,6 +12,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/unix"
 	losetup "gopkg.in/freddierice/go-losetup.v1"
@@ -22,7 +22,7 @@ import (
 
 	"github.com/libopenstorage/openstorage/api"
 	"github.com/libopenstorage/openstorage/config"
-	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/pkg/job"
 	"github.com/libopenstorage/openstorage/pkg/mount"
 	"github.com/libopenstorage/openstorage/pkg/seed"
 	"github.com/libopenstorage/openstorage/pkg/util"
@@ -57,6 +57,12 @@ type driver struct {
 	nfsServers []string
 	nfsPath    string
 	mounter    mount.Manager
+	backends   map[string]snapshotBackend
+	copies     copyJobs
+	jobs       *job.KvdbProvider
+	nodeID     string
+	stats      *common.StatsCollector
+	stop       chan struct{}
 }
 
 func Init(params map[string]string) (volume.VolumeDriver, error) {
@@ -99,6 +105,10 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 		FilesystemTrimDriver:  volume.FilesystemTrimNotSupported,
 		FilesystemCheckDriver: volume.FilesystemCheckNotSupported,
 		VerifyChecksumDriver:  volume.VerifyChecksumNotSupported,
+		backends:              make(map[string]snapshotBackend),
+		nodeID:                hostname(),
+		stats:                 common.NewStatsCollector(),
+		stop:                  make(chan struct{}),
 	}
 
 	//make directory for each nfs server
@@ -170,6 +180,7 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 		} else {
 			logrus.Infof("NFS: %s already mounted", nfsMountPath+v)
 		}
+		inst.backends[v] = newSnapshotBackend(nfsMountPath + v)
 	}
 
 	volumeInfo, err := inst.StoreEnumerator.Enumerate(&api.VolumeLocator{}, nil)
@@ -182,10 +193,37 @@ func Init(params map[string]string) (volume.VolumeDriver, error) {
 		}
 	}
 
+	go inst.stats.RefreshUsage(inst.StoreEnumerator, inst.volumeSource, common.UsageRefreshInterval, inst.stop)
+
 	logrus.Println("NFS initialized and driver mounted at: ", nfsMountPath)
 	return inst, nil
 }
 
+func hostname() string {
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// SetJobProvider runs the background copies as VOLUME_COPY jobs of the job
+// provider of the cluster, so that they survive restarts, and attaches the
+// volumes with the id of this node in the cluster instead of its hostname.
+// Without it, the volumes of the backends which copy in the background
+// cannot be cloned.
+func (d *driver) SetJobProvider(p *job.KvdbProvider) error {
+	if err := p.RegisterRunner(api.Job_VOLUME_COPY, &copyRunner{d: d}); err != nil {
+		return err
+	}
+	d.jobs = p
+	d.nodeID = p.NodeID()
+
+	vols, err := d.StoreEnumerator.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return err
+	}
+	d.recoverCopies(vols)
+	return nil
+}
+
 func (d *driver) StartVolumeWatcher() {
 	return
 }
@@ -215,7 +253,21 @@ func (d *driver) Version() (*api.StorageVersion, error) {
 
 // Status diagnostic information
 func (d *driver) Status() [][2]string {
-	return [][2]string{}
+	status := make([][2]string, 0, len(d.backends))
+	for _, server := range d.nfsServers {
+		if b, ok := d.backends[server]; ok {
+			status = append(status, [2]string{"Snapshot backend " + server, b.Name()})
+		}
+	}
+	return append(status, d.copies.status()...)
+}
+
+// getBackend returns the snapshot backend of the server the volume is on.
+func (d *driver) getBackend(v *api.Volume) snapshotBackend {
+	if b, ok := d.backends[v.GetLocator().GetVolumeLabels()["server"]]; ok {
+		return b
+	}
+	return streamBackend{}
 }
 
 // Utility functions
@@ -367,10 +419,15 @@ func (d *driver) Create(
 		// Check for cloning
 		if source != nil && len(source.GetParent()) != 0 {
 			// Need to clone
-			if err := d.clone(volumeID, source.GetParent()); err != nil {
+			pending, err := d.clone(volumeID, source.GetParent())
+			if err != nil {
 				d.Delete(ctx, v.GetId())
 				return "", err
 			}
+			if pending {
+				// The copy sets the volume to ready when it completes.
+				return v.Id, nil
+			}
 		}
 
 		// Set to ready
@@ -402,10 +459,15 @@ func (d *driver) Create(
 		// Check if this from as a clone
 		if source != nil && len(source.GetParent()) != 0 {
 			// Need to clone
-			if err := d.clone(volumeID, source.GetParent()); err != nil {
+			pending, err := d.clone(volumeID, source.GetParent())
+			if err != nil {
 				d.Delete(ctx, v.GetId())
 				return "", err
 			}
+			if pending {
+				// The copy sets the volume to ready when it completes.
+				return v.Id, nil
+			}
 		} else {
 			// This is a new volume
 			blockFile := path.Join(volPathParent, volumeID+nfsBlockFile)
@@ -478,6 +540,9 @@ func (d *driver) Delete(ctx context.Context, volumeID string) (e error) {
 	if v.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED {
 		return fmt.Errorf("Volume is still attached and cannot be deleted")
 	}
+	if v.GetState() == api.VolumeState_VOLUME_STATE_PENDING && d.copyActive(v) {
+		return fmt.Errorf("Volume is still being copied from its parent and cannot be deleted")
+	}
 
 	nfsVolPath, err := d.getNFSVolumePath(v)
 	if err != nil {
@@ -492,7 +557,7 @@ func (d *driver) Delete(ctx context.Context, volumeID string) (e error) {
 		}
 	} else {
 		// Delete the block device
-		if err := os.Remove(nfsVolPath + nfsBlockFile); err != nil {
+		if err := os.Remove(nfsVolPath + nfsBlockFile); err != nil && !os.IsNotExist(err) {
 			return fmt.Errorf("Failed to remove %s: %v", nfsVolPath+nfsBlockFile, err)
 		}
 	}
@@ -511,6 +576,7 @@ func (d *driver) MountedAt(ctx context.Context, mountpath string) string {
 }
 
 func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	defer d.stats.Begin("mount", volumeID)()
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		logrus.Println(err)
@@ -569,11 +635,13 @@ func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, o
 		v.AttachPath = make([]string, 0)
 	}
 	v.AttachPath = append(v.AttachPath, mountpath)
+	v.AttachedOn = d.nodeID
 	return d.UpdateVol(v)
 
 }
 
 func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	defer d.stats.Begin("unmount", volumeID)()
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
@@ -593,6 +661,9 @@ func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string,
 			return err
 		}
 		v.AttachPath = d.mounter.Mounts(nfsVolPath)
+		if len(v.AttachPath) == 0 {
+			v.AttachedOn = ""
+		}
 	} else {
 		if err := syscall.Unmount(mountpath, 0); err != nil {
 			return err
@@ -602,109 +673,137 @@ func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string,
 	return d.UpdateVol(v)
 }
 
-func (d *driver) clone(newVolumeID, volumeID string) error {
-	nfsVolPath, err := d.getNFSVolumePathById(volumeID)
+// clone copies the data of volumeID into newVolumeID using the snapshot
+// backend of its server. It returns true if the copy continues in the
+// background, in which case the new volume stays pending until it completes.
+func (d *driver) clone(newVolumeID, volumeID string) (bool, error) {
+	parent, err := d.GetVol(volumeID)
 	if err != nil {
-		return err
+		return false, err
 	}
-
-	newNfsVolPath, err := d.getNFSVolumePathById(newVolumeID)
+	if err := d.checkCopied(parent); err != nil {
+		return false, err
+	}
+	v, err := d.GetVol(newVolumeID)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	v, err := d.GetVol(volumeID)
+	src, dst, isDir, err := d.copyPaths(parent, v)
 	if err != nil {
-		return err
+		return false, err
+	}
+	if isDir {
+		// The backend creates the directory.
+		if err := os.Remove(dst); err != nil && !os.IsNotExist(err) {
+			return false, err
+		}
 	}
 
-	// NFS does not support snapshots, so just copy the files.
+	backend := d.getBackend(parent)
+	// A background copy would include some of the writes made while it
+	// runs, so the snapshot would not be of a point in time.
+	if backend.Async() &&
+		(parent.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED || len(parent.GetAttachPath()) != 0) {
+		return false, fmt.Errorf("Volume %s must be detached to be copied, since the %s "+
+			"backend of its server copies in the background", volumeID, backend.Name())
+	}
+	if err := d.copyVolumeData(newVolumeID, volumeID, src, dst, isDir, backend); err != nil {
+		logrus.Errorf("Failed to clone %s to %s: %v", src, dst, err)
+		return false, fmt.Errorf("Failed to clone %s to %s: %v", src, dst, err)
+	}
+	logrus.Infof("Cloning %s to %s using %s backend", src, dst, backend.Name())
+	return backend.Async(), nil
+}
 
-	if d.isShared(v.GetSpec()) {
-		// Copy directory
-		if err := copyDir(nfsVolPath, newNfsVolPath); err != nil {
-			d.Delete(context.Background(), newVolumeID)
-			return err
-		}
-	} else {
-		origBlockPath := nfsVolPath + nfsBlockFile
-		cloneBlockPath := newNfsVolPath + nfsBlockFile
-		// Copy the block file. Could take a while so try to optimize it.
-		_, err = exec.Command(
-			"/bin/cp",
-			"--reflink=always",
-			origBlockPath,
-			cloneBlockPath,
-		).Output()
-		if err == nil {
-			logrus.Infof("Cloned %s to %s using reflink copy",
-				origBlockPath,
-				cloneBlockPath)
-		} else {
-			// Second try sparse copy
-			_, err = exec.Command(
-				"/bin/cp",
-				"--sparse=always",
-				origBlockPath,
-				cloneBlockPath,
-			).Output()
-			if err == nil {
-				logrus.Infof("Cloned %s to %s using sparse copy",
-					origBlockPath,
-					cloneBlockPath)
-			} else {
-				// slow copy
-				if err := copyFile(origBlockPath, cloneBlockPath); err == nil {
-					logrus.Infof("Cloned %s to %s using slow copy",
-						origBlockPath,
-						cloneBlockPath)
-				} else {
-					logrus.Errorf("Failed to clone %s to %s: %v",
-						origBlockPath,
-						cloneBlockPath,
-						err)
-					return fmt.Errorf("Failed to clone %s to %s: %v",
-						origBlockPath,
-						cloneBlockPath,
-						err)
-				}
-			}
-		}
+// copyPaths returns the paths the data of parent is copied from and into v.
+// The data of shared volumes is a directory tree, that of block volumes a
+// file.
+func (d *driver) copyPaths(parent, v *api.Volume) (string, string, bool, error) {
+	src, err := d.getNFSVolumePath(parent)
+	if err != nil {
+		return "", "", false, err
 	}
-	return nil
+	dst, err := d.getNFSVolumePath(v)
+	if err != nil {
+		return "", "", false, err
+	}
+	if d.isShared(parent.GetSpec()) {
+		return src, dst, true, nil
+	}
+	return src + nfsBlockFile, dst + nfsBlockFile, false, nil
 }
 
 func (d *driver) Snapshot(ctx context.Context, volumeID string, readonly bool, locator *api.VolumeLocator, noRetry bool) (string, error) {
-	volIDs := []string{volumeID}
-	vols, err := d.Inspect(nil, volIDs)
+	defer d.stats.Begin("snapshot", volumeID)()
+	v, err := d.GetVol(volumeID)
 	if err != nil {
-		return "", nil
+		return "", err
+	}
+	// Snapshots are kept on the parent's server so they can share extents.
+	locator = proto.Clone(locator).(*api.VolumeLocator)
+	if locator.VolumeLabels == nil {
+		locator.VolumeLabels = make(map[string]string)
 	}
-	source := &api.Source{Parent: volumeID}
+	locator.VolumeLabels["server"] = v.GetLocator().GetVolumeLabels()["server"]
+
+	source := &api.Source{Parent: v.Id}
 	logrus.Infof("Creating snap vol name: %s", locator.Name)
-	return d.Create(context.TODO(), locator, source, vols[0].Spec)
+	return d.Create(context.TODO(), locator, source, v.Spec)
 }
 
 func (d *driver) Restore(volumeID string, snapID string) error {
-	if _, err := d.Inspect(correlation.TODO(), []string{volumeID, snapID}); err != nil {
+	defer d.stats.Begin("restore", volumeID)()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
 		return err
 	}
+	snap, err := d.GetVol(snapID)
+	if err != nil {
+		return err
+	}
+	if err := d.checkCopied(snap); err != nil {
+		return err
+	}
+	if v.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED || len(v.GetAttachPath()) != 0 {
+		return fmt.Errorf("Volume %s must be detached to restore", volumeID)
+	}
 
-	nfsVolPath, err := d.getNFSVolumePathById(volumeID)
+	nfsVolPath, err := d.getNFSVolumePath(v)
 	if err != nil {
 		return err
 	}
 
-	snapNfsVolPath, err := d.getNFSVolumePathById(snapID)
+	snapNfsVolPath, err := d.getNFSVolumePath(snap)
 	if err != nil {
 		return err
 	}
 
-	// NFS does not support restore, so just copy the files.
-	if err := copyDir(snapNfsVolPath, nfsVolPath); err != nil {
+	// Copy into a scratch location and swap it in, so that a failed
+	// restore leaves the volume untouched. Restores always run in the
+	// foreground since the volume must be usable once they return.
+	src, dst := snapNfsVolPath+nfsBlockFile, nfsVolPath+nfsBlockFile
+	isDir := d.isShared(v.GetSpec())
+	if isDir {
+		src, dst = snapNfsVolPath, nfsVolPath
+	}
+	tmp, old := dst+".restore", dst+".old"
+	os.RemoveAll(tmp)
+	os.RemoveAll(old)
+	if err := d.getBackend(snap).Clone(src, tmp, isDir, nil); err != nil {
+		os.RemoveAll(tmp)
 		return err
 	}
-	return nil
+	if err := os.Rename(dst, old); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, dst); err != nil {
+		os.Rename(old, dst)
+		os.RemoveAll(tmp)
+		return err
+	}
+	return os.RemoveAll(old)
 }
 
 func (d *driver) SnapshotGroup(groupID string, labels map[string]string, volumeIDs []string, deleteOnFailure bool) (*api.GroupSnapCreateResponse, error) {
@@ -726,6 +825,10 @@ func (d *driver) Attach(ctx context.Context, volumeID string, attachOptions map[
 		return "", err
 	}
 
+	if err := d.checkCopied(v); err != nil {
+		return "", err
+	}
+
 	// If it has no size, no need to attach
 	if d.isShared(v.GetSpec()) {
 		return nfsPath, nil
@@ -743,6 +846,7 @@ func (d *driver) Attach(ctx context.Context, volumeID string, attachOptions map[
 	// Update volume info
 	v.DevicePath = dev.Path()
 	v.State = api.VolumeState_VOLUME_STATE_ATTACHED
+	v.AttachedOn = d.nodeID
 	if err := d.UpdateVol(v); err != nil {
 		dev.Detach()
 		return "", err
@@ -782,6 +886,7 @@ func (d *driver) Detach(ctx context.Context, volumeID string, options map[string
 	// Update volume info
 	v.DevicePath = ""
 	v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+	v.AttachedOn = ""
 	if err := d.UpdateVol(v); err != nil {
 		return err
 	}
@@ -805,6 +910,7 @@ func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLo
 
 func (d *driver) Shutdown() {
 	logrus.Printf("%s Shutting down", Name)
+	close(d.stop)
 
 	for _, v := range d.nfsServers {
 		logrus.Infof("Umounting: %s", nfsMountPath+v)
@@ -812,73 +918,93 @@ func (d *driver) Shutdown() {
 	}
 }
 
-func copyFile(source string, dest string) (err error) {
-	sourcefile, err := os.Open(source)
+// volumeSource locates the data and I/O counters of a volume for the stats
+// collector. Attached block volumes report the counters of their loop
+// device, other volumes those of the NFS mount of their server.
+func (d *driver) volumeSource(v *api.Volume) (*common.VolumeSource, error) {
+	nfsPath, err := d.getNFSPath(v)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	defer sourcefile.Close()
-
-	destfile, err := os.Create(dest)
-	if err != nil {
-		return err
+	src := &common.VolumeSource{
+		Path:      path.Join(nfsPath, v.GetId()),
+		MountPath: nfsPath,
 	}
-
-	defer destfile.Close()
-
-	_, err = io.Copy(destfile, sourcefile)
-	if err == nil {
-		sourceinfo, err := os.Stat(source)
-		if err != nil {
-			err = os.Chmod(dest, sourceinfo.Mode())
+	if !d.isShared(v.GetSpec()) {
+		src.Path += nfsBlockFile
+		if v.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED && v.GetDevicePath() != "" {
+			src.MountPath = ""
+			src.Device = v.GetDevicePath()
 		}
-
 	}
-
-	return
+	return src, nil
 }
 
-func copyDir(source string, dest string) (err error) {
-	// get properties of source dir
-	sourceinfo, err := os.Stat(source)
+func (d *driver) Stats(ctx context.Context, volumeID string, cumulative bool) (*api.Stats, error) {
+	v, err := d.GetVol(volumeID)
 	if err != nil {
-		return err
+		return nil, err
 	}
-
-	// create dest dir
-
-	err = os.MkdirAll(dest, sourceinfo.Mode())
+	src, err := d.volumeSource(v)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return d.stats.Stats(src)
+}
 
-	directory, _ := os.Open(source)
-
-	objects, err := directory.Readdir(-1)
-
-	for _, obj := range objects {
-
-		sourcefilepointer := source + "/" + obj.Name()
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	src, err := d.volumeSource(v)
+	if err != nil {
+		return 0, err
+	}
+	u, err := d.stats.Usage(src)
+	if err != nil {
+		return 0, err
+	}
+	return u.UsedBytes, nil
+}
 
-		destinationfilepointer := dest + "/" + obj.Name()
+func (d *driver) CapacityUsage(ID string) (*api.CapacityUsageResponse, error) {
+	v, err := d.GetVol(ID)
+	if err != nil {
+		return nil, err
+	}
+	src, err := d.volumeSource(v)
+	if err != nil {
+		return nil, err
+	}
+	return d.stats.CapacityUsage(src)
+}
 
-		if obj.IsDir() {
-			// create sub-directories - recursively
-			err = copyDir(sourcefilepointer, destinationfilepointer)
-			if err != nil {
-				fmt.Println(err)
-			}
-		} else {
-			// perform copy
-			err = copyFile(sourcefilepointer, destinationfilepointer)
-			if err != nil {
-				fmt.Println(err)
-			}
+// VolumeUsageByNode returns the usage of the volumes attached or mounted on
+// the node, or on this node if nodeID is empty. The data of every volume is
+// on the NFS servers, so the usage of any node's volumes can be computed
+// here. A shared volume mounted on several nodes is counted on the node
+// which mounted it last.
+func (d *driver) VolumeUsageByNode(ctx context.Context, nodeID string) (*api.VolumeUsageByNode, error) {
+	if len(nodeID) == 0 {
+		nodeID = d.nodeID
+	}
+	vols, err := d.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	onNode := make([]*api.Volume, 0, len(vols))
+	for _, v := range vols {
+		if v.GetAttachedOn() == nodeID &&
+			(v.GetState() == api.VolumeState_VOLUME_STATE_ATTACHED || len(v.GetAttachPath()) != 0) {
+			onNode = append(onNode, v)
 		}
-
 	}
-	return
+	return d.stats.VolumeUsageByNode(onNode, d.volumeSource)
+}
+
+func (d *driver) GetActiveRequests() (*api.ActiveRequests, error) {
+	return d.stats.ActiveRequests(), nil
 }
 
 func (d *driver) Catalog(volumeID, path, depth string) (api.CatalogResponse, error) {
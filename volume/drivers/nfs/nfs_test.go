@@ -1,11 +1,20 @@
 package nfs
 
 import (
+	"context"
 	"os"
+	"path"
 	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/chattr"
+	"github.com/libopenstorage/openstorage/pkg/job"
 	"github.com/libopenstorage/openstorage/volume/drivers/test"
+	"github.com/portworx/kvdb"
 )
 
 var (
@@ -28,3 +37,156 @@ func TestAll(t *testing.T) {
 
 	test.RunShort(t, ctx)
 }
+
+func TestStreamBackend(t *testing.T) {
+	dir := t.TempDir()
+	src := path.Join(dir, "src"+nfsBlockFile)
+	dst := path.Join(dir, "dst"+nfsBlockFile)
+
+	// A sparse block file with data at both ends.
+	f, err := os.Create(src)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(64*1024*1024))
+	_, err = f.WriteAt([]byte("head"), 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte("tail"), 64*1024*1024-4)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	j := &copyJob{volumeID: "dst", sourceID: "src", total: 8}
+	require.NoError(t, streamBackend{}.Clone(src, dst, false, j.progress))
+	assert.True(t, j.copied >= 8)
+
+	data, err := os.ReadFile(dst)
+	require.NoError(t, err)
+	assert.Len(t, data, 64*1024*1024)
+	assert.Equal(t, "head", string(data[:4]))
+	assert.Equal(t, "tail", string(data[len(data)-4:]))
+
+	v := &api.Volume{}
+	setCopyState(v, j, copyStateDone, nil)
+	require.Len(t, v.RuntimeState, 1)
+	assert.Equal(t, copyStateDone, v.RuntimeState[0].RuntimeState[copyStateKey])
+	assert.Equal(t, "100", v.RuntimeState[0].RuntimeState[copyPercentKey])
+	assert.Equal(t, "src", v.RuntimeState[0].RuntimeState[copySourceKey])
+}
+
+func TestBackgroundCopy(t *testing.T) {
+	require.NoError(t, os.MkdirAll(testPath, 0744))
+	vd, err := Init(map[string]string{"path": testPath})
+	require.NoError(t, err)
+	d := vd.(*driver)
+	for server := range d.backends {
+		d.backends[server] = streamBackend{}
+	}
+
+	id, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "copy-parent"}, nil,
+		&api.VolumeSpec{Size: 1024 * 1024, Format: api.FSType_FS_TYPE_NONE})
+	require.NoError(t, err)
+	defer d.Delete(context.TODO(), id)
+	parentPath, err := d.getNFSVolumePathById(id)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(parentPath+nfsBlockFile, []byte("data"), 0644))
+
+	// Background copies need the job provider of the cluster
+	_, err = d.Snapshot(context.TODO(), id, true, &api.VolumeLocator{Name: "copy-nojobs"}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "job provider of the cluster")
+
+	jobs, err := job.NewKvdbProvider(kvdb.Instance(), "node1")
+	require.NoError(t, err)
+	require.NoError(t, d.SetJobProvider(jobs))
+	assert.Equal(t, "node1", d.nodeID)
+	require.NoError(t, jobs.Start())
+	defer jobs.Stop()
+
+	// The copy runs as a job, and the locator of the caller is not changed
+	labels := map[string]string{"app": "db"}
+	snapID, err := d.Snapshot(context.TODO(), id, true,
+		&api.VolumeLocator{Name: "copy-snap", VolumeLabels: labels}, false)
+	require.NoError(t, err)
+	defer d.Delete(context.TODO(), snapID)
+	assert.Equal(t, map[string]string{"app": "db"}, labels)
+
+	require.Eventually(t, func() bool {
+		v, err := d.GetVol(snapID)
+		return err == nil && v.GetState() == api.VolumeState_VOLUME_STATE_AVAILABLE
+	}, 10*time.Second, 10*time.Millisecond)
+	snapPath, err := d.getNFSVolumePathById(snapID)
+	require.NoError(t, err)
+	data, err := os.ReadFile(snapPath + nfsBlockFile)
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	require.Eventually(t, func() bool {
+		resp, err := d.jobs.GetJobStatus(context.TODO(), &api.SdkGetJobStatusRequest{Id: copyJobID(snapID)})
+		return err == nil && resp.GetJob().GetState() == api.Job_DONE
+	}, 10*time.Second, 10*time.Millisecond)
+	resp, err := d.jobs.GetJobStatus(context.TODO(), &api.SdkGetJobStatusRequest{Id: copyJobID(snapID)})
+	require.NoError(t, err)
+	assert.Equal(t, api.Job_VOLUME_COPY, resp.GetJob().GetType())
+	assert.Equal(t, id, resp.GetJob().GetVolumeCopy().GetParentId())
+	assert.Equal(t, snapID, resp.GetJob().GetVolumeCopy().GetVolumeId())
+
+	// Attached parents could be written while they are copied
+	parent, err := d.GetVol(id)
+	require.NoError(t, err)
+	parent.AttachPath = []string{"/mnt/copy-parent"}
+	require.NoError(t, d.UpdateVol(parent))
+	_, err = d.Snapshot(context.TODO(), id, true, &api.VolumeLocator{Name: "copy-attached"}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "must be detached")
+	parent.AttachPath = nil
+	require.NoError(t, d.UpdateVol(parent))
+
+	// A volume left pending by a copy without a job, such as after a crash
+	// before the job was saved, is failed
+	orphan := &api.Volume{
+		Id:      "copy-orphan",
+		Locator: &api.VolumeLocator{Name: "copy-orphan"},
+		State:   api.VolumeState_VOLUME_STATE_PENDING,
+	}
+	setCopyState(orphan, &copyJob{volumeID: orphan.Id, sourceID: id}, copyStateRunning, nil)
+	require.NoError(t, d.CreateVol(orphan))
+	defer d.DeleteVol(orphan.Id)
+	d.recoverCopies([]*api.Volume{orphan})
+	v, err := d.GetVol(orphan.Id)
+	require.NoError(t, err)
+	assert.Equal(t, api.VolumeState_VOLUME_STATE_ERROR, v.GetState())
+	assert.Error(t, d.checkCopied(v))
+}
+
+func TestVolumeUsageByNode(t *testing.T) {
+	require.NoError(t, os.MkdirAll(testPath, 0744))
+	vd, err := Init(map[string]string{"path": testPath})
+	require.NoError(t, err)
+	d := vd.(*driver)
+
+	id, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "usage-by-node"}, nil,
+		&api.VolumeSpec{Shared: true})
+	require.NoError(t, err)
+	defer d.Delete(context.TODO(), id)
+
+	usage, err := d.VolumeUsageByNode(context.TODO(), "")
+	require.NoError(t, err)
+	assert.Empty(t, usage.GetVolumeUsage())
+
+	// The mount path is left immutable after the unmount
+	mountpath := path.Join(t.TempDir(), "mnt")
+	require.NoError(t, os.Mkdir(mountpath, 0755))
+	defer chattr.RemoveImmutable(mountpath)
+	require.NoError(t, d.Mount(context.TODO(), id, mountpath, nil))
+	defer d.Unmount(context.TODO(), id, mountpath, nil)
+
+	// Volumes mounted on other nodes are not counted
+	usage, err = d.VolumeUsageByNode(context.TODO(), "")
+	require.NoError(t, err)
+	require.Len(t, usage.GetVolumeUsage(), 1)
+	assert.Equal(t, id, usage.GetVolumeUsage()[0].GetVolumeId())
+	usage, err = d.VolumeUsageByNode(context.TODO(), d.nodeID)
+	require.NoError(t, err)
+	assert.Len(t, usage.GetVolumeUsage(), 1)
+	usage, err = d.VolumeUsageByNode(context.TODO(), "other-node")
+	require.NoError(t, err)
+	assert.Empty(t, usage.GetVolumeUsage())
+}
@@ -0,0 +1,406 @@
+package nfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/job"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+)
+
+const (
+	// Keys of the runtime state published on a volume while its data is
+	// being copied from its parent.
+	copyStateKey      = "CopyState"
+	copySourceKey     = "CopySource"
+	copyBytesKey      = "CopyBytes"
+	copyTotalBytesKey = "CopyTotalBytes"
+	copyPercentKey    = "CopyPercent"
+	copyErrorKey      = "CopyError"
+	copyJobKey        = "CopyJob"
+
+	copyStateRunning = "running"
+	copyStateDone    = "done"
+	copyStateFailed  = "failed"
+
+	// copyProgressInterval is how often the progress of a copy is published.
+	copyProgressInterval = 5 * time.Second
+)
+
+// snapshotBackend copies the data of a volume into a new volume on the same
+// NFS export.
+type snapshotBackend interface {
+	// Name of the backend.
+	Name() string
+	// Async returns true if Clone is slow enough that it should run in the
+	// background while the new volume is reported as pending.
+	Async() bool
+	// Clone copies the file or directory tree at src to dst, which must not
+	// exist. progress, if set, is called with the number of bytes copied,
+	// and the copy stops with its error if it returns one.
+	Clone(src, dst string, isDir bool, progress func(int64) error) error
+}
+
+// reflinkBackend shares extents with the parent through FICLONE, which NFS
+// v4.2 forwards to the server as a CLONE operation. Clones are instant and
+// use no space until either side is modified.
+type reflinkBackend struct{}
+
+func (reflinkBackend) Name() string {
+	return "reflink"
+}
+
+func (reflinkBackend) Async() bool {
+	return false
+}
+
+func (reflinkBackend) Clone(src, dst string, isDir bool, progress func(int64) error) error {
+	if isDir {
+		return common.CloneTree(src, dst, false)
+	}
+	return common.CloneFile(src, dst)
+}
+
+// streamBackend copies the data, using copy_file_range so that NFS v4.2
+// servers can copy server side, and preserving holes.
+type streamBackend struct{}
+
+func (streamBackend) Name() string {
+	return "stream"
+}
+
+func (streamBackend) Async() bool {
+	return true
+}
+
+func (streamBackend) Clone(src, dst string, isDir bool, progress func(int64) error) error {
+	if isDir {
+		return common.CopyTree(src, dst, progress)
+	}
+	return common.CopyFile(src, dst, progress)
+}
+
+// newSnapshotBackend picks the backend for the export mounted at dir.
+func newSnapshotBackend(dir string) snapshotBackend {
+	if err := common.Reflink(dir); err != nil {
+		logrus.Infof("NFS export at %s does not support reflinks (%v), "+
+			"snapshots will be copied in the background", dir, err)
+		return streamBackend{}
+	}
+	logrus.Infof("NFS export at %s supports reflinks", dir)
+	return reflinkBackend{}
+}
+
+// copyJob tracks the copy of a parent volume's data into a new volume on
+// this node.
+type copyJob struct {
+	volumeID string
+	sourceID string
+	total    uint64
+	copied   uint64
+	// ctx, if set, stops the copy when it is done.
+	ctx context.Context
+}
+
+func (j *copyJob) progress(n int64) error {
+	atomic.AddUint64(&j.copied, uint64(n))
+	if j.ctx != nil {
+		return j.ctx.Err()
+	}
+	return nil
+}
+
+func (j *copyJob) percent() uint64 {
+	if j.total == 0 {
+		return 0
+	}
+	p := atomic.LoadUint64(&j.copied) * 100 / j.total
+	if p > 99 {
+		// Estimates are based on allocated space, only done is 100%.
+		p = 99
+	}
+	return p
+}
+
+func (j *copyJob) String() string {
+	return fmt.Sprintf("%v of %v bytes", atomic.LoadUint64(&j.copied), j.total)
+}
+
+// copyJobs are the copies currently running on this node.
+type copyJobs struct {
+	sync.Mutex
+	jobs map[string]*copyJob
+}
+
+func (c *copyJobs) add(j *copyJob) {
+	c.Lock()
+	defer c.Unlock()
+	if c.jobs == nil {
+		c.jobs = make(map[string]*copyJob)
+	}
+	c.jobs[j.volumeID] = j
+}
+
+func (c *copyJobs) remove(volumeID string) {
+	c.Lock()
+	defer c.Unlock()
+	delete(c.jobs, volumeID)
+}
+
+func (c *copyJobs) status() [][2]string {
+	c.Lock()
+	defer c.Unlock()
+	status := make([][2]string, 0, len(c.jobs))
+	for _, j := range c.jobs {
+		status = append(status, [2]string{
+			"Copy " + j.sourceID + " to " + j.volumeID,
+			j.String(),
+		})
+	}
+	return status
+}
+
+// copyJobID returns the id of the job which copies the data of a volume.
+func copyJobID(volumeID string) string {
+	return "volumecopy-" + volumeID
+}
+
+// setCopyState publishes the state of a copy in the volume's runtime state.
+func setCopyState(v *api.Volume, j *copyJob, state string, err error) {
+	rs := map[string]string{
+		copyStateKey:      state,
+		copySourceKey:     j.sourceID,
+		copyJobKey:        copyJobID(j.volumeID),
+		copyBytesKey:      strconv.FormatUint(atomic.LoadUint64(&j.copied), 10),
+		copyTotalBytesKey: strconv.FormatUint(j.total, 10),
+		copyPercentKey:    strconv.FormatUint(j.percent(), 10),
+	}
+	switch state {
+	case copyStateDone:
+		rs[copyPercentKey] = "100"
+	case copyStateFailed:
+		rs[copyErrorKey] = err.Error()
+	}
+	v.RuntimeState = []*api.RuntimeStateMap{{RuntimeState: rs}}
+}
+
+// copyVolumeData copies the data of the parent at src into the new volume at
+// dst. Synchronous backends complete before returning. Asynchronous ones
+// return once a VOLUME_COPY job is created for the copy; the volume stays
+// pending, with its progress in its runtime state and in the job, until the
+// job completes. The job is run by any node, since they all mount the
+// exports, and is resumed by another node if the one running it stops.
+// Asynchronous copies are not point in time, so parents which are attached,
+// and could be written while the copy runs, are rejected by clone.
+func (d *driver) copyVolumeData(
+	newVolumeID string,
+	parentID string,
+	src string,
+	dst string,
+	isDir bool,
+	backend snapshotBackend,
+) error {
+	if !backend.Async() {
+		return backend.Clone(src, dst, isDir, nil)
+	}
+	if d.jobs == nil {
+		return fmt.Errorf("%s backend copies in the background, which needs the job provider of the cluster",
+			backend.Name())
+	}
+
+	// The volume refers to the job before the job exists, so that a volume
+	// left pending by a crash in between is failed by recoverCopies.
+	j := &copyJob{
+		volumeID: newVolumeID,
+		sourceID: parentID,
+	}
+	if err := d.updateCopyState(j, copyStateRunning, nil); err != nil {
+		return err
+	}
+	_, err := d.jobs.CreateJob(&api.Job{
+		Id:   copyJobID(newVolumeID),
+		Type: api.Job_VOLUME_COPY,
+		Job: &api.Job_VolumeCopy{
+			VolumeCopy: &api.VolumeCopyJob{
+				VolumeId: newVolumeID,
+				ParentId: parentID,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	go d.jobs.Schedule()
+	return nil
+}
+
+// copyRunner runs the VOLUME_COPY jobs.
+type copyRunner struct {
+	d *driver
+}
+
+// Run copies the data of the parent into the volume. A copy which was
+// stopped starts over.
+func (r *copyRunner) Run(ctx context.Context, j *api.Job, reporter job.Reporter) error {
+	d := r.d
+	v, err := d.GetVol(j.GetVolumeCopy().GetVolumeId())
+	if err != nil {
+		return err
+	}
+	if v.GetState() != api.VolumeState_VOLUME_STATE_PENDING {
+		// The copy completed before the node running it stopped.
+		return nil
+	}
+	cj := &copyJob{
+		volumeID: v.GetId(),
+		sourceID: j.GetVolumeCopy().GetParentId(),
+		ctx:      ctx,
+	}
+	fail := func(err error) error {
+		logrus.Errorf("Failed to copy %s to %s: %v", cj.sourceID, cj.volumeID, err)
+		d.updateCopyState(cj, copyStateFailed, err)
+		return err
+	}
+
+	parent, err := d.GetVol(cj.sourceID)
+	if err != nil {
+		return fail(err)
+	}
+	src, dst, isDir, err := d.copyPaths(parent, v)
+	if err != nil {
+		return fail(err)
+	}
+	if err := os.RemoveAll(dst); err != nil {
+		return fail(err)
+	}
+	if cj.total, _, err = common.DiskUsage(src); err != nil {
+		return fail(err)
+	}
+	d.copies.add(cj)
+	defer d.copies.remove(cj.volumeID)
+	d.updateCopyState(cj, copyStateRunning, nil)
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(copyProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				d.updateCopyState(cj, copyStateRunning, nil)
+				reporter.SetProgress(uint32(cj.percent()), cj.String())
+			}
+		}
+	}()
+
+	start := time.Now()
+	backend := d.getBackend(parent)
+	err = backend.Clone(src, dst, isDir, cj.progress)
+	close(done)
+	if err != nil {
+		os.RemoveAll(dst)
+		if ctx.Err() == nil || d.copyCancelled(j.GetId()) {
+			return fail(err)
+		}
+		// The job was paused, or this node is stopping. The copy starts
+		// over when the job is run again.
+		logrus.Infof("Copy of %s to %s is stopped", src, dst)
+		return err
+	}
+	logrus.Infof("Copied %v from %s to %s in %v using %s backend",
+		cj, src, dst, time.Since(start), backend.Name())
+	return d.updateCopyState(cj, copyStateDone, nil)
+}
+
+// copyCancelled returns true if the copy job has been cancelled.
+func (d *driver) copyCancelled(jobID string) bool {
+	resp, err := d.jobs.GetJobStatus(context.Background(), &api.SdkGetJobStatusRequest{Id: jobID})
+	return err == nil && resp.GetJob().GetState() == api.Job_CANCELLED
+}
+
+// copyActive returns true if the copy job of the volume can still complete.
+func (d *driver) copyActive(v *api.Volume) bool {
+	if d.jobs == nil {
+		return false
+	}
+	resp, err := d.jobs.GetJobStatus(context.Background(), &api.SdkGetJobStatusRequest{
+		Id: copyJobID(v.GetId()),
+	})
+	if err != nil {
+		return false
+	}
+	switch resp.GetJob().GetState() {
+	case api.Job_PENDING, api.Job_RUNNING, api.Job_PAUSED:
+		return true
+	}
+	return false
+}
+
+// recoverCopies fails the volumes left pending by a copy which can no
+// longer complete, because its job was cancelled or never created. Copies
+// whose job is still active are resumed by the job provider.
+func (d *driver) recoverCopies(vols []*api.Volume) {
+	for _, v := range vols {
+		if v.GetState() != api.VolumeState_VOLUME_STATE_PENDING {
+			continue
+		}
+		var rs map[string]string
+		if len(v.GetRuntimeState()) != 0 {
+			rs = v.GetRuntimeState()[0].GetRuntimeState()
+		}
+		if rs[copyStateKey] != copyStateRunning || d.copyActive(v) {
+			continue
+		}
+		j := &copyJob{volumeID: v.GetId(), sourceID: rs[copySourceKey]}
+		err := fmt.Errorf("Copy from %s did not complete", j.sourceID)
+		logrus.Warnf("Failing volume %s: %v", v.GetId(), err)
+		d.updateCopyState(j, copyStateFailed, err)
+	}
+}
+
+// updateCopyState saves the state of the copy in the volume with a compare
+// and set, so that concurrent updates of the volume are kept. The state of a
+// volume is only changed while it is pending, so that a late progress update
+// does not revive a copy which completed.
+func (d *driver) updateCopyState(j *copyJob, state string, err error) error {
+	uerr := common.UpdateVolFunc(d.StoreEnumerator, j.volumeID, func(v *api.Volume) bool {
+		if v.GetState() != api.VolumeState_VOLUME_STATE_PENDING {
+			return false
+		}
+		setCopyState(v, j, state, err)
+		switch state {
+		case copyStateDone:
+			v.State = api.VolumeState_VOLUME_STATE_AVAILABLE
+		case copyStateFailed:
+			v.State = api.VolumeState_VOLUME_STATE_ERROR
+			v.Error = err.Error()
+		}
+		return true
+	})
+	if uerr != nil {
+		logrus.Warnf("Failed to update copy state of %v: %v", j.volumeID, uerr)
+	}
+	return uerr
+}
+
+// checkCopied returns an error if the data of the volume is still being
+// copied or the copy failed.
+func (d *driver) checkCopied(v *api.Volume) error {
+	if v.GetState() == api.VolumeState_VOLUME_STATE_PENDING {
+		return fmt.Errorf("Volume %v is still being copied from its parent", v.GetId())
+	}
+	if v.GetState() == api.VolumeState_VOLUME_STATE_ERROR {
+		return fmt.Errorf("Volume %v failed to copy from its parent: %v", v.GetId(), v.GetError())
+	}
+	return nil
+}
@@ -0,0 +1,414 @@
+package tmpfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/pborman/uuid"
+	"github.com/portworx/kvdb"
+)
+
+const (
+	// Name of the driver
+	Name = "tmpfs"
+	// Type of the driver
+	Type = api.DriverType_DRIVER_TYPE_FILE
+	// TmpfsBasePath is the default directory the tmpfs of each volume is
+	// mounted under.
+	TmpfsBasePath = "/var/lib/openstorage/tmpfs/"
+)
+
+// driver keeps the data of each volume in a tmpfs of its own, whose size
+// option enforces the size of the volume. The data lives in memory and
+// does not survive a reboot. Ephemeral volumes are deleted once their last
+// mount is removed.
+type driver struct {
+	volume.IODriver
+	volume.BlockDriver
+	volume.StoreEnumerator
+	volume.SnapshotDriver
+	volume.StatsDriver
+	volume.QuiesceDriver
+	volume.CredsDriver
+	volume.CloudBackupDriver
+	volume.CloudMigrateDriver
+	volume.FilesystemTrimDriver
+	volume.FilesystemCheckDriver
+	volume.VerifyChecksumDriver
+	basePath string
+	// lock serializes mounts and unmounts, so that an ephemeral volume is
+	// deleted exactly once.
+	lock     sync.Mutex
+	stats    *common.StatsCollector
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// Init initializes the tmpfs driver. The optional "path" parameter overrides
+// the directory the tmpfs of the volumes are mounted under.
+func Init(params map[string]string) (volume.VolumeDriver, error) {
+	basePath, ok := params["path"]
+	if !ok {
+		basePath = TmpfsBasePath
+	}
+	if err := os.MkdirAll(basePath, 0744); err != nil {
+		return nil, err
+	}
+
+	inst := &driver{
+		IODriver:              volume.IONotSupported,
+		BlockDriver:           volume.BlockNotSupported,
+		StoreEnumerator:       common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
+		SnapshotDriver:        volume.SnapshotNotSupported,
+		StatsDriver:           volume.StatsNotSupported,
+		QuiesceDriver:         volume.QuiesceNotSupported,
+		CredsDriver:           volume.CredsNotSupported,
+		CloudBackupDriver:     volume.CloudBackupNotSupported,
+		CloudMigrateDriver:    volume.CloudMigrateNotSupported,
+		FilesystemTrimDriver:  volume.FilesystemTrimNotSupported,
+		FilesystemCheckDriver: volume.FilesystemCheckNotSupported,
+		VerifyChecksumDriver:  volume.VerifyChecksumNotSupported,
+		basePath:              basePath,
+		stats:                 common.NewStatsCollector(),
+		stop:                  make(chan struct{}),
+	}
+
+	// The tmpfs of the volumes do not survive a reboot. Ephemeral volumes
+	// are gone with them, the others come back empty.
+	vols, err := inst.Enumerate(&api.VolumeLocator{}, nil)
+	if err == nil {
+		for _, v := range vols {
+			if isTmpfs(v.DevicePath) {
+				continue
+			}
+			if v.Spec.Ephemeral {
+				logrus.Infof("Ephemeral volume %v did not survive a restart", v.Id)
+				inst.DeleteVol(v.Id)
+				continue
+			}
+			logrus.Warnf("Data of volume %v was lost, recreating it empty", v.Id)
+			if err := mountTmpfs(v.DevicePath, v.Spec.Size); err != nil {
+				logrus.Warnf("Failed to recreate volume %v: %v", v.Id, err)
+			}
+			v.AttachPath = nil
+			inst.UpdateVol(v)
+		}
+	} else {
+		logrus.Println("Could not enumerate Volumes, ", err)
+	}
+
+	go inst.stats.RefreshUsage(inst.StoreEnumerator, inst.volumeSource, common.UsageRefreshInterval, inst.stop)
+
+	logrus.Println("Tmpfs driver initialized with volumes at: ", basePath)
+	return inst, nil
+}
+
+func (d *driver) StartVolumeWatcher() {
+	return
+}
+
+func (d *driver) GetVolumeWatcher(locator *api.VolumeLocator, labels map[string]string) (chan *api.Volume, error) {
+	return nil, nil
+}
+
+func (d *driver) StopVolumeWatcher() {
+	return
+}
+
+func (d *driver) Name() string {
+	return Name
+}
+
+func (d *driver) Type() api.DriverType {
+	return Type
+}
+
+func (d *driver) Version() (*api.StorageVersion, error) {
+	return &api.StorageVersion{
+		Driver:  d.Name(),
+		Version: "1.0.0",
+	}, nil
+}
+
+func (d *driver) Status() [][2]string {
+	return [][2]string{}
+}
+
+func (d *driver) volumePath(volumeID string) string {
+	return filepath.Join(d.basePath, volumeID)
+}
+
+// mountTmpfs mounts a tmpfs of size bytes at p.
+func mountTmpfs(p string, size uint64) error {
+	if err := os.MkdirAll(p, 0755); err != nil {
+		return err
+	}
+	if err := syscall.Mount(Name, p, Name, 0, fmt.Sprintf("size=%d,mode=0755", size)); err != nil {
+		return fmt.Errorf("Failed to mount tmpfs at %v: %v", p, err)
+	}
+	return nil
+}
+
+// isTmpfs returns whether p is the root of a tmpfs.
+func isTmpfs(p string) bool {
+	var st unix.Statfs_t
+	if p == "" || unix.Statfs(p, &st) != nil || st.Type != unix.TMPFS_MAGIC {
+		return false
+	}
+	var self, parent unix.Stat_t
+	if unix.Stat(p, &self) != nil || unix.Stat(filepath.Dir(p), &parent) != nil {
+		return false
+	}
+	return self.Dev != parent.Dev
+}
+
+// Create mounts a tmpfs limited to the size of the volume.
+func (d *driver) Create(
+	ctx context.Context,
+	locator *api.VolumeLocator,
+	source *api.Source,
+	spec *api.VolumeSpec,
+) (string, error) {
+	if source != nil && source.Parent != "" {
+		return "", volume.ErrNotSupported
+	}
+	if spec.Size == 0 {
+		return "", fmt.Errorf("Volume size cannot be zero: tmpfs")
+	}
+	volumeID := strings.TrimSuffix(uuid.New(), "\n")
+	p := d.volumePath(volumeID)
+	if err := mountTmpfs(p, spec.Size); err != nil {
+		os.Remove(p)
+		return "", err
+	}
+
+	v := common.NewVolume(
+		volumeID,
+		api.FSType_FS_TYPE_VFS,
+		locator,
+		source,
+		spec,
+	)
+	v.DevicePath = p
+	if err := d.CreateVol(v); err != nil {
+		d.removeTmpfs(p)
+		return "", err
+	}
+	logrus.Infof("Tmpfs created volume %v (size=%v, ephemeral=%v)", volumeID, spec.Size, spec.Ephemeral)
+	return volumeID, nil
+}
+
+func (d *driver) removeTmpfs(p string) error {
+	if err := syscall.Unmount(p, 0); err != nil && err != syscall.EINVAL && err != syscall.ENOENT {
+		return err
+	}
+	if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Delete unmounts the tmpfs of the volume, discarding its data.
+func (d *driver) Delete(ctx context.Context, volumeID string) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) > 0 {
+		return volume.ErrVolAttached
+	}
+	return d.delete(v)
+}
+
+func (d *driver) delete(v *api.Volume) error {
+	if err := d.removeTmpfs(v.DevicePath); err != nil {
+		return err
+	}
+	if err := d.DeleteVol(v.Id); err != nil {
+		return err
+	}
+	logrus.Infof("Tmpfs deleted volume %v", v.Id)
+	return nil
+}
+
+func (d *driver) MountedAt(ctx context.Context, mountpath string) string {
+	return ""
+}
+
+// Mount bind mounts the tmpfs of the volume at mountpath. A volume can be
+// mounted at several paths.
+func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	defer d.stats.Begin("mount", volumeID)()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	for _, p := range v.AttachPath {
+		if p == mountpath {
+			return fmt.Errorf("Volume %q already mounted at %q", volumeID, mountpath)
+		}
+	}
+	if err := syscall.Mount(v.DevicePath, mountpath, "", syscall.MS_BIND, ""); err != nil {
+		return fmt.Errorf("Failed to mount %v at %v: %v", v.DevicePath, mountpath, err)
+	}
+	if v.Readonly {
+		if err := syscall.Mount("", mountpath, "", syscall.MS_REMOUNT|syscall.MS_BIND|syscall.MS_RDONLY, ""); err != nil {
+			syscall.Unmount(mountpath, 0)
+			return fmt.Errorf("Failed to mount %v read-only: %v", mountpath, err)
+		}
+	}
+	v.AttachPath = append(v.AttachPath, mountpath)
+	return d.UpdateVol(v)
+}
+
+// Unmount removes the mount of the volume at mountpath. Ephemeral volumes
+// are deleted with their last mount.
+func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	defer d.stats.Begin("unmount", volumeID)()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	paths := make([]string, 0, len(v.AttachPath))
+	for _, p := range v.AttachPath {
+		if p != mountpath {
+			paths = append(paths, p)
+		}
+	}
+	if len(paths) == len(v.AttachPath) {
+		return fmt.Errorf("Volume %v not mounted at %v", volumeID, mountpath)
+	}
+	if err := syscall.Unmount(mountpath, 0); err != nil {
+		return err
+	}
+	v.AttachPath = paths
+	if len(paths) == 0 && v.Spec.Ephemeral {
+		return d.delete(v)
+	}
+	return d.UpdateVol(v)
+}
+
+// Set updates the locator of the volume or its size, by remounting its
+// tmpfs. The size cannot be set below the bytes in use.
+func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	if spec != nil {
+		if spec.Size == v.Spec.Size {
+			return volume.ErrNotSupported
+		}
+		if err := d.resize(v, spec.Size); err != nil {
+			return err
+		}
+		v.Spec.Size = spec.Size
+	}
+	if locator != nil {
+		v.Locator = locator
+	}
+	return d.UpdateVol(v)
+}
+
+func (d *driver) resize(v *api.Volume, size uint64) error {
+	if size == 0 {
+		return fmt.Errorf("Volume size cannot be zero: tmpfs")
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(v.DevicePath, &st); err != nil {
+		return err
+	}
+	if used := (st.Blocks - st.Bfree) * uint64(st.Bsize); size < used {
+		return fmt.Errorf("Cannot shrink volume %v to %v bytes, it uses %v bytes",
+			v.Id, size, used)
+	}
+	if err := syscall.Mount(Name, v.DevicePath, Name, syscall.MS_REMOUNT,
+		fmt.Sprintf("size=%d", size)); err != nil {
+		return fmt.Errorf("Failed to resize volume %v: %v", v.Id, err)
+	}
+	logrus.Infof("Tmpfs resized volume %v to %v bytes", v.Id, size)
+	return nil
+}
+
+// volumeSource locates the data of a volume for the stats collector.
+func (d *driver) volumeSource(v *api.Volume) (*common.VolumeSource, error) {
+	return &common.VolumeSource{Path: v.GetDevicePath()}, nil
+}
+
+// Stats reports the memory used by the files of the volume.
+func (d *driver) Stats(ctx context.Context, volumeID string, cumulative bool) (*api.Stats, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	src, _ := d.volumeSource(v)
+	return d.stats.Stats(src)
+}
+
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	src, _ := d.volumeSource(v)
+	u, err := d.stats.Usage(src)
+	if err != nil {
+		return 0, err
+	}
+	return u.UsedBytes, nil
+}
+
+func (d *driver) CapacityUsage(ID string) (*api.CapacityUsageResponse, error) {
+	v, err := d.GetVol(ID)
+	if err != nil {
+		return nil, err
+	}
+	src, _ := d.volumeSource(v)
+	return d.stats.CapacityUsage(src)
+}
+
+// VolumeUsageByNode returns the memory used by every volume. Each volume is
+// a tmpfs mounted on this node, so they all belong to it.
+func (d *driver) VolumeUsageByNode(ctx context.Context, nodeID string) (*api.VolumeUsageByNode, error) {
+	vols, err := d.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return d.stats.VolumeUsageByNode(vols, d.volumeSource)
+}
+
+func (d *driver) GetActiveRequests() (*api.ActiveRequests, error) {
+	return d.stats.ActiveRequests(), nil
+}
+
+func (d *driver) Shutdown() {
+	logrus.Printf("%s Shutting down", Name)
+	d.stopOnce.Do(func() { close(d.stop) })
+}
+
+func (d *driver) Catalog(volumeID, path, depth string) (api.CatalogResponse, error) {
+	return api.CatalogResponse{}, volume.ErrNotSupported
+}
+
+func (d *driver) VolService(volumeID string, vtreq *api.VolumeServiceRequest) (*api.VolumeServiceResponse, error) {
+	return nil, volume.ErrNotSupported
+}
@@ -0,0 +1,112 @@
+package tmpfs
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/volume/drivers/test"
+)
+
+const mib = 1024 * 1024
+
+func newDriver(t *testing.T) *driver {
+	if os.Geteuid() != 0 {
+		t.Skip("Mounting tmpfs requires root")
+	}
+	d, err := Init(map[string]string{"path": t.TempDir()})
+	require.NoError(t, err)
+	t.Cleanup(d.Shutdown)
+	return d.(*driver)
+}
+
+func TestAll(t *testing.T) {
+	d := newDriver(t)
+	test.RunShort(t, test.NewContext(d))
+}
+
+func TestSizeLimit(t *testing.T) {
+	d := newDriver(t)
+	id, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "limited"}, nil,
+		&api.VolumeSpec{Size: mib})
+	require.NoError(t, err)
+	defer d.Delete(context.TODO(), id)
+
+	mountpath := t.TempDir()
+	require.NoError(t, d.Mount(context.TODO(), id, mountpath, nil))
+	defer d.Unmount(context.TODO(), id, mountpath, nil)
+
+	data := make([]byte, 2*mib)
+	assert.Error(t, os.WriteFile(filepath.Join(mountpath, "big"), data, 0644))
+	require.NoError(t, os.Remove(filepath.Join(mountpath, "big")))
+
+	// Grow the mounted volume.
+	require.NoError(t, d.Set(context.TODO(), id, nil, &api.VolumeSpec{Size: 4 * mib}))
+	require.NoError(t, os.WriteFile(filepath.Join(mountpath, "big"), data, 0644))
+	assert.Error(t, d.Set(context.TODO(), id, nil, &api.VolumeSpec{Size: mib}))
+
+	vols, err := d.Inspect(correlation.TODO(), []string{id})
+	require.NoError(t, err)
+	require.Len(t, vols, 1)
+	assert.Equal(t, uint64(4*mib), vols[0].Spec.Size)
+
+	stats, err := d.Stats(context.TODO(), id, true)
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, stats.BytesUsed, uint64(2*mib))
+	used, err := d.UsedSize(id)
+	require.NoError(t, err)
+	assert.Equal(t, stats.BytesUsed, used)
+}
+
+func TestEphemeral(t *testing.T) {
+	d := newDriver(t)
+	labels := map[string]string{"pipeline": "build-42"}
+	id, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "scratch", VolumeLabels: labels}, nil,
+		&api.VolumeSpec{Size: mib, Ephemeral: true})
+	require.NoError(t, err)
+	kept, err := d.Create(context.TODO(), &api.VolumeLocator{Name: "kept"}, nil,
+		&api.VolumeSpec{Size: mib})
+	require.NoError(t, err)
+	defer d.Delete(context.TODO(), kept)
+
+	vols, err := d.Enumerate(&api.VolumeLocator{VolumeLabels: labels}, nil)
+	require.NoError(t, err)
+	require.Len(t, vols, 1)
+	assert.Equal(t, id, vols[0].Id)
+
+	first, second := t.TempDir(), t.TempDir()
+	require.NoError(t, d.Mount(context.TODO(), id, first, nil))
+	require.NoError(t, d.Mount(context.TODO(), id, second, nil))
+	assert.Error(t, d.Mount(context.TODO(), id, second, nil))
+	require.NoError(t, os.WriteFile(filepath.Join(first, "file"), []byte("data"), 0644))
+	data, err := os.ReadFile(filepath.Join(second, "file"))
+	require.NoError(t, err)
+	assert.Equal(t, "data", string(data))
+
+	require.NoError(t, d.Unmount(context.TODO(), id, first, nil))
+	vols, err = d.Inspect(correlation.TODO(), []string{id})
+	require.NoError(t, err)
+	require.Len(t, vols, 1)
+	assert.Equal(t, []string{second}, vols[0].AttachPath)
+
+	// The last unmount deletes the volume.
+	require.NoError(t, d.Unmount(context.TODO(), id, second, nil))
+	vols, err = d.Inspect(correlation.TODO(), []string{id})
+	require.NoError(t, err)
+	assert.Empty(t, vols)
+	_, err = os.Stat(d.volumePath(id))
+	assert.True(t, os.IsNotExist(err))
+
+	// Volumes that are not ephemeral are kept.
+	require.NoError(t, d.Mount(context.TODO(), kept, first, nil))
+	require.NoError(t, d.Unmount(context.TODO(), kept, first, nil))
+	vols, err = d.Inspect(correlation.TODO(), []string{kept})
+	require.NoError(t, err)
+	assert.Len(t, vols, 1)
+}
@@ -0,0 +1,51 @@
+package vfs
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pborman/uuid"
+	"github.com/sirupsen/logrus"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+)
+
+// BackupSnapshot clones the volume directory for the cloud backup engine,
+// the same way Snapshot does.
+func (d *driver) BackupSnapshot(v *api.Volume) (string, func(), error) {
+	defer d.stats.Begin("snapshot", v.Id)()
+	snap := filepath.Join(volume.VolumeBase,
+		"."+v.Id+".backup-"+strings.TrimSuffix(uuid.New(), "\n"))
+	if err := common.CloneTree(volumePath(v.Id), snap, false); err != nil {
+		os.RemoveAll(snap)
+		return "", nil, err
+	}
+	return snap, func() { os.RemoveAll(snap) }, nil
+}
+
+// RestoreVolume creates the volume a cloud backup is restored to.
+func (d *driver) RestoreVolume(locator *api.VolumeLocator, spec *api.VolumeSpec) (*api.Volume, string, error) {
+	id, err := d.Create(correlation.TODO(), locator, &api.Source{}, spec)
+	if err != nil {
+		return nil, "", err
+	}
+	v, err := d.GetVol(id)
+	if err != nil {
+		return nil, "", err
+	}
+	return v, volumePath(id), nil
+}
+
+// RestoreDone leaves volumes whose restore failed in place, for their
+// content to be inspected or the volume deleted.
+func (d *driver) RestoreDone(v *api.Volume, err error) {
+	if err != nil {
+		logrus.Errorf("Restore of volume %v from the cloud failed: %v", v.Id, err)
+		return
+	}
+	logrus.Infof("Restored volume %v from the cloud", v.Id)
+}
@@ -0,0 +1,250 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/pkg/correlation"
+	"github.com/libopenstorage/openstorage/volume"
+	"github.com/libopenstorage/openstorage/volume/drivers/common"
+	"github.com/pborman/uuid"
+)
+
+func volumePath(volumeID string) string {
+	return filepath.Join(volume.VolumeBase, volumeID)
+}
+
+// Snapshot clones the volume directory into a new volume. Files are
+// reflinked where the filesystem supports it and copied otherwise.
+func (d *driver) Snapshot(
+	ctx context.Context,
+	volumeID string,
+	readonly bool,
+	locator *api.VolumeLocator,
+	noRetry bool,
+) (string, error) {
+	defer d.stats.Begin("snapshot", volumeID)()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return "", err
+	}
+
+	snapID := newSnapID()
+	// Hardlinks are only safe between trees that are never modified in
+	// place, that is a read-only snapshot of a read-only snapshot.
+	hardlink := readonly && v.Readonly
+	if err := common.CloneTree(volumePath(v.Id), volumePath(snapID), hardlink); err != nil {
+		os.RemoveAll(volumePath(snapID))
+		return "", err
+	}
+	return d.createSnapshot(v, snapID, readonly, locator)
+}
+
+func newSnapID() string {
+	return strings.TrimSuffix(uuid.New(), "\n")
+}
+
+// createSnapshot saves the snapshot snapID of v, whose tree is in place
+func (d *driver) createSnapshot(
+	v *api.Volume,
+	snapID string,
+	readonly bool,
+	locator *api.VolumeLocator,
+) (string, error) {
+	snap := common.NewVolume(
+		snapID,
+		api.FSType_FS_TYPE_VFS,
+		locator,
+		&api.Source{Parent: v.Id},
+		v.Spec.Copy(),
+	)
+	snap.Readonly = readonly
+	snap.DevicePath = volumePath(snapID)
+	if err := d.CreateVol(snap); err != nil {
+		os.RemoveAll(volumePath(snapID))
+		return "", err
+	}
+	logrus.Infof("Created snapshot %v of volume %v", snapID, v.Id)
+	return snapID, nil
+}
+
+// Restore replaces the contents of the volume with those of the snapshot.
+// The volume must not be mounted, since bind mounts would keep referring to
+// the replaced directory.
+func (d *driver) Restore(volumeID string, snapID string) error {
+	defer d.stats.Begin("restore", volumeID)()
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return err
+	}
+	snap, err := d.GetVol(snapID)
+	if err != nil {
+		return err
+	}
+	if len(v.AttachPath) > 0 && len(v.AttachPath[0]) > 0 {
+		return fmt.Errorf("Volume %q must be unmounted to restore, mounted at %q",
+			volumeID, v.AttachPath[0])
+	}
+
+	tmp := filepath.Join(volume.VolumeBase, "."+v.Id+".restore")
+	old := filepath.Join(volume.VolumeBase, "."+v.Id+".old")
+	os.RemoveAll(tmp)
+	os.RemoveAll(old)
+	if err := common.CloneTree(volumePath(snap.Id), tmp, false); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(volumePath(v.Id), old); err != nil {
+		os.RemoveAll(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, volumePath(v.Id)); err != nil {
+		os.Rename(old, volumePath(v.Id))
+		os.RemoveAll(tmp)
+		return err
+	}
+	os.RemoveAll(old)
+	logrus.Infof("Restored volume %v from snapshot %v", v.Id, snap.Id)
+	return nil
+}
+
+// SnapshotGroup snapshots a group of volumes. The mounted volumes are
+// copied to the staging directory while the filesystems they are mounted on
+// are frozen, each once, so that the snapshots are crash consistent. The
+// copies are moved into place once the filesystems are thawed.
+func (d *driver) SnapshotGroup(
+	groupID string,
+	labels map[string]string,
+	volumeIDs []string,
+	deleteOnFailure bool,
+) (*api.GroupSnapCreateResponse, error) {
+	vols, err := common.GroupVolumes(d, groupID, labels, volumeIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	staged, err := d.stageMounted(vols)
+	defer func() {
+		for _, dir := range staged {
+			os.RemoveAll(dir)
+		}
+	}()
+	if err != nil {
+		return nil, err
+	}
+
+	return common.SnapshotVolumes(
+		vols,
+		deleteOnFailure,
+		func(v *api.Volume) (string, error) {
+			locator := &api.VolumeLocator{
+				Name:         v.GetLocator().GetName() + "-" + groupID + "-snap",
+				VolumeLabels: labels,
+			}
+			dir, ok := staged[v.Id]
+			if !ok {
+				return d.Snapshot(correlation.TODO(), v.Id, true, locator, false)
+			}
+			snapID := newSnapID()
+			if err := moveTree(dir, volumePath(snapID)); err != nil {
+				os.RemoveAll(volumePath(snapID))
+				return "", err
+			}
+			return d.createSnapshot(v, snapID, true, locator)
+		},
+		func(snapID string) error {
+			return d.Delete(correlation.TODO(), snapID)
+		},
+	)
+}
+
+// stageMounted copies the mounted volumes to the staging directory while
+// their filesystems are frozen, and returns the copies by volume id. The
+// filesystems are thawed before it returns, whether it fails or not.
+func (d *driver) stageMounted(vols []*api.Volume) (map[string]string, error) {
+	staged := make(map[string]string)
+	// The filesystems to freeze by device, with a path where each is mounted
+	mounts := make(map[uint64]string)
+	for _, v := range vols {
+		if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
+			continue
+		}
+		dev, err := deviceOf(v.AttachPath[0])
+		if err != nil {
+			return staged, err
+		}
+		if _, ok := mounts[dev]; !ok {
+			mounts[dev] = v.AttachPath[0]
+		}
+	}
+	if len(mounts) == 0 {
+		return staged, nil
+	}
+
+	if err := os.MkdirAll(d.staging, 0700); err != nil {
+		return staged, err
+	}
+	stagingDev, err := deviceOf(d.staging)
+	if err != nil {
+		return staged, err
+	}
+	if path, ok := mounts[stagingDev]; ok {
+		return staged, fmt.Errorf("Snapshot staging directory %v is on the filesystem "+
+			"mounted at %v, which is frozen during group snapshots. Set %v to a "+
+			"directory on another filesystem", d.staging, path, SnapshotStagingParam)
+	}
+
+	var frozen []string
+	defer func() {
+		for _, path := range frozen {
+			if err := d.freeze(path, false); err != nil {
+				logrus.Warnf("Failed to thaw filesystem at %v: %v", path, err)
+			}
+		}
+	}()
+	for _, path := range mounts {
+		if err := d.freeze(path, true); err != nil {
+			return staged, err
+		}
+		frozen = append(frozen, path)
+	}
+
+	for _, v := range vols {
+		if len(v.AttachPath) == 0 || len(v.AttachPath[0]) == 0 {
+			continue
+		}
+		dir := filepath.Join(d.staging, newSnapID())
+		staged[v.Id] = dir
+		if err := common.CloneTree(volumePath(v.Id), dir, false); err != nil {
+			return staged, fmt.Errorf("Failed to copy volume %v: %v", v.Id, err)
+		}
+	}
+	return staged, nil
+}
+
+// moveTree moves the tree at src to dst, copying it when they are on
+// different filesystems
+func moveTree(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := common.CloneTree(src, dst, false); err != nil {
+		return err
+	}
+	return os.RemoveAll(src)
+}
+
+func deviceOf(path string) (uint64, error) {
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Dev), nil
+}
@@ -0,0 +1,153 @@
+package vfs
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/libopenstorage/openstorage/api"
+	"github.com/libopenstorage/openstorage/volume"
+	_ "github.com/libopenstorage/openstorage/volume/drivers/test"
+)
+
+func newDriver(t *testing.T) *driver {
+	if os.Geteuid() != 0 {
+		t.Skip("Bind mounting volumes requires root")
+	}
+	require.NoError(t, os.MkdirAll(volume.VolumeBase, 0755))
+	d, err := Init(map[string]string{})
+	require.NoError(t, err)
+	t.Cleanup(d.Shutdown)
+	return d.(*driver)
+}
+
+func createVolume(t *testing.T, d *driver, name string) string {
+	id, err := d.Create(context.TODO(), &api.VolumeLocator{Name: name}, nil,
+		&api.VolumeSpec{Size: 1024 * 1024, Format: api.FSType_FS_TYPE_VFS})
+	require.NoError(t, err)
+	t.Cleanup(func() { d.Delete(context.TODO(), id) })
+	return id
+}
+
+func mountVolume(t *testing.T, d *driver, id string) string {
+	mountpath := t.TempDir()
+	require.NoError(t, d.Mount(context.TODO(), id, mountpath, nil))
+	t.Cleanup(func() { d.Unmount(context.TODO(), id, mountpath, nil) })
+	return mountpath
+}
+
+func readFile(t *testing.T, path string) string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	d := newDriver(t)
+	id := createVolume(t, d, "snaprestore")
+	require.NoError(t, os.WriteFile(filepath.Join(volumePath(id), "data"), []byte("v1"), 0644))
+
+	snapID, err := d.Snapshot(context.TODO(), id, true, &api.VolumeLocator{Name: "snap"}, false)
+	require.NoError(t, err)
+	defer d.Delete(context.TODO(), snapID)
+	assert.Equal(t, "v1", readFile(t, filepath.Join(volumePath(snapID), "data")))
+	snaps, err := d.Inspect(context.TODO(), []string{snapID})
+	require.NoError(t, err)
+	require.Len(t, snaps, 1)
+	assert.Equal(t, id, snaps[0].GetSource().GetParent())
+	assert.True(t, snaps[0].GetReadonly())
+
+	require.NoError(t, os.WriteFile(filepath.Join(volumePath(id), "data"), []byte("v2"), 0644))
+	assert.Equal(t, "v1", readFile(t, filepath.Join(volumePath(snapID), "data")))
+	require.NoError(t, d.Restore(id, snapID))
+	assert.Equal(t, "v1", readFile(t, filepath.Join(volumePath(id), "data")))
+
+	// A mounted volume cannot be restored
+	mountVolume(t, d, id)
+	assert.Error(t, d.Restore(id, snapID))
+
+	used, err := d.UsedSize(id)
+	require.NoError(t, err)
+	assert.NotZero(t, used)
+}
+
+// testFreezer records the freezes of the filesystems
+type testFreezer struct {
+	calls []string
+	err   error
+}
+
+func (f *testFreezer) freeze(path string, freeze bool) error {
+	if freeze && f.err != nil {
+		return f.err
+	}
+	f.calls = append(f.calls, fmt.Sprintf("%v %v", path, freeze))
+	return nil
+}
+
+func TestSnapshotGroup(t *testing.T) {
+	d := newDriver(t)
+	base, err := deviceOf(volume.VolumeBase)
+	require.NoError(t, err)
+	shm, err := deviceOf("/dev/shm")
+	if err != nil || shm == base {
+		t.Skip("Needs /dev/shm on another filesystem than the volumes")
+	}
+	d.staging = filepath.Join("/dev/shm", "vfs-test-staging")
+	defer os.RemoveAll(d.staging)
+	freezer := &testFreezer{}
+	d.freeze = freezer.freeze
+
+	id1 := createVolume(t, d, "group1")
+	id2 := createVolume(t, d, "group2")
+	id3 := createVolume(t, d, "group3")
+	for _, id := range []string{id1, id2, id3} {
+		require.NoError(t, os.WriteFile(filepath.Join(volumePath(id), "data"), []byte(id), 0644))
+	}
+	mount1 := mountVolume(t, d, id1)
+	mountVolume(t, d, id2)
+
+	resp, err := d.SnapshotGroup("", nil, []string{id1, id2, id3}, true)
+	require.NoError(t, err)
+	require.Len(t, resp.GetSnapshots(), 3)
+	for _, id := range []string{id1, id2, id3} {
+		snapID := resp.GetSnapshots()[id].GetVolumeCreateResponse().GetId()
+		defer d.Delete(context.TODO(), snapID)
+		assert.Equal(t, id, readFile(t, filepath.Join(volumePath(snapID), "data")))
+	}
+
+	// Both mounts are on the filesystem of the volumes, which is frozen
+	// once, and the copies are moved out of the staging directory
+	require.Len(t, freezer.calls, 2)
+	assert.Contains(t, freezer.calls[0], " true")
+	assert.Contains(t, freezer.calls[1], " false")
+	entries, err := os.ReadDir(d.staging)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// The filesystem is thawed when a copy fails
+	freezer.calls = nil
+	require.NoError(t, os.RemoveAll(volumePath(id2)))
+	_, err = d.SnapshotGroup("", nil, []string{id1, id2}, true)
+	assert.Error(t, err)
+	assert.Equal(t, []string{mount1 + " true", mount1 + " false"}, freezer.calls)
+
+	// Nothing is snapshotted if the filesystem cannot be frozen
+	freezer.err = fmt.Errorf("freeze failed")
+	_, err = d.SnapshotGroup("", nil, []string{id1}, true)
+	assert.Error(t, err)
+
+	// The staging directory must not be frozen
+	freezer.err = nil
+	freezer.calls = nil
+	d.staging = filepath.Join(volume.VolumeBase, ".vfs-test-staging")
+	defer os.RemoveAll(d.staging)
+	_, err = d.SnapshotGroup("", nil, []string{id1}, true)
+	assert.Error(t, err)
+	assert.Empty(t, freezer.calls)
+}
@@ -10,6 +10,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/sirupsen/logrus"
 
 	"github.com/libopenstorage/openstorage/api"
@@ -26,12 +27,15 @@ const (
 	Type = api.DriverType_DRIVER_TYPE_FILE
 	// freezebin free binary
 	freezebin = "/usr/sbin/fsfreeze"
+	// SnapshotStagingParam is the directory where the mounted volumes of a
+	// group snapshot are copied while they are frozen. It must not be on
+	// the filesystem of the volumes, which is frozen.
+	SnapshotStagingParam = "snapshot_staging_path"
 )
 
 type driver struct {
 	volume.IODriver
 	volume.BlockDriver
-	volume.SnapshotDriver
 	volume.StoreEnumerator
 	volume.StatsDriver
 	volume.CredsDriver
@@ -40,23 +44,46 @@ type driver struct {
 	volume.FilesystemTrimDriver
 	volume.FilesystemCheckDriver
 	volume.VerifyChecksumDriver
+	stats *common.StatsCollector
+	qos   *common.QosEnforcer
+	stop  chan struct{}
+	// staging is the directory of the group snapshots of mounted volumes
+	staging string
+	// freeze freezes or thaws the filesystem mounted at a path
+	freeze func(path string, freeze bool) error
 }
 
-// Init Driver intialization.
+// Init Driver intialization. The optional common.QosCgroupParam parameter
+// selects the cgroup whose I/O to the volumes is throttled, which workloads
+// must run in, and the optional
+// SnapshotStagingParam parameter the staging directory of group snapshots,
+// which defaults to a directory under os.TempDir(). Volumes are backed up to
+// s3 credentials with the common cloud backup engine.
 func Init(params map[string]string) (volume.VolumeDriver, error) {
-	return &driver{
+	kv := kvdb.Instance()
+	d := &driver{
 		volume.IONotSupported,
 		volume.BlockNotSupported,
-		volume.SnapshotNotSupported,
-		common.NewDefaultStoreEnumerator(Name, kvdb.Instance()),
+		common.NewDefaultStoreEnumerator(Name, kv),
 		volume.StatsNotSupported,
-		volume.CredsNotSupported,
-		volume.CloudBackupNotSupported,
+		common.NewDefaultCredsDriver(Name, kv),
+		nil,
 		volume.CloudMigrateNotSupported,
 		volume.FilesystemTrimNotSupported,
 		volume.FilesystemCheckNotSupported,
 		volume.VerifyChecksumNotSupported,
-	}, nil
+		common.NewStatsCollector(),
+		common.NewQosEnforcer(params[common.QosCgroupParam]),
+		make(chan struct{}),
+		params[SnapshotStagingParam],
+		freezePath,
+	}
+	if d.staging == "" {
+		d.staging = filepath.Join(os.TempDir(), "openstorage-vfs-snapshots")
+	}
+	d.CloudBackupDriver = common.NewCloudBackupEngine(Name, kv, d, d.CredsDriver, d)
+	go d.stats.RefreshUsage(d.StoreEnumerator, d.volumeSource, common.UsageRefreshInterval, d.stop)
+	return d, nil
 }
 
 func (d *driver) StartVolumeWatcher() {
@@ -110,6 +137,9 @@ func (d *driver) Delete(ctx context.Context, volumeID string) error {
 	if _, err := d.GetVol(volumeID); err != nil {
 		return err
 	}
+	if err := d.qos.Remove(volumeID); err != nil {
+		logrus.Warnf("Failed to remove I/O limits of volume %v: %v", volumeID, err)
+	}
 	os.RemoveAll(filepath.Join(volume.VolumeBase, string(volumeID)))
 	if err := d.DeleteVol(volumeID); err != nil {
 		return err
@@ -125,12 +155,13 @@ func (d *driver) MountedAt(ctx context.Context, mountpath string) string {
 // Mount volume at specified path
 // Errors ErrEnoEnt, ErrVolDetached may be returned.
 func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	defer d.stats.Begin("mount", volumeID)()
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		logrus.Println(err)
 		return err
 	}
-	if len(v.AttachPath) > 0 && len(v.AttachPath) > 0 {
+	if len(v.AttachPath) > 0 && len(v.AttachPath[0]) > 0 {
 		return fmt.Errorf("Volume %q already mounted at %q", volumeID, v.AttachPath[0])
 	}
 	syscall.Unmount(mountpath, 0)
@@ -147,6 +178,12 @@ func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, o
 		)
 		return err
 	}
+	// Volumes share the device of volume.VolumeBase, and with it their
+	// limits, so mounted volumes must all be given the same limits.
+	if err := d.qos.Apply(v.Id, volumePath(v.Id), v.Spec.IoThrottle); err != nil {
+		syscall.Unmount(mountpath, 0)
+		return fmt.Errorf("Failed to throttle I/O of volume %v: %v", v.Id, err)
+	}
 	if v.AttachPath == nil {
 		v.AttachPath = make([]string, 1)
 	}
@@ -157,6 +194,7 @@ func (d *driver) Mount(ctx context.Context, volumeID string, mountpath string, o
 // Unmount volume at specified path
 // Errors ErrEnoEnt, ErrVolDetached may be returned.
 func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string, options map[string]string) error {
+	defer d.stats.Begin("unmount", volumeID)()
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
@@ -167,29 +205,107 @@ func (d *driver) Unmount(ctx context.Context, volumeID string, mountpath string,
 	if err := syscall.Unmount(v.AttachPath[0], 0); err != nil {
 		return err
 	}
+	if err := d.qos.Remove(v.Id); err != nil {
+		logrus.Warnf("Failed to remove I/O limits of volume %v: %v", v.Id, err)
+	}
 	v.AttachPath = nil
 	return d.UpdateVol(v)
 }
 
+// Set updates the locator or the I/O limits of the volume. Limits of mounted
+// volumes are changed online.
 func (d *driver) Set(ctx context.Context, volumeID string, locator *api.VolumeLocator, spec *api.VolumeSpec) error {
-	if spec != nil {
-		return volume.ErrNotSupported
-	}
 	v, err := d.GetVol(volumeID)
 	if err != nil {
 		return err
 	}
+	if spec != nil {
+		if proto.Equal(spec.GetIoThrottle(), v.Spec.GetIoThrottle()) {
+			return volume.ErrNotSupported
+		}
+		if len(v.AttachPath) > 0 {
+			if err := d.qos.Apply(v.Id, volumePath(v.Id), spec.IoThrottle); err != nil {
+				return err
+			}
+		}
+		v.Spec.IoThrottle = spec.IoThrottle
+	}
 	if locator != nil {
 		v.Locator = locator
 	}
 	return d.UpdateVol(v)
 }
 
+// volumeSource locates the data of a volume for the stats collector. I/O
+// counters are those of the device holding volume.VolumeBase, which is
+// shared by all volumes.
+func (d *driver) volumeSource(v *api.Volume) (*common.VolumeSource, error) {
+	return &common.VolumeSource{Path: volumePath(v.Id), SharedDevice: true}, nil
+}
+
+// Stats reports the space used by the volume directory. All volumes are on
+// the device holding VolumeBase, whose counters would include the I/O of
+// every volume, so I/O counters are only reported for throttled volumes.
+func (d *driver) Stats(ctx context.Context, volumeID string, cumulative bool) (*api.Stats, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return nil, err
+	}
+	src, _ := d.volumeSource(v)
+	stats, err := d.stats.Stats(src)
+	if err != nil {
+		return nil, err
+	}
+	// I/O of throttled volumes is reported as counted against the limits.
+	if err := d.qos.UpdateStats(v.Id, cumulative, stats); err != nil {
+		logrus.Debugf("No throttled I/O statistics for %v: %v", v.Id, err)
+	}
+	return stats, nil
+}
+
+// CapacityUsage returns the space used by the volume directory.
+func (d *driver) CapacityUsage(ID string) (*api.CapacityUsageResponse, error) {
+	v, err := d.GetVol(ID)
+	if err != nil {
+		return nil, err
+	}
+	src, _ := d.volumeSource(v)
+	return d.stats.CapacityUsage(src)
+}
+
+// VolumeUsageByNode returns the usage of every volume. The volumes are
+// directories under VolumeBase on this node, so they all belong to it.
+func (d *driver) VolumeUsageByNode(ctx context.Context, nodeID string) (*api.VolumeUsageByNode, error) {
+	vols, err := d.Enumerate(&api.VolumeLocator{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return d.stats.VolumeUsageByNode(vols, d.volumeSource)
+}
+
+// GetActiveRequests returns the mounts, unmounts, snapshots and restores in
+// progress.
+func (d *driver) GetActiveRequests() (*api.ActiveRequests, error) {
+	return d.stats.ActiveRequests(), nil
+}
+
+// UsedSize walks the volume directory and returns the bytes allocated to it.
+func (d *driver) UsedSize(volumeID string) (uint64, error) {
+	v, err := d.GetVol(volumeID)
+	if err != nil {
+		return 0, err
+	}
+	used, _, err := common.DiskUsage(volumePath(v.Id))
+	return used, err
+}
+
 func (d *driver) Status() [][2]string {
 	return [][2]string{}
 }
 
-func (d *driver) Shutdown() {}
+func (d *driver) Shutdown() {
+	close(d.stop)
+}
 
 func (d *driver) fsFreeze(volumeID string, freeze bool) error {
 	v, err := d.GetVol(volumeID)
@@ -203,13 +319,20 @@ func (d *driver) fsFreeze(volumeID string, freeze bool) error {
 		}
 		return fmt.Errorf("Volume not mounted")
 	}
+	return d.freeze(v.AttachPath[0], freeze)
+}
+
+// freezePath freezes or thaws the filesystem mounted at path
+func freezePath(path string, freeze bool) error {
 	freezeOpt := "-f"
 	if !freeze {
 		freezeOpt = "-u"
 	}
-	_, err = exec.Command(freezebin, freezeOpt,
-		v.AttachPath[0]).Output()
-	return err
+	out, err := exec.Command(freezebin, freezeOpt, path).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s %s failed: %v: %s", freezebin, freezeOpt, path, err, out)
+	}
+	return nil
 }
 
 func (d *driver) Quiesce(